@@ -26,6 +26,9 @@ import (
 
 var usageStr = `
 Usage: nats-server [options]
+       nats-server backup --stream <name> [options]
+       nats-server restore [options]
+       nats-server store check|repair [options]
 
 Server Options:
     -a, --addr, --net <host>         Bind to host address (default: 0.0.0.0)
@@ -59,6 +62,11 @@ JetStream Options:
     -js, --jetstream                 Enable JetStream functionality
     -sd, --store_dir <dir>           Set the storage directory
 
+Backup, Restore and Store Options (on the store directory of a stopped server):
+        --account <name>             Account of the streams (default: $G, all for store)
+        --stream <name>              Stream to backup or check (default: all for store)
+    -f, --file <file>                Backup file (default: stdout for backup, stdin for restore)
+
 Authorization Options:
         --user <user>                User required for connections
         --pass <password>            Password required for connections
@@ -98,6 +106,17 @@ func usage() {
 func main() {
 	exe := "nats-server"
 
+	// Offline tools that work on the store directory of a stopped server.
+	if len(os.Args) > 1 {
+		switch cmd := os.Args[1]; cmd {
+		case "backup", "restore", "store":
+			if err := runStoreCommand(exe, cmd, os.Args[2:]); err != nil {
+				server.PrintAndDie(fmt.Sprintf("%s %s: %s", exe, cmd, err))
+			}
+			return
+		}
+	}
+
 	// Create a FlagSet and sets the usage
 	fs := flag.NewFlagSet(exe, flag.ExitOnError)
 	fs.Usage = usage
@@ -138,3 +157,123 @@ func main() {
 
 	s.WaitForShutdown()
 }
+
+// runStoreCommand runs the backup, restore and store subcommands. They accept
+// the regular server options, so the store directory and encryption keys can be
+// picked up from the configuration file.
+func runStoreCommand(exe, cmd string, args []string) error {
+	// The store command takes check or repair first.
+	var mode string
+	if cmd == "store" {
+		if len(args) > 0 {
+			mode, args = args[0], args[1:]
+		}
+		if mode != "check" && mode != "repair" {
+			return fmt.Errorf("expected check or repair")
+		}
+		cmd += " " + mode
+	}
+
+	fs := flag.NewFlagSet(exe+" "+cmd, flag.ExitOnError)
+	fs.Usage = usage
+
+	var account, stream, file string
+	fs.StringVar(&account, "account", "", "Account of the streams.")
+	fs.StringVar(&stream, "stream", "", "Stream to backup or check.")
+	fs.StringVar(&file, "f", "", "Backup file.")
+	fs.StringVar(&file, "file", "", "Backup file.")
+
+	opts, err := server.ConfigureOptions(fs, args,
+		server.PrintServerAndExit,
+		fs.Usage,
+		server.PrintTLSHelpAndDie)
+	if err != nil {
+		return err
+	}
+	s, err := server.NewServer(opts)
+	if err != nil {
+		return err
+	}
+
+	if account == "" && mode == "" {
+		account = server.DEFAULT_GLOBAL_ACCOUNT
+	}
+
+	switch cmd {
+	case "backup":
+		if stream == "" {
+			return fmt.Errorf("a stream name is required")
+		}
+		w := os.Stdout
+		if file != "" {
+			if w, err = os.Create(file); err != nil {
+				return err
+			}
+		}
+		state, err := s.BackupStream(account, stream, w)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			if file != "" {
+				os.Remove(file)
+			}
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: backed up stream %q in account %q with %d messages\n", exe, stream, account, state.Msgs)
+	case "restore":
+		r := os.Stdin
+		if file != "" {
+			if r, err = os.Open(file); err != nil {
+				return err
+			}
+			defer r.Close()
+		}
+		cfg, err := s.RestoreBackup(account, r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: restored stream %q in account %q\n", exe, cfg.Name, account)
+	default:
+		checks, err := s.CheckStore(account, stream, mode == "repair")
+		if err != nil {
+			return err
+		}
+		var damaged int
+		for _, sc := range checks {
+			status := "OK"
+			if sc.Repaired {
+				status = "REPAIRED"
+			}
+			if !sc.OK() && !sc.Repaired {
+				status = "DAMAGED"
+				damaged++
+			}
+			fmt.Printf("%s > %s: %s, %d blocks, %d msgs, first seq %d, last seq %d, %d consumers\n",
+				sc.Account, sc.Stream, status, sc.Blocks, sc.Msgs, sc.FirstSeq, sc.LastSeq, sc.Consumers)
+			for _, bc := range sc.BadBlocks {
+				fmt.Printf("    bad block %d [%d-%d]", bc.Index, bc.FirstSeq, bc.LastSeq)
+				if bc.Lost != nil {
+					fmt.Printf(", %d msgs and %d bytes after last valid record", len(bc.Lost.Msgs), bc.Lost.Bytes)
+				}
+				if bc.Error != "" {
+					fmt.Printf(": %s", bc.Error)
+				}
+				fmt.Println()
+			}
+			if sc.Lost != nil && len(sc.Lost.Msgs) > 0 {
+				fmt.Printf("    lost %d msgs [%d-%d]\n", len(sc.Lost.Msgs), sc.Lost.Msgs[0], sc.Lost.Msgs[len(sc.Lost.Msgs)-1])
+			}
+			for _, gap := range sc.Gaps {
+				fmt.Printf("    gap [%d-%d]\n", gap[0], gap[1])
+			}
+			for _, p := range sc.Problems {
+				fmt.Printf("    %s\n", p)
+			}
+		}
+		if damaged > 0 {
+			return fmt.Errorf("found damage in %d of %d streams", damaged, len(checks))
+		}
+	}
+	return nil
+}
@@ -57,6 +57,8 @@ type Account struct {
 		gw    stats // Gateways
 		rt    stats // Routes
 		ln    stats // Leafnodes
+
+		rateLimited int64 // Messages that exceeded a user rate limit
 	}
 
 	gwReplyMapping
@@ -91,6 +93,7 @@ type Account struct {
 	exports      exportMap
 	js           *jsAccount
 	jsLimits     map[string]JetStreamAccountLimits
+	jsAutoStrs   []*StreamAutoCreateRule
 	nrgAccount   string
 	limits
 	expired      atomic.Bool
@@ -113,6 +116,10 @@ type Account struct {
 	// and if it falls between 0 and that value, message tracing will be triggered.
 	traceDest         string
 	traceDestSampling int
+	// Percentage of sampled external traces exported as OTLP spans when the
+	// server has message trace OTLP export enabled. If 0, the server default
+	// is used.
+	traceOTLPSampling int
 	// Guarantee that only one goroutine can be running either checkJetStreamMigrate
 	// or clearObserverState at a given time for this account to prevent interleaving.
 	jscmMu sync.Mutex
@@ -299,6 +306,7 @@ func (a *Account) shallowCopy(na *Account) {
 	na.Nkey = a.Nkey
 	na.Issuer = a.Issuer
 	na.traceDest, na.traceDestSampling = a.traceDest, a.traceDestSampling
+	na.traceOTLPSampling = a.traceOTLPSampling
 
 	if a.imports.streams != nil {
 		na.imports.streams = make([]*streamImport, 0, len(a.imports.streams))
@@ -345,6 +353,7 @@ func (a *Account) shallowCopy(na *Account) {
 
 	// JetStream
 	na.jsLimits = a.jsLimits
+	na.jsAutoStrs = a.jsAutoStrs
 	// Server config account limits.
 	na.limits = a.limits
 }
@@ -3440,6 +3449,10 @@ func (s *Server) updateAccountClaimsWithRefresh(a *Account, ac *jwt.AccountClaim
 	// collect mappings that need to be removed
 	removeList := []string{}
 	for _, m := range a.mappings {
+		// Mappings of partitioned streams are not part of the account claims.
+		if m.isPartitionMapping() {
+			continue
+		}
 		if _, ok := ac.Mappings[jwt.Subject(m.src)]; !ok {
 			removeList = append(removeList, m.src)
 		}
@@ -3997,6 +4010,8 @@ func buildInternalNkeyUser(uc *jwt.UserClaims, acts map[string]struct{}, acc *Ac
 		p = acc.defaultPerms.clone()
 	}
 	nu.Permissions = p
+	// Invalid rate limits are rejected when authenticating.
+	nu.RateLimits, _ = parseRateLimitTags(uc.Tags)
 	return nu
 }
 
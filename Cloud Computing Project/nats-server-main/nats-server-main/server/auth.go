@@ -65,6 +65,7 @@ type NkeyUser struct {
 	Account                *Account            `json:"account,omitempty"`
 	SigningKey             string              `json:"signing_key,omitempty"`
 	AllowedConnectionTypes map[string]struct{} `json:"connection_types,omitempty"`
+	RateLimits             []*RateLimit        `json:"rate_limits,omitempty"`
 }
 
 // User is for multiple accounts/users.
@@ -75,6 +76,7 @@ type User struct {
 	Account                *Account            `json:"account,omitempty"`
 	ConnectionDeadline     time.Time           `json:"connection_deadline,omitempty"`
 	AllowedConnectionTypes map[string]struct{} `json:"connection_types,omitempty"`
+	RateLimits             []*RateLimit        `json:"rate_limits,omitempty"`
 }
 
 // clone performs a deep copy of the User struct, returning a new clone with
@@ -94,6 +96,7 @@ func (u *User) clone() *User {
 			clone.AllowedConnectionTypes[k] = v
 		}
 	}
+	clone.RateLimits = cloneRateLimits(u.RateLimits)
 
 	return clone
 }
@@ -115,6 +118,7 @@ func (n *NkeyUser) clone() *NkeyUser {
 			clone.AllowedConnectionTypes[k] = v
 		}
 	}
+	clone.RateLimits = cloneRateLimits(n.RateLimits)
 
 	return clone
 }
@@ -999,6 +1003,10 @@ func (s *Server) processClientOrLeafAuthentication(c *client, opts *Options) (au
 			c.Errorf("Outside connect times")
 			return false
 		}
+		if _, err := parseRateLimitTags(juc.Tags); err != nil {
+			c.Errorf("User %s", err)
+			return false
+		}
 
 		nkey = buildInternalNkeyUser(juc, allowedConnTypes, acc)
 		if err := c.RegisterNkeyUser(nkey); err != nil {
@@ -223,6 +223,7 @@ const (
 	MinimumVersionRequired
 	ClusterNamesIdentical
 	Kicked
+	RateLimitExceeded
 )
 
 // Some flags passed to processMsgResults
@@ -242,6 +243,7 @@ type client struct {
 	srv   *Server
 	acc   *Account
 	perms *permissions
+	rl    *rateLimiter
 	in    readCache
 	parseState
 	opts       ClientOpts
@@ -937,6 +939,7 @@ func (c *client) RegisterUser(user *User) {
 	} else {
 		c.setPermissions(user.Permissions)
 	}
+	c.rl = newRateLimiter(user.RateLimits)
 
 	// allows custom authenticators to set a username to be reported in
 	// server events and more
@@ -974,6 +977,7 @@ func (c *client) RegisterNkeyUser(user *NkeyUser) error {
 	} else {
 		c.setPermissions(user.Permissions)
 	}
+	c.rl = newRateLimiter(user.RateLimits)
 	c.mu.Unlock()
 	return nil
 }
@@ -4043,7 +4047,7 @@ func (c *client) processInboundClientMsg(msg []byte) (bool, bool) {
 		c.mu.Unlock()
 		return false, false
 	}
-	acc := c.acc
+	acc, rl := c.acc, c.rl
 	genidAddr := &acc.sl.genid
 
 	// Check pub permissions
@@ -4067,6 +4071,11 @@ func (c *client) processInboundClientMsg(msg []byte) (bool, bool) {
 		return false, true
 	}
 
+	// Check publish rate limits of the user, if any.
+	if rl != nil && !c.checkRateLimits(rl, acc, len(msg)-LEN_CR_LF) {
+		return false, true
+	}
+
 	if c.opts.Verbose {
 		c.sendOK()
 	}
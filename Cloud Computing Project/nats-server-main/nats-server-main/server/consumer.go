@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"reflect"
 	"regexp"
@@ -114,6 +115,8 @@ type ConsumerConfig struct {
 	Replicas int `json:"num_replicas"`
 	// Force memory storage.
 	MemoryStorage bool `json:"mem_storage,omitempty"`
+	// Place the consumer on the learners of the stream, delivering from their local copy.
+	OnLearners bool `json:"on_learners,omitempty"`
 
 	// Don't add to general clients.
 	Direct bool `json:"direct,omitempty"`
@@ -125,11 +128,58 @@ type ConsumerConfig struct {
 	PauseUntil *time.Time `json:"pause_until,omitempty"`
 
 	// Priority groups
-	PriorityGroups []string       `json:"priority_groups,omitempty"`
-	PriorityPolicy PriorityPolicy `json:"priority_policy,omitempty"`
-	PinnedTTL      time.Duration  `json:"priority_timeout,omitempty"`
+	PriorityGroups  []string       `json:"priority_groups,omitempty"`
+	PriorityPolicy  PriorityPolicy `json:"priority_policy,omitempty"`
+	PinnedTTL       time.Duration  `json:"priority_timeout,omitempty"`
+	PriorityWeights map[string]int `json:"priority_weights,omitempty"` // Weighted policy only, groups default to a weight of 1.
+
+	// DeadLetter is where messages are republished when they hit MaxDeliver or are terminated.
+	DeadLetter *ConsumerDeadLetter `json:"dead_letter,omitempty"`
+
+	// MsgFilter skips messages whose headers or payload do not match, in addition
+	// to the subject filters. Skipped messages are treated as acknowledged, and since
+	// they are only evaluated on delivery, NumPending becomes an upper bound.
+	MsgFilter *MsgFilter `json:"msg_filter,omitempty"`
+
+	// LagThresholds makes the consumer leader send advisories when the consumer falls behind.
+	LagThresholds *ConsumerLagThresholds `json:"lag_thresholds,omitempty"`
+
+	// Watch makes this a subject state watch. It first delivers the last message per
+	// matching subject and then live updates. When the watcher falls behind only the
+	// latest message per subject is delivered. Watches keep no state in the consumer store.
+	Watch bool `json:"watch,omitempty"`
+}
+
+// ConsumerLagThresholds are checked periodically by the consumer leader. When any
+// of them is exceeded a lag exceeded advisory is sent, and once all of them are met
+// again a lag recovered advisory is sent. Thresholds that are zero are not checked.
+type ConsumerLagThresholds struct {
+	// PendingMsgs is the number of messages not yet delivered or awaiting an ack.
+	PendingMsgs uint64 `json:"pending_msgs,omitempty"`
+	// PendingBytes is the size of those messages, estimated from the average message size of the stream.
+	PendingBytes uint64 `json:"pending_bytes,omitempty"`
+	// OldestUnackedAge is the age of the oldest message that was not yet acknowledged.
+	OldestUnackedAge time.Duration `json:"oldest_unacked_age,omitempty"`
+}
+
+// ConsumerDeadLetter is the target for messages that exceeded MaxDeliver or were
+// terminated with +TERM. The original message is republished to Subject, if Stream
+// is set the publish is rejected unless that stream captures Subject.
+type ConsumerDeadLetter struct {
+	Subject string `json:"subject"`
+	Stream  string `json:"stream,omitempty"`
 }
 
+// Headers for dead lettered messages.
+const (
+	JSDeadLetterStream     = "Nats-Dead-Letter-Stream"
+	JSDeadLetterSubject    = "Nats-Dead-Letter-Subject"
+	JSDeadLetterSequence   = "Nats-Dead-Letter-Sequence"
+	JSDeadLetterConsumer   = "Nats-Dead-Letter-Consumer"
+	JSDeadLetterDeliveries = "Nats-Dead-Letter-Deliveries"
+	JSDeadLetterReason     = "Nats-Dead-Letter-Reason"
+)
+
 // SequenceInfo has both the consumer and the stream sequence and last activity.
 type SequenceInfo struct {
 	Consumer uint64     `json:"consumer_seq"`
@@ -205,7 +255,8 @@ func (a *ConsumerAction) UnmarshalJSON(data []byte) error {
 
 // ConsumerNakOptions is for optional NAK values, e.g. delay.
 type ConsumerNakOptions struct {
-	Delay time.Duration `json:"delay"`
+	Delay  time.Duration `json:"delay"`
+	Reason string        `json:"reason,omitempty"`
 }
 
 // PriorityPolicy determines policy for selecting messages based on priority.
@@ -218,18 +269,26 @@ const (
 	PriorityOverflow
 	// Single client takes over handling of the messages, while others are on standby.
 	PriorityPinnedClient
+	// Requests with the lowest priority number are served first, others only when there are none left.
+	PriorityPrioritized
+	// Messages are distributed across the priority groups according to their weights.
+	PriorityWeighted
 )
 
 const (
 	PriorityNoneJSONString         = `"none"`
 	PriorityOverflowJSONString     = `"overflow"`
 	PriorityPinnedClientJSONString = `"pinned_client"`
+	PriorityPrioritizedJSONString  = `"prioritized"`
+	PriorityWeightedJSONString     = `"weighted"`
 )
 
 var (
 	PriorityNoneJSONBytes         = []byte(PriorityNoneJSONString)
 	PriorityOverflowJSONBytes     = []byte(PriorityOverflowJSONString)
 	PriorityPinnedClientJSONBytes = []byte(PriorityPinnedClientJSONString)
+	PriorityPrioritizedJSONBytes  = []byte(PriorityPrioritizedJSONString)
+	PriorityWeightedJSONBytes     = []byte(PriorityWeightedJSONString)
 )
 
 func (pp PriorityPolicy) String() string {
@@ -238,6 +297,10 @@ func (pp PriorityPolicy) String() string {
 		return PriorityOverflowJSONString
 	case PriorityPinnedClient:
 		return PriorityPinnedClientJSONString
+	case PriorityPrioritized:
+		return PriorityPrioritizedJSONString
+	case PriorityWeighted:
+		return PriorityWeightedJSONString
 	default:
 		return PriorityNoneJSONString
 	}
@@ -249,6 +312,10 @@ func (pp PriorityPolicy) MarshalJSON() ([]byte, error) {
 		return PriorityOverflowJSONBytes, nil
 	case PriorityPinnedClient:
 		return PriorityPinnedClientJSONBytes, nil
+	case PriorityPrioritized:
+		return PriorityPrioritizedJSONBytes, nil
+	case PriorityWeighted:
+		return PriorityWeightedJSONBytes, nil
 	case PriorityNone:
 		return PriorityNoneJSONBytes, nil
 	default:
@@ -262,6 +329,10 @@ func (pp *PriorityPolicy) UnmarshalJSON(data []byte) error {
 		*pp = PriorityOverflow
 	case PriorityPinnedClientJSONString:
 		*pp = PriorityPinnedClient
+	case PriorityPrioritizedJSONString:
+		*pp = PriorityPrioritized
+	case PriorityWeightedJSONString:
+		*pp = PriorityWeighted
 	case PriorityNoneJSONString:
 		*pp = PriorityNone
 	default:
@@ -376,6 +447,13 @@ const (
 
 // Calculate accurate replicas for the consumer config with the parent stream config.
 func (consCfg ConsumerConfig) replicas(strCfg *StreamConfig) int {
+	// Consumers on learners are limited by the learners instead.
+	if consCfg.OnLearners && strCfg.Placement != nil && strCfg.Placement.Learners != nil {
+		if lr := strCfg.Placement.Learners.Replicas; consCfg.Replicas == 0 || consCfg.Replicas > lr {
+			return lr
+		}
+		return consCfg.Replicas
+	}
 	if consCfg.Replicas == 0 || consCfg.Replicas > strCfg.Replicas {
 		if !isDurableConsumer(&consCfg) && strCfg.Retention == LimitsPolicy && consCfg.Replicas == 0 {
 			// Matches old-school ephemerals only, where the replica count is 0.
@@ -448,6 +526,8 @@ type consumer struct {
 	replay            bool
 	dtmr              *time.Timer
 	uptmr             *time.Timer // Unpause timer
+	lagtmr            *time.Timer // Lag thresholds check timer
+	lagging           bool        // Whether a lag exceeded advisory was sent
 	gwdtmr            *time.Timer
 	dthresh           time.Duration
 	mch               chan struct{} // Message channel
@@ -457,6 +537,13 @@ type consumer struct {
 	ackEventT         string
 	nakEventT         string
 	deliveryExcEventT string
+	nakr              map[uint64]string             // Last NAK reason per sequence, only tracked with a dead letter target.
+	dlSub             *subscription                 // Receives the acks of the dead letter target.
+	dlPre             string                        // Reply prefix for the acks of the dead letter target.
+	dlw               map[string]*pendingDeadLetter // Dead letters waiting for an ack, by reply subject.
+	dlr               map[uint64]uint64             // Dead letters for MaxDeliver to retry, with their delivery count.
+	dltmr             *time.Timer                   // Retries dead letters for MaxDeliver.
+	wsm               StoreMsg                      // Used by watches to look up the last message per subject.
 	created           time.Time
 	ldt               time.Time
 	lat               time.Time
@@ -497,6 +584,17 @@ type consumer struct {
 	/// pinnedTtl is the remaining time before the current PinId expires.
 	pinnedTtl *time.Timer
 	pinnedTS  time.Time
+
+	// pgw are the current weights of the priority groups for the weighted policy.
+	pgw map[string]int
+
+	// part is the partitioning of our stream, if any. Set on creation and immutable.
+	part *StreamPartitioning
+	// psub receives the pull requests for the partitioned stream when we are on a partition.
+	psub *subscription
+	// pksub receives the signals of other partitions that have new messages, pkick is when we last signaled.
+	pksub *subscription
+	pkick time.Time
 }
 
 // A single subject filter.
@@ -594,6 +692,11 @@ func setConsumerConfigDefaults(config *ConsumerConfig, streamCfg *StreamConfig,
 	if config.PriorityPolicy == PriorityPinnedClient && config.PinnedTTL == 0 {
 		config.PinnedTTL = JsDefaultPinnedTTL
 	}
+
+	// Watches always start with the last message per subject.
+	if config.Watch && config.DeliverPolicy == DeliverAll {
+		config.DeliverPolicy = DeliverLastPerSubject
+	}
 	return nil
 }
 
@@ -607,8 +710,19 @@ func checkConsumerCfg(
 	isRecovering bool,
 ) *ApiError {
 
-	// Check if replicas is defined but exceeds parent stream.
-	if config.Replicas > 0 && config.Replicas > cfg.Replicas {
+	// Check if replicas is defined but exceeds parent stream, or its learners.
+	if config.OnLearners {
+		if cfg.Placement == nil || cfg.Placement.Learners == nil {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer on learners requires a stream with learners"))
+		}
+		if config.Replicas > cfg.Placement.Learners.Replicas {
+			return NewJSConsumerReplicasExceedsStreamError()
+		}
+		// Acks remove messages from the local copy, which learners can not do on their own.
+		if cfg.Retention != LimitsPolicy {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer on learners requires a limits based stream"))
+		}
+	} else if config.Replicas > 0 && config.Replicas > cfg.Replicas {
 		return NewJSConsumerReplicasExceedsStreamError()
 	}
 	// Check that it is not negative
@@ -837,6 +951,87 @@ func checkConsumerCfg(
 		}
 	}
 
+	if len(config.PriorityWeights) > 0 {
+		if config.PriorityPolicy != PriorityWeighted {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer priority weights require the weighted priority policy"))
+		}
+		for group, weight := range config.PriorityWeights {
+			if !slices.Contains(config.PriorityGroups, group) {
+				return NewJSConsumerInvalidPolicyError(fmt.Errorf("consumer priority weight for unknown group %q", group))
+			}
+			if weight <= 0 {
+				return NewJSConsumerInvalidPolicyError(fmt.Errorf("consumer priority weight for group %q must be positive", group))
+			}
+		}
+	}
+
+	if config.MsgFilter != nil {
+		if err := config.MsgFilter.validate(); err != nil {
+			return NewJSConsumerMsgFilterInvalidError(err)
+		}
+	}
+
+	if lt := config.LagThresholds; lt != nil {
+		if lt.OldestUnackedAge < 0 {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer lag oldest unacked age can not be negative"))
+		}
+		if lt.PendingMsgs == 0 && lt.PendingBytes == 0 && lt.OldestUnackedAge == 0 {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer lag thresholds require at least one threshold"))
+		}
+	}
+
+	// Consumers on partitioned streams are backed by a consumer on each partition.
+	if cfg.Partitioning.isParent() && !config.Direct {
+		if !isDurableConsumer(config) {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumers on partitioned streams must be durable"))
+		}
+		if config.InactiveThreshold > 0 {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumers on partitioned streams can not have an inactive threshold"))
+		}
+		if config.PriorityPolicy == PriorityPinnedClient {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumers on partitioned streams can not use the pinned client priority policy"))
+		}
+	}
+
+	if config.Watch {
+		if config.DeliverPolicy != DeliverLastPerSubject {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer watch requires the last per subject deliver policy"))
+		}
+		if config.AckPolicy != AckNone {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer watch requires the none ack policy"))
+		}
+		if isDurableConsumer(config) || config.Direct {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer watch must be ephemeral"))
+		}
+		if config.Replicas > 1 {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer watch can not be replicated"))
+		}
+		if cfg.Retention != LimitsPolicy {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer watch requires a limits based stream"))
+		}
+	}
+
+	if dl := config.DeadLetter; dl != nil {
+		if config.AckPolicy == AckNone {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer dead letter requires an ack policy"))
+		}
+		if dl.Subject == _EMPTY_ || !IsValidPublishSubject(dl.Subject) {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer dead letter subject is not a valid publish subject"))
+		}
+		if dl.Stream != _EMPTY_ && !isValidName(dl.Stream) {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer dead letter stream name is not valid"))
+		}
+		// Do not allow the dead letter to loop back into the parent stream.
+		if dl.Stream == cfg.Name {
+			return NewJSConsumerInvalidPolicyError(errors.New("consumer dead letter stream can not be the consumer's stream"))
+		}
+		for _, subj := range cfg.Subjects {
+			if SubjectsCollide(dl.Subject, subj) {
+				return NewJSConsumerInvalidPolicyError(fmt.Errorf("consumer dead letter subject %q overlaps with stream subject %q", dl.Subject, subj))
+			}
+		}
+	}
+
 	// For now don't allow preferred server in placement.
 	if cfg.Placement != nil && cfg.Placement.Preferred != _EMPTY_ {
 		return NewJSStreamInvalidConfigError(fmt.Errorf("preferred server not permitted in placement"))
@@ -1080,8 +1275,8 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 		return nil, NewJSConsumerBadDurableNameError()
 	}
 
-	// Setup our storage if not a direct consumer.
-	if !config.Direct {
+	// Setup our storage if not a direct consumer or a watch.
+	if !config.Direct && !config.Watch {
 		store, err := mset.store.ConsumerStore(o.name, config)
 		if err != nil {
 			mset.mu.Unlock()
@@ -1159,6 +1354,7 @@ func (mset *stream) addConsumerWithAssignment(config *ConsumerConfig, oname stri
 	o.ackReplyT = fmt.Sprintf("%s.%%d.%%d.%%d.%%d.%%d", pre)
 	o.ackSubj = fmt.Sprintf("%s.*.*.*.*.*", pre)
 	o.nextMsgSubj = fmt.Sprintf(JSApiRequestNextT, mn, o.name)
+	o.part = cfg.Partitioning
 
 	// Check/update the inactive threshold
 	o.updateInactiveThreshold(&o.cfg)
@@ -1261,6 +1457,108 @@ func (o *consumer) updatePauseState(cfg *ConsumerConfig) {
 	})
 }
 
+// How often the consumer leader checks the lag thresholds.
+var consumerLagCheckInterval = 10 * time.Second
+
+// Updates the lag tracking. If we are the leader and lag thresholds are
+// configured we will start a timer to check them periodically.
+// Lock should be held.
+func (o *consumer) updateLagState(cfg *ConsumerConfig) {
+	stopAndClearTimer(&o.lagtmr)
+	// A new leader starts over, and will send a lag exceeded advisory
+	// again if the consumer is still lagging.
+	o.lagging = false
+	if !o.isLeader() || cfg.LagThresholds == nil {
+		return
+	}
+	o.lagtmr = time.AfterFunc(consumerLagCheckInterval, o.checkLag)
+}
+
+// Checks the lag thresholds and sends an advisory when the consumer
+// starts or stops exceeding them.
+func (o *consumer) checkLag() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lt := o.cfg.LagThresholds
+	if o.closed || o.mset == nil || o.lagtmr == nil || lt == nil || !o.isLeader() {
+		return
+	}
+	defer o.lagtmr.Reset(consumerLagCheckInterval)
+
+	pending := o.numPending() + uint64(len(o.pending))
+	var pbytes uint64
+	var state StreamState
+	o.mset.store.FastState(&state)
+	if state.Msgs > 0 {
+		pbytes = pending * (state.Bytes / state.Msgs)
+	}
+	var age time.Duration
+	if pending > 0 {
+		if ts := o.oldestUnackedTimestamp(); ts > 0 {
+			age = time.Since(time.Unix(0, ts))
+		}
+	}
+
+	var exceeded []string
+	if lt.PendingMsgs > 0 && pending > lt.PendingMsgs {
+		exceeded = append(exceeded, "pending_msgs")
+	}
+	if lt.PendingBytes > 0 && pbytes > lt.PendingBytes {
+		exceeded = append(exceeded, "pending_bytes")
+	}
+	if lt.OldestUnackedAge > 0 && age > lt.OldestUnackedAge {
+		exceeded = append(exceeded, "oldest_unacked_age")
+	}
+	lagging := len(exceeded) > 0
+	if lagging == o.lagging {
+		return
+	}
+	o.lagging = lagging
+
+	e := JSConsumerLagAdvisory{
+		TypedEvent: TypedEvent{
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:           o.stream,
+		Consumer:         o.name,
+		PendingMsgs:      pending,
+		PendingBytes:     pbytes,
+		OldestUnackedAge: age,
+		Thresholds:       lt,
+		Exceeded:         exceeded,
+		Domain:           o.srv.getOpts().JetStreamDomain,
+	}
+	subj := JSAdvisoryConsumerLagRecoveredPre + "." + o.stream + "." + o.name
+	e.Type = JSConsumerLagRecoveredAdvisoryType
+	if lagging {
+		subj = JSAdvisoryConsumerLagExceededPre + "." + o.stream + "." + o.name
+		e.Type = JSConsumerLagExceededAdvisoryType
+	}
+	o.sendAdvisory(subj, e)
+}
+
+// Returns the timestamp of the first message for us above the ack floor,
+// or zero if there is none.
+// Lock should be held.
+func (o *consumer) oldestUnackedTimestamp() int64 {
+	var smv StoreMsg
+	var sm *StoreMsg
+	store, fseq := o.mset.store, o.asflr+1
+	if o.filters != nil {
+		sm, _, _ = store.LoadNextMsgMulti(o.filters, fseq, &smv)
+	} else if len(o.subjf) > 0 {
+		sm, _, _ = store.LoadNextMsg(o.subjf[0].subject, o.subjf[0].hasWildcard, fseq, &smv)
+	} else {
+		sm, _, _ = store.LoadNextMsg(_EMPTY_, false, fseq, &smv)
+	}
+	if sm == nil {
+		return 0
+	}
+	return sm.ts
+}
+
 func (o *consumer) consumerAssignment() *consumerAssignment {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
@@ -1407,6 +1705,12 @@ func (o *consumer) setLeader(isLeader bool) {
 			o.infoSub, _ = s.systemSubscribe(isubj, _EMPTY_, false, o.sysc, o.handleClusterConsumerInfoRequest)
 		}
 
+		// Consumers on a partitioned stream are served by the consumers on its partitions.
+		if o.part.isParent() {
+			o.mu.Unlock()
+			return
+		}
+
 		var err error
 		if o.cfg.AckPolicy != AckNone {
 			if o.ackSub, err = o.subscribeInternal(o.ackSubj, o.pushAck); err != nil {
@@ -1418,7 +1722,17 @@ func (o *consumer) setLeader(isLeader bool) {
 
 		// Setup the internal sub for next message requests regardless.
 		// Will error if wrong mode to provide feedback to users.
-		if o.reqSub, err = o.subscribeInternal(o.nextMsgSubj, o.processNextMsgReq); err != nil {
+		// On a partition we also serve the requests for the partitioned stream.
+		if o.part.isPartition() {
+			if o.reqSub, err = o.subscribeInternal(o.nextMsgSubj, o.processPartitionNextMsgReq); err == nil {
+				if o.psub, err = o.subscribeToPartitionedNext(); err == nil {
+					o.pksub, err = o.subscribeInternal(fmt.Sprintf(jsPartitionKickT, o.part.Stream, o.name), o.processPartitionKick)
+				}
+			}
+		} else {
+			o.reqSub, err = o.subscribeInternal(o.nextMsgSubj, o.processNextMsgReq)
+		}
+		if err != nil {
 			o.mu.Unlock()
 			o.deleteWithoutAdvisory()
 			return
@@ -1462,6 +1776,9 @@ func (o *consumer) setLeader(isLeader bool) {
 		// Update the consumer pause tracking.
 		o.updatePauseState(&o.cfg)
 
+		// Start checking lag thresholds, if any.
+		o.updateLagState(&o.cfg)
+
 		// If we are not in ReplayInstant mode mark us as in replay state until resolved.
 		if o.cfg.ReplayPolicy != ReplayInstant {
 			o.replay = true
@@ -1534,6 +1851,8 @@ func (o *consumer) setLeader(isLeader bool) {
 		stopAndClearTimer(&o.dtmr)
 		// Stop any unpause timers. Should only be running on leaders.
 		stopAndClearTimer(&o.uptmr)
+		// Stop checking lag thresholds. Should only be running on leaders.
+		stopAndClearTimer(&o.lagtmr)
 		// Make sure to clear out any re-deliver queues
 		o.stopAndClearPtmr()
 		o.rdq = nil
@@ -1544,7 +1863,10 @@ func (o *consumer) setLeader(isLeader bool) {
 		o.unsubscribe(o.ackSub)
 		o.unsubscribe(o.reqSub)
 		o.unsubscribe(o.fcSub)
-		o.ackSub, o.reqSub, o.fcSub = nil, nil, nil
+		o.unsubscribe(o.psub)
+		o.unsubscribe(o.pksub)
+		o.ackSub, o.reqSub, o.fcSub, o.psub, o.pksub = nil, nil, nil, nil, nil
+		o.clearDeadLetters()
 		if o.infoSub != nil {
 			o.srv.sysUnsubscribe(o.infoSub)
 			o.infoSub = nil
@@ -1570,6 +1892,10 @@ func (o *consumer) setLeader(isLeader bool) {
 
 // This is coming on the wire so do not block here.
 func (o *consumer) handleClusterConsumerInfoRequest(sub *subscription, c *client, _ *Account, subject, reply string, msg []byte) {
+	if o.part.isParent() {
+		go o.partitionedInfoAndReply(reply)
+		return
+	}
 	go o.infoWithSnapAndReply(false, reply)
 }
 
@@ -2028,6 +2354,7 @@ func (o *consumer) hasMaxDeliveries(seq uint64) bool {
 		// Only send the advisory once.
 		if dc == o.maxdc {
 			o.notifyDeliveryExceeded(seq, dc)
+			o.deadLetterMaxDelivered(seq, dc)
 		}
 		// Determine if we signal to start flow of messages again.
 		if o.maxp > 0 && len(o.pending) >= o.maxp {
@@ -2132,6 +2459,12 @@ func (acc *Account) checkNewConsumerConfig(cfg, ncfg *ConsumerConfig) error {
 	if cfg.OptStartSeq != ncfg.OptStartSeq {
 		return errors.New("start sequence can not be updated")
 	}
+	if cfg.Watch != ncfg.Watch {
+		return errors.New("watch can not be updated")
+	}
+	if cfg.OnLearners != ncfg.OnLearners {
+		return errors.New("on learners can not be updated")
+	}
 	if cfg.OptStartTime != nil && ncfg.OptStartTime != nil {
 		// Both have start times set, compare them directly:
 		if !cfg.OptStartTime.Equal(*ncfg.OptStartTime) {
@@ -2268,6 +2601,16 @@ func (o *consumer) updateConfig(cfg *ConsumerConfig) error {
 		}
 	}
 
+	// Check whether the lag thresholds have changed.
+	if !reflect.DeepEqual(cfg.LagThresholds, o.cfg.LagThresholds) {
+		o.updateLagState(cfg)
+	}
+
+	// Start over distributing messages by weight.
+	if cfg.PriorityPolicy != o.cfg.PriorityPolicy || !maps.Equal(cfg.PriorityWeights, o.cfg.PriorityWeights) {
+		o.pgw = nil
+	}
+
 	// Check for Subject Filters update.
 	newSubjects := gatherSubjectFilters(cfg.FilterSubject, cfg.FilterSubjects)
 	if !subjectSliceEqual(newSubjects, o.subjf.subjects()) {
@@ -2424,7 +2767,7 @@ func (o *consumer) processAck(subject, reply string, hdr int, rmsg []byte) {
 		}
 	case bytes.HasPrefix(msg, AckNext):
 		o.processAckMsg(sseq, dseq, dc, _EMPTY_, true)
-		o.processNextMsgRequest(reply, msg[len(AckNext):])
+		o.processNextMsgRequest(reply, msg[len(AckNext):], false)
 		skipAckReply = true
 	case bytes.HasPrefix(msg, AckNak):
 		o.processNak(sseq, dseq, dc, msg)
@@ -2766,6 +3109,13 @@ func (o *consumer) processNak(sseq, dseq, dc uint64, nak []byte) {
 				var nd ConsumerNakOptions
 				if err = json.Unmarshal(arg, &nd); err == nil {
 					d = nd.Delay
+					// Only worth remembering if we may dead letter this message.
+					if nd.Reason != _EMPTY_ && o.cfg.DeadLetter != nil {
+						if o.nakr == nil {
+							o.nakr = make(map[uint64]string)
+						}
+						o.nakr[sseq] = nd.Reason
+					}
 				}
 			} else {
 				d, err = time.ParseDuration(string(arg))
@@ -2807,7 +3157,34 @@ func (o *consumer) processNak(sseq, dseq, dc uint64, nak []byte) {
 // to the client, or `false` if there was an error or the ack is replicated (in which
 // case the reply will be sent later).
 func (o *consumer) processTerm(sseq, dseq, dc uint64, reason, reply string) bool {
-	// Treat like an ack to suppress redelivery.
+	// With a dead letter target, the message is only acked once the target stored it.
+	// If that fails the message stays pending and will be redelivered.
+	if reason != ackTermLimitsReason && reason != ackTermUnackedLimitsReason {
+		o.mu.Lock()
+		if _, ok := o.pending[sseq]; ok {
+			if dlm := o.deadLetterMsg(sseq, dc, reason); dlm != nil {
+				o.publishDeadLetter(dlm, func(err error) {
+					if err != nil {
+						o.srv.Warnf("JetStream consumer '%s > %s > %s' failed to dead letter terminated message %d: %v",
+							o.acc.Name, o.stream, o.name, sseq, err)
+						return
+					}
+					if o.terminate(sseq, dseq, dc, reason, reply) && reply != _EMPTY_ {
+						o.sendAckReply(reply)
+					}
+				})
+				o.mu.Unlock()
+				return false
+			}
+		}
+		o.mu.Unlock()
+	}
+	return o.terminate(sseq, dseq, dc, reason, reply)
+}
+
+// Acks the terminated message to suppress redelivery, and sends the advisory.
+// Returns the same as processAckMsg.
+func (o *consumer) terminate(sseq, dseq, dc uint64, reason, reply string) bool {
 	ackedInPlace := o.processAckMsg(sseq, dseq, dc, reply, false)
 
 	o.mu.Lock()
@@ -3223,6 +3600,7 @@ func (o *consumer) processAckMsg(sseq, dseq, dc uint64, reply string, doSample b
 			}
 		}
 		delete(o.rdc, sseq)
+		delete(o.nakr, sseq)
 		o.removeFromRedeliverQueue(sseq)
 	case AckAll:
 		// no-op
@@ -3241,6 +3619,7 @@ func (o *consumer) processAckMsg(sseq, dseq, dc uint64, reply string, doSample b
 		remove := func(seq uint64) {
 			delete(o.pending, seq)
 			delete(o.rdc, seq)
+			delete(o.nakr, seq)
 			o.removeFromRedeliverQueue(seq)
 			if seq < floor {
 				floor = seq
@@ -3410,8 +3789,12 @@ type PriorityGroup struct {
 	MinPending    int64  `json:"min_pending,omitempty"`
 	MinAckPending int64  `json:"min_ack_pending,omitempty"`
 	Id            string `json:"id,omitempty"`
+	Priority      int    `json:"priority,omitempty"`
 }
 
+// Highest priority number a pull request can use with the prioritized policy.
+const maxPullRequestPriority = 9
+
 // Used in nextReqFromMsg, since the json.Unmarshal causes the request
 // struct to escape to the heap always. This should reduce GC pressure.
 var jsGetNextPool = sync.Pool{
@@ -3473,6 +3856,7 @@ type waitingRequest struct {
 	hbt           time.Time
 	noWait        bool
 	priorityGroup *PriorityGroup
+	partitioned   bool // Received for the partitioned stream.
 }
 
 // sync.Pool for waiting requests.
@@ -3482,6 +3866,14 @@ var wrPool = sync.Pool{
 	},
 }
 
+// Returns the priority group and priority of the request.
+func (wr *waitingRequest) groupAndPriority() (string, int) {
+	if wr.priorityGroup == nil {
+		return _EMPTY_, 0
+	}
+	return wr.priorityGroup.Group, wr.priorityGroup.Priority
+}
+
 // Recycle this request. This request can not be accessed after this call.
 func (wr *waitingRequest) recycleIfDone() bool {
 	if wr != nil && wr.n <= 0 {
@@ -3602,6 +3994,17 @@ func (wq *waitQueue) cycle() {
 	}
 }
 
+// Returns the lowest priority number of the waiting requests.
+func (wq *waitQueue) minPriority() int {
+	minp := maxPullRequestPriority
+	for wr := wq.peek(); wr != nil; wr = wr.next {
+		if _, p := wr.groupAndPriority(); p < minp {
+			minp = p
+		}
+	}
+	return minp
+}
+
 // pop will return the next request and move the read cursor.
 // This will now place a request that still has pending items at the ends of the list.
 func (wq *waitQueue) pop() *waitingRequest {
@@ -3693,11 +4096,40 @@ func (o *consumer) nextWaiting(sz int) *waitingRequest {
 		priorityGroup = o.cfg.PriorityGroups[0]
 	}
 
+	// With the prioritized and weighted policies only some of the requests can
+	// receive this message. Which ones is determined again when requests are removed.
+	prioritized, weighted := o.cfg.PriorityPolicy == PriorityPrioritized, o.cfg.PriorityPolicy == PriorityWeighted
+	var (
+		wlen        int
+		minPriority int
+		nextGroup   string
+	)
+
 	numCycled := 0
 	for wr := o.waiting.peek(); !o.waiting.isEmpty(); wr = o.waiting.peek() {
 		if wr == nil {
 			break
 		}
+		if prioritized || weighted {
+			if n := o.waiting.len(); n != wlen {
+				wlen = n
+				if prioritized {
+					minPriority = o.waiting.minPriority()
+				} else {
+					nextGroup = o.nextWeightedGroup()
+				}
+			}
+			group, priority := wr.groupAndPriority()
+			if prioritized && priority != minPriority || weighted && group != nextGroup {
+				o.waiting.cycle()
+				numCycled++
+				// We're done cycling through the requests.
+				if numCycled >= o.waiting.len() {
+					return nil
+				}
+				continue
+			}
+		}
 		// Check if we have max bytes set.
 		if wr.b > 0 {
 			if sz <= wr.b {
@@ -3782,16 +4214,25 @@ func (o *consumer) nextWaiting(sz int) *waitingRequest {
 				if needNewPin {
 					o.sendPinnedAdvisoryLocked(priorityGroup)
 				}
+				if weighted {
+					o.advanceWeightedGroups(nextGroup)
+				}
 				return o.waiting.pop()
 			} else if time.Since(wr.received) < defaultGatewayRecentSubExpiration && (o.srv.leafNodeEnabled || o.srv.gateway.enabled) {
 				if needNewPin {
 					o.sendPinnedAdvisoryLocked(priorityGroup)
 				}
+				if weighted {
+					o.advanceWeightedGroups(nextGroup)
+				}
 				return o.waiting.pop()
 			} else if o.srv.gateway.enabled && o.srv.hasGatewayInterest(wr.acc.Name, wr.interest) {
 				if needNewPin {
 					o.sendPinnedAdvisoryLocked(priorityGroup)
 				}
+				if weighted {
+					o.advanceWeightedGroups(nextGroup)
+				}
 				return o.waiting.pop()
 			}
 		} else {
@@ -3833,10 +4274,75 @@ func (o *consumer) nextWaiting(sz int) *waitingRequest {
 	return nil
 }
 
+// Selects the priority group to deliver the next message to with the weighted policy.
+// This is a smooth weighted round robin across the groups that have waiting requests.
+// The current weights are only advanced once a request is picked, see advanceWeightedGroups.
+// Lock should be held.
+func (o *consumer) nextWeightedGroup() string {
+	var (
+		group string
+		best  int
+	)
+	weights := o.waitingGroupWeights()
+	for wr := o.waiting.peek(); wr != nil; wr = wr.next {
+		g, _ := wr.groupAndPriority()
+		w, ok := weights[g]
+		if !ok {
+			continue
+		}
+		if cw := o.pgw[g] + w; group == _EMPTY_ || cw > best {
+			group, best = g, cw
+		}
+		delete(weights, g)
+	}
+	return group
+}
+
+// Advances the current weights after a request of the given group was picked.
+// Weights are recomputed from the groups that are waiting right now, and groups
+// without waiting requests start over once they come back.
+// Lock should be held.
+func (o *consumer) advanceWeightedGroups(group string) {
+	weights := o.waitingGroupWeights()
+	if o.pgw == nil {
+		o.pgw = make(map[string]int, len(weights))
+	}
+	for g := range o.pgw {
+		if _, ok := weights[g]; !ok {
+			delete(o.pgw, g)
+		}
+	}
+	var total int
+	for g, w := range weights {
+		o.pgw[g] += w
+		total += w
+	}
+	o.pgw[group] -= total
+}
+
+// Returns the configured weights of the priority groups with waiting requests.
+// Lock should be held.
+func (o *consumer) waitingGroupWeights() map[string]int {
+	weights := make(map[string]int, len(o.cfg.PriorityGroups))
+	for wr := o.waiting.peek(); wr != nil; wr = wr.next {
+		g, _ := wr.groupAndPriority()
+		if _, ok := weights[g]; ok {
+			continue
+		}
+		w, ok := o.cfg.PriorityWeights[g]
+		if !ok {
+			w = 1
+		}
+		weights[g] = w
+	}
+	return weights
+}
+
 // Next message request.
 type nextMsgReq struct {
-	reply string
-	msg   []byte
+	reply       string
+	msg         []byte
+	partitioned bool // Request for the partitioned stream.
 }
 
 var nextMsgReqPool sync.Pool
@@ -3852,7 +4358,7 @@ func newNextMsgReq(reply string, msg []byte) *nextMsgReq {
 	// When getting something from a pool it is critical that all fields are
 	// initialized. Doing this way guarantees that if someone adds a field to
 	// the structure, the compiler will fail the build if this line is not updated.
-	(*nmr) = nextMsgReq{reply, msg}
+	(*nmr) = nextMsgReq{reply, msg, false}
 	return nmr
 }
 
@@ -3868,6 +4374,12 @@ func (nmr *nextMsgReq) returnToPool() {
 // a single message. If the payload is a formal request or a number parseable with Atoi(), then we will send a
 // batch of messages without requiring another request to this endpoint, or an ACK.
 func (o *consumer) processNextMsgReq(_ *subscription, c *client, _ *Account, _, reply string, msg []byte) {
+	o.queueNextMsgReq(c, reply, msg, false)
+}
+
+// queueNextMsgReq queues a pull request to be processed by the consumer.
+// Requests for the partitioned stream can be handed off to other partitions.
+func (o *consumer) queueNextMsgReq(c *client, reply string, msg []byte, partitioned bool) {
 	if reply == _EMPTY_ {
 		return
 	}
@@ -3880,10 +4392,12 @@ func (o *consumer) processNextMsgReq(_ *subscription, c *client, _ *Account, _,
 	}
 
 	_, msg = c.msgParts(msg)
-	o.nextMsgReqs.push(newNextMsgReq(reply, copyBytes(msg)))
+	nmr := newNextMsgReq(reply, copyBytes(msg))
+	nmr.partitioned = partitioned
+	o.nextMsgReqs.push(nmr)
 }
 
-func (o *consumer) processNextMsgRequest(reply string, msg []byte) {
+func (o *consumer) processNextMsgRequest(reply string, msg []byte, partitioned bool) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
@@ -3933,6 +4447,16 @@ func (o *consumer) processNextMsgRequest(reply string, msg []byte) {
 		if priorityGroup.Id != _EMPTY_ && o.cfg.PriorityPolicy != PriorityPinnedClient {
 			sendErr(400, "Bad Request - Not a Pinned Client Priority consumer")
 		}
+
+		if priorityGroup.Priority != 0 && o.cfg.PriorityPolicy != PriorityPrioritized {
+			sendErr(400, "Bad Request - Not a Prioritized Priority consumer")
+			return
+		}
+
+		if priorityGroup.Priority < 0 || priorityGroup.Priority > maxPullRequestPriority {
+			sendErr(400, fmt.Sprintf("Bad Request - Priority must be between 0 and %d", maxPullRequestPriority))
+			return
+		}
 	}
 
 	if priorityGroup != nil && o.cfg.PriorityPolicy != PriorityNone {
@@ -4002,6 +4526,7 @@ func (o *consumer) processNextMsgRequest(reply string, msg []byte) {
 	wr.acc, wr.interest, wr.reply, wr.n, wr.d, wr.noWait, wr.expires, wr.hb, wr.hbt, wr.priorityGroup = acc, interest, reply, batchSize, 0, noWait, expires, hb, hbt, priorityGroup
 	wr.b = maxBytes
 	wr.received = time.Now()
+	wr.partitioned = partitioned
 
 	if err := o.waiting.add(wr); err != nil {
 		// If the client has a heartbeat interval set, don't bother responding with a 409,
@@ -4071,6 +4596,175 @@ func (o *consumer) decDeliveryCount(sseq uint64) {
 	o.rdc[sseq] -= 1
 }
 
+// Maximum time to wait for the dead letter target to ack a dead letter.
+const deadLetterAckWait = 2 * time.Second
+
+var errDeadLetterTimeout = errors.New("timeout waiting for dead letter ack")
+
+// pendingDeadLetter is a dead letter waiting for the ack of the target stream.
+type pendingDeadLetter struct {
+	timer *time.Timer
+	done  func(err error)
+}
+
+// Republish a message that hit MaxDeliver to the dead letter target if one is configured.
+// The original is not acked, but if the target did not store the dead letter it is
+// retried after AckWait until it does, or until the original is gone.
+// Lock should be held.
+func (o *consumer) deadLetterMaxDelivered(sseq, dc uint64) {
+	dlm := o.deadLetterMsg(sseq, dc, _EMPTY_)
+	if dlm == nil {
+		return
+	}
+	o.publishDeadLetter(dlm, func(err error) {
+		if err == nil {
+			return
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.srv.Warnf("JetStream consumer '%s > %s > %s' failed to dead letter message %d, will retry: %v",
+			o.acc.Name, o.stream, o.name, sseq, err)
+		if o.dlr == nil {
+			o.dlr = make(map[uint64]uint64)
+		}
+		o.dlr[sseq] = dc
+		if o.dltmr == nil && o.isLeader() {
+			o.dltmr = time.AfterFunc(o.ackWait(0), o.retryDeadLetters)
+		}
+	})
+}
+
+// Retries the dead letters for MaxDeliver the target did not store.
+func (o *consumer) retryDeadLetters() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.dltmr = nil
+	if !o.isLeader() {
+		return
+	}
+	dlr := o.dlr
+	o.dlr = nil
+	for sseq, dc := range dlr {
+		o.deadLetterMaxDelivered(sseq, dc)
+	}
+}
+
+// Publishes the dead letter and calls done, from its own go routine, once the target
+// stream acked it, or with the error if it rejected the message or did not ack in time.
+// Lock should be held.
+func (o *consumer) publishDeadLetter(pmsg *jsPubMsg, done func(err error)) {
+	if o.dlSub == nil {
+		pre := syncSubject("$JSC.DL")
+		sub, err := o.subscribeInternal(pre+".*", o.processDeadLetterAck)
+		if err != nil {
+			go done(err)
+			return
+		}
+		o.dlSub, o.dlPre = sub, pre
+	}
+	if o.dlw == nil {
+		o.dlw = make(map[string]*pendingDeadLetter)
+	}
+	reply := o.dlPre + "." + nuid.Next()
+	pdl := &pendingDeadLetter{done: done}
+	pdl.timer = time.AfterFunc(deadLetterAckWait, func() {
+		if pdl := o.takePendingDeadLetter(reply); pdl != nil {
+			pdl.done(errDeadLetterTimeout)
+		}
+	})
+	o.dlw[reply] = pdl
+	pmsg.reply = reply
+	o.mset.outq.send(pmsg)
+}
+
+// Removes and returns the dead letter waiting for an ack on the reply subject.
+func (o *consumer) takePendingDeadLetter(reply string) *pendingDeadLetter {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	pdl := o.dlw[reply]
+	if pdl != nil {
+		delete(o.dlw, reply)
+		pdl.timer.Stop()
+	}
+	return pdl
+}
+
+// Processes the ack of the dead letter target. The dead letter is stored if the ack has no error,
+// the expected stream header makes sure it is stored in the configured stream.
+func (o *consumer) processDeadLetterAck(_ *subscription, c *client, _ *Account, subject, _ string, rmsg []byte) {
+	pdl := o.takePendingDeadLetter(subject)
+	if pdl == nil {
+		return
+	}
+	_, msg := c.msgParts(rmsg)
+	var resp JSPubAckResponse
+	err := json.Unmarshal(msg, &resp)
+	if err == nil {
+		if resp.Error != nil {
+			err = resp.Error
+		} else if resp.PubAck == nil {
+			err = errors.New("invalid dead letter ack")
+		}
+	}
+	go pdl.done(err)
+}
+
+// Stops waiting for dead letter acks and retrying dead letters.
+// Lock should be held.
+func (o *consumer) clearDeadLetters() {
+	o.unsubscribe(o.dlSub)
+	o.dlSub, o.dlPre = nil, _EMPTY_
+	for _, pdl := range o.dlw {
+		pdl.timer.Stop()
+	}
+	o.dlw, o.dlr = nil, nil
+	stopAndClearTimer(&o.dltmr)
+}
+
+// Build the dead letter message for the original stream message.
+// Only the leader will dead letter, and the message carries a stable
+// Nats-Msg-Id so a new leader republishing the same message will be
+// deduplicated by the target stream.
+// Lock should be held.
+func (o *consumer) deadLetterMsg(sseq, dc uint64, reason string) *jsPubMsg {
+	dl, mset := o.cfg.DeadLetter, o.mset
+	if nr, ok := o.nakr[sseq]; ok {
+		if reason == _EMPTY_ {
+			reason = nr
+		}
+		delete(o.nakr, sseq)
+	}
+	if dl == nil || mset == nil || mset.store == nil || !o.isLeader() {
+		return nil
+	}
+	var smv StoreMsg
+	sm, err := mset.store.LoadMsg(sseq, &smv)
+	if sm == nil || err != nil {
+		return nil
+	}
+
+	// Strip anything that would make the target stream treat this as a conditional publish.
+	hdr := copyBytes(sm.hdr)
+	for _, key := range []string{JSMsgId, JSMsgRollup, JSMessageIncr, JSBatchId, JSBatchSeq, JSBatchCommit} {
+		hdr = removeHeaderIfPresent(hdr, key)
+	}
+	hdr = removeHeaderIfPrefixPresent(hdr, "Nats-Expected-")
+
+	hdr = genHeader(hdr, JSDeadLetterStream, o.stream)
+	hdr = genHeader(hdr, JSDeadLetterSubject, sm.subj)
+	hdr = genHeader(hdr, JSDeadLetterSequence, strconv.FormatUint(sseq, 10))
+	hdr = genHeader(hdr, JSDeadLetterConsumer, o.name)
+	hdr = genHeader(hdr, JSDeadLetterDeliveries, strconv.FormatUint(dc, 10))
+	if reason != _EMPTY_ {
+		hdr = genHeader(hdr, JSDeadLetterReason, reason)
+	}
+	hdr = genHeader(hdr, JSMsgId, fmt.Sprintf("%s:%s:%d", o.stream, o.name, sseq))
+	if dl.Stream != _EMPTY_ {
+		hdr = genHeader(hdr, JSExpectedStream, dl.Stream)
+	}
+	return newJSPubMsg(dl.Subject, _EMPTY_, _EMPTY_, hdr, copyBytes(sm.msg), nil, 0)
+}
+
 // send a delivery exceeded advisory.
 func (o *consumer) notifyDeliveryExceeded(sseq, dc uint64) {
 	e := JSConsumerDeliveryExceededAdvisory{
@@ -4154,6 +4848,7 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 				// Only send once
 				if dc == o.maxdc+1 {
 					o.notifyDeliveryExceeded(seq, dc-1)
+					o.deadLetterMaxDelivered(seq, dc-1)
 				}
 				// Make sure to remove from pending.
 				if p, ok := o.pending[seq]; ok && p != nil {
@@ -4189,7 +4884,10 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 		return nil, 0, errMaxAckPending
 	}
 
-	if o.hasSkipListPending() {
+	// Scheduled messages are skipped until due, their due copy is delivered instead.
+	sched := o.mset.sched.Load()
+
+	for o.hasSkipListPending() {
 		seq := o.lss.seqs[0]
 		if len(o.lss.seqs) == 1 {
 			o.sseq = o.lss.resume
@@ -4205,6 +4903,16 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 			pmsg.returnToPool()
 		}
 		o.sseq++
+		// Watches skip messages that were superseded since the skip list was made.
+		if sm != nil && ((sched != nil && sched.has(seq)) || o.isCoalesced(o.mset.store, sm)) {
+			pmsg.returnToPool()
+			continue
+		}
+		if mf := o.cfg.MsgFilter; sm != nil && mf != nil && !mf.match(sm.hdr, sm.msg) {
+			o.skipFilteredMsg(seq)
+			pmsg.returnToPool()
+			continue
+		}
 		return pmsg, 1, err
 	}
 
@@ -4218,15 +4926,36 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 
 	// Grab next message applicable to us.
 	filters, subjf, fseq := o.filters, o.subjf, o.sseq
-	// Check if we are multi-filtered or not.
-	if filters != nil {
-		sm, sseq, err = store.LoadNextMsgMulti(filters, fseq, &pmsg.StoreMsg)
-	} else if len(subjf) > 0 { // Means single filtered subject since o.filters means > 1.
-		filter, wc := subjf[0].subject, subjf[0].hasWildcard
-		sm, sseq, err = store.LoadNextMsg(filter, wc, fseq, &pmsg.StoreMsg)
-	} else {
-		// No filter here.
-		sm, sseq, err = store.LoadNextMsg(_EMPTY_, false, fseq, &pmsg.StoreMsg)
+	for {
+		// Check if we are multi-filtered or not.
+		if filters != nil {
+			sm, sseq, err = store.LoadNextMsgMulti(filters, fseq, &pmsg.StoreMsg)
+		} else if len(subjf) > 0 { // Means single filtered subject since o.filters means > 1.
+			filter, wc := subjf[0].subject, subjf[0].hasWildcard
+			sm, sseq, err = store.LoadNextMsg(filter, wc, fseq, &pmsg.StoreMsg)
+		} else {
+			// No filter here.
+			sm, sseq, err = store.LoadNextMsg(_EMPTY_, false, fseq, &pmsg.StoreMsg)
+		}
+		if sm == nil || err != nil {
+			break
+		}
+		if sched != nil && sched.has(sseq) {
+			fseq = sseq + 1
+			continue
+		}
+		// Watches that fell behind skip to the latest message per subject.
+		if o.isCoalesced(store, sm) {
+			fseq = sseq + 1
+			continue
+		}
+		// Messages not matching our message filter are skipped.
+		if mf := o.cfg.MsgFilter; mf != nil && !mf.match(sm.hdr, sm.msg) {
+			o.skipFilteredMsg(sseq)
+			fseq = sseq + 1
+			continue
+		}
+		break
 	}
 	if sm == nil {
 		pmsg.returnToPool()
@@ -4244,6 +4973,52 @@ func (o *consumer) getNextMsg() (*jsPubMsg, uint64, error) {
 	return pmsg, 1, err
 }
 
+// Returns true if we are a watch and a newer message for the same subject is stored,
+// in which case that one will be delivered instead.
+// Lock should be held.
+func (o *consumer) isCoalesced(store StreamStore, sm *StoreMsg) bool {
+	if !o.cfg.Watch {
+		return false
+	}
+	lsm, err := store.LoadLastMsg(sm.subj, &o.wsm)
+	return err == nil && lsm.seq > sm.seq
+}
+
+// Skip a message that does not match our message filter, acting as if it was
+// delivered and acknowledged so that it does not hold back the ack floor, and
+// for interest or workqueue retention, so that it can be removed from the stream.
+// With messages pending the ack floor moves past it once those are acked.
+// Lock should be held.
+func (o *consumer) skipFilteredMsg(sseq uint64) {
+	if len(o.pending) == 0 && sseq > o.asflr {
+		o.adflr, o.asflr = o.dseq-1, sseq
+	}
+	// Replicas, us included, record the skip and ack the message when applying it.
+	if o.node != nil {
+		o.updateSkipped(sseq + 1)
+		return
+	}
+	if o.store != nil {
+		o.store.UpdateStarting(sseq)
+	}
+	if o.retention != LimitsPolicy && o.mset != nil && o.mset.ackq != nil {
+		o.mset.ackq.push(sseq)
+	}
+}
+
+// Returns whether the message at sseq was skipped for not matching our message filter,
+// in which case it needs to be acknowledged on the stream when the skip is applied.
+// Lock should be held.
+func (o *consumer) isFilteredSkip(sseq uint64) bool {
+	mf := o.cfg.MsgFilter
+	if mf == nil || o.retention == LimitsPolicy || o.mset == nil || sseq == 0 {
+		return false
+	}
+	var smv StoreMsg
+	sm, err := o.mset.store.LoadMsg(sseq, &smv)
+	return err == nil && o.isFilteredMatch(sm.subj) && !mf.match(sm.hdr, sm.msg)
+}
+
 // Will check for expiration and lack of interest on waiting requests.
 // Will also do any heartbeats and return the next expiration or HB interval.
 func (o *consumer) processWaiting(eos bool) (int, int, int, time.Time) {
@@ -4497,7 +5272,7 @@ func (o *consumer) processInboundNextMsgReqs(qch chan struct{}) {
 		case <-o.nextMsgReqs.ch:
 			reqs := o.nextMsgReqs.pop()
 			for _, req := range reqs {
-				o.processNextMsgRequest(req.reply, req.msg)
+				o.processNextMsgRequest(req.reply, req.msg, req.partitioned)
 				req.returnToPool()
 			}
 			o.nextMsgReqs.recycle(&reqs)
@@ -4838,7 +5613,8 @@ func (o *consumer) checkNumPending() uint64 {
 		var state StreamState
 		o.mset.store.FastState(&state)
 		npc := o.numPending()
-		if o.sseq > state.LastSeq && npc > 0 || npc > state.Msgs {
+		// Watches coalesce updates per subject so the running count can be too high.
+		if o.cfg.Watch || o.sseq > state.LastSeq && npc > 0 || npc > state.Msgs {
 			// Re-calculate.
 			o.streamNumPending()
 		}
@@ -4901,12 +5677,23 @@ func (o *consumer) calculateNumPending() (npc, npf uint64) {
 	filters, subjf := o.filters, o.subjf
 
 	if filters != nil {
-		return o.mset.store.NumPendingMulti(o.sseq, filters, isLastPerSubject)
+		npc, npf = o.mset.store.NumPendingMulti(o.sseq, filters, isLastPerSubject)
 	} else if len(subjf) > 0 {
 		filter := subjf[0].subject
-		return o.mset.store.NumPending(o.sseq, filter, isLastPerSubject)
+		npc, npf = o.mset.store.NumPending(o.sseq, filter, isLastPerSubject)
+	} else {
+		npc, npf = o.mset.store.NumPending(o.sseq, _EMPTY_, isLastPerSubject)
 	}
-	return o.mset.store.NumPending(o.sseq, _EMPTY_, isLastPerSubject)
+
+	// Scheduled messages only count once they are due.
+	if ms := o.mset.sched.Load(); ms != nil {
+		if n := ms.numPending(o.sseq, o.isFilteredMatch); n < npc {
+			npc -= n
+		} else {
+			npc = 0
+		}
+	}
+	return npc, npf
 }
 
 func convertToHeadersOnly(pmsg *jsPubMsg) {
@@ -5261,6 +6048,7 @@ func (o *consumer) checkPending() {
 		if seq < fseq || seq <= o.asflr {
 			delete(o.pending, seq)
 			delete(o.rdc, seq)
+			delete(o.nakr, seq)
 			o.removeFromRedeliverQueue(seq)
 			shouldUpdateState = true
 			// Check if we need to move ack floors.
@@ -5420,6 +6208,32 @@ func (o *consumer) nextSeq() uint64 {
 	return dseq
 }
 
+// Returns whether the message at the given stream sequence was delivered, and if
+// so whether it is still pending an ack. The leader answers from its in-memory
+// state, others from the replicated state in the store. Since that state lags
+// behind the leader, it is only reported as current if our raft node is current.
+func (o *consumer) ackState(sseq uint64) (delivered, pending, current bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if o.isLeader() {
+		if o.cfg.AckPolicy == AckNone {
+			return sseq < o.sseq, false, true
+		}
+		_, pending = o.pending[sseq]
+		return sseq < o.sseq, pending, true
+	}
+	if o.store == nil || (o.node != nil && !o.node.Current()) {
+		return false, false, false
+	}
+	state, err := o.store.State()
+	if err != nil || state == nil {
+		return false, false, false
+	}
+	_, pending = state.Pending[sseq]
+	return sseq <= state.Delivered.Stream, pending, true
+}
+
 // Used to hold skip list when deliver policy is last per subject.
 type lastSeqSkipList struct {
 	resume uint64
@@ -5671,7 +6485,7 @@ func (o *consumer) purge(sseq uint64, slseq uint64, isWider bool) {
 
 	// This means we can reset everything at this point.
 	if len(o.pending) == 0 {
-		o.pending, o.rdc = nil, nil
+		o.pending, o.rdc, o.nakr = nil, nil, nil
 		o.adflr, o.asflr = o.dseq-1, o.sseq-1
 	}
 
@@ -5789,9 +6603,14 @@ func (o *consumer) stopWithFlags(dflag, sdflag, doSignal, advisory bool) error {
 	o.unsubscribe(o.ackSub)
 	o.unsubscribe(o.reqSub)
 	o.unsubscribe(o.fcSub)
+	o.unsubscribe(o.psub)
+	o.unsubscribe(o.pksub)
+	o.clearDeadLetters()
 	o.ackSub = nil
 	o.reqSub = nil
 	o.fcSub = nil
+	o.psub = nil
+	o.pksub = nil
 	if o.infoSub != nil {
 		o.srv.sysUnsubscribe(o.infoSub)
 		o.infoSub = nil
@@ -5803,6 +6622,7 @@ func (o *consumer) stopWithFlags(dflag, sdflag, doSignal, advisory bool) error {
 	o.stopAndClearPtmr()
 	stopAndClearTimer(&o.dtmr)
 	stopAndClearTimer(&o.gwdtmr)
+	stopAndClearTimer(&o.lagtmr)
 	delivery := o.cfg.DeliverSubject
 	o.waiting = nil
 	// Break us out of the readLoop.
@@ -6067,6 +6887,9 @@ func (o *consumer) processStreamSignal(seq uint64) {
 	}
 	if o.isPushMode() && o.active || o.isPullMode() && !o.waiting.isEmpty() {
 		o.signalNewMessages()
+	} else if o.isPullMode() && o.part.isPartition() {
+		// Other partitions might be holding on to requests that we can serve.
+		o.kickPartitions()
 	}
 }
 
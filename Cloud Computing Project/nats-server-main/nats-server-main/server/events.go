@@ -61,6 +61,8 @@ const (
 	shutdownEventSubj         = "$SYS.SERVER.%s.SHUTDOWN"
 	clientKickReqSubj         = "$SYS.REQ.SERVER.%s.KICK"
 	clientLDMReqSubj          = "$SYS.REQ.SERVER.%s.LDM"
+	raftSnapshotReqSubj       = "$SYS.REQ.SERVER.%s.RAFT.SNAPSHOT"
+	raftMigrateReqSubj        = "$SYS.REQ.SERVER.%s.RAFT.MIGRATE"
 	authErrorEventSubj        = "$SYS.SERVER.%s.CLIENT.AUTH.ERR"
 	authErrorAccountEventSubj = "$SYS.ACCOUNT.CLIENT.AUTH.ERR"
 	serverStatsSubj           = "$SYS.SERVER.%s.STATSZ"
@@ -224,6 +226,7 @@ type AccountStat struct {
 	Sent          DataStats `json:"sent"`
 	Received      DataStats `json:"received"`
 	SlowConsumers int64     `json:"slow_consumers"`
+	RateLimited   int64     `json:"rate_limited,omitempty"`
 }
 
 const AccountNumConnsMsgType = "io.nats.server.advisory.v1.account_connections"
@@ -268,6 +271,7 @@ const (
 	JetStreamEnabled     ServerCapability = 1 << iota // Server had JetStream enabled.
 	BinaryStreamSnapshot                              // New stream snapshot capability.
 	AccountNRG                                        // Move NRG traffic out of system account.
+	NRGPreVote                                        // Answers NRG pre-vote requests.
 )
 
 // Set JetStream capability.
@@ -304,6 +308,17 @@ func (si *ServerInfo) AccountNRG() bool {
 	return si.Flags&AccountNRG != 0
 }
 
+// Set NRG pre-vote capability.
+func (si *ServerInfo) SetNRGPreVote() {
+	si.Flags |= NRGPreVote
+}
+
+// NRGPreVote indicates whether or not we answer pre-vote requests, which allows
+// our peers to hold pre-elections.
+func (si *ServerInfo) NRGPreVote() bool {
+	return si.Flags&NRGPreVote != 0
+}
+
 // ClientInfo is detailed information about the client forming a connection.
 type ClientInfo struct {
 	Start      *time.Time    `json:"start,omitempty"`
@@ -541,6 +556,7 @@ RESET:
 						if s.accountNRGAllowed.Load() {
 							si.SetAccountNRG()
 						}
+						si.SetNRGPreVote()
 					}
 				}
 				var b []byte
@@ -1468,6 +1484,18 @@ func (s *Server) initEventTracking() {
 		s.Errorf("Error setting up client LDM service: %v", err)
 		return
 	}
+	// Raft group snapshot and compaction
+	subject = fmt.Sprintf(raftSnapshotReqSubj, s.info.ID)
+	if _, err := s.sysSubscribe(subject, s.noInlineCallback(s.raftSnapshotGroup)); err != nil {
+		s.Errorf("Error setting up Raft snapshot service: %v", err)
+		return
+	}
+	// Raft group WAL format migration
+	subject = fmt.Sprintf(raftMigrateReqSubj, s.info.ID)
+	if _, err := s.sysSubscribe(subject, s.noInlineCallback(s.raftMigrateGroup)); err != nil {
+		s.Errorf("Error setting up Raft migrate service: %v", err)
+		return
+	}
 }
 
 // UserInfo returns basic information to a user about bound account and user permissions.
@@ -1723,6 +1751,7 @@ func (s *Server) remoteServerUpdate(sub *subscription, c *client, _ *Account, su
 		si.JetStreamEnabled(),
 		si.BinaryStreamSnapshot(),
 		accountNRG,
+		si.NRGPreVote(),
 	})
 	if oldInfo == nil || accountNRG != oldInfo.(nodeInfo).accountNRG {
 		// One of the servers we received statsz from changed its mind about
@@ -1777,6 +1806,7 @@ func (s *Server) processNewServer(si *ServerInfo) {
 				si.JetStreamEnabled(),
 				si.BinaryStreamSnapshot(),
 				si.AccountNRG(),
+				si.NRGPreVote(),
 			})
 		}
 	}
@@ -2457,6 +2487,7 @@ func (a *Account) statz() *AccountStat {
 		},
 	}
 	slowConsumers := a.stats.slowConsumers
+	rateLimited := a.stats.rateLimited
 	a.stats.Unlock()
 
 	return &AccountStat{
@@ -2469,6 +2500,7 @@ func (a *Account) statz() *AccountStat {
 		Received:      received,
 		Sent:          sent,
 		SlowConsumers: slowConsumers,
+		RateLimited:   rateLimited,
 	}
 }
 
@@ -3192,6 +3224,83 @@ func (s *Server) ldmClient(_ *subscription, c *client, _ *Account, subject, repl
 	})
 }
 
+// RaftSnapshotReq asks a server to snapshot and compact the WAL of one of its Raft groups.
+type RaftSnapshotReq struct {
+	Group string `json:"group"`
+}
+
+// RaftSnapshotResp holds the WAL state of the Raft group before and after the snapshot.
+type RaftSnapshotResp struct {
+	Group  string      `json:"group"`
+	Before StreamState `json:"before"`
+	After  StreamState `json:"after"`
+}
+
+func (s *Server) raftSnapshotGroup(_ *subscription, c *client, _ *Account, subject, reply string, hdr, msg []byte) {
+	if !s.eventsRunning() {
+		return
+	}
+
+	var req RaftSnapshotReq
+	if err := json.Unmarshal(msg, &req); err != nil {
+		s.sys.client.Errorf("Error unmarshalling Raft snapshot request: %v", err)
+		return
+	}
+
+	optz := &EventFilterOptions{}
+	s.zReq(c, reply, hdr, msg, optz, optz, func() (any, error) {
+		n, ok := s.lookupRaftNode(req.Group).(*raft)
+		if !ok || n == nil {
+			return nil, errUnknownRaftGroup
+		}
+		resp := &RaftSnapshotResp{Group: req.Group}
+		n.RLock()
+		n.wal.FastState(&resp.Before)
+		n.RUnlock()
+		if err := s.JetStreamSnapshotGroup(req.Group); err != nil {
+			return nil, err
+		}
+		n.RLock()
+		n.wal.FastState(&resp.After)
+		n.RUnlock()
+		return resp, nil
+	})
+}
+
+// RaftMigrateReq asks a server to migrate the WAL of one of its Raft groups to another format.
+type RaftMigrateReq struct {
+	Group       string           `json:"group"`
+	Compression StoreCompression `json:"compression"`
+}
+
+// RaftMigrateResp holds the format of the Raft group WAL and the number of blocks rewritten.
+type RaftMigrateResp struct {
+	Group       string           `json:"group"`
+	Compression StoreCompression `json:"compression"`
+	Blocks      int              `json:"blocks"`
+}
+
+func (s *Server) raftMigrateGroup(_ *subscription, c *client, _ *Account, subject, reply string, hdr, msg []byte) {
+	if !s.eventsRunning() {
+		return
+	}
+
+	var req RaftMigrateReq
+	if err := json.Unmarshal(msg, &req); err != nil {
+		s.sys.client.Errorf("Error unmarshalling Raft migrate request: %v", err)
+		return
+	}
+
+	optz := &EventFilterOptions{}
+	s.zReq(c, reply, hdr, msg, optz, optz, func() (any, error) {
+		blocks, err := s.JetStreamMigrateGroupWAL(req.Group, req.Compression)
+		if err != nil {
+			return nil, err
+		}
+		return &RaftMigrateResp{Group: req.Group, Compression: req.Compression, Blocks: blocks}, nil
+	})
+}
+
 // Helper to grab account name for a client.
 func accForClient(c *client) string {
 	if c.acc != nil {
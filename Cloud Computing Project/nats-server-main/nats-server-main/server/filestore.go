@@ -42,6 +42,7 @@ import (
 	"time"
 
 	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
 	"github.com/minio/highwayhash"
 	"github.com/nats-io/nats-server/v2/server/ats"
 	"github.com/nats-io/nats-server/v2/server/avl"
@@ -71,9 +72,21 @@ type FileStoreConfig struct {
 	Cipher StoreCipher
 	// Compression is the algorithm to use when compressing.
 	Compression StoreCompression
+	// CompressionLevel is the zstd compression level, 0 for the default.
+	CompressionLevel int
+	// BlobStore is the tiered storage backend sealed blocks can be offloaded to.
+	BlobStore BlobStore
+	// BlobCacheSize is the size of the cache for blocks fetched from the BlobStore.
+	BlobCacheSize int64
 
 	// Internal reference to our server.
 	srv *Server
+	// Cache for blocks fetched from the BlobStore shared by all streams of the server.
+	// If not set, the store uses its own cache of BlobCacheSize.
+	blobCache *blobCache
+	// Set when the store is a copy that shares the tiered blobs of the original store,
+	// these are never removed through the copy.
+	sharedBlobs bool
 }
 
 // FileStreamInfo allows us to remember created time.
@@ -108,6 +121,13 @@ type StoreCompression uint8
 const (
 	NoCompression StoreCompression = iota
 	S2Compression
+	ZstdCompression
+)
+
+// Range of the zstd compression levels, as for the zstd command line tool.
+const (
+	ZstdMinCompressionLevel = 1
+	ZstdMaxCompressionLevel = 22
 )
 
 func (alg StoreCompression) String() string {
@@ -116,6 +136,8 @@ func (alg StoreCompression) String() string {
 		return "None"
 	case S2Compression:
 		return "S2"
+	case ZstdCompression:
+		return "Zstd"
 	default:
 		return "Unknown StoreCompression"
 	}
@@ -126,6 +148,8 @@ func (alg StoreCompression) MarshalJSON() ([]byte, error) {
 	switch alg {
 	case S2Compression:
 		str = "s2"
+	case ZstdCompression:
+		str = "zstd"
 	case NoCompression:
 		str = "none"
 	default:
@@ -142,6 +166,8 @@ func (alg *StoreCompression) UnmarshalJSON(b []byte) error {
 	switch str {
 	case "s2":
 		*alg = S2Compression
+	case "zstd":
+		*alg = ZstdCompression
 	case "none":
 		*alg = NoCompression
 	default:
@@ -175,6 +201,7 @@ type fileStore struct {
 	state       StreamState
 	tombs       []uint64
 	ld          *LostStreamData
+	rserr       error // Why the full state could not be recovered from index.db, if so.
 	scb         StorageUpdateHandler
 	rmcb        StorageRemoveMsgHandler
 	sdmcb       SubjectDeleteMarkerUpdateHandler
@@ -206,6 +233,10 @@ type fileStore struct {
 	ttls        *thw.HashWheel
 	sdm         *SDMMeta
 	lpex        time.Time // Last PurgeEx call.
+	cmpi        StreamCompactionInfo
+	blobs       BlobStore
+	bcache      *blobCache
+	tierPrefix  string
 }
 
 // Represents a message store block and its data.
@@ -253,6 +284,8 @@ type msgBlock struct {
 	syncAlways bool
 	noCompact  bool
 	closed     bool
+	offloaded  bool   // Block was moved to tiered storage.
+	bkey       string // Key of the offloaded block in tiered storage.
 	ttls       uint64 // How many msgs have TTLs?
 
 	// Used to mock write failures.
@@ -421,6 +454,20 @@ func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created tim
 		qch:    make(chan struct{}),
 		fsld:   make(chan struct{}),
 		srv:    fcfg.srv,
+		blobs:  fcfg.BlobStore,
+	}
+
+	// Offloaded blocks are keyed by account and stream name, and by the server and creation time
+	// of this store so replicas sharing the same blob store don't overwrite each other's blocks.
+	if fs.blobs != nil {
+		if fs.bcache = fcfg.blobCache; fs.bcache == nil {
+			fs.bcache = newBlobCache(fcfg.BlobCacheSize)
+		}
+		node := "_"
+		if fs.srv != nil {
+			node = fs.srv.NodeName()
+		}
+		fs.tierPrefix = fmt.Sprintf("%s/%s/%s/%d", filepath.Base(filepath.Dir(filepath.Dir(fcfg.StoreDir))), cfg.Name, node, created.UnixNano())
 	}
 
 	// Register with access time service.
@@ -471,6 +518,7 @@ func newFileStoreWithCreated(fcfg FileStoreConfig, cfg StreamConfig, created tim
 	if err != nil {
 		if !os.IsNotExist(err) {
 			fs.warn("Recovering stream state from index errored: %v", err)
+			fs.rserr = err
 		}
 		// Hold onto state
 		prior := fs.state
@@ -649,6 +697,10 @@ func (fs *fileStore) UpdateConfig(cfg *StreamConfig) error {
 		return err
 	}
 
+	// Compression changes apply to blocks sealed from now on. Existing blocks
+	// keep the algorithm recorded in their metadata and remain readable.
+	fs.fcfg.Compression, fs.fcfg.CompressionLevel = cfg.Compression, cfg.CompressionLevel
+
 	// Create or delete the THW if needed.
 	if cfg.AllowMsgTTL && fs.ttls == nil {
 		fs.ttls = thw.NewHashWheel()
@@ -1050,20 +1102,26 @@ func (mb *msgBlock) ensureLastChecksumLoaded() {
 }
 
 // Lock held on entry
-func (fs *fileStore) recoverMsgBlock(index uint32) (*msgBlock, error) {
+func (fs *fileStore) recoverMsgBlock(index uint32, offloaded bool) (*msgBlock, error) {
 	mb := fs.initMsgBlock(index)
-	// Open up the message file, but we will try to recover from the index file.
-	// We will check that the last checksums match.
-	file, err := mb.openBlock()
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	if fi, err := file.Stat(); fi != nil {
-		mb.rbytes = uint64(fi.Size())
+	// Offloaded blocks are read in place from tiered storage.
+	var file *os.File
+	if mb.offloaded = offloaded; offloaded {
+		mb.loadBlobKey()
 	} else {
-		return nil, err
+		// Open up the message file, but we will try to recover from the index file.
+		// We will check that the last checksums match.
+		var err error
+		if file, err = mb.openBlock(); err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		if fi, err := file.Stat(); fi != nil {
+			mb.rbytes = uint64(fi.Size())
+		} else {
+			return nil, err
+		}
 	}
 
 	// Make sure encryption loaded if needed.
@@ -1071,18 +1129,20 @@ func (fs *fileStore) recoverMsgBlock(index uint32) (*msgBlock, error) {
 
 	// Grab last checksum from main block file.
 	var lchk [8]byte
-	if mb.rbytes >= checksumSize {
-		if mb.bek != nil {
-			if buf, _ := mb.loadBlock(nil); len(buf) >= checksumSize {
+	if mb.bek != nil || offloaded {
+		if buf, _ := mb.loadBlock(nil); len(buf) >= checksumSize {
+			if mb.bek != nil {
 				mb.bek.XORKeyStream(buf, buf)
-				copy(lchk[0:], buf[len(buf)-checksumSize:])
 			}
-		} else {
-			file.ReadAt(lchk[:], int64(mb.rbytes)-checksumSize)
+			copy(lchk[0:], buf[len(buf)-checksumSize:])
 		}
+	} else if mb.rbytes >= checksumSize {
+		file.ReadAt(lchk[:], int64(mb.rbytes)-checksumSize)
 	}
 
-	file.Close()
+	if file != nil {
+		file.Close()
+	}
 
 	// Read our index file. Use this as source of truth if possible.
 	// This not applicable in >= 2.10 servers. Here for upgrade paths from < 2.10.
@@ -1215,6 +1275,9 @@ func (fs *fileStore) rebuildStateLocked(ld *LostStreamData) {
 
 // Attempt to convert the cipher used for this message block.
 func (mb *msgBlock) convertCipher() error {
+	if err := mb.rehydrate(); err != nil {
+		return err
+	}
 	fs := mb.fs
 	sc := fs.fcfg.Cipher
 
@@ -1302,6 +1365,9 @@ func (mb *msgBlock) convertToEncrypted() error {
 	if mb.bek == nil {
 		return nil
 	}
+	if err := mb.rehydrate(); err != nil {
+		return err
+	}
 	buf, err := mb.loadBlock(nil)
 	if err != nil {
 		return err
@@ -1767,6 +1833,7 @@ func (fs *fileStore) recoverFullState() (rerr error) {
 
 	if numBlocks := readU64(); numBlocks > 0 {
 		lastIndex := int(numBlocks - 1)
+		offloaded := offloadedBlockIndexes(filepath.Join(fs.fcfg.StoreDir, msgDir))
 		fs.blks = make([]*msgBlock, 0, numBlocks)
 		for i := 0; i < int(numBlocks); i++ {
 			index, nbytes, fseq, fts, lseq, lts, numDeleted := uint32(readU64()), readU64(), readU64(), readI64(), readU64(), readI64(), readU64()
@@ -1784,6 +1851,10 @@ func (fs *fileStore) recoverFullState() (rerr error) {
 			mb.msgs, mb.bytes = lseq-fseq+1, nbytes
 			mb.first.ts, mb.last.ts = fts+baseTime, lts+baseTime
 			mb.ttls = ttls
+			if _, ok := offloaded[index]; ok {
+				mb.offloaded = true
+				mb.loadBlobKey()
+			}
 			if numDeleted > 0 {
 				dmap, n, err := avl.Decode(buf[bi:])
 				if err != nil {
@@ -1967,13 +2038,36 @@ func (fs *fileStore) recoverTTLState() error {
 // Grabs last checksum for the named block file.
 // Takes into account encryption etc.
 func (mb *msgBlock) lastChecksum() []byte {
+	var lchk [8]byte
+	if mb.offloaded {
+		if err := mb.checkAndLoadEncryption(); err != nil {
+			return nil
+		}
+		buf, err := mb.loadBlock(nil)
+		if err != nil {
+			return nil
+		}
+		defer recycleMsgBlockBuf(buf)
+		if len(buf) >= checksumSize {
+			if mb.bek != nil {
+				bek, err := genBlockEncryptionKey(mb.fs.fcfg.Cipher, mb.seed, mb.nonce)
+				if err != nil {
+					return nil
+				}
+				mb.bek = bek
+				mb.bek.XORKeyStream(buf, buf)
+			}
+			copy(lchk[0:], buf[len(buf)-checksumSize:])
+		}
+		return lchk[:]
+	}
+
 	f, err := mb.openBlock()
 	if err != nil {
 		return nil
 	}
 	defer f.Close()
 
-	var lchk [8]byte
 	if fi, _ := f.Stat(); fi != nil {
 		mb.rbytes = uint64(fi.Size())
 	}
@@ -2063,12 +2157,21 @@ func (fs *fileStore) recoverMsgs() error {
 			indices = append(indices, index)
 		}
 	}
+	// Offloaded blocks are rebuilt in place.
+	offloaded := fs.recoverOffloadedBlocks(mdir, dirs)
+	if len(offloaded) > 0 && fs.blobs == nil {
+		return errNoBlobStore
+	}
+	for index := range offloaded {
+		indices = append(indices, int(index))
+	}
 	indices.Sort()
 
 	// Recover all of the msg blocks.
 	// We now guarantee they are coming in order.
 	for _, index := range indices {
-		if mb, err := fs.recoverMsgBlock(uint32(index)); err == nil && mb != nil {
+		_, isOffloaded := offloaded[uint32(index)]
+		if mb, err := fs.recoverMsgBlock(uint32(index), isOffloaded); err == nil && mb != nil {
 			// This is a truncate block with possibly no index. If the OS got shutdown
 			// out from underneath of us this is possible.
 			if mb.first.seq == 0 {
@@ -4305,7 +4408,7 @@ func (fs *fileStore) SkipMsgs(seq uint64, num uint64) error {
 		if mb != nil && fs.fcfg.Compression != NoCompression {
 			// We've now reached the end of this message block, if we want
 			// to compress blocks then now's the time to do it.
-			go mb.recompressOnDiskIfNeeded()
+			go mb.recompressOnDiskIfNeeded(fs.fcfg.Compression, fs.fcfg.CompressionLevel)
 		}
 		var err error
 		if mb, err = fs.newMsgBlockForWrite(); err != nil {
@@ -4653,8 +4756,14 @@ func (fs *fileStore) removePerSubject(subj string) uint64 {
 
 // Remove a message, optionally rewriting the mb file.
 func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (bool, error) {
+	removed, _, err := fs.removeMsgWithSize(seq, secure, viaLimits, needFSLock)
+	return removed, err
+}
+
+// Same as removeMsg, but also returns the size of the removed message.
+func (fs *fileStore) removeMsgWithSize(seq uint64, secure, viaLimits, needFSLock bool) (bool, uint64, error) {
 	if seq == 0 {
-		return false, ErrStoreMsgNotFound
+		return false, 0, ErrStoreMsgNotFound
 	}
 	fsLock := func() {
 		if needFSLock {
@@ -4671,11 +4780,11 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 
 	if fs.closed {
 		fsUnlock()
-		return false, ErrStoreClosed
+		return false, 0, ErrStoreClosed
 	}
 	if !viaLimits && fs.sips > 0 {
 		fsUnlock()
-		return false, ErrStoreSnapshotInProgress
+		return false, 0, ErrStoreSnapshotInProgress
 	}
 	// If in encrypted mode negate secure rewrite here.
 	if secure && fs.prf != nil {
@@ -4689,7 +4798,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 			err = ErrStoreMsgNotFound
 		}
 		fsUnlock()
-		return false, err
+		return false, 0, err
 	}
 
 	mb.mu.Lock()
@@ -4698,7 +4807,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 	if mb.closed || seq < atomic.LoadUint64(&mb.first.seq) || mb.dmap.Exists(seq) {
 		mb.mu.Unlock()
 		fsUnlock()
-		return false, nil
+		return false, 0, nil
 	}
 
 	// We used to not have to load in the messages except with callbacks or the filtered subject state (which is now always on).
@@ -4708,7 +4817,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 		if err := mb.loadMsgsWithLock(); err != nil {
 			mb.mu.Unlock()
 			fsUnlock()
-			return false, err
+			return false, 0, err
 		}
 	}
 
@@ -4721,7 +4830,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 		if err == errDeletedMsg {
 			err = nil
 		}
-		return false, err
+		return false, 0, err
 	}
 	// Grab size
 	msz := fileStoreMsgSize(sm.subj, sm.hdr, sm.msg)
@@ -4766,7 +4875,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 		// Grab record info.
 		ri, rl, _, _ := mb.slotInfo(int(seq - mb.cache.fseq))
 		if err := mb.eraseMsg(seq, int(ri), int(rl)); err != nil {
-			return false, err
+			return false, 0, err
 		}
 	}
 
@@ -4853,7 +4962,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 		fs.mu.Unlock()
 	}
 
-	return true, nil
+	return true, msz, nil
 }
 
 // Tests whether we should try to compact this block while inline removing msgs.
@@ -4861,7 +4970,7 @@ func (fs *fileStore) removeMsg(seq uint64, secure, viaLimits, needFSLock bool) (
 // If we compacted before but rbytes didn't improve much, guard against constantly compacting.
 // Lock should be held.
 func (mb *msgBlock) shouldCompactInline() bool {
-	return mb.rbytes > compactMinimum && mb.bytes*2 < mb.rbytes && (mb.cbytes == 0 || mb.bytes*2 < mb.cbytes)
+	return !mb.offloaded && mb.rbytes > compactMinimum && mb.bytes*2 < mb.rbytes && (mb.cbytes == 0 || mb.bytes*2 < mb.cbytes)
 }
 
 // Tests whether we should try to compact this block while running periodic sync.
@@ -4869,7 +4978,7 @@ func (mb *msgBlock) shouldCompactInline() bool {
 // Ignores 2MB minimum.
 // Lock should be held.
 func (mb *msgBlock) shouldCompactSync() bool {
-	return mb.bytes*2 < mb.rbytes && !mb.noCompact
+	return mb.bytes*2 < mb.rbytes && !mb.noCompact && !mb.offloaded
 }
 
 // This will compact and rewrite this block. This version will not process any tombstone cleanup.
@@ -5154,6 +5263,11 @@ func (mb *msgBlock) flushLoop(fch, qch chan struct{}) {
 
 // Lock should be held.
 func (mb *msgBlock) eraseMsg(seq uint64, ri, rl int) error {
+	// We need a local copy to rewrite.
+	if err := mb.rehydrate(); err != nil {
+		return err
+	}
+
 	var le = binary.LittleEndian
 	var hdr [msgHdrSize]byte
 
@@ -5848,6 +5962,9 @@ func (mb *msgBlock) enableForWriting(fip bool) error {
 	if mb.mfd != nil {
 		return nil
 	}
+	if err := mb.rehydrate(); err != nil {
+		return err
+	}
 	<-dios
 	mfd, err := os.OpenFile(mb.mfn, os.O_CREATE|os.O_RDWR, defaultFilePerms)
 	dios <- struct{}{}
@@ -6121,7 +6238,7 @@ func (fs *fileStore) checkLastBlock(rl uint64) (lmb *msgBlock, err error) {
 			if fs.fcfg.Compression != NoCompression {
 				// We've now reached the end of this message block, if we want
 				// to compress blocks then now's the time to do it.
-				go lmb.recompressOnDiskIfNeeded()
+				go lmb.recompressOnDiskIfNeeded(fs.fcfg.Compression, fs.fcfg.CompressionLevel)
 			}
 		}
 		if lmb, err = fs.newMsgBlockForWrite(); err != nil {
@@ -6176,11 +6293,48 @@ func (fs *fileStore) writeTombstoneNoFlush(seq uint64, ts int64) error {
 	return lmb.writeTombstoneNoFlush(seq, ts)
 }
 
-func (mb *msgBlock) recompressOnDiskIfNeeded() error {
-	alg := mb.fs.fcfg.Compression
+// recompressBlocks seals the last block and rewrites all blocks on disk with the
+// current compression, so that they all use the same format. Returns the number of
+// blocks that were checked. Blocks removed in the meantime are skipped.
+func (fs *fileStore) recompressBlocks() (int, error) {
+	fs.mu.Lock()
+	if fs.closed {
+		fs.mu.Unlock()
+		return 0, ErrStoreClosed
+	}
+	fs.checkAndFlushAllBlocks()
+	if lmb := fs.lmb; lmb != nil && lmb.msgs > 0 {
+		if _, err := fs.newMsgBlockForWrite(); err != nil {
+			fs.mu.Unlock()
+			return 0, err
+		}
+	}
+	var blks []*msgBlock
+	if len(fs.blks) > 1 {
+		blks = slices.Clone(fs.blks[:len(fs.blks)-1])
+	}
+	alg, level := fs.fcfg.Compression, fs.fcfg.CompressionLevel
+	fs.mu.Unlock()
+
+	for _, mb := range blks {
+		if err := mb.recompressOnDiskIfNeeded(alg, level); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+	}
+	return len(blks), nil
+}
+
+// Compresses the block on disk with the given algorithm (and level for zstd),
+// which are passed in since the stream's compression may have changed since.
+func (mb *msgBlock) recompressOnDiskIfNeeded(alg StoreCompression, level int) error {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
+	// Offloaded blocks are left as they were uploaded.
+	if mb.offloaded {
+		return nil
+	}
+
 	origFN := mb.mfn                    // The original message block on disk.
 	tmpFN := mb.mfn + compressTmpSuffix // The compressed block will be written here.
 
@@ -6225,7 +6379,7 @@ func (mb *msgBlock) recompressOnDiskIfNeeded() error {
 		// to ensure we don't do unnecessary work in case something asked us
 		// to recompress an already compressed block with the same algorithm.
 		return nil
-	} else if alg != NoCompression {
+	} else if meta.Algorithm != NoCompression {
 		// The block is already compressed using some algorithm, so we need
 		// to decompress the block using the existing algorithm before we can
 		// recompress it with the new one.
@@ -6255,7 +6409,7 @@ func (mb *msgBlock) recompressOnDiskIfNeeded() error {
 	// The original buffer at this point is uncompressed, so we will now compress
 	// it if needed. Note that if the selected algorithm is NoCompression, the
 	// Compress function will just return the input buffer unmodified.
-	cmpBuf, err := alg.Compress(origBuf)
+	cmpBuf, err := alg.CompressWithLevel(origBuf, level)
 	if err != nil {
 		return errorCleanup(fmt.Errorf("failed to compress block: %w", err))
 	}
@@ -6458,6 +6612,7 @@ func (fs *fileStore) syncBlocks() {
 	if markDirty {
 		fs.dirty++
 	}
+	tiered := fs.blobs != nil && fs.cfg.Tiering != nil
 
 	// Sync state file if we are not running with sync always.
 	if !fs.fcfg.SyncAlways {
@@ -6471,6 +6626,157 @@ func (fs *fileStore) syncBlocks() {
 		}
 	}
 	fs.mu.Unlock()
+
+	// Move any blocks that aged out to tiered storage.
+	if tiered {
+		fs.offloadBlocks()
+	}
+}
+
+// StreamCompactionPolicy enables background compaction by subject. The full history
+// is kept for messages inside the head window, older messages are removed once a newer
+// message for the same subject exists.
+type StreamCompactionPolicy struct {
+	// HeadWindow is how long the full history of every subject is kept.
+	HeadWindow time.Duration `json:"head_window"`
+	// MinCleanableRatio is the ratio of superseded messages to all messages outside of
+	// the head window that needs to be reached before they are removed.
+	MinCleanableRatio float64 `json:"min_cleanable_ratio,omitempty"`
+}
+
+// StreamCompactionInfo shows the progress of background compaction by subject.
+type StreamCompactionInfo struct {
+	Runs           uint64    `json:"runs"`
+	Msgs           uint64    `json:"msgs"`
+	Bytes          uint64    `json:"bytes"`
+	CleanableRatio float64   `json:"cleanable_ratio"`
+	LastRun        time.Time `json:"last_run,omitempty"`
+}
+
+// interval returns how often the stream is compacted by subject.
+func (p *StreamCompactionPolicy) interval() time.Duration {
+	return min(max(p.HeadWindow/2, time.Second), time.Minute)
+}
+
+// supersededMsgs returns the messages older than the head window that were superseded by a
+// newer message on the same subject, once they reach the min cleanable ratio.
+// Only subjects with more than one message are looked at, and the ratio is calculated from the
+// per-subject index, so messages are only looked up once there is something to remove.
+// The messages are not removed here, so the stream can remove them the same way on all replicas.
+func (fs *fileStore) supersededMsgs() []uint64 {
+	fs.mu.RLock()
+	if fs.closed || fs.cfg.Compaction == nil {
+		fs.mu.RUnlock()
+		return nil
+	}
+	cutoff := time.Now().Add(-fs.cfg.Compaction.HeadWindow)
+	minRatio := fs.cfg.Compaction.MinCleanableRatio
+	var subjs []string
+	fs.psim.IterFast(func(subj []byte, psi *psi) bool {
+		if psi.total > 1 {
+			subjs = append(subjs, string(subj))
+		}
+		return true
+	})
+	fs.mu.RUnlock()
+
+	// All messages before the head window, to calculate the cleanable ratio.
+	var ss StreamState
+	fs.FastState(&ss)
+	hseq := fs.GetSeqFromTime(cutoff.Add(time.Nanosecond))
+	pending, _ := fs.NumPending(hseq, fwcs, false)
+	total := ss.Msgs - min(pending, ss.Msgs)
+
+	// The messages of a subject before the head window are superseded, except for the last
+	// message of the subject if it is before the head window too.
+	bounds := make(map[string]uint64, len(subjs))
+	var n uint64
+	for _, subj := range subjs {
+		fss := fs.FilteredState(1, subj)
+		if fss.Msgs <= 1 {
+			continue
+		}
+		bound := min(hseq, fss.Last)
+		np, _ := fs.NumPending(bound, subj, false)
+		if before := fss.Msgs - min(np, fss.Msgs); before > 0 {
+			bounds[subj] = bound
+			n += before
+		}
+	}
+
+	var ratio float64
+	if total > 0 {
+		ratio = min(float64(n)/float64(total), 1)
+	}
+
+	fs.mu.Lock()
+	fs.cmpi.Runs++
+	fs.cmpi.CleanableRatio, fs.cmpi.LastRun = ratio, time.Now().UTC()
+	fs.mu.Unlock()
+	if n == 0 || ratio < minRatio {
+		return nil
+	}
+
+	var rm []uint64
+	for subj, bound := range bounds {
+		rm = append(rm, fs.subjectSeqsBefore(subj, bound)...)
+	}
+	slices.Sort(rm)
+	return rm
+}
+
+// subjectSeqsBefore returns the sequences of the messages on the literal subject before the given
+// sequence. The per-subject index of each block is used to only look up the range holding the subject.
+func (fs *fileStore) subjectSeqsBefore(subj string, bound uint64) []uint64 {
+	var seqs []uint64
+	var smv StoreMsg
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	for _, mb := range fs.blks {
+		if atomic.LoadUint64(&mb.first.seq) >= bound {
+			break
+		}
+		mb.mu.Lock()
+		// If we do not have our fss, expire the cache again once done.
+		shouldExpire := mb.fssNotLoaded()
+		if t, f, l := mb.filteredPendingLocked(subj, false, atomic.LoadUint64(&mb.first.seq)); t > 0 {
+			if mb.cacheNotLoaded() {
+				mb.loadMsgsWithLock()
+				shouldExpire = true
+			}
+			for seq := f; seq <= min(l, bound-1); seq++ {
+				if sm, _ := mb.cacheLookupNoCopy(seq, &smv); sm != nil && sm.subj == subj {
+					seqs = append(seqs, seq)
+				}
+			}
+		}
+		if shouldExpire {
+			mb.tryForceExpireCacheLocked()
+		}
+		mb.mu.Unlock()
+	}
+	return seqs
+}
+
+// removeSupersededMsg removes a message that compaction by subject found to be superseded, and
+// accounts for it in the progress of compaction. Called on every replica when the removal is applied.
+func (fs *fileStore) removeSupersededMsg(seq uint64) (bool, error) {
+	removed, msz, err := fs.removeMsgWithSize(seq, false, false, true)
+	if removed {
+		fs.mu.Lock()
+		fs.cmpi.Msgs++
+		fs.cmpi.Bytes += msz
+		fs.mu.Unlock()
+	}
+	return removed, err
+}
+
+// Returns the progress of background compaction by subject.
+func (fs *fileStore) compactionInfo() *StreamCompactionInfo {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	cmpi := fs.cmpi
+	return &cmpi
 }
 
 // Select the message block where this message should be found.
@@ -6893,6 +7199,10 @@ func (mb *msgBlock) openBlock() (*os.File, error) {
 // Used to load in the block contents.
 // Lock should be held and all conditionals satisfied prior.
 func (mb *msgBlock) loadBlock(buf []byte) ([]byte, error) {
+	if mb.offloaded {
+		return mb.loadOffloadedBlock(buf)
+	}
+
 	var f *os.File
 	// Re-use if we have mfd open.
 	if mb.mfd != nil {
@@ -8194,6 +8504,7 @@ func (fs *fileStore) purge(fseq uint64) (uint64, error) {
 	fs.state.Bytes = 0
 	fs.state.Msgs = 0
 
+	fs.removeOffloadedBlobs(fs.blks)
 	for _, mb := range fs.blks {
 		mb.dirtyClose()
 	}
@@ -8429,6 +8740,8 @@ func (fs *fileStore) compact(seq uint64) (uint64, error) {
 			if err != nil {
 				goto SKIP
 			}
+			// The block is local again.
+			smb.clearOffloaded()
 			// Make sure to remove fss state.
 			smb.fss = nil
 			smb.clearCacheAndOffset()
@@ -8790,6 +9103,7 @@ func (mb *msgBlock) dirtyCloseWithRemove(remove bool) error {
 	if remove {
 		// Clear any tracking by subject if we are removing.
 		mb.fss = nil
+		mb.clearOffloaded()
 		if mb.mfn != _EMPTY_ {
 			err := os.Remove(mb.mfn)
 			if isPermissionError(err) {
@@ -9153,6 +9467,7 @@ func (fs *fileStore) Delete() error {
 
 	// Quickly close all blocks and simulate a purge w/o overhead an new write block.
 	fs.mu.Lock()
+	fs.removeOffloadedBlobs(fs.blks)
 	for _, mb := range fs.blks {
 		mb.dirtyClose()
 	}
@@ -11094,7 +11409,48 @@ func (c *CompressionInfo) UnmarshalMetadata(b []byte) (int, error) {
 	return 4 + n, nil
 }
 
+// Encoders are safe for concurrent use with EncodeAll, so we keep one per
+// zstd compression level, and a single decoder since it is level agnostic.
+var (
+	zstdEncoders    sync.Map // map[zstd.EncoderLevel]*zstd.Encoder
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	zstdDecoderOnce sync.Once
+)
+
+func getZstdEncoder(level int) (*zstd.Encoder, error) {
+	el := zstd.SpeedDefault
+	if level != 0 {
+		el = zstd.EncoderLevelFromZstd(level)
+	}
+	if enc, ok := zstdEncoders.Load(el); ok {
+		return enc.(*zstd.Encoder), nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(el), zstd.WithEncoderCRC(false))
+	if err != nil {
+		return nil, err
+	}
+	if existing, loaded := zstdEncoders.LoadOrStore(el, enc); loaded {
+		enc.Close()
+		return existing.(*zstd.Encoder), nil
+	}
+	return enc, nil
+}
+
+func getZstdDecoder() (*zstd.Decoder, error) {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	return zstdDecoder, zstdDecoderErr
+}
+
 func (alg StoreCompression) Compress(buf []byte) ([]byte, error) {
+	return alg.CompressWithLevel(buf, 0)
+}
+
+// CompressWithLevel is like Compress, with the compression level to use for
+// algorithms that support it. A level of 0 selects the default level.
+func (alg StoreCompression) CompressWithLevel(buf []byte, level int) ([]byte, error) {
 	if len(buf) < checksumSize {
 		return nil, fmt.Errorf("uncompressed buffer is too short")
 	}
@@ -11106,6 +11462,15 @@ func (alg StoreCompression) Compress(buf []byte) ([]byte, error) {
 		return buf, nil
 	case S2Compression:
 		writer = s2.NewWriter(&output)
+	case ZstdCompression:
+		enc, err := getZstdEncoder(level)
+		if err != nil {
+			return nil, fmt.Errorf("error creating compression encoder: %w", err)
+		}
+		// The whole block is in memory, so compress it in one go, and
+		// preserve the checksum at the end of the block as-is.
+		out := enc.EncodeAll(buf[:bodyLen], make([]byte, 0, len(buf)/2))
+		return append(out, buf[bodyLen:]...), nil
 	default:
 		return nil, fmt.Errorf("compression algorithm not known")
 	}
@@ -11147,6 +11512,16 @@ func (alg StoreCompression) Decompress(buf []byte) ([]byte, error) {
 		return buf, nil
 	case S2Compression:
 		reader = io.NopCloser(s2.NewReader(input))
+	case ZstdCompression:
+		dec, err := getZstdDecoder()
+		if err != nil {
+			return nil, fmt.Errorf("error creating compression decoder: %w", err)
+		}
+		output, err := dec.DecodeAll(buf[:bodyLen], nil)
+		if err != nil {
+			return nil, fmt.Errorf("error reading compression reader: %w", err)
+		}
+		return append(output, buf[bodyLen:]...), nil
 	default:
 		return nil, fmt.Errorf("compression algorithm not known")
 	}
@@ -57,6 +57,8 @@ func testFileStoreAllPermutations(t *testing.T, fn func(t *testing.T, fcfg FileS
 		{Cipher: AES, Compression: S2Compression},
 		{Cipher: ChaCha, Compression: NoCompression},
 		{Cipher: ChaCha, Compression: S2Compression},
+		{Cipher: NoCipher, Compression: ZstdCompression},
+		{Cipher: AES, Compression: ZstdCompression},
 	} {
 		subtestName := fmt.Sprintf("%s-%s", fcfg.Cipher, fcfg.Compression)
 		t.Run(subtestName, func(t *testing.T) {
@@ -7783,6 +7785,354 @@ func TestFileStoreSyncCompressOnlyIfDirty(t *testing.T) {
 	require_False(t, noCompact)
 }
 
+func TestFileStoreMixedCompressionBlocks(t *testing.T) {
+	sd := t.TempDir()
+	fcfg := FileStoreConfig{StoreDir: sd, BlockSize: 1024, Compression: S2Compression}
+	cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo.*"}, Storage: FileStorage, Compression: S2Compression}
+	fs, err := newFileStore(fcfg, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+
+	msg := bytes.Repeat([]byte("ABCDEFGH"), 16)
+	store := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			_, _, err := fs.StoreMsg(fmt.Sprintf("foo.%d", i%4), nil, msg, 0)
+			require_NoError(t, err)
+		}
+	}
+	// Waits for all but the last block to be compressed and returns the
+	// algorithm of each block.
+	checkCompressed := func() []StoreCompression {
+		t.Helper()
+		var algs []StoreCompression
+		checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+			algs = algs[:0]
+			fs.mu.RLock()
+			defer fs.mu.RUnlock()
+			for i, mb := range fs.blks {
+				mb.mu.RLock()
+				cmp := mb.cmp
+				mb.mu.RUnlock()
+				if cmp == NoCompression && i < len(fs.blks)-1 {
+					return fmt.Errorf("block %d not compressed yet", mb.index)
+				}
+				algs = append(algs, cmp)
+			}
+			return nil
+		})
+		return algs
+	}
+
+	store(50)
+	algs := checkCompressed()
+	require_True(t, len(algs) > 2)
+	s2Blocks := len(algs) - 1
+
+	// Switch to zstd, new blocks are compressed with it, while the existing
+	// ones are left as they are.
+	cfg.Compression, cfg.CompressionLevel = ZstdCompression, 19
+	require_NoError(t, fs.UpdateConfig(&cfg))
+	store(50)
+	algs = checkCompressed()
+	for i := 0; i < s2Blocks; i++ {
+		require_Equal(t, algs[i], S2Compression)
+	}
+	require_Equal(t, algs[len(algs)-2], ZstdCompression)
+
+	checkMsgs := func() {
+		t.Helper()
+		var smv StoreMsg
+		for seq := uint64(1); seq <= 100; seq++ {
+			sm, err := fs.LoadMsg(seq, &smv)
+			require_NoError(t, err)
+			require_True(t, bytes.Equal(sm.msg, msg))
+		}
+		var n int
+		for seq := uint64(1); ; n++ {
+			sm, nseq, err := fs.LoadNextMsg("foo.1", false, seq, &smv)
+			if err == ErrStoreEOF {
+				break
+			}
+			require_NoError(t, err)
+			require_True(t, bytes.Equal(sm.msg, msg))
+			seq = nseq + 1
+		}
+		require_Equal(t, n, 26)
+	}
+	checkMsgs()
+
+	// Same after a restart, with the new compression configured.
+	fs.Stop()
+	fcfg.Compression, fcfg.CompressionLevel = ZstdCompression, 19
+	fs, err = newFileStore(fcfg, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+	checkMsgs()
+}
+
+func TestFileStoreZstdCompressionLevels(t *testing.T) {
+	buf := make([]byte, 0, 64*1024+checksumSize)
+	for i := 0; len(buf) < 64*1024; i++ {
+		buf = append(buf, fmt.Sprintf("msg-%d ", i%100)...)
+	}
+	buf = append(buf, bytes.Repeat([]byte{0xFF}, checksumSize)...)
+
+	for _, level := range []int{1, 0, 19} {
+		cbuf, err := ZstdCompression.CompressWithLevel(buf, level)
+		require_NoError(t, err)
+		require_True(t, len(cbuf) < len(buf))
+		// The checksum is preserved as-is.
+		require_True(t, bytes.Equal(cbuf[len(cbuf)-checksumSize:], buf[len(buf)-checksumSize:]))
+		dbuf, err := ZstdCompression.Decompress(cbuf)
+		require_NoError(t, err)
+		require_True(t, bytes.Equal(dbuf, buf))
+	}
+}
+
+func TestFileStoreTieredStorageOffload(t *testing.T) {
+	testFileStoreAllPermutations(t, func(t *testing.T, fcfg FileStoreConfig) {
+		blobs, err := NewDirBlobStore(t.TempDir())
+		require_NoError(t, err)
+		fcfg.BlockSize = 1024
+		fcfg.BlobStore = blobs
+		cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo.*"}, Storage: FileStorage,
+			Tiering: &StreamTieringPolicy{OffloadAfter: time.Millisecond}}
+		created := time.Now()
+		fs, err := newFileStoreWithCreated(fcfg, cfg, created, prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+
+		msg := bytes.Repeat([]byte("Z"), 100)
+		for i := 0; i < 100; i++ {
+			_, _, err := fs.StoreMsg(fmt.Sprintf("foo.%d", i%5), nil, msg, 0)
+			require_NoError(t, err)
+		}
+		fs.mu.RLock()
+		nblks, lmb := len(fs.blks), fs.lmb
+		fs.mu.RUnlock()
+		require_True(t, nblks > 2)
+
+		// Blocks that are being compressed in the background are skipped, so retry.
+		checkFor(t, 2*time.Second, 20*time.Millisecond, func() error {
+			fs.offloadBlocks()
+			if n := fs.tieringInfo().OffloadedBlocks; n != nblks-1 {
+				return fmt.Errorf("expected %d offloaded blocks, got %d", nblks-1, n)
+			}
+			return nil
+		})
+		ti := fs.tieringInfo()
+		require_Equal(t, ti.LocalBytes, lmb.bytes)
+		require_Equal(t, ti.OffloadedBytes+ti.LocalBytes, fs.State().Bytes)
+
+		// Only the last block is left locally, along with the markers.
+		mdir := filepath.Join(fcfg.StoreDir, msgDir)
+		blkFiles, err := filepath.Glob(filepath.Join(mdir, "*"+blkSuffix))
+		require_NoError(t, err)
+		require_Equal(t, len(blkFiles), 1)
+		markers, err := filepath.Glob(filepath.Join(mdir, "*"+tierSuffix))
+		require_NoError(t, err)
+		require_Equal(t, len(markers), nblks-1)
+
+		checkMsgs := func(fs *fileStore) {
+			t.Helper()
+			var smv StoreMsg
+			for seq := uint64(1); seq <= 100; seq++ {
+				sm, err := fs.LoadMsg(seq, &smv)
+				require_NoError(t, err)
+				require_Equal(t, sm.subj, fmt.Sprintf("foo.%d", (seq-1)%5))
+				require_True(t, bytes.Equal(sm.msg, msg))
+			}
+			var n int
+			for seq := uint64(1); ; n++ {
+				sm, nseq, err := fs.LoadNextMsg("foo.3", false, seq, &smv)
+				if err == ErrStoreEOF {
+					break
+				}
+				require_NoError(t, err)
+				require_Equal(t, sm.subj, "foo.3")
+				seq = nseq + 1
+			}
+			require_Equal(t, n, 20)
+		}
+		checkMsgs(fs)
+
+		// Offloaded blocks are still offloaded after a restart.
+		fs.Stop()
+		fs, err = newFileStoreWithCreated(fcfg, cfg, created, prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+		require_Equal(t, fs.tieringInfo().OffloadedBlocks, nblks-1)
+		checkMsgs(fs)
+
+		// Removing all messages of an offloaded block drops its blob.
+		fs.mu.RLock()
+		mb := fs.blks[0]
+		fs.mu.RUnlock()
+		key := mb.blobKey()
+		fseq, lseq := atomic.LoadUint64(&mb.first.seq), atomic.LoadUint64(&mb.last.seq)
+		for seq := fseq; seq <= lseq; seq++ {
+			_, err := fs.RemoveMsg(seq)
+			require_NoError(t, err)
+		}
+		_, err = blobs.Get(key)
+		require_Error(t, err, ErrBlobNotFound)
+
+		// Securely erasing a message brings the block back to local storage.
+		// Encrypted stores do not rewrite blocks on erase.
+		if fcfg.Cipher == NoCipher {
+			fs.mu.RLock()
+			mb = fs.blks[0]
+			fs.mu.RUnlock()
+			key = mb.blobKey()
+			_, err = fs.EraseMsg(atomic.LoadUint64(&mb.first.seq))
+			require_NoError(t, err)
+			mb.mu.RLock()
+			offloaded := mb.offloaded
+			mb.mu.RUnlock()
+			require_False(t, offloaded)
+			_, err = blobs.Get(key)
+			require_Error(t, err, ErrBlobNotFound)
+		}
+
+		// Purge removes everything.
+		_, err = fs.Purge()
+		require_NoError(t, err)
+		checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+			keys, err := filepath.Glob(filepath.Join(blobs.(*dirBlobStore).dir, "*", "zzz", "*", "*", "*"+blkSuffix))
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				return fmt.Errorf("still have %d blobs", len(keys))
+			}
+			return nil
+		})
+	})
+}
+
+func TestFileStoreTieredStorageRecoverWithoutIndex(t *testing.T) {
+	sd := t.TempDir()
+	blobs, err := NewDirBlobStore(t.TempDir())
+	require_NoError(t, err)
+	fcfg := FileStoreConfig{StoreDir: sd, BlockSize: 1024, BlobStore: blobs}
+	cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage,
+		Tiering: &StreamTieringPolicy{OffloadAfter: time.Millisecond}}
+	fs, err := newFileStore(fcfg, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+
+	msg := bytes.Repeat([]byte("Z"), 100)
+	for i := 0; i < 50; i++ {
+		_, _, err := fs.StoreMsg("foo", nil, msg, 0)
+		require_NoError(t, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	fs.offloadBlocks()
+	offloaded := fs.tieringInfo().OffloadedBlocks
+	require_True(t, offloaded > 0)
+	fs.Stop()
+
+	// Without the stream state the offloaded blocks are rebuilt in place.
+	require_NoError(t, os.Remove(filepath.Join(sd, msgDir, streamStreamStateFile)))
+	fs, err = newFileStore(fcfg, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+
+	state := fs.State()
+	require_Equal(t, state.Msgs, 50)
+	require_Equal(t, fs.tieringInfo().OffloadedBlocks, offloaded)
+	markers, err := filepath.Glob(filepath.Join(sd, msgDir, "*"+tierSuffix))
+	require_NoError(t, err)
+	require_Equal(t, len(markers), offloaded)
+	var smv StoreMsg
+	for seq := uint64(1); seq <= 50; seq++ {
+		_, err := fs.LoadMsg(seq, &smv)
+		require_NoError(t, err)
+	}
+}
+
+func TestFileStoreTieredStorageSharedBlobs(t *testing.T) {
+	sd := t.TempDir()
+	bdir := t.TempDir()
+	blobs, err := NewDirBlobStore(bdir)
+	require_NoError(t, err)
+	fcfg := FileStoreConfig{StoreDir: sd, BlockSize: 1024, BlobStore: blobs}
+	cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo"}, Storage: FileStorage,
+		Tiering: &StreamTieringPolicy{OffloadAfter: time.Millisecond}}
+	fs, err := newFileStore(fcfg, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+
+	msg := bytes.Repeat([]byte("Z"), 100)
+	for i := 0; i < 50; i++ {
+		_, _, err := fs.StoreMsg("foo", nil, msg, 0)
+		require_NoError(t, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	fs.offloadBlocks()
+	offloaded := fs.tieringInfo().OffloadedBlocks
+	require_True(t, offloaded > 0)
+	fs.Stop()
+
+	countBlobs := func() int {
+		t.Helper()
+		var n int
+		err := filepath.WalkDir(bdir, func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				n++
+			}
+			return err
+		})
+		require_NoError(t, err)
+		return n
+	}
+	require_Equal(t, countBlobs(), offloaded)
+
+	// A copy shares the blobs of the original, removing messages through it leaves them alone.
+	cdir := filepath.Join(t.TempDir(), filepath.Base(filepath.Dir(filepath.Dir(sd))), filepath.Base(filepath.Dir(sd)), filepath.Base(sd))
+	require_NoError(t, copyStoreDir(sd, cdir))
+	ccfg := fcfg
+	ccfg.StoreDir, ccfg.sharedBlobs = cdir, true
+	cfs, err := newFileStore(ccfg, cfg)
+	require_NoError(t, err)
+	defer cfs.Stop()
+	_, err = cfs.Purge()
+	require_NoError(t, err)
+	cfs.Stop()
+	// Blobs of purged blocks are removed in the background.
+	time.Sleep(100 * time.Millisecond)
+	require_Equal(t, countBlobs(), offloaded)
+
+	fs, err = newFileStore(fcfg, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+	var smv StoreMsg
+	for seq := uint64(1); seq <= 50; seq++ {
+		_, err := fs.LoadMsg(seq, &smv)
+		require_NoError(t, err)
+	}
+}
+
+func TestFileStoreBlobCacheEviction(t *testing.T) {
+	bc := newBlobCache(100)
+	bc.add("a", make([]byte, 40))
+	bc.add("b", make([]byte, 40))
+	_, ok := bc.get("a")
+	require_True(t, ok)
+	// Evicts b, the least recently used.
+	bc.add("c", make([]byte, 40))
+	_, ok = bc.get("b")
+	require_False(t, ok)
+	_, ok = bc.get("a")
+	require_True(t, ok)
+	// Too big to be cached at all.
+	bc.add("d", make([]byte, 200))
+	_, ok = bc.get("d")
+	require_False(t, ok)
+	require_Equal(t, bc.size, 80)
+}
+
 // This test is for deleted interior message tracking after compaction from limits based deletes, meaning no tombstones.
 // Bug was that dmap would not be properly be hydrated after the compact from rebuild. But we did so in populateGlobalInfo.
 // So this is just to fix a bug in rebuildState tracking gaps after a compact.
@@ -9765,3 +10115,114 @@ func TestFileStoreNoPanicOnRecoverTTLWithCorruptBlocks(t *testing.T) {
 		require_NoError(t, fs.recoverTTLState())
 	})
 }
+
+func TestFileStoreCompactionByKey(t *testing.T) {
+	testFileStoreAllPermutations(t, func(t *testing.T, fcfg FileStoreConfig) {
+		fcfg.BlockSize = 1024
+		cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo.*"}, Storage: FileStorage,
+			Compaction: &StreamCompactionPolicy{HeadWindow: time.Hour}}
+		created := time.Now()
+		fs, err := newFileStoreWithCreated(fcfg, cfg, created, prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+
+		// 100 messages outside of the head window, and 10 inside for foo.0 and foo.1.
+		msg := bytes.Repeat([]byte("Z"), 100)
+		old := time.Now().Add(-2 * time.Hour).UnixNano()
+		for i := 0; i < 100; i++ {
+			require_NoError(t, fs.StoreRawMsg(fmt.Sprintf("foo.%d", i%5), nil, msg, uint64(i+1), old+int64(i), 0))
+		}
+		for i := 100; i < 110; i++ {
+			require_NoError(t, fs.StoreRawMsg(fmt.Sprintf("foo.%d", i%2), nil, msg, uint64(i+1), time.Now().UnixNano(), 0))
+		}
+
+		rawBytes := func() (n uint64) {
+			fs.mu.RLock()
+			defer fs.mu.RUnlock()
+			for _, mb := range fs.blks {
+				mb.mu.RLock()
+				n += mb.rbytes
+				mb.mu.RUnlock()
+			}
+			return n
+		}
+		before := rawBytes()
+
+		// The superseded messages are removed by the stream, blocks are rewritten when syncing.
+		compact := func() {
+			for _, seq := range fs.supersededMsgs() {
+				_, err := fs.removeSupersededMsg(seq)
+				require_NoError(t, err)
+			}
+			fs.syncBlocks()
+		}
+		compact()
+		state := fs.State()
+		require_Equal(t, state.Msgs, 13)
+		require_Equal(t, state.LastSeq, 110)
+		require_True(t, rawBytes() < before)
+
+		ci := fs.compactionInfo()
+		require_Equal(t, ci.Runs, 1)
+		require_Equal(t, ci.Msgs, 97)
+		require_Equal(t, ci.Bytes, 97*fileStoreMsgSize("foo.0", nil, msg))
+		require_Equal(t, ci.CleanableRatio, 0.97)
+		require_False(t, ci.LastRun.IsZero())
+
+		// The last value outside of the head window is kept for subjects not updated since.
+		var smv StoreMsg
+		for i, seq := range []uint64{98, 99, 100} {
+			sm, err := fs.LoadLastMsg(fmt.Sprintf("foo.%d", i+2), &smv)
+			require_NoError(t, err)
+			require_Equal(t, sm.seq, seq)
+		}
+		// The full history is kept in the head window.
+		require_Equal(t, fs.FilteredState(1, "foo.0").Msgs, 5)
+		require_Equal(t, fs.FilteredState(1, "foo.1").Msgs, 5)
+
+		// Nothing left to clean.
+		compact()
+		ci = fs.compactionInfo()
+		require_Equal(t, ci.Runs, 2)
+		require_Equal(t, ci.Msgs, 97)
+		require_Equal(t, ci.CleanableRatio, 0)
+
+		// Still the same after a restart.
+		fs.Stop()
+		fs, err = newFileStoreWithCreated(fcfg, cfg, created, prf(&fcfg), nil)
+		require_NoError(t, err)
+		defer fs.Stop()
+		require_Equal(t, fs.State().Msgs, 13)
+	})
+}
+
+func TestFileStoreCompactionByKeyMinCleanableRatio(t *testing.T) {
+	sd := t.TempDir()
+	cfg := StreamConfig{Name: "zzz", Subjects: []string{"foo.*"}, Storage: FileStorage,
+		Compaction: &StreamCompactionPolicy{MinCleanableRatio: 0.5}}
+	fs, err := newFileStore(FileStoreConfig{StoreDir: sd}, cfg)
+	require_NoError(t, err)
+	defer fs.Stop()
+
+	// Only 1 out of 4 messages is superseded.
+	for _, subj := range []string{"foo.a", "foo.b", "foo.c", "foo.a"} {
+		_, _, err := fs.StoreMsg(subj, nil, nil, 0)
+		require_NoError(t, err)
+	}
+	require_Len(t, len(fs.supersededMsgs()), 0)
+	ci := fs.compactionInfo()
+	require_Equal(t, ci.Msgs, 0)
+	require_Equal(t, ci.CleanableRatio, 0.25)
+
+	// Updates are picked up.
+	cfg.Compaction.MinCleanableRatio = 0.2
+	require_NoError(t, fs.UpdateConfig(&cfg))
+	seqs := fs.supersededMsgs()
+	require_Len(t, len(seqs), 1)
+	require_Equal(t, seqs[0], 1)
+	// Only counted once removed.
+	require_Equal(t, fs.compactionInfo().Msgs, 0)
+	_, err = fs.removeSupersededMsg(seqs[0])
+	require_NoError(t, err)
+	require_Equal(t, fs.compactionInfo().Msgs, 1)
+}
@@ -0,0 +1,521 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BlobStore is a backend that sealed message blocks can be offloaded to.
+// Blocks are stored exactly as they are on disk, so still encrypted and
+// compressed if the stream is. Implementations must be safe for concurrent use.
+type BlobStore interface {
+	// Put stores data under the given key, replacing any existing blob.
+	Put(key string, data []byte) error
+	// Get returns the blob stored under key, or ErrBlobNotFound.
+	Get(key string) ([]byte, error)
+	// Delete removes the blob stored under key, it is not an error if it does not exist.
+	Delete(key string) error
+}
+
+// ErrBlobNotFound is returned by a BlobStore when a key does not exist.
+var ErrBlobNotFound = errors.New("blob not found")
+
+var errNoBlobStore = errors.New("message block offloaded but no tiered storage configured")
+
+const (
+	// Marker left in place of an offloaded block file.
+	tierScan = "%d.tier"
+	// Suffix of the tier marker files.
+	tierSuffix = ".tier"
+	// Default size of the cache for blocks fetched back from tiered storage.
+	defaultBlobCacheSize = 32 * 1024 * 1024 // 32MB
+)
+
+// dirBlobStore is a BlobStore keeping blobs as files in a local directory.
+type dirBlobStore struct {
+	dir string
+}
+
+// NewDirBlobStore returns a BlobStore that keeps blobs under the given directory.
+// This is mostly useful for testing, or when the directory is on a network mount.
+func NewDirBlobStore(dir string) (BlobStore, error) {
+	if dir == _EMPTY_ {
+		return nil, errors.New("tiered storage directory can not be empty")
+	}
+	dir = filepath.Clean(dir)
+	if err := os.MkdirAll(dir, defaultDirPerms); err != nil {
+		return nil, fmt.Errorf("could not create tiered storage directory: %w", err)
+	}
+	return &dirBlobStore{dir: dir}, nil
+}
+
+func (ds *dirBlobStore) path(key string) (string, error) {
+	fn := filepath.Join(ds.dir, filepath.FromSlash(path.Clean("/"+key)))
+	if !strings.HasPrefix(fn, ds.dir+string(os.PathSeparator)) {
+		return _EMPTY_, fmt.Errorf("invalid blob key %q", key)
+	}
+	return fn, nil
+}
+
+func (ds *dirBlobStore) Put(key string, data []byte) error {
+	fn, err := ds.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), defaultDirPerms); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that a partial blob is never visible.
+	tmp := fn + compressTmpSuffix
+	if err := writeFileWithSync(tmp, data, defaultFilePerms); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+func (ds *dirBlobStore) Get(key string) ([]byte, error) {
+	fn, err := ds.path(key)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return buf, err
+}
+
+func (ds *dirBlobStore) Delete(key string) error {
+	fn, err := ds.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// S3Client is the subset of an S3-compatible object store client needed
+// for tiered storage. It allows plugging in any SDK without the server
+// depending on it. GetObject should return ErrBlobNotFound for missing keys.
+type S3Client interface {
+	PutObject(bucket, key string, data []byte) error
+	GetObject(bucket, key string) ([]byte, error)
+	DeleteObject(bucket, key string) error
+}
+
+// s3BlobStore is a BlobStore on top of an S3-compatible object store.
+type s3BlobStore struct {
+	client S3Client
+	bucket string
+	prefix string
+}
+
+// NewS3BlobStore returns a BlobStore keeping blobs in the given bucket,
+// with all keys placed under prefix.
+func NewS3BlobStore(client S3Client, bucket, prefix string) BlobStore {
+	if prefix != _EMPTY_ && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &s3BlobStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (ss *s3BlobStore) Put(key string, data []byte) error {
+	return ss.client.PutObject(ss.bucket, ss.prefix+key, data)
+}
+
+func (ss *s3BlobStore) Get(key string) ([]byte, error) {
+	return ss.client.GetObject(ss.bucket, ss.prefix+key)
+}
+
+func (ss *s3BlobStore) Delete(key string) error {
+	return ss.client.DeleteObject(ss.bucket, ss.prefix+key)
+}
+
+// blobCache is a size bounded LRU cache of blocks fetched from tiered storage.
+type blobCache struct {
+	mu    sync.Mutex
+	max   int64
+	size  int64
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+type blobCacheEntry struct {
+	key string
+	buf []byte
+}
+
+func newBlobCache(max int64) *blobCache {
+	if max <= 0 {
+		max = defaultBlobCacheSize
+	}
+	return &blobCache{max: max, lru: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Returns the cached blob, callers must not modify it.
+func (bc *blobCache) get(key string) ([]byte, bool) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if e, ok := bc.items[key]; ok {
+		bc.lru.MoveToFront(e)
+		return e.Value.(*blobCacheEntry).buf, true
+	}
+	return nil, false
+}
+
+func (bc *blobCache) add(key string, buf []byte) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	// Do not let a single blob flush everything else out.
+	if int64(len(buf)) > bc.max {
+		return
+	}
+	if e, ok := bc.items[key]; ok {
+		bc.size -= int64(len(e.Value.(*blobCacheEntry).buf))
+		e.Value.(*blobCacheEntry).buf = buf
+		bc.lru.MoveToFront(e)
+	} else {
+		bc.items[key] = bc.lru.PushFront(&blobCacheEntry{key, buf})
+	}
+	bc.size += int64(len(buf))
+	for bc.size > bc.max {
+		e := bc.lru.Back()
+		ent := e.Value.(*blobCacheEntry)
+		bc.lru.Remove(e)
+		delete(bc.items, ent.key)
+		bc.size -= int64(len(ent.buf))
+	}
+}
+
+func (bc *blobCache) remove(key string) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	if e, ok := bc.items[key]; ok {
+		bc.lru.Remove(e)
+		delete(bc.items, key)
+		bc.size -= int64(len(e.Value.(*blobCacheEntry).buf))
+	}
+}
+
+// StreamTieringPolicy controls offloading of sealed message blocks to tiered storage.
+type StreamTieringPolicy struct {
+	// OffloadAfter is how old the newest message of a sealed block needs
+	// to be before the block is moved to tiered storage.
+	OffloadAfter time.Duration `json:"offload_after"`
+}
+
+// StreamTieringInfo shows how the stream data is split between local and tiered storage.
+type StreamTieringInfo struct {
+	LocalBytes      uint64 `json:"local_bytes"`
+	OffloadedBytes  uint64 `json:"offloaded_bytes"`
+	OffloadedBlocks int    `json:"offloaded_blocks"`
+}
+
+// Key for a message block in the blob store. Offloaded blocks keep the key they
+// were uploaded under, so copies of the store still find them.
+// Lock should be held.
+func (mb *msgBlock) blobKey() string {
+	if mb.bkey != _EMPTY_ {
+		return mb.bkey
+	}
+	return mb.fs.tierPrefix + "/" + fmt.Sprintf(blkScan, mb.index)
+}
+
+// Loads the key an offloaded block was uploaded under from its marker file.
+// Lock should be held.
+func (mb *msgBlock) loadBlobKey() {
+	if buf, err := os.ReadFile(mb.tierMarker()); err == nil && len(buf) > 0 {
+		mb.bkey = string(buf)
+	}
+}
+
+// Name of the marker file for an offloaded block.
+func (mb *msgBlock) tierMarker() string {
+	return filepath.Join(mb.fs.fcfg.StoreDir, msgDir, fmt.Sprintf(tierScan, mb.index))
+}
+
+// Loads the raw contents of an offloaded block, from the cache if possible.
+// Lock should be held.
+func (mb *msgBlock) loadOffloadedBlock(buf []byte) ([]byte, error) {
+	fs := mb.fs
+	if fs.blobs == nil {
+		return nil, errNoBlobStore
+	}
+	key := mb.blobKey()
+	data, ok := fs.bcache.get(key)
+	if !ok {
+		var err error
+		if data, err = fs.blobs.Get(key); err != nil {
+			if err == ErrBlobNotFound {
+				err = errNoBlkData
+			}
+			return nil, err
+		}
+		fs.bcache.add(key, data)
+	}
+	// Callers decrypt in place, so we always hand out a copy.
+	if buf == nil {
+		buf = getMsgBlockBuf(len(data))
+	}
+	buf = append(buf[:0], data...)
+	mb.rbytes = uint64(len(buf))
+	return buf, nil
+}
+
+// Moves this block to tiered storage if it has not seen any writes since
+// the cutoff. The upload happens without holding the lock, and is only
+// committed if the block did not change on disk in the meantime.
+func (mb *msgBlock) offload(cutoff int64) error {
+	fs := mb.fs
+	mb.mu.Lock()
+	if mb.closed || mb.offloaded || mb.msgs == 0 || mb.last.ts > cutoff || mb.pendingWriteSizeLocked() > 0 {
+		mb.mu.Unlock()
+		return nil
+	}
+	fi, err := os.Stat(mb.mfn)
+	if err != nil {
+		mb.mu.Unlock()
+		return err
+	}
+	buf, err := mb.loadBlock(nil)
+	if err != nil {
+		mb.mu.Unlock()
+		return err
+	}
+	data := append([]byte(nil), buf...)
+	recycleMsgBlockBuf(buf)
+	key := mb.blobKey()
+	mb.mu.Unlock()
+
+	if err := fs.blobs.Put(key, data); err != nil {
+		return err
+	}
+
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	// Make sure nothing changed while we were uploading.
+	if nfi, err := os.Stat(mb.mfn); mb.closed || mb.offloaded || err != nil ||
+		nfi.Size() != fi.Size() || !nfi.ModTime().Equal(fi.ModTime()) || mb.pendingWriteSizeLocked() > 0 {
+		if !mb.offloaded {
+			fs.deleteBlob(key)
+		}
+		return nil
+	}
+	// Leave the marker before removing the block, so we do not lose track of it on a crash.
+	<-dios
+	err = os.WriteFile(mb.tierMarker(), []byte(key), defaultFilePerms)
+	dios <- struct{}{}
+	if err != nil {
+		return err
+	}
+	mb.closeFDsLockedNoCheck()
+	<-dios
+	err = os.Remove(mb.mfn)
+	dios <- struct{}{}
+	if err != nil {
+		os.Remove(mb.tierMarker())
+		return err
+	}
+	mb.offloaded, mb.bkey = true, key
+	mb.rbytes = uint64(len(data))
+	return nil
+}
+
+// Brings an offloaded block back to local storage, needed before rewriting it.
+// Lock should be held.
+func (mb *msgBlock) rehydrate() error {
+	if !mb.offloaded {
+		return nil
+	}
+	buf, err := mb.loadOffloadedBlock(nil)
+	if err != nil {
+		return err
+	}
+	defer recycleMsgBlockBuf(buf)
+	<-dios
+	err = writeFileWithSync(mb.mfn, buf, defaultFilePerms)
+	dios <- struct{}{}
+	if err != nil {
+		return err
+	}
+	mb.clearOffloaded()
+	return nil
+}
+
+// Drops the tiered copy of this block, once the block is local again or removed.
+// Lock should be held.
+func (mb *msgBlock) clearOffloaded() {
+	if !mb.offloaded {
+		return
+	}
+	mb.offloaded = false
+	os.Remove(mb.tierMarker())
+	if mb.fs.blobs == nil {
+		return
+	}
+	if err := mb.fs.deleteBlob(mb.blobKey()); err != nil {
+		mb.fs.warn("Error removing offloaded message block %d: %v", mb.index, err)
+	}
+	mb.bkey = _EMPTY_
+}
+
+// Returns the indexes of the offloaded blocks, so they can be rebuilt from scratch
+// reading them in place from tiered storage.
+// Lock should be held.
+func (fs *fileStore) recoverOffloadedBlocks(mdir string, dirs []os.DirEntry) map[uint32]struct{} {
+	local := make(map[uint32]struct{})
+	var offloaded []uint32
+	for _, fi := range dirs {
+		var index uint32
+		if strings.HasSuffix(fi.Name(), blkSuffix) {
+			if n, err := fmt.Sscanf(fi.Name(), blkScan, &index); err == nil && n == 1 {
+				local[index] = struct{}{}
+			}
+		} else if strings.HasSuffix(fi.Name(), tierSuffix) {
+			if n, err := fmt.Sscanf(fi.Name(), tierScan, &index); err == nil && n == 1 {
+				offloaded = append(offloaded, index)
+			}
+		}
+	}
+	var rebuild map[uint32]struct{}
+	for _, index := range offloaded {
+		// We crashed before removing the local copy, so just drop the tiered one.
+		if _, ok := local[index]; ok {
+			mb := &msgBlock{fs: fs, index: index, mfn: filepath.Join(mdir, fmt.Sprintf(blkScan, index)), offloaded: true}
+			mb.loadBlobKey()
+			mb.clearOffloaded()
+			continue
+		}
+		if rebuild == nil {
+			rebuild = make(map[uint32]struct{})
+		}
+		rebuild[index] = struct{}{}
+	}
+	return rebuild
+}
+
+// Returns the indexes of the offloaded blocks that have no local copy.
+func offloadedBlockIndexes(mdir string) map[uint32]struct{} {
+	<-dios
+	f, err := os.Open(mdir)
+	if err != nil {
+		dios <- struct{}{}
+		return nil
+	}
+	dirs, _ := f.ReadDir(-1)
+	f.Close()
+	dios <- struct{}{}
+
+	var offloaded map[uint32]struct{}
+	for _, fi := range dirs {
+		var index uint32
+		if !strings.HasSuffix(fi.Name(), tierSuffix) {
+			continue
+		}
+		if n, err := fmt.Sscanf(fi.Name(), tierScan, &index); err != nil || n != 1 {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(mdir, fmt.Sprintf(blkScan, index))); err == nil {
+			continue
+		}
+		if offloaded == nil {
+			offloaded = make(map[uint32]struct{})
+		}
+		offloaded[index] = struct{}{}
+	}
+	return offloaded
+}
+
+// Moves sealed blocks that have aged past the tiering policy to the blob store.
+func (fs *fileStore) offloadBlocks() {
+	fs.mu.RLock()
+	if fs.closed || fs.sips > 0 || fs.blobs == nil || fs.cfg.Tiering == nil || fs.cfg.Tiering.OffloadAfter <= 0 {
+		fs.mu.RUnlock()
+		return
+	}
+	cutoff := time.Now().Add(-fs.cfg.Tiering.OffloadAfter).UnixNano()
+	blks, lmb := append([]*msgBlock(nil), fs.blks...), fs.lmb
+	fs.mu.RUnlock()
+
+	for _, mb := range blks {
+		if mb == lmb {
+			continue
+		}
+		if err := mb.offload(cutoff); err != nil {
+			fs.warn("Error offloading message block %d: %v", mb.index, err)
+		}
+	}
+}
+
+// Removes the tiered copies of the given blocks, used when they are dropped in bulk.
+func (fs *fileStore) removeOffloadedBlobs(blks []*msgBlock) {
+	if fs.blobs == nil || fs.fcfg.sharedBlobs {
+		return
+	}
+	var keys []string
+	for _, mb := range blks {
+		mb.mu.RLock()
+		if mb.offloaded {
+			keys = append(keys, mb.blobKey())
+		}
+		mb.mu.RUnlock()
+	}
+	if len(keys) == 0 {
+		return
+	}
+	go func() {
+		for _, key := range keys {
+			fs.deleteBlob(key)
+		}
+	}()
+}
+
+// Removes a blob, unless the store is a copy sharing its blobs with the original store.
+func (fs *fileStore) deleteBlob(key string) error {
+	fs.bcache.remove(key)
+	if fs.fcfg.sharedBlobs {
+		return nil
+	}
+	return fs.blobs.Delete(key)
+}
+
+// Returns how the stream's bytes are split between local and tiered storage.
+func (fs *fileStore) tieringInfo() *StreamTieringInfo {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	var ti StreamTieringInfo
+	for _, mb := range fs.blks {
+		mb.mu.RLock()
+		if mb.offloaded {
+			ti.OffloadedBytes += mb.bytes
+			ti.OffloadedBlocks++
+		} else {
+			ti.LocalBytes += mb.bytes
+		}
+		mb.mu.RUnlock()
+	}
+	return &ti
+}
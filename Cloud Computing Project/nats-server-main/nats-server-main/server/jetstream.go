@@ -117,6 +117,8 @@ type jetStream struct {
 	accounts      map[string]*jsAccount
 	apiSubs       *Sublist
 	started       time.Time
+	blobs         BlobStore  // Tiered storage backend, if any.
+	bcache        *blobCache // Cache for blocks fetched from tiered storage, shared by all streams.
 
 	// System level request to purge a stream move
 	accountPurge *subscription
@@ -157,6 +159,13 @@ type jsAccount struct {
 	templates map[string]*streamTemplate
 	store     TemplateStore
 
+	// Rules to auto-create streams, their client and messages held while streams are created.
+	autos    []*autoStream
+	ac       *client
+	acmu     sync.Mutex
+	acsid    uint64
+	apending map[string][]*autoStreamMsg
+
 	// From server
 	sendq *ipQueue[*pubMsg]
 
@@ -418,6 +427,16 @@ func (s *Server) enableJetStream(cfg JetStreamConfig) error {
 	// TODO: Not currently reloadable.
 	atomic.StoreInt64(&js.queueLimit, s.getOpts().JetStreamRequestQueueLimit)
 
+	// Setup tiered storage for offloading sealed message blocks, if configured.
+	blobs, err := tieredBlobStoreFromOptions(s.getOpts())
+	if err != nil {
+		return err
+	}
+	js.blobs = blobs
+	if blobs != nil {
+		js.bcache = newBlobCache(s.getOpts().JetStreamTieredCacheSize)
+	}
+
 	s.js.Store(js)
 
 	// FIXME(dlc) - Allow memory only operation?
@@ -1090,6 +1109,31 @@ func (s *Server) getJetStream() *jetStream {
 	return s.js.Load()
 }
 
+// Returns the tiered storage backend configured in the options, or nil if none.
+func tieredBlobStoreFromOptions(opts *Options) (BlobStore, error) {
+	if opts.JetStreamTieredStore != nil {
+		return opts.JetStreamTieredStore, nil
+	} else if opts.JetStreamTieredStoreDir != _EMPTY_ {
+		return NewDirBlobStore(opts.JetStreamTieredStoreDir)
+	}
+	return nil, nil
+}
+
+// Returns the tiered storage backend, or nil if not configured.
+func (s *Server) tieredBlobStore() BlobStore {
+	if js := s.getJetStream(); js != nil {
+		return js.blobs
+	}
+	return nil
+}
+
+func (s *Server) tieredBlobCache() *blobCache {
+	if js := s.getJetStream(); js != nil {
+		return js.bcache
+	}
+	return nil
+}
+
 func (a *Account) assignJetStreamLimits(limits map[string]JetStreamAccountLimits) {
 	a.mu.Lock()
 	a.jsLimits = limits
@@ -1492,6 +1536,11 @@ func (a *Account) EnableJetStream(limits map[string]JetStreamAccountLimits) erro
 
 	s.Debugf("JetStream state for account %q recovered", a.Name)
 
+	// Now that streams are recovered, setup any rules to auto-create streams.
+	if err := jsa.enableAutoStreams(); err != nil {
+		s.Warnf("  Error enabling auto-create stream rules for account %q: %v", a.Name, err)
+	}
+
 	return nil
 }
 
@@ -2364,8 +2413,14 @@ func (jsa *jsAccount) delete() {
 		ts = append(ts, t.Name)
 	}
 	jsa.templates = nil
+	ac := jsa.ac
+	jsa.ac, jsa.autos, jsa.apending = nil, nil, nil
 	jsa.mu.Unlock()
 
+	if ac != nil {
+		ac.closeConnection(ClientClosed)
+	}
+
 	for _, mset := range streams {
 		mset.stop(false, false)
 	}
@@ -109,6 +109,11 @@ const (
 	JSApiStreamRestore  = "$JS.API.STREAM.RESTORE.*"
 	JSApiStreamRestoreT = "$JS.API.STREAM.RESTORE.%s"
 
+	// JSApiStreamClone is the endpoint to create a new stream seeded from an existing stream.
+	// Will return JSON response.
+	JSApiStreamClone  = "$JS.API.STREAM.CLONE.*"
+	JSApiStreamCloneT = "$JS.API.STREAM.CLONE.%s"
+
 	// JSApiMsgDelete is the endpoint to delete messages from a stream.
 	// Will return JSON response.
 	JSApiMsgDelete  = "$JS.API.STREAM.MSG.DELETE.*"
@@ -190,6 +195,16 @@ const (
 	JSApiStreamRemovePeer  = "$JS.API.STREAM.PEER.REMOVE.*"
 	JSApiStreamRemovePeerT = "$JS.API.STREAM.PEER.REMOVE.%s"
 
+	// JSApiStreamPromotePeer is the endpoint to promote a learner of a clustered stream to a voter.
+	// Will return JSON response.
+	JSApiStreamPromotePeer  = "$JS.API.STREAM.PEER.PROMOTE.*"
+	JSApiStreamPromotePeerT = "$JS.API.STREAM.PEER.PROMOTE.%s"
+
+	// JSApiStreamFailover is the endpoint to switch a stream of a replication pair between the mirror and primary role.
+	// Will return JSON response.
+	JSApiStreamFailover  = "$JS.API.STREAM.FAILOVER.*"
+	JSApiStreamFailoverT = "$JS.API.STREAM.FAILOVER.%s"
+
 	// JSApiStreamLeaderStepDown is the endpoint to have stream leader stepdown.
 	// Will return JSON response.
 	JSApiStreamLeaderStepDown  = "$JS.API.STREAM.LEADER.STEPDOWN.*"
@@ -269,6 +284,18 @@ const (
 	// JSAdvisoryStreamUpdatedPre notification that a stream was updated.
 	JSAdvisoryStreamUpdatedPre = "$JS.EVENT.ADVISORY.STREAM.UPDATED"
 
+	// JSAdvisoryStreamAutoCreatedPre notification that a stream was created by an account auto-create rule.
+	JSAdvisoryStreamAutoCreatedPre = "$JS.EVENT.ADVISORY.STREAM.AUTO_CREATED"
+
+	// JSAdvisoryStreamReplicationLagExceededPre notification that a mirror exceeded its lag threshold.
+	JSAdvisoryStreamReplicationLagExceededPre = "$JS.EVENT.ADVISORY.STREAM.REPLICATION_LAG_EXCEEDED"
+
+	// JSAdvisoryStreamReplicationLagRecoveredPre notification that a mirror caught up with its origin.
+	JSAdvisoryStreamReplicationLagRecoveredPre = "$JS.EVENT.ADVISORY.STREAM.REPLICATION_LAG_RECOVERED"
+
+	// JSAdvisoryStreamRoleChangedPre notification that a stream switched between the primary and mirror role.
+	JSAdvisoryStreamRoleChangedPre = "$JS.EVENT.ADVISORY.STREAM.ROLE_CHANGED"
+
 	// JSAdvisoryConsumerCreatedPre notification that a consumer was created.
 	JSAdvisoryConsumerCreatedPre = "$JS.EVENT.ADVISORY.CONSUMER.CREATED"
 
@@ -284,6 +311,12 @@ const (
 	// JSAdvisoryConsumerUnpinnedPre notification that a consumer was unpinned.
 	JSAdvisoryConsumerUnpinnedPre = "$JS.EVENT.ADVISORY.CONSUMER.UNPINNED"
 
+	// JSAdvisoryConsumerLagExceededPre notification that a consumer exceeded its lag thresholds.
+	JSAdvisoryConsumerLagExceededPre = "$JS.EVENT.ADVISORY.CONSUMER.LAG_EXCEEDED"
+
+	// JSAdvisoryConsumerLagRecoveredPre notification that a consumer is back within its lag thresholds.
+	JSAdvisoryConsumerLagRecoveredPre = "$JS.EVENT.ADVISORY.CONSUMER.LAG_RECOVERED"
+
 	// JSAdvisoryStreamSnapshotCreatePre notification that a snapshot was created.
 	JSAdvisoryStreamSnapshotCreatePre = "$JS.EVENT.ADVISORY.STREAM.SNAPSHOT_CREATE"
 
@@ -305,6 +338,9 @@ const (
 	// JSAdvisoryStreamQuorumLostPre notification that a stream and its consumers are stalled.
 	JSAdvisoryStreamQuorumLostPre = "$JS.EVENT.ADVISORY.STREAM.QUORUM_LOST"
 
+	// JSAdvisoryStreamSchemaValidationFailedPre notification that a message was rejected by the stream schema.
+	JSAdvisoryStreamSchemaValidationFailedPre = "$JS.EVENT.ADVISORY.STREAM.SCHEMA_VALIDATION_FAILED"
+
 	// JSAdvisoryConsumerLeaderElectedPre notification that a replicated consumer has elected a leader.
 	JSAdvisoryConsumerLeaderElectedPre = "$JS.EVENT.ADVISORY.CONSUMER.LEADER_ELECTED"
 
@@ -584,6 +620,28 @@ type JSApiStreamRestoreResponse struct {
 
 const JSApiStreamRestoreResponseType = "io.nats.jetstream.api.v1.stream_restore_response"
 
+// JSApiStreamCloneRequest is the required clone request.
+// The messages are stored again in the new stream, its message blocks are not copied or
+// linked from the source, as they are checksummed and encrypted per stream.
+type JSApiStreamCloneRequest struct {
+	// Configuration of the new stream.
+	Config StreamConfig `json:"config"`
+	// Last sequence of the source stream to include, all messages if not set.
+	UpToSeq uint64 `json:"up_to_seq,omitempty"`
+	// Only include messages stored before this time.
+	UpToTime *time.Time `json:"up_to_time,omitempty"`
+	// Clone the durable consumers and their ack state as well.
+	Consumers bool `json:"consumers,omitempty"`
+}
+
+// JSApiStreamCloneResponse is the response to the clone request.
+type JSApiStreamCloneResponse struct {
+	ApiResponse
+	*StreamInfo
+}
+
+const JSApiStreamCloneResponseType = "io.nats.jetstream.api.v1.stream_clone_response"
+
 // JSApiStreamRemovePeerRequest is the required remove peer request.
 type JSApiStreamRemovePeerRequest struct {
 	// Server name of the peer to be removed.
@@ -598,6 +656,52 @@ type JSApiStreamRemovePeerResponse struct {
 
 const JSApiStreamRemovePeerResponseType = "io.nats.jetstream.api.v1.stream_remove_peer_response"
 
+// JSApiStreamPromotePeerRequest is the required promote peer request.
+type JSApiStreamPromotePeerRequest struct {
+	// Server name of the learner to be promoted.
+	Peer string `json:"peer"`
+}
+
+// JSApiStreamPromotePeerResponse is the response to a promote peer request.
+type JSApiStreamPromotePeerResponse struct {
+	ApiResponse
+	Success bool `json:"success,omitempty"`
+}
+
+const JSApiStreamPromotePeerResponseType = "io.nats.jetstream.api.v1.stream_promote_peer_response"
+
+// JSApiStreamFailoverRequest switches a stream of a replication pair between the mirror and primary role.
+// Without a mirror the stream is promoted from mirror to primary. If its origin is in the same JetStream
+// cluster it is demoted to a mirror of the promoted stream, which reverses the direction of the pair.
+// Otherwise, e.g. for an origin in another domain, the origin is demoted by a request with the mirror set.
+type JSApiStreamFailoverRequest struct {
+	// Subjects the stream will capture once promoted, defaults to the subjects of the origin
+	// when it is demoted along with the promotion, or the stream name otherwise.
+	Subjects []string `json:"subjects,omitempty"`
+	// Force the promotion even if the mirror is not replicating or has not replicated all messages of its origin.
+	Force bool `json:"force,omitempty"`
+	// Mirror is the new primary this stream will mirror once demoted.
+	Mirror *StreamSource `json:"mirror,omitempty"`
+	// Seq is the last sequence the new primary replicated before it was promoted.
+	// Messages after it would conflict with those written to the new primary.
+	Seq uint64 `json:"seq,omitempty"`
+}
+
+// JSApiStreamFailoverResponse is the response to a failover request.
+type JSApiStreamFailoverResponse struct {
+	ApiResponse
+	// Role of the stream after the failover.
+	Role string `json:"role,omitempty"`
+	// Mirror of the stream before it was promoted, or after it was demoted.
+	Mirror *StreamSource `json:"mirror,omitempty"`
+	// LastSeq is the last sequence of the stream at the time of the failover.
+	LastSeq uint64 `json:"last_seq"`
+	// Gap is the number of messages that were not replicated between the pair.
+	Gap uint64 `json:"gap,omitempty"`
+}
+
+const JSApiStreamFailoverResponseType = "io.nats.jetstream.api.v1.stream_failover_response"
+
 // JSApiStreamLeaderStepDownResponse is the response to a leader stepdown request.
 type JSApiStreamLeaderStepDownResponse struct {
 	ApiResponse
@@ -688,6 +792,14 @@ type JSApiMsgGetRequest struct {
 	UpToTime *time.Time `json:"up_to_time,omitempty"`
 	// Only return the message payload, excluding headers if present.
 	NoHeaders bool `json:"no_hdr,omitempty"`
+	// Only return messages matching this filter, requires next by subject or a batch.
+	// Skipped messages do not count toward the batch, and the returned num pending
+	// becomes an upper bound. A batch stops early after skipping too many messages,
+	// its last sequence is then the last one skipped.
+	Filter *MsgFilter `json:"filter,omitempty"`
+	// Only return messages once the stream has applied all writes committed before the request,
+	// as confirmed by the stream leader. Otherwise replicas may return stale messages.
+	Linearizable bool `json:"linearizable,omitempty"`
 }
 
 type JSApiMsgGetResponse struct {
@@ -885,6 +997,24 @@ func (js *jetStream) apiDispatch(sub *subscription, c *client, acc *Account, sub
 	// If we are here we have received this request over a non-client connection.
 	// We need to make sure not to block. We will send the request to a long-lived
 	// pool of go routines.
+	js.queueRoutedRequest(jsub, sub, c, acc, subject, reply, rmsg)
+}
+
+// queueAPIRequest passes an API request on to the pool of go routines processing API requests.
+func (s *Server) queueAPIRequest(sub *subscription, c *client, acc *Account, subject, reply string, rmsg []byte) {
+	js := s.getJetStream()
+	if js == nil {
+		return
+	}
+	if rr := js.apiSubs.Match(subject); len(rr.psubs) == 1 {
+		js.queueRoutedRequest(rr.psubs[0], sub, c, acc, subject, reply, rmsg)
+	}
+}
+
+// queueRoutedRequest queues an API request for the pool of go routines processing
+// API requests, for requests that should not be processed in place.
+func (js *jetStream) queueRoutedRequest(jsub *subscription, sub *subscription, c *client, acc *Account, subject, reply string, rmsg []byte) {
+	s := js.srv
 
 	// Increment inflight. Do this before queueing.
 	atomic.AddInt64(&js.apiInflight, 1)
@@ -991,7 +1121,10 @@ func (s *Server) setJetStreamExportSubs() error {
 		{JSApiStreamPurge, s.jsStreamPurgeRequest},
 		{JSApiStreamSnapshot, s.jsStreamSnapshotRequest},
 		{JSApiStreamRestore, s.jsStreamRestoreRequest},
+		{JSApiStreamClone, s.jsStreamCloneRequest},
 		{JSApiStreamRemovePeer, s.jsStreamRemovePeerRequest},
+		{JSApiStreamPromotePeer, s.jsStreamPromotePeerRequest},
+		{JSApiStreamFailover, s.jsStreamFailoverRequest},
 		{JSApiStreamLeaderStepDown, s.jsStreamLeaderStepDownRequest},
 		{JSApiConsumerLeaderStepDown, s.jsConsumerLeaderStepDownRequest},
 		{JSApiMsgDelete, s.jsMsgDeleteRequest},
@@ -2132,6 +2265,9 @@ func (s *Server) jsStreamInfoRequest(sub *subscription, c *client, a *Account, s
 		Mirror:     mset.mirrorInfo(),
 		Sources:    mset.sourcesInfo(),
 		Alternates: js.streamAlternates(ci, config.Name),
+		Tiering:    mset.tieringInfo(),
+		Compaction: mset.compactionInfo(),
+		Schema:     mset.schemaInfo(),
 		TimeStamp:  time.Now().UTC(),
 	}
 	if clusterWideConsCount > 0 {
@@ -2184,6 +2320,16 @@ func (s *Server) jsStreamInfoRequest(sub *subscription, c *client, a *Account, s
 		mset.checkClusterInfo(resp.StreamInfo.Cluster)
 	}
 
+	// Partitioned streams report the state of their partitions, which we need to ask for.
+	// This blocks, so is only done by the pool of go routines processing API requests.
+	if config.Partitioning.isParent() {
+		if c.kind != JETSTREAM {
+			s.queueAPIRequest(sub, c, a, subject, reply, rmsg)
+			return
+		}
+		s.addPartitionsInfo(acc.Name, resp.StreamInfo)
+	}
+
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
@@ -2297,6 +2443,93 @@ func (s *Server) jsStreamLeaderStepDownRequest(sub *subscription, c *client, _ *
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
+// Request to switch a stream of a replication pair between the mirror and primary role.
+func (s *Server) jsStreamFailoverRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, _, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	name := tokenAt(subject, 5)
+
+	var resp = JSApiStreamFailoverResponse{ApiResponse: ApiResponse{Type: JSApiStreamFailoverResponseType}}
+
+	// If we are not in clustered mode this is a failed request.
+	if !s.JetStreamIsClustered() {
+		resp.Error = NewJSClusterRequiredError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// If we are here we are clustered. See if we are the stream leader in order to proceed.
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+	if js.isLeaderless() {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js.mu.RLock()
+	isLeader, sa := cc.isLeader(), js.streamAssignment(acc.Name, name)
+	js.mu.RUnlock()
+
+	if isLeader && sa == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	} else if sa == nil {
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	// Check to see if we are a member of the group and if the group has no leader.
+	if js.isGroupLeaderless(sa.Group) {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// We have the stream assigned and a leader, so only the stream leader should answer.
+	if !acc.JetStreamIsStreamLeader(name) {
+		return
+	}
+
+	mset, err := acc.lookupStream(name)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiStreamFailoverRequest
+	if isJSONObjectOrArray(msg) {
+		if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+			resp.Error = NewJSInvalidJSONError(err)
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+	}
+
+	// We respond once the stream assignment has been applied.
+	if resp.Error = js.failoverStream(mset, ci, subject, reply, &req, &resp); resp.Error != nil {
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+	}
+}
+
 // Request to have a consumer leader stepdown.
 func (s *Server) jsConsumerLeaderStepDownRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
@@ -2517,6 +2750,108 @@ func (s *Server) jsStreamRemovePeerRequest(sub *subscription, c *client, _ *Acco
 	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
 }
 
+// Request to promote a learner of a clustered stream to a voter.
+func (s *Server) jsStreamPromotePeerRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamEnabled() {
+		return
+	}
+	ci, acc, _, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	// Have extra token for this one.
+	name := tokenAt(subject, 6)
+
+	var resp = JSApiStreamPromotePeerResponse{ApiResponse: ApiResponse{Type: JSApiStreamPromotePeerResponseType}}
+
+	// If we are not in clustered mode this is a failed request.
+	if !s.JetStreamIsClustered() {
+		resp.Error = NewJSClusterRequiredError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+	if js.isLeaderless() {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	js.mu.RLock()
+	isLeader, sa := cc.isLeader(), js.streamAssignment(acc.Name, name)
+	js.mu.RUnlock()
+
+	// Make sure we are meta leader.
+	if !isLeader {
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiStreamPromotePeerRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Peer == _EMPTY_ {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if sa == nil {
+		// No stream present.
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	// Peers here is a server name, convert to node name.
+	nodeName := getHash(req.Peer)
+
+	js.mu.RLock()
+	isLearner, numPeers := sa.Group.isLearner(nodeName), len(sa.Group.Peers)
+	js.mu.RUnlock()
+
+	if !isLearner {
+		resp.Error = NewJSClusterPeerNotLearnerError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if numPeers >= StreamMaxReplicas {
+		resp.Error = NewJSStreamInvalidConfigError(fmt.Errorf("maximum replicas is %d", StreamMaxReplicas))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if !js.promoteLearnerInStream(sa, nodeName) {
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	resp.Success = true
+	s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(resp))
+}
+
 // Request to have the metaleader remove a peer from the system.
 func (s *Server) jsLeaderServerRemoveRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
@@ -3450,8 +3785,32 @@ func (s *Server) jsMsgGetRequest(sub *subscription, c *client, _ *Account, subje
 		return
 	}
 
+	// As the leader we still need to confirm no newer leader has committed writes.
+	// That takes a heartbeat round, so respond once it completes instead of blocking.
+	if req.Linearizable {
+		if n := mset.raftNode(); n != nil {
+			request := string(msg)
+			n.ReadIndexAsync(linearizableReadTimeout, func(_ uint64, err error) {
+				if err != nil {
+					resp.Error = NewJSClusterNotAvailError()
+					s.sendAPIErrResponse(ci, acc, subject, reply, request, s.jsonResponse(&resp))
+					return
+				}
+				s.sendMsgGetResponse(ci, acc, mset, subject, reply, request, &req)
+			})
+			return
+		}
+	}
+
+	s.sendMsgGetResponse(ci, acc, mset, subject, reply, string(msg), &req)
+}
+
+// Loads the message for a message get request and sends the response.
+func (s *Server) sendMsgGetResponse(ci *ClientInfo, acc *Account, mset *stream, subject, reply, request string, req *JSApiMsgGetRequest) {
+	var resp = JSApiMsgGetResponse{ApiResponse: ApiResponse{Type: JSApiMsgGetResponseType}}
 	var svp StoreMsg
 	var sm *StoreMsg
+	var err error
 
 	// If AsOfTime is set, perform this first to get the sequence.
 	var seq uint64
@@ -3470,7 +3829,7 @@ func (s *Server) jsMsgGetRequest(sub *subscription, c *client, _ *Account, subje
 	}
 	if err != nil {
 		resp.Error = NewJSNoMessageFoundError()
-		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		s.sendAPIErrResponse(ci, acc, subject, reply, request, s.jsonResponse(&resp))
 		return
 	}
 	resp.Message = &StoredMsg{
@@ -4025,6 +4384,106 @@ func (s *Server) processStreamRestore(ci *ClientInfo, acc *Account, cfg *StreamC
 	return doneCh
 }
 
+// Request to clone a stream into a new stream.
+func (s *Server) jsStreamCloneRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	if c == nil || !s.JetStreamIsLeader() {
+		return
+	}
+	ci, acc, _, msg, err := s.getRequestInfo(c, rmsg)
+	if err != nil {
+		s.Warnf(badAPIRequestT, msg)
+		return
+	}
+
+	var resp = JSApiStreamCloneResponse{ApiResponse: ApiResponse{Type: JSApiStreamCloneResponseType}}
+	if !acc.JetStreamEnabled() {
+		resp.Error = NewJSNotEnabledForAccountError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if isEmptyRequest(msg) {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	var req JSApiStreamCloneRequest
+	if err := s.unmarshalRequest(c, acc, subject, msg, &req); err != nil {
+		resp.Error = NewJSInvalidJSONError(err)
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.UpToSeq > 0 && req.UpToTime != nil {
+		resp.Error = NewJSBadRequestError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	if req.Config.Mirror != nil {
+		resp.Error = NewJSStreamInvalidConfigError(errors.New("stream clone can not be a mirror"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+	// Partitioned streams are created through their parent, which holds no messages itself.
+	if req.Config.Partitioning != nil {
+		resp.Error = NewJSStreamInvalidConfigError(errors.New("stream clone can not be partitioned"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	stream := streamNameFromSubject(subject)
+	cfg, apiErr := s.checkStreamCfg(&req.Config, acc, false)
+	if apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if s.JetStreamIsClustered() {
+		s.jsClusteredStreamCloneRequest(ci, acc, stream, &req, &cfg, subject, reply, rmsg)
+		return
+	}
+
+	src, err := acc.lookupStream(stream)
+	if err != nil {
+		resp.Error = NewJSStreamNotFoundError(Unless(err))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if err := acc.jsNonClusteredStreamLimitsCheck(&cfg); err != nil {
+		resp.Error = err
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if _, err := acc.lookupStream(cfg.Name); err == nil {
+		resp.Error = NewJSStreamNameExistError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		return
+	}
+
+	if hasJS, doErr := acc.checkJetStream(); !hasJS {
+		if doErr {
+			resp.Error = NewJSNotEnabledForAccountError()
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+		}
+		return
+	}
+
+	// Copying could take a while so do this in a separate Go routine.
+	sc := &streamClone{Stream: stream, UpToSeq: req.UpToSeq, UpToTime: req.UpToTime, Consumers: req.Consumers}
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		mset, err := acc.cloneStream(src, &cfg, sc)
+		if err != nil {
+			resp.Error = NewJSStreamCloneError(err, Unless(err))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
+			return
+		}
+		s.sendAPIResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(s.streamCloneResponse(mset, nil)))
+	})
+}
+
 // Process a snapshot request.
 func (s *Server) jsStreamSnapshotRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
@@ -4687,7 +5146,7 @@ func (s *Server) jsConsumerListRequest(sub *subscription, c *client, _ *Account,
 }
 
 // Request for information about an consumer.
-func (s *Server) jsConsumerInfoRequest(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+func (s *Server) jsConsumerInfoRequest(sub *subscription, c *client, a *Account, subject, reply string, rmsg []byte) {
 	if c == nil || !s.JetStreamEnabled() {
 		return
 	}
@@ -4860,7 +5319,18 @@ func (s *Server) jsConsumerInfoRequest(sub *subscription, c *client, _ *Account,
 		return
 	}
 
-	if resp.ConsumerInfo = setDynamicConsumerInfoMetadata(obs.info()); resp.ConsumerInfo == nil {
+	// Consumers on partitioned streams report the state of their partitions, which we need to ask for.
+	// This blocks, so is only done by the pool of go routines processing API requests.
+	info := obs.info
+	if obs.part.isParent() {
+		if c.kind != JETSTREAM {
+			s.queueAPIRequest(sub, c, a, subject, reply, rmsg)
+			return
+		}
+		info = obs.partitionedInfo
+	}
+
+	if resp.ConsumerInfo = setDynamicConsumerInfoMetadata(info()); resp.ConsumerInfo == nil {
 		// This consumer returned nil which means it's closed. Respond with not found.
 		resp.Error = NewJSConsumerNotFoundError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(msg), s.jsonResponse(&resp))
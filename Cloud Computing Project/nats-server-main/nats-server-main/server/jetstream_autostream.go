@@ -0,0 +1,323 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nuid"
+)
+
+// StreamAutoCreateRule creates a stream from a template config when a message is published to
+// a subject matching the rule and no stream captures that subject yet. This replaces stream templates.
+type StreamAutoCreateRule struct {
+	// Subject is the pattern published subjects need to match, e.g. "tenant.*.>".
+	Subject string `json:"subject"`
+	// Name is a subject transform destination for the stream name using the wildcards of Subject,
+	// e.g. "TENANT.{{wildcard(1)}}". Tokens of the result are joined with underscores.
+	Name string `json:"name"`
+	// Config is the template for created streams. The name and subjects are set from the rule,
+	// the subjects being Subject with all wildcards referenced by Name replaced by the published tokens.
+	Config StreamConfig `json:"config"`
+}
+
+// Prefix of the reply subjects for stream create requests.
+const jsAutoStreamReplyPre = "$JS.AUTO."
+
+var (
+	// How long we wait for the stream to be created.
+	autoStreamCreateTimeout = 10 * time.Second
+	// Maximum number of messages held for a stream while it is being created.
+	autoStreamMaxPending = 1024
+)
+
+// autoStream is a compiled auto-create rule.
+type autoStream struct {
+	*StreamAutoCreateRule
+	tr     *subjectTransform
+	stoks  []string // Tokens of the rule subject.
+	ntoks  int      // Number of tokens the name is derived from, so excluding a full wildcard.
+	pinned []int    // Token positions of the wildcards referenced by the name.
+}
+
+// A message held while its stream is being created.
+type autoStreamMsg struct {
+	subj  string
+	reply string
+	hdr   []byte
+	msg   []byte
+}
+
+// compile validates the rule and prepares it for matching.
+func (r *StreamAutoCreateRule) compile() (*autoStream, error) {
+	if !IsValidSubject(r.Subject) {
+		return nil, fmt.Errorf("invalid auto-create subject %q", r.Subject)
+	}
+	if r.Name == _EMPTY_ {
+		return nil, errors.New("auto-create stream name is required")
+	}
+	if r.Config.Name != _EMPTY_ || len(r.Config.Subjects) > 0 {
+		return nil, errors.New("auto-create stream config can not set a name or subjects")
+	}
+	stoks := strings.Split(r.Subject, tsep)
+	ntoks := len(stoks)
+	if stoks[ntoks-1] == fwcs {
+		ntoks--
+	}
+	if ntoks == 0 {
+		return nil, errors.New("auto-create subject requires a token before the full wildcard")
+	}
+	tr, err := NewSubjectTransform(strings.Join(stoks[:ntoks], tsep), r.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto-create stream name %q: %w", r.Name, err)
+	}
+	as := &autoStream{StreamAutoCreateRule: r, tr: tr, stoks: stoks, ntoks: ntoks}
+	for _, idxs := range tr.dtokmftokindexesargs {
+		for _, i := range idxs {
+			if i >= 0 && !slices.Contains(as.pinned, i) {
+				as.pinned = append(as.pinned, i)
+			}
+		}
+	}
+	return as, nil
+}
+
+// Returns the name of the stream for a subject matching the rule.
+func (as *autoStream) streamName(tokens []string) string {
+	return strings.ReplaceAll(as.tr.TransformTokenizedSubject(tokens[:as.ntoks]), tsep, "_")
+}
+
+// Returns the subject of the stream for a subject matching the rule.
+func (as *autoStream) streamSubject(tokens []string) string {
+	stoks := slices.Clone(as.stoks)
+	for _, i := range as.pinned {
+		stoks[i] = tokens[i]
+	}
+	return strings.Join(stoks, tsep)
+}
+
+// enableAutoStreams sets up the subscriptions for the auto-create rules of the account.
+func (jsa *jsAccount) enableAutoStreams() error {
+	acc := jsa.account
+	acc.mu.RLock()
+	rules, s := acc.jsAutoStrs, acc.srv
+	acc.mu.RUnlock()
+	if len(rules) == 0 || s == nil {
+		return nil
+	}
+
+	c := s.createInternalAccountClient()
+	c.registerWithAccount(acc)
+	autos := make([]*autoStream, 0, len(rules))
+	for i, r := range rules {
+		as, err := r.compile()
+		if err == nil {
+			_, err = c.processSub([]byte(r.Subject), nil, []byte(strconv.Itoa(i+1)), jsa.processAutoStreamMsg(as), false)
+		}
+		if err != nil {
+			c.closeConnection(ClientClosed)
+			return err
+		}
+		autos = append(autos, as)
+	}
+
+	jsa.mu.Lock()
+	jsa.ac, jsa.autos, jsa.acsid = c, autos, uint64(len(rules))
+	jsa.mu.Unlock()
+	return nil
+}
+
+// Returns the handler for messages matching an auto-create rule. If no stream exists for
+// the message one is created, and the message is held until that is done.
+func (jsa *jsAccount) processAutoStreamMsg(as *autoStream) msgHandler {
+	return func(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+		var _tsa [32]string
+		tokens := tokenizeSubjectIntoSlice(_tsa[:0], subject)
+		name := as.streamName(tokens)
+		if !jsa.needsAutoStream(name, subject) {
+			return
+		}
+
+		hdr, msg := c.msgParts(rmsg)
+		pm := &autoStreamMsg{subject, reply, copyBytes(hdr), copyBytes(msg)}
+		s := jsa.js.srv
+
+		jsa.mu.Lock()
+		if jsa.ac == nil {
+			jsa.mu.Unlock()
+			return
+		}
+		if pending, ok := jsa.apending[name]; ok {
+			if len(pending) < autoStreamMaxPending {
+				jsa.apending[name] = append(pending, pm)
+			} else {
+				s.RateLimitWarnf("JetStream dropped message for account %q on subject %q, too many pending for auto-created stream %q",
+					jsa.account.Name, subject, name)
+			}
+			jsa.mu.Unlock()
+			return
+		}
+		if jsa.apending == nil {
+			jsa.apending = make(map[string][]*autoStreamMsg)
+		}
+		jsa.apending[name] = []*autoStreamMsg{pm}
+		jsa.mu.Unlock()
+
+		cfg := as.Config.clone()
+		cfg.Name, cfg.Subjects = name, []string{as.streamSubject(tokens)}
+		s.startGoRoutine(func() {
+			defer s.grWG.Done()
+			jsa.createAutoStream(as, cfg, subject)
+		})
+	}
+}
+
+// Returns true if there is no stream with the given name and none captures the subject.
+func (jsa *jsAccount) needsAutoStream(name, subject string) bool {
+	js, acc := jsa.js, jsa.account
+	js.mu.RLock()
+	if cc := js.cluster; cc != nil {
+		needs := js.streamAssignment(acc.Name, name) == nil && !cc.subjectsOverlap(acc.Name, []string{subject}, nil, _EMPTY_)
+		js.mu.RUnlock()
+		return needs
+	}
+	js.mu.RUnlock()
+
+	jsa.mu.RLock()
+	defer jsa.mu.RUnlock()
+	_, ok := jsa.streams[name]
+	return !ok && !jsa.subjectsOverlap([]string{subject}, nil, _EMPTY_)
+}
+
+// createAutoStream creates the stream through the JetStream API, so in clustered mode this is
+// handled by the meta leader and account limits are checked as for any stream. Once created,
+// the messages held for it are published again.
+func (jsa *jsAccount) createAutoStream(as *autoStream, cfg *StreamConfig, subject string) {
+	s, acc := jsa.js.srv, jsa.account
+	resp, err := jsa.autoStreamRequest(cfg)
+	if err == nil && resp.Error != nil {
+		err = resp.Error
+	}
+
+	jsa.mu.Lock()
+	pending := jsa.apending[cfg.Name]
+	delete(jsa.apending, cfg.Name)
+	jsa.mu.Unlock()
+
+	if err != nil {
+		s.RateLimitWarnf("JetStream could not auto-create stream %q for account %q on subject %q: %v", cfg.Name, acc.Name, subject, err)
+		return
+	}
+	if resp.DidCreate {
+		s.Noticef("Auto-created stream '%s > %s' for subject %q", acc.Name, cfg.Name, subject)
+		adv := &JSStreamAutoCreatedAdvisory{
+			TypedEvent: TypedEvent{
+				Type: JSStreamAutoCreatedAdvisoryType,
+				ID:   nuid.Next(),
+				Time: time.Now().UTC(),
+			},
+			Stream:  cfg.Name,
+			Rule:    as.Subject,
+			Subject: subject,
+			Domain:  s.getOpts().JetStreamDomain,
+		}
+		s.publishAdvisory(acc, JSAdvisoryStreamAutoCreatedPre+"."+cfg.Name, adv)
+	}
+	for _, pm := range pending {
+		jsa.publishAutoStreamMsg(pm.subj, pm.reply, pm.hdr, pm.msg)
+	}
+}
+
+// Sends the stream create request on behalf of the account and waits for the response.
+func (jsa *jsAccount) autoStreamRequest(cfg *StreamConfig) (*JSApiStreamCreateResponse, error) {
+	s := jsa.js.srv
+	req, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	jsa.mu.Lock()
+	c := jsa.ac
+	jsa.acsid++
+	sid := strconv.FormatUint(jsa.acsid, 10)
+	jsa.mu.Unlock()
+	if c == nil {
+		return nil, NewJSNotEnabledForAccountError()
+	}
+
+	rch := make(chan []byte, 1)
+	reply := fmt.Sprintf("%s%s", jsAutoStreamReplyPre, nuid.Next())
+	sub, err := c.processSub([]byte(reply), nil, []byte(sid), func(_ *subscription, c *client, _ *Account, _, _ string, rmsg []byte) {
+		_, msg := c.msgParts(rmsg)
+		select {
+		case rch <- copyBytes(msg):
+		default:
+		}
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	defer c.processUnsub(sub.sid)
+
+	jsa.publishAutoStreamMsg(fmt.Sprintf(JSApiStreamCreateT, cfg.Name), reply, nil, req)
+
+	timeout := time.NewTimer(autoStreamCreateTimeout)
+	defer timeout.Stop()
+	select {
+	case msg := <-rch:
+		var resp JSApiStreamCreateResponse
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	case <-timeout.C:
+		return nil, errors.New("timeout waiting for stream to be created")
+	case <-s.quitCh:
+		return nil, ErrServerNotRunning
+	}
+}
+
+// Publishes a message into the account from the auto-create client.
+func (jsa *jsAccount) publishAutoStreamMsg(subject, reply string, hdr, msg []byte) {
+	jsa.mu.RLock()
+	c := jsa.ac
+	jsa.mu.RUnlock()
+	if c == nil {
+		return
+	}
+
+	jsa.acmu.Lock()
+	defer jsa.acmu.Unlock()
+
+	c.pa.subject, c.pa.reply = []byte(subject), []byte(reply)
+	c.pa.size = len(hdr) + len(msg)
+	c.pa.szb = []byte(strconv.Itoa(c.pa.size))
+	if len(hdr) > 0 {
+		c.pa.hdr = len(hdr)
+		c.pa.hdb = []byte(strconv.Itoa(c.pa.hdr))
+	} else {
+		c.pa.hdr, c.pa.hdb = -1, nil
+	}
+	buf := make([]byte, 0, c.pa.size+len(_CRLF_))
+	buf = append(append(append(buf, hdr...), msg...), _CRLF_...)
+	c.processInboundClientMsg(buf)
+	c.pa.szb, c.pa.subject, c.pa.reply = nil, nil, nil
+	c.flushClients(0)
+}
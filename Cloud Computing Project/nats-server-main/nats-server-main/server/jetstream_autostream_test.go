@@ -0,0 +1,199 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestJetStreamAutoStreamRuleCompile(t *testing.T) {
+	for _, test := range []struct {
+		rule    StreamAutoCreateRule
+		subject string
+		name    string
+		ssubj   string
+		err     bool
+	}{
+		{StreamAutoCreateRule{Subject: "tenant.*.>", Name: "TENANT.{{wildcard(1)}}"}, "tenant.acme.orders.new", "TENANT_acme", "tenant.acme.>", false},
+		{StreamAutoCreateRule{Subject: "iot.*.*", Name: "IOT.{{wildcard(2)}}"}, "iot.eu.dev1", "IOT_dev1", "iot.*.dev1", false},
+		{StreamAutoCreateRule{Subject: "orders", Name: "ORDERS"}, "orders", "ORDERS", "orders", false},
+		{StreamAutoCreateRule{Subject: ">", Name: "ALL"}, _EMPTY_, _EMPTY_, _EMPTY_, true},
+		{StreamAutoCreateRule{Subject: "foo.*", Name: _EMPTY_}, _EMPTY_, _EMPTY_, _EMPTY_, true},
+		{StreamAutoCreateRule{Subject: "foo.*", Name: "FOO.{{wildcard(2)}}"}, _EMPTY_, _EMPTY_, _EMPTY_, true},
+		{StreamAutoCreateRule{Subject: "foo..bar", Name: "FOO"}, _EMPTY_, _EMPTY_, _EMPTY_, true},
+		{StreamAutoCreateRule{Subject: "foo.*", Name: "FOO", Config: StreamConfig{Subjects: []string{"bar"}}}, _EMPTY_, _EMPTY_, _EMPTY_, true},
+	} {
+		t.Run(test.rule.Subject, func(t *testing.T) {
+			as, err := test.rule.compile()
+			if test.err {
+				require_Error(t, err)
+				return
+			}
+			require_NoError(t, err)
+			tokens := strings.Split(test.subject, tsep)
+			require_Equal(t, as.streamName(tokens), test.name)
+			require_Equal(t, as.streamSubject(tokens), test.ssubj)
+		})
+	}
+}
+
+func TestJetStreamAutoStreamConfigParse(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		accounts {
+			A {
+				jetstream: {
+					auto_streams: [
+						{subject: "tenant.*.>", name: "TENANT.{{wildcard(1)}}", config: {storage: memory, max_age: "1h", max_msgs: 100}}
+					]
+				}
+			}
+		}
+	`))
+	opts, err := ProcessConfigFile(conf)
+	require_NoError(t, err)
+	var acc *Account
+	for _, a := range opts.Accounts {
+		if a.Name == "A" {
+			acc = a
+		}
+	}
+	require_NotNil(t, acc)
+	require_Len(t, len(acc.jsAutoStrs), 1)
+	r := acc.jsAutoStrs[0]
+	require_Equal(t, r.Subject, "tenant.*.>")
+	require_Equal(t, r.Name, "TENANT.{{wildcard(1)}}")
+	require_Equal(t, r.Config.Storage, MemoryStorage)
+	require_Equal(t, r.Config.MaxAge, time.Hour)
+	require_Equal(t, r.Config.MaxMsgs, 100)
+
+	conf = createConfFile(t, []byte(`
+		accounts {
+			A {
+				jetstream: {
+					auto_streams: [{subject: "foo.*", name: "FOO.{{wildcard(2)}}"}]
+				}
+			}
+		}
+	`))
+	_, err = ProcessConfigFile(conf)
+	require_Error(t, err)
+}
+
+func TestJetStreamAutoStreamCreate(t *testing.T) {
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		jetstream: {store_dir: %q}
+		accounts {
+			A {
+				users: [{user: a, password: pwd}]
+				jetstream: {
+					max_streams: 2
+					auto_streams: [
+						{subject: "tenant.*.>", name: "TENANT.{{wildcard(1)}}", config: {storage: file, max_msgs: 10}}
+					]
+				}
+			}
+		}
+	`, t.TempDir())))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s, nats.UserInfo("a", "pwd"))
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync(JSAdvisoryStreamAutoCreatedPre + ".>")
+	require_NoError(t, err)
+	require_NoError(t, nc.Flush())
+
+	// The message that triggers the creation is stored.
+	pa, err := js.Publish("tenant.acme.orders", []byte("hello"))
+	require_NoError(t, err)
+	require_Equal(t, pa.Stream, "TENANT_acme")
+	require_Equal(t, pa.Sequence, 1)
+
+	si, err := js.StreamInfo("TENANT_acme")
+	require_NoError(t, err)
+	require_Equal(t, si.Config.Subjects[0], "tenant.acme.>")
+	require_Equal(t, si.Config.MaxMsgs, 10)
+	require_Equal(t, si.Config.Storage, nats.FileStorage)
+
+	msg, err := sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_Equal(t, msg.Subject, JSAdvisoryStreamAutoCreatedPre+".TENANT_acme")
+	var adv JSStreamAutoCreatedAdvisory
+	require_NoError(t, json.Unmarshal(msg.Data, &adv))
+	require_Equal(t, adv.Type, JSStreamAutoCreatedAdvisoryType)
+	require_Equal(t, adv.Stream, "TENANT_acme")
+	require_Equal(t, adv.Rule, "tenant.*.>")
+	require_Equal(t, adv.Subject, "tenant.acme.orders")
+
+	// Subsequent messages go to the existing stream.
+	pa, err = js.Publish("tenant.acme.invoices", nil)
+	require_NoError(t, err)
+	require_Equal(t, pa.Stream, "TENANT_acme")
+	require_Equal(t, pa.Sequence, 2)
+	_, err = sub.NextMsg(250 * time.Millisecond)
+	require_Error(t, err, nats.ErrTimeout)
+
+	pa, err = js.Publish("tenant.globex.orders", nil)
+	require_NoError(t, err)
+	require_Equal(t, pa.Stream, "TENANT_globex")
+
+	// Account limits are respected.
+	_, err = js.Publish("tenant.initech.orders", nil, nats.AckWait(500*time.Millisecond))
+	require_Error(t, err, nats.ErrNoStreamResponse, nats.ErrTimeout)
+	_, err = js.StreamInfo("TENANT_initech")
+	require_Error(t, err, nats.ErrStreamNotFound)
+
+	// Subjects not matching any rule don't create streams.
+	_, err = js.Publish("other", nil, nats.AckWait(250*time.Millisecond))
+	require_Error(t, err, nats.ErrNoStreamResponse, nats.ErrTimeout)
+	var names []string
+	for name := range js.StreamNames() {
+		names = append(names, name)
+	}
+	require_Len(t, len(names), 2)
+}
+
+func TestJetStreamClusterAutoStreamCreate(t *testing.T) {
+	tmpl := strings.Replace(jsClusterAccountsTempl, "jetstream: enabled", `jetstream: {
+		auto_streams: [{subject: "tenant.*.>", name: "TENANT.{{wildcard(1)}}", config: {storage: file, num_replicas: 3}}]
+	}`, 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	for _, s := range c.servers {
+		nc, js := jsClientConnect(t, s, nats.UserInfo("one", "p"))
+		tenant := strings.ToLower(s.Name())
+		name := "TENANT_" + tenant
+		pa, err := js.Publish(fmt.Sprintf("tenant.%s.orders", tenant), nil)
+		require_NoError(t, err)
+		require_Equal(t, pa.Stream, name)
+		require_Equal(t, pa.Sequence, 1)
+
+		si, err := js.StreamInfo(name)
+		require_NoError(t, err)
+		require_Equal(t, si.Config.Replicas, 3)
+		require_NotNil(t, si.Cluster)
+		nc.Close()
+	}
+}
@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,11 +40,18 @@ type batchGroup struct {
 // mset.clMu lock must be held.
 func checkMsgHeadersPreClusteredProposal(
 	mset *stream, subject string, hdr []byte, msg []byte, sourced bool, name string,
-	jsa *jsAccount, allowTTL bool, allowMsgCounter bool, stype StorageType, store StreamStore,
+	jsa *jsAccount, allowTTL bool, allowMsgCounter bool, allowMsgSchedules bool, schema *streamSchema, stype StorageType, store StreamStore,
 	interestPolicy bool, discard DiscardPolicy, maxMsgs int64, maxBytes int64,
 ) ([]byte, []byte, uint64, *ApiError, error) {
 	var incr *big.Int
 
+	// Validate the payload against the schema, if any.
+	if schema != nil && !sourced {
+		if err := schema.validate(msg); err != nil {
+			return hdr, msg, 0, mset.schemaRejected(schema, name, subject, err), err
+		}
+	}
+
 	// Some header checks must be checked pre proposal.
 	if len(hdr) > 0 {
 		// Since we encode header len as u16 make sure we do not exceed.
@@ -97,6 +106,14 @@ func checkMsgHeadersPreClusteredProposal(
 				return hdr, msg, 0, NewJSMessageTTLInvalidError(), err
 			}
 		}
+		// Same for scheduled messages.
+		if due, err := getMessageSchedule(hdr, time.Now().UnixNano()); !sourced && (due != 0 || err != nil) {
+			if !allowMsgSchedules {
+				return hdr, msg, 0, NewJSMessageSchedulesDisabledError(), errMsgSchedulesDisabled
+			} else if err != nil {
+				return hdr, msg, 0, NewJSMessageScheduleInvalidError(), err
+			}
+		}
 		// Check for MsgIds here at the cluster level to avoid excessive CLFS accounting.
 		// Will help during restarts.
 		if msgId := getMsgId(hdr); msgId != _EMPTY_ {
@@ -310,3 +327,303 @@ func checkMsgHeadersPreClusteredProposal(
 
 	return hdr, msg, 0, nil, nil
 }
+
+// File the committed batch consumer acks not yet applied are persisted in.
+const batchAcksFile = "batch_acks.inf"
+
+// Interval at which the stream leader resends the committed batch consumer acks not yet applied.
+const batchAckRetryInterval = 2 * time.Second
+
+// batchAck is a consumer ack carried by the commit of an atomic batch, in the
+// form of the ack reply subject of the consumed message. The ack is only applied
+// to the consumer once the batch is committed, and the batch is only committed if
+// the message is still pending an ack. This allows a message to be consumed from
+// one stream and the results published to another exactly once.
+type batchAck struct {
+	subject  string
+	stream   string
+	consumer string
+	sseq     uint64
+}
+
+// parseBatchAck parses the ack reply subject carried in the Nats-Batch-Ack header.
+func parseBatchAck(subject string) (*batchAck, error) {
+	tokens := strings.Split(subject, tsep)
+	if len(tokens) != expectedNumReplyTokens || tokens[0] != "$JS" || tokens[1] != "ACK" || !subjectIsLiteral(subject) {
+		return nil, fmt.Errorf("%q is not an ack reply subject", subject)
+	}
+	sseq := parseAckReplyNum(tokens[5])
+	if sseq <= 0 {
+		return nil, fmt.Errorf("%q has an invalid stream sequence", subject)
+	}
+	return &batchAck{subject: subject, stream: tokens[2], consumer: tokens[3], sseq: uint64(sseq)}, nil
+}
+
+// Identifies the consumer the ack is for.
+func (ba *batchAck) consumerKey() string {
+	return ba.stream + " > " + ba.consumer
+}
+
+// Identifies the ack itself, regardless of the delivery it came from.
+func (ba *batchAck) key() string {
+	return ba.consumerKey() + " > " + strconv.FormatUint(ba.sseq, 10)
+}
+
+// lookupBatchAckConsumer returns the consumer an ack is for, which must be in the same
+// account and hosted on this server.
+func lookupBatchAckConsumer(acc *Account, ack *batchAck) (*consumer, error) {
+	amset, err := acc.lookupStream(ack.stream)
+	if err != nil {
+		return nil, fmt.Errorf("stream %q not found", ack.stream)
+	}
+	o := amset.lookupConsumer(ack.consumer)
+	if o == nil {
+		return nil, fmt.Errorf("consumer %q not found", ack.consumer)
+	}
+	return o, nil
+}
+
+// checkBatchAckPreClusteredProposal checks the consumer ack carried by the commit of an
+// atomic batch. The consumer must be hosted on this server so that we can check the
+// message is still pending an ack.
+// mset.clMu lock must NOT be held.
+func checkBatchAckPreClusteredProposal(mset *stream, jsa *jsAccount, subject string) (*batchAck, *ApiError) {
+	ack, err := parseBatchAck(subject)
+	if err != nil {
+		return nil, NewJSAtomicPublishInvalidBatchAckError(err)
+	}
+	o, err := lookupBatchAckConsumer(jsa.acc(), ack)
+	if err != nil {
+		return nil, NewJSAtomicPublishInvalidBatchAckError(err)
+	}
+	// Forget about committed acks the consumer has applied since.
+	mset.pruneBatchAcks(ack, o)
+	_, pending, current := o.ackState(ack.sseq)
+	if !current {
+		return nil, NewJSAtomicPublishInvalidBatchAckError(fmt.Errorf("consumer %q is not current", ack.consumer))
+	}
+	if !pending {
+		return nil, NewJSAtomicPublishInvalidBatchAckError(fmt.Errorf("message %d is not pending an ack", ack.sseq))
+	}
+	return ack, nil
+}
+
+// Stages the consumer ack of a batch about to be proposed, at the given clseq.
+// An ack that is already in process or committed, but not yet applied by the
+// consumer, is a duplicate.
+// mset.clMu lock must be held.
+func (mset *stream) stageBatchAck(ack *batchAck, clseq uint64) *ApiError {
+	key := ack.key()
+	if _, found := mset.batchAckInProcess[key]; found {
+		return NewJSAtomicPublishDuplicateError()
+	}
+	if _, found := mset.batchAcks[ack.consumerKey()][ack.sseq]; found {
+		// The ack might have been lost, send it again.
+		mset.outq.sendMsg(ack.subject, AckAck)
+		return NewJSAtomicPublishDuplicateError()
+	}
+	if mset.batchAckSequence == nil {
+		mset.batchAckSequence = make(map[uint64]string)
+	}
+	if mset.batchAckInProcess == nil {
+		mset.batchAckInProcess = make(map[string]struct{})
+	}
+	mset.batchAckSequence[clseq] = key
+	mset.batchAckInProcess[key] = struct{}{}
+	return nil
+}
+
+// Removes the consumer ack of a batch that failed to be proposed.
+// mset.clMu lock must be held.
+func (mset *stream) unstageBatchAck(clseq uint64) {
+	if key, found := mset.batchAckSequence[clseq]; found {
+		delete(mset.batchAckSequence, clseq)
+		delete(mset.batchAckInProcess, key)
+	}
+}
+
+// applyBatchAck is called when the commit of an atomic batch carrying a consumer ack
+// has been stored. The ack is tracked until the consumer has applied it, so it can't
+// be committed again, and the leader sends it to the consumer. Since every replica
+// tracks the ack, and file based streams persist it, the ack survives a leader change
+// or restart that happens before the consumer applied it.
+func (mset *stream) applyBatchAck(subject string, isLeader bool) {
+	ack, err := parseBatchAck(subject)
+	if err != nil {
+		return
+	}
+	// Could be replaying an ack that was applied already.
+	if o, err := lookupBatchAckConsumer(mset.account(), ack); err == nil {
+		mset.pruneBatchAcks(ack, o)
+		if delivered, pending, current := o.ackState(ack.sseq); current && delivered && !pending {
+			return
+		}
+	}
+
+	mset.clMu.Lock()
+	if mset.batchAcks == nil {
+		mset.batchAcks = make(map[string]map[uint64]string)
+	}
+	ck := ack.consumerKey()
+	if mset.batchAcks[ck] == nil {
+		mset.batchAcks[ck] = make(map[uint64]string)
+	}
+	mset.batchAcks[ck][ack.sseq] = ack.subject
+	mset.writeBatchAcksLocked()
+	mset.clMu.Unlock()
+
+	if isLeader {
+		mset.outq.sendMsg(ack.subject, AckAck)
+	}
+}
+
+// Removes committed acks for the consumer that it has applied.
+// mset.clMu lock must NOT be held.
+func (mset *stream) pruneBatchAcks(ack *batchAck, o *consumer) {
+	ck := ack.consumerKey()
+	mset.clMu.Lock()
+	acks := mset.batchAcks[ck]
+	if len(acks) == 0 {
+		mset.clMu.Unlock()
+		return
+	}
+	seqs := make([]uint64, 0, len(acks))
+	for sseq := range acks {
+		seqs = append(seqs, sseq)
+	}
+	mset.clMu.Unlock()
+
+	var applied []uint64
+	for _, sseq := range seqs {
+		if delivered, pending, current := o.ackState(sseq); current && delivered && !pending {
+			applied = append(applied, sseq)
+		}
+	}
+	if len(applied) == 0 {
+		return
+	}
+
+	mset.clMu.Lock()
+	for _, sseq := range applied {
+		delete(mset.batchAcks[ck], sseq)
+	}
+	if len(mset.batchAcks[ck]) == 0 {
+		delete(mset.batchAcks, ck)
+	}
+	mset.writeBatchAcksLocked()
+	mset.clMu.Unlock()
+}
+
+// resendBatchAcks is called on the stream leader to send the committed acks the
+// consumers have not applied yet. The ack sent when the batch was committed is
+// lost if the consumer had no leader, or if this stream changed leader before it
+// could be sent. Acks for consumers that no longer exist are dropped.
+// mset.clMu lock must NOT be held.
+func (mset *stream) resendBatchAcks() {
+	mset.clMu.Lock()
+	if len(mset.batchAcks) == 0 {
+		mset.clMu.Unlock()
+		return
+	}
+	// One ack per consumer to look it up with.
+	cacks := make(map[string]*batchAck, len(mset.batchAcks))
+	for ck, acks := range mset.batchAcks {
+		for _, subject := range acks {
+			if ack, err := parseBatchAck(subject); err == nil {
+				cacks[ck] = ack
+			}
+			break
+		}
+	}
+	mset.clMu.Unlock()
+
+	acc, js := mset.account(), mset.js
+	for ck, ack := range cacks {
+		if o, err := lookupBatchAckConsumer(acc, ack); err == nil {
+			mset.pruneBatchAcks(ack, o)
+		} else if js != nil && js.isClustered() {
+			js.mu.RLock()
+			ca := js.consumerAssignment(acc.Name, ack.stream, ack.consumer)
+			js.mu.RUnlock()
+			if ca == nil {
+				mset.clMu.Lock()
+				delete(mset.batchAcks, ck)
+				mset.writeBatchAcksLocked()
+				mset.clMu.Unlock()
+			}
+		}
+	}
+
+	mset.clMu.Lock()
+	var acks []string
+	for _, cacks := range mset.batchAcks {
+		for _, subject := range cacks {
+			acks = append(acks, subject)
+		}
+	}
+	mset.clMu.Unlock()
+
+	for _, subject := range acks {
+		mset.outq.sendMsg(subject, AckAck)
+	}
+}
+
+// Persists the committed acks not yet applied by their consumers, only for file based streams.
+// mset.clMu lock should be held.
+func (mset *stream) writeBatchAcksLocked() {
+	if mset.bafn == _EMPTY_ {
+		return
+	}
+	if len(mset.batchAcks) == 0 {
+		if err := os.Remove(mset.bafn); err != nil && !os.IsNotExist(err) {
+			mset.srv.Warnf("Error removing batch acks %q: %v", mset.bafn, err)
+		}
+		return
+	}
+	var acks []string
+	for _, cacks := range mset.batchAcks {
+		for _, subject := range cacks {
+			acks = append(acks, subject)
+		}
+	}
+	b, _ := json.Marshal(acks)
+	if err := writeFileWithSync(mset.bafn, b, defaultFilePerms); err != nil {
+		mset.srv.Warnf("Error writing batch acks %q: %v", mset.bafn, err)
+	}
+}
+
+// Recovers the committed acks not yet applied by their consumers, only for file based streams.
+func (mset *stream) loadBatchAcks() {
+	if mset.bafn == _EMPTY_ {
+		return
+	}
+	b, err := os.ReadFile(mset.bafn)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			mset.srv.Warnf("Error reading batch acks %q: %v", mset.bafn, err)
+		}
+		return
+	}
+	var acks []string
+	if err := json.Unmarshal(b, &acks); err != nil {
+		mset.srv.Warnf("Error decoding batch acks %q: %v", mset.bafn, err)
+		return
+	}
+
+	mset.clMu.Lock()
+	defer mset.clMu.Unlock()
+	for _, subject := range acks {
+		ack, err := parseBatchAck(subject)
+		if err != nil {
+			continue
+		}
+		if mset.batchAcks == nil {
+			mset.batchAcks = make(map[string]map[uint64]string)
+		}
+		ck := ack.consumerKey()
+		if mset.batchAcks[ck] == nil {
+			mset.batchAcks[ck] = make(map[uint64]string)
+		}
+		mset.batchAcks[ck][ack.sseq] = ack.subject
+	}
+}
@@ -18,6 +18,8 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -303,3 +305,195 @@ func TestJetStreamAtomicBatchPublishSourceAndMirror(t *testing.T) {
 	t.Run("R1", func(t *testing.T) { test(t, 1) })
 	t.Run("R3", func(t *testing.T) { test(t, 3) })
 }
+
+func TestJetStreamAtomicBatchPublishConsumerAck(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:     "IN",
+		Subjects: []string{"in"},
+		Storage:  FileStorage,
+		Replicas: 3,
+	})
+	require_NoError(t, err)
+
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:               "OUT",
+		Subjects:           []string{"out"},
+		Storage:            FileStorage,
+		AllowAtomicPublish: true,
+		Replicas:           3,
+	})
+	require_NoError(t, err)
+
+	_, err = js.AddConsumer("IN", &nats.ConsumerConfig{
+		Durable:   "C",
+		AckPolicy: nats.AckExplicitPolicy,
+		Replicas:  3,
+	})
+	require_NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = js.Publish("in", nil)
+		require_NoError(t, err)
+	}
+
+	sub, err := js.PullSubscribe(_EMPTY_, "C", nats.Bind("IN", "C"))
+	require_NoError(t, err)
+	defer sub.Unsubscribe()
+
+	msgs, err := sub.Fetch(1)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	ackSubj := msgs[0].Reply
+
+	// Publishes a batch of the given size, with the ack set on the given message.
+	publish := func(batchId string, size, ackOn int, ack string, msgId string) *JSPubAckResponse {
+		t.Helper()
+		for seq := 1; seq <= size; seq++ {
+			m := nats.NewMsg("out")
+			m.Header.Set("Nats-Batch-Id", batchId)
+			m.Header.Set("Nats-Batch-Sequence", strconv.Itoa(seq))
+			if seq == ackOn {
+				m.Header.Set("Nats-Batch-Ack", ack)
+			}
+			if seq == 1 && msgId != _EMPTY_ {
+				m.Header.Set("Nats-Msg-Id", msgId)
+			}
+			if seq != size {
+				require_NoError(t, nc.PublishMsg(m))
+				continue
+			}
+			m.Header.Set("Nats-Batch-Commit", "1")
+			rmsg, err := nc.RequestMsg(m, time.Second)
+			require_NoError(t, err)
+			var pubAck JSPubAckResponse
+			require_NoError(t, json.Unmarshal(rmsg.Data, &pubAck))
+			return &pubAck
+		}
+		return nil
+	}
+
+	checkAckPending := func(n int) {
+		t.Helper()
+		checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+			ci, err := js.ConsumerInfo("IN", "C")
+			if err != nil {
+				return err
+			}
+			if ci.NumAckPending != n {
+				return fmt.Errorf("expected %d pending acks, got %d", n, ci.NumAckPending)
+			}
+			return nil
+		})
+	}
+
+	// Invalid acks are rejected, and nothing is committed.
+	for _, ack := range []string{
+		"foo",
+		"$JS.ACK.IN.C.1.*.1.0.0",
+		"$JS.ACK.IN.X.1.1.1.0.0",
+		"$JS.ACK.X.C.1.1.1.0.0",
+		// Not delivered yet.
+		"$JS.ACK.IN.C.1.2.2.0.0",
+	} {
+		pubAck := publish("uuid", 2, 2, ack, _EMPTY_)
+		require_NotNil(t, pubAck.Error)
+		require_Equal(t, pubAck.Error.ErrCode, uint16(JSAtomicPublishInvalidBatchAckErrF))
+	}
+
+	// The ack must be on the commit.
+	pubAck := publish("uuid", 2, 1, ackSubj, _EMPTY_)
+	require_NotNil(t, pubAck.Error)
+	require_Equal(t, pubAck.Error.ErrCode, uint16(JSAtomicPublishInvalidBatchAckErrF))
+
+	// A batch that fails to commit does not apply the ack.
+	pubAck = publish("uuid", 2, 2, ackSubj, "msgId")
+	require_Error(t, pubAck.Error, NewJSAtomicPublishDuplicateError())
+	checkAckPending(1)
+
+	si, err := js.StreamInfo("OUT")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 0)
+
+	// A committed batch applies the ack.
+	pubAck = publish("uuid", 2, 2, ackSubj, _EMPTY_)
+	require_True(t, pubAck.Error == nil)
+	require_Equal(t, pubAck.Sequence, 2)
+	require_Equal(t, pubAck.BatchSize, 2)
+	checkAckPending(0)
+
+	ci, err := js.ConsumerInfo("IN", "C")
+	require_NoError(t, err)
+	require_Equal(t, ci.AckFloor.Stream, 1)
+
+	rsm, err := js.GetMsg("OUT", 2)
+	require_NoError(t, err)
+	require_Equal(t, rsm.Header.Get("Nats-Batch-Ack"), ackSubj)
+
+	// Committing the same ack again is rejected.
+	pubAck = publish("uuid", 2, 2, ackSubj, _EMPTY_)
+	require_NotNil(t, pubAck.Error)
+	si, err = js.StreamInfo("OUT")
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 2)
+
+	// Applies the ack as if a batch carrying it was committed on all replicas,
+	// but the stream leader went away before it could send the ack to the consumer.
+	commitLostAck := func(ack string) {
+		t.Helper()
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("OUT")
+			require_NoError(t, err)
+			mset.applyBatchAck(ack, false)
+			_, err = os.Stat(filepath.Join(s.StoreDir(), globalAccountName, streamsDir, "OUT", batchAcksFile))
+			require_NoError(t, err)
+		}
+	}
+
+	// The new stream leader sends the committed ack that was lost.
+	msgs, err = sub.Fetch(1)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	ackSubj = msgs[0].Reply
+	checkAckPending(1)
+	commitLostAck(ackSubj)
+	_, err = nc.Request(fmt.Sprintf(JSApiStreamLeaderStepDownT, "OUT"), nil, time.Second)
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "OUT")
+	checkAckPending(0)
+	ci, err = js.ConsumerInfo("IN", "C")
+	require_NoError(t, err)
+	require_Equal(t, ci.AckFloor.Stream, 2)
+
+	// A committed ack that was lost survives a restart.
+	_, err = js.Publish("in", nil)
+	require_NoError(t, err)
+	msgs, err = sub.Fetch(1)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 1)
+	ackSubj = msgs[0].Reply
+	checkAckPending(1)
+	commitLostAck(ackSubj)
+	nc.Close()
+	c.stopAll()
+	c.restartAll()
+	c.waitOnStreamLeader(globalAccountName, "OUT")
+	c.waitOnConsumerLeader(globalAccountName, "IN", "C")
+
+	nc, js = jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+	checkAckPending(0)
+	ci, err = js.ConsumerInfo("IN", "C")
+	require_NoError(t, err)
+	require_Equal(t, ci.AckFloor.Stream, 3)
+
+	// And once applied, the ack can not be committed again.
+	pubAck = publish("uuid", 2, 2, ackSubj, _EMPTY_)
+	require_NotNil(t, pubAck.Error)
+	require_Equal(t, pubAck.Error.ErrCode, uint16(JSAtomicPublishInvalidBatchAckErrF))
+}
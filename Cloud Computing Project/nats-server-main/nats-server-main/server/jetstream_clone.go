@@ -0,0 +1,302 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// streamClone describes which part of a source stream is used to seed a new stream.
+type streamClone struct {
+	Stream    string     `json:"stream"`
+	UpToSeq   uint64     `json:"up_to_seq,omitempty"`
+	UpToTime  *time.Time `json:"up_to_time,omitempty"`
+	Consumers bool       `json:"consumers,omitempty"`
+}
+
+// lastSeq returns the last sequence of the source store to be included in the clone.
+func (sc *streamClone) lastSeq(store StreamStore) uint64 {
+	var state StreamState
+	store.FastState(&state)
+	lseq := state.LastSeq
+	if sc.UpToSeq > 0 && sc.UpToSeq < lseq {
+		lseq = sc.UpToSeq
+	}
+	if sc.UpToTime != nil {
+		// This returns the first sequence at or after the given time.
+		if seq := store.GetSeqFromTime(*sc.UpToTime); seq > 0 && seq-1 < lseq {
+			lseq = seq - 1
+		}
+	}
+	return lseq
+}
+
+// Number of messages copied while holding the new stream's lock when seeding a clone.
+const streamCloneBatchSize = 10_000
+
+// How long a replica of the source waits to be able to seed a clone before failing it.
+const streamCloneSourceTimeout = 5 * time.Second
+
+// checkCloneSource returns an error if the local replica of the source can not seed the
+// clone, as it could be missing messages that were requested. The leader always can, a
+// follower needs to be current and needs to have stored the requested sequence.
+func (mset *stream) checkCloneSource(sc *streamClone) error {
+	if mset.isLeader() {
+		return nil
+	}
+	mset.mu.RLock()
+	current := mset.isCurrent()
+	mset.mu.RUnlock()
+	if !current {
+		return errors.New("replica of the source is not current")
+	}
+	if sc.UpToSeq > 0 {
+		if lseq := mset.lastSeq(); lseq < sc.UpToSeq {
+			return fmt.Errorf("replica of the source is at sequence %d, before %d", lseq, sc.UpToSeq)
+		}
+	}
+	return nil
+}
+
+// waitForCloneSource waits for the local replica of the source to be able to seed the clone.
+func (mset *stream) waitForCloneSource(sc *streamClone) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.NewTimer(streamCloneSourceTimeout)
+	defer timeout.Stop()
+	for {
+		err := mset.checkCloneSource(sc)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			return NewJSStreamCloneError(err)
+		case <-mset.monitorQuitC():
+			return NewJSStreamCloneError(errStreamClosed)
+		}
+	}
+}
+
+// cloneStream will create a new stream with the given config seeded from the source stream.
+// Message blocks are checksummed with a key derived from the stream name, so rather than
+// copying blocks the messages are stored again, keeping their sequences and timestamps.
+// The new stream does not process inbound messages until seeding has completed.
+func (a *Account) cloneStream(src *stream, ncfg *StreamConfig, sc *streamClone) (*stream, error) {
+	if ncfg == nil || sc == nil {
+		return nil, errors.New("nil config on stream clone")
+	}
+	if _, err := a.lookupStream(ncfg.Name); err == nil {
+		return nil, NewJSStreamNameExistError()
+	}
+
+	mset, err := a.addStream(ncfg)
+	if err != nil {
+		return nil, err
+	}
+
+	src.mu.RLock()
+	store := src.store
+	src.mu.RUnlock()
+
+	lseq := sc.lastSeq(store)
+	if err = mset.seedFromStore(store, lseq); err != nil {
+		mset.delete()
+		return nil, err
+	}
+
+	if !sc.Consumers {
+		return mset, nil
+	}
+	replicas := mset.cfg.Replicas
+	for _, o := range src.getPublicConsumers() {
+		cfg := o.config()
+		if !isDurableConsumer(&cfg) {
+			continue
+		}
+		// Inherit from the clone if it has fewer replicas than the consumer.
+		if cfg.Replicas > replicas {
+			cfg.Replicas = 0
+		}
+		state, err := o.store.State()
+		if err != nil {
+			mset.delete()
+			return nil, err
+		}
+		capConsumerState(state, lseq)
+		no, err := mset.addConsumer(&cfg)
+		if err != nil {
+			mset.delete()
+			return nil, err
+		}
+		no.mu.Lock()
+		err = no.setStoreState(state)
+		no.mu.Unlock()
+		if err != nil {
+			mset.delete()
+			return nil, err
+		}
+	}
+	return mset, nil
+}
+
+// seedFromStore copies all messages up to and including lseq from the store, keeping
+// their sequences and timestamps. Interior deletes are preserved as skipped messages.
+// Messages are copied in batches so the stream's lock is not held for the whole copy.
+// Lock should not be held.
+func (mset *stream) seedFromStore(store StreamStore, lseq uint64) error {
+	mset.mu.Lock()
+	mset.seeding = true
+	mset.mu.Unlock()
+	defer func() {
+		mset.mu.Lock()
+		mset.seeding = false
+		mset.mu.Unlock()
+	}()
+
+	var smv StoreMsg
+	next := uint64(1)
+	for next <= lseq {
+		done, err := mset.seedBatch(store, &smv, &next, lseq)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+	if next <= lseq {
+		if err := mset.store.SkipMsgs(next, lseq-next+1); err != nil {
+			return err
+		}
+	}
+	mset.setLastSeq(lseq)
+	return nil
+}
+
+// seedBatch copies up to streamCloneBatchSize messages from the store starting at next,
+// advancing next past the last copied message. Returns true once there is nothing left to copy.
+func (mset *stream) seedBatch(store StreamStore, smv *StoreMsg, next *uint64, lseq uint64) (bool, error) {
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+
+	for i := 0; i < streamCloneBatchSize && *next <= lseq; i++ {
+		sm, _, err := store.LoadNextMsg(fwcs, true, *next, smv)
+		if err == ErrStoreEOF || sm != nil && sm.seq > lseq {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if sm.seq > *next {
+			if err := mset.store.SkipMsgs(*next, sm.seq-*next); err != nil {
+				return false, err
+			}
+		}
+		subj := sm.subj
+		if mset.itr != nil {
+			if ts, err := mset.itr.Match(subj); err == nil {
+				subj = ts
+			}
+		}
+		ttl, _ := getMessageTTL(sm.hdr)
+		if err := mset.store.StoreRawMsg(subj, sm.hdr, sm.msg, sm.seq, sm.ts, ttl); err != nil {
+			return false, err
+		}
+		*next = sm.seq + 1
+	}
+	return false, nil
+}
+
+// capConsumerState removes any delivered or pending state above the last sequence of a clone.
+func capConsumerState(state *ConsumerState, lseq uint64) {
+	if state.Delivered.Stream > lseq {
+		state.Delivered.Stream = lseq
+	}
+	if state.AckFloor.Stream > lseq {
+		state.AckFloor.Stream = lseq
+	}
+	for seq := range state.Pending {
+		if seq > lseq {
+			delete(state.Pending, seq)
+		}
+	}
+	for seq := range state.Redelivered {
+		if seq > lseq {
+			delete(state.Redelivered, seq)
+		}
+	}
+}
+
+// streamCloneResponse returns the response for a completed clone.
+func (s *Server) streamCloneResponse(mset *stream, rg *raftGroup) *JSApiStreamCloneResponse {
+	resp := &JSApiStreamCloneResponse{ApiResponse: ApiResponse{Type: JSApiStreamCloneResponseType}}
+	msetCfg := mset.config()
+	resp.StreamInfo = &StreamInfo{
+		Created:   mset.createdTime(),
+		State:     mset.state(),
+		Config:    *setDynamicStreamMetadata(&msetCfg),
+		TimeStamp: time.Now().UTC(),
+	}
+	if rg != nil {
+		resp.StreamInfo.Cluster = s.getJetStream().clusterInfo(rg)
+	}
+	return resp
+}
+
+// processStreamClone will seed a clustered stream assignment from the local replica of the
+// source stream. This is only done by the preferred leader, which is placed on a server
+// hosting the source, the followers will catch up from its snapshot once done.
+// The clone fails if the local replica of the source can not be used to seed it.
+func (s *Server) processStreamClone(acc *Account, sa *streamAssignment) <-chan error {
+	js := s.getJetStream()
+	js.mu.RLock()
+	ci, cfg, sc, rg, subject, reply := sa.Client, sa.Config, sa.Clone, sa.Group, sa.Subject, sa.Reply
+	js.mu.RUnlock()
+
+	doneCh := make(chan error, 1)
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+
+		src, err := acc.lookupStream(sc.Stream)
+		if err != nil {
+			doneCh <- NewJSStreamCloneNotLocalError()
+			return
+		}
+		if err := src.waitForCloneSource(sc); err != nil {
+			s.Warnf("Clone of stream '%s > %s' into '%s' failed: %v", acc.Name, sc.Stream, cfg.Name, err)
+			doneCh <- err
+			return
+		}
+		s.Noticef("Starting clone of stream '%s > %s' into '%s'", acc.Name, sc.Stream, cfg.Name)
+		start := time.Now()
+		mset, err := acc.cloneStream(src, cfg, sc)
+		if err != nil {
+			s.Warnf("Clone of stream '%s > %s' into '%s' failed: %v", acc.Name, sc.Stream, cfg.Name, err)
+			doneCh <- err
+			return
+		}
+		s.Noticef("Completed clone of stream '%s > %s' into '%s' in %v",
+			acc.Name, sc.Stream, cfg.Name, time.Since(start).Round(time.Millisecond))
+		s.sendAPIResponse(ci, acc, subject, reply, _EMPTY_, s.jsonResponse(s.streamCloneResponse(mset, rg)))
+		doneCh <- nil
+	})
+	return doneCh
+}
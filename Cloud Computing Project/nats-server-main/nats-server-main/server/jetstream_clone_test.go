@@ -0,0 +1,293 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func jsStreamClone(t *testing.T, nc *nats.Conn, stream string, req *JSApiStreamCloneRequest) (*StreamInfo, error) {
+	t.Helper()
+	b, err := json.Marshal(req)
+	require_NoError(t, err)
+	msg, err := nc.Request(fmt.Sprintf(JSApiStreamCloneT, stream), b, 5*time.Second)
+	require_NoError(t, err)
+	var resp JSApiStreamCloneResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.StreamInfo, nil
+}
+
+func TestJetStreamStreamClone(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		storage   StorageType
+		transform *SubjectTransformConfig
+	}{
+		{"File", FileStorage, nil},
+		{"Memory", MemoryStorage, nil},
+		{"Transform", FileStorage, &SubjectTransformConfig{Source: "foo.>", Destination: "bar.>"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := RunBasicJetStreamServer(t)
+			defer s.Shutdown()
+
+			nc, js := jsClientConnect(t, s)
+			defer nc.Close()
+
+			_, err := jsStreamCreate(t, nc, &StreamConfig{
+				Name:     "SRC",
+				Subjects: []string{"foo.>"},
+				Storage:  test.storage,
+			})
+			require_NoError(t, err)
+
+			for i := 1; i <= 10; i++ {
+				_, err = js.Publish(fmt.Sprintf("foo.%d", i), []byte("ok"))
+				require_NoError(t, err)
+			}
+
+			// Ack the first 6 messages and leave 7 and 8 pending.
+			_, err = js.AddConsumer("SRC", &nats.ConsumerConfig{Durable: "DUR", AckPolicy: nats.AckExplicitPolicy})
+			require_NoError(t, err)
+			sub, err := js.PullSubscribe(_EMPTY_, "DUR", nats.Bind("SRC", "DUR"))
+			require_NoError(t, err)
+			msgs, err := sub.Fetch(6)
+			require_NoError(t, err)
+			for _, m := range msgs {
+				require_NoError(t, m.AckSync())
+			}
+			_, err = sub.Fetch(2)
+			require_NoError(t, err)
+
+			// Interior deletes are kept.
+			require_NoError(t, js.DeleteMsg("SRC", 5))
+
+			si, err := jsStreamClone(t, nc, "SRC", &JSApiStreamCloneRequest{
+				Config: StreamConfig{
+					Name:             "CLONE",
+					Subjects:         []string{"bar.>"},
+					Storage:          test.storage,
+					SubjectTransform: test.transform,
+				},
+				UpToSeq:   7,
+				Consumers: true,
+			})
+			require_NoError(t, err)
+			require_Equal(t, si.Config.Name, "CLONE")
+			require_Equal(t, si.State.Msgs, 6)
+			require_Equal(t, si.State.FirstSeq, 1)
+			require_Equal(t, si.State.LastSeq, 7)
+			require_Equal(t, si.State.NumDeleted, 1)
+
+			// Sequences and timestamps are preserved.
+			for _, seq := range []uint64{1, 7} {
+				sm, err := js.GetMsg("SRC", seq)
+				require_NoError(t, err)
+				cm, err := js.GetMsg("CLONE", seq)
+				require_NoError(t, err)
+				require_True(t, cm.Time.Equal(sm.Time))
+				if test.transform != nil {
+					require_Equal(t, cm.Subject, fmt.Sprintf("bar.%d", seq))
+				} else {
+					require_Equal(t, cm.Subject, sm.Subject)
+				}
+			}
+
+			// Consumer state is capped to the clone.
+			ci, err := js.ConsumerInfo("CLONE", "DUR")
+			require_NoError(t, err)
+			require_Equal(t, ci.Delivered.Stream, 7)
+			require_Equal(t, ci.AckFloor.Stream, 6)
+			require_Equal(t, ci.NumAckPending, 1)
+
+			// New messages continue after the clone.
+			pa, err := js.Publish("bar.new", nil)
+			require_NoError(t, err)
+			require_Equal(t, pa.Stream, "CLONE")
+			require_Equal(t, pa.Sequence, 8)
+
+			// Source is untouched.
+			ssi, err := js.StreamInfo("SRC")
+			require_NoError(t, err)
+			require_Equal(t, ssi.State.LastSeq, 10)
+		})
+	}
+}
+
+func TestJetStreamStreamCloneUpToTime(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "SRC", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	upTo := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+
+	// Without consumers or subjects.
+	si, err := jsStreamClone(t, nc, "SRC", &JSApiStreamCloneRequest{
+		Config:   StreamConfig{Name: "CLONE", Subjects: []string{"bar"}, Storage: FileStorage},
+		UpToTime: &upTo,
+	})
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 5)
+	require_Equal(t, si.State.LastSeq, 5)
+	require_Equal(t, si.State.Consumers, 0)
+
+	// Before the first message results in an empty stream.
+	before := upTo.Add(-time.Hour)
+	si, err = jsStreamClone(t, nc, "SRC", &JSApiStreamCloneRequest{
+		Config:   StreamConfig{Name: "EMPTY", Subjects: []string{"baz"}, Storage: FileStorage},
+		UpToTime: &before,
+	})
+	require_NoError(t, err)
+	require_Equal(t, si.State.Msgs, 0)
+	require_Equal(t, si.State.LastSeq, 0)
+}
+
+func TestJetStreamStreamCloneErrors(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "SRC", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+
+	upTo := time.Now()
+	for _, test := range []struct {
+		name   string
+		stream string
+		req    JSApiStreamCloneRequest
+		err    error
+	}{
+		{"not found", "MISSING", JSApiStreamCloneRequest{Config: StreamConfig{Name: "CLONE", Subjects: []string{"bar"}, Storage: FileStorage}}, NewJSStreamNotFoundError()},
+		{"exists", "SRC", JSApiStreamCloneRequest{Config: StreamConfig{Name: "SRC", Subjects: []string{"bar"}, Storage: FileStorage}}, NewJSStreamNameExistError()},
+		{"overlap", "SRC", JSApiStreamCloneRequest{Config: StreamConfig{Name: "CLONE", Subjects: []string{"foo"}, Storage: FileStorage}}, NewJSStreamSubjectOverlapError()},
+		{"seq and time", "SRC", JSApiStreamCloneRequest{Config: StreamConfig{Name: "CLONE", Storage: FileStorage}, UpToSeq: 1, UpToTime: &upTo}, NewJSBadRequestError()},
+		{"mirror", "SRC", JSApiStreamCloneRequest{Config: StreamConfig{Name: "CLONE", Storage: FileStorage, Mirror: &StreamSource{Name: "SRC"}}}, NewJSStreamInvalidConfigError(fmt.Errorf("stream clone can not be a mirror"))},
+		{"partitioned", "SRC", JSApiStreamCloneRequest{Config: StreamConfig{Name: "CLONE", Subjects: []string{"bar.*"}, Storage: FileStorage, Partitioning: &StreamPartitioning{Partitions: 2}}}, NewJSStreamInvalidConfigError(fmt.Errorf("stream clone can not be partitioned"))},
+		{"partition", "SRC", JSApiStreamCloneRequest{Config: StreamConfig{Name: "CLONE", Subjects: []string{"bar.*"}, Storage: FileStorage, Partitioning: &StreamPartitioning{Partitions: 2, Stream: "P"}}}, NewJSStreamInvalidConfigError(fmt.Errorf("stream clone can not be partitioned"))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := jsStreamClone(t, nc, test.stream, &test.req)
+			require_Error(t, err, test.err)
+		})
+	}
+}
+
+func TestJetStreamClusterStreamClone(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "SRC", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", nil)
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("SRC", &nats.ConsumerConfig{Durable: "DUR", AckPolicy: nats.AckExplicitPolicy, Replicas: 3})
+	require_NoError(t, err)
+	sub, err := js.PullSubscribe(_EMPTY_, "DUR", nats.Bind("SRC", "DUR"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(4)
+	require_NoError(t, err)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+
+	for _, replicas := range []int{1, 3} {
+		t.Run(fmt.Sprintf("R%d", replicas), func(t *testing.T) {
+			name := fmt.Sprintf("CLONE%d", replicas)
+			si, err := jsStreamClone(t, nc, "SRC", &JSApiStreamCloneRequest{
+				Config:    StreamConfig{Name: name, Subjects: []string{name}, Storage: FileStorage, Replicas: replicas},
+				UpToSeq:   8,
+				Consumers: true,
+			})
+			require_NoError(t, err)
+			require_Equal(t, si.State.Msgs, 8)
+			require_Equal(t, si.State.LastSeq, 8)
+			require_NotNil(t, si.Cluster)
+
+			c.waitOnStreamLeader(globalAccountName, name)
+			// All replicas are seeded.
+			checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+				for _, s := range c.servers {
+					mset, err := s.globalAccount().lookupStream(name)
+					if err != nil {
+						if replicas == 1 {
+							continue
+						}
+						return err
+					}
+					if state := mset.state(); state.Msgs != 8 || state.LastSeq != 8 {
+						return fmt.Errorf("expected 8 messages, got %d with last %d", state.Msgs, state.LastSeq)
+					}
+				}
+				return nil
+			})
+
+			// The consumer is assigned with its ack state.
+			c.waitOnConsumerLeader(globalAccountName, name, "DUR")
+			ci, err := js.ConsumerInfo(name, "DUR")
+			require_NoError(t, err)
+			require_Equal(t, ci.AckFloor.Stream, 4)
+			require_Equal(t, ci.NumPending, 4)
+
+			pa, err := js.Publish(name, nil)
+			require_NoError(t, err)
+			require_Equal(t, pa.Sequence, 9)
+		})
+	}
+
+	// Only the leader or a current follower that stored the requested sequence can seed a clone.
+	c.waitOnAllCurrent()
+	sl := c.streamLeader(globalAccountName, "SRC")
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("SRC")
+		require_NoError(t, err)
+		require_NoError(t, mset.checkCloneSource(&streamClone{Stream: "SRC", UpToSeq: 10}))
+		if err = mset.checkCloneSource(&streamClone{Stream: "SRC", UpToSeq: 11}); s == sl {
+			require_NoError(t, err)
+		} else {
+			require_Error(t, err)
+		}
+	}
+}
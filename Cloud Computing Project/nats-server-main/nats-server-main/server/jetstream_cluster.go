@@ -79,9 +79,18 @@ type inflightInfo struct {
 
 // Used to guide placement of streams and meta controllers in clustered JetStream.
 type Placement struct {
-	Cluster   string   `json:"cluster,omitempty"`
-	Tags      []string `json:"tags,omitempty"`
-	Preferred string   `json:"preferred,omitempty"`
+	Cluster   string            `json:"cluster,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Preferred string            `json:"preferred,omitempty"`
+	Learners  *LearnerPlacement `json:"learners,omitempty"`
+}
+
+// LearnerPlacement is used to place non-voting replicas of a stream. Learners receive the
+// replicated log and snapshots and serve direct gets, but never vote or count toward quorum.
+type LearnerPlacement struct {
+	Replicas int      `json:"num_replicas"`
+	Cluster  string   `json:"cluster,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
 }
 
 // Define types of the entry.
@@ -125,6 +134,7 @@ type raftGroup struct {
 	Cluster   string      `json:"cluster,omitempty"`
 	Preferred string      `json:"preferred,omitempty"`
 	ScaleUp   bool        `json:"scale_up,omitempty"`
+	Learners  []string    `json:"learners,omitempty"`
 	// Internal
 	node RaftNode
 }
@@ -139,6 +149,9 @@ type streamAssignment struct {
 	Subject string        `json:"subject,omitempty"`
 	Reply   string        `json:"reply,omitempty"`
 	Restore *StreamState  `json:"restore_state,omitempty"`
+	Clone   *streamClone  `json:"clone,omitempty"`
+	// Set when the update switches the stream between the primary and mirror role of a replication pair.
+	Failover *streamFailover `json:"failover,omitempty"`
 	// Internal
 	consumers   map[string]*consumerAssignment
 	responded   bool
@@ -183,8 +196,10 @@ type streamMsgDelete struct {
 	Stream  string      `json:"stream"`
 	Seq     uint64      `json:"seq"`
 	NoErase bool        `json:"no_erase,omitempty"`
-	Subject string      `json:"subject"`
-	Reply   string      `json:"reply"`
+	// Compact is set when compaction by subject removes a superseded message.
+	Compact bool   `json:"compact,omitempty"`
+	Subject string `json:"subject"`
+	Reply   string `json:"reply"`
 }
 
 const (
@@ -366,6 +381,424 @@ func (s *Server) JetStreamSnapshotStream(account, stream string) error {
 	return err
 }
 
+func (s *Server) JetStreamSnapshotConsumer(account, stream, consumer string) error {
+	js, cc := s.getJetStreamCluster()
+	if js == nil {
+		return NewJSNotEnabledForAccountError()
+	}
+	if cc == nil {
+		return NewJSClusterNotActiveError()
+	}
+	// Grab account
+	acc, err := s.LookupAccount(account)
+	if err != nil {
+		return err
+	}
+	// Grab stream
+	mset, err := acc.lookupStream(stream)
+	if err != nil {
+		return err
+	}
+	// Grab consumer
+	o := mset.lookupConsumer(consumer)
+	if o == nil {
+		return NewJSConsumerNotFoundError()
+	}
+
+	// Hold lock when installing snapshot.
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.node == nil || o.store == nil {
+		return nil
+	}
+	snap, err := o.store.EncodedState()
+	if err != nil {
+		return err
+	}
+	return o.node.InstallSnapshot(snap)
+}
+
+// JetStreamSnapshotGroup installs a snapshot for the Raft group with the given name,
+// which compacts its WAL. The group can be the meta group or that of any stream or
+// consumer that this server is a member of.
+func (s *Server) JetStreamSnapshotGroup(group string) error {
+	js, cc := s.getJetStreamCluster()
+	if js == nil {
+		return NewJSNotEnabledError()
+	}
+	if cc == nil {
+		return NewJSClusterNotActiveError()
+	}
+	if s.lookupRaftNode(group) == nil {
+		return errUnknownRaftGroup
+	}
+
+	js.mu.RLock()
+	meta := cc.meta
+	if meta != nil && meta.Group() == group {
+		js.mu.RUnlock()
+		// Any peer can snapshot its own meta state, not only the leader.
+		snap, err := js.metaSnapshot()
+		if err != nil {
+			return err
+		}
+		return meta.InstallSnapshot(snap)
+	}
+	var account, stream, consumer string
+	for accName, asa := range cc.streams {
+		for _, sa := range asa {
+			if sa.Group != nil && sa.Group.Name == group {
+				account, stream = accName, sa.Config.Name
+			}
+			for _, ca := range sa.consumers {
+				if ca.Group != nil && ca.Group.Name == group {
+					account, stream, consumer = accName, ca.Stream, ca.Name
+				}
+			}
+		}
+	}
+	js.mu.RUnlock()
+
+	switch {
+	case consumer != _EMPTY_:
+		return s.JetStreamSnapshotConsumer(account, stream, consumer)
+	case stream != _EMPTY_:
+		return s.JetStreamSnapshotStream(account, stream)
+	default:
+		return errUnknownRaftGroup
+	}
+}
+
+// streamFailover is set on a stream assignment update that switches the stream between
+// the primary and mirror role of a replication pair.
+type streamFailover struct {
+	// Seq is the last sequence replicated between the pair. A demoted stream drops any
+	// messages after it, since these conflict with the messages of the new primary.
+	Seq uint64 `json:"seq"`
+	// Gap is the number of messages the promoted mirror had not replicated from its origin.
+	Gap uint64 `json:"gap,omitempty"`
+}
+
+// Maximum time since we last heard from the mirror consumer for its lag to be current.
+// The consumer sends heartbeats when idle, so an active mirror is heard from at least this often.
+const streamFailoverActiveThreshold = 3 * sourceHealthHB
+
+const (
+	// Maximum time to wait for a mirror to replicate the last message of its fenced origin.
+	streamFailoverFenceTimeout = 5 * time.Second
+	// How often the fenced origin and the mirror are checked.
+	streamFailoverFenceInterval = 100 * time.Millisecond
+)
+
+// failoverStream switches a stream of a replication pair between the mirror and primary role.
+// Called on the stream leader, which checks the failover against the state of the stream and
+// then proposes the updated stream assignments to the meta leader. A mirror is only promoted
+// when its mirror consumer is active and replicated all messages of its origin, unless forced.
+// If the origin is known to the meta layer it is fenced first by sealing it, so it stops
+// accepting messages. The mirror is promoted once it replicated the last message of the sealed
+// origin, and the origin is then demoted to mirror it, which reverses the direction of the pair.
+// A primary is only demoted by itself when it has no messages that were not replicated to the
+// new primary. The stream leader responds once the update has been applied.
+func (js *jetStream) failoverStream(mset *stream, ci *ClientInfo, subject, reply string, req *JSApiStreamFailoverRequest, resp *JSApiStreamFailoverResponse) *ApiError {
+	s, accName := js.srv, mset.accName()
+
+	mset.mu.RLock()
+	lseq, jsa := mset.lseq, mset.jsa
+	var lag uint64
+	var active bool
+	if mirror := mset.mirror; mirror != nil {
+		// The lag is reported by the mirror consumer, so it is stale if we have not heard from it.
+		lag = mirror.lag
+		active = mirror.sub != nil && time.Since(time.Unix(0, mirror.last.Load())) < streamFailoverActiveThreshold
+	}
+	mset.mu.RUnlock()
+
+	js.mu.RLock()
+	cc := js.cluster
+	sa := js.streamAssignment(accName, mset.name())
+	if sa == nil {
+		js.mu.RUnlock()
+		return NewJSStreamNotFoundError()
+	}
+	ocfg, ncfg := sa.Config, sa.Config.clone()
+	// The origin of a mirror that is being promoted, if it is a primary in this JetStream cluster.
+	var osa *streamAssignment
+	if req.Mirror == nil && ocfg.Mirror != nil && ocfg.Mirror.External == nil {
+		if osa = js.streamAssignment(accName, ocfg.Mirror.Name); osa != nil && osa.Config.Mirror != nil {
+			osa = nil
+		}
+	}
+	js.mu.RUnlock()
+
+	resp.LastSeq = lseq
+	// The demoted origin, and the origin sealed or restored while fencing it.
+	var dsa, fsa, rsa *streamAssignment
+	if req.Mirror == nil {
+		if ocfg.Mirror == nil {
+			return NewJSStreamNotMirrorError()
+		}
+		resp.Gap = lag
+		if !req.Force {
+			if !active {
+				return NewJSStreamFailoverInactiveError()
+			}
+			if lag > 0 {
+				return NewJSStreamFailoverLagError(lag)
+			}
+		}
+		subjects := req.Subjects
+		if osa != nil {
+			// The promoted stream takes over the subjects of its origin by default.
+			if len(subjects) == 0 {
+				subjects = osa.Config.Subjects
+			}
+			// The demoted config is checked when applied, since it would mirror a stream
+			// that until then still mirrors it.
+			dcfg := osa.Config.clone()
+			dcfg.Mirror = &StreamSource{Name: ocfg.Name, LagThreshold: ocfg.Mirror.LagThreshold}
+			dcfg.Subjects = nil
+			setStaticStreamMetadata(dcfg)
+			fcfg := osa.Config.clone()
+			fcfg.Sealed = true
+			js.mu.RLock()
+			dsa, fsa, rsa = osa.copyGroup(), osa.copyGroup(), osa.copyGroup()
+			js.mu.RUnlock()
+			dsa.Config, dsa.Failover = dcfg, &streamFailover{Seq: lseq}
+			fsa.Config, fsa.Failover = fcfg, &streamFailover{Seq: lseq}
+			rsa.Failover = &streamFailover{Seq: lseq}
+			for _, a := range []*streamAssignment{dsa, fsa, rsa} {
+				a.Subject, a.Reply = subject, _EMPTY_
+			}
+		}
+		resp.Role, resp.Mirror = StreamRolePrimary, ocfg.Mirror
+		ncfg.Mirror, ncfg.MirrorDirect, ncfg.Subjects = nil, false, subjects
+	} else {
+		if ocfg.Mirror != nil {
+			return NewJSStreamMirrorNotUpdatableError()
+		}
+		if lseq > req.Seq {
+			resp.Gap = lseq - req.Seq
+			return NewJSStreamFailoverConflictError(resp.Gap)
+		}
+		resp.Role, resp.Mirror = StreamRoleMirror, req.Mirror
+		ncfg.Mirror, ncfg.Subjects = req.Mirror, nil
+	}
+
+	// Update asset version metadata.
+	setStaticStreamMetadata(ncfg)
+	cfg, err := jsa.failoverUpdateCheck(ocfg, ncfg, s, false, true)
+	if err != nil {
+		return NewJSStreamUpdateError(err, Unless(err))
+	}
+
+	js.mu.RLock()
+	// The origin gives up its subjects in the same step.
+	if cc.subjectsOverlapExcept(accName, cfg.Subjects, cfg.partitionedStream(), sa, osa) {
+		js.mu.RUnlock()
+		return NewJSStreamSubjectOverlapError()
+	}
+	csa := sa.copyGroup()
+	csa.Config, csa.Failover = cfg, &streamFailover{Seq: lseq, Gap: resp.Gap}
+	csa.Client, csa.Subject, csa.Reply = ci, subject, reply
+	meta := cc.meta
+	js.mu.RUnlock()
+
+	if fsa == nil {
+		if err := meta.ForwardProposal(encodeUpdateStreamAssignment(csa)); err != nil {
+			return NewJSClusterNotAvailError()
+		}
+		return nil
+	}
+	// Fence the origin, messages it acknowledged from here on would be lost by the promotion.
+	if err := meta.ForwardProposal(encodeUpdateStreamAssignment(fsa)); err != nil {
+		return NewJSClusterNotAvailError()
+	}
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		js.completeFailover(mset, csa, dsa, rsa, req.Force)
+	})
+	return nil
+}
+
+// completeFailover promotes a mirror once its origin has been fenced. It waits for the sealed
+// origin to report its last sequence and for the mirror to replicate up to it, then promotes the
+// mirror and demotes the origin. Since the origin no longer stores messages, there is no gap
+// between the pair. If the mirror does not catch up in time the failover is aborted and the
+// origin restored, unless forced, in which case the origin drops what was not replicated.
+func (js *jetStream) completeFailover(mset *stream, csa, dsa, rsa *streamAssignment, force bool) {
+	s, origin := js.srv, rsa.Config.Name
+
+	// The last sequence of the origin is only final once we have seen it twice after it was sealed.
+	var last uint64
+	var fenced bool
+	deadline := time.Now().Add(streamFailoverFenceTimeout)
+	for !fenced && time.Now().Before(deadline) {
+		select {
+		case <-time.After(streamFailoverFenceInterval):
+		case <-s.quitCh:
+			return
+		}
+		if mset.closed.Load() {
+			return
+		}
+		si, err := mset.requestStreamInfo(origin, time.Until(deadline))
+		if err != nil || si.Config.Mirror != nil || !si.Config.Sealed {
+			continue
+		}
+		fenced = si.State.LastSeq == last && mset.lastSeq() >= last
+		last = si.State.LastSeq
+	}
+
+	js.mu.RLock()
+	meta := js.cluster.meta
+	js.mu.RUnlock()
+
+	if !fenced && !force {
+		mset.mu.RLock()
+		var lag uint64
+		if mset.mirror != nil {
+			lag = mset.mirror.lag
+		}
+		mset.mu.RUnlock()
+		meta.ForwardProposal(encodeUpdateStreamAssignment(rsa))
+		resp := JSApiStreamFailoverResponse{ApiResponse: ApiResponse{Type: JSApiStreamFailoverResponseType}, LastSeq: mset.lastSeq(), Gap: lag}
+		if lag > 0 {
+			resp.Error = NewJSStreamFailoverLagError(lag)
+		} else {
+			resp.Error = NewJSStreamFailoverInactiveError()
+		}
+		s.sendAPIErrResponse(csa.Client, mset.account(), csa.Subject, csa.Reply, _EMPTY_, s.jsonResponse(&resp))
+		return
+	}
+
+	// If forced, the origin drops the messages after the last one we replicated.
+	if !fenced {
+		last = mset.lastSeq()
+	} else {
+		csa.Failover.Gap = 0
+	}
+	csa.Failover.Seq, dsa.Failover.Seq = last, last
+	if err := meta.ForwardProposal(encodeUpdateStreamAssignment(csa)); err != nil {
+		resp := JSApiStreamFailoverResponse{ApiResponse: ApiResponse{Type: JSApiStreamFailoverResponseType}}
+		resp.Error = NewJSClusterNotAvailError()
+		s.sendAPIErrResponse(csa.Client, mset.account(), csa.Subject, csa.Reply, _EMPTY_, s.jsonResponse(&resp))
+		return
+	}
+	meta.ForwardProposal(encodeUpdateStreamAssignment(dsa))
+}
+
+// requestStreamInfo requests the info of another stream in our account from its stream leader.
+func (mset *stream) requestStreamInfo(name string, timeout time.Duration) (*StreamInfo, error) {
+	if mset.outq == nil {
+		return nil, errors.New("outq required")
+	}
+	respCh := make(chan *JSApiStreamInfoResponse, 1)
+	reply := infoReplySubject()
+	sub, err := mset.subscribeInternal(reply, func(sub *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+		_, msg := c.msgParts(rmsg)
+		var sir JSApiStreamInfoResponse
+		if err := json.Unmarshal(msg, &sir); err != nil {
+			return
+		}
+		select {
+		case respCh <- &sir:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		mset.mu.Lock()
+		mset.unsubscribe(sub)
+		mset.mu.Unlock()
+	}()
+	mset.outq.send(newJSPubMsg(fmt.Sprintf(JSApiStreamInfoT, name), _EMPTY_, reply, nil, nil, nil, 0))
+
+	select {
+	case sir := <-respCh:
+		if sir.Error != nil {
+			return nil, sir.Error
+		}
+		if sir.StreamInfo == nil {
+			return nil, NewJSStreamNotFoundError()
+		}
+		return sir.StreamInfo, nil
+	case <-time.After(timeout):
+		return nil, errors.New("timeout")
+	}
+}
+
+// failoverResponse returns the response for a failover that has been applied to the stream.
+func (js *jetStream) failoverResponse(mset *stream, osa, sa *streamAssignment) *JSApiStreamFailoverResponse {
+	resp := &JSApiStreamFailoverResponse{
+		ApiResponse: ApiResponse{Type: JSApiStreamFailoverResponseType},
+		Role:        StreamRolePrimary,
+		Mirror:      osa.Config.Mirror,
+		LastSeq:     mset.lastSeq(),
+		Gap:         sa.Failover.Gap,
+	}
+	if sa.Config.Mirror != nil {
+		resp.Role, resp.Mirror = StreamRoleMirror, sa.Config.Mirror
+	}
+	return resp
+}
+
+// JetStreamMigrateGroupWAL switches the WAL of the Raft group with the given name to
+// the given compression while the group keeps running. The last block is sealed and
+// all blocks are rewritten in the new format. The format is kept in the WAL metadata
+// so the group continues to use it after a restart. Returns the number of blocks rewritten.
+func (s *Server) JetStreamMigrateGroupWAL(group string, alg StoreCompression) (int, error) {
+	js, cc := s.getJetStreamCluster()
+	if js == nil {
+		return 0, NewJSNotEnabledError()
+	}
+	if cc == nil {
+		return 0, NewJSClusterNotActiveError()
+	}
+	switch alg {
+	case NoCompression, S2Compression, ZstdCompression:
+	default:
+		return 0, fmt.Errorf("invalid compression %q", alg)
+	}
+	n, ok := s.lookupRaftNode(group).(*raft)
+	if !ok || n == nil {
+		return 0, errUnknownRaftGroup
+	}
+	n.RLock()
+	fs, ok := n.wal.(*fileStore)
+	n.RUnlock()
+	if !ok {
+		return 0, errRaftWALNotFile
+	}
+
+	fs.mu.RLock()
+	cfg := fs.cfg.StreamConfig
+	fs.mu.RUnlock()
+	if cfg.Compression != alg {
+		cfg.Compression, cfg.CompressionLevel = alg, 0
+		if err := fs.UpdateConfig(&cfg); err != nil {
+			return 0, err
+		}
+		s.Noticef("Migrating WAL of Raft group %q to %s compression", group, alg)
+	}
+	return fs.recompressBlocks()
+}
+
+// Returns the compression of an existing Raft WAL, as changed by JetStreamMigrateGroupWAL.
+func (s *Server) raftWALCompression(storeDir string) StoreCompression {
+	if _, err := os.Stat(filepath.Join(storeDir, JetStreamMetaFile)); err != nil {
+		return NoCompression
+	}
+	// The WAL of a group is encrypted with the group name as the context.
+	fsi, err := s.readStreamMeta(storeDir, filepath.Base(storeDir))
+	if err != nil {
+		s.Warnf("Error reading Raft WAL metafile in %q: %v", storeDir, err)
+		return NoCompression
+	}
+	return fsi.Compression
+}
+
 func (s *Server) JetStreamClusterPeers() []string {
 	js := s.getJetStream()
 	if js == nil {
@@ -559,11 +992,21 @@ func (js *jetStream) isConsumerHealthy(mset *stream, consumer string, ca *consum
 // subjectsOverlap checks all existing stream assignments for the account cross-cluster for subject overlap
 // Use only for clustered JetStream
 // Read lock should be held.
-func (jsc *jetStreamCluster) subjectsOverlap(acc string, subjects []string, osa *streamAssignment) bool {
+func (jsc *jetStreamCluster) subjectsOverlap(acc string, subjects []string, osa *streamAssignment, partitioned string) bool {
+	return jsc.subjectsOverlapExcept(acc, subjects, partitioned, osa)
+}
+
+// Same as subjectsOverlap, but skips all of the given stream assignments.
+// Used when a failover moves the subjects of one stream to another.
+func (jsc *jetStreamCluster) subjectsOverlapExcept(acc string, subjects []string, partitioned string, skip ...*streamAssignment) bool {
 	asa := jsc.streams[acc]
 	for _, sa := range asa {
 		// can't overlap yourself, assume osa pre-checked for deep equal if passed
-		if osa != nil && sa == osa {
+		if slices.Contains(skip, sa) {
+			continue
+		}
+		// The partitions of a partitioned stream all share its subjects.
+		if partitioned != _EMPTY_ && sa.Config.partitionedStream() == partitioned {
 			continue
 		}
 		for _, subj := range sa.Config.Subjects {
@@ -703,9 +1146,10 @@ func (js *jetStream) setupMetaGroup() error {
 	syncAlways := js.srv.opts.SyncAlways
 	syncInterval := js.srv.opts.SyncInterval
 	js.srv.optsMu.RUnlock()
+	alg := s.raftWALCompression(storeDir)
 	fs, err := newFileStoreWithCreated(
-		FileStoreConfig{StoreDir: storeDir, BlockSize: defaultMetaFSBlkSize, AsyncFlush: false, SyncAlways: syncAlways, SyncInterval: syncInterval, srv: s},
-		StreamConfig{Name: defaultMetaGroupName, Storage: FileStorage},
+		FileStoreConfig{StoreDir: storeDir, BlockSize: defaultMetaFSBlkSize, AsyncFlush: false, SyncAlways: syncAlways, SyncInterval: syncInterval, Compression: alg, srv: s},
+		StreamConfig{Name: defaultMetaGroupName, Storage: FileStorage, Compression: alg},
 		time.Now().UTC(),
 		s.jsKeyGen(s.getOpts().JetStreamKey, defaultMetaGroupName),
 		s.jsKeyGen(s.getOpts().JetStreamOldKey, defaultMetaGroupName),
@@ -1624,7 +2068,7 @@ func (js *jetStream) setStreamAssignmentRecovering(sa *streamAssignment) {
 	defer js.mu.Unlock()
 	sa.responded = true
 	sa.recovering = true
-	sa.Restore = nil
+	sa.Restore, sa.Clone = nil, nil
 	if sa.Group != nil {
 		sa.Group.Preferred = _EMPTY_
 		sa.Group.ScaleUp = false
@@ -1648,7 +2092,10 @@ func (js *jetStream) setConsumerAssignmentRecovering(ca *consumerAssignment) {
 func (sa *streamAssignment) copyGroup() *streamAssignment {
 	csa, cg := *sa, *sa.Group
 	csa.Group = &cg
+	// A failover only applies to the update that carried it.
+	csa.Failover = nil
 	csa.Group.Peers = copyStrings(sa.Group.Peers)
+	csa.Group.Learners = copyStrings(sa.Group.Learners)
 	return &csa
 }
 
@@ -1761,6 +2208,8 @@ func (js *jetStream) processRemovePeer(peer string) {
 		for _, sa := range asa {
 			if rg := sa.Group; rg.isMember(peer) {
 				js.removePeerFromStreamLocked(sa, peer)
+			} else if rg.isLearner(peer) {
+				js.removeLearnerFromStreamLocked(sa, peer)
 			}
 		}
 	}
@@ -1792,6 +2241,10 @@ func (js *jetStream) removePeerFromStreamLocked(sa *streamAssignment, peer strin
 	cc.meta.Propose(encodeAddStreamAssignment(csa))
 	rg := csa.Group
 	for _, ca := range sa.consumers {
+		// Consumers on learners stay there.
+		if ca.Config.OnLearners {
+			continue
+		}
 		// Ephemerals are R=1, so only auto-remap durables, or R>1.
 		if ca.Config.Durable != _EMPTY_ {
 			cca := ca.copyGroup()
@@ -1805,6 +2258,59 @@ func (js *jetStream) removePeerFromStreamLocked(sa *streamAssignment, peer strin
 	return replaced
 }
 
+// Removes a learner from the stream, learners are not replaced.
+// Lock should be held.
+func (js *jetStream) removeLearnerFromStreamLocked(sa *streamAssignment, peer string) {
+	cc := js.cluster
+	if cc == nil || cc.meta == nil {
+		return
+	}
+	csa := sa.copyGroup()
+	csa.Group.Learners = slices.DeleteFunc(csa.Group.Learners, func(p string) bool { return p == peer })
+	csa.Config = csa.Config.withLearners(len(csa.Group.Learners))
+	cc.meta.Propose(encodeUpdateStreamAssignment(csa))
+
+	// Move consumers on the learner to one of the remaining learners.
+	for _, ca := range sa.consumers {
+		if !ca.Config.OnLearners || !ca.Group.isMember(peer) {
+			continue
+		}
+		if !isDurableConsumer(ca.Config) {
+			cc.meta.Propose(encodeDeleteConsumerAssignment(ca))
+			continue
+		}
+		i := slices.IndexFunc(csa.Group.Learners, func(p string) bool { return !ca.Group.isMember(p) })
+		if i < 0 {
+			js.srv.Warnf("JetStream cluster could not replace learner for consumer '%s > %s > %s'",
+				sa.Client.serviceAccount(), sa.Config.Name, ca.Name)
+			continue
+		}
+		cca := ca.copyGroup()
+		cca.Group.Peers = slices.DeleteFunc(cca.Group.Peers, func(p string) bool { return p == peer })
+		cca.Group.Peers = append(cca.Group.Peers, csa.Group.Learners[i])
+		cca.Group.Preferred = _EMPTY_
+		cc.meta.Propose(encodeAddConsumerAssignment(cca))
+	}
+}
+
+// promoteLearnerInStream will have the meta leader make a learner of the stream a voter.
+// The stream leader adds the peer to the group once it has been told it is no longer a learner.
+func (js *jetStream) promoteLearnerInStream(sa *streamAssignment, peer string) bool {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	cc := js.cluster
+	if cc == nil || cc.meta == nil || !sa.Group.isLearner(peer) {
+		return false
+	}
+	csa := sa.copyGroup()
+	csa.Group.Learners = slices.DeleteFunc(csa.Group.Learners, func(p string) bool { return p == peer })
+	csa.Group.Peers = append(csa.Group.Peers, peer)
+	csa.Config = csa.Config.withLearners(len(csa.Group.Learners))
+	csa.Config.Replicas = len(csa.Group.Peers)
+	return cc.meta.Propose(encodeUpdateStreamAssignment(csa)) == nil
+}
+
 // Check if we have peer related entries.
 func (js *jetStream) hasPeerEntries(entries []*Entry) bool {
 	for _, e := range entries {
@@ -1988,6 +2494,18 @@ func (rg *raftGroup) isMember(id string) bool {
 	return false
 }
 
+func (rg *raftGroup) isLearner(id string) bool {
+	if rg == nil {
+		return false
+	}
+	return slices.Contains(rg.Learners, id)
+}
+
+// Returns true if the server hosts a replica of the group, either as a voter or a learner.
+func (rg *raftGroup) isMemberOrLearner(id string) bool {
+	return rg.isMember(id) || rg.isLearner(id)
+}
+
 func (rg *raftGroup) setPreferred() {
 	if rg == nil || len(rg.Peers) == 0 {
 		return
@@ -2015,7 +2533,7 @@ func (js *jetStream) createRaftGroup(accName string, rg *raftGroup, recovering b
 	}
 
 	// If this is a single peer raft group or we are not a member return.
-	if len(rg.Peers) <= 1 || !rg.isMember(cc.meta.ID()) {
+	if len(rg.Peers) <= 1 || !rg.isMemberOrLearner(cc.meta.ID()) {
 		// Nothing to do here.
 		return nil, nil
 	}
@@ -2031,6 +2549,7 @@ retry:
 			goto retry
 		}
 		s.Debugf("JetStream cluster already has raft group %q assigned", rg.Name)
+		node.SetLearners(rg.Learners)
 		// Check and see if the group has the same peers. If not then we
 		// will update the known peers, which will send a peerstate if leader.
 		groupPeerIDs := append([]string{}, rg.Peers...)
@@ -2094,9 +2613,10 @@ retry:
 		syncAlways := js.srv.opts.SyncAlways
 		syncInterval := js.srv.opts.SyncInterval
 		js.srv.optsMu.RUnlock()
+		alg := s.raftWALCompression(storeDir)
 		fs, err := newFileStoreWithCreated(
-			FileStoreConfig{StoreDir: storeDir, BlockSize: defaultMediumBlockSize, AsyncFlush: false, SyncAlways: syncAlways, SyncInterval: syncInterval, srv: s},
-			StreamConfig{Name: rg.Name, Storage: FileStorage, Metadata: labels},
+			FileStoreConfig{StoreDir: storeDir, BlockSize: defaultMediumBlockSize, AsyncFlush: false, SyncAlways: syncAlways, SyncInterval: syncInterval, Compression: alg, srv: s},
+			StreamConfig{Name: rg.Name, Storage: FileStorage, Metadata: labels, Compression: alg},
 			time.Now().UTC(),
 			s.jsKeyGen(s.getOpts().JetStreamKey, rg.Name),
 			s.jsKeyGen(s.getOpts().JetStreamOldKey, rg.Name),
@@ -2115,7 +2635,7 @@ retry:
 		store = ms
 	}
 
-	cfg := &RaftConfig{Name: rg.Name, Store: storeDir, Log: store, Track: true, Recovering: recovering, ScaleUp: rg.ScaleUp}
+	cfg := &RaftConfig{Name: rg.Name, Store: storeDir, Log: store, Track: true, Recovering: recovering, ScaleUp: rg.ScaleUp, Learners: rg.Learners}
 
 	if _, err := readPeerState(storeDir); err != nil {
 		s.bootstrapRaftNode(cfg, rg.Peers, true)
@@ -2298,9 +2818,13 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 	t := time.NewTicker(compactInterval + rci)
 	defer t.Stop()
 
+	// Resend committed batch consumer acks the consumers did not apply yet.
+	bat := time.NewTicker(batchAckRetryInterval)
+	defer bat.Stop()
+
 	js.mu.RLock()
 	isLeader := cc.isStreamLeader(sa.Client.serviceAccount(), sa.Config.Name)
-	isRestore := sa.Restore != nil
+	isRestore := sa.Restore != nil || sa.Clone != nil
 	js.mu.RUnlock()
 
 	acc, err := s.LookupAccount(sa.Client.serviceAccount())
@@ -2502,7 +3026,11 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 				}
 				if isRestore {
 					acc, _ := s.LookupAccount(sa.Client.serviceAccount())
-					restoreDoneCh = s.processStreamRestore(sa.Client, acc, sa.Config, _EMPTY_, sa.Reply, _EMPTY_)
+					if sa.Clone != nil {
+						restoreDoneCh = s.processStreamClone(acc, sa)
+					} else {
+						restoreDoneCh = s.processStreamRestore(sa.Client, acc, sa.Config, _EMPTY_, sa.Reply, _EMPTY_)
+					}
 					continue
 				} else if n != nil && n.NeedSnapshot() {
 					doSnapshot()
@@ -2587,10 +3115,21 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 		case <-t.C:
 			doSnapshot()
 
+		case <-bat.C:
+			if isLeader && mset != nil {
+				mset.resendBatchAcks()
+			}
+
 		case <-uch:
 			// keep stream assignment current
 			sa = mset.streamAssignment()
 
+			// Keep our learners current, this handles promotions to voters.
+			js.mu.RLock()
+			learners := copyStrings(sa.Group.Learners)
+			js.mu.RUnlock()
+			n.SetLearners(learners)
+
 			// We get this when we have a new stream assignment caused by an update.
 			// We want to know if we are migrating.
 			if migrating := mset.isMigrating(); migrating {
@@ -2691,8 +3230,9 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 			if err != nil {
 				s.Debugf("Stream restore failed: %v", err)
 			}
+			isClone := sa.Clone != nil
 			isRestore = false
-			sa.Restore = nil
+			sa.Restore, sa.Clone = nil, nil
 			// If we were successful lookup up our stream now.
 			if err == nil {
 				if mset, err = acc.lookupStream(sa.Config.Name); mset != nil {
@@ -2723,9 +3263,14 @@ func (js *jetStream) monitorStream(mset *stream, sa *streamAssignment, sendSnaps
 				result := &streamAssignmentResult{
 					Account: sa.Client.serviceAccount(),
 					Stream:  sa.Config.Name,
-					Restore: &JSApiStreamRestoreResponse{ApiResponse: ApiResponse{Type: JSApiStreamRestoreResponseType}},
 				}
-				result.Restore.Error = NewJSStreamAssignmentError(err, Unless(err))
+				if isClone {
+					result.Clone = &JSApiStreamCloneResponse{ApiResponse: ApiResponse{Type: JSApiStreamCloneResponseType}}
+					result.Clone.Error = NewJSStreamCloneError(err, Unless(err))
+				} else {
+					result.Restore = &JSApiStreamRestoreResponse{ApiResponse: ApiResponse{Type: JSApiStreamRestoreResponseType}}
+					result.Restore.Error = NewJSStreamAssignmentError(err, Unless(err))
+				}
 				js.mu.Unlock()
 				// Send response to the metadata leader. They will forward to the user as needed.
 				s.sendInternalMsgLocked(streamAssignmentSubj, _EMPTY_, nil, result)
@@ -3031,6 +3576,16 @@ func (js *jetStream) applyStreamEntries(mset *stream, ce *CommittedEntry, isReco
 					mset.clMu.Unlock()
 				}
 
+				// Clear batch consumer ack state after processing.
+				if mset.batchAckSequence != nil {
+					mset.clMu.Lock()
+					if key, found := mset.batchAckSequence[lseq]; found {
+						delete(mset.batchAckSequence, lseq)
+						delete(mset.batchAckInProcess, key)
+					}
+					mset.clMu.Unlock()
+				}
+
 				if err != nil {
 					if err == errLastSeqMismatch {
 
@@ -3070,7 +3625,9 @@ func (js *jetStream) applyStreamEntries(mset *stream, ce *CommittedEntry, isReco
 				s, cc := js.server(), js.cluster
 
 				var removed bool
-				if md.NoErase {
+				if md.Compact {
+					removed, err = mset.removeSupersededMsg(md.Seq)
+				} else if md.NoErase {
 					removed, err = mset.removeMsg(md.Seq)
 				} else {
 					removed, err = mset.eraseMsg(md.Seq)
@@ -3090,7 +3647,8 @@ func (js *jetStream) applyStreamEntries(mset *stream, ce *CommittedEntry, isReco
 				isLeader := cc.isStreamLeader(md.Client.serviceAccount(), md.Stream)
 				js.mu.RUnlock()
 
-				if isLeader && !isRecovering {
+				// Removals proposed by the stream itself have no one to respond to.
+				if isLeader && !isRecovering && md.Reply != _EMPTY_ {
 					var resp = JSApiMsgDeleteResponse{ApiResponse: ApiResponse{Type: JSApiMsgDeleteResponseType}}
 					if err != nil {
 						resp.Error = NewJSStreamMsgDeleteFailedError(err, Unless(err))
@@ -3283,6 +3841,9 @@ func (js *jetStream) processStreamLeaderChange(mset *stream, isLeader bool) {
 	// Clear expected per subject state.
 	mset.expectedPerSubjectSequence = nil
 	mset.expectedPerSubjectInProcess = nil
+	// Clear batch consumer acks in process.
+	mset.batchAckSequence = nil
+	mset.batchAckInProcess = nil
 	mset.clMu.Unlock()
 
 	js.mu.Lock()
@@ -3316,6 +3877,11 @@ func (js *jetStream) processStreamLeaderChange(mset *stream, isLeader bool) {
 	// Tell stream to switch leader status.
 	mset.setLeader(isLeader)
 
+	// Send the committed batch consumer acks that could have been lost with the previous leader.
+	if isLeader {
+		mset.resendBatchAcks()
+	}
+
 	if !isLeader || hasResponded {
 		return
 	}
@@ -3454,7 +4020,7 @@ func (js *jetStream) processStreamAssignment(sa *streamAssignment) bool {
 	}
 	var isMember bool
 	if sa.Group != nil && ourID != _EMPTY_ {
-		isMember = sa.Group.isMember(ourID)
+		isMember = sa.Group.isMemberOrLearner(ourID)
 	}
 
 	// Remove this stream from the inflight proposals
@@ -3507,6 +4073,13 @@ func (js *jetStream) processStreamAssignment(sa *streamAssignment) bool {
 
 	var didRemove bool
 
+	// Messages for a partitioned stream are mapped to its partitions on all servers.
+	if sa.Config.Partitioning.isParent() {
+		if err := acc.addPartitionMappings(sa.Config); err != nil {
+			s.Warnf("Could not add partition mappings for '%s > %s': %v", accName, stream, err)
+		}
+	}
+
 	// Check if this is for us..
 	if isMember {
 		js.processClusterCreateStream(acc, sa)
@@ -3548,7 +4121,7 @@ func (js *jetStream) processUpdateStreamAssignment(sa *streamAssignment) {
 
 	var isMember bool
 	if sa.Group != nil {
-		isMember = sa.Group.isMember(ourID)
+		isMember = sa.Group.isMemberOrLearner(ourID)
 	}
 
 	accStreams := cc.streams[accName]
@@ -3595,6 +4168,13 @@ func (js *jetStream) processUpdateStreamAssignment(sa *streamAssignment) {
 		return
 	}
 
+	if sa.Config.Partitioning.isParent() {
+		acc.removePartitionMappings(osa.Config)
+		if err := acc.addPartitionMappings(sa.Config); err != nil {
+			s.Warnf("Could not update partition mappings for '%s > %s': %v", accName, stream, err)
+		}
+	}
+
 	// Check if this is for us..
 	if isMember {
 		js.processClusterUpdateStream(acc, osa, sa)
@@ -3646,7 +4226,7 @@ func (js *jetStream) processClusterUpdateStream(acc *Account, osa, sa *streamAss
 	client, subject, reply := sa.Client, sa.Subject, sa.Reply
 	alreadyRunning, numReplicas := osa.Group.node != nil, len(rg.Peers)
 	needsNode := rg.node == nil
-	storage, cfg := sa.Config.Storage, sa.Config
+	storage, cfg, failover := sa.Config.Storage, sa.Config, sa.Failover
 	hasResponded := sa.responded
 	sa.responded = true
 	recovering := sa.recovering
@@ -3709,7 +4289,7 @@ func (js *jetStream) processClusterUpdateStream(acc *Account, osa, sa *streamAss
 		mset.setStreamAssignment(sa)
 
 		// Call update.
-		if err = mset.updateWithAdvisory(cfg, !recovering, false); err != nil {
+		if err = mset.updateWithFailover(cfg, !recovering, false, failover); err != nil {
 			s.Warnf("JetStream cluster error updating stream %q for account %q: %v", cfg.Name, acc.Name, err)
 		}
 	}
@@ -3757,6 +4337,14 @@ func (js *jetStream) processClusterUpdateStream(acc *Account, osa, sa *streamAss
 		return
 	}
 
+	// A failover is answered with its own response, the origin demoted by it has no requester.
+	if failover != nil {
+		if reply != _EMPTY_ {
+			s.sendAPIResponse(client, acc, subject, reply, _EMPTY_, s.jsonResponse(js.failoverResponse(mset, osa, sa)))
+		}
+		return
+	}
+
 	// Send our response.
 	var resp = JSApiStreamUpdateResponse{ApiResponse: ApiResponse{Type: JSApiStreamUpdateResponseType}}
 	msetCfg := mset.config()
@@ -3784,7 +4372,7 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 	s, rg := js.srv, sa.Group
 	alreadyRunning := rg.node != nil
 	storage := sa.Config.Storage
-	restore := sa.Restore
+	restore, clone := sa.Restore != nil || sa.Clone != nil, sa.Clone != nil
 	recovering := sa.recovering
 	js.mu.RUnlock()
 
@@ -3798,12 +4386,12 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 	// If we are restoring, create the stream if we are R>1 and not the preferred who handles the
 	// receipt of the snapshot itself.
 	shouldCreate := true
-	if restore != nil {
+	if restore {
 		if len(rg.Peers) == 1 || rg.node != nil && rg.node.ID() == rg.Preferred {
 			shouldCreate = false
 		} else {
 			js.mu.Lock()
-			sa.Restore = nil
+			sa.Restore, sa.Clone = nil, nil
 			js.mu.Unlock()
 		}
 	}
@@ -3858,7 +4446,7 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 			// Check if our config has really been updated.
 			cfg := mset.config()
 			if !reflect.DeepEqual(&cfg, sa.Config) {
-				if err = mset.updateWithAdvisory(sa.Config, false, false); err != nil {
+				if err = mset.updateWithFailover(sa.Config, false, false, sa.Failover); err != nil {
 					s.Warnf("JetStream cluster error updating stream %q for account %q: %v", sa.Config.Name, acc.Name, err)
 					if osa != nil {
 						// Process the raft group and make sure it's running if needed.
@@ -3953,9 +4541,14 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 	} else {
 		// Single replica stream, process manually here.
 		// If we are restoring, process that first.
-		if sa.Restore != nil {
-			// We are restoring a stream here.
-			restoreDoneCh := s.processStreamRestore(sa.Client, acc, sa.Config, _EMPTY_, sa.Reply, _EMPTY_)
+		if restore {
+			// We are restoring or cloning a stream here.
+			var restoreDoneCh <-chan error
+			if clone {
+				restoreDoneCh = s.processStreamClone(acc, sa)
+			} else {
+				restoreDoneCh = s.processStreamRestore(sa.Client, acc, sa.Config, _EMPTY_, sa.Reply, _EMPTY_)
+			}
 			s.startGoRoutine(func() {
 				defer s.grWG.Done()
 				select {
@@ -3976,9 +4569,14 @@ func (js *jetStream) processClusterCreateStream(acc *Account, sa *streamAssignme
 						result := &streamAssignmentResult{
 							Account: sa.Client.serviceAccount(),
 							Stream:  sa.Config.Name,
-							Restore: &JSApiStreamRestoreResponse{ApiResponse: ApiResponse{Type: JSApiStreamRestoreResponseType}},
 						}
-						result.Restore.Error = NewJSStreamRestoreError(err, Unless(err))
+						if clone {
+							result.Clone = &JSApiStreamCloneResponse{ApiResponse: ApiResponse{Type: JSApiStreamCloneResponseType}}
+							result.Clone.Error = NewJSStreamCloneError(err, Unless(err))
+						} else {
+							result.Restore = &JSApiStreamRestoreResponse{ApiResponse: ApiResponse{Type: JSApiStreamRestoreResponseType}}
+							result.Restore.Error = NewJSStreamRestoreError(err, Unless(err))
+						}
 						js.mu.Unlock()
 						// Send response to the metadata leader. They will forward to the user as needed.
 						b, _ := json.Marshal(result) // Avoids auto-processing and doing fancy json with newlines.
@@ -4053,13 +4651,17 @@ func (js *jetStream) processStreamRemoval(sa *streamAssignment) {
 		return
 	}
 	stream := sa.Config.Name
-	isMember := sa.Group.isMember(cc.meta.ID())
+	isMember := sa.Group.isMemberOrLearner(cc.meta.ID())
 	wasLeader := cc.isStreamLeader(sa.Client.serviceAccount(), stream)
 
 	// Check if we already have this assigned.
 	accStreams := cc.streams[sa.Client.serviceAccount()]
 	needDelete := accStreams != nil && accStreams[stream] != nil
+	var parent *StreamConfig
 	if needDelete {
+		if osa := accStreams[stream]; osa.Config.Partitioning.isParent() {
+			parent = osa.Config
+		}
 		delete(accStreams, stream)
 		if len(accStreams) == 0 {
 			delete(cc.streams, sa.Client.serviceAccount())
@@ -4067,6 +4669,11 @@ func (js *jetStream) processStreamRemoval(sa *streamAssignment) {
 	}
 	js.mu.Unlock()
 
+	if parent != nil {
+		if acc, err := s.LookupAccount(sa.Client.serviceAccount()); err == nil {
+			acc.removePartitionMappings(parent)
+		}
+	}
 	if needDelete {
 		js.processClusterDeleteStream(sa, isMember, wasLeader)
 	}
@@ -5128,7 +5735,11 @@ func (js *jetStream) applyConsumerEntries(o *consumer, ce *CommittedEntry, isLea
 				if o.store != nil {
 					o.store.UpdateStarting(sseq - 1)
 				}
+				mset, filtered := o.mset, o.isFilteredSkip(sseq-1)
 				o.mu.Unlock()
+				if filtered {
+					mset.ackMsg(o, sseq-1)
+				}
 			case addPendingRequest:
 				o.mu.Lock()
 				if !o.isLeader() {
@@ -5403,6 +6014,7 @@ type streamAssignmentResult struct {
 	Stream   string                      `json:"stream"`
 	Response *JSApiStreamCreateResponse  `json:"create_response,omitempty"`
 	Restore  *JSApiStreamRestoreResponse `json:"restore_response,omitempty"`
+	Clone    *JSApiStreamCloneResponse   `json:"clone_response,omitempty"`
 	Update   bool                        `json:"is_update,omitempty"`
 }
 
@@ -5480,6 +6092,8 @@ func (js *jetStream) processStreamAssignmentResults(sub *subscription, c *client
 			resp = s.jsonResponse(result.Response)
 		} else if result.Restore != nil {
 			resp = s.jsonResponse(result.Restore)
+		} else if result.Clone != nil {
+			resp = s.jsonResponse(result.Clone)
 		}
 		if !sa.responded || result.Update {
 			sa.responded = true
@@ -6138,8 +6752,13 @@ func tieredStreamAndReservationCount(asa map[string]*streamAssignment, tier stri
 	for _, sa := range asa {
 		// Don't count the stream toward the limit if it already exists.
 		if (tier == _EMPTY_ || isSameTier(sa.Config, cfg)) && sa.Config.Name != cfg.Name {
+			// Partitions of the same stream are counted below.
+			if p := sa.Config.Partitioning; p.isPartition() && p.Stream == cfg.Name {
+				continue
+			}
 			numStreams++
-			if sa.Config.MaxBytes > 0 && sa.Config.Storage == cfg.Storage {
+			// Partitioned streams hold no messages, their partitions carry the reservation.
+			if sa.Config.MaxBytes > 0 && sa.Config.Storage == cfg.Storage && !sa.Config.Partitioning.isParent() {
 				// If tier is empty, all storage is flat and we should adjust for replicas.
 				// Otherwise if tiered, storage replication already taken into consideration.
 				if tier == _EMPTY_ && cfg.Replicas > 1 {
@@ -6150,6 +6769,9 @@ func tieredStreamAndReservationCount(asa map[string]*streamAssignment, tier stri
 			}
 		}
 	}
+	if p := cfg.Partitioning; p.isParent() {
+		numStreams += p.Partitions
+	}
 	return numStreams, reservation
 }
 
@@ -6181,11 +6803,40 @@ func (js *jetStream) createGroupForStream(ci *ClientInfo, cfg *StreamConfig) (*r
 			errs.accumulate(err)
 			continue
 		}
-		return &raftGroup{Name: groupNameForStream(peers, cfg.Storage), Storage: cfg.Storage, Peers: peers, Cluster: cn}, nil
+		rg := &raftGroup{Name: groupNameForStream(peers, cfg.Storage), Storage: cfg.Storage, Peers: peers, Cluster: cn}
+		if cfg.Placement != nil && cfg.Placement.Learners != nil {
+			learners, err := cc.selectLearnerGroup(cn, cfg, peers)
+			if err != nil {
+				errs.accumulate(err)
+				continue
+			}
+			rg.Learners = learners
+		}
+		return rg, nil
 	}
 	return nil, errs
 }
 
+// selectLearnerGroup selects the servers for the learners of a stream. These default
+// to the cluster of the voters, but are never placed on the same servers.
+// Lock should be held.
+func (cc *jetStreamCluster) selectLearnerGroup(cluster string, cfg *StreamConfig, peers []string) ([]string, *selectPeerError) {
+	lp := cfg.Placement.Learners
+	if lp.Cluster != _EMPTY_ {
+		cluster = lp.Cluster
+	}
+	lcfg := *cfg
+	lcfg.Placement = &Placement{Cluster: cluster, Tags: lp.Tags}
+	learners, err := cc.selectPeerGroup(lp.Replicas, cluster, &lcfg, nil, 0, peers)
+	if len(learners) < lp.Replicas {
+		if err == nil {
+			err = &selectPeerError{misc: true}
+		}
+		return nil, err
+	}
+	return learners, nil
+}
+
 func (acc *Account) selectLimits(replicas int) (*JetStreamAccountLimits, string, *jsAccount, *ApiError) {
 	// Grab our jetstream account info.
 	acc.mu.RLock()
@@ -6282,8 +6933,15 @@ func (s *Server) jsClusteredStreamRequest(ci *ClientInfo, acc *Account, subject,
 		return
 	}
 
+	// The streams backing partitions are only created through their partitioned stream.
+	if cfg.Partitioning.isPartition() && self == nil {
+		resp.Error = NewJSStreamInvalidConfigError(fmt.Errorf("stream partition can not be created directly"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
 	// Check for subject collisions here.
-	if cc.subjectsOverlap(acc.Name, cfg.Subjects, self) {
+	if cc.subjectsOverlap(acc.Name, cfg.Subjects, self, cfg.partitionedStream()) {
 		resp.Error = NewJSStreamSubjectOverlapError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
 		return
@@ -6331,6 +6989,16 @@ func (s *Server) jsClusteredStreamRequest(ci *ClientInfo, acc *Account, subject,
 	if syncSubject == _EMPTY_ {
 		syncSubject = syncSubjForStream()
 	}
+
+	// Partitioned streams are backed by a stream per partition, these are proposed first.
+	if cfg.Partitioning.isParent() && self == nil {
+		if apiErr := js.proposePartitionStreams(ci, acc, cfg); apiErr != nil {
+			resp.Error = apiErr
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
+	}
+
 	// Sync subject for post snapshot sync.
 	sa := &streamAssignment{Group: rg, Sync: syncSubject, Config: cfg, Subject: subject, Reply: reply, Client: ci, Created: time.Now().UTC()}
 	if err := cc.meta.Propose(encodeAddStreamAssignment(sa)); err == nil {
@@ -6420,6 +7088,13 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 		return
 	}
 
+	// The streams backing partitions are updated through their partitioned stream.
+	if p := osa.Config.Partitioning; p.isPartition() {
+		resp.Error = NewJSStreamUpdateError(fmt.Errorf("stream is a partition of %q", p.Stream))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
 	// Don't allow updating if all peers are offline.
 	if s.allPeersOffline(osa.Group) {
 		resp.Error = NewJSStreamOfflineError()
@@ -6455,12 +7130,37 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 	}
 
 	// Check for subject collisions here.
-	if cc.subjectsOverlap(acc.Name, cfg.Subjects, osa) {
+	if cc.subjectsOverlap(acc.Name, cfg.Subjects, osa, cfg.partitionedStream()) {
 		resp.Error = NewJSStreamSubjectOverlapError()
 		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
 		return
 	}
 
+	// Learners are only changed through promotion. A config from before learners were
+	// promoted, where the extra learners match the extra voters we have now, is kept as is.
+	var olp, nlp *LearnerPlacement
+	if osa.Config.Placement != nil {
+		olp = osa.Config.Placement.Learners
+	}
+	if newCfg.Placement != nil {
+		nlp = newCfg.Placement.Learners
+	}
+	if !reflect.DeepEqual(olp, nlp) {
+		promoted := osa.Config.Replicas - newCfg.Replicas
+		if nlp == nil || promoted <= 0 || nlp.Replicas-len(osa.Group.Learners) != promoted ||
+			olp != nil && (olp.Cluster != nlp.Cluster || !slices.Equal(olp.Tags, nlp.Tags)) {
+			resp.Error = NewJSStreamUpdateError(errors.New("stream learners can not be updated"))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
+		newCfg.Replicas = osa.Config.Replicas
+		newCfg.Placement.Learners = nil
+		if olp != nil {
+			lp := *olp
+			newCfg.Placement.Learners = &lp
+		}
+	}
+
 	// Make copy so to not change original.
 	rg := osa.copyGroup().Group
 
@@ -6523,6 +7223,13 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 		return
 	}
 
+	// Partitioned streams can not be moved or scaled for now.
+	if newCfg.Partitioning.isParent() && (isMoveRequest || isReplicaChange) {
+		resp.Error = NewJSStreamUpdateError(fmt.Errorf("partitioned streams can not be moved or scaled"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
 	// Can not move and scale at same time.
 	if isMoveRequest && isReplicaChange {
 		resp.Error = NewJSStreamMoveAndScaleError()
@@ -6554,7 +7261,7 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 					rg.Cluster = ci.Cluster
 				}
 			}
-			peers, err := cc.selectPeerGroup(newCfg.Replicas, rg.Cluster, newCfg, rg.Peers, 0, nil)
+			peers, err := cc.selectPeerGroup(newCfg.Replicas, rg.Cluster, newCfg, rg.Peers, 0, rg.Learners)
 			if err != nil {
 				resp.Error = NewJSClusterNoPeersError(err)
 				s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
@@ -6610,8 +7317,11 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 			rg.Peers = selected
 		}
 
-		// Need to remap any consumers.
+		// Need to remap any consumers, consumers on learners are not affected.
 		for _, ca := range osa.consumers {
+			if ca.Config.OnLearners {
+				continue
+			}
 			// Legacy ephemerals are R=1 but present as R=0, so only auto-remap named consumers, or if we are downsizing the consumer peers.
 			// If stream is interest or workqueue policy always remaps since they require peer parity with stream.
 			numPeers := len(ca.Group.Peers)
@@ -6717,6 +7427,9 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 		rg.Peers = peerSet
 
 		for _, ca := range osa.consumers {
+			if ca.Config.OnLearners {
+				continue
+			}
 			cca := ca.copyGroup()
 			r := cca.Config.replicas(osa.Config)
 			// shuffle part of cluster peer set we will be keeping
@@ -6766,6 +7479,11 @@ func (s *Server) jsClusteredStreamUpdateRequest(ci *ClientInfo, acc *Account, su
 		rg.Preferred = _EMPTY_
 	}
 
+	// Partitions are updated first, so they are up to date once we respond.
+	if newCfg.Partitioning.isParent() {
+		js.proposePartitionStreamsUpdate(ci, acc, newCfg)
+	}
+
 	sa := &streamAssignment{Group: rg, Sync: osa.Sync, Created: osa.Created, Config: newCfg, Subject: subject, Reply: reply, Client: ci}
 	meta.Propose(encodeUpdateStreamAssignment(sa))
 
@@ -6796,6 +7514,16 @@ func (s *Server) jsClusteredStreamDeleteRequest(ci *ClientInfo, acc *Account, st
 		return
 	}
 
+	// The streams backing partitions are deleted with their partitioned stream.
+	if p := osa.Config.Partitioning; p.isPartition() {
+		var resp = JSApiStreamDeleteResponse{ApiResponse: ApiResponse{Type: JSApiStreamDeleteResponseType}}
+		resp.Error = NewJSStreamDeleteError(fmt.Errorf("stream is a partition of %q", p.Stream))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	} else if p.isParent() {
+		js.proposePartitionStreamsDelete(ci, acc, osa.Config)
+	}
+
 	sa := &streamAssignment{Group: osa.Group, Config: osa.Config, Subject: subject, Reply: reply, Client: ci}
 	cc.meta.Propose(encodeDeleteStreamAssignment(sa))
 }
@@ -6895,6 +7623,106 @@ func (s *Server) jsClusteredStreamRestoreRequest(
 	cc.meta.Propose(encodeAddStreamAssignment(sa))
 }
 
+func (s *Server) jsClusteredStreamCloneRequest(
+	ci *ClientInfo,
+	acc *Account,
+	stream string,
+	req *JSApiStreamCloneRequest,
+	cfg *StreamConfig,
+	subject, reply string, rmsg []byte) {
+
+	js, cc := s.getJetStreamCluster()
+	if js == nil || cc == nil {
+		return
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if cc.meta == nil {
+		return
+	}
+
+	resp := JSApiStreamCloneResponse{ApiResponse: ApiResponse{Type: JSApiStreamCloneResponseType}}
+
+	osa := js.streamAssignment(ci.serviceAccount(), stream)
+	if osa == nil {
+		resp.Error = NewJSStreamNotFoundError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	// Partitioned streams hold no messages themselves.
+	if osa.Config.Partitioning.isParent() {
+		resp.Error = NewJSStreamCloneError(errors.New("partitioned streams can not be cloned"))
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
+	if err := js.jsClusteredStreamLimitsCheck(acc, cfg); err != nil {
+		resp.Error = err
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
+	if sa := js.streamAssignment(ci.serviceAccount(), cfg.Name); sa != nil {
+		resp.Error = NewJSStreamNameExistError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
+	if cc.subjectsOverlap(acc.Name, cfg.Subjects, nil, cfg.partitionedStream()) {
+		resp.Error = NewJSStreamSubjectOverlapError()
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+
+	rg, apiErr := js.createGroupForClone(ci, cfg, osa.Group)
+	if apiErr != nil {
+		resp.Error = apiErr
+		s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+		return
+	}
+	sa := &streamAssignment{Group: rg, Sync: syncSubjForStream(), Config: cfg, Subject: subject, Reply: reply, Client: ci, Created: time.Now().UTC()}
+	// The preferred peer will seed the clone from its local replica of the source.
+	sa.Clone = &streamClone{Stream: stream, UpToSeq: req.UpToSeq, UpToTime: req.UpToTime, Consumers: req.Consumers}
+	cc.meta.Propose(encodeAddStreamAssignment(sa))
+}
+
+// createGroupForClone will create a group for a stream clone. The preferred peer, which seeds
+// the clone, needs to host the source stream. Without a placement we keep to the source peers.
+// Lock should be held.
+func (js *jetStream) createGroupForClone(ci *ClientInfo, cfg *StreamConfig, srg *raftGroup) (*raftGroup, *ApiError) {
+	var rg *raftGroup
+	if cfg.Placement == nil {
+		replicas := max(cfg.Replicas, 1)
+		peers, err := js.cluster.selectPeerGroup(replicas, srg.Cluster, cfg, slices.Clone(srg.Peers), 0, nil)
+		if err != nil {
+			return nil, NewJSClusterNoPeersError(err)
+		}
+		rg = &raftGroup{Name: groupNameForStream(peers, cfg.Storage), Storage: cfg.Storage, Peers: peers, Cluster: srg.Cluster}
+	} else {
+		var err *selectPeerError
+		if rg, err = js.createGroupForStream(ci, cfg); err != nil {
+			return nil, NewJSClusterNoPeersError(err)
+		}
+	}
+
+	// Pick an online peer hosting the source as preferred.
+	for _, peer := range rg.Peers {
+		if !slices.Contains(srg.Peers, peer) {
+			continue
+		}
+		if si, ok := js.srv.nodeToInfo.Load(peer); ok && si != nil && !si.(nodeInfo).offline {
+			rg.Preferred = peer
+			break
+		}
+	}
+	if rg.Preferred == _EMPTY_ {
+		return nil, NewJSStreamCloneNotLocalError()
+	}
+	return rg, nil
+}
+
 // Determine if all peers for this group are offline.
 func (s *Server) allPeersOffline(rg *raftGroup) bool {
 	if rg == nil {
@@ -7263,6 +8091,9 @@ func (s *Server) jsClusteredConsumerDeleteRequest(ci *ClientInfo, acc *Account,
 		return
 	}
 	oca.deleted = true
+	if sa.Config.Partitioning.isParent() {
+		js.proposePartitionConsumersDelete(ci, acc, sa, consumer)
+	}
 	ca := &consumerAssignment{Group: oca.Group, Stream: stream, Name: consumer, Config: oca.Config, Subject: subject, Reply: reply, Client: ci}
 	cc.meta.Propose(encodeDeleteConsumerAssignment(ca))
 }
@@ -7379,12 +8210,17 @@ func decodeDeleteRange(buf []byte) (*DeleteRange, error) {
 }
 
 // createGroupForConsumer will create a new group from same peer set as the stream.
+// Consumers on learners use the learners of the stream instead.
 func (cc *jetStreamCluster) createGroupForConsumer(cfg *ConsumerConfig, sa *streamAssignment) *raftGroup {
-	if len(sa.Group.Peers) == 0 || cfg.Replicas > len(sa.Group.Peers) {
+	peers := sa.Group.Peers
+	if cfg.OnLearners {
+		peers = sa.Group.Learners
+	}
+	if len(peers) == 0 || cfg.Replicas > len(peers) {
 		return nil
 	}
 
-	peers := copyStrings(sa.Group.Peers)
+	peers = copyStrings(peers)
 	var _ss [5]string
 	active := _ss[:0]
 
@@ -7690,6 +8526,19 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 		rBefore := nca.Config.replicas(sa.Config)
 		rAfter := cfg.replicas(sa.Config)
 
+		// Consumers on partitioned streams can not be scaled for now.
+		if rBefore != rAfter && sa.Config.Partitioning.isParent() {
+			resp.Error = NewJSConsumerCreateError(fmt.Errorf("consumers on partitioned streams can not be scaled"))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
+		// Neither can consumers on learners.
+		if rBefore != rAfter && cfg.OnLearners {
+			resp.Error = NewJSConsumerCreateError(fmt.Errorf("consumers on learners can not be scaled"))
+			s.sendAPIErrResponse(ci, acc, subject, reply, string(rmsg), s.jsonResponse(&resp))
+			return
+		}
+
 		var curLeader string
 		if rBefore != rAfter {
 			// We are modifying nodes here. We want to do our best to preserve the current leader.
@@ -7758,6 +8607,11 @@ func (s *Server) jsClusteredConsumerRequest(ci *ClientInfo, acc *Account, subjec
 		ca = nca
 	}
 
+	// Consumers on partitioned streams are backed by a consumer on each partition, these are proposed first.
+	if sa.Config.Partitioning.isParent() {
+		js.proposePartitionConsumers(ci, acc, sa, ca)
+	}
+
 	// Do formal proposal.
 	if err := cc.meta.Propose(encodeAddConsumerAssignment(ca)); err == nil {
 		// Mark this as pending.
@@ -8021,6 +8875,7 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 	s, js, jsa, st, r, tierName, outq, node := mset.srv, mset.js, mset.jsa, mset.cfg.Storage, mset.cfg.Replicas, mset.tier, mset.outq, mset.node
 	maxMsgSize, lseq := int(mset.cfg.MaxMsgSize), mset.lseq
 	isLeader, isSealed, allowTTL, allowMsgCounter, allowAtomicPublish := mset.isLeader(), mset.cfg.Sealed, mset.cfg.AllowMsgTTL, mset.cfg.AllowMsgCounter, mset.cfg.AllowAtomicPublish
+	allowMsgSchedules, schema := mset.cfg.AllowMsgSchedules, mset.schema
 	mset.mu.RUnlock()
 
 	// This should not happen but possible now that we allow scale up, and scale down where this could trigger.
@@ -8194,6 +9049,21 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 
 		// Proceed with proposing the batch.
 
+		// The commit can carry a consumer ack, which is only applied if the batch is committed.
+		var ack *batchAck
+		if ackSubj := getBatchAck(hdr); ackSubj != _EMPTY_ {
+			var apiErr *ApiError
+			if ack, apiErr = checkBatchAckPreClusteredProposal(mset, jsa, ackSubj); apiErr != nil {
+				cleanup()
+				batches.mu.Unlock()
+				if canRespond {
+					buf, _ := json.Marshal(&JSPubAckResponse{PubAck: &PubAck{Stream: name}, Error: apiErr})
+					outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, nil, buf, nil, 0))
+				}
+				return apiErr
+			}
+		}
+
 		// We only use mset.clseq for clustering and in case we run ahead of actual commits.
 		// Check if we need to set initial value here
 		mset.clMu.Lock()
@@ -8250,7 +9120,7 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 			}
 
 			var apiErr *ApiError
-			if bhdr, bmsg, _, apiErr, err = checkMsgHeadersPreClusteredProposal(mset, subject, bhdr, bmsg, sourced, name, jsa, allowTTL, allowMsgCounter, stype, store, interestPolicy, discard, maxMsgs, maxBytes); err != nil {
+			if bhdr, bmsg, _, apiErr, err = checkMsgHeadersPreClusteredProposal(mset, subject, bhdr, bmsg, sourced, name, jsa, allowTTL, allowMsgCounter, allowMsgSchedules, schema, stype, store, interestPolicy, discard, maxMsgs, maxBytes); err != nil {
 				// TODO(mvv): reset in-memory expected header maps
 				mset.clseq -= seq - 1
 				mset.clMu.Unlock()
@@ -8263,6 +9133,24 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 				return err
 			}
 
+			// Only the commit can carry a consumer ack, stage it so it can't be committed twice.
+			if seq != batchSeq && getBatchAck(bhdr) != _EMPTY_ {
+				apiErr = NewJSAtomicPublishInvalidBatchAckError(errors.New("ack must be on the batch commit"))
+			} else if seq == batchSeq && ack != nil {
+				apiErr = mset.stageBatchAck(ack, mset.clseq)
+			}
+			if apiErr != nil {
+				mset.clseq -= seq - 1
+				mset.clMu.Unlock()
+				cleanup()
+				batches.mu.Unlock()
+				if canRespond {
+					buf, _ := json.Marshal(&JSPubAckResponse{PubAck: &PubAck{Stream: name}, Error: apiErr})
+					outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, nil, buf, nil, 0))
+				}
+				return apiErr
+			}
+
 			var _reply string
 			if seq == batchSeq {
 				_reply = reply
@@ -8280,6 +9168,9 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 		} else {
 			// TODO(mvv): reset in-memory expected header maps
 			mset.clseq -= batchSeq
+			if ack != nil {
+				mset.unstageBatchAck(mset.clseq + batchSeq - 1)
+			}
 		}
 
 		// Check to see if we are being overrun.
@@ -8310,7 +9201,7 @@ func (mset *stream) processClusteredInboundMsg(subject, reply string, hdr, msg [
 		apiErr *ApiError
 		err    error
 	)
-	if hdr, msg, dseq, apiErr, err = checkMsgHeadersPreClusteredProposal(mset, subject, hdr, msg, sourced, name, jsa, allowTTL, allowMsgCounter, stype, store, interestPolicy, discard, maxMsgs, maxBytes); err != nil {
+	if hdr, msg, dseq, apiErr, err = checkMsgHeadersPreClusteredProposal(mset, subject, hdr, msg, sourced, name, jsa, allowTTL, allowMsgCounter, allowMsgSchedules, schema, stype, store, interestPolicy, discard, maxMsgs, maxBytes); err != nil {
 		// TODO(mvv): reset in-memory expected header maps
 		mset.clMu.Unlock()
 		if err == errMsgIdDuplicate && dseq > 0 {
@@ -8909,6 +9800,11 @@ func (mset *stream) processCatchupMsg(msg []byte) (uint64, error) {
 		return 0, err
 	}
 
+	// Track the consumer ack the batch commit carried, in case the consumer did not apply it yet.
+	if ackSubj := getBatchAck(hdr); ackSubj != _EMPTY_ && getBatchId(hdr) != _EMPTY_ {
+		mset.applyBatchAck(ackSubj, false)
+	}
+
 	mset.mu.Lock()
 	defer mset.mu.Unlock()
 	// Update our lseq.
@@ -8981,44 +9877,54 @@ func (js *jetStream) clusterInfo(rg *raftGroup) *ClusterInfo {
 		id = _EMPTY_
 	}
 
+	peerInfo := func(rp *Peer) *PeerInfo {
+		var lastSeen time.Duration
+		if now.After(rp.Last) && !rp.Last.IsZero() {
+			lastSeen = now.Sub(rp.Last)
+		}
+		current := rp.Current
+		if current && lastSeen > lostQuorumInterval {
+			current = false
+		}
+		// Create a peer info with common settings if the peer has not been seen
+		// yet (which can happen after the whole cluster is stopped and only some
+		// of the nodes are restarted).
+		pi := &PeerInfo{
+			Current: current,
+			Offline: true,
+			Active:  lastSeen,
+			Lag:     rp.Lag,
+			Peer:    rp.ID,
+		}
+		// If node is found, complete/update the settings.
+		if sir, ok := s.nodeToInfo.Load(rp.ID); ok && sir != nil {
+			si := sir.(nodeInfo)
+			pi.Name, pi.Offline, pi.cluster = si.name, si.offline, si.cluster
+		} else {
+			// If not, then add a name that indicates that the server name
+			// is unknown at this time, and clear the lag since it is misleading
+			// (the node may not have that much lag).
+			// Note: We return now the Peer ID in PeerInfo, so the "(peerID: %s)"
+			// would technically not be required, but keeping it for now.
+			pi.Name, pi.Lag = fmt.Sprintf("Server name unknown at this time (peerID: %s)", rp.ID), 0
+		}
+		return pi
+	}
+
 	for _, rp := range peers {
 		if rp.ID != id && rg.isMember(rp.ID) {
-			var lastSeen time.Duration
-			if now.After(rp.Last) && !rp.Last.IsZero() {
-				lastSeen = now.Sub(rp.Last)
-			}
-			current := rp.Current
-			if current && lastSeen > lostQuorumInterval {
-				current = false
-			}
-			// Create a peer info with common settings if the peer has not been seen
-			// yet (which can happen after the whole cluster is stopped and only some
-			// of the nodes are restarted).
-			pi := &PeerInfo{
-				Current: current,
-				Offline: true,
-				Active:  lastSeen,
-				Lag:     rp.Lag,
-				Peer:    rp.ID,
-			}
-			// If node is found, complete/update the settings.
-			if sir, ok := s.nodeToInfo.Load(rp.ID); ok && sir != nil {
-				si := sir.(nodeInfo)
-				pi.Name, pi.Offline, pi.cluster = si.name, si.offline, si.cluster
-			} else {
-				// If not, then add a name that indicates that the server name
-				// is unknown at this time, and clear the lag since it is misleading
-				// (the node may not have that much lag).
-				// Note: We return now the Peer ID in PeerInfo, so the "(peerID: %s)"
-				// would technically not be required, but keeping it for now.
-				pi.Name, pi.Lag = fmt.Sprintf("Server name unknown at this time (peerID: %s)", rp.ID), 0
-			}
-			ci.Replicas = append(ci.Replicas, pi)
+			ci.Replicas = append(ci.Replicas, peerInfo(rp))
+		}
+	}
+	for _, rp := range n.Learners() {
+		if rg.isLearner(rp.ID) {
+			ci.Learners = append(ci.Learners, peerInfo(rp))
 		}
 	}
 	// Order the result based on the name so that we get something consistent
 	// when doing repeated stream info in the CLI, etc...
 	slices.SortFunc(ci.Replicas, func(i, j *PeerInfo) int { return cmp.Compare(i.Name, j.Name) })
+	slices.SortFunc(ci.Learners, func(i, j *PeerInfo) int { return cmp.Compare(i.Name, j.Name) })
 	return ci
 }
 
@@ -9107,13 +10013,16 @@ func (mset *stream) processClusterStreamInfoRequest(reply string) {
 	}
 
 	si := &StreamInfo{
-		Created:   mset.createdTime(),
-		State:     mset.state(),
-		Config:    config,
-		Cluster:   js.clusterInfo(mset.raftGroup()),
-		Sources:   mset.sourcesInfo(),
-		Mirror:    mset.mirrorInfo(),
-		TimeStamp: time.Now().UTC(),
+		Created:    mset.createdTime(),
+		State:      mset.state(),
+		Config:     config,
+		Cluster:    js.clusterInfo(mset.raftGroup()),
+		Sources:    mset.sourcesInfo(),
+		Mirror:     mset.mirrorInfo(),
+		Tiering:    mset.tieringInfo(),
+		Compaction: mset.compactionInfo(),
+		Schema:     mset.schemaInfo(),
+		TimeStamp:  time.Now().UTC(),
 	}
 
 	// Check for out of band catchups.
@@ -9121,6 +10030,11 @@ func (mset *stream) processClusterStreamInfoRequest(reply string) {
 		mset.checkClusterInfo(si.Cluster)
 	}
 
+	// Partitioned streams report the state of their partitions.
+	if config.Partitioning.isParent() {
+		mset.srv.addPartitionsInfo(mset.accName(), si)
+	}
+
 	sysc.sendInternalMsg(reply, _EMPTY_, nil, si)
 }
 
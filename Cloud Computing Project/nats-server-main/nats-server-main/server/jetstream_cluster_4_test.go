@@ -16,6 +16,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"encoding/json"
@@ -6623,3 +6624,648 @@ func TestJetStreamClusterSDMMaxAgeProposeExpiryShortRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestJetStreamClusterStreamLearners(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R4S", 4)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:        "TEST",
+		Subjects:    []string{"foo"},
+		Storage:     FileStorage,
+		Replicas:    3,
+		AllowDirect: true,
+		Placement:   &Placement{Learners: &LearnerPlacement{Replicas: 1}},
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	sl := c.streamLeader(globalAccountName, "TEST")
+	sa := sl.getJetStream().streamAssignment(globalAccountName, "TEST")
+	require_NotNil(t, sa)
+	sl.getJetStream().mu.RLock()
+	peers, learners := copyStrings(sa.Group.Peers), copyStrings(sa.Group.Learners)
+	sl.getJetStream().mu.RUnlock()
+	require_Len(t, len(peers), 3)
+	require_Len(t, len(learners), 1)
+	require_False(t, slices.Contains(peers, learners[0]))
+
+	var ls *Server
+	for _, s := range c.servers {
+		if s.NodeName() == learners[0] {
+			ls = s
+		}
+	}
+	require_NotNil(t, ls)
+
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	// The learner has its own copy and serves direct gets.
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		mset, err := ls.globalAccount().lookupStream("TEST")
+		if err != nil {
+			return err
+		}
+		if state := mset.state(); state.Msgs != 10 {
+			return fmt.Errorf("expected 10 messages on learner, got %d", state.Msgs)
+		}
+		mset.mu.RLock()
+		hasDirect := mset.directSub != nil
+		mset.mu.RUnlock()
+		if !hasDirect {
+			return errors.New("learner not subscribed for direct gets")
+		}
+		return nil
+	})
+	mset, err := ls.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_True(t, mset.raftNode().IsLearner())
+
+	streamInfo := func() *StreamInfo {
+		t.Helper()
+		msg, err := nc.Request(fmt.Sprintf(JSApiStreamInfoT, "TEST"), nil, time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamInfoResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		require_True(t, resp.Error == nil)
+		return resp.StreamInfo
+	}
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		ci := streamInfo().Cluster
+		if len(ci.Replicas) != 2 || len(ci.Learners) != 1 {
+			return fmt.Errorf("expected 2 replicas and 1 learner, got %d and %d", len(ci.Replicas), len(ci.Learners))
+		}
+		if l := ci.Learners[0]; l.Name != ls.Name() || !l.Current {
+			return fmt.Errorf("unexpected learner info: %+v", l)
+		}
+		return nil
+	})
+
+	// Learners can only be changed through promotion.
+	_, err = jsStreamUpdate(t, nc, &StreamConfig{
+		Name:      "TEST",
+		Subjects:  []string{"foo"},
+		Storage:   FileStorage,
+		Replicas:  3,
+		Placement: &Placement{Learners: &LearnerPlacement{Replicas: 2}},
+	})
+	require_Error(t, err)
+
+	promote := func(peer string) *JSApiStreamPromotePeerResponse {
+		t.Helper()
+		req, err := json.Marshal(&JSApiStreamPromotePeerRequest{Peer: peer})
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiStreamPromotePeerT, "TEST"), req, time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamPromotePeerResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+	resp := promote(sl.Name())
+	require_Error(t, resp.Error, NewJSClusterPeerNotLearnerError())
+
+	resp = promote(ls.Name())
+	require_True(t, resp.Error == nil)
+	require_True(t, resp.Success)
+
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		if mset.raftNode().IsLearner() {
+			return errors.New("still a learner")
+		}
+		si := streamInfo()
+		if si.Config.Replicas != 4 || si.Config.Placement != nil && si.Config.Placement.Learners != nil {
+			return fmt.Errorf("unexpected config after promotion: %+v", si.Config)
+		}
+		if ci := si.Cluster; len(ci.Replicas) != 3 || len(ci.Learners) != 0 {
+			return fmt.Errorf("expected 3 replicas and no learners, got %d and %d", len(ci.Replicas), len(ci.Learners))
+		}
+		return nil
+	})
+	sl = c.streamLeader(globalAccountName, "TEST")
+	mset, err = sl.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_Equal(t, mset.raftNode().ClusterSize(), 4)
+
+	// The original config can still be used for updates and keeps the promoted voter.
+	cfg, err := jsStreamUpdate(t, nc, &StreamConfig{
+		Name:        "TEST",
+		Subjects:    []string{"foo"},
+		Storage:     FileStorage,
+		Replicas:    3,
+		AllowDirect: true,
+		Placement:   &Placement{Learners: &LearnerPlacement{Replicas: 1}},
+		Description: "updated",
+	})
+	require_NoError(t, err)
+	require_Equal(t, cfg.Replicas, 4)
+	require_Equal(t, cfg.Description, "updated")
+	require_True(t, cfg.Placement == nil || cfg.Placement.Learners == nil)
+
+	pa, err := js.Publish("foo", nil)
+	require_NoError(t, err)
+	require_Equal(t, pa.Sequence, 11)
+}
+
+func TestJetStreamClusterStreamLearnersConsumers(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R4S", 4)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:      "TEST",
+		Subjects:  []string{"foo"},
+		Storage:   FileStorage,
+		Replicas:  3,
+		Placement: &Placement{Learners: &LearnerPlacement{Replicas: 1}},
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	// Can not have more replicas than learners.
+	_, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST",
+		Config: ConsumerConfig{Durable: "BAD", AckPolicy: AckExplicit, OnLearners: true, Replicas: 2}})
+	require_Error(t, apiErr, NewJSConsumerReplicasExceedsStreamError())
+
+	_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST",
+		Config: ConsumerConfig{Durable: "DUR", AckPolicy: AckExplicit, OnLearners: true}})
+	require_True(t, apiErr == nil)
+	c.waitOnConsumerLeader(globalAccountName, "TEST", "DUR")
+
+	// The consumer is placed on the learner and delivers from its local copy.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	sjs := sl.getJetStream()
+	sjs.mu.RLock()
+	sa := sjs.streamAssignment(globalAccountName, "TEST")
+	learners, peers := copyStrings(sa.Group.Learners), copyStrings(sa.consumers["DUR"].Group.Peers)
+	sjs.mu.RUnlock()
+	require_Len(t, len(learners), 1)
+	require_Equal(t, strings.Join(peers, ","), learners[0])
+	cl := c.consumerLeader(globalAccountName, "TEST", "DUR")
+	require_Equal(t, cl.NodeName(), learners[0])
+
+	sub, err := js.PullSubscribe(_EMPTY_, "DUR", nats.Bind("TEST", "DUR"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(10)
+	require_NoError(t, err)
+	require_Len(t, len(msgs), 10)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+	ci, err := js.ConsumerInfo("TEST", "DUR")
+	require_NoError(t, err)
+	require_Equal(t, ci.AckFloor.Stream, 10)
+
+	// Can not be moved off the learners.
+	_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST",
+		Config: ConsumerConfig{Durable: "DUR", AckPolicy: AckExplicit}})
+	require_NotNil(t, apiErr)
+
+	// Requires a stream with learners.
+	_, err = jsStreamCreate(t, nc, &StreamConfig{Name: "NOL", Subjects: []string{"bar"}, Storage: FileStorage, Replicas: 3})
+	require_NoError(t, err)
+	_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "NOL",
+		Config: ConsumerConfig{Durable: "DUR", AckPolicy: AckExplicit, OnLearners: true}})
+	require_NotNil(t, apiErr)
+}
+
+func TestJetStreamClusterStreamLearnersInvalidConfig(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	for _, test := range []struct {
+		name     string
+		replicas int
+		learners int
+	}{
+		{"R1", 1, 1},
+		{"negative", 3, -1},
+		{"too many", 3, StreamMaxReplicas + 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := jsStreamCreate(t, nc, &StreamConfig{
+				Name:      "TEST",
+				Subjects:  []string{"foo"},
+				Storage:   FileStorage,
+				Replicas:  test.replicas,
+				Placement: &Placement{Learners: &LearnerPlacement{Replicas: test.learners}},
+			})
+			require_Error(t, err)
+		})
+	}
+
+	// No servers left for the learner.
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:      "TEST",
+		Subjects:  []string{"foo"},
+		Storage:   FileStorage,
+		Replicas:  3,
+		Placement: &Placement{Learners: &LearnerPlacement{Replicas: 1}},
+	})
+	require_Error(t, err)
+}
+
+func TestJetStreamClusterLinearizableGet(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{
+		Name:        "TEST",
+		Subjects:    []string{"foo"},
+		Replicas:    3,
+		AllowDirect: true,
+	})
+	require_NoError(t, err)
+	_, err = js.Publish("foo", []byte("1"))
+	require_NoError(t, err)
+	c.waitOnAllCurrent()
+
+	fs := c.randomNonStreamLeader(globalAccountName, "TEST")
+	mset, err := fs.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	fn := mset.raftNode()
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		mset.mu.RLock()
+		defer mset.mu.RUnlock()
+		if mset.directSub == nil {
+			return errors.New("follower not subscribed for direct gets")
+		}
+		return nil
+	})
+	// Only the follower serves direct gets.
+	for _, s := range c.servers {
+		if s == fs {
+			continue
+		}
+		omset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		omset.mu.Lock()
+		omset.unsubscribeToDirect()
+		omset.mu.Unlock()
+	}
+	fnc, _ := jsClientConnect(t, fs)
+	defer fnc.Close()
+
+	directGet := func(linearizable bool) *nats.Msg {
+		t.Helper()
+		req, err := json.Marshal(&JSApiMsgGetRequest{LastFor: "foo", Linearizable: linearizable})
+		require_NoError(t, err)
+		msg, err := fnc.Request(fmt.Sprintf(JSDirectMsgGetT, "TEST"), req, 5*time.Second)
+		require_NoError(t, err)
+		return msg
+	}
+	require_Equal(t, string(directGet(true).Data), "1")
+
+	// With the follower not applying, a regular direct get is stale.
+	require_NoError(t, fn.PauseApply())
+	_, err = js.Publish("foo", []byte("2"))
+	require_NoError(t, err)
+	require_Equal(t, string(directGet(false).Data), "1")
+
+	// A linearizable one waits until the follower has applied the write.
+	mch := make(chan *nats.Msg, 1)
+	go func() { mch <- directGet(true) }()
+	time.Sleep(250 * time.Millisecond)
+	fn.ResumeApply()
+	select {
+	case msg := <-mch:
+		require_Equal(t, msg.Header.Get(JSSequence), "2")
+		require_Equal(t, string(msg.Data), "2")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout waiting for linearizable direct get")
+	}
+
+	// The leader serves linearizable message gets.
+	req, err := json.Marshal(&JSApiMsgGetRequest{LastFor: "foo", Linearizable: true})
+	require_NoError(t, err)
+	msg, err := nc.Request(fmt.Sprintf(JSApiMsgGetT, "TEST"), req, 5*time.Second)
+	require_NoError(t, err)
+	var resp JSApiMsgGetResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Message.Sequence, 2)
+	require_Equal(t, string(resp.Message.Data), "2")
+}
+
+func TestJetStreamClusterRaftSnapshotRequest(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	sub, err := js.PullSubscribe("foo", "C", nats.Bind("TEST", "C"))
+	require_NoError(t, err)
+	msgs, err := sub.Fetch(10)
+	require_NoError(t, err)
+	for _, m := range msgs {
+		require_NoError(t, m.AckSync())
+	}
+	c.waitOnAllCurrent()
+
+	s := c.randomNonStreamLeader(globalAccountName, "TEST")
+	mset, err := s.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	o := mset.lookupConsumer("C")
+	require_NotNil(t, o)
+
+	ncs, err := nats.Connect(s.ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	require_NoError(t, err)
+	defer ncs.Close()
+
+	snapshot := func(group string) (*RaftSnapshotResp, *ApiError) {
+		t.Helper()
+		req, err := json.Marshal(&RaftSnapshotReq{Group: group})
+		require_NoError(t, err)
+		msg, err := ncs.Request(fmt.Sprintf(raftSnapshotReqSubj, s.ID()), req, time.Second)
+		require_NoError(t, err)
+		var resp struct {
+			Data  *RaftSnapshotResp `json:"data"`
+			Error *ApiError         `json:"error"`
+		}
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return resp.Data, resp.Error
+	}
+
+	// The stream WAL holds all messages and is compacted by the snapshot.
+	resp, apiErr := snapshot(mset.raftNode().Group())
+	require_True(t, apiErr == nil)
+	require_True(t, resp.Before.Msgs >= 100)
+	require_True(t, resp.After.Msgs < resp.Before.Msgs)
+
+	// Same for the consumer, which holds the acks.
+	resp, apiErr = snapshot(o.raftNode().Group())
+	require_True(t, apiErr == nil)
+	require_True(t, resp.Before.Msgs >= 10)
+	require_True(t, resp.After.Msgs < resp.Before.Msgs)
+
+	// The meta group can be snapshotted on a follower as well.
+	resp, apiErr = snapshot(defaultMetaGroupName)
+	require_True(t, apiErr == nil)
+	require_Equal(t, resp.Group, defaultMetaGroupName)
+
+	group := mset.raftNode().Group()
+	rz := s.Raftz(&RaftzOptions{AccountFilter: globalAccountName, GroupFilter: group})
+	require_NotNil(t, rz)
+	info, ok := (*rz)[globalAccountName][group]
+	require_True(t, ok)
+	require_NotEqual(t, info.Metrics.SnapshotAge, _EMPTY_)
+
+	_, apiErr = snapshot("UNKNOWN")
+	require_NotNil(t, apiErr)
+	require_Contains(t, apiErr.Description, errUnknownRaftGroup.Error())
+}
+
+func TestJetStreamClusterRaftMigrateRequest(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "MEM", Subjects: []string{"bar"}, Replicas: 3, Storage: nats.MemoryStorage})
+	require_NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	c.waitOnStreamCurrent(c.randomNonStreamLeader(globalAccountName, "TEST"), globalAccountName, "TEST")
+
+	s := c.randomNonStreamLeader(globalAccountName, "TEST")
+	mset, err := s.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	group := mset.raftNode().Group()
+
+	ncs, err := nats.Connect(s.ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	require_NoError(t, err)
+	defer ncs.Close()
+
+	migrate := func(group string, alg StoreCompression) (*RaftMigrateResp, *ApiError) {
+		t.Helper()
+		req, err := json.Marshal(&RaftMigrateReq{Group: group, Compression: alg})
+		require_NoError(t, err)
+		msg, err := ncs.Request(fmt.Sprintf(raftMigrateReqSubj, s.ID()), req, 5*time.Second)
+		require_NoError(t, err)
+		var resp struct {
+			Data  *RaftMigrateResp `json:"data"`
+			Error *ApiError        `json:"error"`
+		}
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return resp.Data, resp.Error
+	}
+
+	walCompression := func(s *Server) (StoreCompression, StoreCompression) {
+		t.Helper()
+		n := s.lookupRaftNode(group).(*raft)
+		n.RLock()
+		fs := n.wal.(*fileStore)
+		n.RUnlock()
+		fs.mu.RLock()
+		alg, mfn := fs.fcfg.Compression, fs.blks[0].mfn
+		fs.mu.RUnlock()
+		buf, err := os.ReadFile(mfn)
+		require_NoError(t, err)
+		var meta CompressionInfo
+		_, err = meta.UnmarshalMetadata(buf)
+		require_NoError(t, err)
+		return alg, meta.Algorithm
+	}
+
+	// The existing blocks are rewritten in the new format.
+	resp, apiErr := migrate(group, S2Compression)
+	require_True(t, apiErr == nil)
+	require_Equal(t, resp.Group, group)
+	require_Equal(t, resp.Compression, S2Compression)
+	require_True(t, resp.Blocks >= 1)
+	alg, blk := walCompression(s)
+	require_Equal(t, alg, S2Compression)
+	require_Equal(t, blk, S2Compression)
+
+	// The group keeps working and using the new format, also after a restart.
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	c.waitOnStreamCurrent(s, globalAccountName, "TEST")
+	ncs.Close()
+	s.Shutdown()
+	s = c.restartServer(s)
+	c.waitOnServerCurrent(s)
+	c.waitOnStreamCurrent(s, globalAccountName, "TEST")
+	mset, err = s.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_Equal(t, mset.state().Msgs, 110)
+	// New blocks are compressed once sealed.
+	alg, _ = walCompression(s)
+	require_Equal(t, alg, S2Compression)
+
+	ncs, err = nats.Connect(s.ClientURL(), nats.UserInfo("admin", "s3cr3t!"))
+	require_NoError(t, err)
+	defer ncs.Close()
+
+	// Memory based groups have no WAL on disk.
+	mset, err = s.globalAccount().lookupStream("MEM")
+	require_NoError(t, err)
+	_, apiErr = migrate(mset.raftNode().Group(), S2Compression)
+	require_NotNil(t, apiErr)
+	require_Contains(t, apiErr.Description, errRaftWALNotFile.Error())
+
+	_, apiErr = migrate("UNKNOWN", S2Compression)
+	require_NotNil(t, apiErr)
+	require_Contains(t, apiErr.Description, errUnknownRaftGroup.Error())
+}
+
+func TestJetStreamClusterStreamCompactionByKey(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:       "TEST",
+		Subjects:   []string{"kv.>"},
+		Storage:    FileStorage,
+		Replicas:   3,
+		Compaction: &StreamCompactionPolicy{HeadWindow: 250 * time.Millisecond},
+	})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	for i := 0; i < 30; i++ {
+		_, err := js.Publish(fmt.Sprintf("kv.%d", i%3), []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	// The leader proposes the removals, so all replicas end up with the same messages.
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			var ss StreamState
+			mset.store.FastState(&ss)
+			if ss.Msgs != 3 {
+				return fmt.Errorf("expected 3 msgs on %s, got %d", s, ss.Msgs)
+			}
+			// Every replica counts the removals when applying them.
+			if ci := mset.compactionInfo(); ci == nil || ci.Msgs != 27 {
+				return fmt.Errorf("expected 27 compacted msgs on %s, got %+v", s, ci)
+			}
+			for i, seq := range []uint64{28, 29, 30} {
+				sm, err := mset.store.LoadLastMsg(fmt.Sprintf("kv.%d", i), nil)
+				if err != nil {
+					return err
+				}
+				if sm.seq != seq {
+					return fmt.Errorf("expected last seq %d for kv.%d on %s, got %d", seq, i, s, sm.seq)
+				}
+			}
+		}
+		return nil
+	})
+
+	// Only the leader compacts.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	for _, s := range c.servers {
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		mset.mu.RLock()
+		running := mset.cmptmr != nil
+		mset.mu.RUnlock()
+		require_Equal(t, running, s == sl)
+	}
+}
+
+func TestJetStreamClusterTieredStorageReplicas(t *testing.T) {
+	bdir := t.TempDir()
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", fmt.Sprintf("store_dir: '%%s', tiered_store_dir: %q}", bdir), 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	cfg := &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, Replicas: 3, MaxBytes: 100_000,
+		Tiering: &StreamTieringPolicy{OffloadAfter: time.Millisecond}}
+	_, err := jsStreamCreate(t, nc, cfg)
+	require_NoError(t, err)
+
+	msg := bytes.Repeat([]byte("Z"), 1000)
+	for i := 0; i < 90; i++ {
+		_, err := js.Publish("foo", msg)
+		require_NoError(t, err)
+	}
+	c.waitOnStreamCurrent(c.streamLeader(globalAccountName, "TEST"), globalAccountName, "TEST")
+
+	// Every replica offloads its own blocks, through the cache shared by the server.
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		for _, s := range c.servers {
+			mset, err := s.globalAccount().lookupStream("TEST")
+			if err != nil {
+				return err
+			}
+			fs := mset.store.(*fileStore)
+			if fs.bcache != s.tieredBlobCache() {
+				return fmt.Errorf("stream on %s does not use the server's blob cache", s)
+			}
+			fs.offloadBlocks()
+			if fs.tieringInfo().OffloadedBlocks == 0 {
+				return fmt.Errorf("no blocks offloaded on %s yet", s)
+			}
+		}
+		return nil
+	})
+	nodes, err := os.ReadDir(filepath.Join(bdir, globalAccountName, "TEST"))
+	require_NoError(t, err)
+	require_Equal(t, len(nodes), 3)
+
+	// Removing the blocks of one replica leaves those of the others alone.
+	sl := c.streamLeader(globalAccountName, "TEST")
+	mset, err := sl.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	_, err = mset.store.Purge()
+	require_NoError(t, err)
+	for _, s := range c.servers {
+		if s == sl {
+			continue
+		}
+		mset, err := s.globalAccount().lookupStream("TEST")
+		require_NoError(t, err)
+		var smv StoreMsg
+		for seq := uint64(1); seq <= 90; seq++ {
+			sm, err := mset.store.LoadMsg(seq, &smv)
+			require_NoError(t, err)
+			require_True(t, bytes.Equal(sm.msg, msg))
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"net/url"
 	"reflect"
@@ -2425,6 +2426,133 @@ func TestJetStreamConsumerOverflow(t *testing.T) {
 	require_NotNil(t, msg)
 }
 
+func TestJetStreamConsumerPrioritized(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, _ := jsClientConnect(t, s)
+	defer nc.Close()
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{
+		Name:     "TEST",
+		Subjects: []string{"foo"},
+		Storage:  FileStorage,
+	})
+	require_NoError(t, err)
+
+	o, err := mset.addConsumer(&ConsumerConfig{
+		Durable:        "C",
+		PriorityGroups: []string{"A"},
+		PriorityPolicy: PriorityPrioritized,
+		AckPolicy:      AckExplicit,
+	})
+	require_NoError(t, err)
+
+	// Priorities are limited.
+	req := JSApiConsumerGetNextRequest{Batch: 1, Expires: 5 * time.Second, PriorityGroup: PriorityGroup{Group: "A", Priority: 10}}
+	invalid := sendRequest(t, nc, "invalid", req)
+	msg, err := invalid.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_Equal(t, msg.Header.Get("Status"), "400")
+
+	// The standby is only served once the active client has no capacity left.
+	req = JSApiConsumerGetNextRequest{Batch: 10, Expires: 90 * time.Second, PriorityGroup: PriorityGroup{Group: "A", Priority: 1}}
+	standby := sendRequest(t, nc, "standby", req)
+	req = JSApiConsumerGetNextRequest{Batch: 3, Expires: 90 * time.Second, PriorityGroup: PriorityGroup{Group: "A"}}
+	active := sendRequest(t, nc, "active", req)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if n := o.info().NumWaiting; n != 2 {
+			return fmt.Errorf("expected 2 waiting requests, got %d", n)
+		}
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		sendStreamMsg(t, nc, "foo", fmt.Sprintf("msg-%d", i))
+	}
+	checkSubsPending(t, active, 3)
+	checkSubsPending(t, standby, 2)
+	for i := 0; i < 3; i++ {
+		msg, err = active.NextMsg(time.Second)
+		require_NoError(t, err)
+		require_Equal(t, string(msg.Data), fmt.Sprintf("msg-%d", i))
+	}
+
+	// Other policies don't accept a priority.
+	_, err = mset.addConsumer(&ConsumerConfig{
+		Durable:        "O",
+		PriorityGroups: []string{"A"},
+		PriorityPolicy: PriorityOverflow,
+		AckPolicy:      AckExplicit,
+	})
+	require_NoError(t, err)
+	req = JSApiConsumerGetNextRequest{Batch: 1, Expires: 5 * time.Second, PriorityGroup: PriorityGroup{Group: "A", Priority: 1}}
+	reqb, _ := json.Marshal(req)
+	msg, err = nc.Request("$JS.API.CONSUMER.MSG.NEXT.TEST.O", reqb, time.Second)
+	require_NoError(t, err)
+	require_Equal(t, msg.Header.Get("Description"), "Bad Request - Not a Prioritized Priority consumer")
+}
+
+func TestJetStreamConsumerWeighted(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, _ := jsClientConnect(t, s)
+	defer nc.Close()
+
+	mset, err := s.GlobalAccount().addStream(&StreamConfig{
+		Name:     "TEST",
+		Subjects: []string{"foo"},
+		Storage:  FileStorage,
+	})
+	require_NoError(t, err)
+
+	// Invalid weights.
+	for _, cfg := range []*ConsumerConfig{
+		{Durable: "BAD", PriorityGroups: []string{"A"}, PriorityPolicy: PriorityOverflow, PriorityWeights: map[string]int{"A": 2}},
+		{Durable: "BAD", PriorityGroups: []string{"A"}, PriorityPolicy: PriorityWeighted, PriorityWeights: map[string]int{"B": 2}},
+		{Durable: "BAD", PriorityGroups: []string{"A"}, PriorityPolicy: PriorityWeighted, PriorityWeights: map[string]int{"A": 0}},
+	} {
+		_, err = mset.addConsumer(cfg)
+		require_Error(t, err, NewJSConsumerInvalidPolicyError(errors.New("")))
+	}
+
+	o, err := mset.addConsumer(&ConsumerConfig{
+		Durable:         "C",
+		PriorityGroups:  []string{"A", "B", "C"},
+		PriorityPolicy:  PriorityWeighted,
+		PriorityWeights: map[string]int{"A": 3},
+		AckPolicy:       AckExplicit,
+	})
+	require_NoError(t, err)
+
+	// Group C has no waiting requests, so it is skipped.
+	req := JSApiConsumerGetNextRequest{Batch: 100, Expires: 90 * time.Second, PriorityGroup: PriorityGroup{Group: "A"}}
+	a := sendRequest(t, nc, "a", req)
+	req = JSApiConsumerGetNextRequest{Batch: 100, Expires: 90 * time.Second, PriorityGroup: PriorityGroup{Group: "B"}}
+	b := sendRequest(t, nc, "b", req)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if n := o.info().NumWaiting; n != 2 {
+			return fmt.Errorf("expected 2 waiting requests, got %d", n)
+		}
+		return nil
+	})
+
+	for i := 0; i < 40; i++ {
+		sendStreamMsg(t, nc, "foo", "msg")
+	}
+	checkSubsPending(t, a, 30)
+	checkSubsPending(t, b, 10)
+
+	// Selecting a group without delivering to it must not advance the weights.
+	o.mu.Lock()
+	pgw := maps.Clone(o.pgw)
+	group := o.nextWeightedGroup()
+	require_Equal(t, o.nextWeightedGroup(), group)
+	require_True(t, maps.Equal(o.pgw, pgw))
+	o.mu.Unlock()
+}
+
 func TestJetStreamConsumerMultipleFitersWithStartDate(t *testing.T) {
 	s := RunBasicJetStreamServer(t)
 	defer s.Shutdown()
@@ -9867,3 +9995,568 @@ func TestJetStreamConsumerNotInactiveDuringAckWaitBackoff(t *testing.T) {
 	t.Run("R1", func(t *testing.T) { test(t, 1) })
 	t.Run("R3", func(t *testing.T) { test(t, 3) })
 }
+
+func TestJetStreamConsumerDeadLetter(t *testing.T) {
+	test := func(t *testing.T, s *Server, replicas int) {
+		nc, js := jsClientConnect(t, s)
+		defer nc.Close()
+
+		_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*"}, Replicas: replicas})
+		require_NoError(t, err)
+		_, err = js.AddStream(&nats.StreamConfig{Name: "DLQ", Subjects: []string{"dlq"}, Replicas: replicas})
+		require_NoError(t, err)
+
+		// Invalid configs.
+		_, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+			Durable: "BAD", AckPolicy: AckNone, DeadLetter: &ConsumerDeadLetter{Subject: "dlq"},
+		}})
+		require_NotNil(t, apiErr)
+		require_Equal(t, apiErr.ErrCode, uint16(JSConsumerInvalidPolicyErrF))
+		_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+			Durable: "BAD", AckPolicy: AckExplicit, DeadLetter: &ConsumerDeadLetter{Subject: "foo.bar"},
+		}})
+		require_NotNil(t, apiErr)
+		require_Equal(t, apiErr.ErrCode, uint16(JSConsumerInvalidPolicyErrF))
+
+		_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+			Durable:    "C",
+			AckPolicy:  AckExplicit,
+			MaxDeliver: 2,
+			DeadLetter: &ConsumerDeadLetter{Subject: "dlq", Stream: "DLQ"},
+		}})
+		require_True(t, apiErr == nil)
+
+		_, err = js.Publish("foo.nak", []byte("nak"), nats.MsgId("original"))
+		require_NoError(t, err)
+		_, err = js.Publish("foo.term", []byte("term"))
+		require_NoError(t, err)
+
+		sub, err := js.PullSubscribe(_EMPTY_, "C", nats.BindStream("TEST"))
+		require_NoError(t, err)
+		defer sub.Drain()
+
+		msgs, err := sub.Fetch(2)
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 2)
+		require_NoError(t, msgs[0].Respond([]byte(`-NAK {"reason":"bad payload"}`)))
+		require_NoError(t, msgs[1].Respond([]byte("+TERM invalid")))
+
+		// Second delivery hits MaxDeliver.
+		msgs, err = sub.Fetch(1)
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 1)
+		require_NoError(t, msgs[0].Nak())
+		_, err = sub.Fetch(1, nats.MaxWait(250*time.Millisecond))
+		require_Error(t, err, nats.ErrTimeout)
+
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			si, err := js.StreamInfo("DLQ")
+			if err != nil {
+				return err
+			}
+			if si.State.Msgs != 2 {
+				return fmt.Errorf("expected 2 dead lettered messages, got %d", si.State.Msgs)
+			}
+			return nil
+		})
+
+		// The terminated message is dead lettered first.
+		rsm, err := js.GetMsg("DLQ", 1)
+		require_NoError(t, err)
+		require_Equal(t, string(rsm.Data), "term")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterStream), "TEST")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterSubject), "foo.term")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterSequence), "2")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterConsumer), "C")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterDeliveries), "1")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterReason), "invalid")
+
+		rsm, err = js.GetMsg("DLQ", 2)
+		require_NoError(t, err)
+		require_Equal(t, string(rsm.Data), "nak")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterSubject), "foo.nak")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterSequence), "1")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterDeliveries), "2")
+		require_Equal(t, rsm.Header.Get(JSDeadLetterReason), "bad payload")
+		// The original message id is replaced so the target stream can deduplicate.
+		require_Equal(t, rsm.Header.Get(JSMsgId), "TEST:C:1")
+	}
+
+	t.Run("R1", func(t *testing.T) {
+		s := RunBasicJetStreamServer(t)
+		defer s.Shutdown()
+		test(t, s, 1)
+	})
+	t.Run("R3", func(t *testing.T) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+		test(t, c.randomServer(), 3)
+	})
+}
+
+func TestJetStreamConsumerMsgFilter(t *testing.T) {
+	test := func(t *testing.T, servers []*Server, replicas int) {
+		nc, js := jsClientConnect(t, servers[0])
+		defer nc.Close()
+
+		_, err := js.AddStream(&nats.StreamConfig{
+			Name:      "TEST",
+			Subjects:  []string{"orders"},
+			Retention: nats.InterestPolicy,
+			Replicas:  replicas,
+		})
+		require_NoError(t, err)
+
+		_, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+			Durable: "BAD", AckPolicy: AckExplicit, MsgFilter: &MsgFilter{Header: "Region", Op: "like"},
+		}})
+		require_NotNil(t, apiErr)
+		require_Equal(t, apiErr.ErrCode, uint16(JSConsumerMsgFilterInvalidErrF))
+
+		_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+			Durable:   "EU",
+			AckPolicy: AckExplicit,
+			MsgFilter: &MsgFilter{All: []*MsgFilter{
+				{Header: "Region", Op: MsgFilterPrefix, Value: "eu-"},
+				{Payload: "total", Op: MsgFilterGte, Value: "10"},
+			}},
+		}})
+		require_True(t, apiErr == nil)
+
+		publish := func(region string, total int) {
+			t.Helper()
+			m := nats.NewMsg("orders")
+			m.Header.Set("Region", region)
+			m.Data = []byte(fmt.Sprintf(`{"total":%d}`, total))
+			_, err := js.PublishMsg(m)
+			require_NoError(t, err)
+		}
+		publish("us-east", 100)
+		publish("eu-west", 5)
+		publish("eu-west", 50)
+		publish("us-west", 100)
+		publish("eu-central", 20)
+		publish("us-east", 1)
+
+		sub, err := js.PullSubscribe(_EMPTY_, "EU", nats.BindStream("TEST"))
+		require_NoError(t, err)
+		defer sub.Drain()
+
+		msgs, err := sub.Fetch(10, nats.MaxWait(500*time.Millisecond))
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 2)
+		for i, seq := range []uint64{3, 5} {
+			meta, err := msgs[i].Metadata()
+			require_NoError(t, err)
+			require_Equal(t, meta.Sequence.Stream, seq)
+			require_NoError(t, msgs[i].AckSync())
+		}
+
+		// The consumer skipped the other messages, so with interest retention
+		// they are removed from the stream as if they were acknowledged.
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			si, err := js.StreamInfo("TEST")
+			if err != nil {
+				return err
+			}
+			if si.State.Msgs != 0 {
+				return fmt.Errorf("expected no messages, got %d", si.State.Msgs)
+			}
+			return nil
+		})
+		ci, err := js.ConsumerInfo("TEST", "EU")
+		require_NoError(t, err)
+		require_Equal(t, ci.NumAckPending, 0)
+		require_Equal(t, ci.NumPending, 0)
+
+		// Every replica removed the skipped messages and stored the skip in its ack floor.
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			for _, s := range servers {
+				mset, err := s.globalAccount().lookupStream("TEST")
+				if err != nil {
+					return err
+				}
+				if msgs := mset.state().Msgs; msgs != 0 {
+					return fmt.Errorf("expected no messages on %s, got %d", s.Name(), msgs)
+				}
+				o := mset.lookupConsumer("EU")
+				if o == nil {
+					return fmt.Errorf("consumer not found on %s", s.Name())
+				}
+				state, err := o.store.State()
+				if err != nil {
+					return err
+				}
+				if state.AckFloor.Stream != 6 {
+					return fmt.Errorf("expected ack floor of 6 on %s, got %d", s.Name(), state.AckFloor.Stream)
+				}
+			}
+			return nil
+		})
+	}
+
+	t.Run("R1", func(t *testing.T) {
+		s := RunBasicJetStreamServer(t)
+		defer s.Shutdown()
+		test(t, []*Server{s}, 1)
+	})
+	t.Run("R3", func(t *testing.T) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+		test(t, c.servers, 3)
+	})
+}
+
+func TestJetStreamConsumerLagAdvisories(t *testing.T) {
+	old := consumerLagCheckInterval
+	consumerLagCheckInterval = 50 * time.Millisecond
+	defer func() { consumerLagCheckInterval = old }()
+
+	test := func(t *testing.T, s *Server, replicas int) {
+		nc, js := jsClientConnect(t, s)
+		defer nc.Close()
+
+		_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: replicas})
+		require_NoError(t, err)
+
+		// Invalid configs.
+		for _, lt := range []*ConsumerLagThresholds{{}, {OldestUnackedAge: -time.Second}} {
+			_, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+				Durable: "BAD", AckPolicy: AckExplicit, LagThresholds: lt,
+			}})
+			require_NotNil(t, apiErr)
+			require_Equal(t, apiErr.ErrCode, uint16(JSConsumerInvalidPolicyErrF))
+		}
+
+		exceeded, err := nc.SubscribeSync(JSAdvisoryConsumerLagExceededPre + ".TEST.C")
+		require_NoError(t, err)
+		defer exceeded.Unsubscribe()
+		recovered, err := nc.SubscribeSync(JSAdvisoryConsumerLagRecoveredPre + ".TEST.C")
+		require_NoError(t, err)
+		defer recovered.Unsubscribe()
+		require_NoError(t, nc.Flush())
+
+		_, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Config: ConsumerConfig{
+			Durable:       "C",
+			AckPolicy:     AckExplicit,
+			LagThresholds: &ConsumerLagThresholds{PendingMsgs: 5},
+		}})
+		require_True(t, apiErr == nil)
+
+		for i := 0; i < 10; i++ {
+			_, err = js.Publish("foo", []byte("hello"))
+			require_NoError(t, err)
+		}
+
+		msg, err := exceeded.NextMsg(2 * time.Second)
+		require_NoError(t, err)
+		var adv JSConsumerLagAdvisory
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Type, JSConsumerLagExceededAdvisoryType)
+		require_Equal(t, adv.Stream, "TEST")
+		require_Equal(t, adv.Consumer, "C")
+		require_Equal(t, adv.PendingMsgs, 10)
+		require_True(t, adv.PendingBytes > 0)
+		require_True(t, adv.OldestUnackedAge > 0)
+		require_Len(t, len(adv.Exceeded), 1)
+		require_Equal(t, adv.Exceeded[0], "pending_msgs")
+
+		// Only sent once while lagging.
+		_, err = exceeded.NextMsg(250 * time.Millisecond)
+		require_Error(t, err, nats.ErrTimeout)
+
+		// Messages delivered but not acked still count.
+		sub, err := js.PullSubscribe(_EMPTY_, "C", nats.BindStream("TEST"))
+		require_NoError(t, err)
+		defer sub.Drain()
+		msgs, err := sub.Fetch(10)
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 10)
+		_, err = recovered.NextMsg(250 * time.Millisecond)
+		require_Error(t, err, nats.ErrTimeout)
+
+		for _, m := range msgs {
+			require_NoError(t, m.AckSync())
+		}
+		msg, err = recovered.NextMsg(2 * time.Second)
+		require_NoError(t, err)
+		adv = JSConsumerLagAdvisory{}
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Type, JSConsumerLagRecoveredAdvisoryType)
+		require_True(t, adv.PendingMsgs <= 5)
+		require_Len(t, len(adv.Exceeded), 0)
+
+		// Thresholds can be updated, here the oldest unacked message becomes too old.
+		_, apiErr = addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: "TEST", Action: ActionUpdate, Config: ConsumerConfig{
+			Durable:       "C",
+			AckPolicy:     AckExplicit,
+			LagThresholds: &ConsumerLagThresholds{OldestUnackedAge: 100 * time.Millisecond},
+		}})
+		require_True(t, apiErr == nil)
+		_, err = js.Publish("foo", []byte("hello"))
+		require_NoError(t, err)
+
+		msg, err = exceeded.NextMsg(2 * time.Second)
+		require_NoError(t, err)
+		adv = JSConsumerLagAdvisory{}
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.PendingMsgs, 1)
+		require_True(t, adv.OldestUnackedAge > 100*time.Millisecond)
+		require_Len(t, len(adv.Exceeded), 1)
+		require_Equal(t, adv.Exceeded[0], "oldest_unacked_age")
+	}
+
+	t.Run("R1", func(t *testing.T) {
+		s := RunBasicJetStreamServer(t)
+		defer s.Shutdown()
+		test(t, s, 1)
+	})
+	t.Run("R3", func(t *testing.T) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+		test(t, c.randomServer(), 3)
+	})
+}
+
+func addWatchWithError(t *testing.T, nc *nats.Conn, req *CreateConsumerRequest) (*ConsumerInfo, *ApiError) {
+	t.Helper()
+	b, err := json.Marshal(req)
+	require_NoError(t, err)
+	msg, err := nc.Request(fmt.Sprintf(JSApiConsumerCreateT, req.Stream), b, 5*time.Second)
+	require_NoError(t, err)
+	var resp JSApiConsumerCreateResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	return resp.ConsumerInfo, resp.Error
+}
+
+func TestJetStreamConsumerWatch(t *testing.T) {
+	test := func(t *testing.T, s *Server, replicas int, leader func() *Server) {
+		nc, js := jsClientConnect(t, s)
+		defer nc.Close()
+
+		_, err := js.AddStream(&nats.StreamConfig{
+			Name:              "KV",
+			Subjects:          []string{"kv.>"},
+			MaxMsgsPerSubject: 10,
+			Replicas:          replicas,
+		})
+		require_NoError(t, err)
+
+		for _, subj := range []string{"kv.a", "kv.b", "kv.a", "kv.c", "kv.b"} {
+			_, err = js.Publish(subj, nil)
+			require_NoError(t, err)
+		}
+
+		inbox := nats.NewInbox()
+		ci, apiErr := addWatchWithError(t, nc, &CreateConsumerRequest{Stream: "KV", Config: ConsumerConfig{
+			Name:           "W",
+			Watch:          true,
+			FilterSubject:  "kv.>",
+			DeliverSubject: inbox,
+			AckPolicy:      AckNone,
+		}})
+		require_True(t, apiErr == nil)
+		require_Equal(t, ci.Config.DeliverPolicy, DeliverLastPerSubject)
+		require_Equal(t, ci.NumPending, 3)
+
+		// Updates while the watcher is not keeping up are coalesced.
+		for _, subj := range []string{"kv.a", "kv.a", "kv.b"} {
+			_, err = js.Publish(subj, nil)
+			require_NoError(t, err)
+		}
+		nci, err := js.ConsumerInfo("KV", "W")
+		require_NoError(t, err)
+		require_Equal(t, nci.NumPending, 3)
+
+		sub, err := nc.SubscribeSync(inbox)
+		require_NoError(t, err)
+		defer sub.Unsubscribe()
+
+		expect := func(subj string, seq uint64) {
+			t.Helper()
+			msg, err := sub.NextMsg(2 * time.Second)
+			require_NoError(t, err)
+			require_Equal(t, msg.Subject, subj)
+			meta, err := msg.Metadata()
+			require_NoError(t, err)
+			require_Equal(t, meta.Sequence.Stream, seq)
+		}
+		expect("kv.c", 4)
+		expect("kv.a", 7)
+		expect("kv.b", 8)
+
+		// Live updates.
+		_, err = js.Publish("kv.c", nil)
+		require_NoError(t, err)
+		expect("kv.c", 9)
+
+		// Watches keep no consumer state.
+		mset, err := leader().globalAccount().lookupStream("KV")
+		require_NoError(t, err)
+		o := mset.lookupConsumer("W")
+		require_NotNil(t, o)
+		o.mu.RLock()
+		store := o.store
+		o.mu.RUnlock()
+		require_True(t, store == nil)
+	}
+
+	t.Run("R1", func(t *testing.T) {
+		s := RunBasicJetStreamServer(t)
+		defer s.Shutdown()
+		test(t, s, 1, func() *Server { return s })
+	})
+	t.Run("R3", func(t *testing.T) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+		test(t, c.randomServer(), 3, func() *Server { return c.consumerLeader(globalAccountName, "KV", "W") })
+	})
+}
+
+func TestJetStreamConsumerWatchInvalidConfig(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "KV", Subjects: []string{"kv.>"}})
+	require_NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "INTEREST", Subjects: []string{"i.>"}, Retention: nats.InterestPolicy})
+	require_NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		stream string
+		cfg    ConsumerConfig
+	}{
+		{"durable", "KV", ConsumerConfig{Durable: "W", Watch: true, FilterSubject: "kv.>", DeliverSubject: "w", AckPolicy: AckNone}},
+		{"ack explicit", "KV", ConsumerConfig{Watch: true, FilterSubject: "kv.>", DeliverSubject: "w", AckPolicy: AckExplicit}},
+		{"deliver new", "KV", ConsumerConfig{Watch: true, FilterSubject: "kv.>", DeliverSubject: "w", AckPolicy: AckNone, DeliverPolicy: DeliverNew}},
+		{"interest", "INTEREST", ConsumerConfig{Watch: true, FilterSubject: "i.>", DeliverSubject: "w", AckPolicy: AckNone}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			add := addWatchWithError
+			if test.cfg.Durable != _EMPTY_ {
+				add = addConsumerWithError
+			}
+			_, apiErr := add(t, nc, &CreateConsumerRequest{Stream: test.stream, Config: test.cfg})
+			require_NotNil(t, apiErr)
+			require_Equal(t, apiErr.ErrCode, uint16(JSConsumerInvalidPolicyErrF))
+		})
+	}
+
+	// Watch can not be toggled on update.
+	_, apiErr := addWatchWithError(t, nc, &CreateConsumerRequest{Stream: "KV", Config: ConsumerConfig{
+		Name: "W", Watch: true, FilterSubject: "kv.>", DeliverSubject: "w", AckPolicy: AckNone,
+	}})
+	require_True(t, apiErr == nil)
+	_, apiErr = addWatchWithError(t, nc, &CreateConsumerRequest{Stream: "KV", Action: ActionUpdate, Config: ConsumerConfig{
+		Name: "W", FilterSubject: "kv.>", DeliverSubject: "w", AckPolicy: AckNone, DeliverPolicy: DeliverLastPerSubject,
+	}})
+	require_NotNil(t, apiErr)
+}
+
+func TestJetStreamConsumerDeadLetterFailure(t *testing.T) {
+	test := func(t *testing.T, s *Server, replicas int) {
+		nc, js := jsClientConnect(t, s)
+		defer nc.Close()
+
+		for _, cfg := range []*nats.StreamConfig{
+			{Name: "TERM", Subjects: []string{"term"}, Retention: nats.WorkQueuePolicy, Replicas: replicas},
+			{Name: "MAX", Subjects: []string{"max"}, Retention: nats.WorkQueuePolicy, Replicas: replicas},
+			// Captures the dead letter subject, but is not the expected dead letter stream.
+			{Name: "OTHER", Subjects: []string{"dlq.term"}, Replicas: replicas},
+		} {
+			_, err := js.AddStream(cfg)
+			require_NoError(t, err)
+		}
+		for stream, maxDeliver := range map[string]int{"TERM": -1, "MAX": 1} {
+			_, apiErr := addConsumerWithError(t, nc, &CreateConsumerRequest{Stream: stream, Config: ConsumerConfig{
+				Durable:    "C",
+				AckPolicy:  AckExplicit,
+				AckWait:    time.Second,
+				MaxDeliver: maxDeliver,
+				DeadLetter: &ConsumerDeadLetter{Subject: "dlq." + strings.ToLower(stream), Stream: "DLQ"},
+			}})
+			require_True(t, apiErr == nil)
+			_, err := js.Publish(strings.ToLower(stream), []byte(stream))
+			require_NoError(t, err)
+		}
+
+		checkMsgs := func(stream string, msgs uint64) {
+			t.Helper()
+			checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+				si, err := js.StreamInfo(stream)
+				if err != nil {
+					return err
+				}
+				if si.State.Msgs != msgs {
+					return fmt.Errorf("expected %d messages in %s, got %d", msgs, stream, si.State.Msgs)
+				}
+				return nil
+			})
+		}
+
+		tsub, err := js.PullSubscribe(_EMPTY_, "C", nats.BindStream("TERM"))
+		require_NoError(t, err)
+		defer tsub.Drain()
+		msub, err := js.PullSubscribe(_EMPTY_, "C", nats.BindStream("MAX"))
+		require_NoError(t, err)
+		defer msub.Drain()
+
+		// Dead letters are rejected by the stream that is not the expected one.
+		msgs, err := tsub.Fetch(1)
+		require_NoError(t, err)
+		require_NoError(t, msgs[0].Term())
+		msgs, err = msub.Fetch(1)
+		require_NoError(t, err)
+		require_NoError(t, msgs[0].Nak())
+		// The redelivery hits MaxDeliver, without a stream for the dead letter subject.
+		_, err = msub.Fetch(1, nats.MaxWait(250*time.Millisecond))
+		require_Error(t, err, nats.ErrTimeout)
+
+		// The terminated message is not removed from the work queue, but redelivered.
+		msgs, err = tsub.Fetch(1, nats.MaxWait(2*time.Second))
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 1)
+		checkMsgs("TERM", 1)
+		checkMsgs("OTHER", 0)
+
+		// Without a stream for the dead letter subject the terminated message is still kept.
+		require_NoError(t, js.DeleteStream("OTHER"))
+		require_NoError(t, msgs[0].Term())
+		time.Sleep(deadLetterAckWait + 250*time.Millisecond)
+		checkMsgs("TERM", 1)
+
+		// Once the dead letter stream exists, the next TERM removes the original.
+		_, err = js.AddStream(&nats.StreamConfig{Name: "DLQ", Subjects: []string{"dlq.*"}, Replicas: replicas})
+		require_NoError(t, err)
+		msgs, err = tsub.Fetch(1, nats.MaxWait(2*time.Second))
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 1)
+		require_NoError(t, msgs[0].Term())
+		checkMsgs("TERM", 0)
+
+		// The dead letter for MaxDeliver is retried until it is stored.
+		checkMsgs("DLQ", 2)
+		checkMsgs("MAX", 1)
+		rsm, err := js.GetLastMsg("DLQ", "dlq.max")
+		require_NoError(t, err)
+		require_Equal(t, string(rsm.Data), "MAX")
+		rsm, err = js.GetLastMsg("DLQ", "dlq.term")
+		require_NoError(t, err)
+		require_Equal(t, string(rsm.Data), "TERM")
+	}
+
+	t.Run("R1", func(t *testing.T) {
+		s := RunBasicJetStreamServer(t)
+		defer s.Shutdown()
+		test(t, s, 1)
+	})
+	t.Run("R3", func(t *testing.T) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+		test(t, c.randomServer(), 3)
+	})
+}
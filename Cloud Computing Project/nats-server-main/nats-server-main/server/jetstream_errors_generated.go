@@ -17,6 +17,9 @@ const (
 	// JSAtomicPublishIncompleteBatchErr atomic publish batch is incomplete
 	JSAtomicPublishIncompleteBatchErr ErrorIdentifier = 10176
 
+	// JSAtomicPublishInvalidBatchAckErrF atomic publish batch ack is invalid: {err}
+	JSAtomicPublishInvalidBatchAckErrF ErrorIdentifier = 10182
+
 	// JSAtomicPublishMissingSeqErr atomic publish sequence is missing
 	JSAtomicPublishMissingSeqErr ErrorIdentifier = 10175
 
@@ -41,6 +44,9 @@ const (
 	// JSClusterNotLeaderErr JetStream cluster can not handle request
 	JSClusterNotLeaderErr ErrorIdentifier = 10009
 
+	// JSClusterPeerNotLearnerErr peer not a learner
+	JSClusterPeerNotLearnerErr ErrorIdentifier = 10186
+
 	// JSClusterPeerNotMemberErr peer not a member
 	JSClusterPeerNotMemberErr ErrorIdentifier = 10040
 
@@ -170,6 +176,9 @@ const (
 	// JSConsumerMetadataLengthErrF consumer metadata exceeds maximum size of {limit}
 	JSConsumerMetadataLengthErrF ErrorIdentifier = 10135
 
+	// JSConsumerMsgFilterInvalidErrF consumer message filter is invalid: {err}
+	JSConsumerMsgFilterInvalidErrF ErrorIdentifier = 10181
+
 	// JSConsumerMultipleFiltersNotAllowed consumer with multiple subject filters cannot use subject based API
 	JSConsumerMultipleFiltersNotAllowed ErrorIdentifier = 10137
 
@@ -272,6 +281,12 @@ const (
 	// JSMessageIncrPayloadErr message counter has payload
 	JSMessageIncrPayloadErr ErrorIdentifier = 10170
 
+	// JSMessageScheduleInvalidErr invalid message schedule
+	JSMessageScheduleInvalidErr ErrorIdentifier = 10180
+
+	// JSMessageSchedulesDisabledErr message schedules is disabled
+	JSMessageSchedulesDisabledErr ErrorIdentifier = 10179
+
 	// JSMessageTTLDisabledErr per-message TTL is disabled
 	JSMessageTTLDisabledErr ErrorIdentifier = 10166
 
@@ -386,6 +401,12 @@ const (
 	// JSStreamAssignmentErrF Generic stream assignment error string ({err})
 	JSStreamAssignmentErrF ErrorIdentifier = 10048
 
+	// JSStreamCloneErrF clone failed: {err}
+	JSStreamCloneErrF ErrorIdentifier = 10184
+
+	// JSStreamCloneNotLocalErr stream clone must be placed on a server hosting the source stream
+	JSStreamCloneNotLocalErr ErrorIdentifier = 10185
+
 	// JSStreamCreateErrF Generic stream creation error string ({err})
 	JSStreamCreateErrF ErrorIdentifier = 10049
 
@@ -404,6 +425,15 @@ const (
 	// JSStreamExternalDelPrefixOverlapsErrF stream external delivery prefix {prefix} overlaps with stream subject {subject}
 	JSStreamExternalDelPrefixOverlapsErrF ErrorIdentifier = 10022
 
+	// JSStreamFailoverConflictErrF stream has {gap} messages not replicated to the new primary
+	JSStreamFailoverConflictErrF ErrorIdentifier = 10188
+
+	// JSStreamFailoverInactiveErr stream mirror is not replicating from its origin
+	JSStreamFailoverInactiveErr ErrorIdentifier = 10190
+
+	// JSStreamFailoverLagErrF stream mirror has not replicated {gap} messages of its origin
+	JSStreamFailoverLagErrF ErrorIdentifier = 10187
+
 	// JSStreamGeneralErrorF General stream failure string ({err})
 	JSStreamGeneralErrorF ErrorIdentifier = 10051
 
@@ -467,6 +497,9 @@ const (
 	// JSStreamNotMatchErr expected stream does not match
 	JSStreamNotMatchErr ErrorIdentifier = 10060
 
+	// JSStreamNotMirrorErr stream is not a mirror
+	JSStreamNotMirrorErr ErrorIdentifier = 10189
+
 	// JSStreamOfflineErr stream is offline
 	JSStreamOfflineErr ErrorIdentifier = 10118
 
@@ -485,6 +518,9 @@ const (
 	// JSStreamRollupFailedF Generic stream rollup failure error string ({err})
 	JSStreamRollupFailedF ErrorIdentifier = 10111
 
+	// JSStreamSchemaValidationFailedErrF message failed schema validation: {err}
+	JSStreamSchemaValidationFailedErrF ErrorIdentifier = 10183
+
 	// JSStreamSealedErr invalid operation on sealed stream
 	JSStreamSealedErr ErrorIdentifier = 10109
 
@@ -543,6 +579,7 @@ var (
 		JSAtomicPublishDisabledErr:                 {Code: 400, ErrCode: 10174, Description: "atomic publish is disabled"},
 		JSAtomicPublishDuplicateErr:                {Code: 400, ErrCode: 10177, Description: "atomic publish duplicates not allowed"},
 		JSAtomicPublishIncompleteBatchErr:          {Code: 400, ErrCode: 10176, Description: "atomic publish batch is incomplete"},
+		JSAtomicPublishInvalidBatchAckErrF:         {Code: 400, ErrCode: 10182, Description: "atomic publish batch ack is invalid: {err}"},
 		JSAtomicPublishMissingSeqErr:               {Code: 400, ErrCode: 10175, Description: "atomic publish sequence is missing"},
 		JSBadRequestErr:                            {Code: 400, ErrCode: 10003, Description: "bad request"},
 		JSClusterIncompleteErr:                     {Code: 503, ErrCode: 10004, Description: "incomplete results"},
@@ -551,6 +588,7 @@ var (
 		JSClusterNotAssignedErr:                    {Code: 500, ErrCode: 10007, Description: "JetStream cluster not assigned to this server"},
 		JSClusterNotAvailErr:                       {Code: 503, ErrCode: 10008, Description: "JetStream system temporarily unavailable"},
 		JSClusterNotLeaderErr:                      {Code: 500, ErrCode: 10009, Description: "JetStream cluster can not handle request"},
+		JSClusterPeerNotLearnerErr:                 {Code: 400, ErrCode: 10186, Description: "peer not a learner"},
 		JSClusterPeerNotMemberErr:                  {Code: 400, ErrCode: 10040, Description: "peer not a member"},
 		JSClusterRequiredErr:                       {Code: 503, ErrCode: 10010, Description: "JetStream clustering support required"},
 		JSClusterServerNotMemberErr:                {Code: 400, ErrCode: 10044, Description: "server is not a member of the cluster"},
@@ -594,6 +632,7 @@ var (
 		JSConsumerMaxRequestExpiresToSmall:         {Code: 400, ErrCode: 10115, Description: "consumer max request expires needs to be >= 1ms"},
 		JSConsumerMaxWaitingNegativeErr:            {Code: 400, ErrCode: 10087, Description: "consumer max waiting needs to be positive"},
 		JSConsumerMetadataLengthErrF:               {Code: 400, ErrCode: 10135, Description: "consumer metadata exceeds maximum size of {limit}"},
+		JSConsumerMsgFilterInvalidErrF:             {Code: 400, ErrCode: 10181, Description: "consumer message filter is invalid: {err}"},
 		JSConsumerMultipleFiltersNotAllowed:        {Code: 400, ErrCode: 10137, Description: "consumer with multiple subject filters cannot use subject based API"},
 		JSConsumerNameContainsPathSeparatorsErr:    {Code: 400, ErrCode: 10127, Description: "Consumer name can not contain path separators"},
 		JSConsumerNameExistErr:                     {Code: 400, ErrCode: 10013, Description: "consumer name already in use"},
@@ -628,6 +667,8 @@ var (
 		JSMessageIncrInvalidErr:                    {Code: 400, ErrCode: 10171, Description: "message counter increment is invalid"},
 		JSMessageIncrMissingErr:                    {Code: 400, ErrCode: 10169, Description: "message counter increment is missing"},
 		JSMessageIncrPayloadErr:                    {Code: 400, ErrCode: 10170, Description: "message counter has payload"},
+		JSMessageScheduleInvalidErr:                {Code: 400, ErrCode: 10180, Description: "invalid message schedule"},
+		JSMessageSchedulesDisabledErr:              {Code: 400, ErrCode: 10179, Description: "message schedules is disabled"},
 		JSMessageTTLDisabledErr:                    {Code: 400, ErrCode: 10166, Description: "per-message TTL is disabled"},
 		JSMessageTTLInvalidErr:                     {Code: 400, ErrCode: 10165, Description: "invalid per-message TTL"},
 		JSMirrorConsumerSetupFailedErrF:            {Code: 500, ErrCode: 10029, Description: "{err}"},
@@ -666,12 +707,17 @@ var (
 		JSSourceOverlappingSubjectFilters:          {Code: 400, ErrCode: 10147, Description: "source filters can not overlap"},
 		JSStorageResourcesExceededErr:              {Code: 500, ErrCode: 10047, Description: "insufficient storage resources available"},
 		JSStreamAssignmentErrF:                     {Code: 500, ErrCode: 10048, Description: "{err}"},
+		JSStreamCloneErrF:                          {Code: 500, ErrCode: 10184, Description: "clone failed: {err}"},
+		JSStreamCloneNotLocalErr:                   {Code: 400, ErrCode: 10185, Description: "stream clone must be placed on a server hosting the source stream"},
 		JSStreamCreateErrF:                         {Code: 500, ErrCode: 10049, Description: "{err}"},
 		JSStreamDeleteErrF:                         {Code: 500, ErrCode: 10050, Description: "{err}"},
 		JSStreamDuplicateMessageConflict:           {Code: 409, ErrCode: 10158, Description: "duplicate message id is in process"},
 		JSStreamExpectedLastSeqPerSubjectNotReady:  {Code: 503, ErrCode: 10163, Description: "expected last sequence per subject temporarily unavailable"},
 		JSStreamExternalApiOverlapErrF:             {Code: 400, ErrCode: 10021, Description: "stream external api prefix {prefix} must not overlap with {subject}"},
 		JSStreamExternalDelPrefixOverlapsErrF:      {Code: 400, ErrCode: 10022, Description: "stream external delivery prefix {prefix} overlaps with stream subject {subject}"},
+		JSStreamFailoverConflictErrF:               {Code: 400, ErrCode: 10188, Description: "stream has {gap} messages not replicated to the new primary"},
+		JSStreamFailoverInactiveErr:                {Code: 400, ErrCode: 10190, Description: "stream mirror is not replicating from its origin"},
+		JSStreamFailoverLagErrF:                    {Code: 400, ErrCode: 10187, Description: "stream mirror has not replicated {gap} messages of its origin"},
 		JSStreamGeneralErrorF:                      {Code: 500, ErrCode: 10051, Description: "{err}"},
 		JSStreamHeaderExceedsMaximumErr:            {Code: 400, ErrCode: 10097, Description: "header size exceeds maximum allowed of 64k"},
 		JSStreamInfoMaxSubjectsErr:                 {Code: 500, ErrCode: 10117, Description: "subject details would exceed maximum allowed"},
@@ -693,12 +739,14 @@ var (
 		JSStreamNameExistRestoreFailedErr:          {Code: 400, ErrCode: 10130, Description: "stream name already in use, cannot restore"},
 		JSStreamNotFoundErr:                        {Code: 404, ErrCode: 10059, Description: "stream not found"},
 		JSStreamNotMatchErr:                        {Code: 400, ErrCode: 10060, Description: "expected stream does not match"},
+		JSStreamNotMirrorErr:                       {Code: 400, ErrCode: 10189, Description: "stream is not a mirror"},
 		JSStreamOfflineErr:                         {Code: 500, ErrCode: 10118, Description: "stream is offline"},
 		JSStreamPurgeFailedF:                       {Code: 500, ErrCode: 10110, Description: "{err}"},
 		JSStreamReplicasNotSupportedErr:            {Code: 500, ErrCode: 10074, Description: "replicas > 1 not supported in non-clustered mode"},
 		JSStreamReplicasNotUpdatableErr:            {Code: 400, ErrCode: 10061, Description: "Replicas configuration can not be updated"},
 		JSStreamRestoreErrF:                        {Code: 500, ErrCode: 10062, Description: "restore failed: {err}"},
 		JSStreamRollupFailedF:                      {Code: 500, ErrCode: 10111, Description: "{err}"},
+		JSStreamSchemaValidationFailedErrF:         {Code: 400, ErrCode: 10183, Description: "message failed schema validation: {err}"},
 		JSStreamSealedErr:                          {Code: 400, ErrCode: 10109, Description: "invalid operation on sealed stream"},
 		JSStreamSequenceNotMatchErr:                {Code: 503, ErrCode: 10063, Description: "expected stream sequence does not match"},
 		JSStreamSnapshotErrF:                       {Code: 500, ErrCode: 10064, Description: "snapshot failed: {err}"},
@@ -781,6 +829,22 @@ func NewJSAtomicPublishIncompleteBatchError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSAtomicPublishIncompleteBatchErr]
 }
 
+// NewJSAtomicPublishInvalidBatchAckError creates a new JSAtomicPublishInvalidBatchAckErrF error: "atomic publish batch ack is invalid: {err}"
+func NewJSAtomicPublishInvalidBatchAckError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSAtomicPublishInvalidBatchAckErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSAtomicPublishMissingSeqError creates a new JSAtomicPublishMissingSeqErr error: "atomic publish sequence is missing"
 func NewJSAtomicPublishMissingSeqError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -867,6 +931,16 @@ func NewJSClusterNotLeaderError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSClusterNotLeaderErr]
 }
 
+// NewJSClusterPeerNotLearnerError creates a new JSClusterPeerNotLearnerErr error: "peer not a learner"
+func NewJSClusterPeerNotLearnerError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSClusterPeerNotLearnerErr]
+}
+
 // NewJSClusterPeerNotMemberError creates a new JSClusterPeerNotMemberErr error: "peer not a member"
 func NewJSClusterPeerNotMemberError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1345,6 +1419,22 @@ func NewJSConsumerMetadataLengthError(limit interface{}, opts ...ErrorOption) *A
 	}
 }
 
+// NewJSConsumerMsgFilterInvalidError creates a new JSConsumerMsgFilterInvalidErrF error: "consumer message filter is invalid: {err}"
+func NewJSConsumerMsgFilterInvalidError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSConsumerMsgFilterInvalidErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSConsumerMultipleFiltersNotAllowedError creates a new JSConsumerMultipleFiltersNotAllowed error: "consumer with multiple subject filters cannot use subject based API"
 func NewJSConsumerMultipleFiltersNotAllowedError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -1703,6 +1793,26 @@ func NewJSMessageIncrPayloadError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSMessageIncrPayloadErr]
 }
 
+// NewJSMessageScheduleInvalidError creates a new JSMessageScheduleInvalidErr error: "invalid message schedule"
+func NewJSMessageScheduleInvalidError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSMessageScheduleInvalidErr]
+}
+
+// NewJSMessageSchedulesDisabledError creates a new JSMessageSchedulesDisabledErr error: "message schedules is disabled"
+func NewJSMessageSchedulesDisabledError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSMessageSchedulesDisabledErr]
+}
+
 // NewJSMessageTTLDisabledError creates a new JSMessageTTLDisabledErr error: "per-message TTL is disabled"
 func NewJSMessageTTLDisabledError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2149,6 +2259,32 @@ func NewJSStreamAssignmentError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSStreamCloneError creates a new JSStreamCloneErrF error: "clone failed: {err}"
+func NewJSStreamCloneError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamCloneErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSStreamCloneNotLocalError creates a new JSStreamCloneNotLocalErr error: "stream clone must be placed on a server hosting the source stream"
+func NewJSStreamCloneNotLocalError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamCloneNotLocalErr]
+}
+
 // NewJSStreamCreateError creates a new JSStreamCreateErrF error: "{err}"
 func NewJSStreamCreateError(err error, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2233,6 +2369,48 @@ func NewJSStreamExternalDelPrefixOverlapsError(prefix interface{}, subject inter
 	}
 }
 
+// NewJSStreamFailoverConflictError creates a new JSStreamFailoverConflictErrF error: "stream has {gap} messages not replicated to the new primary"
+func NewJSStreamFailoverConflictError(gap interface{}, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamFailoverConflictErrF]
+	args := e.toReplacerArgs([]interface{}{"{gap}", gap})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
+// NewJSStreamFailoverInactiveError creates a new JSStreamFailoverInactiveErr error: "stream mirror is not replicating from its origin"
+func NewJSStreamFailoverInactiveError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamFailoverInactiveErr]
+}
+
+// NewJSStreamFailoverLagError creates a new JSStreamFailoverLagErrF error: "stream mirror has not replicated {gap} messages of its origin"
+func NewJSStreamFailoverLagError(gap interface{}, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamFailoverLagErrF]
+	args := e.toReplacerArgs([]interface{}{"{gap}", gap})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSStreamGeneralError creates a new JSStreamGeneralErrorF error: "{err}"
 func NewJSStreamGeneralError(err error, opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2479,6 +2657,16 @@ func NewJSStreamNotMatchError(opts ...ErrorOption) *ApiError {
 	return ApiErrors[JSStreamNotMatchErr]
 }
 
+// NewJSStreamNotMirrorError creates a new JSStreamNotMirrorErr error: "stream is not a mirror"
+func NewJSStreamNotMirrorError(opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	return ApiErrors[JSStreamNotMirrorErr]
+}
+
 // NewJSStreamOfflineError creates a new JSStreamOfflineErr error: "stream is offline"
 func NewJSStreamOfflineError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -2557,6 +2745,22 @@ func NewJSStreamRollupFailedError(err error, opts ...ErrorOption) *ApiError {
 	}
 }
 
+// NewJSStreamSchemaValidationFailedError creates a new JSStreamSchemaValidationFailedErrF error: "message failed schema validation: {err}"
+func NewJSStreamSchemaValidationFailedError(err error, opts ...ErrorOption) *ApiError {
+	eopts := parseOpts(opts)
+	if ae, ok := eopts.err.(*ApiError); ok {
+		return ae
+	}
+
+	e := ApiErrors[JSStreamSchemaValidationFailedErrF]
+	args := e.toReplacerArgs([]interface{}{"{err}", err})
+	return &ApiError{
+		Code:        e.Code,
+		ErrCode:     e.ErrCode,
+		Description: strings.NewReplacer(args...).Replace(e.Description),
+	}
+}
+
 // NewJSStreamSealedError creates a new JSStreamSealedErr error: "invalid operation on sealed stream"
 func NewJSStreamSealedError(opts ...ErrorOption) *ApiError {
 	eopts := parseOpts(opts)
@@ -79,6 +79,50 @@ type JSStreamActionAdvisory struct {
 
 const JSStreamActionAdvisoryType = "io.nats.jetstream.advisory.v1.stream_action"
 
+// JSStreamAutoCreatedAdvisory indicates that a stream was created by an account auto-create rule
+// after a message was published to a subject matching the rule.
+type JSStreamAutoCreatedAdvisory struct {
+	TypedEvent
+	Stream  string `json:"stream"`
+	Rule    string `json:"rule"`
+	Subject string `json:"subject"`
+	Domain  string `json:"domain,omitempty"`
+}
+
+const JSStreamAutoCreatedAdvisoryType = "io.nats.jetstream.advisory.v1.stream_auto_created"
+
+// JSStreamReplicationLagAdvisory indicates that a mirror fell more than its lag threshold
+// behind its origin, or that it has caught up again.
+type JSStreamReplicationLagAdvisory struct {
+	TypedEvent
+	Stream    string `json:"stream"`
+	Mirror    string `json:"mirror"`
+	Lag       uint64 `json:"lag"`
+	LastSeq   uint64 `json:"last_seq"`
+	Threshold uint64 `json:"threshold"`
+	Domain    string `json:"domain,omitempty"`
+}
+
+// JSStreamReplicationLagExceededAdvisoryType is the schema type for JSStreamReplicationLagAdvisory when the threshold is exceeded
+const JSStreamReplicationLagExceededAdvisoryType = "io.nats.jetstream.advisory.v1.stream_replication_lag_exceeded"
+
+// JSStreamReplicationLagRecoveredAdvisoryType is the schema type for JSStreamReplicationLagAdvisory when the mirror caught up
+const JSStreamReplicationLagRecoveredAdvisoryType = "io.nats.jetstream.advisory.v1.stream_replication_lag_recovered"
+
+// JSStreamRoleChangedAdvisory indicates that a stream of a replication pair was promoted
+// from mirror to primary, or demoted from primary to mirror.
+type JSStreamRoleChangedAdvisory struct {
+	TypedEvent
+	Stream  string        `json:"stream"`
+	Role    string        `json:"role"`
+	Mirror  *StreamSource `json:"mirror,omitempty"`
+	LastSeq uint64        `json:"last_seq"`
+	Gap     uint64        `json:"gap,omitempty"`
+	Domain  string        `json:"domain,omitempty"`
+}
+
+const JSStreamRoleChangedAdvisoryType = "io.nats.jetstream.advisory.v1.stream_role_changed"
+
 // JSConsumerActionAdvisory indicates that a consumer was created or deleted
 type JSConsumerActionAdvisory struct {
 	TypedEvent
@@ -102,6 +146,26 @@ type JSConsumerPauseAdvisory struct {
 
 const JSConsumerPauseAdvisoryType = "io.nats.jetstream.advisory.v1.consumer_pause"
 
+// JSConsumerLagAdvisory indicates that a consumer exceeded its lag thresholds,
+// or that it is back within all of them.
+type JSConsumerLagAdvisory struct {
+	TypedEvent
+	Stream           string                 `json:"stream"`
+	Consumer         string                 `json:"consumer"`
+	PendingMsgs      uint64                 `json:"pending_msgs"`
+	PendingBytes     uint64                 `json:"pending_bytes"`
+	OldestUnackedAge time.Duration          `json:"oldest_unacked_age"`
+	Thresholds       *ConsumerLagThresholds `json:"thresholds"`
+	Exceeded         []string               `json:"exceeded,omitempty"`
+	Domain           string                 `json:"domain,omitempty"`
+}
+
+// JSConsumerLagExceededAdvisoryType is the schema type for JSConsumerLagAdvisory when a threshold is exceeded
+const JSConsumerLagExceededAdvisoryType = "io.nats.jetstream.advisory.v1.consumer_lag_exceeded"
+
+// JSConsumerLagRecoveredAdvisoryType is the schema type for JSConsumerLagAdvisory when all thresholds are met again
+const JSConsumerLagRecoveredAdvisoryType = "io.nats.jetstream.advisory.v1.consumer_lag_recovered"
+
 // JSConsumerAckMetric is a metric published when a user acknowledges a message, the
 // number of these that will be published is dependent on SampleFrequency
 type JSConsumerAckMetric struct {
@@ -308,6 +372,21 @@ type JSConsumerGroupUnpinnedAdvisory struct {
 	Reason string `json:"reason"`
 }
 
+// JSStreamSchemaValidationFailedAdvisoryType is sent when a message is rejected by the stream schema.
+const JSStreamSchemaValidationFailedAdvisoryType = "io.nats.jetstream.advisory.v1.stream_schema_validation_failed"
+
+// JSStreamSchemaValidationFailedAdvisory indicates that a message did not conform to the stream schema.
+type JSStreamSchemaValidationFailedAdvisory struct {
+	TypedEvent
+	Stream  string `json:"stream"`
+	Subject string `json:"subject"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error"`
+	// Suppressed is the number of rejections since the last advisory that were not sent.
+	Suppressed uint64 `json:"suppressed,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+}
+
 // JSServerOutOfStorageAdvisoryType is sent when the server is out of storage space.
 const JSServerOutOfStorageAdvisoryType = "io.nats.jetstream.advisory.v1.server_out_of_space"
 
@@ -0,0 +1,274 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MsgFilterOp is the comparison done by a message filter.
+type MsgFilterOp string
+
+const (
+	// MsgFilterEq matches if the value is equal to the filter's value.
+	MsgFilterEq = MsgFilterOp("eq")
+	// MsgFilterNeq matches if the value is absent or not equal to the filter's value.
+	MsgFilterNeq = MsgFilterOp("neq")
+	// MsgFilterPrefix matches if the value starts with the filter's value.
+	MsgFilterPrefix = MsgFilterOp("prefix")
+	// MsgFilterExists matches if the value is present.
+	MsgFilterExists = MsgFilterOp("exists")
+	// MsgFilterGt, MsgFilterGte, MsgFilterLt and MsgFilterLte compare the
+	// value and the filter's value as numbers.
+	MsgFilterGt  = MsgFilterOp("gt")
+	MsgFilterGte = MsgFilterOp("gte")
+	MsgFilterLt  = MsgFilterOp("lt")
+	MsgFilterLte = MsgFilterOp("lte")
+)
+
+const (
+	// Limits on the size of a message filter, since it is evaluated
+	// for every message considered for delivery.
+	msgFilterMaxDepth = 8
+	msgFilterMaxNodes = 64
+)
+
+// MsgFilter is a predicate evaluated against the headers and optionally the JSON
+// payload of a stored message. A filter is either a comparison on the value of a
+// single header or payload field, or a boolean combination of other filters.
+//
+// Header names are matched case sensitively, and only the first value of a header
+// is considered. Payload is a dot separated path into a JSON object, where array
+// elements are selected by their index, e.g. "order.items.0.sku".
+type MsgFilter struct {
+	Header  string      `json:"header,omitempty"`
+	Payload string      `json:"payload,omitempty"`
+	Op      MsgFilterOp `json:"op,omitempty"`
+	Value   string      `json:"value,omitempty"`
+
+	All []*MsgFilter `json:"all,omitempty"`
+	Any []*MsgFilter `json:"any,omitempty"`
+	Not *MsgFilter   `json:"not,omitempty"`
+}
+
+func (f *MsgFilter) validate() error {
+	nodes := 0
+	return f.validateNode(0, &nodes)
+}
+
+func (f *MsgFilter) validateNode(depth int, nodes *int) error {
+	if f == nil {
+		return errors.New("filter can not be empty")
+	}
+	if depth >= msgFilterMaxDepth {
+		return fmt.Errorf("filter can not be nested more than %d levels", msgFilterMaxDepth)
+	}
+	if *nodes++; *nodes > msgFilterMaxNodes {
+		return fmt.Errorf("filter can not have more than %d conditions", msgFilterMaxNodes)
+	}
+
+	var kinds int
+	if len(f.All) > 0 {
+		kinds++
+	}
+	if len(f.Any) > 0 {
+		kinds++
+	}
+	if f.Not != nil {
+		kinds++
+	}
+	isLeaf := f.Header != _EMPTY_ || f.Payload != _EMPTY_ || f.Op != _EMPTY_ || f.Value != _EMPTY_
+	if isLeaf {
+		kinds++
+	}
+	if kinds != 1 {
+		return errors.New("filter must have exactly one of a condition, all, any or not")
+	}
+
+	for _, sf := range f.All {
+		if err := sf.validateNode(depth+1, nodes); err != nil {
+			return err
+		}
+	}
+	for _, sf := range f.Any {
+		if err := sf.validateNode(depth+1, nodes); err != nil {
+			return err
+		}
+	}
+	if f.Not != nil {
+		return f.Not.validateNode(depth+1, nodes)
+	}
+	if !isLeaf {
+		return nil
+	}
+
+	if (f.Header == _EMPTY_) == (f.Payload == _EMPTY_) {
+		return errors.New("filter condition needs either a header or a payload path")
+	}
+	if f.Header != _EMPTY_ && strings.ContainsAny(f.Header, ": \t\r\n") {
+		return fmt.Errorf("filter header %q is not valid", f.Header)
+	}
+	if f.Payload != _EMPTY_ {
+		for _, tk := range strings.Split(f.Payload, ".") {
+			if tk == _EMPTY_ {
+				return fmt.Errorf("filter payload path %q is not valid", f.Payload)
+			}
+		}
+	}
+	switch f.Op {
+	case MsgFilterEq, MsgFilterNeq, MsgFilterPrefix:
+	case MsgFilterExists:
+		if f.Value != _EMPTY_ {
+			return errors.New("filter exists condition can not have a value")
+		}
+	case MsgFilterGt, MsgFilterGte, MsgFilterLt, MsgFilterLte:
+		if _, err := strconv.ParseFloat(f.Value, 64); err != nil {
+			return fmt.Errorf("filter %s condition value %q is not a number", f.Op, f.Value)
+		}
+	case _EMPTY_:
+		return errors.New("filter condition needs an op")
+	default:
+		return fmt.Errorf("filter op %q is not valid", f.Op)
+	}
+	return nil
+}
+
+// Returns true if the message with the given header and payload matches.
+// The payload is only decoded if the filter references it.
+func (f *MsgFilter) match(hdr, msg []byte) bool {
+	var (
+		doc    any
+		parsed bool
+		valid  bool
+	)
+	payload := func() (any, bool) {
+		if !parsed {
+			parsed = true
+			valid = json.Unmarshal(msg, &doc) == nil
+		}
+		return doc, valid
+	}
+	return f.eval(hdr, payload)
+}
+
+func (f *MsgFilter) eval(hdr []byte, payload func() (any, bool)) bool {
+	switch {
+	case len(f.All) > 0:
+		for _, sf := range f.All {
+			if !sf.eval(hdr, payload) {
+				return false
+			}
+		}
+		return true
+	case len(f.Any) > 0:
+		for _, sf := range f.Any {
+			if sf.eval(hdr, payload) {
+				return true
+			}
+		}
+		return false
+	case f.Not != nil:
+		return !f.Not.eval(hdr, payload)
+	}
+
+	var (
+		val   string
+		found bool
+	)
+	if f.Header != _EMPTY_ {
+		if v := sliceHeader(f.Header, hdr); v != nil {
+			val, found = bytesToString(v), true
+		}
+	} else if doc, ok := payload(); ok {
+		val, found = msgFilterPayloadValue(doc, f.Payload)
+	}
+
+	switch f.Op {
+	case MsgFilterExists:
+		return found
+	case MsgFilterNeq:
+		return !found || val != f.Value
+	}
+	if !found {
+		return false
+	}
+	switch f.Op {
+	case MsgFilterEq:
+		return val == f.Value
+	case MsgFilterPrefix:
+		return strings.HasPrefix(val, f.Value)
+	}
+	// Numeric comparisons, values that are not numbers never match.
+	v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		return false
+	}
+	fv, err := strconv.ParseFloat(f.Value, 64)
+	if err != nil {
+		return false
+	}
+	switch f.Op {
+	case MsgFilterGt:
+		return v > fv
+	case MsgFilterGte:
+		return v >= fv
+	case MsgFilterLt:
+		return v < fv
+	case MsgFilterLte:
+		return v <= fv
+	}
+	return false
+}
+
+// Walks the decoded JSON document following the dotted path and returns the
+// value found as a string. Objects and arrays are returned JSON encoded.
+func msgFilterPayloadValue(doc any, path string) (string, bool) {
+	for _, tk := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]any:
+			var ok bool
+			if doc, ok = v[tk]; !ok {
+				return _EMPTY_, false
+			}
+		case []any:
+			i, err := strconv.Atoi(tk)
+			if err != nil || i < 0 || i >= len(v) {
+				return _EMPTY_, false
+			}
+			doc = v[i]
+		default:
+			return _EMPTY_, false
+		}
+	}
+	switch v := doc.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "null", true
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return _EMPTY_, false
+		}
+		return string(b), true
+	}
+}
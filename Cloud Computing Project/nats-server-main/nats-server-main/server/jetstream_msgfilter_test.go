@@ -0,0 +1,114 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"testing"
+)
+
+func TestJetStreamMsgFilterValidate(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		f    *MsgFilter
+		ok   bool
+	}{
+		{"header eq", &MsgFilter{Header: "Region", Op: MsgFilterEq, Value: "eu"}, true},
+		{"payload gt", &MsgFilter{Payload: "order.total", Op: MsgFilterGt, Value: "10.5"}, true},
+		{"exists", &MsgFilter{Header: "Region", Op: MsgFilterExists}, true},
+		{"combination", &MsgFilter{All: []*MsgFilter{
+			{Header: "Region", Op: MsgFilterPrefix, Value: "eu-"},
+			{Not: &MsgFilter{Header: "Test", Op: MsgFilterExists}},
+		}}, true},
+		{"empty", &MsgFilter{}, false},
+		{"no op", &MsgFilter{Header: "Region", Value: "eu"}, false},
+		{"bad op", &MsgFilter{Header: "Region", Op: "like", Value: "eu"}, false},
+		{"header and payload", &MsgFilter{Header: "Region", Payload: "region", Op: MsgFilterEq}, false},
+		{"bad header", &MsgFilter{Header: "Re gion", Op: MsgFilterEq}, false},
+		{"bad path", &MsgFilter{Payload: "order..total", Op: MsgFilterEq}, false},
+		{"not a number", &MsgFilter{Header: "Total", Op: MsgFilterLt, Value: "ten"}, false},
+		{"exists with value", &MsgFilter{Header: "Region", Op: MsgFilterExists, Value: "eu"}, false},
+		{"condition and any", &MsgFilter{Header: "Region", Op: MsgFilterExists,
+			Any: []*MsgFilter{{Header: "Test", Op: MsgFilterExists}}}, false},
+		{"nil child", &MsgFilter{Any: []*MsgFilter{nil}}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.f.validate()
+			if test.ok {
+				require_NoError(t, err)
+			} else {
+				require_Error(t, err)
+			}
+		})
+	}
+
+	// Too deeply nested.
+	f := &MsgFilter{Header: "Region", Op: MsgFilterExists}
+	for i := 0; i < msgFilterMaxDepth; i++ {
+		f = &MsgFilter{Not: f}
+	}
+	require_Error(t, f.validate())
+}
+
+func TestJetStreamMsgFilterMatch(t *testing.T) {
+	hdr := genHeader(nil, "Region", "eu-west")
+	hdr = genHeader(hdr, "Priority", "7")
+	msg := []byte(`{"order":{"total":42.5,"express":true,"items":[{"sku":"A1"},{"sku":"B2"}]}}`)
+
+	for _, test := range []struct {
+		name  string
+		f     *MsgFilter
+		match bool
+	}{
+		{"header eq", &MsgFilter{Header: "Region", Op: MsgFilterEq, Value: "eu-west"}, true},
+		{"header eq mismatch", &MsgFilter{Header: "Region", Op: MsgFilterEq, Value: "us-east"}, false},
+		{"header neq", &MsgFilter{Header: "Region", Op: MsgFilterNeq, Value: "us-east"}, true},
+		{"missing header neq", &MsgFilter{Header: "Missing", Op: MsgFilterNeq, Value: "x"}, true},
+		{"missing header eq", &MsgFilter{Header: "Missing", Op: MsgFilterEq, Value: "x"}, false},
+		{"header prefix", &MsgFilter{Header: "Region", Op: MsgFilterPrefix, Value: "eu-"}, true},
+		{"header exists", &MsgFilter{Header: "Priority", Op: MsgFilterExists}, true},
+		{"header gte", &MsgFilter{Header: "Priority", Op: MsgFilterGte, Value: "7"}, true},
+		{"header lt", &MsgFilter{Header: "Priority", Op: MsgFilterLt, Value: "7"}, false},
+		{"header not a number", &MsgFilter{Header: "Region", Op: MsgFilterGt, Value: "1"}, false},
+		{"payload gt", &MsgFilter{Payload: "order.total", Op: MsgFilterGt, Value: "40"}, true},
+		{"payload lte", &MsgFilter{Payload: "order.total", Op: MsgFilterLte, Value: "40"}, false},
+		{"payload bool", &MsgFilter{Payload: "order.express", Op: MsgFilterEq, Value: "true"}, true},
+		{"payload array", &MsgFilter{Payload: "order.items.1.sku", Op: MsgFilterEq, Value: "B2"}, true},
+		{"payload array out of range", &MsgFilter{Payload: "order.items.2.sku", Op: MsgFilterExists}, false},
+		{"payload missing", &MsgFilter{Payload: "order.missing", Op: MsgFilterExists}, false},
+		{"all", &MsgFilter{All: []*MsgFilter{
+			{Header: "Region", Op: MsgFilterPrefix, Value: "eu-"},
+			{Payload: "order.total", Op: MsgFilterGt, Value: "40"},
+		}}, true},
+		{"all mismatch", &MsgFilter{All: []*MsgFilter{
+			{Header: "Region", Op: MsgFilterPrefix, Value: "eu-"},
+			{Payload: "order.total", Op: MsgFilterGt, Value: "50"},
+		}}, false},
+		{"any", &MsgFilter{Any: []*MsgFilter{
+			{Header: "Region", Op: MsgFilterPrefix, Value: "us-"},
+			{Payload: "order.total", Op: MsgFilterGt, Value: "40"},
+		}}, true},
+		{"not", &MsgFilter{Not: &MsgFilter{Header: "Region", Op: MsgFilterExists}}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require_NoError(t, test.f.validate())
+			require_Equal(t, test.f.match(hdr, msg), test.match)
+		})
+	}
+
+	// Payload conditions never match a payload that is not JSON.
+	f := &MsgFilter{Payload: "order.total", Op: MsgFilterExists}
+	require_False(t, f.match(hdr, []byte("not json")))
+}
@@ -0,0 +1,689 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/minio/highwayhash"
+)
+
+// The functions here work directly on the store directory of a stopped server,
+// so streams can be backed up and restored without a running system.
+// The server is only used for its options, e.g. the store directory and
+// the JetStream encryption keys.
+
+// BackupStream writes a snapshot of a stream in the given account to w. The format
+// is the same as a snapshot taken through the JetStream API, including consumers.
+// Opening a store can change it, so the snapshot is taken from a copy of the stopped
+// store, leaving the original untouched. Message blocks are checked before being written.
+func (s *Server) BackupStream(account, stream string, w io.Writer) (*StreamState, error) {
+	adir, err := s.offlineAccountDir(account)
+	if err != nil {
+		return nil, err
+	}
+	if !isValidName(stream) {
+		return nil, fmt.Errorf("invalid stream name %q", stream)
+	}
+	if _, err := os.Stat(filepath.Join(adir, streamsDir, stream)); err != nil {
+		return nil, fmt.Errorf("stream %q not found in account %q", stream, account)
+	}
+	sdir, cleanup, err := copyOfflineStream(adir, account, stream, "backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	cfg, err := s.readStreamMeta(sdir, account)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := s.openOfflineStore(account, sdir, cfg, true)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Stop()
+	if err := s.openOfflineConsumers(fs, account); err != nil {
+		return nil, err
+	}
+	if bad := fs.verifyBlocks(); bad > 0 {
+		return nil, fmt.Errorf("backup check detected %d bad message blocks", bad)
+	}
+
+	sr, err := fs.Snapshot(0, false, true)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(w, sr.Reader)
+	sr.Reader.Close()
+	if serr := <-sr.errCh; serr != _EMPTY_ {
+		return nil, errors.New(serr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sr.State, nil
+}
+
+// RestoreBackup restores a snapshot read from r into the store directory of the
+// given account. The stream must not exist yet. Message blocks are checked before
+// the stream is moved into place, and if the server is configured with a JetStream
+// key the restored stream and its consumers will be encrypted.
+func (s *Server) RestoreBackup(account string, r io.Reader) (*FileStreamInfo, error) {
+	adir, err := s.offlineAccountDir(account)
+	if err != nil {
+		return nil, err
+	}
+	sd := filepath.Join(adir, snapsDir)
+	if err := os.MkdirAll(sd, defaultDirPerms); err != nil {
+		return nil, fmt.Errorf("could not create snapshots directory - %v", err)
+	}
+	sdir, err := os.MkdirTemp(sd, "restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(sdir)
+	sdirCheck := filepath.Clean(sdir) + string(os.PathSeparator)
+
+	tr := tar.NewReader(s2.NewReader(r))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break // End of snapshot
+		}
+		if err != nil {
+			return nil, err
+		}
+		fpath := filepath.Join(sdir, filepath.Clean(hdr.Name))
+		if hdr.Typeflag != tar.TypeReg || !strings.HasPrefix(fpath, sdirCheck) {
+			return nil, fmt.Errorf("unexpected content %q in snapshot", hdr.Name)
+		}
+		os.MkdirAll(filepath.Dir(fpath), defaultDirPerms)
+		fd, err := os.OpenFile(fpath, os.O_CREATE|os.O_RDWR, defaultFilePerms)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(fd, tr)
+		fd.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A snapshot that failed part way will have recorded why.
+	if buf, err := os.ReadFile(filepath.Join(sdir, errFile)); err == nil {
+		return nil, fmt.Errorf("snapshot is incomplete: %s", buf)
+	}
+
+	// Snapshots are always plaintext, so we can check the metadata directly.
+	buf, err := os.ReadFile(filepath.Join(sdir, JetStreamMetaFile))
+	if err != nil {
+		return nil, err
+	}
+	var fcfg FileStreamInfo
+	if err := json.Unmarshal(buf, &fcfg); err != nil {
+		return nil, err
+	}
+	if !isValidName(fcfg.Name) {
+		return nil, fmt.Errorf("invalid stream name %q in snapshot", fcfg.Name)
+	}
+	if err := checkMetaSum(fcfg.Name, sdir, buf); err != nil {
+		return nil, err
+	}
+
+	ndir := filepath.Join(adir, streamsDir, fcfg.Name)
+	if _, err := os.Stat(ndir); err == nil {
+		return nil, fmt.Errorf("stream %q already exists in account %q", fcfg.Name, account)
+	}
+
+	// Check the message blocks before moving into place. If we are
+	// encrypted, opening the store will also convert it.
+	fs, err := s.openOfflineStore(account, sdir, &fcfg, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openOfflineConsumers(fs, account); err != nil {
+		fs.Stop()
+		return nil, err
+	}
+	ld := fs.checkMsgs()
+	fs.Stop()
+	if ld != nil && len(ld.Msgs) > 0 {
+		return nil, fmt.Errorf("restore check detected %d bad messages", len(ld.Msgs))
+	}
+
+	if err := os.MkdirAll(filepath.Join(adir, streamsDir), defaultDirPerms); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(sdir, ndir); err != nil {
+		return nil, err
+	}
+	return &fcfg, nil
+}
+
+// Returns the JetStream directory of the account in the configured store directory.
+func (s *Server) offlineAccountDir(account string) (string, error) {
+	opts := s.getOpts()
+	if opts.StoreDir == _EMPTY_ {
+		return _EMPTY_, errors.New("no store directory configured")
+	}
+	if !isValidName(account) {
+		return _EMPTY_, fmt.Errorf("invalid account name %q", account)
+	}
+	return filepath.Join(opts.StoreDir, JetStreamStoreDir, account), nil
+}
+
+// readStreamMeta will read and check the metafile of a stream directory,
+// decrypting it if needed.
+func (s *Server) readStreamMeta(sdir, account string) (*FileStreamInfo, error) {
+	name := filepath.Base(sdir)
+	buf, err := os.ReadFile(filepath.Join(sdir, JetStreamMetaFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading stream metafile: %v", err)
+	}
+	if err := checkMetaSum(name, sdir, buf); err != nil {
+		return nil, err
+	}
+	if ekey, err := os.ReadFile(filepath.Join(sdir, JetStreamMetaFileKey)); err == nil {
+		if buf, _, err = s.decryptMeta(s.getOpts().JetStreamCipher, ekey, buf, account, name); err != nil {
+			return nil, fmt.Errorf("error decrypting stream metafile: %v", err)
+		}
+	}
+	var cfg FileStreamInfo
+	if err := json.Unmarshal(buf, &cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshalling stream metafile: %v", err)
+	}
+	return &cfg, nil
+}
+
+// readConsumerMeta will read the metafile of a consumer directory, decrypting it if needed.
+func (s *Server) readConsumerMeta(odir, account, stream string) (*FileConsumerInfo, error) {
+	name := filepath.Base(odir)
+	buf, err := os.ReadFile(filepath.Join(odir, JetStreamMetaFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading consumer metafile: %v", err)
+	}
+	if ekey, err := os.ReadFile(filepath.Join(odir, JetStreamMetaFileKey)); err == nil {
+		if buf, _, err = s.decryptMeta(s.getOpts().JetStreamCipher, ekey, buf, account, stream+tsep+name); err != nil {
+			return nil, fmt.Errorf("error decrypting consumer metafile: %v", err)
+		}
+	}
+	var cfg FileConsumerInfo
+	if err := json.Unmarshal(buf, &cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshalling consumer metafile: %v", err)
+	}
+	return &cfg, nil
+}
+
+// Checks the stream metafile against its checksum.
+func checkMetaSum(name, sdir string, buf []byte) error {
+	sum, err := os.ReadFile(filepath.Join(sdir, JetStreamMetaFileSum))
+	if err != nil {
+		return fmt.Errorf("error reading stream metafile checksum: %v", err)
+	}
+	key := sha256.Sum256([]byte(name))
+	hh, err := highwayhash.New64(key[:])
+	if err != nil {
+		return err
+	}
+	hh.Write(buf)
+	if checksum := hex.EncodeToString(hh.Sum(nil)); checksum != string(sum) {
+		return fmt.Errorf("stream metafile checksums do not match %q vs %q", sum, checksum)
+	}
+	return nil
+}
+
+// openOfflineStore opens the stream directory with the server's encryption keys
+// and tiered storage. Nothing that would remove or move messages is
+// applied, that is left for when the server recovers the stream.
+// A copy of a stream directory shares the tiered blobs of the original, so these
+// are never removed through it.
+func (s *Server) openOfflineStore(account, sdir string, cfg *FileStreamInfo, shared bool) (*fileStore, error) {
+	opts := s.getOpts()
+	blobs, err := tieredBlobStoreFromOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	fcfg := FileStoreConfig{
+		StoreDir:         sdir,
+		Compression:      cfg.Compression,
+		CompressionLevel: cfg.CompressionLevel,
+		BlobStore:        blobs,
+		BlobCacheSize:    opts.JetStreamTieredCacheSize,
+		srv:              s,
+		sharedBlobs:      shared,
+	}
+	prf := s.jsKeyGen(opts.JetStreamKey, account)
+	if prf != nil {
+		fcfg.Cipher = opts.JetStreamCipher
+	}
+	oldprf := s.jsKeyGen(opts.JetStreamOldKey, account)
+
+	scfg := cfg.StreamConfig
+	scfg.MaxMsgs, scfg.MaxBytes, scfg.MaxAge, scfg.MaxMsgsPer = -1, -1, 0, -1
+	scfg.FirstSeq, scfg.AllowMsgTTL, scfg.Tiering = 0, false, nil
+
+	fs, err := newFileStoreWithCreated(fcfg, scfg, cfg.Created, prf, oldprf)
+	if err != nil {
+		return nil, err
+	}
+	// Put back the real config, this is what is written to the metafile and snapshots.
+	fs.mu.Lock()
+	fs.cfg.StreamConfig = cfg.StreamConfig
+	err = fs.writeStreamMeta()
+	fs.mu.Unlock()
+	if err != nil {
+		fs.Stop()
+		return nil, err
+	}
+	return fs, nil
+}
+
+// openOfflineConsumers opens the consumer stores found in the stream directory.
+func (s *Server) openOfflineConsumers(fs *fileStore, account string) error {
+	odir := filepath.Join(fs.fcfg.StoreDir, consumerDir)
+	ofis, _ := os.ReadDir(odir)
+	for _, ofi := range ofis {
+		ocfg, err := s.readConsumerMeta(filepath.Join(odir, ofi.Name()), account, fs.cfg.Name)
+		if err == nil {
+			_, err = fs.ConsumerStore(ofi.Name(), &ocfg.ConsumerConfig)
+		}
+		if err != nil {
+			return fmt.Errorf("consumer %q: %v", ofi.Name(), err)
+		}
+	}
+	return nil
+}
+
+// copyOfflineStream copies the directory of a stream to a temporary directory in the
+// account's snapshots directory, so it can be opened without changing the original.
+// The returned function removes the copy.
+func copyOfflineStream(adir, account, stream, prefix string) (string, func(), error) {
+	tdir := filepath.Join(adir, snapsDir)
+	if err := os.MkdirAll(tdir, defaultDirPerms); err != nil {
+		return _EMPTY_, nil, fmt.Errorf("could not create snapshots directory - %v", err)
+	}
+	tmp, err := os.MkdirTemp(tdir, prefix)
+	if err != nil {
+		return _EMPTY_, nil, err
+	}
+	// Keep the account and stream names in the path, the store derives them from it.
+	cdir := filepath.Join(tmp, account, streamsDir, stream)
+	if err := copyStoreDir(filepath.Join(adir, streamsDir, stream), cdir); err != nil {
+		os.RemoveAll(tmp)
+		return _EMPTY_, nil, fmt.Errorf("could not copy stream directory - %v", err)
+	}
+	return cdir, func() { os.RemoveAll(tmp) }, nil
+}
+
+// copyStoreDir copies the regular files and directories under src to dst.
+func copyStoreDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, defaultDirPerms)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFilePerms)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// StreamCheck is the result of checking the store of a stream.
+type StreamCheck struct {
+	Account   string          `json:"account"`
+	Stream    string          `json:"stream"`
+	Blocks    int             `json:"blocks"`
+	Msgs      uint64          `json:"messages"`
+	Bytes     uint64          `json:"bytes"`
+	FirstSeq  uint64          `json:"first_seq"`
+	LastSeq   uint64          `json:"last_seq"`
+	Consumers int             `json:"consumers"`
+	BadBlocks []*BlockCheck   `json:"bad_blocks,omitempty"`
+	Lost      *LostStreamData `json:"lost,omitempty"`
+	Gaps      [][2]uint64     `json:"gaps,omitempty"`
+	Problems  []string        `json:"problems,omitempty"`
+	Repaired  bool            `json:"repaired,omitempty"`
+}
+
+// BlockCheck describes a message block with bad records.
+type BlockCheck struct {
+	Index    uint32          `json:"index"`
+	FirstSeq uint64          `json:"first_seq"`
+	LastSeq  uint64          `json:"last_seq"`
+	Lost     *LostStreamData `json:"lost,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// OK returns whether no damage was found. Gaps alone are not damage,
+// they are left behind when whole blocks are removed.
+func (sc *StreamCheck) OK() bool {
+	return len(sc.BadBlocks) == 0 && len(sc.Problems) == 0 && (sc.Lost == nil || len(sc.Lost.Msgs) == 0)
+}
+
+func (sc *StreamCheck) problem(format string, args ...any) {
+	sc.Problems = append(sc.Problems, fmt.Sprintf(format, args...))
+}
+
+// CheckStore checks the streams in the store directory, optionally limited to an
+// account and stream. Every record of every message block is validated, and the
+// stream and per-subject state in index.db and the consumer states are checked
+// against the message blocks. Without repair the checks run on a copy of each
+// stream. With repair, damaged blocks are truncated at their last valid record
+// and the index is rebuilt in place. Consumer state is only reported.
+func (s *Server) CheckStore(account, stream string, repair bool) ([]*StreamCheck, error) {
+	opts := s.getOpts()
+	if opts.StoreDir == _EMPTY_ {
+		return nil, errors.New("no store directory configured")
+	}
+	jsDir := filepath.Join(opts.StoreDir, JetStreamStoreDir)
+	afis, err := os.ReadDir(jsDir)
+	if err != nil {
+		return nil, err
+	}
+	var checks []*StreamCheck
+	for _, afi := range afis {
+		if !afi.IsDir() || account != _EMPTY_ && afi.Name() != account {
+			continue
+		}
+		sfis, _ := os.ReadDir(filepath.Join(jsDir, afi.Name(), streamsDir))
+		for _, sfi := range sfis {
+			// Skip partially deleted streams, they are removed on startup.
+			if !sfi.IsDir() || strings.HasPrefix(sfi.Name(), tsep) || stream != _EMPTY_ && sfi.Name() != stream {
+				continue
+			}
+			checks = append(checks, s.checkStream(afi.Name(), sfi.Name(), repair))
+		}
+	}
+	if len(checks) == 0 && (account != _EMPTY_ || stream != _EMPTY_) {
+		return nil, fmt.Errorf("no matching streams found in %q", jsDir)
+	}
+	return checks, nil
+}
+
+// checkStream checks the store of a single stream.
+func (s *Server) checkStream(account, stream string, repair bool) *StreamCheck {
+	sc := &StreamCheck{Account: account, Stream: stream}
+	adir := filepath.Join(s.getOpts().StoreDir, JetStreamStoreDir, account)
+	sdir := filepath.Join(adir, streamsDir, stream)
+
+	cfg, err := s.readStreamMeta(sdir, account)
+	if err != nil {
+		sc.problem("%v", err)
+		return sc
+	}
+
+	// Checking truncates damaged blocks, so unless repairing do this on a copy.
+	if !repair {
+		cdir, cleanup, err := copyOfflineStream(adir, account, stream, "check-")
+		if err != nil {
+			sc.problem("%v", err)
+			return sc
+		}
+		defer cleanup()
+		sdir = cdir
+	}
+
+	fs, err := s.openOfflineStore(account, sdir, cfg, !repair)
+	if err != nil {
+		sc.problem("could not open stream store: %v", err)
+		return sc
+	}
+	defer fs.Stop()
+
+	damaged := fs.checkBlocks(sc)
+	s.checkConsumers(fs, account, sc)
+	sc.Repaired = repair && damaged
+	return sc
+}
+
+// checkBlocks validates every record of the message blocks, truncating a block at its
+// last valid record, and rebuilds the stream and per-subject state from what remains.
+// The state recovered from index.db is compared to the rebuilt state.
+// Returns whether the blocks or the index were damaged.
+func (fs *fileStore) checkBlocks(sc *StreamCheck) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	np := len(sc.Problems)
+
+	// If index.db could be used, remember what it had.
+	var prior StreamState
+	var subjs map[string]uint64
+	if fs.rserr != nil {
+		sc.problem("stream state could not be recovered from index: %v", fs.rserr)
+	} else {
+		prior = fs.state
+		subjs = make(map[string]uint64, fs.psim.Size())
+		fs.psim.IterFast(func(subj []byte, psi *psi) bool {
+			subjs[string(subj)] = psi.total
+			return true
+		})
+	}
+
+	fs.checkAndFlushAllBlocks()
+	fs.psim, fs.tsl = fs.psim.Empty(), 0
+
+	var last uint64
+	for _, mb := range fs.blks {
+		fs.loadEncryptionForMsgBlock(mb)
+		ld, _, err := mb.rebuildState()
+		mb.mu.RLock()
+		first, lseq, msgs := atomic.LoadUint64(&mb.first.seq), atomic.LoadUint64(&mb.last.seq), mb.msgs
+		mb.mu.RUnlock()
+		if err != nil || ld != nil && len(ld.Msgs) > 0 {
+			bc := &BlockCheck{Index: mb.index, FirstSeq: first, LastSeq: lseq, Lost: ld}
+			if err != nil {
+				bc.Error = err.Error()
+			}
+			sc.BadBlocks = append(sc.BadBlocks, bc)
+			fs.addLostData(ld)
+		}
+		if msgs > 0 {
+			if last > 0 && first > last+1 {
+				sc.Gaps = append(sc.Gaps, [2]uint64{last + 1, first - 1})
+			}
+			last = lseq
+		}
+		fs.populateGlobalPerSubjectInfo(mb)
+	}
+	fs.rebuildStateLocked(nil)
+
+	if subjs != nil {
+		if prior.Msgs != fs.state.Msgs || prior.Bytes != fs.state.Bytes {
+			sc.problem("stream state in index has %d msgs and %d bytes, blocks have %d msgs and %d bytes",
+				prior.Msgs, prior.Bytes, fs.state.Msgs, fs.state.Bytes)
+		}
+		var mismatched int
+		fs.psim.IterFast(func(subj []byte, psi *psi) bool {
+			if total, ok := subjs[string(subj)]; !ok || total != psi.total {
+				mismatched++
+			}
+			delete(subjs, string(subj))
+			return true
+		})
+		if mismatched += len(subjs); mismatched > 0 {
+			sc.problem("per-subject state in index does not match blocks for %d subjects", mismatched)
+		}
+	}
+
+	// Never go back on the last sequence, a tombstone makes sure it is not reused.
+	if prior.LastSeq > fs.state.LastSeq {
+		fs.state.LastSeq, fs.state.LastTime = prior.LastSeq, prior.LastTime
+		if fs.state.Msgs == 0 {
+			fs.state.FirstSeq = fs.state.LastSeq + 1
+			fs.state.FirstTime = time.Time{}
+		}
+		if _, err := fs.newMsgBlockForWrite(); err == nil {
+			fs.writeTombstone(prior.LastSeq, prior.LastTime.UnixNano())
+		}
+	}
+
+	if fs.ld != nil {
+		sc.Lost = &LostStreamData{Msgs: slices.Clone(fs.ld.Msgs), Bytes: fs.ld.Bytes}
+		slices.Sort(sc.Lost.Msgs)
+	}
+	sc.Blocks = len(fs.blks)
+	sc.Msgs, sc.Bytes = fs.state.Msgs, fs.state.Bytes
+	sc.FirstSeq, sc.LastSeq = fs.state.FirstSeq, fs.state.LastSeq
+
+	// Make sure the index is written out again when stopped.
+	fs.dirty++
+
+	return len(sc.Problems) > np || len(sc.BadBlocks) > 0 || sc.Lost != nil && len(sc.Lost.Msgs) > 0
+}
+
+// verifyBlocks checks every record of the message blocks against its checksum, without
+// changing the blocks or the stream state like checkBlocks does. Returns the number of bad blocks.
+func (fs *fileStore) verifyBlocks() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.checkAndFlushAllBlocks()
+
+	var bad int
+	for _, mb := range fs.blks {
+		fs.loadEncryptionForMsgBlock(mb)
+		if err := mb.verifyRecords(); err != nil {
+			fs.warn("Message block %d failed check: %v", mb.index, err)
+			bad++
+		}
+	}
+	return bad
+}
+
+// verifyRecords checks every record of the block against its checksum.
+func (mb *msgBlock) verifyRecords() error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	buf, err := mb.loadBlock(nil)
+	if err != nil {
+		return err
+	}
+	defer recycleMsgBlockBuf(buf)
+	if mb.bek != nil && len(buf) > 0 {
+		bek, err := genBlockEncryptionKey(mb.fs.fcfg.Cipher, mb.seed, mb.nonce)
+		if err != nil {
+			return err
+		}
+		bek.XORKeyStream(buf, buf)
+	}
+	if buf, err = mb.decompressIfNeeded(buf); err != nil {
+		return err
+	}
+
+	le := binary.LittleEndian
+	for index, lbuf := uint32(0), uint32(len(buf)); index < lbuf; {
+		if index+msgHdrSize > lbuf {
+			return errBadMsg
+		}
+		hdr := buf[index : index+msgHdrSize]
+		rl, slen := le.Uint32(hdr[0:]), int(le.Uint16(hdr[20:]))
+		hasHeaders := rl&hbit != 0
+		rl &^= hbit
+		dlen := int(rl) - msgHdrSize
+		if dlen < 0 || slen > (dlen-recordHashSize) || dlen > int(rl) || index+rl > lbuf || rl > rlBadThresh {
+			return errBadMsg
+		}
+		data := buf[index+msgHdrSize : index+rl]
+		if hh := mb.hh; hh != nil {
+			hh.Reset()
+			hh.Write(hdr[4:20])
+			hh.Write(data[:slen])
+			if hasHeaders {
+				hh.Write(data[slen+4 : dlen-recordHashSize])
+			} else {
+				hh.Write(data[slen : dlen-recordHashSize])
+			}
+			if !bytes.Equal(hh.Sum(nil), data[len(data)-recordHashSize:]) {
+				return errBadMsg
+			}
+		}
+		index += rl
+	}
+	return nil
+}
+
+// checkConsumers checks the consumer states of the stream against its messages.
+func (s *Server) checkConsumers(fs *fileStore, account string, sc *StreamCheck) {
+	odir := filepath.Join(fs.fcfg.StoreDir, consumerDir)
+	ofis, _ := os.ReadDir(odir)
+	for _, ofi := range ofis {
+		sc.Consumers++
+		name := ofi.Name()
+		ocfg, err := s.readConsumerMeta(filepath.Join(odir, name), account, fs.cfg.Name)
+		if err != nil {
+			sc.problem("consumer %q: %v", name, err)
+			continue
+		}
+		o, err := fs.ConsumerStore(name, &ocfg.ConsumerConfig)
+		if err != nil {
+			sc.problem("consumer %q: %v", name, err)
+			continue
+		}
+		state, err := o.State()
+		if err != nil {
+			sc.problem("consumer %q: bad state: %v", name, err)
+			continue
+		}
+		if state.Delivered.Stream > sc.LastSeq {
+			sc.problem("consumer %q: delivered stream sequence %d is past the last sequence %d", name, state.Delivered.Stream, sc.LastSeq)
+		}
+		if state.AckFloor.Stream > state.Delivered.Stream || state.AckFloor.Consumer > state.Delivered.Consumer {
+			sc.problem("consumer %q: ack floor is past delivered", name)
+		}
+		for seq := range state.Pending {
+			if seq <= state.AckFloor.Stream || seq > state.Delivered.Stream {
+				sc.problem("consumer %q: pending sequence %d outside of ack floor and delivered", name, seq)
+				break
+			}
+		}
+	}
+}
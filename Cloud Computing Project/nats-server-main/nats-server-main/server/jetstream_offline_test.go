@@ -0,0 +1,287 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestJetStreamOfflineBackupRestore(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		key  string
+	}{
+		{"Plaintext", _EMPTY_},
+		{"Encrypted", "s3cr3t!!"},
+	} {
+		key := test.key
+		t.Run(test.name, func(t *testing.T) {
+			runServer := func(sd string) *Server {
+				opts := DefaultTestOptions
+				opts.Port = -1
+				opts.JetStream = true
+				opts.StoreDir = sd
+				opts.JetStreamKey = key
+				return RunServer(&opts)
+			}
+
+			sd := t.TempDir()
+			s := runServer(sd)
+			defer s.Shutdown()
+
+			nc, js := jsClientConnect(t, s)
+			defer nc.Close()
+
+			_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, MaxAge: time.Hour})
+			require_NoError(t, err)
+			_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+			require_NoError(t, err)
+			for i := 0; i < 100; i++ {
+				_, err = js.Publish("foo", []byte("ok"))
+				require_NoError(t, err)
+			}
+			sub, err := js.PullSubscribe("foo", "C")
+			require_NoError(t, err)
+			msgs, err := sub.Fetch(10)
+			require_NoError(t, err)
+			for _, m := range msgs {
+				require_NoError(t, m.AckSync())
+			}
+			nc.Close()
+			s.Shutdown()
+
+			newOfflineServer := func(sd string) *Server {
+				s, err := NewServer(&Options{StoreDir: sd, JetStreamKey: key})
+				require_NoError(t, err)
+				return s
+			}
+
+			// The backup must leave the stopped store as it was.
+			sdir := filepath.Join(sd, JetStreamStoreDir, globalAccountName, streamsDir, "TEST")
+			readDir := func() map[string][]byte {
+				files := make(map[string][]byte)
+				err := filepath.WalkDir(sdir, func(path string, d fs.DirEntry, err error) error {
+					if err != nil || d.IsDir() {
+						return err
+					}
+					buf, err := os.ReadFile(path)
+					files[path] = buf
+					return err
+				})
+				require_NoError(t, err)
+				return files
+			}
+			before := readDir()
+
+			var buf bytes.Buffer
+			state, err := newOfflineServer(sd).BackupStream(globalAccountName, "TEST", &buf)
+			require_NoError(t, err)
+			require_Equal(t, state.Msgs, 100)
+			if after := readDir(); !reflect.DeepEqual(before, after) {
+				t.Fatalf("Expected stream directory to be unchanged by the backup")
+			}
+
+			// Unknown streams are reported.
+			_, err = newOfflineServer(sd).BackupStream(globalAccountName, "NOPE", &bytes.Buffer{})
+			require_Error(t, err)
+
+			// Names that are not valid stream names are rejected.
+			for _, name := range []string{"../TEST", "a/b", _EMPTY_} {
+				_, err = newOfflineServer(sd).BackupStream(globalAccountName, name, &bytes.Buffer{})
+				require_Error(t, err)
+			}
+
+			// Restore into an empty store directory.
+			rd := t.TempDir()
+			snap := buf.Bytes()
+			cfg, err := newOfflineServer(rd).RestoreBackup(globalAccountName, bytes.NewReader(snap))
+			require_NoError(t, err)
+			require_Equal(t, cfg.Name, "TEST")
+
+			// Can not restore over an existing stream.
+			_, err = newOfflineServer(rd).RestoreBackup(globalAccountName, bytes.NewReader(snap))
+			require_Error(t, err)
+
+			// Restored store is encrypted if we have a key.
+			_, err = os.Stat(filepath.Join(rd, JetStreamStoreDir, globalAccountName, streamsDir, "TEST", JetStreamMetaFileKey))
+			if key == _EMPTY_ {
+				require_True(t, os.IsNotExist(err))
+			} else {
+				require_NoError(t, err)
+			}
+
+			s = runServer(rd)
+			defer s.Shutdown()
+
+			nc, js = jsClientConnect(t, s)
+			defer nc.Close()
+
+			si, err := js.StreamInfo("TEST")
+			require_NoError(t, err)
+			require_Equal(t, si.State.Msgs, 100)
+			require_Equal(t, si.Config.MaxAge, time.Hour)
+
+			ci, err := js.ConsumerInfo("TEST", "C")
+			require_NoError(t, err)
+			require_Equal(t, ci.AckFloor.Stream, 10)
+			require_Equal(t, ci.NumPending, 90)
+		})
+	}
+}
+
+func TestJetStreamOfflineBackupBadBlock(t *testing.T) {
+	sd := t.TempDir()
+	s := RunJetStreamServerOnPort(-1, filepath.Join(sd, JetStreamStoreDir))
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("foo", []byte("ok"))
+		require_NoError(t, err)
+	}
+	nc.Close()
+	s.Shutdown()
+
+	// Corrupt the message payloads.
+	fn := filepath.Join(sd, JetStreamStoreDir, globalAccountName, streamsDir, "TEST", msgDir, "1.blk")
+	buf, err := os.ReadFile(fn)
+	require_NoError(t, err)
+	buf = bytes.ReplaceAll(buf, []byte("ok"), []byte("ko"))
+	require_NoError(t, os.WriteFile(fn, buf, defaultFilePerms))
+
+	ns, err := NewServer(&Options{StoreDir: sd})
+	require_NoError(t, err)
+	_, err = ns.BackupStream(globalAccountName, "TEST", &bytes.Buffer{})
+	require_Error(t, err)
+
+	// The store itself was not changed.
+	nbuf, err := os.ReadFile(fn)
+	require_NoError(t, err)
+	require_True(t, bytes.Equal(buf, nbuf))
+}
+
+func TestJetStreamOfflineStoreCheckRepair(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		key  string
+	}{
+		{"Plaintext", _EMPTY_},
+		{"Encrypted", "s3cr3t!!"},
+	} {
+		key := test.key
+		t.Run(test.name, func(t *testing.T) {
+			sd := t.TempDir()
+			opts := DefaultTestOptions
+			opts.Port = -1
+			opts.JetStream = true
+			opts.StoreDir = sd
+			opts.JetStreamKey = key
+			s := RunServer(&opts)
+			defer s.Shutdown()
+
+			nc, js := jsClientConnect(t, s)
+			defer nc.Close()
+
+			_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo.*"}})
+			require_NoError(t, err)
+			_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+			require_NoError(t, err)
+			for i := 0; i < 10; i++ {
+				_, err = js.Publish(fmt.Sprintf("foo.%d", i%3), []byte("ok"))
+				require_NoError(t, err)
+			}
+			sub, err := js.PullSubscribe(_EMPTY_, "C", nats.BindStream("TEST"))
+			require_NoError(t, err)
+			msgs, err := sub.Fetch(3)
+			require_NoError(t, err)
+			for _, m := range msgs {
+				require_NoError(t, m.AckSync())
+			}
+			nc.Close()
+			s.Shutdown()
+
+			check := func(repair bool) *StreamCheck {
+				t.Helper()
+				ns, err := NewServer(&Options{StoreDir: sd, JetStreamKey: key})
+				require_NoError(t, err)
+				checks, err := ns.CheckStore(_EMPTY_, _EMPTY_, repair)
+				require_NoError(t, err)
+				require_Len(t, len(checks), 1)
+				return checks[0]
+			}
+
+			sc := check(false)
+			require_True(t, sc.OK())
+			require_Equal(t, sc.Msgs, 10)
+			require_Equal(t, sc.Consumers, 1)
+
+			// Flip a byte half way into the block, damaging that record.
+			fn := filepath.Join(sd, JetStreamStoreDir, globalAccountName, streamsDir, "TEST", msgDir, "1.blk")
+			buf, err := os.ReadFile(fn)
+			require_NoError(t, err)
+			buf[len(buf)/2] ^= 0xff
+			require_NoError(t, os.WriteFile(fn, buf, defaultFilePerms))
+
+			sc = check(false)
+			require_False(t, sc.OK())
+			require_False(t, sc.Repaired)
+			require_Len(t, len(sc.BadBlocks), 1)
+			require_True(t, sc.Lost != nil && len(sc.Lost.Msgs) > 0)
+			lost := len(sc.Lost.Msgs)
+
+			// Checking does not change the store.
+			nbuf, err := os.ReadFile(fn)
+			require_NoError(t, err)
+			require_True(t, bytes.Equal(buf, nbuf))
+
+			sc = check(true)
+			require_True(t, sc.Repaired)
+			require_Equal(t, sc.Msgs, uint64(10-lost))
+			require_Equal(t, sc.LastSeq, 10)
+
+			sc = check(false)
+			require_True(t, sc.OK())
+
+			s = RunServer(&opts)
+			defer s.Shutdown()
+
+			nc, js = jsClientConnect(t, s)
+			defer nc.Close()
+
+			si, err := js.StreamInfo("TEST")
+			require_NoError(t, err)
+			require_Equal(t, si.State.Msgs, uint64(10-lost))
+
+			// Sequences are not reused.
+			pa, err := js.Publish("foo.0", []byte("ok"))
+			require_NoError(t, err)
+			require_Equal(t, pa.Sequence, 11)
+		})
+	}
+}
@@ -0,0 +1,612 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamPartitioning splits a stream into a number of partitions, each backed by
+// its own stream and Raft group. Messages are mapped to a partition by hashing
+// their partition key, so ordering is only guaranteed for messages with the same key.
+type StreamPartitioning struct {
+	// Partitions is the number of partitions.
+	Partitions int `json:"partitions"`
+	// KeyTokens are the 1-based positions of the subject tokens making up the
+	// partition key. The whole subject is used when none are set.
+	KeyTokens []int `json:"key_tokens,omitempty"`
+	// Stream and Partition are set by the server on the streams backing the partitions.
+	Stream    string `json:"stream,omitempty"`
+	Partition int    `json:"partition,omitempty"`
+}
+
+// StreamPartitionInfo shows the state of a single partition of a partitioned stream.
+type StreamPartitionInfo struct {
+	Name    string       `json:"name"`
+	State   StreamState  `json:"state"`
+	Cluster *ClusterInfo `json:"cluster,omitempty"`
+}
+
+// JSMaxStreamPartitions is the maximum number of partitions of a stream.
+const JSMaxStreamPartitions = 128
+
+// Header used to track how many partitions a pull request was passed through.
+const jsPartitionHops = "Nats-Partition-Hops"
+
+// Queue group used by the partition consumers for pull requests on the partitioned stream.
+const jsPartitionQueue = "_partitions"
+
+// Subject the partition consumers signal each other on when they have new messages.
+const jsPartitionKickT = "$JSC.PK.%s.%s"
+
+// Minimum interval between signals of a partition consumer with new messages.
+const partitionKickInterval = 50 * time.Millisecond
+
+// partitionStreamName returns the name of the stream backing the given partition.
+func partitionStreamName(stream string, partition int) string {
+	return fmt.Sprintf("%s#%d", stream, partition)
+}
+
+// isParent returns true for the stream the partitions belong to.
+func (p *StreamPartitioning) isParent() bool {
+	return p != nil && p.Stream == _EMPTY_
+}
+
+// isPartition returns true for a stream backing a single partition.
+func (p *StreamPartitioning) isPartition() bool {
+	return p != nil && p.Stream != _EMPTY_
+}
+
+func (p *StreamPartitioning) clone() *StreamPartitioning {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	clone.KeyTokens = slices.Clone(p.KeyTokens)
+	return &clone
+}
+
+// partitionFor returns the partition the subject maps to.
+// This uses the same hashing as the partition subject transform, which hashes the
+// whole subject when none of the key tokens are present.
+func (p *StreamPartitioning) partitionFor(subject string) int {
+	h := fnv.New32a()
+	var keyed bool
+	if len(p.KeyTokens) > 0 {
+		tokens := tokenizeSubject(subject)
+		for _, pos := range p.KeyTokens {
+			if pos <= len(tokens) {
+				h.Write(stringToBytes(tokens[pos-1]))
+				keyed = true
+			}
+		}
+	}
+	if !keyed {
+		h.Write(stringToBytes(subject))
+	}
+	return int(h.Sum32() % uint32(p.Partitions))
+}
+
+// Prefix of the subjects the messages of a partitioned stream are mapped to.
+const jsPartitionSubjectPrefix = "$JS.PART."
+
+// partitionSubjectPrefix returns the prefix of the subjects the given partition receives its messages on.
+func partitionSubjectPrefix(stream string, partition int) string {
+	return fmt.Sprintf("%s%s.%d.", jsPartitionSubjectPrefix, stream, partition)
+}
+
+// partitionMappings returns the account subject mappings that send the messages of a partitioned
+// stream to its partitions, keyed by source subject. The partition is selected by the partition
+// subject transform, so every partition only receives the messages that map to it.
+func partitionMappings(cfg *StreamConfig) (map[string]string, error) {
+	p := cfg.Partitioning
+	mappings := make(map[string]string)
+	add := func(src []string) error {
+		// The partition transform can only hash wildcard tokens, so refer to the key tokens by their wildcard index.
+		dest, widx := make([]string, 0, len(src)), make([]int, len(src))
+		var nwc int
+		for i, token := range src {
+			if token == pwcs {
+				nwc++
+				widx[i] = nwc
+				dest = append(dest, fmt.Sprintf("{{wildcard(%d)}}", nwc))
+			} else {
+				dest = append(dest, token)
+			}
+		}
+		var args strings.Builder
+		for _, pos := range p.KeyTokens {
+			if pos > len(src) {
+				continue
+			}
+			if widx[pos-1] == 0 {
+				return fmt.Errorf("partition key token %d of subject %q must be a wildcard", pos, strings.Join(src, tsep))
+			}
+			fmt.Fprintf(&args, ",%d", widx[pos-1])
+		}
+		mappings[strings.Join(src, tsep)] = fmt.Sprintf("%s%s.{{partition(%d%s)}}.%s",
+			jsPartitionSubjectPrefix, cfg.Name, p.Partitions, args.String(), strings.Join(dest, tsep))
+		return nil
+	}
+
+	maxKey := 0
+	for _, pos := range p.KeyTokens {
+		maxKey = max(maxKey, pos)
+	}
+	for _, subj := range cfg.Subjects {
+		tokens := tokenizeSubject(subj)
+		n := len(tokens)
+		if tokens[n-1] != fwcs || maxKey < n {
+			if err := add(tokens); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// A trailing full wildcard covers some of the key tokens, expand it into
+		// a mapping per subject length up to the last key token.
+		src := slices.Clone(tokens[:n-1])
+		for len(src) < maxKey {
+			src = append(src, pwcs)
+			if err := add(src); err != nil {
+				return nil, err
+			}
+		}
+		if err := add(append(slices.Clone(src), fwcs)); err != nil {
+			return nil, err
+		}
+	}
+	return mappings, nil
+}
+
+// addPartitionMappings installs the subject mappings of a partitioned stream.
+func (a *Account) addPartitionMappings(cfg *StreamConfig) error {
+	mappings, err := partitionMappings(cfg)
+	if err != nil {
+		return err
+	}
+	for src, dest := range mappings {
+		if err := a.AddMapping(src, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removePartitionMappings removes the subject mappings of a partitioned stream.
+func (a *Account) removePartitionMappings(cfg *StreamConfig) {
+	mappings, _ := partitionMappings(cfg)
+	for src := range mappings {
+		a.RemoveMapping(src)
+	}
+}
+
+// isPartitionMapping returns true for the mappings installed for partitioned streams.
+// Lock should be held.
+func (m *mapping) isPartitionMapping() bool {
+	return len(m.dests) > 0 && strings.HasPrefix(m.dests[0].tr.dest, jsPartitionSubjectPrefix)
+}
+
+// partitionedStream returns the name of the partitioned stream the configuration belongs to, if any.
+func (cfg *StreamConfig) partitionedStream() string {
+	if p := cfg.Partitioning; p.isPartition() {
+		return p.Stream
+	} else if p.isParent() {
+		return cfg.Name
+	}
+	return _EMPTY_
+}
+
+// checkStreamPartitioning validates the partitioning of a stream configuration.
+func (s *Server) checkStreamPartitioning(cfg *StreamConfig) error {
+	p := cfg.Partitioning
+	if !s.JetStreamIsClustered() {
+		return errors.New("partitioned streams require clustered mode")
+	}
+	if p.Partitions < 2 || p.Partitions > JSMaxStreamPartitions {
+		return fmt.Errorf("partitions must be between 2 and %d", JSMaxStreamPartitions)
+	}
+	if p.isPartition() && (p.Partition < 0 || p.Partition >= p.Partitions) {
+		return errors.New("partition out of range")
+	}
+	for _, pos := range p.KeyTokens {
+		if pos < 1 {
+			return errors.New("partition key tokens must be positive")
+		}
+	}
+	if cfg.Mirror != nil || len(cfg.Sources) > 0 {
+		return errors.New("partitioned streams can not be a mirror or have sources")
+	}
+	if cfg.AllowMsgCounter {
+		return errors.New("partitioned streams can not use message counters")
+	}
+	if cfg.AllowAtomicPublish {
+		return errors.New("partitioned streams can not use atomic publish")
+	}
+	if cfg.AllowMsgSchedules {
+		return errors.New("partitioned streams can not use message schedules")
+	}
+	if _, err := partitionMappings(cfg); err != nil {
+		return err
+	}
+	return nil
+}
+
+// partitionConfig returns the configuration of the stream backing the given partition.
+func partitionConfig(cfg *StreamConfig, partition int) *StreamConfig {
+	pcfg := cfg.clone()
+	pcfg.Name = partitionStreamName(cfg.Name, partition)
+	pcfg.Partitioning.Stream, pcfg.Partitioning.Partition = cfg.Name, partition
+
+	// Stream wide limits are split evenly across the partitions.
+	n := int64(cfg.Partitioning.Partitions)
+	if pcfg.MaxMsgs > 0 {
+		pcfg.MaxMsgs = max(pcfg.MaxMsgs/n, 1)
+	}
+	if pcfg.MaxBytes > 0 {
+		pcfg.MaxBytes = max(pcfg.MaxBytes/n, 1)
+	}
+	return pcfg
+}
+
+// proposePartitionStreams proposes the streams backing the partitions of a new partitioned stream.
+// Lock should be held.
+func (js *jetStream) proposePartitionStreams(ci *ClientInfo, acc *Account, cfg *StreamConfig) *ApiError {
+	cc := js.cluster
+	inflight := cc.inflight[acc.Name]
+
+	// Make sure we do not collide with an existing stream first.
+	for i := 0; i < cfg.Partitioning.Partitions; i++ {
+		if js.streamAssignment(acc.Name, partitionStreamName(cfg.Name, i)) != nil {
+			return NewJSStreamNameExistError()
+		}
+	}
+
+	for i := 0; i < cfg.Partitioning.Partitions; i++ {
+		pcfg := partitionConfig(cfg, i)
+		var rg *raftGroup
+		var syncSubject string
+		// Re-use the group of an inflight proposal for the same partition.
+		if existing, ok := inflight[pcfg.Name]; ok {
+			rg, syncSubject = existing.rg, existing.sync
+		} else {
+			nrg, err := js.createGroupForStream(ci, pcfg)
+			if err != nil {
+				return NewJSClusterNoPeersError(err)
+			}
+			rg, syncSubject = nrg, syncSubjForStream()
+			rg.setPreferred()
+		}
+		sa := &streamAssignment{Group: rg, Sync: syncSubject, Config: pcfg, Client: ci, Created: time.Now().UTC()}
+		if err := cc.meta.Propose(encodeAddStreamAssignment(sa)); err == nil && inflight != nil {
+			inflight[pcfg.Name] = &inflightInfo{rg, syncSubject}
+		}
+	}
+	return nil
+}
+
+// proposePartitionStreamsUpdate proposes the updated configuration to the streams backing the partitions.
+// Lock should be held.
+func (js *jetStream) proposePartitionStreamsUpdate(ci *ClientInfo, acc *Account, cfg *StreamConfig) {
+	for i := 0; i < cfg.Partitioning.Partitions; i++ {
+		psa := js.streamAssignment(acc.Name, partitionStreamName(cfg.Name, i))
+		if psa == nil {
+			continue
+		}
+		rg := psa.copyGroup().Group
+		rg.Preferred = _EMPTY_
+		sa := &streamAssignment{Group: rg, Sync: psa.Sync, Created: psa.Created, Config: partitionConfig(cfg, i), Client: ci}
+		js.cluster.meta.Propose(encodeUpdateStreamAssignment(sa))
+	}
+}
+
+// proposePartitionStreamsDelete proposes the removal of the streams backing the partitions.
+// Lock should be held.
+func (js *jetStream) proposePartitionStreamsDelete(ci *ClientInfo, acc *Account, cfg *StreamConfig) {
+	for i := 0; i < cfg.Partitioning.Partitions; i++ {
+		psa := js.streamAssignment(acc.Name, partitionStreamName(cfg.Name, i))
+		if psa == nil {
+			continue
+		}
+		sa := &streamAssignment{Group: psa.Group, Config: psa.Config, Client: ci}
+		js.cluster.meta.Propose(encodeDeleteStreamAssignment(sa))
+	}
+}
+
+// proposePartitionConsumers proposes the consumers backing a consumer on a partitioned stream,
+// one on each partition with the same name and configuration.
+// Lock should be held.
+func (js *jetStream) proposePartitionConsumers(ci *ClientInfo, acc *Account, sa *streamAssignment, ca *consumerAssignment) {
+	cc := js.cluster
+	for i := 0; i < sa.Config.Partitioning.Partitions; i++ {
+		psa := js.streamAssignment(acc.Name, partitionStreamName(sa.Config.Name, i))
+		if psa == nil {
+			continue
+		}
+		var pca *consumerAssignment
+		if oca := psa.consumers[ca.Name]; oca != nil && !oca.deleted {
+			pca = oca.copyGroup()
+			pca.Group.ScaleUp = false
+			pca.Config, pca.Client = ca.Config, ci
+			pca.Subject, pca.Reply = _EMPTY_, _EMPTY_
+		} else {
+			rg := cc.createGroupForConsumer(ca.Config, psa)
+			if rg == nil {
+				js.srv.Warnf("No peers available for consumer '%s > %s > %s'", acc.Name, psa.Config.Name, ca.Name)
+				continue
+			}
+			rg.setPreferred()
+			rg.Cluster = psa.Group.Cluster
+			pca = &consumerAssignment{
+				Group:   rg,
+				Stream:  psa.Config.Name,
+				Name:    ca.Name,
+				Config:  ca.Config,
+				Client:  ci,
+				Created: time.Now().UTC(),
+			}
+		}
+		if err := cc.meta.Propose(encodeAddConsumerAssignment(pca)); err == nil {
+			if psa.consumers == nil {
+				psa.consumers = make(map[string]*consumerAssignment)
+			}
+			pca.pending = true
+			psa.consumers[pca.Name] = pca
+		}
+	}
+}
+
+// proposePartitionConsumersDelete proposes the removal of the consumers backing a consumer on a partitioned stream.
+// Lock should be held.
+func (js *jetStream) proposePartitionConsumersDelete(ci *ClientInfo, acc *Account, sa *streamAssignment, consumer string) {
+	for i := 0; i < sa.Config.Partitioning.Partitions; i++ {
+		psa := js.streamAssignment(acc.Name, partitionStreamName(sa.Config.Name, i))
+		if psa == nil {
+			continue
+		}
+		oca := psa.consumers[consumer]
+		if oca == nil {
+			continue
+		}
+		oca.deleted = true
+		ca := &consumerAssignment{Group: oca.Group, Stream: psa.Config.Name, Name: consumer, Config: oca.Config, Client: ci}
+		js.cluster.meta.Propose(encodeDeleteConsumerAssignment(ca))
+	}
+}
+
+// addPartitionsInfo merges the state of the partitions into the info of a partitioned stream.
+// This will block while the partitions are asked for their info.
+func (s *Server) addPartitionsInfo(accName string, si *StreamInfo) {
+	p := si.Config.Partitioning
+	if !p.isParent() {
+		return
+	}
+
+	pis := make([]*StreamPartitionInfo, p.Partitions)
+	var wg sync.WaitGroup
+	for i := range pis {
+		pi := &StreamPartitionInfo{Name: partitionStreamName(si.Config.Name, i)}
+		pis[i] = pi
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			psi, err := sysRequest[StreamInfo](s, clusterStreamInfoT, accName, pi.Name)
+			if err != nil {
+				s.Warnf("Did not receive stream info results for '%s > %s' due to: %s", accName, pi.Name, err)
+				return
+			}
+			pi.State, pi.Cluster = psi.State, psi.Cluster
+		}()
+	}
+	wg.Wait()
+
+	state := &si.State
+	for _, pi := range pis {
+		ps := &pi.State
+		state.Msgs += ps.Msgs
+		state.Bytes += ps.Bytes
+		state.NumDeleted += ps.NumDeleted
+		state.NumSubjects += ps.NumSubjects
+		if ps.Msgs == 0 {
+			continue
+		}
+		if state.FirstTime.IsZero() || ps.FirstTime.Before(state.FirstTime) {
+			state.FirstTime = ps.FirstTime
+		}
+		if ps.LastTime.After(state.LastTime) {
+			state.LastTime = ps.LastTime
+		}
+	}
+	si.Partitions = pis
+}
+
+// addPartitionsConsumerInfo merges the state of the consumers on the partitions into
+// the info of a consumer on a partitioned stream.
+// This will block while the partitions are asked for their info.
+func (s *Server) addPartitionsConsumerInfo(accName string, p *StreamPartitioning, ci *ConsumerInfo) {
+	pcis := make([]*ConsumerInfo, p.Partitions)
+	var wg sync.WaitGroup
+	for i := range pcis {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stream := partitionStreamName(ci.Stream, i)
+			pci, err := sysRequest[ConsumerInfo](s, clusterConsumerInfoT, accName, stream, ci.Name)
+			if err != nil {
+				s.Warnf("Did not receive consumer info results for '%s > %s > %s' due to: %s", accName, stream, ci.Name, err)
+				return
+			}
+			pcis[i] = pci
+		}()
+	}
+	wg.Wait()
+
+	for _, pci := range pcis {
+		if pci == nil {
+			continue
+		}
+		ci.Delivered.Consumer += pci.Delivered.Consumer
+		ci.AckFloor.Consumer += pci.AckFloor.Consumer
+		ci.NumAckPending += pci.NumAckPending
+		ci.NumRedelivered += pci.NumRedelivered
+		ci.NumWaiting += pci.NumWaiting
+		ci.NumPending += pci.NumPending
+		ci.PushBound = ci.PushBound || pci.PushBound
+		if last := pci.Delivered.Last; last != nil && (ci.Delivered.Last == nil || last.After(*ci.Delivered.Last)) {
+			ci.Delivered.Last = last
+		}
+		if last := pci.AckFloor.Last; last != nil && (ci.AckFloor.Last == nil || last.After(*ci.AckFloor.Last)) {
+			ci.AckFloor.Last = last
+		}
+	}
+}
+
+// partitionedInfo returns the info of a consumer on a partitioned stream, merged with its partitions.
+// This will block while the partitions are asked for their info.
+func (o *consumer) partitionedInfo() *ConsumerInfo {
+	info := o.info()
+	if info == nil {
+		return nil
+	}
+	o.srv.addPartitionsConsumerInfo(o.acc.Name, o.part, info)
+	return info
+}
+
+func (o *consumer) partitionedInfoAndReply(reply string) {
+	info := o.partitionedInfo()
+	if info == nil {
+		return
+	}
+	o.mu.RLock()
+	sysc := o.sysc
+	o.mu.RUnlock()
+	if sysc != nil {
+		sysc.sendInternalMsg(reply, _EMPTY_, nil, info)
+	}
+}
+
+// subscribeToPartitionedNext subscribes a consumer on a partition to the pull requests
+// sent to the consumer on the partitioned stream.
+// Lock should be held.
+func (o *consumer) subscribeToPartitionedNext() (*subscription, error) {
+	c := o.client
+	if c == nil {
+		return nil, fmt.Errorf("invalid consumer")
+	}
+	o.sid++
+	subject := fmt.Sprintf(JSApiRequestNextT, o.part.Stream, o.name)
+	return c.processSub(stringToBytes(subject), []byte(jsPartitionQueue), []byte(strconv.Itoa(o.sid)), o.processPartitionNextMsgReq, false)
+}
+
+// processPartitionNextMsgReq handles pull requests for a consumer on a partition.
+// Requests for the partitioned stream are passed on to the next partition while this
+// one has nothing to deliver, until all partitions have been visited.
+func (o *consumer) processPartitionNextMsgReq(sub *subscription, c *client, acc *Account, subject, reply string, rmsg []byte) {
+	if reply == _EMPTY_ {
+		return
+	}
+	hdr, msg := c.msgParts(rmsg)
+	hops := -1
+	if v := sliceHeader(jsPartitionHops, hdr); v != nil {
+		hops = int(parseInt64(v))
+	} else if subject != o.nextMsgSubj {
+		hops = 0
+	}
+	if hops >= 0 && o.forwardNextMsgReq(reply, msg, hops) {
+		return
+	}
+	o.queueNextMsgReq(c, reply, rmsg, hops >= 0)
+}
+
+// forwardNextMsgReq passes a pull request on to the next partition if we have nothing pending.
+func (o *consumer) forwardNextMsgReq(reply string, msg []byte, hops int) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	p := o.part
+	if hops+1 >= p.Partitions || o.isPushMode() || o.numPending()+uint64(len(o.rdq)) > 0 {
+		return false
+	}
+	next := partitionStreamName(p.Stream, (p.Partition+1)%p.Partitions)
+	subject := fmt.Sprintf(JSApiRequestNextT, next, o.name)
+	hdr := genHeader(nil, jsPartitionHops, strconv.Itoa(hops+1))
+	o.outq.send(newJSPubMsg(subject, _EMPTY_, reply, hdr, copyBytes(msg), nil, 0))
+	return true
+}
+
+// kickPartitions signals the other partitions that we have new messages, so requests
+// for the partitioned stream they hold on to can be handed off to us.
+// Lock should be held.
+func (o *consumer) kickPartitions() {
+	if time.Since(o.pkick) < partitionKickInterval {
+		return
+	}
+	o.pkick = time.Now()
+	subject := fmt.Sprintf(jsPartitionKickT, o.part.Stream, o.name)
+	o.outq.send(newJSPubMsg(subject, _EMPTY_, _EMPTY_, nil, []byte(strconv.Itoa(o.part.Partition)), nil, 0))
+}
+
+// processPartitionKick hands off the waiting requests for the partitioned stream to
+// a partition with new messages, as long as we have nothing to deliver ourselves.
+func (o *consumer) processPartitionKick(_ *subscription, c *client, _ *Account, _, _ string, rmsg []byte) {
+	_, msg := c.msgParts(rmsg)
+	partition, err := strconv.Atoi(string(msg))
+	if err != nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	p := o.part
+	if !o.isLeader() || o.waiting.isEmpty() || partition == p.Partition || partition < 0 || partition >= p.Partitions {
+		return
+	}
+	if o.numPending()+uint64(len(o.rdq)) > 0 {
+		return
+	}
+	subject := fmt.Sprintf(JSApiRequestNextT, partitionStreamName(p.Stream, partition), o.name)
+	var pre *waitingRequest
+	for wr := o.waiting.peek(); wr != nil; {
+		next := wr.next
+		req := JSApiConsumerGetNextRequest{Batch: wr.n, MaxBytes: wr.b, NoWait: wr.noWait, Heartbeat: wr.hb}
+		if !wr.expires.IsZero() {
+			req.Expires = time.Until(wr.expires)
+		}
+		// Requests sent to this partition directly, or expired ones, stay here.
+		if !wr.partitioned || !wr.expires.IsZero() && req.Expires <= 0 {
+			pre, wr = wr, next
+			continue
+		}
+		if wr.priorityGroup != nil {
+			req.PriorityGroup = *wr.priorityGroup
+		}
+		b, _ := json.Marshal(req)
+		hdr := genHeader(nil, jsPartitionHops, "0")
+		o.outq.send(newJSPubMsg(subject, _EMPTY_, wr.reply, hdr, b, nil, 0))
+		o.waiting.remove(pre, wr)
+		if o.node != nil {
+			o.removeClusterPendingRequest(wr.reply)
+		}
+		wr.recycle()
+		wr = next
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestJetStreamPartitionFor(t *testing.T) {
+	p := &StreamPartitioning{Partitions: 4, KeyTokens: []int{2}}
+	// Only the key tokens are hashed.
+	require_Equal(t, p.partitionFor("orders.1.created"), p.partitionFor("orders.1.shipped"))
+	// Missing tokens are ignored, the whole subject is hashed without any.
+	require_Equal(t, (&StreamPartitioning{Partitions: 4, KeyTokens: []int{2, 3}}).partitionFor("orders.1"), p.partitionFor("orders.1"))
+	require_Equal(t, p.partitionFor("orders"), (&StreamPartitioning{Partitions: 4}).partitionFor("orders"))
+
+	// Keys are spread over all partitions.
+	seen := make(map[int]struct{})
+	for i := 0; i < 100; i++ {
+		n := p.partitionFor(fmt.Sprintf("orders.%d", i))
+		require_True(t, n >= 0 && n < 4)
+		seen[n] = struct{}{}
+	}
+	require_Len(t, len(seen), 4)
+}
+
+func TestJetStreamPartitionMappings(t *testing.T) {
+	for _, test := range []struct {
+		subjects []string
+		p        *StreamPartitioning
+		srcs     []string
+	}{
+		{[]string{"orders.>"}, &StreamPartitioning{Partitions: 3}, []string{"orders.>"}},
+		{[]string{"orders.>"}, &StreamPartitioning{Partitions: 3, KeyTokens: []int{3}}, []string{"orders.*", "orders.*.*", "orders.*.*.>"}},
+		{[]string{"orders.>"}, &StreamPartitioning{Partitions: 3, KeyTokens: []int{2}}, []string{"orders.*", "orders.*.>"}},
+		{[]string{"orders.*.>"}, &StreamPartitioning{Partitions: 5, KeyTokens: []int{3, 2}}, []string{"orders.*.*", "orders.*.*.>"}},
+		{[]string{"orders.*", "items"}, &StreamPartitioning{Partitions: 2, KeyTokens: []int{2}}, []string{"orders.*", "items"}},
+	} {
+		cfg := &StreamConfig{Name: "TEST", Subjects: test.subjects, Partitioning: test.p}
+		mappings, err := partitionMappings(cfg)
+		require_NoError(t, err)
+		require_Len(t, len(mappings), len(test.srcs))
+		for _, src := range test.srcs {
+			require_True(t, mappings[src] != _EMPTY_)
+		}
+
+		// The mappings select the same partition as the partitioned stream.
+		for i := 0; i < 100; i++ {
+			for _, subj := range []string{
+				fmt.Sprintf("orders.%d", i),
+				fmt.Sprintf("orders.%d.a", i),
+				fmt.Sprintf("orders.x.%d", i),
+				fmt.Sprintf("orders.%d.%d.b", i, i),
+				"items",
+			} {
+				var matches, mapped int
+				for _, ss := range test.subjects {
+					if subjectIsSubsetMatch(subj, ss) {
+						matches++
+					}
+				}
+				for src, dest := range mappings {
+					if !subjectIsSubsetMatch(subj, src) {
+						continue
+					}
+					tr, err := NewSubjectTransform(src, dest)
+					require_NoError(t, err)
+					prefix := partitionSubjectPrefix("TEST", test.p.partitionFor(subj))
+					require_Equal(t, tr.TransformSubject(subj), prefix+subj)
+					mapped++
+				}
+				require_Equal(t, mapped, matches)
+			}
+		}
+	}
+
+	// Key tokens need to be wildcards.
+	_, err := partitionMappings(&StreamConfig{Name: "TEST", Subjects: []string{"orders.*"}, Partitioning: &StreamPartitioning{Partitions: 2, KeyTokens: []int{1}}})
+	require_Error(t, err, errors.New("partition key token 1 of subject \"orders.*\" must be a wildcard"))
+}
+
+func TestJetStreamPartitionedStreamConfig(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, _ := jsClientConnect(t, s)
+	defer nc.Close()
+
+	// Partitioned streams need a cluster.
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:         "TEST",
+		Subjects:     []string{"foo.>"},
+		Storage:      FileStorage,
+		Partitioning: &StreamPartitioning{Partitions: 2},
+	})
+	require_Error(t, err, NewJSStreamInvalidConfigError(fmt.Errorf("partitioned streams require clustered mode")))
+
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, _ = jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	for _, test := range []struct {
+		name string
+		cfg  StreamConfig
+		err  string
+	}{
+		{"too few partitions", StreamConfig{Partitioning: &StreamPartitioning{Partitions: 1}}, "partitions must be between 2 and 128"},
+		{"too many partitions", StreamConfig{Partitioning: &StreamPartitioning{Partitions: 129}}, "partitions must be between 2 and 128"},
+		{"bad key token", StreamConfig{Partitioning: &StreamPartitioning{Partitions: 2, KeyTokens: []int{0}}}, "partition key tokens must be positive"},
+		{"literal key token", StreamConfig{Partitioning: &StreamPartitioning{Partitions: 2, KeyTokens: []int{1}}}, "partition key token 1 of subject \"foo.>\" must be a wildcard"},
+		{"counters", StreamConfig{AllowMsgCounter: true, Partitioning: &StreamPartitioning{Partitions: 2}}, "partitioned streams can not use message counters"},
+		{"atomic", StreamConfig{AllowAtomicPublish: true, Partitioning: &StreamPartitioning{Partitions: 2}}, "partitioned streams can not use atomic publish"},
+		{"partition", StreamConfig{Partitioning: &StreamPartitioning{Partitions: 2, Stream: "OTHER"}}, "stream partition can not be created directly"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := test.cfg
+			cfg.Name, cfg.Subjects, cfg.Storage = "TEST", []string{"foo.>"}, FileStorage
+			_, err := jsStreamCreate(t, nc, &cfg)
+			require_Error(t, err, NewJSStreamInvalidConfigError(fmt.Errorf("%s", test.err)))
+		})
+	}
+}
+
+func TestJetStreamPartitionedStream(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	cfg := &StreamConfig{
+		Name:         "ORDERS",
+		Subjects:     []string{"orders.>"},
+		Storage:      FileStorage,
+		Replicas:     3,
+		MaxMsgs:      3000,
+		Partitioning: &StreamPartitioning{Partitions: 3, KeyTokens: []int{2}},
+	}
+	_, err := jsStreamCreate(t, nc, cfg)
+	require_NoError(t, err)
+
+	// The partitions are streams of their own, with the limits split across them.
+	for i := 0; i < 3; i++ {
+		name := partitionStreamName("ORDERS", i)
+		c.waitOnStreamLeader(globalAccountName, name)
+		si, err := js.StreamInfo(name)
+		require_NoError(t, err)
+		require_Equal(t, si.Config.MaxMsgs, 1000)
+		require_Equal(t, si.Config.Replicas, 3)
+	}
+	c.waitOnStreamLeader(globalAccountName, "ORDERS")
+
+	// Messages for the same key always land on the same partition.
+	partitions := make(map[string]string)
+	for i := 0; i < 30; i++ {
+		key := strconv.Itoa(i % 10)
+		pa, err := js.Publish("orders."+key, []byte(strconv.Itoa(i)))
+		require_NoError(t, err)
+		if p, ok := partitions[key]; ok {
+			require_Equal(t, pa.Stream, p)
+		}
+		partitions[key] = pa.Stream
+		require_Equal(t, pa.Stream, partitionStreamName("ORDERS", cfg.Partitioning.partitionFor("orders."+key)))
+	}
+
+	// The partitioned stream reports the state of all of its partitions.
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		rmsg, err := nc.Request(fmt.Sprintf(JSApiStreamInfoT, "ORDERS"), nil, 5*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamInfoResponse
+		require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+		require_True(t, resp.Error == nil)
+		require_Len(t, len(resp.Partitions), 3)
+		var msgs uint64
+		for i, pi := range resp.Partitions {
+			require_Equal(t, pi.Name, partitionStreamName("ORDERS", i))
+			msgs += pi.State.Msgs
+		}
+		if msgs != 30 || resp.State.Msgs != 30 {
+			return fmt.Errorf("expected 30 messages, got %d and %d", msgs, resp.State.Msgs)
+		}
+		return nil
+	})
+
+	// Partitions are managed through their partitioned stream.
+	pcfg := partitionConfig(cfg, 0)
+	pcfg.MaxMsgs = 10
+	_, err = jsStreamUpdate(t, nc, pcfg)
+	require_Error(t, err, NewJSStreamUpdateError(fmt.Errorf("stream is a partition of %q", "ORDERS")))
+	require_Error(t, js.DeleteStream(pcfg.Name))
+
+	// Partitioning can not be changed, and partitioned streams can not be scaled.
+	ucfg := *cfg
+	ucfg.Partitioning = &StreamPartitioning{Partitions: 4, KeyTokens: []int{2}}
+	_, err = jsStreamUpdate(t, nc, &ucfg)
+	require_Error(t, err, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change partitioning")))
+	ucfg = *cfg
+	ucfg.Replicas = 1
+	_, err = jsStreamUpdate(t, nc, &ucfg)
+	require_Error(t, err, NewJSStreamUpdateError(fmt.Errorf("partitioned streams can not be moved or scaled")))
+
+	// Other updates are passed on to the partitions.
+	ucfg = *cfg
+	ucfg.MaxMsgs = 300
+	_, err = jsStreamUpdate(t, nc, &ucfg)
+	require_NoError(t, err)
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		for i := 0; i < 3; i++ {
+			si, err := js.StreamInfo(partitionStreamName("ORDERS", i))
+			if err != nil {
+				return err
+			}
+			if si.Config.MaxMsgs != 100 {
+				return fmt.Errorf("expected max msgs of 100, got %d", si.Config.MaxMsgs)
+			}
+		}
+		return nil
+	})
+
+	// Deleting the partitioned stream deletes its partitions.
+	require_NoError(t, js.DeleteStream("ORDERS"))
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		for name := range js.StreamNames() {
+			return fmt.Errorf("stream %q still exists", name)
+		}
+		return nil
+	})
+}
+
+func TestJetStreamPartitionedStreamConsumers(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	cfg := &StreamConfig{
+		Name:         "ORDERS",
+		Subjects:     []string{"orders.>"},
+		Storage:      FileStorage,
+		Replicas:     3,
+		Partitioning: &StreamPartitioning{Partitions: 3, KeyTokens: []int{2}},
+	}
+	_, err := jsStreamCreate(t, nc, cfg)
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "ORDERS")
+
+	// Consumers need to be durable.
+	_, err = js.AddConsumer("ORDERS", &nats.ConsumerConfig{AckPolicy: nats.AckExplicitPolicy})
+	require_Error(t, err, NewJSConsumerInvalidPolicyError(fmt.Errorf("consumers on partitioned streams must be durable")))
+
+	_, err = js.AddConsumer("ORDERS", &nats.ConsumerConfig{Durable: "PULL", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+	_, err = js.AddConsumer("ORDERS", &nats.ConsumerConfig{Durable: "PUSH", DeliverSubject: "deliver", AckPolicy: nats.AckNonePolicy})
+	require_NoError(t, err)
+	for i := 0; i < 3; i++ {
+		c.waitOnConsumerLeader(globalAccountName, partitionStreamName("ORDERS", i), "PULL")
+		c.waitOnConsumerLeader(globalAccountName, partitionStreamName("ORDERS", i), "PUSH")
+	}
+	c.waitOnConsumerLeader(globalAccountName, "ORDERS", "PULL")
+
+	for i := 0; i < 30; i++ {
+		_, err := js.Publish(fmt.Sprintf("orders.%d", i%10), []byte(strconv.Itoa(i)))
+		require_NoError(t, err)
+	}
+
+	// Messages are merged across the partitions, in order per key.
+	checkOrder := func(last map[string]int, msg *nats.Msg) {
+		t.Helper()
+		n, err := strconv.Atoi(string(msg.Data))
+		require_NoError(t, err)
+		if prev, ok := last[msg.Subject]; ok {
+			require_True(t, n > prev)
+		}
+		last[msg.Subject] = n
+	}
+
+	sub, err := js.PullSubscribe(_EMPTY_, "PULL", nats.Bind("ORDERS", "PULL"))
+	require_NoError(t, err)
+	defer sub.Unsubscribe()
+
+	last := make(map[string]int)
+	var received int
+	for deadline := time.Now().Add(5 * time.Second); received < 30 && time.Now().Before(deadline); {
+		msgs, _ := sub.Fetch(10, nats.MaxWait(500*time.Millisecond))
+		for _, msg := range msgs {
+			checkOrder(last, msg)
+			require_NoError(t, msg.AckSync())
+			received++
+		}
+	}
+	require_Equal(t, received, 30)
+
+	// The consumer reports the state of all of its partitions.
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		ci, err := js.ConsumerInfo("ORDERS", "PULL")
+		if err != nil {
+			return err
+		}
+		if ci.Delivered.Consumer != 30 || ci.AckFloor.Consumer != 30 || ci.NumPending != 0 || ci.NumAckPending != 0 {
+			return fmt.Errorf("unexpected consumer state: %+v", ci)
+		}
+		return nil
+	})
+
+	// Push consumers deliver from all partitions.
+	psub, err := nc.SubscribeSync("deliver")
+	require_NoError(t, err)
+	defer psub.Unsubscribe()
+	last = make(map[string]int)
+	for i := 0; i < 30; i++ {
+		msg, err := psub.NextMsg(2 * time.Second)
+		require_NoError(t, err)
+		checkOrder(last, msg)
+	}
+
+	// Requests held on to by partitions without messages are handed off to the partition with new ones.
+	_, err = js.AddConsumer("ORDERS", &nats.ConsumerConfig{Durable: "WAIT", AckPolicy: nats.AckExplicitPolicy, DeliverPolicy: nats.DeliverNewPolicy})
+	require_NoError(t, err)
+	for i := 0; i < 3; i++ {
+		c.waitOnConsumerLeader(globalAccountName, partitionStreamName("ORDERS", i), "WAIT")
+	}
+	c.waitOnConsumerLeader(globalAccountName, "ORDERS", "WAIT")
+	wsub, err := js.PullSubscribe(_EMPTY_, "WAIT", nats.Bind("ORDERS", "WAIT"))
+	require_NoError(t, err)
+	defer wsub.Unsubscribe()
+	for p := 0; p < 3; p++ {
+		var subj string
+		for i := 0; subj == _EMPTY_; i++ {
+			if s := fmt.Sprintf("orders.w%d", i); cfg.Partitioning.partitionFor(s) == p {
+				subj = s
+			}
+		}
+		ch := make(chan []*nats.Msg, 1)
+		go func() {
+			msgs, _ := wsub.Fetch(1, nats.MaxWait(3*time.Second))
+			ch <- msgs
+		}()
+		time.Sleep(250 * time.Millisecond)
+		_, err = js.Publish(subj, nil)
+		require_NoError(t, err)
+		msgs := <-ch
+		require_Len(t, len(msgs), 1)
+		require_Equal(t, msgs[0].Subject, subj)
+		require_NoError(t, msgs[0].AckSync())
+	}
+
+	// Deleting the consumer deletes it from the partitions.
+	require_NoError(t, js.DeleteConsumer("ORDERS", "PULL"))
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		for i := 0; i < 3; i++ {
+			if _, err := js.ConsumerInfo(partitionStreamName("ORDERS", i), "PULL"); err == nil {
+				return fmt.Errorf("consumer still exists on partition %d", i)
+			}
+		}
+		return nil
+	})
+}
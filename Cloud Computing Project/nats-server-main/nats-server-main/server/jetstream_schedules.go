@@ -0,0 +1,425 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server/thw"
+)
+
+// A scheduled message is stored like any other message, but is hidden from
+// consumers until it is due. When due, the stream leader stores a copy of it
+// without the schedule headers and with Nats-Scheduled-Sequence pointing back
+// at the original, which is then removed on every replica as the copy is applied.
+
+// msgSchedules tracks the scheduled messages of a stream that are not due yet.
+// It has its own lock so consumers can consult it without the stream lock.
+type msgSchedules struct {
+	mu     sync.Mutex
+	thw    *thw.HashWheel
+	msgs   map[uint64]*schedMsg
+	tmr    *time.Timer
+	leader bool
+	fire   func()
+}
+
+type schedMsg struct {
+	subj string
+	due  int64
+}
+
+func newMsgSchedules(fire func()) *msgSchedules {
+	return &msgSchedules{
+		thw:  thw.NewHashWheel(),
+		msgs: make(map[uint64]*schedMsg),
+		fire: fire,
+	}
+}
+
+// getMessageSchedule returns when a message becomes visible to consumers, based on
+// either the Nats-Schedule-At or the Nats-Delay header. A delay is relative to the
+// stored timestamp of the message. Zero means the message has no schedule.
+func getMessageSchedule(hdr []byte, ts int64) (int64, error) {
+	if len(hdr) == 0 {
+		return 0, nil
+	}
+	at, delay := getHeader(JSScheduleAt, hdr), getHeader(JSScheduleDelay, hdr)
+	switch {
+	case len(at) == 0 && len(delay) == 0:
+		return 0, nil
+	case len(at) > 0 && len(delay) > 0:
+		return 0, NewJSMessageScheduleInvalidError()
+	case len(at) > 0:
+		t, err := time.Parse(time.RFC3339Nano, bytesToString(at))
+		if err != nil {
+			return 0, NewJSMessageScheduleInvalidError()
+		}
+		return t.UnixNano(), nil
+	default:
+		d, err := time.ParseDuration(bytesToString(delay))
+		if err != nil || d <= 0 {
+			return 0, NewJSMessageScheduleInvalidError()
+		}
+		return ts + int64(d), nil
+	}
+}
+
+// getScheduledSeq returns the sequence of the original scheduled message.
+func getScheduledSeq(hdr []byte) uint64 {
+	seq := getHeader(JSScheduledSeq, hdr)
+	if len(seq) == 0 {
+		return 0
+	}
+	n, err := strconv.ParseUint(bytesToString(seq), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// add tracks a scheduled message.
+func (ms *msgSchedules) add(seq uint64, subj string, due int64) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.msgs[seq]; ok {
+		return
+	}
+	ms.msgs[seq] = &schedMsg{subj: subj, due: due}
+	ms.thw.Add(seq, due)
+	ms.resetTimer()
+}
+
+// remove stops tracking a scheduled message, returns if it was tracked.
+func (ms *msgSchedules) remove(seq uint64) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	sm, ok := ms.msgs[seq]
+	if !ok {
+		return false
+	}
+	delete(ms.msgs, seq)
+	ms.thw.Remove(seq, sm.due)
+	return true
+}
+
+// isDue returns whether seq is a tracked scheduled message on subj that is due at ts.
+func (ms *msgSchedules) isDue(seq uint64, subj string, ts int64) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	sm, ok := ms.msgs[seq]
+	return ok && sm.subj == subj && sm.due <= ts
+}
+
+// has returns whether seq is a tracked scheduled message, which consumers skip.
+func (ms *msgSchedules) has(seq uint64) bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	_, ok := ms.msgs[seq]
+	return ok
+}
+
+// numPending returns how many scheduled messages at or above sseq match.
+func (ms *msgSchedules) numPending(sseq uint64, match func(subj string) bool) uint64 {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var n uint64
+	for seq, sm := range ms.msgs {
+		if seq >= sseq && match(sm.subj) {
+			n++
+		}
+	}
+	return n
+}
+
+// count returns the number of scheduled messages not due yet.
+func (ms *msgSchedules) count() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return len(ms.msgs)
+}
+
+// setLeader will arm or stop the timer. Only the leader fires schedules.
+// When becoming leader all tracked messages go back into the wheel, since
+// a previous leader may have fired them without the copy being applied.
+func (ms *msgSchedules) setLeader(isLeader bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.leader = isLeader
+	if !isLeader {
+		stopAndClearTimer(&ms.tmr)
+		return
+	}
+	ms.thw = thw.NewHashWheel()
+	for seq, sm := range ms.msgs {
+		ms.thw.Add(seq, sm.due)
+	}
+	ms.resetTimer()
+}
+
+// stop will stop the timer.
+func (ms *msgSchedules) stop() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.leader = false
+	stopAndClearTimer(&ms.tmr)
+}
+
+// expire pulls due messages out of the wheel and re-arms the timer.
+// The messages stay tracked until the copy is stored.
+func (ms *msgSchedules) expire() []uint64 {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if !ms.leader {
+		return nil
+	}
+	var seqs []uint64
+	ms.thw.ExpireTasks(func(seq uint64, _ int64) bool {
+		seqs = append(seqs, seq)
+		return true
+	})
+	ms.resetTimer()
+	return seqs
+}
+
+// Lock should be held.
+func (ms *msgSchedules) resetTimer() {
+	if !ms.leader {
+		return
+	}
+	next := ms.thw.GetNextExpiration(math.MaxInt64)
+	if next == math.MaxInt64 {
+		stopAndClearTimer(&ms.tmr)
+		return
+	}
+	fireIn := time.Until(time.Unix(0, next))
+	if fireIn < 0 {
+		fireIn = 0
+	}
+	if ms.tmr == nil {
+		ms.tmr = time.AfterFunc(fireIn, ms.fire)
+	} else {
+		ms.tmr.Reset(fireIn)
+	}
+}
+
+// File the index of scheduled messages is persisted in when the stream stops,
+// so that only messages stored after it need to be scanned when the stream starts.
+const msgSchedulesStateFile = "sched.db"
+
+var errBadMsgSchedulesState = errors.New("bad message schedules state")
+
+// encode returns the index of scheduled messages, valid for all messages below highSeq.
+func (ms *msgSchedules) encode(highSeq uint64) []byte {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	b := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(ms.msgs)*(3*binary.MaxVarintLen64+16))
+	b = append(b, 1) // Version
+	b = binary.AppendUvarint(b, highSeq)
+	b = binary.AppendUvarint(b, uint64(len(ms.msgs)))
+	for seq, sm := range ms.msgs {
+		b = binary.AppendUvarint(b, seq)
+		b = binary.AppendVarint(b, sm.due)
+		b = binary.AppendUvarint(b, uint64(len(sm.subj)))
+		b = append(b, sm.subj...)
+	}
+	return b
+}
+
+// decode tracks the scheduled messages of an encoded index, and returns the
+// sequence from which messages were stored after it.
+func (ms *msgSchedules) decode(b []byte) (uint64, error) {
+	if len(b) == 0 || b[0] != 1 {
+		return 0, errBadMsgSchedulesState
+	}
+	bi := 1
+	readU64 := func() uint64 {
+		if bi < 0 || bi >= len(b) {
+			bi = -1
+			return 0
+		}
+		v, n := binary.Uvarint(b[bi:])
+		if n <= 0 {
+			bi = -1
+			return 0
+		}
+		bi += n
+		return v
+	}
+	highSeq, count := readU64(), readU64()
+	msgs := make(map[uint64]*schedMsg, count)
+	for i := uint64(0); i < count && bi > 0; i++ {
+		seq := readU64()
+		if bi < 0 || bi >= len(b) {
+			bi = -1
+			break
+		}
+		due, n := binary.Varint(b[bi:])
+		if n <= 0 {
+			bi = -1
+			break
+		}
+		bi += n
+		sl := readU64()
+		if bi < 0 || uint64(len(b)-bi) < sl {
+			bi = -1
+			break
+		}
+		msgs[seq] = &schedMsg{subj: string(b[bi : bi+int(sl)]), due: due}
+		bi += int(sl)
+	}
+	if bi < 0 {
+		return 0, errBadMsgSchedulesState
+	}
+	for seq, sm := range msgs {
+		ms.add(seq, sm.subj, sm.due)
+	}
+	return highSeq, nil
+}
+
+// enableMsgSchedules sets up tracking of scheduled messages. The index persisted when
+// the stream stopped is recovered, and only messages stored after it are scanned.
+// Tracked messages that are no longer stored are dropped once they are due.
+func (mset *stream) enableMsgSchedules() {
+	if mset.sched.Load() != nil {
+		return
+	}
+	ms := newMsgSchedules(mset.processMsgSchedules)
+	var state StreamState
+	mset.store.FastState(&state)
+
+	fseq := state.FirstSeq
+	if fn := mset.msgSchedulesFile(); fn != _EMPTY_ {
+		if buf, err := os.ReadFile(fn); err == nil {
+			if hseq, err := ms.decode(buf); err != nil {
+				mset.srv.Warnf("Error decoding message schedules %q: %v", fn, err)
+				ms = newMsgSchedules(mset.processMsgSchedules)
+			} else if hseq > fseq {
+				fseq = hseq
+			}
+		} else if !os.IsNotExist(err) {
+			mset.srv.Warnf("Error reading message schedules %q: %v", fn, err)
+		}
+	}
+
+	var smv StoreMsg
+	for seq := fseq; seq <= state.LastSeq && state.Msgs > 0; seq++ {
+		sm, nseq, err := mset.store.LoadNextMsg(fwcs, true, seq, &smv)
+		if err != nil || sm == nil {
+			break
+		}
+		seq = nseq
+		if due, err := getMessageSchedule(sm.hdr, sm.ts); err == nil && due > sm.ts {
+			ms.add(seq, sm.subj, due)
+		}
+	}
+	mset.sched.Store(ms)
+}
+
+// Returns the file the index of scheduled messages is persisted in, only for file based streams.
+func (mset *stream) msgSchedulesFile() string {
+	mset.mu.RLock()
+	defer mset.mu.RUnlock()
+	if mset.cfg.Storage != FileStorage || mset.jsa == nil {
+		return _EMPTY_
+	}
+	mset.jsa.mu.RLock()
+	defer mset.jsa.mu.RUnlock()
+	return filepath.Join(mset.jsa.storeDir, streamsDir, mset.cfg.Name, msgSchedulesStateFile)
+}
+
+// writeMsgSchedules persists the index of scheduled messages, called when the stream stops.
+func (mset *stream) writeMsgSchedules() {
+	ms, fn := mset.sched.Load(), mset.msgSchedulesFile()
+	if ms == nil || fn == _EMPTY_ {
+		return
+	}
+	var state StreamState
+	mset.store.FastState(&state)
+	if err := writeFileWithSync(fn, ms.encode(state.LastSeq+1), defaultFilePerms); err != nil {
+		mset.srv.Warnf("Error writing message schedules %q: %v", fn, err)
+	}
+}
+
+// trackScheduledMsg is called when a message has been stored. It will track
+// the message if scheduled, or remove the original if this is the due copy.
+// Returns true if the message is scheduled and should not be signaled to consumers.
+// Lock should not be held.
+func (mset *stream) trackScheduledMsg(seq uint64, subj string, hdr []byte, ts int64) bool {
+	ms := mset.sched.Load()
+	if ms == nil || len(hdr) == 0 {
+		return false
+	}
+	if due, _ := getMessageSchedule(hdr, ts); due > ts {
+		ms.add(seq, subj, due)
+		return true
+	}
+	// The header is stripped from client and sourced messages, so only our due copies carry it.
+	if pseq := getScheduledSeq(hdr); pseq > 0 && ms.isDue(pseq, subj, ts) {
+		// The store callback will stop tracking it.
+		mset.removeMsg(pseq)
+	}
+	return false
+}
+
+// processMsgSchedules is called by the leader when scheduled messages are due.
+// The copy goes through the normal inbound path so it is replicated, and it
+// carries a message id so a leader change can't store it twice.
+func (mset *stream) processMsgSchedules() {
+	ms := mset.sched.Load()
+	if ms == nil || mset.closed.Load() || !mset.isLeader() {
+		return
+	}
+	for _, seq := range ms.expire() {
+		var smv StoreMsg
+		sm, err := mset.store.LoadMsg(seq, &smv)
+		if err != nil || sm == nil {
+			ms.remove(seq)
+			continue
+		}
+		hdr := copyBytes(sm.hdr)
+		for _, key := range []string{JSScheduleAt, JSScheduleDelay, JSMsgId, JSMsgRollup, JSBatchId, JSBatchSeq, JSBatchCommit} {
+			hdr = removeHeaderIfPresent(hdr, key)
+		}
+		hdr = removeHeaderIfPrefixPresent(hdr, "Nats-Expected-")
+		hdr = genHeader(hdr, JSScheduledSeq, strconv.FormatUint(seq, 10))
+		hdr = genHeader(hdr, JSMsgId, "sched-"+strconv.FormatUint(seq, 10))
+		mset.queueInbound(mset.msgs, sm.subj, _EMPTY_, hdr, copyBytes(sm.msg), nil, nil)
+	}
+}
+
+// prune stops tracking scheduled messages that are no longer stored, e.g. after a purge.
+func (ms *msgSchedules) prune(store StreamStore) {
+	ms.mu.Lock()
+	seqs := make([]uint64, 0, len(ms.msgs))
+	for seq := range ms.msgs {
+		seqs = append(seqs, seq)
+	}
+	ms.mu.Unlock()
+
+	// Do not hold our lock while calling into the store, it calls back into us.
+	var smv StoreMsg
+	for _, seq := range seqs {
+		if _, err := store.LoadMsg(seq, &smv); err == ErrStoreMsgNotFound || err == errDeletedMsg || err == ErrStoreEOF {
+			ms.remove(seq)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestJetStreamMessageScheduleHeaders(t *testing.T) {
+	ts := time.Now().UnixNano()
+	at := time.Now().Add(time.Hour).UTC()
+
+	for _, test := range []struct {
+		desc string
+		hdr  []byte
+		due  int64
+		err  bool
+	}{
+		{"none", nil, 0, false},
+		{"delay", genHeader(nil, JSScheduleDelay, "5s"), ts + int64(5*time.Second), false},
+		{"at", genHeader(nil, JSScheduleAt, at.Format(time.RFC3339Nano)), at.UnixNano(), false},
+		{"bad-delay", genHeader(nil, JSScheduleDelay, "soon"), 0, true},
+		{"negative-delay", genHeader(nil, JSScheduleDelay, "-5s"), 0, true},
+		{"bad-at", genHeader(nil, JSScheduleAt, "tomorrow"), 0, true},
+		{"both", genHeader(genHeader(nil, JSScheduleAt, at.Format(time.RFC3339Nano)), JSScheduleDelay, "5s"), 0, true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			due, err := getMessageSchedule(test.hdr, ts)
+			if test.err {
+				require_Error(t, err, NewJSMessageScheduleInvalidError())
+				return
+			}
+			require_NoError(t, err)
+			require_Equal(t, due, test.due)
+		})
+	}
+
+	// A schedule in the past is not hidden from consumers.
+	due, err := getMessageSchedule(genHeader(nil, JSScheduleAt, time.Unix(0, ts).Add(-time.Second).Format(time.RFC3339Nano)), ts)
+	require_NoError(t, err)
+	require_True(t, due <= ts)
+	due, err = getMessageSchedule(genHeader(nil, JSScheduleDelay, "1s"), ts)
+	require_NoError(t, err)
+	require_True(t, due > ts)
+}
+
+func TestJetStreamMessageSchedulesDisabled(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage})
+	require_NoError(t, err)
+
+	m := nats.NewMsg("foo")
+	m.Header.Set(JSScheduleDelay, "1s")
+	_, err = js.PublishMsg(m)
+	require_Error(t, err, NewJSMessageSchedulesDisabledError())
+
+	// Can be enabled, but not disabled again.
+	_, err = jsStreamUpdate(t, nc, &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, AllowMsgSchedules: true})
+	require_NoError(t, err)
+	_, err = js.PublishMsg(m)
+	require_NoError(t, err)
+	_, err = jsStreamUpdate(t, nc, &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage})
+	require_Error(t, err)
+
+	m = nats.NewMsg("foo")
+	m.Header.Set(JSScheduleDelay, "whenever")
+	_, err = js.PublishMsg(m)
+	require_Error(t, err, NewJSMessageScheduleInvalidError())
+}
+
+func TestJetStreamMessageSchedules(t *testing.T) {
+	test := func(t *testing.T, s *Server, replicas int) {
+		nc, js := jsClientConnect(t, s)
+		defer nc.Close()
+
+		_, err := jsStreamCreate(t, nc, &StreamConfig{
+			Name:              "TEST",
+			Subjects:          []string{"jobs.*"},
+			Storage:           FileStorage,
+			Replicas:          replicas,
+			AllowMsgSchedules: true,
+		})
+		require_NoError(t, err)
+
+		_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "C", AckPolicy: nats.AckExplicitPolicy})
+		require_NoError(t, err)
+
+		m := nats.NewMsg("jobs.later")
+		m.Header.Set(JSScheduleDelay, "1500ms")
+		m.Data = []byte("later")
+		_, err = js.PublishMsg(m)
+		require_NoError(t, err)
+		_, err = js.Publish("jobs.now", []byte("now"))
+		require_NoError(t, err)
+
+		// Only the due message counts as pending.
+		ci, err := js.ConsumerInfo("TEST", "C")
+		require_NoError(t, err)
+		require_Equal(t, ci.NumPending, 1)
+
+		sub, err := js.PullSubscribe(_EMPTY_, "C", nats.BindStream("TEST"))
+		require_NoError(t, err)
+		defer sub.Drain()
+
+		msgs, err := sub.Fetch(2, nats.MaxWait(250*time.Millisecond))
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 1)
+		require_Equal(t, string(msgs[0].Data), "now")
+		require_NoError(t, msgs[0].AckSync())
+
+		// Once due, the scheduled message is delivered.
+		msgs, err = sub.Fetch(1, nats.MaxWait(5*time.Second))
+		require_NoError(t, err)
+		require_Len(t, len(msgs), 1)
+		require_Equal(t, string(msgs[0].Data), "later")
+		require_Equal(t, msgs[0].Subject, "jobs.later")
+		require_Equal(t, msgs[0].Header.Get(JSScheduledSeq), "1")
+		require_Equal(t, msgs[0].Header.Get(JSScheduleDelay), _EMPTY_)
+		require_NoError(t, msgs[0].AckSync())
+
+		// The original scheduled message is removed.
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			si, err := js.StreamInfo("TEST")
+			if err != nil {
+				return err
+			}
+			if si.State.Msgs != 2 {
+				return fmt.Errorf("expected 2 msgs, got %d", si.State.Msgs)
+			}
+			return nil
+		})
+		_, err = js.GetMsg("TEST", 1)
+		require_Error(t, err, nats.ErrMsgNotFound)
+	}
+
+	t.Run("R1", func(t *testing.T) {
+		s := RunBasicJetStreamServer(t)
+		defer s.Shutdown()
+		test(t, s, 1)
+	})
+	t.Run("R3", func(t *testing.T) {
+		c := createJetStreamClusterExplicit(t, "R3S", 3)
+		defer c.shutdown()
+		test(t, c.randomServer(), 3)
+	})
+}
+
+func TestJetStreamMessageSchedulesRecoverOnRestart(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:              "TEST",
+		Subjects:          []string{"foo"},
+		Storage:           FileStorage,
+		AllowMsgSchedules: true,
+	})
+	require_NoError(t, err)
+
+	m := nats.NewMsg("foo")
+	m.Header.Set(JSScheduleAt, time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano))
+	_, err = js.PublishMsg(m)
+	require_NoError(t, err)
+	nc.Close()
+
+	sd := s.JetStreamConfig().StoreDir
+	s.Shutdown()
+	// The scheduled messages are persisted so they don't need to be scanned for.
+	_, err = os.Stat(filepath.Join(sd, globalAccountName, streamsDir, "TEST", msgSchedulesStateFile))
+	require_NoError(t, err)
+	s = RunJetStreamServerOnPort(-1, sd)
+	defer s.Shutdown()
+
+	mset, err := s.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_Equal(t, mset.sched.Load().count(), 1)
+
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		if n := mset.sched.Load().count(); n != 0 {
+			return fmt.Errorf("expected no scheduled msgs, got %d", n)
+		}
+		var smv StoreMsg
+		sm, err := mset.store.LoadLastMsg("foo", &smv)
+		if err != nil {
+			return err
+		}
+		if getScheduledSeq(sm.hdr) != 1 {
+			return fmt.Errorf("expected due copy of seq 1")
+		}
+		return nil
+	})
+}
+
+func TestJetStreamMessageSchedulesStripScheduledSeq(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := jsStreamCreate(t, nc, &StreamConfig{Name: "ORIGIN", Subjects: []string{"origin"}, Storage: FileStorage})
+	require_NoError(t, err)
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:              "TEST",
+		Subjects:          []string{"foo"},
+		Storage:           FileStorage,
+		AllowMsgSchedules: true,
+		Sources:           []*StreamSource{{Name: "ORIGIN"}},
+	})
+	require_NoError(t, err)
+
+	m := nats.NewMsg("foo")
+	m.Header.Set(JSScheduleDelay, "1h")
+	_, err = js.PublishMsg(m)
+	require_NoError(t, err)
+
+	// Neither clients nor sources can claim to be the due copy of a scheduled message.
+	for _, subj := range []string{"foo", "origin"} {
+		m = nats.NewMsg(subj)
+		m.Header.Set(JSScheduledSeq, "1")
+		_, err = js.PublishMsg(m)
+		require_NoError(t, err)
+	}
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		si, err := js.StreamInfo("TEST")
+		if err != nil {
+			return err
+		}
+		if si.State.Msgs != 3 {
+			return fmt.Errorf("expected 3 msgs, got %d", si.State.Msgs)
+		}
+		return nil
+	})
+	for seq := uint64(2); seq <= 3; seq++ {
+		rsm, err := js.GetMsg("TEST", seq)
+		require_NoError(t, err)
+		require_Equal(t, rsm.Header.Get(JSScheduledSeq), _EMPTY_)
+	}
+
+	// The scheduled message is still tracked.
+	mset, err := s.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	require_Equal(t, mset.sched.Load().count(), 1)
+}
@@ -0,0 +1,778 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nats-io/nuid"
+)
+
+// SchemaType is the format of a stream schema.
+type SchemaType string
+
+const (
+	// JSONSchemaType validates JSON payloads against a JSON Schema document.
+	JSONSchemaType = SchemaType("json")
+	// ProtobufSchemaType validates protobuf encoded payloads against a message
+	// from a FileDescriptorSet.
+	ProtobufSchemaType = SchemaType("protobuf")
+)
+
+// StreamSchema is used to validate the payload of messages published to a stream.
+// Messages that do not conform are rejected before they are stored.
+type StreamSchema struct {
+	// Type is the format of the schema.
+	Type SchemaType `json:"type"`
+	// Version is the version of the schema, reported with rejections.
+	Version string `json:"version,omitempty"`
+	// Definition is the JSON Schema document, for the json type.
+	Definition json.RawMessage `json:"definition,omitempty"`
+	// Descriptor is a serialized FileDescriptorSet, for the protobuf type.
+	Descriptor []byte `json:"descriptor,omitempty"`
+	// Message is the fully qualified name of the protobuf message in the descriptor set.
+	Message string `json:"message,omitempty"`
+	// Strict rejects protobuf messages with fields that are not part of the message type.
+	// By default these are skipped, so producers can add fields before the schema is updated.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// StreamSchemaInfo shows the schema in use and how many messages it rejected.
+type StreamSchemaInfo struct {
+	Type     SchemaType `json:"type"`
+	Version  string     `json:"version,omitempty"`
+	Rejected uint64     `json:"rejected"`
+}
+
+func (ss *StreamSchema) clone() *StreamSchema {
+	clone := *ss
+	clone.Definition = copyBytes(ss.Definition)
+	clone.Descriptor = copyBytes(ss.Descriptor)
+	return &clone
+}
+
+// schemaValidator validates the payload of a message.
+type schemaValidator interface {
+	validate(msg []byte) error
+}
+
+// JSMaxSchemaSize is the maximum size of a schema definition or descriptor,
+// since it is part of the stream configuration.
+const JSMaxSchemaSize = 256 * 1024
+
+// Minimum interval between schema rejected advisories for a stream.
+const schemaRejectedAdvisoryInterval = time.Second
+
+// compile checks the schema and returns the validator for it.
+func (ss *StreamSchema) compile() (schemaValidator, error) {
+	if len(ss.Definition) > JSMaxSchemaSize || len(ss.Descriptor) > JSMaxSchemaSize {
+		return nil, fmt.Errorf("schema can not be larger than %d bytes", JSMaxSchemaSize)
+	}
+	switch ss.Type {
+	case JSONSchemaType:
+		if len(ss.Descriptor) > 0 || ss.Message != _EMPTY_ || ss.Strict {
+			return nil, errors.New("json schema can not have a protobuf descriptor, message or strict mode")
+		}
+		return compileJSONSchema(ss.Definition)
+	case ProtobufSchemaType:
+		if len(ss.Definition) > 0 {
+			return nil, errors.New("protobuf schema can not have a json definition")
+		}
+		return compileProtoSchema(ss.Descriptor, ss.Message, ss.Strict)
+	case _EMPTY_:
+		return nil, errors.New("schema type is required")
+	default:
+		return nil, fmt.Errorf("schema type %q is not supported", ss.Type)
+	}
+}
+
+// streamSchema is the compiled schema of a stream.
+type streamSchema struct {
+	schemaValidator
+	version string
+}
+
+// Sets the schema for the stream, when created or updated.
+// Lock should be held.
+func (mset *stream) setSchema(ss *StreamSchema) {
+	mset.schema = nil
+	if ss == nil {
+		return
+	}
+	// Already checked as part of the stream config.
+	if sv, err := ss.compile(); err == nil {
+		mset.schema = &streamSchema{sv, ss.Version}
+	}
+}
+
+// schemaRejected counts a message rejected by the stream schema and sends an advisory.
+// Advisories are rate limited, the ones not sent are counted in the next one.
+// mset.mu lock must NOT be held or used.
+func (mset *stream) schemaRejected(sch *streamSchema, name, subject string, err error) *ApiError {
+	mset.schemaRejections.Add(1)
+
+	now := time.Now()
+	last := mset.schemaAdvisory.Load()
+	if now.UnixNano()-last < int64(schemaRejectedAdvisoryInterval) || !mset.schemaAdvisory.CompareAndSwap(last, now.UnixNano()) {
+		mset.schemaSuppressed.Add(1)
+		return NewJSStreamSchemaValidationFailedError(err)
+	}
+
+	s := mset.srv
+	s.publishAdvisory(mset.acc, JSAdvisoryStreamSchemaValidationFailedPre+"."+name, &JSStreamSchemaValidationFailedAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamSchemaValidationFailedAdvisoryType,
+			ID:   nuid.Next(),
+			Time: now.UTC(),
+		},
+		Stream:     name,
+		Subject:    subject,
+		Version:    sch.version,
+		Error:      err.Error(),
+		Suppressed: mset.schemaSuppressed.Swap(0),
+		Domain:     s.getOpts().JetStreamDomain,
+	})
+	return NewJSStreamSchemaValidationFailedError(err)
+}
+
+// Returns the schema in use and its rejections, or nil if the stream does not use one.
+func (mset *stream) schemaInfo() *StreamSchemaInfo {
+	mset.mu.RLock()
+	ss := mset.cfg.Schema
+	mset.mu.RUnlock()
+	if ss == nil {
+		return nil
+	}
+	return &StreamSchemaInfo{Type: ss.Type, Version: ss.Version, Rejected: mset.schemaRejections.Load()}
+}
+
+// jsonSchema is a compiled JSON Schema. The validation keywords for types,
+// objects, arrays, strings, numbers and combinations are supported, as well
+// as references to definitions within the same document. Annotations, such as
+// formats, are ignored and any other keyword is rejected.
+type jsonSchema struct {
+	root   *jsonSchemaRoot
+	always *bool // For the true and false schemas.
+	ref    string
+
+	types []string
+	enum  []any
+	cnst  any
+	hasC  bool
+
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	minProperties        int
+	maxProperties        int
+
+	items    *jsonSchema
+	minItems int
+	maxItems int
+
+	minLength int
+	maxLength int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	allOf []*jsonSchema
+	anyOf []*jsonSchema
+	oneOf []*jsonSchema
+	not   *jsonSchema
+}
+
+// Holds the definitions that can be referenced, keyed by their JSON pointer.
+type jsonSchemaRoot struct {
+	defs map[string]*jsonSchema
+}
+
+// Limit on nested validation, since references can be recursive.
+const jsonSchemaMaxDepth = 64
+
+// The keywords a schema can use. Annotations are accepted but ignored, any other
+// keyword is rejected, so a schema never validates less than its author expects.
+var jsonSchemaKeywords = map[string]struct{}{
+	// Validation.
+	"$ref": {}, "type": {}, "enum": {}, "const": {},
+	"properties": {}, "required": {}, "additionalProperties": {}, "minProperties": {}, "maxProperties": {},
+	"items": {}, "minItems": {}, "maxItems": {},
+	"minLength": {}, "maxLength": {}, "pattern": {},
+	"minimum": {}, "maximum": {}, "exclusiveMinimum": {}, "exclusiveMaximum": {}, "multipleOf": {},
+	"allOf": {}, "anyOf": {}, "oneOf": {}, "not": {},
+	// Definitions and annotations.
+	"$schema": {}, "$id": {}, "$comment": {}, "$defs": {}, "definitions": {},
+	"title": {}, "description": {}, "default": {}, "examples": {}, "deprecated": {},
+	"readOnly": {}, "writeOnly": {}, "format": {}, "contentEncoding": {}, "contentMediaType": {},
+}
+
+var jsonSchemaTypes = map[string]struct{}{
+	"null": {}, "boolean": {}, "object": {}, "array": {}, "number": {}, "integer": {}, "string": {},
+}
+
+// Decodes a JSON document, keeping numbers as json.Number.
+func decodeJSONSchemaDoc(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+	return doc, nil
+}
+
+func compileJSONSchema(def []byte) (*jsonSchema, error) {
+	if len(bytes.TrimSpace(def)) == 0 {
+		return nil, errors.New("json schema definition is required")
+	}
+	doc, err := decodeJSONSchemaDoc(def)
+	if err != nil {
+		return nil, fmt.Errorf("json schema definition is not valid json: %v", err)
+	}
+	root := &jsonSchemaRoot{defs: make(map[string]*jsonSchema)}
+	if m, ok := doc.(map[string]any); ok {
+		for _, key := range []string{"$defs", "definitions"} {
+			defs, ok := m[key].(map[string]any)
+			if !ok {
+				continue
+			}
+			for name, d := range defs {
+				ptr := "#/" + key + "/" + name
+				js, err := compileJSONSchemaNode(d, root, ptr)
+				if err != nil {
+					return nil, err
+				}
+				root.defs[ptr] = js
+			}
+		}
+	}
+	js, err := compileJSONSchemaNode(doc, root, "#")
+	if err != nil {
+		return nil, err
+	}
+	root.defs["#"] = js
+
+	// Make sure all references can be resolved.
+	var check func(js *jsonSchema) error
+	check = func(js *jsonSchema) error {
+		if js == nil {
+			return nil
+		}
+		if js.ref != _EMPTY_ {
+			if _, ok := root.defs[js.ref]; !ok {
+				return fmt.Errorf("json schema reference %q can not be resolved", js.ref)
+			}
+		}
+		subs := []*jsonSchema{js.additionalProperties, js.items, js.not}
+		for _, p := range js.properties {
+			subs = append(subs, p)
+		}
+		subs = append(subs, js.allOf...)
+		subs = append(subs, js.anyOf...)
+		subs = append(subs, js.oneOf...)
+		for _, sub := range subs {
+			if err := check(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, d := range root.defs {
+		if err := check(d); err != nil {
+			return nil, err
+		}
+	}
+	return js, nil
+}
+
+func compileJSONSchemaNode(doc any, root *jsonSchemaRoot, loc string) (*jsonSchema, error) {
+	js := &jsonSchema{root: root, maxProperties: -1, maxItems: -1, maxLength: -1}
+	switch v := doc.(type) {
+	case bool:
+		js.always = &v
+		return js, nil
+	case map[string]any:
+	default:
+		return nil, fmt.Errorf("json schema at %q must be an object or boolean", loc)
+	}
+	m := doc.(map[string]any)
+
+	// Check in order so errors are stable.
+	keywords := make([]string, 0, len(m))
+	for kw := range m {
+		keywords = append(keywords, kw)
+	}
+	sort.Strings(keywords)
+	for _, kw := range keywords {
+		if _, ok := jsonSchemaKeywords[kw]; !ok {
+			return nil, fmt.Errorf("json schema keyword %q at %q is not supported", kw, loc)
+		}
+	}
+
+	invalid := func(kw string) error {
+		return fmt.Errorf("json schema keyword %q at %q is not valid", kw, loc)
+	}
+	number := func(kw string) (*float64, error) {
+		v, ok := m[kw]
+		if !ok {
+			return nil, nil
+		}
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, invalid(kw)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, invalid(kw)
+		}
+		return &f, nil
+	}
+	count := func(kw string, def int) (int, error) {
+		f, err := number(kw)
+		if err != nil || f == nil {
+			return def, err
+		}
+		if *f < 0 || *f != math.Trunc(*f) {
+			return 0, invalid(kw)
+		}
+		return int(*f), nil
+	}
+	schema := func(kw string) (*jsonSchema, error) {
+		v, ok := m[kw]
+		if !ok {
+			return nil, nil
+		}
+		return compileJSONSchemaNode(v, root, loc+"/"+kw)
+	}
+	schemas := func(kw string) ([]*jsonSchema, error) {
+		v, ok := m[kw]
+		if !ok {
+			return nil, nil
+		}
+		arr, ok := v.([]any)
+		if !ok || len(arr) == 0 {
+			return nil, invalid(kw)
+		}
+		var list []*jsonSchema
+		for i, sv := range arr {
+			sub, err := compileJSONSchemaNode(sv, root, fmt.Sprintf("%s/%s/%d", loc, kw, i))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, sub)
+		}
+		return list, nil
+	}
+
+	var err error
+	if v, ok := m["$ref"]; ok {
+		ref, ok := v.(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return nil, fmt.Errorf("json schema reference at %q must be local to the document", loc)
+		}
+		js.ref = ref
+	}
+	if v, ok := m["type"]; ok {
+		switch t := v.(type) {
+		case string:
+			js.types = []string{t}
+		case []any:
+			for _, tv := range t {
+				ts, ok := tv.(string)
+				if !ok {
+					return nil, invalid("type")
+				}
+				js.types = append(js.types, ts)
+			}
+		default:
+			return nil, invalid("type")
+		}
+		for _, t := range js.types {
+			if _, ok := jsonSchemaTypes[t]; !ok {
+				return nil, fmt.Errorf("json schema type %q at %q is not valid", t, loc)
+			}
+		}
+	}
+	if v, ok := m["enum"]; ok {
+		if js.enum, ok = v.([]any); !ok {
+			return nil, invalid("enum")
+		}
+	}
+	if v, ok := m["const"]; ok {
+		js.cnst, js.hasC = v, true
+	}
+
+	// Objects.
+	if v, ok := m["properties"]; ok {
+		props, ok := v.(map[string]any)
+		if !ok {
+			return nil, invalid("properties")
+		}
+		js.properties = make(map[string]*jsonSchema, len(props))
+		for name, pv := range props {
+			if js.properties[name], err = compileJSONSchemaNode(pv, root, loc+"/properties/"+name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v, ok := m["required"]; ok {
+		req, ok := v.([]any)
+		if !ok {
+			return nil, invalid("required")
+		}
+		for _, rv := range req {
+			name, ok := rv.(string)
+			if !ok {
+				return nil, invalid("required")
+			}
+			js.required = append(js.required, name)
+		}
+	}
+	if js.additionalProperties, err = schema("additionalProperties"); err != nil {
+		return nil, err
+	}
+	if js.minProperties, err = count("minProperties", 0); err != nil {
+		return nil, err
+	}
+	if js.maxProperties, err = count("maxProperties", -1); err != nil {
+		return nil, err
+	}
+
+	// Arrays.
+	if js.items, err = schema("items"); err != nil {
+		return nil, err
+	}
+	if js.minItems, err = count("minItems", 0); err != nil {
+		return nil, err
+	}
+	if js.maxItems, err = count("maxItems", -1); err != nil {
+		return nil, err
+	}
+
+	// Strings.
+	if js.minLength, err = count("minLength", 0); err != nil {
+		return nil, err
+	}
+	if js.maxLength, err = count("maxLength", -1); err != nil {
+		return nil, err
+	}
+	if v, ok := m["pattern"]; ok {
+		p, ok := v.(string)
+		if !ok {
+			return nil, invalid("pattern")
+		}
+		if js.pattern, err = regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("json schema pattern at %q is not valid: %v", loc, err)
+		}
+	}
+
+	// Numbers.
+	if js.minimum, err = number("minimum"); err != nil {
+		return nil, err
+	}
+	if js.maximum, err = number("maximum"); err != nil {
+		return nil, err
+	}
+	if js.exclusiveMinimum, err = number("exclusiveMinimum"); err != nil {
+		return nil, err
+	}
+	if js.exclusiveMaximum, err = number("exclusiveMaximum"); err != nil {
+		return nil, err
+	}
+	if js.multipleOf, err = number("multipleOf"); err != nil {
+		return nil, err
+	}
+	if js.multipleOf != nil && *js.multipleOf <= 0 {
+		return nil, invalid("multipleOf")
+	}
+
+	// Combinations.
+	if js.allOf, err = schemas("allOf"); err != nil {
+		return nil, err
+	}
+	if js.anyOf, err = schemas("anyOf"); err != nil {
+		return nil, err
+	}
+	if js.oneOf, err = schemas("oneOf"); err != nil {
+		return nil, err
+	}
+	if js.not, err = schema("not"); err != nil {
+		return nil, err
+	}
+	return js, nil
+}
+
+func (js *jsonSchema) validate(msg []byte) error {
+	doc, err := decodeJSONSchemaDoc(msg)
+	if err != nil {
+		return fmt.Errorf("payload is not valid json: %v", err)
+	}
+	return js.check(doc, _EMPTY_, 0)
+}
+
+// Returns the location of a value in the payload for errors.
+func jsonSchemaLoc(path string) string {
+	if path == _EMPTY_ {
+		return "/"
+	}
+	return path
+}
+
+func jsonSchemaTypeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+// Compares two decoded JSON values, numbers are compared by value.
+func jsonSchemaEqual(a, b any) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if ov, ok := bv[k]; !ok || !jsonSchemaEqual(v, ov) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonSchemaEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func (js *jsonSchema) check(v any, path string, depth int) error {
+	if depth > jsonSchemaMaxDepth {
+		return fmt.Errorf("%s: schema is nested too deeply", jsonSchemaLoc(path))
+	}
+	if js.always != nil {
+		if !*js.always {
+			return fmt.Errorf("%s: value is not allowed", jsonSchemaLoc(path))
+		}
+		return nil
+	}
+	if js.ref != _EMPTY_ {
+		if err := js.root.defs[js.ref].check(v, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if len(js.types) > 0 {
+		vt, match := jsonSchemaTypeOf(v), false
+		for _, t := range js.types {
+			if t == vt || (t == "number" && vt == "integer") {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return fmt.Errorf("%s: expected %s, got %s", jsonSchemaLoc(path), strings.Join(js.types, " or "), vt)
+		}
+	}
+	if js.enum != nil {
+		var found bool
+		for _, ev := range js.enum {
+			if jsonSchemaEqual(v, ev) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of the allowed values", jsonSchemaLoc(path))
+		}
+	}
+	if js.hasC && !jsonSchemaEqual(v, js.cnst) {
+		return fmt.Errorf("%s: value does not match the constant", jsonSchemaLoc(path))
+	}
+
+	switch tv := v.(type) {
+	case map[string]any:
+		if err := js.checkObject(tv, path, depth); err != nil {
+			return err
+		}
+	case []any:
+		if len(tv) < js.minItems {
+			return fmt.Errorf("%s: expected at least %d items", jsonSchemaLoc(path), js.minItems)
+		}
+		if js.maxItems >= 0 && len(tv) > js.maxItems {
+			return fmt.Errorf("%s: expected at most %d items", jsonSchemaLoc(path), js.maxItems)
+		}
+		if js.items != nil {
+			for i, iv := range tv {
+				if err := js.items.check(iv, fmt.Sprintf("%s/%d", path, i), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(tv)
+		if n < js.minLength {
+			return fmt.Errorf("%s: expected at least %d characters", jsonSchemaLoc(path), js.minLength)
+		}
+		if js.maxLength >= 0 && n > js.maxLength {
+			return fmt.Errorf("%s: expected at most %d characters", jsonSchemaLoc(path), js.maxLength)
+		}
+		if js.pattern != nil && !js.pattern.MatchString(tv) {
+			return fmt.Errorf("%s: does not match pattern %q", jsonSchemaLoc(path), js.pattern.String())
+		}
+	case json.Number:
+		if err := js.checkNumber(tv, path); err != nil {
+			return err
+		}
+	}
+
+	for _, sub := range js.allOf {
+		if err := sub.check(v, path, depth+1); err != nil {
+			return err
+		}
+	}
+	if len(js.anyOf) > 0 {
+		var match bool
+		for _, sub := range js.anyOf {
+			if sub.check(v, path, depth+1) == nil {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return fmt.Errorf("%s: does not match any of the allowed schemas", jsonSchemaLoc(path))
+		}
+	}
+	if len(js.oneOf) > 0 {
+		var matches int
+		for _, sub := range js.oneOf {
+			if sub.check(v, path, depth+1) == nil {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s: expected to match exactly one schema, matched %d", jsonSchemaLoc(path), matches)
+		}
+	}
+	if js.not != nil && js.not.check(v, path, depth+1) == nil {
+		return fmt.Errorf("%s: matches a schema that is not allowed", jsonSchemaLoc(path))
+	}
+	return nil
+}
+
+func (js *jsonSchema) checkObject(obj map[string]any, path string, depth int) error {
+	if len(obj) < js.minProperties {
+		return fmt.Errorf("%s: expected at least %d properties", jsonSchemaLoc(path), js.minProperties)
+	}
+	if js.maxProperties >= 0 && len(obj) > js.maxProperties {
+		return fmt.Errorf("%s: expected at most %d properties", jsonSchemaLoc(path), js.maxProperties)
+	}
+	for _, name := range js.required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", jsonSchemaLoc(path), name)
+		}
+	}
+	// Check in order so errors are stable.
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ppath := path + "/" + name
+		if ps, ok := js.properties[name]; ok {
+			if err := ps.check(obj[name], ppath, depth+1); err != nil {
+				return err
+			}
+		} else if js.additionalProperties != nil {
+			if err := js.additionalProperties.check(obj[name], ppath, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (js *jsonSchema) checkNumber(n json.Number, path string) error {
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("%s: number is out of range", jsonSchemaLoc(path))
+	}
+	if js.minimum != nil && f < *js.minimum {
+		return fmt.Errorf("%s: must be >= %v", jsonSchemaLoc(path), *js.minimum)
+	}
+	if js.maximum != nil && f > *js.maximum {
+		return fmt.Errorf("%s: must be <= %v", jsonSchemaLoc(path), *js.maximum)
+	}
+	if js.exclusiveMinimum != nil && f <= *js.exclusiveMinimum {
+		return fmt.Errorf("%s: must be > %v", jsonSchemaLoc(path), *js.exclusiveMinimum)
+	}
+	if js.exclusiveMaximum != nil && f >= *js.exclusiveMaximum {
+		return fmt.Errorf("%s: must be < %v", jsonSchemaLoc(path), *js.exclusiveMaximum)
+	}
+	if js.multipleOf != nil {
+		if q := f / *js.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			return fmt.Errorf("%s: must be a multiple of %v", jsonSchemaLoc(path), *js.multipleOf)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,504 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Protobuf wire types.
+const (
+	protoWireVarint     = 0
+	protoWireFixed64    = 1
+	protoWireBytes      = 2
+	protoWireStartGroup = 3
+	protoWireEndGroup   = 4
+	protoWireFixed32    = 5
+)
+
+// Field types and labels from descriptor.proto.
+const (
+	protoTypeDouble   = 1
+	protoTypeFloat    = 2
+	protoTypeInt64    = 3
+	protoTypeUint64   = 4
+	protoTypeInt32    = 5
+	protoTypeFixed64  = 6
+	protoTypeFixed32  = 7
+	protoTypeBool     = 8
+	protoTypeString   = 9
+	protoTypeGroup    = 10
+	protoTypeMessage  = 11
+	protoTypeBytes    = 12
+	protoTypeUint32   = 13
+	protoTypeEnum     = 14
+	protoTypeSfixed32 = 15
+	protoTypeSfixed64 = 16
+	protoTypeSint32   = 17
+	protoTypeSint64   = 18
+
+	protoLabelRequired = 2
+	protoLabelRepeated = 3
+)
+
+// Limit on nested messages, since message types can be recursive.
+const protoMaxDepth = 64
+
+// protoSchema validates protobuf encoded payloads against a message type. The
+// payload must be well formed, use the expected wire types for the fields known
+// to the message type, contain valid UTF-8 for proto3 strings, and have all proto2
+// required fields. Nested messages and groups are validated the same way. Unknown
+// fields are skipped, as protobuf parsers do, unless strict.
+type protoSchema struct {
+	msg    *protoMessageDesc
+	strict bool
+}
+
+type protoMessageDesc struct {
+	name     string
+	proto3   bool
+	fields   map[uint64]*protoFieldDesc
+	required []uint64
+}
+
+type protoFieldDesc struct {
+	name     string
+	number   uint64
+	typ      uint64
+	repeated bool
+	required bool
+	typeName string
+	msg      *protoMessageDesc // Resolved for message and group fields.
+}
+
+// Returns the wire type a field is encoded with, when not packed.
+func (fd *protoFieldDesc) wireType() uint64 {
+	switch fd.typ {
+	case protoTypeDouble, protoTypeFixed64, protoTypeSfixed64:
+		return protoWireFixed64
+	case protoTypeFloat, protoTypeFixed32, protoTypeSfixed32:
+		return protoWireFixed32
+	case protoTypeString, protoTypeBytes, protoTypeMessage:
+		return protoWireBytes
+	case protoTypeGroup:
+		return protoWireStartGroup
+	default:
+		return protoWireVarint
+	}
+}
+
+// protoReader decodes protobuf wire format.
+type protoReader struct {
+	buf   []byte
+	depth int // Nesting of the groups being skipped.
+}
+
+func (r *protoReader) done() bool { return len(r.buf) == 0 }
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errors.New("malformed varint")
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *protoReader) fixed(n int) error {
+	if len(r.buf) < n {
+		return errors.New("truncated fixed width value")
+	}
+	r.buf = r.buf[n:]
+	return nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(len(r.buf)) {
+		return nil, errors.New("truncated length delimited value")
+	}
+	b := r.buf[:l]
+	r.buf = r.buf[l:]
+	return b, nil
+}
+
+// Returns the field number and wire type of the next field.
+func (r *protoReader) tag() (uint64, uint64, error) {
+	t, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	num, wt := t>>3, t&7
+	if num == 0 || num > 1<<29-1 {
+		return 0, 0, fmt.Errorf("invalid field number %d", num)
+	}
+	return num, wt, nil
+}
+
+// Returns the fields of the group with the given field number, and consumes its end.
+func (r *protoReader) group(num uint64) ([]byte, error) {
+	if r.depth++; r.depth > protoMaxDepth {
+		return nil, errors.New("group is nested too deeply")
+	}
+	defer func() { r.depth-- }()
+	start := r.buf
+	for {
+		if r.done() {
+			return nil, errors.New("truncated group")
+		}
+		end := r.buf
+		n, wt, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		if wt == protoWireEndGroup {
+			if n != num {
+				return nil, fmt.Errorf("end of group %d does not match group %d", n, num)
+			}
+			return start[:len(start)-len(end)], nil
+		}
+		if err := r.skip(n, wt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Skips a value of the field with the given number and wire type.
+func (r *protoReader) skip(num, wt uint64) error {
+	switch wt {
+	case protoWireVarint:
+		_, err := r.varint()
+		return err
+	case protoWireFixed64:
+		return r.fixed(8)
+	case protoWireFixed32:
+		return r.fixed(4)
+	case protoWireBytes:
+		_, err := r.bytes()
+		return err
+	case protoWireStartGroup:
+		_, err := r.group(num)
+		return err
+	case protoWireEndGroup:
+		return fmt.Errorf("unexpected end of group %d", num)
+	default:
+		return fmt.Errorf("unsupported wire type %d", wt)
+	}
+}
+
+// Descriptors as read from a FileDescriptorSet, before resolving types.
+type protoRawMessage struct {
+	name   string
+	proto3 bool
+	fields []*protoFieldDesc
+	nested []*protoRawMessage
+}
+
+func compileProtoSchema(descriptor []byte, message string, strict bool) (*protoSchema, error) {
+	if len(descriptor) == 0 {
+		return nil, errors.New("protobuf descriptor set is required")
+	}
+	if message == _EMPTY_ {
+		return nil, errors.New("protobuf message name is required")
+	}
+	msgs := make(map[string]*protoMessageDesc)
+	var fields []*protoFieldDesc
+
+	// FileDescriptorSet has repeated FileDescriptorProto as field 1.
+	r := &protoReader{buf: descriptor}
+	for !r.done() {
+		num, wt, err := r.tag()
+		if err != nil {
+			return nil, fmt.Errorf("protobuf descriptor set is not valid: %v", err)
+		}
+		if num != 1 || wt != protoWireBytes {
+			if err := r.skip(num, wt); err != nil {
+				return nil, fmt.Errorf("protobuf descriptor set is not valid: %v", err)
+			}
+			continue
+		}
+		fb, err := r.bytes()
+		if err != nil {
+			return nil, fmt.Errorf("protobuf descriptor set is not valid: %v", err)
+		}
+		pkg, raws, err := parseProtoFile(fb)
+		if err != nil {
+			return nil, fmt.Errorf("protobuf descriptor set is not valid: %v", err)
+		}
+		prefix := "."
+		if pkg != _EMPTY_ {
+			prefix += pkg + "."
+		}
+		var register func(prefix string, raw *protoRawMessage)
+		register = func(prefix string, raw *protoRawMessage) {
+			fqn := prefix + raw.name
+			md := &protoMessageDesc{name: strings.TrimPrefix(fqn, "."), proto3: raw.proto3, fields: make(map[uint64]*protoFieldDesc)}
+			for _, fd := range raw.fields {
+				md.fields[fd.number] = fd
+				if fd.typ == protoTypeMessage || fd.typ == protoTypeGroup {
+					fields = append(fields, fd)
+				}
+				if !raw.proto3 && fd.required {
+					md.required = append(md.required, fd.number)
+				}
+			}
+			msgs[fqn] = md
+			for _, n := range raw.nested {
+				register(fqn+".", n)
+			}
+		}
+		for _, raw := range raws {
+			register(prefix, raw)
+		}
+	}
+
+	// Resolve message field types.
+	for _, fd := range fields {
+		md, ok := msgs[fd.typeName]
+		if !ok {
+			return nil, fmt.Errorf("protobuf message type %q of field %q can not be resolved", fd.typeName, fd.name)
+		}
+		fd.msg = md
+	}
+	md, ok := msgs["."+strings.TrimPrefix(message, ".")]
+	if !ok {
+		return nil, fmt.Errorf("protobuf message %q not found in descriptor set", message)
+	}
+	return &protoSchema{msg: md, strict: strict}, nil
+}
+
+// Returns the package and top level messages of a FileDescriptorProto.
+func parseProtoFile(b []byte) (string, []*protoRawMessage, error) {
+	var pkg, syntax string
+	var msgs []*protoRawMessage
+	var msgBufs [][]byte
+	r := &protoReader{buf: b}
+	for !r.done() {
+		num, wt, err := r.tag()
+		if err != nil {
+			return _EMPTY_, nil, err
+		}
+		if wt != protoWireBytes {
+			if err := r.skip(num, wt); err != nil {
+				return _EMPTY_, nil, err
+			}
+			continue
+		}
+		v, err := r.bytes()
+		if err != nil {
+			return _EMPTY_, nil, err
+		}
+		switch num {
+		case 2: // package
+			pkg = string(v)
+		case 4: // message_type
+			msgBufs = append(msgBufs, v)
+		case 12: // syntax
+			syntax = string(v)
+		}
+	}
+	// Syntax comes after the messages, so parse them last.
+	for _, mb := range msgBufs {
+		m, err := parseProtoMessage(mb, syntax == "proto3")
+		if err != nil {
+			return _EMPTY_, nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return pkg, msgs, nil
+}
+
+// Parses a DescriptorProto.
+func parseProtoMessage(b []byte, proto3 bool) (*protoRawMessage, error) {
+	m := &protoRawMessage{proto3: proto3}
+	r := &protoReader{buf: b}
+	for !r.done() {
+		num, wt, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		if wt != protoWireBytes {
+			if err := r.skip(num, wt); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		v, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		switch num {
+		case 1: // name
+			m.name = string(v)
+		case 2: // field
+			fd, err := parseProtoField(v)
+			if err != nil {
+				return nil, err
+			}
+			m.fields = append(m.fields, fd)
+		case 3: // nested_type
+			n, err := parseProtoMessage(v, proto3)
+			if err != nil {
+				return nil, err
+			}
+			m.nested = append(m.nested, n)
+		}
+	}
+	if m.name == _EMPTY_ {
+		return nil, errors.New("message without a name")
+	}
+	return m, nil
+}
+
+// Parses a FieldDescriptorProto.
+func parseProtoField(b []byte) (*protoFieldDesc, error) {
+	fd := &protoFieldDesc{}
+	var label uint64
+	r := &protoReader{buf: b}
+	for !r.done() {
+		num, wt, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case wt == protoWireVarint:
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			switch num {
+			case 3: // number
+				fd.number = v
+			case 4: // label
+				label = v
+			case 5: // type
+				fd.typ = v
+			}
+		case wt == protoWireBytes:
+			v, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			switch num {
+			case 1: // name
+				fd.name = string(v)
+			case 6: // type_name
+				fd.typeName = string(v)
+			}
+		default:
+			if err := r.skip(num, wt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if fd.number == 0 || fd.typ == 0 || fd.typ > protoTypeSint64 {
+		return nil, fmt.Errorf("field %q is not valid", fd.name)
+	}
+	if (fd.typ == protoTypeMessage || fd.typ == protoTypeGroup) && !strings.HasPrefix(fd.typeName, ".") {
+		return nil, fmt.Errorf("field %q must have a fully qualified type name", fd.name)
+	}
+	fd.repeated, fd.required = label == protoLabelRepeated, label == protoLabelRequired
+	return fd, nil
+}
+
+func (ps *protoSchema) validate(msg []byte) error {
+	return ps.msg.check(msg, ps.msg.name, 0, ps.strict)
+}
+
+func (md *protoMessageDesc) check(b []byte, path string, depth int, strict bool) error {
+	if depth > protoMaxDepth {
+		return fmt.Errorf("%s: message is nested too deeply", path)
+	}
+	var seen map[uint64]struct{}
+	if len(md.required) > 0 {
+		seen = make(map[uint64]struct{}, len(md.required))
+	}
+	r := &protoReader{buf: b}
+	for !r.done() {
+		num, wt, err := r.tag()
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		fd, ok := md.fields[num]
+		if !ok {
+			if strict {
+				return fmt.Errorf("%s: unknown field %d", path, num)
+			}
+			if err := r.skip(num, wt); err != nil {
+				return fmt.Errorf("%s: field %d: %v", path, num, err)
+			}
+			continue
+		}
+		fpath := path + "." + fd.name
+		ewt := fd.wireType()
+		if wt != ewt {
+			// Repeated scalars can be packed.
+			scalar := ewt == protoWireVarint || ewt == protoWireFixed64 || ewt == protoWireFixed32
+			if !(fd.repeated && wt == protoWireBytes && scalar) {
+				return fmt.Errorf("%s: wrong wire type %d", fpath, wt)
+			}
+			v, err := r.bytes()
+			if err != nil {
+				return fmt.Errorf("%s: %v", fpath, err)
+			}
+			pr := &protoReader{buf: v}
+			for !pr.done() {
+				if err := pr.skip(num, ewt); err != nil {
+					return fmt.Errorf("%s: %v", fpath, err)
+				}
+			}
+		} else if wt == protoWireStartGroup {
+			v, err := r.group(num)
+			if err != nil {
+				return fmt.Errorf("%s: %v", fpath, err)
+			}
+			if err := fd.msg.check(v, fpath, depth+1, strict); err != nil {
+				return err
+			}
+		} else if wt == protoWireBytes {
+			v, err := r.bytes()
+			if err != nil {
+				return fmt.Errorf("%s: %v", fpath, err)
+			}
+			switch fd.typ {
+			case protoTypeString:
+				if md.proto3 && !utf8.Valid(v) {
+					return fmt.Errorf("%s: string is not valid UTF-8", fpath)
+				}
+			case protoTypeMessage:
+				if err := fd.msg.check(v, fpath, depth+1, strict); err != nil {
+					return err
+				}
+			}
+		} else if err := r.skip(num, wt); err != nil {
+			return fmt.Errorf("%s: %v", fpath, err)
+		}
+		if seen != nil {
+			seen[num] = struct{}{}
+		}
+	}
+	for _, num := range md.required {
+		if _, ok := seen[num]; !ok {
+			return fmt.Errorf("%s: missing required field %q", path, md.fields[num].name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_js_tests
+
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestJetStreamSchemaJSONValidate(t *testing.T) {
+	def := `{
+		"type": "object",
+		"required": ["id", "qty"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "pattern": "^o-[0-9]+$"},
+			"qty": {"type": "integer", "minimum": 1, "maximum": 100},
+			"price": {"type": "number", "exclusiveMinimum": 0, "multipleOf": 0.01},
+			"status": {"enum": ["new", "paid"]},
+			"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "maxItems": 2},
+			"item": {"$ref": "#/$defs/item"},
+			"note": {"anyOf": [{"type": "null"}, {"type": "string", "maxLength": 5}]}
+		},
+		"$defs": {
+			"item": {"type": "object", "required": ["sku"], "properties": {"sku": {"const": "A1"}}}
+		}
+	}`
+	sv, err := (&StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(def)}).compile()
+	require_NoError(t, err)
+
+	for _, test := range []struct {
+		name string
+		msg  string
+		ok   bool
+	}{
+		{"minimal", `{"id":"o-1","qty":1}`, true},
+		{"full", `{"id":"o-1","qty":100,"price":9.99,"status":"paid","tags":["a","b"],"item":{"sku":"A1"},"note":null}`, true},
+		{"not json", `not json`, false},
+		{"not an object", `[1,2]`, false},
+		{"missing required", `{"id":"o-1"}`, false},
+		{"additional property", `{"id":"o-1","qty":1,"extra":true}`, false},
+		{"pattern", `{"id":"x-1","qty":1}`, false},
+		{"not an integer", `{"id":"o-1","qty":1.5}`, false},
+		{"maximum", `{"id":"o-1","qty":101}`, false},
+		{"exclusive minimum", `{"id":"o-1","qty":1,"price":0}`, false},
+		{"multiple of", `{"id":"o-1","qty":1,"price":1.005}`, false},
+		{"enum", `{"id":"o-1","qty":1,"status":"lost"}`, false},
+		{"max items", `{"id":"o-1","qty":1,"tags":["a","b","c"]}`, false},
+		{"item min length", `{"id":"o-1","qty":1,"tags":[""]}`, false},
+		{"ref", `{"id":"o-1","qty":1,"item":{"sku":"B2"}}`, false},
+		{"any of", `{"id":"o-1","qty":1,"note":"too long"}`, false},
+		{"trailing data", `{"id":"o-1","qty":1} {}`, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := sv.validate([]byte(test.msg))
+			if test.ok {
+				require_NoError(t, err)
+			} else {
+				require_Error(t, err)
+			}
+		})
+	}
+}
+
+func TestJetStreamSchemaCompile(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		ss   *StreamSchema
+	}{
+		{"no type", &StreamSchema{Definition: json.RawMessage(`{}`)}},
+		{"unknown type", &StreamSchema{Type: "avro", Definition: json.RawMessage(`{}`)}},
+		{"no definition", &StreamSchema{Type: JSONSchemaType}},
+		{"bad json", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{`)}},
+		{"bad type keyword", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"type":"decimal"}`)}},
+		{"bad pattern", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"pattern":"("}`)}},
+		{"unresolved ref", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"$ref":"#/$defs/missing"}`)}},
+		{"remote ref", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"$ref":"https://example.com/s.json"}`)}},
+		{"unsupported keyword", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"type":"array","uniqueItems":true}`)}},
+		{"unsupported nested keyword", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"properties":{"a":{"if":{"type":"string"}}}}`)}},
+		{"too large", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"description":"` + strings.Repeat("a", JSMaxSchemaSize) + `"}`)}},
+		{"json with descriptor", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{}`), Descriptor: []byte{1}}},
+		{"json strict", &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{}`), Strict: true}},
+		{"no descriptor", &StreamSchema{Type: ProtobufSchemaType, Message: "test.Order"}},
+		{"no message", &StreamSchema{Type: ProtobufSchemaType, Descriptor: testProtoDescriptorSet()}},
+		{"unknown message", &StreamSchema{Type: ProtobufSchemaType, Descriptor: testProtoDescriptorSet(), Message: "test.Missing"}},
+		{"bad descriptor", &StreamSchema{Type: ProtobufSchemaType, Descriptor: []byte{0x0a, 0xff}, Message: "test.Order"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.ss.compile()
+			require_Error(t, err)
+		})
+	}
+}
+
+// Helpers to encode protobuf wire format for descriptors and messages.
+func pbTag(b []byte, num, wt uint64) []byte {
+	return binary.AppendUvarint(b, num<<3|wt)
+}
+
+func pbVarint(b []byte, num, v uint64) []byte {
+	return binary.AppendUvarint(pbTag(b, num, protoWireVarint), v)
+}
+
+func pbBytes(b []byte, num uint64, v []byte) []byte {
+	b = binary.AppendUvarint(pbTag(b, num, protoWireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+func pbField(name string, number, label, typ uint64, typeName string) []byte {
+	b := pbBytes(nil, 1, []byte(name))
+	b = pbVarint(b, 3, number)
+	b = pbVarint(b, 4, label)
+	b = pbVarint(b, 5, typ)
+	if typeName != _EMPTY_ {
+		b = pbBytes(b, 6, []byte(typeName))
+	}
+	return b
+}
+
+// Returns a descriptor set with a proto3 file equivalent to:
+//
+//	package test;
+//	message Order {
+//	  message Item { string sku = 1; }
+//	  string id = 1;
+//	  int64 qty = 2;
+//	  repeated int32 tags = 3;
+//	  Item item = 4;
+//	}
+//
+// And a proto2 file equivalent to:
+//
+//	package legacy;
+//	message Ping {
+//	  required uint32 id = 1;
+//	  optional fixed64 ts = 2;
+//	  optional group Extra = 3 { required string note = 1; }
+//	}
+func testProtoDescriptorSet() []byte {
+	const optional = 1
+	item := pbBytes(nil, 1, []byte("Item"))
+	item = pbBytes(item, 2, pbField("sku", 1, optional, protoTypeString, _EMPTY_))
+
+	order := pbBytes(nil, 1, []byte("Order"))
+	order = pbBytes(order, 2, pbField("id", 1, optional, protoTypeString, _EMPTY_))
+	order = pbBytes(order, 2, pbField("qty", 2, optional, protoTypeInt64, _EMPTY_))
+	order = pbBytes(order, 2, pbField("tags", 3, protoLabelRepeated, protoTypeInt32, _EMPTY_))
+	order = pbBytes(order, 2, pbField("item", 4, optional, protoTypeMessage, ".test.Order.Item"))
+	order = pbBytes(order, 3, item)
+
+	file := pbBytes(nil, 1, []byte("test.proto"))
+	file = pbBytes(file, 2, []byte("test"))
+	file = pbBytes(file, 4, order)
+	file = pbBytes(file, 12, []byte("proto3"))
+
+	extra := pbBytes(nil, 1, []byte("Extra"))
+	extra = pbBytes(extra, 2, pbField("note", 1, protoLabelRequired, protoTypeString, _EMPTY_))
+
+	ping := pbBytes(nil, 1, []byte("Ping"))
+	ping = pbBytes(ping, 2, pbField("id", 1, protoLabelRequired, protoTypeUint32, _EMPTY_))
+	ping = pbBytes(ping, 2, pbField("ts", 2, optional, protoTypeFixed64, _EMPTY_))
+	ping = pbBytes(ping, 2, pbField("extra", 3, optional, protoTypeGroup, ".legacy.Ping.Extra"))
+	ping = pbBytes(ping, 3, extra)
+
+	legacy := pbBytes(nil, 1, []byte("legacy.proto"))
+	legacy = pbBytes(legacy, 2, []byte("legacy"))
+	legacy = pbBytes(legacy, 4, ping)
+
+	fds := pbBytes(nil, 1, file)
+	return pbBytes(fds, 1, legacy)
+}
+
+func TestJetStreamSchemaProtobufValidate(t *testing.T) {
+	fds := testProtoDescriptorSet()
+	order, err := (&StreamSchema{Type: ProtobufSchemaType, Descriptor: fds, Message: "test.Order"}).compile()
+	require_NoError(t, err)
+	ping, err := (&StreamSchema{Type: ProtobufSchemaType, Descriptor: fds, Message: ".legacy.Ping"}).compile()
+	require_NoError(t, err)
+	strict, err := (&StreamSchema{Type: ProtobufSchemaType, Descriptor: fds, Message: "test.Order", Strict: true}).compile()
+	require_NoError(t, err)
+
+	item := pbBytes(nil, 1, []byte("A1"))
+	packed := binary.AppendUvarint(binary.AppendUvarint(nil, 1), 2)
+	group := func(b []byte, num uint64, fields []byte) []byte {
+		return pbTag(append(pbTag(b, num, protoWireStartGroup), fields...), num, protoWireEndGroup)
+	}
+
+	for _, test := range []struct {
+		name string
+		sv   schemaValidator
+		msg  []byte
+		ok   bool
+	}{
+		{"empty", order, nil, true},
+		{"fields", order, pbVarint(pbBytes(nil, 1, []byte("o-1")), 2, 5), true},
+		{"repeated", order, pbVarint(pbVarint(nil, 3, 1), 3, 2), true},
+		{"packed", order, pbBytes(nil, 3, packed), true},
+		{"nested", order, pbBytes(nil, 4, item), true},
+		{"unknown field", order, pbVarint(nil, 9, 1), true},
+		{"unknown group", order, group(nil, 9, pbVarint(group(nil, 10, nil), 1, 1)), true},
+		{"unknown field strict", strict, pbVarint(nil, 9, 1), false},
+		{"bad unknown field", order, pbTag(nil, 9, protoWireBytes), false},
+		{"unterminated unknown group", order, pbTag(nil, 9, protoWireStartGroup), false},
+		{"stray end of group", order, pbTag(nil, 9, protoWireEndGroup), false},
+		{"wrong wire type", order, pbVarint(nil, 1, 1), false},
+		{"invalid utf8", order, pbBytes(nil, 1, []byte{0xff, 0xfe}), false},
+		{"truncated", order, pbBytes(nil, 1, []byte("o-1"))[:3], false},
+		{"bad nested", order, pbBytes(nil, 4, pbVarint(nil, 1, 1)), false},
+		{"not packable", order, pbBytes(nil, 2, packed), false},
+		{"required", ping, pbVarint(nil, 1, 7), true},
+		{"required and optional", ping, binary.LittleEndian.AppendUint64(pbTag(pbVarint(nil, 1, 7), 2, protoWireFixed64), 1), true},
+		{"missing required", ping, binary.LittleEndian.AppendUint64(pbTag(nil, 2, protoWireFixed64), 1), false},
+		{"group", ping, group(pbVarint(nil, 1, 7), 3, pbBytes(nil, 1, []byte("n"))), true},
+		{"group missing required", ping, group(pbVarint(nil, 1, 7), 3, nil), false},
+		{"group mismatched end", ping, pbTag(pbTag(pbVarint(nil, 1, 7), 3, protoWireStartGroup), 4, protoWireEndGroup), false},
+		{"group as bytes", ping, pbBytes(pbVarint(nil, 1, 7), 3, nil), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.sv.validate(test.msg)
+			if test.ok {
+				require_NoError(t, err)
+			} else {
+				require_Error(t, err)
+			}
+		})
+	}
+}
+
+func TestJetStreamSchemaStreamConfig(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, _ := jsClientConnect(t, s)
+	defer nc.Close()
+
+	schema := &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"type":"object"}`)}
+
+	// Invalid schemas are rejected.
+	_, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:     "TEST",
+		Subjects: []string{"foo"},
+		Storage:  FileStorage,
+		Schema:   &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{"type":1}`)},
+	})
+	require_Error(t, err)
+
+	// Counters can't have a schema.
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:            "TEST",
+		Subjects:        []string{"foo"},
+		Storage:         FileStorage,
+		AllowMsgCounter: true,
+		Schema:          schema,
+	})
+	require_Error(t, err)
+
+	// Mirrors can't have a schema.
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:    "M",
+		Storage: FileStorage,
+		Mirror:  &StreamSource{Name: "TEST"},
+		Schema:  schema,
+	})
+	require_Error(t, err)
+
+	// The schema can be added, changed and removed.
+	cfg := &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage}
+	_, err = jsStreamCreate(t, nc, cfg)
+	require_NoError(t, err)
+
+	cfg.Schema = schema
+	ncfg, err := jsStreamUpdate(t, nc, cfg)
+	require_NoError(t, err)
+	require_NotNil(t, ncfg.Schema)
+	require_Equal(t, ncfg.Schema.Type, JSONSchemaType)
+
+	cfg.Schema = nil
+	ncfg, err = jsStreamUpdate(t, nc, cfg)
+	require_NoError(t, err)
+	require_True(t, ncfg.Schema == nil)
+}
+
+func TestJetStreamSchemaPublish(t *testing.T) {
+	test := func(t *testing.T, replicas int) {
+		var s *Server
+		if replicas == 1 {
+			s = RunBasicJetStreamServer(t)
+			defer s.Shutdown()
+		} else {
+			c := createJetStreamClusterExplicit(t, "R3S", replicas)
+			defer c.shutdown()
+			s = c.randomServer()
+		}
+
+		nc, js := jsClientConnect(t, s)
+		defer nc.Close()
+
+		cfg := &StreamConfig{
+			Name:     "TEST",
+			Subjects: []string{"foo"},
+			Storage:  FileStorage,
+			Replicas: replicas,
+			Schema: &StreamSchema{
+				Type:       JSONSchemaType,
+				Version:    "1",
+				Definition: json.RawMessage(`{"type":"object","required":["id"]}`),
+			},
+		}
+		_, err := jsStreamCreate(t, nc, cfg)
+		require_NoError(t, err)
+
+		sub, err := nc.SubscribeSync(JSAdvisoryStreamSchemaValidationFailedPre + ".TEST")
+		require_NoError(t, err)
+		defer sub.Unsubscribe()
+		require_NoError(t, nc.Flush())
+
+		_, err = js.Publish("foo", []byte(`{"id":1}`))
+		require_NoError(t, err)
+
+		_, err = js.Publish("foo", []byte(`{"name":"x"}`))
+		var apiErr *nats.APIError
+		require_True(t, errors.As(err, &apiErr))
+		require_Equal(t, apiErr.ErrorCode, nats.ErrorCode(JSStreamSchemaValidationFailedErrF))
+
+		msg, err := sub.NextMsg(time.Second)
+		require_NoError(t, err)
+		var adv JSStreamSchemaValidationFailedAdvisory
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Type, JSStreamSchemaValidationFailedAdvisoryType)
+		require_Equal(t, adv.Stream, "TEST")
+		require_Equal(t, adv.Subject, "foo")
+		require_Equal(t, adv.Version, "1")
+		require_NotEqual(t, adv.Error, _EMPTY_)
+		require_Equal(t, adv.Suppressed, 0)
+
+		// Advisories are rate limited, the ones not sent are counted in the next one.
+		for i := 0; i < 2; i++ {
+			_, err = js.Publish("foo", []byte(`{"name":"x"}`))
+			require_Error(t, err)
+		}
+		_, err = sub.NextMsg(100 * time.Millisecond)
+		require_Error(t, err, nats.ErrTimeout)
+
+		si, err := js.StreamInfo("TEST")
+		require_NoError(t, err)
+		require_Equal(t, si.State.Msgs, 1)
+
+		// The rejections are reported in the stream info.
+		var resp JSApiStreamInfoResponse
+		rmsg, err := nc.Request(fmt.Sprintf(JSApiStreamInfoT, "TEST"), nil, time.Second)
+		require_NoError(t, err)
+		require_NoError(t, json.Unmarshal(rmsg.Data, &resp))
+		require_True(t, resp.StreamInfo != nil && resp.StreamInfo.Schema != nil)
+		require_Equal(t, resp.StreamInfo.Schema.Version, "1")
+		require_Equal(t, resp.StreamInfo.Schema.Rejected, 3)
+
+		// Updating the schema applies to new messages.
+		cfg.Schema.Version = "2"
+		cfg.Schema.Definition = json.RawMessage(`{"type":"object","required":["name"]}`)
+		_, err = jsStreamUpdate(t, nc, cfg)
+		require_NoError(t, err)
+
+		checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+			_, err := js.Publish("foo", []byte(`{"name":"x"}`))
+			return err
+		})
+		time.Sleep(schemaRejectedAdvisoryInterval)
+		_, err = js.Publish("foo", []byte(`{"id":1}`))
+		require_Error(t, err)
+
+		msg, err = sub.NextMsg(time.Second)
+		require_NoError(t, err)
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Version, "2")
+		require_Equal(t, adv.Suppressed, 2)
+	}
+
+	t.Run("R1", func(t *testing.T) { test(t, 1) })
+	t.Run("R3", func(t *testing.T) { test(t, 3) })
+}
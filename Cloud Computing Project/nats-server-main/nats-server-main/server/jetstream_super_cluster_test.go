@@ -4815,3 +4815,281 @@ func TestJetStreamSuperClusterConsumerPauseAdvisories(t *testing.T) {
 	checkAdvisory(msg, true, deadline)
 	require_Len(t, len(ch), 0) // Should only receive one advisory.
 }
+
+func TestJetStreamSuperClusterStreamFailover(t *testing.T) {
+	sc := createJetStreamSuperCluster(t, 3, 2)
+	defer sc.shutdown()
+
+	nc, js := jsClientConnect(t, sc.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{
+		Name:      "ORDERS",
+		Subjects:  []string{"orders.>"},
+		Replicas:  3,
+		Placement: &nats.Placement{Cluster: "C1"},
+	})
+	require_NoError(t, err)
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:      "DR",
+		Mirror:    &StreamSource{Name: "ORDERS", LagThreshold: 2},
+		Replicas:  3,
+		Storage:   FileStorage,
+		Placement: &Placement{Cluster: "C2"},
+	})
+	require_NoError(t, err)
+
+	checkMsgs := func(stream string, msgs uint64) {
+		t.Helper()
+		checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+			si, err := js.StreamInfo(stream)
+			if err != nil {
+				return err
+			}
+			if si.State.Msgs != msgs {
+				return fmt.Errorf("expected %d msgs, got %d", msgs, si.State.Msgs)
+			}
+			return nil
+		})
+	}
+	streamLeader := func(cluster, stream string) *stream {
+		t.Helper()
+		sc.waitOnStreamLeader(globalAccountName, stream)
+		sl := sc.clusterForName(cluster).streamLeader(globalAccountName, stream)
+		mset, err := sl.globalAccount().lookupStream(stream)
+		require_NoError(t, err)
+		return mset
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err = js.Publish("orders.new", []byte("ok"))
+		require_NoError(t, err)
+	}
+	checkMsgs("DR", 10)
+	mi := streamLeader("C2", "DR").mirrorInfo()
+	require_NotNil(t, mi)
+	require_Equal(t, mi.LastSeq, 10)
+
+	roles, err := nc.SubscribeSync(JSAdvisoryStreamRoleChangedPre + ".*")
+	require_NoError(t, err)
+	lags := make(chan *nats.Msg, 10)
+	for _, subj := range []string{JSAdvisoryStreamReplicationLagExceededPre, JSAdvisoryStreamReplicationLagRecoveredPre} {
+		_, err = nc.ChanSubscribe(subj+".*", lags)
+		require_NoError(t, err)
+	}
+	require_NoError(t, nc.Flush())
+
+	failover := func(stream string, req *JSApiStreamFailoverRequest) *JSApiStreamFailoverResponse {
+		t.Helper()
+		b, err := json.Marshal(req)
+		require_NoError(t, err)
+		msg, err := nc.Request(fmt.Sprintf(JSApiStreamFailoverT, stream), b, 10*time.Second)
+		require_NoError(t, err)
+		var resp JSApiStreamFailoverResponse
+		require_NoError(t, json.Unmarshal(msg.Data, &resp))
+		return &resp
+	}
+	// The promoted and the demoted stream each send a role change, in no particular order.
+	rolesChanged := func(roleOf map[string]string) {
+		t.Helper()
+		for range roleOf {
+			msg, err := roles.NextMsg(5 * time.Second)
+			require_NoError(t, err)
+			var adv JSStreamRoleChangedAdvisory
+			require_NoError(t, json.Unmarshal(msg.Data, &adv))
+			require_Equal(t, adv.Type, JSStreamRoleChangedAdvisoryType)
+			require_Equal(t, adv.Role, roleOf[adv.Stream])
+			require_Equal(t, adv.Gap, 0)
+		}
+	}
+	lagAdvisory := func(typ, stream, mirror string) {
+		t.Helper()
+		msg := require_ChanRead(t, lags, 5*time.Second)
+		var adv JSStreamReplicationLagAdvisory
+		require_NoError(t, json.Unmarshal(msg.Data, &adv))
+		require_Equal(t, adv.Type, typ)
+		require_Equal(t, adv.Stream, stream)
+		require_Equal(t, adv.Mirror, mirror)
+		require_Equal(t, adv.Threshold, 2)
+	}
+
+	// The primary can not be promoted.
+	resp := failover("ORDERS", &JSApiStreamFailoverRequest{})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamNotMirrorErr))
+
+	// The primary has a message that was not replicated.
+	resp = failover("ORDERS", &JSApiStreamFailoverRequest{Mirror: &StreamSource{Name: "DR"}, Seq: 9})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamFailoverConflictErrF))
+	require_Equal(t, resp.Gap, 1)
+
+	// A stalled mirror exceeds its lag threshold, since its lag is no longer known.
+	mset := streamLeader("C2", "DR")
+	mset.mu.Lock()
+	mset.mirror.last.Store(time.Now().Add(-2 * sourceHealthCheckInterval).UnixNano())
+	mset.checkMirrorLag()
+	mset.mu.Unlock()
+	lagAdvisory(JSStreamReplicationLagExceededAdvisoryType, "DR", "ORDERS")
+
+	// A mirror that is not replicating can only be promoted when forced.
+	mset.mu.Lock()
+	mset.cancelMirrorConsumer()
+	mset.mu.Unlock()
+	resp = failover("DR", &JSApiStreamFailoverRequest{})
+	require_NotNil(t, resp.Error)
+	require_Equal(t, resp.Error.ErrCode, uint16(JSStreamFailoverInactiveErr))
+
+	// Promote the mirror, the primary is demoted to mirror it in the same step.
+	resp = failover("DR", &JSApiStreamFailoverRequest{Force: true})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	require_Equal(t, resp.Role, StreamRolePrimary)
+	require_Equal(t, resp.LastSeq, 10)
+	require_Equal(t, resp.Gap, 0)
+	require_Equal(t, resp.Mirror.Name, "ORDERS")
+	rolesChanged(map[string]string{"DR": StreamRolePrimary, "ORDERS": StreamRoleMirror})
+
+	si, err := js.StreamInfo("DR")
+	require_NoError(t, err)
+	require_True(t, si.Config.Mirror == nil)
+	require_True(t, si.Mirror == nil)
+	require_Equal(t, strings.Join(si.Config.Subjects, ","), "orders.>")
+	si, err = js.StreamInfo("ORDERS")
+	require_NoError(t, err)
+	require_NotNil(t, si.Config.Mirror)
+	require_Equal(t, si.Config.Mirror.Name, "DR")
+	require_Len(t, len(si.Config.Subjects), 0)
+
+	// The old primary catches up on what is written to the new one.
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("orders.new", []byte("dr"))
+		require_NoError(t, err)
+	}
+	checkMsgs("DR", 15)
+	checkMsgs("ORDERS", 15)
+
+	// The demoted stream inherits the lag threshold of the pair.
+	mset = streamLeader("C1", "ORDERS")
+	mset.mu.Lock()
+	mset.mirror.last.Store(time.Now().Add(-2 * sourceHealthCheckInterval).UnixNano())
+	mset.checkMirrorLag()
+	mset.mu.Unlock()
+	lagAdvisory(JSStreamReplicationLagExceededAdvisoryType, "ORDERS", "DR")
+	_, err = js.Publish("orders.new", []byte("dr"))
+	require_NoError(t, err)
+	lagAdvisory(JSStreamReplicationLagRecoveredAdvisoryType, "ORDERS", "DR")
+	checkMsgs("ORDERS", 16)
+
+	// Fail back, which reverses the direction again.
+	resp = failover("ORDERS", &JSApiStreamFailoverRequest{})
+	require_True(t, resp.Error == nil)
+	require_Equal(t, resp.Role, StreamRolePrimary)
+	require_Equal(t, resp.LastSeq, 16)
+	rolesChanged(map[string]string{"ORDERS": StreamRolePrimary, "DR": StreamRoleMirror})
+
+	_, err = js.Publish("orders.new", []byte("ok"))
+	require_NoError(t, err)
+	checkMsgs("ORDERS", 17)
+	checkMsgs("DR", 17)
+}
+
+func TestJetStreamSuperClusterStreamFailoverWhilePublishing(t *testing.T) {
+	sc := createJetStreamSuperCluster(t, 3, 2)
+	defer sc.shutdown()
+
+	nc, js := jsClientConnect(t, sc.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{
+		Name:      "ORDERS",
+		Subjects:  []string{"orders.>"},
+		Replicas:  3,
+		Placement: &nats.Placement{Cluster: "C1"},
+	})
+	require_NoError(t, err)
+	_, err = jsStreamCreate(t, nc, &StreamConfig{
+		Name:      "DR",
+		Mirror:    &StreamSource{Name: "ORDERS"},
+		Replicas:  3,
+		Storage:   FileStorage,
+		Placement: &Placement{Cluster: "C2"},
+	})
+	require_NoError(t, err)
+
+	// Keep publishing while failing over, and remember what was acknowledged.
+	// A sequence acknowledged by both streams means one of the messages was lost.
+	type ack struct {
+		seq  uint64
+		data string
+	}
+	var acked []ack
+	var mu sync.Mutex
+	qch, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-qch:
+				return
+			default:
+			}
+			data := fmt.Sprintf("msg-%d", i)
+			if pa, err := js.Publish("orders.new", []byte(data), nats.AckWait(time.Second)); err == nil {
+				mu.Lock()
+				acked = append(acked, ack{pa.Sequence, data})
+				mu.Unlock()
+			}
+		}
+	}()
+	checkFor(t, 5*time.Second, 50*time.Millisecond, func() error {
+		si, err := js.StreamInfo("DR")
+		if err != nil {
+			return err
+		}
+		if si.State.Msgs < 100 {
+			return fmt.Errorf("expected at least 100 msgs, got %d", si.State.Msgs)
+		}
+		return nil
+	})
+
+	msg, err := nc.Request(fmt.Sprintf(JSApiStreamFailoverT, "DR"), nil, 10*time.Second)
+	require_NoError(t, err)
+	var resp JSApiStreamFailoverResponse
+	require_NoError(t, json.Unmarshal(msg.Data, &resp))
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	require_Equal(t, resp.Role, StreamRolePrimary)
+	require_Equal(t, resp.Gap, 0)
+
+	// Publish some more to the new primary before stopping.
+	time.Sleep(250 * time.Millisecond)
+	close(qch)
+	<-done
+
+	// Every acknowledged message is kept by the promoted stream, and replicated back to the demoted one.
+	si, err := js.StreamInfo("DR")
+	require_NoError(t, err)
+	require_True(t, si.Config.Mirror == nil)
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		osi, err := js.StreamInfo("ORDERS")
+		if err != nil {
+			return err
+		}
+		if osi.State.LastSeq != si.State.LastSeq {
+			return fmt.Errorf("expected last seq %d, got %d", si.State.LastSeq, osi.State.LastSeq)
+		}
+		return nil
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	for _, stream := range []string{"DR", "ORDERS"} {
+		for _, a := range acked {
+			sm, err := js.GetMsg(stream, a.seq)
+			require_NoError(t, err)
+			require_Equal(t, string(sm.Data), a.data)
+		}
+	}
+}
@@ -16804,6 +16804,93 @@ func TestJetStreamDirectGetBatch(t *testing.T) {
 	checkResponses(sub, 3, "foo.foo", "foo.bar", "foo.baz", _EMPTY_)
 }
 
+func TestJetStreamDirectGetBatchFilter(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{
+		Name:        "TEST",
+		Subjects:    []string{"foo.*"},
+		AllowDirect: true,
+	})
+	require_NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		m := nats.NewMsg("foo.bar")
+		if i%2 == 0 {
+			m.Header.Set("Kind", "even")
+		} else {
+			m.Header.Set("Kind", "odd")
+		}
+		m.Data = []byte("HELLO")
+		_, err := js.PublishMsg(m)
+		require_NoError(t, err)
+	}
+
+	sendRequest := func(mreq *JSApiMsgGetRequest) *nats.Subscription {
+		t.Helper()
+		req, _ := json.Marshal(mreq)
+		reply := nats.NewInbox()
+		sub, err := nc.SubscribeSync(reply)
+		require_NoError(t, err)
+		require_NoError(t, nc.PublishRequest("$JS.API.DIRECT.GET.TEST", reply, req))
+		return sub
+	}
+
+	odd := &MsgFilter{Header: "Kind", Op: MsgFilterEq, Value: "odd"}
+	for _, mreq := range []*JSApiMsgGetRequest{
+		{Seq: 1, Batch: 3, Filter: odd},
+		{Seq: 1, Batch: 3, NextFor: "foo.*", Filter: odd},
+	} {
+		sub := sendRequest(mreq)
+		checkSubsPending(t, sub, 4)
+		for _, seq := range []string{"2", "4", "6"} {
+			msg, err := sub.NextMsg(10 * time.Millisecond)
+			require_NoError(t, err)
+			require_Equal(t, msg.Header.Get(JSSequence), seq)
+			require_Equal(t, msg.Header.Get("Kind"), "odd")
+		}
+		// EOB marker.
+		msg, err := sub.NextMsg(10 * time.Millisecond)
+		require_NoError(t, err)
+		require_Equal(t, msg.Header.Get("Status"), "204")
+		require_NoError(t, sub.Unsubscribe())
+	}
+
+	// Filters are not allowed with last by subject or a single sequence, and must be valid.
+	for _, mreq := range []*JSApiMsgGetRequest{
+		{LastFor: "foo.bar", Filter: odd},
+		{Seq: 1, Filter: odd},
+		{Seq: 1, Batch: 3, Filter: &MsgFilter{Header: "Kind"}},
+	} {
+		sub := sendRequest(mreq)
+		msg, err := sub.NextMsg(time.Second)
+		require_NoError(t, err)
+		require_Equal(t, msg.Header.Get("Status"), "408")
+		require_NoError(t, sub.Unsubscribe())
+	}
+
+	// Skipping is bounded, a batch then ends at the last skipped message.
+	defer func(old int) { directGetMaxFilterSkip = old }(directGetMaxFilterSkip)
+	directGetMaxFilterSkip = 2
+	none := &MsgFilter{Header: "Kind", Op: MsgFilterEq, Value: "none"}
+	sub := sendRequest(&JSApiMsgGetRequest{Seq: 1, Batch: 3, Filter: none})
+	msg, err := sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_Equal(t, msg.Header.Get("Status"), "204")
+	require_Equal(t, msg.Header.Get(JSLastSequence), "2")
+	require_NoError(t, sub.Unsubscribe())
+
+	sub = sendRequest(&JSApiMsgGetRequest{Seq: 1, NextFor: "foo.*", Filter: none})
+	msg, err = sub.NextMsg(time.Second)
+	require_NoError(t, err)
+	require_Equal(t, msg.Header.Get("Status"), "404")
+	require_NoError(t, sub.Unsubscribe())
+}
+
 func TestJetStreamDirectGetBatchMaxBytes(t *testing.T) {
 	s := RunBasicJetStreamServer(t)
 	defer s.Shutdown()
@@ -21122,3 +21209,196 @@ func TestJetStreamGetNoHeaders(t *testing.T) {
 		require_Equal(t, headers.Get("Nats-Time-Stamp"), _EMPTY_)
 	})
 }
+
+func TestJetStreamStreamZstdCompression(t *testing.T) {
+	s := RunBasicJetStreamServer(t)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	request := func(api string, cfg *StreamConfig) (*JSApiStreamCreateResponse, []byte) {
+		t.Helper()
+		req, err := json.Marshal(cfg)
+		require_NoError(t, err)
+		resp, err := nc.Request(fmt.Sprintf(api, cfg.Name), req, time.Second)
+		require_NoError(t, err)
+		var scResp JSApiStreamCreateResponse
+		require_NoError(t, json.Unmarshal(resp.Data, &scResp))
+		return &scResp, resp.Data
+	}
+
+	// Level requires zstd, and must be within range.
+	cfg := &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, Compression: S2Compression, CompressionLevel: 3}
+	scResp, _ := request(JSApiStreamCreateT, cfg)
+	checkNatsError(t, scResp.Error, JSStreamInvalidConfigF)
+	cfg.Compression, cfg.CompressionLevel = ZstdCompression, ZstdMaxCompressionLevel+1
+	scResp, _ = request(JSApiStreamCreateT, cfg)
+	checkNatsError(t, scResp.Error, JSStreamInvalidConfigF)
+
+	cfg.CompressionLevel = 9
+	scResp, data := request(JSApiStreamCreateT, cfg)
+	require_True(t, scResp.Error == nil)
+	require_Equal(t, scResp.Config.Compression, ZstdCompression)
+	require_Equal(t, scResp.Config.CompressionLevel, 9)
+	require_True(t, bytes.Contains(data, []byte(`"compression":"zstd"`)))
+	require_True(t, bytes.Contains(data, []byte(`"compression_level":9`)))
+
+	msg := bytes.Repeat([]byte("ABCD"), 1024)
+	for i := 0; i < 100; i++ {
+		_, err := js.Publish("foo", msg)
+		require_NoError(t, err)
+	}
+
+	// Switching the compression does not affect existing messages.
+	cfg.Compression, cfg.CompressionLevel = S2Compression, 0
+	scResp, _ = request(JSApiStreamUpdateT, cfg)
+	require_True(t, scResp.Error == nil)
+	require_Equal(t, scResp.Config.Compression, S2Compression)
+	for i := 0; i < 100; i++ {
+		_, err := js.Publish("foo", msg)
+		require_NoError(t, err)
+	}
+	for _, seq := range []uint64{1, 50, 150, 200} {
+		rm, err := js.GetMsg("TEST", seq)
+		require_NoError(t, err)
+		require_True(t, bytes.Equal(rm.Data, msg))
+	}
+}
+
+func TestJetStreamStreamTieredStorage(t *testing.T) {
+	// Tiering requires a backend to be configured.
+	s := RunBasicJetStreamServer(t)
+	nc, _ := jsClientConnect(t, s)
+	cfg := &StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Storage: FileStorage, MaxBytes: 100_000,
+		Tiering: &StreamTieringPolicy{OffloadAfter: time.Millisecond}}
+	req, err := json.Marshal(cfg)
+	require_NoError(t, err)
+	resp, err := nc.Request(fmt.Sprintf(JSApiStreamCreateT, cfg.Name), req, time.Second)
+	require_NoError(t, err)
+	var scResp JSApiStreamCreateResponse
+	require_NoError(t, json.Unmarshal(resp.Data, &scResp))
+	checkNatsError(t, scResp.Error, JSStreamInvalidConfigF)
+	nc.Close()
+	s.Shutdown()
+
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		http: 127.0.0.1:-1
+		jetstream: {store_dir: %q, tiered_store_dir: %q, sync_interval: 100ms}
+	`, t.TempDir(), t.TempDir())))
+	s, _ = RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	resp, err = nc.Request(fmt.Sprintf(JSApiStreamCreateT, cfg.Name), req, time.Second)
+	require_NoError(t, err)
+	scResp = JSApiStreamCreateResponse{}
+	require_NoError(t, json.Unmarshal(resp.Data, &scResp))
+	require_True(t, scResp.Error == nil)
+	require_Equal(t, scResp.Config.Tiering.OffloadAfter, time.Millisecond)
+
+	msg := bytes.Repeat([]byte("Z"), 1000)
+	for i := 0; i < 90; i++ {
+		_, err := js.Publish("foo", msg)
+		require_NoError(t, err)
+	}
+
+	var si StreamInfo
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		resp, err := nc.Request(fmt.Sprintf(JSApiStreamInfoT, cfg.Name), nil, time.Second)
+		if err != nil {
+			return err
+		}
+		var siResp JSApiStreamInfoResponse
+		if err := json.Unmarshal(resp.Data, &siResp); err != nil {
+			return err
+		}
+		if siResp.StreamInfo == nil || siResp.Tiering == nil || siResp.Tiering.OffloadedBlocks == 0 {
+			return fmt.Errorf("no blocks offloaded yet")
+		}
+		si = *siResp.StreamInfo
+		return nil
+	})
+	require_Equal(t, si.Tiering.OffloadedBytes+si.Tiering.LocalBytes, si.State.Bytes)
+
+	for _, seq := range []uint64{1, 45, 90} {
+		rm, err := js.GetMsg("TEST", seq)
+		require_NoError(t, err)
+		require_True(t, bytes.Equal(rm.Data, msg))
+	}
+
+	jsz, err := s.Jsz(&JSzOptions{Accounts: true, Streams: true})
+	require_NoError(t, err)
+	require_Equal(t, len(jsz.AccountDetails), 1)
+	require_Equal(t, len(jsz.AccountDetails[0].Streams), 1)
+	sd := jsz.AccountDetails[0].Streams[0]
+	require_True(t, sd.Tiering != nil)
+	require_True(t, sd.Tiering.OffloadedBlocks > 0)
+}
+
+func TestJetStreamStreamCompactionByKey(t *testing.T) {
+	conf := createConfFile(t, []byte(fmt.Sprintf(`
+		listen: 127.0.0.1:-1
+		http: 127.0.0.1:-1
+		jetstream: {store_dir: %q, sync_interval: 100ms}
+	`, t.TempDir())))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	// Invalid configs.
+	for _, cfg := range []*StreamConfig{
+		{Name: "BAD", Storage: MemoryStorage, Compaction: &StreamCompactionPolicy{HeadWindow: time.Hour}},
+		{Name: "BAD", Storage: FileStorage, Compaction: &StreamCompactionPolicy{HeadWindow: -time.Hour}},
+		{Name: "BAD", Storage: FileStorage, Compaction: &StreamCompactionPolicy{MinCleanableRatio: 1.5}},
+	} {
+		_, err := jsStreamCreate(t, nc, cfg)
+		require_Error(t, err, NewJSStreamInvalidConfigError(errors.New("")))
+	}
+
+	cfg, err := jsStreamCreate(t, nc, &StreamConfig{
+		Name:       "TEST",
+		Subjects:   []string{"kv.>"},
+		Storage:    FileStorage,
+		Compaction: &StreamCompactionPolicy{HeadWindow: 250 * time.Millisecond},
+	})
+	require_NoError(t, err)
+	require_Equal(t, cfg.Compaction.HeadWindow, 250*time.Millisecond)
+
+	for i := 0; i < 30; i++ {
+		_, err := js.Publish(fmt.Sprintf("kv.%d", i%3), []byte("ok"))
+		require_NoError(t, err)
+	}
+
+	var si *StreamInfo
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		resp, err := nc.Request(fmt.Sprintf(JSApiStreamInfoT, "TEST"), nil, time.Second)
+		if err != nil {
+			return err
+		}
+		var siResp JSApiStreamInfoResponse
+		if err := json.Unmarshal(resp.Data, &siResp); err != nil {
+			return err
+		}
+		if siResp.StreamInfo == nil || siResp.Compaction == nil || siResp.Compaction.Msgs != 27 {
+			return fmt.Errorf("messages not compacted yet")
+		}
+		si = siResp.StreamInfo
+		return nil
+	})
+	require_Equal(t, si.State.Msgs, 3)
+	require_Equal(t, si.State.LastSeq, 30)
+	require_True(t, si.Compaction.Runs > 0)
+	require_True(t, si.Compaction.Bytes > 0)
+
+	for i, seq := range []uint64{28, 29, 30} {
+		rm, err := js.GetLastMsg("TEST", fmt.Sprintf("kv.%d", i))
+		require_NoError(t, err)
+		require_Equal(t, rm.Sequence, seq)
+	}
+}
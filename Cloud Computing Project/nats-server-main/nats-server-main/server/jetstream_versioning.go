@@ -55,6 +55,46 @@ func setStaticStreamMetadata(cfg *StreamConfig) {
 		requires(2)
 	}
 
+	// Zstd compression was added in v2.12 and requires API level 2.
+	if cfg.Compression == ZstdCompression {
+		requires(2)
+	}
+
+	// Tiered storage was added in v2.12 and requires API level 2.
+	if cfg.Tiering != nil {
+		requires(2)
+	}
+
+	// Message schedules were added in v2.12 and require API level 2.
+	if cfg.AllowMsgSchedules {
+		requires(2)
+	}
+
+	// Schema validation was added in v2.12 and requires API level 2.
+	if cfg.Schema != nil {
+		requires(2)
+	}
+
+	// Partitioned streams were added in v2.12 and require API level 2.
+	if cfg.Partitioning != nil {
+		requires(2)
+	}
+
+	// Compaction by subject was added in v2.12 and requires API level 2.
+	if cfg.Compaction != nil {
+		requires(2)
+	}
+
+	// Learners were added in v2.12 and require API level 2.
+	if cfg.Placement != nil && cfg.Placement.Learners != nil {
+		requires(2)
+	}
+
+	// Mirror lag thresholds were added in v2.12 and require API level 2.
+	if cfg.Mirror != nil && cfg.Mirror.LagThreshold > 0 {
+		requires(2)
+	}
+
 	cfg.Metadata[JSRequiredLevelMetadataKey] = strconv.Itoa(requiredApiLevel)
 }
 
@@ -126,6 +166,36 @@ func setStaticConsumerMetadata(cfg *ConsumerConfig) {
 		requires(1)
 	}
 
+	// Prioritized and weighted priority policies were added in v2.12 and require API level 2.
+	if cfg.PriorityPolicy == PriorityPrioritized || cfg.PriorityPolicy == PriorityWeighted || len(cfg.PriorityWeights) > 0 {
+		requires(2)
+	}
+
+	// Dead letter targets were added in v2.12 and require API level 2.
+	if cfg.DeadLetter != nil {
+		requires(2)
+	}
+
+	// Message filters were added in v2.12 and require API level 2.
+	if cfg.MsgFilter != nil {
+		requires(2)
+	}
+
+	// Lag thresholds were added in v2.12 and require API level 2.
+	if cfg.LagThresholds != nil {
+		requires(2)
+	}
+
+	// Subject state watches were added in v2.12 and require API level 2.
+	if cfg.Watch {
+		requires(2)
+	}
+
+	// Consumers on learners were added in v2.12 and require API level 2.
+	if cfg.OnLearners {
+		requires(2)
+	}
+
 	cfg.Metadata[JSRequiredLevelMetadataKey] = strconv.Itoa(requiredApiLevel)
 }
 
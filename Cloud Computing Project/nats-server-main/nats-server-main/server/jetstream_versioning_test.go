@@ -82,6 +82,46 @@ func TestJetStreamSetStaticStreamMetadata(t *testing.T) {
 			cfg:              &StreamConfig{AllowAtomicPublish: true},
 			expectedMetadata: metadataAtLevel("2"),
 		},
+		{
+			desc:             "ZstdCompression",
+			cfg:              &StreamConfig{Compression: ZstdCompression},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Tiering",
+			cfg:              &StreamConfig{Tiering: &StreamTieringPolicy{OffloadAfter: time.Hour}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "AllowMsgSchedules",
+			cfg:              &StreamConfig{AllowMsgSchedules: true},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Schema",
+			cfg:              &StreamConfig{Schema: &StreamSchema{Type: JSONSchemaType, Definition: json.RawMessage(`{}`)}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Partitioning",
+			cfg:              &StreamConfig{Partitioning: &StreamPartitioning{Partitions: 3}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Compaction",
+			cfg:              &StreamConfig{Compaction: &StreamCompactionPolicy{HeadWindow: time.Hour}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Learners",
+			cfg:              &StreamConfig{Placement: &Placement{Learners: &LearnerPlacement{Replicas: 1}}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "MirrorLagThreshold",
+			cfg:              &StreamConfig{Mirror: &StreamSource{Name: "O", LagThreshold: 100}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
 			setStaticStreamMetadata(test.cfg)
@@ -224,6 +264,41 @@ func TestJetStreamSetStaticConsumerMetadata(t *testing.T) {
 			cfg:              &ConsumerConfig{PriorityPolicy: PriorityPinnedClient, PriorityGroups: []string{"a"}},
 			expectedMetadata: metadataAtLevel("1"),
 		},
+		{
+			desc:             "Prioritized",
+			cfg:              &ConsumerConfig{PriorityPolicy: PriorityPrioritized, PriorityGroups: []string{"a"}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Weighted",
+			cfg:              &ConsumerConfig{PriorityPolicy: PriorityWeighted, PriorityGroups: []string{"a", "b"}, PriorityWeights: map[string]int{"a": 3}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "DeadLetter",
+			cfg:              &ConsumerConfig{DeadLetter: &ConsumerDeadLetter{Subject: "dlq"}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "MsgFilter",
+			cfg:              &ConsumerConfig{MsgFilter: &MsgFilter{Header: "Region", Op: MsgFilterExists}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "LagThresholds",
+			cfg:              &ConsumerConfig{LagThresholds: &ConsumerLagThresholds{PendingMsgs: 100}},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "Watch",
+			cfg:              &ConsumerConfig{Watch: true},
+			expectedMetadata: metadataAtLevel("2"),
+		},
+		{
+			desc:             "OnLearners",
+			cfg:              &ConsumerConfig{OnLearners: true},
+			expectedMetadata: metadataAtLevel("2"),
+		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
 			setStaticConsumerMetadata(test.cfg)
@@ -112,40 +112,41 @@ const (
 
 // ConnInfo has detailed information on a per connection basis.
 type ConnInfo struct {
-	Cid            uint64         `json:"cid"`
-	Kind           string         `json:"kind,omitempty"`
-	Type           string         `json:"type,omitempty"`
-	IP             string         `json:"ip"`
-	Port           int            `json:"port"`
-	Start          time.Time      `json:"start"`
-	LastActivity   time.Time      `json:"last_activity"`
-	Stop           *time.Time     `json:"stop,omitempty"`
-	Reason         string         `json:"reason,omitempty"`
-	RTT            string         `json:"rtt,omitempty"`
-	Uptime         string         `json:"uptime"`
-	Idle           string         `json:"idle"`
-	Pending        int            `json:"pending_bytes"`
-	InMsgs         int64          `json:"in_msgs"`
-	OutMsgs        int64          `json:"out_msgs"`
-	InBytes        int64          `json:"in_bytes"`
-	OutBytes       int64          `json:"out_bytes"`
-	NumSubs        uint32         `json:"subscriptions"`
-	Name           string         `json:"name,omitempty"`
-	Lang           string         `json:"lang,omitempty"`
-	Version        string         `json:"version,omitempty"`
-	TLSVersion     string         `json:"tls_version,omitempty"`
-	TLSCipher      string         `json:"tls_cipher_suite,omitempty"`
-	TLSPeerCerts   []*TLSPeerCert `json:"tls_peer_certs,omitempty"`
-	TLSFirst       bool           `json:"tls_first,omitempty"`
-	AuthorizedUser string         `json:"authorized_user,omitempty"`
-	Account        string         `json:"account,omitempty"`
-	Subs           []string       `json:"subscriptions_list,omitempty"`
-	SubsDetail     []SubDetail    `json:"subscriptions_list_detail,omitempty"`
-	JWT            string         `json:"jwt,omitempty"`
-	IssuerKey      string         `json:"issuer_key,omitempty"`
-	NameTag        string         `json:"name_tag,omitempty"`
-	Tags           jwt.TagList    `json:"tags,omitempty"`
-	MQTTClient     string         `json:"mqtt_client,omitempty"` // This is the MQTT client id
+	Cid            uint64          `json:"cid"`
+	Kind           string          `json:"kind,omitempty"`
+	Type           string          `json:"type,omitempty"`
+	IP             string          `json:"ip"`
+	Port           int             `json:"port"`
+	Start          time.Time       `json:"start"`
+	LastActivity   time.Time       `json:"last_activity"`
+	Stop           *time.Time      `json:"stop,omitempty"`
+	Reason         string          `json:"reason,omitempty"`
+	RTT            string          `json:"rtt,omitempty"`
+	Uptime         string          `json:"uptime"`
+	Idle           string          `json:"idle"`
+	Pending        int             `json:"pending_bytes"`
+	InMsgs         int64           `json:"in_msgs"`
+	OutMsgs        int64           `json:"out_msgs"`
+	InBytes        int64           `json:"in_bytes"`
+	OutBytes       int64           `json:"out_bytes"`
+	NumSubs        uint32          `json:"subscriptions"`
+	Name           string          `json:"name,omitempty"`
+	Lang           string          `json:"lang,omitempty"`
+	Version        string          `json:"version,omitempty"`
+	TLSVersion     string          `json:"tls_version,omitempty"`
+	TLSCipher      string          `json:"tls_cipher_suite,omitempty"`
+	TLSPeerCerts   []*TLSPeerCert  `json:"tls_peer_certs,omitempty"`
+	TLSFirst       bool            `json:"tls_first,omitempty"`
+	AuthorizedUser string          `json:"authorized_user,omitempty"`
+	Account        string          `json:"account,omitempty"`
+	Subs           []string        `json:"subscriptions_list,omitempty"`
+	SubsDetail     []SubDetail     `json:"subscriptions_list_detail,omitempty"`
+	JWT            string          `json:"jwt,omitempty"`
+	IssuerKey      string          `json:"issuer_key,omitempty"`
+	NameTag        string          `json:"name_tag,omitempty"`
+	Tags           jwt.TagList     `json:"tags,omitempty"`
+	MQTTClient     string          `json:"mqtt_client,omitempty"` // This is the MQTT client id
+	RateLimit      *RateLimitStats `json:"rate_limit,omitempty"`
 
 	// Internal
 	rtt int64 // For fast sorting
@@ -571,6 +572,7 @@ func (ci *ConnInfo) fill(client *client, nc net.Conn, now time.Time, auth bool)
 	// we need to use atomic here.
 	ci.InMsgs = atomic.LoadInt64(&client.inMsgs)
 	ci.InBytes = atomic.LoadInt64(&client.inBytes)
+	ci.RateLimit = client.rl.stats()
 
 	// If the connection is gone, too bad, we won't set TLSVersion and TLSCipher.
 	// Exclude clients that are still doing handshake so we don't block in
@@ -1457,6 +1459,7 @@ func (s *Server) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	<a href=.%s>LeafNodes (%s)</a>
 	<a href=.%s>Gateways (%s)</a>
 	<a href=.%s>Raft Groups (%s)</a>
+	<a href=.%s>Metrics (%s)</a>
 	<a href=.%s class=last>Health Probe (%s)</a>
     <a href=https://docs.nats.io/running-a-nats-service/nats_admin/monitoring class="help">Help</a>
   </body>
@@ -1473,6 +1476,7 @@ func (s *Server) HandleRoot(w http.ResponseWriter, r *http.Request) {
 		s.basePath(LeafzPath), LeafzPath,
 		s.basePath(GatewayzPath), GatewayzPath,
 		s.basePath(RaftzPath), RaftzPath,
+		s.basePath(MetricszPath), MetricszPath,
 		s.basePath(HealthzPath), HealthzPath,
 	)
 }
@@ -2520,6 +2524,8 @@ func (reason ClosedState) String() string {
 		return "Cluster Names Identical"
 	case Kicked:
 		return "Kicked"
+	case RateLimitExceeded:
+		return "Rate Limit Exceeded"
 	}
 
 	return "Unknown State"
@@ -2855,16 +2861,18 @@ type RaftzOptions struct {
 
 // StreamDetail shows information about the stream state and its consumers.
 type StreamDetail struct {
-	Name               string              `json:"name"`
-	Created            time.Time           `json:"created"`
-	Cluster            *ClusterInfo        `json:"cluster,omitempty"`
-	Config             *StreamConfig       `json:"config,omitempty"`
-	State              StreamState         `json:"state,omitempty"`
-	Consumer           []*ConsumerInfo     `json:"consumer_detail,omitempty"`
-	Mirror             *StreamSourceInfo   `json:"mirror,omitempty"`
-	Sources            []*StreamSourceInfo `json:"sources,omitempty"`
-	RaftGroup          string              `json:"stream_raft_group,omitempty"`
-	ConsumerRaftGroups []*RaftGroupDetail  `json:"consumer_raft_groups,omitempty"`
+	Name               string                `json:"name"`
+	Created            time.Time             `json:"created"`
+	Cluster            *ClusterInfo          `json:"cluster,omitempty"`
+	Config             *StreamConfig         `json:"config,omitempty"`
+	State              StreamState           `json:"state,omitempty"`
+	Consumer           []*ConsumerInfo       `json:"consumer_detail,omitempty"`
+	Mirror             *StreamSourceInfo     `json:"mirror,omitempty"`
+	Sources            []*StreamSourceInfo   `json:"sources,omitempty"`
+	Tiering            *StreamTieringInfo    `json:"tiering,omitempty"`
+	Compaction         *StreamCompactionInfo `json:"compaction,omitempty"`
+	RaftGroup          string                `json:"stream_raft_group,omitempty"`
+	ConsumerRaftGroups []*RaftGroupDetail    `json:"consumer_raft_groups,omitempty"`
 }
 
 // RaftGroupDetail shows information details about the Raft group.
@@ -2958,13 +2966,15 @@ func (s *Server) accountDetail(jsa *jsAccount, optStreams, optConsumers, optCfg,
 				continue
 			}
 			sdet := StreamDetail{
-				Name:    stream.name(),
-				Created: stream.createdTime(),
-				State:   stream.state(),
-				Cluster: ci,
-				Config:  cfg,
-				Mirror:  stream.mirrorInfo(),
-				Sources: stream.sourcesInfo(),
+				Name:       stream.name(),
+				Created:    stream.createdTime(),
+				State:      stream.state(),
+				Cluster:    ci,
+				Config:     cfg,
+				Mirror:     stream.mirrorInfo(),
+				Sources:    stream.sourcesInfo(),
+				Tiering:    stream.tieringInfo(),
+				Compaction: stream.compactionInfo(),
 			}
 			if optRaft && rgroup != nil {
 				sdet.RaftGroup = rgroup.Name
@@ -3904,6 +3914,8 @@ type RaftzGroup struct {
 	Size          int                       `json:"size"`
 	QuorumNeeded  int                       `json:"quorum_needed"`
 	Observer      bool                      `json:"observer,omitempty"`
+	PreVote       bool                      `json:"pre_vote,omitempty"`
+	CheckQuorum   bool                      `json:"check_quorum,omitempty"`
 	Paused        bool                      `json:"paused,omitempty"`
 	Committed     uint64                    `json:"committed"`
 	Applied       uint64                    `json:"applied"`
@@ -3921,6 +3933,38 @@ type RaftzGroup struct {
 	WAL           StreamState               `json:"wal"`
 	WALError      error                     `json:"wal_error,omitempty"`
 	Peers         map[string]RaftzGroupPeer `json:"peers"`
+	// Elections avoided by pre-vote and leader step downs by check-quorum.
+	PreVotesLost         uint64 `json:"pre_votes_lost"`
+	PreVotesRejected     uint64 `json:"pre_votes_rejected"`
+	CheckQuorumStepDowns uint64 `json:"check_quorum_step_downs"`
+	// WAL growth and throughput of the group.
+	Metrics *RaftzGroupMetrics `json:"metrics,omitempty"`
+}
+
+// RaftzGroupMetrics contains the storage and throughput metrics of a Raft group.
+type RaftzGroupMetrics struct {
+	// Time since the last snapshot was installed, empty if there is none.
+	SnapshotAge string `json:"snapshot_age,omitempty"`
+	// Number of committed entries not yet applied.
+	ApplyLag uint64 `json:"apply_lag"`
+	// Entries proposed while leader, in total and per second.
+	Proposals       uint64  `json:"proposals"`
+	ProposalsPerSec float64 `json:"proposals_per_sec"`
+	// Latency of appending entries to the WAL.
+	AppendLatency *RaftzLatencyHistogram `json:"append_latency"`
+}
+
+// RaftzLatencyHistogram is a latency histogram with cumulative buckets.
+type RaftzLatencyHistogram struct {
+	Count   uint64               `json:"count"`
+	Sum     time.Duration        `json:"sum"`
+	Buckets []RaftzLatencyBucket `json:"buckets"`
+}
+
+// RaftzLatencyBucket holds the number of observations less than or equal to LE.
+type RaftzLatencyBucket struct {
+	LE    time.Duration `json:"le"`
+	Count uint64        `json:"count"`
 }
 
 type RaftzGroupPeer struct {
@@ -4009,6 +4053,8 @@ func (s *Server) Raftz(opts *RaftzOptions) *RaftzStatus {
 			Size:          n.csz,
 			QuorumNeeded:  n.qn,
 			Observer:      n.observer,
+			PreVote:       n.prevote,
+			CheckQuorum:   n.checkq,
 			Paused:        n.paused,
 			Committed:     n.commit,
 			Applied:       n.applied,
@@ -4025,8 +4071,24 @@ func (s *Server) Raftz(opts *RaftzOptions) *RaftzStatus {
 			IPQApplyLen:   n.apply.len(),
 			WALError:      n.werr,
 			Peers:         map[string]RaftzGroupPeer{},
+
+			PreVotesLost:         n.pvlost,
+			PreVotesRejected:     n.pvreject,
+			CheckQuorumStepDowns: n.cqsd,
 		}
 		n.wal.FastState(&info.WAL)
+		now := time.Now()
+		info.Metrics = &RaftzGroupMetrics{
+			Proposals:       n.metrics.props,
+			ProposalsPerSec: n.metrics.proposalRate(now),
+			AppendLatency:   n.metrics.appendLatency(),
+		}
+		if n.commit > n.applied {
+			info.Metrics.ApplyLag = n.commit - n.applied
+		}
+		if !n.lsnap.IsZero() {
+			info.Metrics.SnapshotAge = now.Sub(n.lsnap).String()
+		}
 		for id, p := range n.peers {
 			if id == n.id {
 				continue
@@ -0,0 +1,506 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Content type for the OpenMetrics text exposition format.
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Types of the OpenMetrics metric families we expose.
+const (
+	metricTypeGauge   = "gauge"
+	metricTypeCounter = "counter"
+	metricTypeInfo    = "info"
+)
+
+// MetricszOptions are options passed to Metricsz.
+type MetricszOptions struct {
+	// Account restricts the account and JetStream series to a single account.
+	Account string `json:"account,omitempty"`
+	// Streams includes per-stream series.
+	Streams bool `json:"streams,omitempty"`
+	// Consumers includes per-consumer series, implies Streams.
+	Consumers bool `json:"consumers,omitempty"`
+}
+
+// metricSample is a single sample of a metric family. Labels are
+// stored as ordered name/value pairs.
+type metricSample struct {
+	labels []string
+	value  float64
+}
+
+// metricFamily groups all samples of a given metric, since OpenMetrics
+// requires those to be exposed contiguously.
+type metricFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples []metricSample
+}
+
+// metricsWriter collects metric families and renders them in the
+// OpenMetrics text format. Families are rendered in insertion order.
+type metricsWriter struct {
+	base []string
+	fams []*metricFamily
+	idx  map[string]*metricFamily
+}
+
+func newMetricsWriter(base ...string) *metricsWriter {
+	return &metricsWriter{base: base, idx: make(map[string]*metricFamily)}
+}
+
+// add records a sample for the given family, creating the family on
+// first use. Labels are name/value pairs appended to the base labels.
+func (mw *metricsWriter) add(name, typ, help string, value float64, labels ...string) {
+	mf := mw.idx[name]
+	if mf == nil {
+		mf = &metricFamily{name: name, typ: typ, help: help}
+		mw.idx[name] = mf
+		mw.fams = append(mw.fams, mf)
+	}
+	lbls := make([]string, 0, len(mw.base)+len(labels))
+	lbls = append(lbls, mw.base...)
+	lbls = append(lbls, labels...)
+	mf.samples = append(mf.samples, metricSample{labels: lbls, value: value})
+}
+
+func (mw *metricsWriter) gauge(name, help string, value float64, labels ...string) {
+	mw.add(name, metricTypeGauge, help, value, labels...)
+}
+
+func (mw *metricsWriter) counter(name, help string, value float64, labels ...string) {
+	mw.add(name, metricTypeCounter, help, value, labels...)
+}
+
+func (mw *metricsWriter) info(name, help string, labels ...string) {
+	mw.add(name, metricTypeInfo, help, 1, labels...)
+}
+
+// bytes renders all collected families.
+func (mw *metricsWriter) bytes() []byte {
+	var b bytes.Buffer
+	for _, mf := range mw.fams {
+		b.WriteString("# TYPE ")
+		b.WriteString(mf.name)
+		b.WriteByte(' ')
+		b.WriteString(mf.typ)
+		b.WriteString("\n# HELP ")
+		b.WriteString(mf.name)
+		b.WriteByte(' ')
+		b.WriteString(escapeMetricHelp(mf.help))
+		b.WriteByte('\n')
+		var suffix string
+		switch mf.typ {
+		case metricTypeCounter:
+			suffix = "_total"
+		case metricTypeInfo:
+			suffix = "_info"
+		}
+		for _, ms := range mf.samples {
+			b.WriteString(mf.name)
+			b.WriteString(suffix)
+			if len(ms.labels) > 0 {
+				b.WriteByte('{')
+				for i := 0; i+1 < len(ms.labels); i += 2 {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(ms.labels[i])
+					b.WriteString(`="`)
+					b.WriteString(escapeMetricLabel(ms.labels[i+1]))
+					b.WriteByte('"')
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatMetricValue(ms.value))
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteString("# EOF\n")
+	return b.Bytes()
+}
+
+var (
+	metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	metricHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeMetricLabel(v string) string {
+	return metricLabelEscaper.Replace(v)
+}
+
+func escapeMetricHelp(v string) string {
+	return metricHelpEscaper.Replace(v)
+}
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func metricBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Metricsz returns the server metrics rendered in the OpenMetrics text format.
+// The data is gathered from Varz, Routez, Leafz, Gatewayz, AccountStatz, Jsz and Raftz.
+func (s *Server) Metricsz(opts *MetricszOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &MetricszOptions{}
+	}
+	if opts.Consumers {
+		opts.Streams = true
+	}
+
+	base := []string{"server", s.Name()}
+	if cn := s.ClusterName(); cn != _EMPTY_ {
+		base = append(base, "cluster", cn)
+	}
+	mw := newMetricsWriter(base...)
+
+	if err := s.varzMetrics(mw); err != nil {
+		return nil, err
+	}
+	if err := s.routezMetrics(mw); err != nil {
+		return nil, err
+	}
+	if err := s.leafzMetrics(mw); err != nil {
+		return nil, err
+	}
+	if err := s.gatewayzMetrics(mw); err != nil {
+		return nil, err
+	}
+	if err := s.accountStatzMetrics(mw, opts); err != nil {
+		return nil, err
+	}
+	if err := s.jszMetrics(mw, opts); err != nil {
+		return nil, err
+	}
+	s.raftzMetrics(mw, opts)
+
+	return mw.bytes(), nil
+}
+
+func (s *Server) varzMetrics(mw *metricsWriter) error {
+	v, err := s.Varz(nil)
+	if err != nil {
+		return err
+	}
+	mw.info("nats_server", "Information about the server.",
+		"server_id", v.ID, "version", v.Version, "go", v.GoVersion, "git_commit", v.GitCommit)
+	mw.gauge("nats_server_start_time_seconds", "Time the server was started, in seconds since the epoch.",
+		float64(v.Start.UnixNano())/1e9)
+	mw.gauge("nats_server_cpu_percent", "Current CPU usage of the server process.", v.CPU)
+	mw.gauge("nats_server_memory_bytes", "Resident memory used by the server process.", float64(v.Mem))
+	mw.gauge("nats_server_cores", "Number of logical CPU cores.", float64(v.Cores))
+	mw.gauge("nats_server_gomaxprocs", "Value of GOMAXPROCS.", float64(v.MaxProcs))
+	mw.gauge("nats_server_connections", "Current number of client connections.", float64(v.Connections))
+	mw.counter("nats_server_accepted_connections", "Number of client connections accepted since start.", float64(v.TotalConnections))
+	mw.gauge("nats_server_max_connections", "Configured maximum number of client connections.", float64(v.MaxConn))
+	mw.gauge("nats_server_routes", "Current number of routes.", float64(v.Routes))
+	mw.gauge("nats_server_remotes", "Current number of remote servers.", float64(v.Remotes))
+	mw.gauge("nats_server_leafnodes", "Current number of leafnode connections.", float64(v.Leafs))
+	mw.gauge("nats_server_subscriptions", "Current number of subscriptions.", float64(v.Subscriptions))
+	mw.counter("nats_server_received_messages", "Number of messages received.", float64(v.InMsgs))
+	mw.counter("nats_server_sent_messages", "Number of messages sent.", float64(v.OutMsgs))
+	mw.counter("nats_server_received_bytes", "Number of bytes received.", float64(v.InBytes))
+	mw.counter("nats_server_sent_bytes", "Number of bytes sent.", float64(v.OutBytes))
+	mw.counter("nats_server_slow_consumers", "Number of slow consumers detected.", float64(v.SlowConsumers))
+	if scs := v.SlowConsumersStats; scs != nil {
+		for _, kv := range []struct {
+			kind string
+			val  uint64
+		}{
+			{"client", scs.Clients},
+			{"route", scs.Routes},
+			{"gateway", scs.Gateways},
+			{"leaf", scs.Leafs},
+		} {
+			mw.counter("nats_server_slow_consumers_by_kind", "Number of slow consumers detected by connection kind.",
+				float64(kv.val), "kind", kv.kind)
+		}
+	}
+	return nil
+}
+
+func (s *Server) routezMetrics(mw *metricsWriter) error {
+	rz, err := s.Routez(nil)
+	if err != nil {
+		return err
+	}
+	for _, r := range rz.Routes {
+		lbls := []string{"route_id", strconv.FormatUint(r.Rid, 10), "remote_name", r.RemoteName, "remote_id", r.RemoteID}
+		mw.gauge("nats_route_pending_bytes", "Bytes pending to be sent on the route.", float64(r.Pending), lbls...)
+		mw.gauge("nats_route_subscriptions", "Number of subscriptions on the route.", float64(r.NumSubs), lbls...)
+		mw.counter("nats_route_received_messages", "Number of messages received on the route.", float64(r.InMsgs), lbls...)
+		mw.counter("nats_route_sent_messages", "Number of messages sent on the route.", float64(r.OutMsgs), lbls...)
+		mw.counter("nats_route_received_bytes", "Number of bytes received on the route.", float64(r.InBytes), lbls...)
+		mw.counter("nats_route_sent_bytes", "Number of bytes sent on the route.", float64(r.OutBytes), lbls...)
+	}
+	return nil
+}
+
+func (s *Server) leafzMetrics(mw *metricsWriter) error {
+	lz, err := s.Leafz(nil)
+	if err != nil {
+		return err
+	}
+	for _, l := range lz.Leafs {
+		lbls := []string{"leafnode_id", strconv.FormatUint(l.ID, 10), "remote_name", l.Name, "account", l.Account}
+		mw.gauge("nats_leafnode_subscriptions", "Number of subscriptions on the leafnode connection.", float64(l.NumSubs), lbls...)
+		mw.counter("nats_leafnode_received_messages", "Number of messages received on the leafnode connection.", float64(l.InMsgs), lbls...)
+		mw.counter("nats_leafnode_sent_messages", "Number of messages sent on the leafnode connection.", float64(l.OutMsgs), lbls...)
+		mw.counter("nats_leafnode_received_bytes", "Number of bytes received on the leafnode connection.", float64(l.InBytes), lbls...)
+		mw.counter("nats_leafnode_sent_bytes", "Number of bytes sent on the leafnode connection.", float64(l.OutBytes), lbls...)
+	}
+	return nil
+}
+
+func (s *Server) gatewayzMetrics(mw *metricsWriter) error {
+	gz, err := s.Gatewayz(nil)
+	if err != nil {
+		return err
+	}
+	emit := func(direction, name string, rgw *RemoteGatewayz) {
+		ci := rgw.Connection
+		if ci == nil {
+			return
+		}
+		lbls := []string{"gateway", name, "direction", direction, "cid", strconv.FormatUint(ci.Cid, 10)}
+		mw.counter("nats_gateway_received_messages", "Number of messages received on the gateway connection.", float64(ci.InMsgs), lbls...)
+		mw.counter("nats_gateway_sent_messages", "Number of messages sent on the gateway connection.", float64(ci.OutMsgs), lbls...)
+		mw.counter("nats_gateway_received_bytes", "Number of bytes received on the gateway connection.", float64(ci.InBytes), lbls...)
+		mw.counter("nats_gateway_sent_bytes", "Number of bytes sent on the gateway connection.", float64(ci.OutBytes), lbls...)
+		mw.gauge("nats_gateway_pending_bytes", "Bytes pending to be sent on the gateway connection.", float64(ci.Pending), lbls...)
+	}
+	// Sort names so that the output is stable between scrapes.
+	for _, name := range sortedKeys(gz.OutboundGateways) {
+		emit("outbound", name, gz.OutboundGateways[name])
+	}
+	for _, name := range sortedKeys(gz.InboundGateways) {
+		for _, rgw := range gz.InboundGateways[name] {
+			emit("inbound", name, rgw)
+		}
+	}
+	return nil
+}
+
+func (s *Server) accountStatzMetrics(mw *metricsWriter, opts *MetricszOptions) error {
+	ao := &AccountStatzOptions{IncludeUnused: true}
+	if opts.Account != _EMPTY_ {
+		ao.Accounts = []string{opts.Account}
+	}
+	az, err := s.AccountStatz(ao)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(az.Accounts, func(i, j *AccountStat) int { return strings.Compare(i.Account, j.Account) })
+	for _, a := range az.Accounts {
+		lbls := []string{"account", a.Account}
+		if a.Name != _EMPTY_ && a.Name != a.Account {
+			lbls = append(lbls, "account_name", a.Name)
+		}
+		mw.gauge("nats_account_connections", "Current number of client connections for the account.", float64(a.Conns), lbls...)
+		mw.gauge("nats_account_leafnodes", "Current number of leafnode connections for the account.", float64(a.LeafNodes), lbls...)
+		mw.gauge("nats_account_total_connections", "Current number of connections, including leafnodes, for the account.", float64(a.TotalConns), lbls...)
+		mw.gauge("nats_account_subscriptions", "Current number of subscriptions for the account.", float64(a.NumSubs), lbls...)
+		mw.counter("nats_account_received_messages", "Number of messages received by the account.", float64(a.Received.Msgs), lbls...)
+		mw.counter("nats_account_sent_messages", "Number of messages sent by the account.", float64(a.Sent.Msgs), lbls...)
+		mw.counter("nats_account_received_bytes", "Number of bytes received by the account.", float64(a.Received.Bytes), lbls...)
+		mw.counter("nats_account_sent_bytes", "Number of bytes sent by the account.", float64(a.Sent.Bytes), lbls...)
+		mw.counter("nats_account_slow_consumers", "Number of slow consumers detected for the account.", float64(a.SlowConsumers), lbls...)
+	}
+	return nil
+}
+
+func (s *Server) jszMetrics(mw *metricsWriter, opts *MetricszOptions) error {
+	jsi, err := s.Jsz(&JSzOptions{
+		Account:  opts.Account,
+		Accounts: true,
+		Streams:  opts.Streams,
+		Consumer: opts.Consumers,
+		// No pagination, we want all accounts.
+		Limit: -1,
+	})
+	if err != nil {
+		return err
+	}
+	mw.gauge("nats_jetstream_enabled", "Whether JetStream is enabled.", metricBool(!jsi.Disabled))
+	if jsi.Disabled {
+		return nil
+	}
+	mw.gauge("nats_jetstream_memory_bytes", "Memory storage used by JetStream.", float64(jsi.Memory))
+	mw.gauge("nats_jetstream_storage_bytes", "File storage used by JetStream.", float64(jsi.Store))
+	mw.gauge("nats_jetstream_reserved_memory_bytes", "Memory storage reserved by JetStream.", float64(jsi.ReservedMemory))
+	mw.gauge("nats_jetstream_reserved_storage_bytes", "File storage reserved by JetStream.", float64(jsi.ReservedStore))
+	mw.gauge("nats_jetstream_max_memory_bytes", "Configured maximum JetStream memory storage.", float64(jsi.Config.MaxMemory))
+	mw.gauge("nats_jetstream_max_storage_bytes", "Configured maximum JetStream file storage.", float64(jsi.Config.MaxStore))
+	mw.gauge("nats_jetstream_accounts", "Number of JetStream enabled accounts.", float64(jsi.Total))
+	mw.gauge("nats_jetstream_ha_assets", "Number of replicated JetStream assets.", float64(jsi.HAAssets))
+	mw.gauge("nats_jetstream_streams", "Number of streams.", float64(jsi.Streams))
+	mw.gauge("nats_jetstream_consumers", "Number of consumers.", float64(jsi.Consumers))
+	mw.gauge("nats_jetstream_messages", "Number of messages stored in all streams.", float64(jsi.Messages))
+	mw.gauge("nats_jetstream_bytes", "Number of bytes stored in all streams.", float64(jsi.Bytes))
+	mw.counter("nats_jetstream_api_requests", "Number of JetStream API requests.", float64(jsi.API.Total))
+	mw.counter("nats_jetstream_api_errors", "Number of JetStream API requests that resulted in an error.", float64(jsi.API.Errors))
+	if jsi.Meta != nil {
+		mw.gauge("nats_jetstream_meta_cluster_size", "Size of the JetStream meta group.", float64(jsi.Meta.Size))
+		mw.gauge("nats_jetstream_meta_pending", "Number of pending routed JetStream API requests.", float64(jsi.Meta.Pending))
+		mw.gauge("nats_jetstream_meta_leader", "Whether this server is the JetStream meta leader.", metricBool(jsi.Meta.Leader == s.Name()))
+	}
+
+	slices.SortFunc(jsi.AccountDetails, func(i, j *AccountDetail) int { return strings.Compare(i.Id, j.Id) })
+	for _, ad := range jsi.AccountDetails {
+		// Jsz falls back to all accounts if the requested one is not JetStream enabled.
+		if opts.Account != _EMPTY_ && ad.Id != opts.Account {
+			continue
+		}
+		albls := []string{"account", ad.Id}
+		mw.gauge("nats_jetstream_account_memory_bytes", "Memory storage used by the account.", float64(ad.Memory), albls...)
+		mw.gauge("nats_jetstream_account_storage_bytes", "File storage used by the account.", float64(ad.Store), albls...)
+		mw.gauge("nats_jetstream_account_reserved_memory_bytes", "Memory storage reserved by the account.", float64(ad.ReservedMemory), albls...)
+		mw.gauge("nats_jetstream_account_reserved_storage_bytes", "File storage reserved by the account.", float64(ad.ReservedStore), albls...)
+		mw.counter("nats_jetstream_account_api_requests", "Number of JetStream API requests for the account.", float64(ad.API.Total), albls...)
+		mw.counter("nats_jetstream_account_api_errors", "Number of JetStream API errors for the account.", float64(ad.API.Errors), albls...)
+
+		slices.SortFunc(ad.Streams, func(i, j StreamDetail) int { return strings.Compare(i.Name, j.Name) })
+		for _, sd := range ad.Streams {
+			slbls := []string{"account", ad.Id, "stream", sd.Name}
+			mw.gauge("nats_jetstream_stream_messages", "Number of messages stored in the stream.", float64(sd.State.Msgs), slbls...)
+			mw.gauge("nats_jetstream_stream_bytes", "Number of bytes stored in the stream.", float64(sd.State.Bytes), slbls...)
+			mw.gauge("nats_jetstream_stream_first_sequence", "First sequence in the stream.", float64(sd.State.FirstSeq), slbls...)
+			mw.gauge("nats_jetstream_stream_last_sequence", "Last sequence in the stream.", float64(sd.State.LastSeq), slbls...)
+			mw.gauge("nats_jetstream_stream_deleted_messages", "Number of interior deletes in the stream.", float64(sd.State.NumDeleted), slbls...)
+			mw.gauge("nats_jetstream_stream_consumers", "Number of consumers on the stream.", float64(sd.State.Consumers), slbls...)
+			if sd.Cluster != nil {
+				mw.gauge("nats_jetstream_stream_leader", "Whether this server is the stream leader.", metricBool(sd.Cluster.Leader == s.Name()), slbls...)
+			}
+
+			slices.SortFunc(sd.Consumer, func(i, j *ConsumerInfo) int { return strings.Compare(i.Name, j.Name) })
+			for _, ci := range sd.Consumer {
+				clbls := []string{"account", ad.Id, "stream", sd.Name, "consumer", ci.Name}
+				mw.gauge("nats_jetstream_consumer_pending_messages", "Number of messages pending delivery to the consumer.", float64(ci.NumPending), clbls...)
+				mw.gauge("nats_jetstream_consumer_ack_pending_messages", "Number of messages delivered but not yet acknowledged.", float64(ci.NumAckPending), clbls...)
+				mw.gauge("nats_jetstream_consumer_redelivered_messages", "Number of messages redelivered and not yet acknowledged.", float64(ci.NumRedelivered), clbls...)
+				mw.gauge("nats_jetstream_consumer_waiting_requests", "Number of waiting pull requests.", float64(ci.NumWaiting), clbls...)
+				mw.gauge("nats_jetstream_consumer_delivered_consumer_sequence", "Last delivered consumer sequence.", float64(ci.Delivered.Consumer), clbls...)
+				mw.gauge("nats_jetstream_consumer_delivered_stream_sequence", "Last delivered stream sequence.", float64(ci.Delivered.Stream), clbls...)
+				mw.gauge("nats_jetstream_consumer_ack_floor_consumer_sequence", "Acknowledgement floor consumer sequence.", float64(ci.AckFloor.Consumer), clbls...)
+				mw.gauge("nats_jetstream_consumer_ack_floor_stream_sequence", "Acknowledgement floor stream sequence.", float64(ci.AckFloor.Stream), clbls...)
+				if ci.Cluster != nil {
+					mw.gauge("nats_jetstream_consumer_leader", "Whether this server is the consumer leader.", metricBool(ci.Cluster.Leader == s.Name()), clbls...)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) raftzMetrics(mw *metricsWriter, opts *MetricszOptions) {
+	// Raftz is filtered per account, so collect the accounts that own Raft groups.
+	accs := make(map[string]struct{})
+	s.rnMu.RLock()
+	for _, rg := range s.raftNodes {
+		if n, ok := rg.(*raft); ok && n != nil {
+			accs[n.accName] = struct{}{}
+		}
+	}
+	s.rnMu.RUnlock()
+
+	for _, acc := range sortedKeys(accs) {
+		if opts.Account != _EMPTY_ && acc != opts.Account {
+			continue
+		}
+		rz := s.Raftz(&RaftzOptions{AccountFilter: acc})
+		if rz == nil {
+			continue
+		}
+		groups := (*rz)[acc]
+		for _, name := range sortedKeys(groups) {
+			g := groups[name]
+			lbls := []string{"account", acc, "group", name}
+			mw.gauge("nats_raft_group_leader", "Whether this server is the leader of the Raft group.", metricBool(g.State == Leader.String()), lbls...)
+			mw.gauge("nats_raft_group_term", "Current term of the Raft group.", float64(g.Term), lbls...)
+			mw.gauge("nats_raft_group_size", "Cluster size of the Raft group.", float64(g.Size), lbls...)
+			mw.gauge("nats_raft_group_committed_index", "Committed index of the Raft group.", float64(g.Committed), lbls...)
+			mw.gauge("nats_raft_group_applied_index", "Applied index of the Raft group.", float64(g.Applied), lbls...)
+			mw.gauge("nats_raft_group_catching_up", "Whether the Raft group is catching up.", metricBool(g.CatchingUp), lbls...)
+			mw.gauge("nats_raft_group_wal_messages", "Number of entries in the Raft group WAL.", float64(g.WAL.Msgs), lbls...)
+			mw.gauge("nats_raft_group_wal_bytes", "Number of bytes in the Raft group WAL.", float64(g.WAL.Bytes), lbls...)
+			mw.gauge("nats_raft_group_proposal_queue", "Length of the proposal queue of the Raft group.", float64(g.IPQPropLen), lbls...)
+			mw.gauge("nats_raft_group_apply_queue", "Length of the apply queue of the Raft group.", float64(g.IPQApplyLen), lbls...)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a string keyed map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// HandleMetricsz process HTTP requests for metrics in the OpenMetrics text format.
+func (s *Server) HandleMetricsz(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.httpReqStats[MetricszPath]++
+	s.mu.Unlock()
+
+	streams, err := decodeBool(w, r, "streams")
+	if err != nil {
+		return
+	}
+	consumers, err := decodeBool(w, r, "consumers")
+	if err != nil {
+		return
+	}
+
+	b, err := s.Metricsz(&MetricszOptions{
+		Account:   r.URL.Query().Get("acc"),
+		Streams:   streams,
+		Consumers: consumers,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
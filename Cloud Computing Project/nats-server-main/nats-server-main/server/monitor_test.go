@@ -6334,3 +6334,124 @@ func TestMonitorVarzMetadata(t *testing.T) {
 		t.Fatalf("expected: %v, got: %v", expected, v.Metadata)
 	}
 }
+
+func TestMonitorMetricsz(t *testing.T) {
+	resetPreviousHTTPConnections()
+	opts := DefaultMonitorOptions()
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+
+	s := RunServer(opts)
+	defer s.Shutdown()
+
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}})
+	require_NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = js.Publish("foo", []byte("hello"))
+		require_NoError(t, err)
+	}
+	_, err = js.AddConsumer("TEST", &nats.ConsumerConfig{Durable: "DUR", AckPolicy: nats.AckExplicitPolicy})
+	require_NoError(t, err)
+
+	base := fmt.Sprintf("http://127.0.0.1:%d%s", s.MonitorAddr().Port, MetricszPath)
+	serverLbl := fmt.Sprintf(`server="%s"`, s.Name())
+
+	// No per-stream or per-consumer series by default.
+	body := string(readBodyEx(t, base, http.StatusOK, openMetricsContentType))
+	require_True(t, strings.HasSuffix(body, "# EOF\n"))
+	for _, line := range []string{
+		"# TYPE nats_server_connections gauge",
+		"# TYPE nats_server_received_messages counter",
+		fmt.Sprintf("nats_server_connections{%s} 1", serverLbl),
+		fmt.Sprintf("nats_jetstream_streams{%s} 1", serverLbl),
+		fmt.Sprintf("nats_jetstream_consumers{%s} 1", serverLbl),
+		fmt.Sprintf("nats_jetstream_messages{%s} 5", serverLbl),
+		fmt.Sprintf(`nats_jetstream_account_api_requests_total{%s,account="%s"}`, serverLbl, DEFAULT_GLOBAL_ACCOUNT),
+		fmt.Sprintf(`nats_account_connections{%s,account="%s"} 1`, serverLbl, DEFAULT_GLOBAL_ACCOUNT),
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("Expected %q in metrics output:\n%s", line, body)
+		}
+	}
+	require_False(t, strings.Contains(body, "nats_jetstream_stream_messages"))
+	require_False(t, strings.Contains(body, "nats_jetstream_consumer_pending_messages"))
+
+	// Include stream series only.
+	body = string(readBodyEx(t, base+"?streams=true", http.StatusOK, openMetricsContentType))
+	sLbls := fmt.Sprintf(`%s,account="%s",stream="TEST"`, serverLbl, DEFAULT_GLOBAL_ACCOUNT)
+	require_Contains(t, body, fmt.Sprintf("nats_jetstream_stream_messages{%s} 5", sLbls))
+	require_Contains(t, body, fmt.Sprintf("nats_jetstream_stream_last_sequence{%s} 5", sLbls))
+	require_False(t, strings.Contains(body, "nats_jetstream_consumer_pending_messages"))
+
+	// Consumers imply streams.
+	body = string(readBodyEx(t, base+"?consumers=true", http.StatusOK, openMetricsContentType))
+	cLbls := fmt.Sprintf(`%s,consumer="DUR"`, sLbls)
+	require_Contains(t, body, fmt.Sprintf("nats_jetstream_stream_messages{%s} 5", sLbls))
+	require_Contains(t, body, fmt.Sprintf("nats_jetstream_consumer_pending_messages{%s} 5", cLbls))
+	require_Contains(t, body, fmt.Sprintf("nats_jetstream_consumer_ack_pending_messages{%s} 0", cLbls))
+
+	// Filter by an account without any JetStream usage.
+	body = string(readBodyEx(t, base+"?streams=true&acc="+DEFAULT_SYSTEM_ACCOUNT, http.StatusOK, openMetricsContentType))
+	require_False(t, strings.Contains(body, "nats_jetstream_stream_messages"))
+	require_Contains(t, body, fmt.Sprintf(`nats_account_connections{%s,account="%s"}`, serverLbl, DEFAULT_SYSTEM_ACCOUNT))
+	require_False(t, strings.Contains(body, fmt.Sprintf(`account="%s"`, DEFAULT_GLOBAL_ACCOUNT)))
+
+	// Bad query parameter.
+	readBodyEx(t, base+"?streams=foo", http.StatusBadRequest, textPlain)
+
+	// Every sample belongs to the family announced right before it.
+	var family string
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			family = strings.Fields(line)[2]
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, family) {
+			t.Fatalf("Sample %q not grouped under family %q", line, family)
+		}
+	}
+}
+
+func TestMonitorMetricszRaftGroups(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	nc, js := jsClientConnect(t, c.randomServer())
+	defer nc.Close()
+
+	_, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"foo"}, Replicas: 3})
+	require_NoError(t, err)
+	c.waitOnStreamLeader(globalAccountName, "TEST")
+
+	sl := c.streamLeader(globalAccountName, "TEST")
+	mset, err := sl.globalAccount().lookupStream("TEST")
+	require_NoError(t, err)
+	b, err := sl.Metricsz(&MetricszOptions{Streams: true})
+	require_NoError(t, err)
+	body := string(b)
+
+	lbls := fmt.Sprintf(`server="%s",cluster="R3S"`, sl.Name())
+	require_Contains(t, body, fmt.Sprintf(`nats_jetstream_stream_leader{%s,account="%s",stream="TEST"} 1`, lbls, globalAccountName))
+	require_Contains(t, body, fmt.Sprintf(`nats_raft_group_size{%s,account="%s",group="%s"} 3`, lbls, globalAccountName, mset.raftGroup().Name))
+	require_Contains(t, body, fmt.Sprintf(`nats_raft_group_leader{%s,account="%s",group="%s"}`, lbls, DEFAULT_SYSTEM_ACCOUNT, defaultMetaGroupName))
+}
+
+func TestMonitorMetricszEscaping(t *testing.T) {
+	mw := newMetricsWriter("server", `a"b\c`)
+	mw.counter("nats_test", "Help with \\ and\nnewline.", 2, "stream", "x\ny")
+	mw.info("nats_test_build", "Build information.", "version", "1.0")
+	require_Equal(t, string(mw.bytes()),
+		"# TYPE nats_test counter\n"+
+			"# HELP nats_test Help with \\\\ and\\nnewline.\n"+
+			`nats_test_total{server="a\"b\\c",stream="x\ny"} 2`+"\n"+
+			"# TYPE nats_test_build info\n"+
+			"# HELP nats_test_build Build information.\n"+
+			`nats_test_build_info{server="a\"b\\c",version="1.0"} 1`+"\n"+
+			"# EOF\n")
+}
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"cmp"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
@@ -35,6 +36,7 @@ import (
 )
 
 // References to "spec" here is from https://docs.oasis-open.org/mqtt/mqtt/v3.1.1/os/mqtt-v3.1.1-os.pdf
+// References to "spec v5" are from https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.pdf
 
 const (
 	mqttPacketConnect    = byte(0x10)
@@ -54,7 +56,8 @@ const (
 	mqttPacketMask       = byte(0xf0)
 	mqttPacketFlagMask   = byte(0x0f)
 
-	mqttProtoLevel = byte(0x4)
+	mqttProtoLevel  = byte(0x4)
+	mqttProtoLevel5 = byte(0x5)
 
 	// Connect flags
 	mqttConnFlagReserved     = byte(0x1)
@@ -88,6 +91,66 @@ const (
 	mqttConnAckRCNotAuthorized               = byte(0x5)
 	mqttConnAckRCQoS2WillRejected            = byte(0x10)
 
+	// MQTT v5 reason codes, used in CONNACK, SUBACK, UNSUBACK, etc..
+	mqttReasonSuccess                 = byte(0x00)
+	mqttReasonDisconnectWithWill      = byte(0x04)
+	mqttReasonUnspecifiedError        = byte(0x80)
+	mqttReasonUnsupportedProtoVersion = byte(0x84)
+	mqttReasonClientIDNotValid        = byte(0x85)
+	mqttReasonBadUserOrPassword       = byte(0x86)
+	mqttReasonNotAuthorized           = byte(0x87)
+	mqttReasonServerUnavailable       = byte(0x88)
+	mqttReasonBadAuthMethod           = byte(0x8C)
+	mqttReasonQoSNotSupported         = byte(0x9B)
+
+	// MQTT v5 property identifiers, Spec v5 [2.2.2.2]
+	mqttPropPayloadFormat        = byte(0x01)
+	mqttPropMessageExpiry        = byte(0x02)
+	mqttPropContentType          = byte(0x03)
+	mqttPropResponseTopic        = byte(0x08)
+	mqttPropCorrelationData      = byte(0x09)
+	mqttPropSubscriptionID       = byte(0x0B)
+	mqttPropSessionExpiry        = byte(0x11)
+	mqttPropAssignedClientID     = byte(0x12)
+	mqttPropServerKeepAlive      = byte(0x13)
+	mqttPropAuthMethod           = byte(0x15)
+	mqttPropAuthData             = byte(0x16)
+	mqttPropRequestProblemInfo   = byte(0x17)
+	mqttPropWillDelay            = byte(0x18)
+	mqttPropRequestResponseInfo  = byte(0x19)
+	mqttPropResponseInfo         = byte(0x1A)
+	mqttPropServerReference      = byte(0x1C)
+	mqttPropReasonString         = byte(0x1F)
+	mqttPropReceiveMaximum       = byte(0x21)
+	mqttPropTopicAliasMaximum    = byte(0x22)
+	mqttPropTopicAlias           = byte(0x23)
+	mqttPropMaximumQoS           = byte(0x24)
+	mqttPropRetainAvailable      = byte(0x25)
+	mqttPropUserProperty         = byte(0x26)
+	mqttPropMaximumPacketSize    = byte(0x27)
+	mqttPropWildcardSubAvailable = byte(0x28)
+	mqttPropSubIDAvailable       = byte(0x29)
+	mqttPropSharedSubAvailable   = byte(0x2A)
+
+	// MQTT v5 subscription options, Spec v5 [3.8.3.1]
+	mqttSubOptQoS            = byte(0x03)
+	mqttSubOptNoLocal        = byte(0x04)
+	mqttSubOptRetainAsPub    = byte(0x08)
+	mqttSubOptRetainHandling = byte(0x30)
+	mqttSubOptReserved       = byte(0xC0)
+
+	// Number of topic aliases that a MQTT v5 client is allowed to use when
+	// publishing to this server.
+	mqttTopicAliasMax = 0xFF
+
+	// Session expiry interval (in seconds) that indicates that a MQTT v5
+	// session never expires.
+	mqttSessExpiryNever = 0xFFFFFFFF
+
+	// Prefix of MQTT v5 shared subscriptions topic filters, followed by the
+	// share name and the actual topic filter.
+	mqttSharedSubPrefix = "$share/"
+
 	// Maximum payload size of a control packet
 	mqttMaxPayloadSize = 0xFFFFFFF
 
@@ -133,6 +196,16 @@ const (
 	mqttPubRelDeliverySubjectPrefix = "$MQTT.deliver.pubrel."
 	mqttPubRelConsumerDurablePrefix = "$MQTT_PUBREL_"
 
+	// Durable name prefix and delivery subject token for JS consumers that
+	// are shared by the members of a MQTT v5 shared subscription.
+	mqttSharedConsumerDurablePrefix = "$MQTT_SHARED_"
+	mqttSharedDeliverySubjectToken  = "shared."
+
+	// Shared JS consumers can't be deleted when one member unsubscribes, so
+	// they are removed by the server after this inactivity period, unless
+	// the server.Options.MQTT.ConsumerInactiveThreshold option is set.
+	mqttDefaultSharedConsumerInactiveThreshold = 5 * time.Minute
+
 	// As per spec, MQTT server may not redeliver QoS 1 and 2 messages to
 	// clients, except after client reconnects. However, NATS Server will
 	// redeliver unacknowledged messages after this default interval. This can
@@ -234,6 +307,8 @@ var (
 	errMQTTPacketIdentifierIsZero     = errors.New("packet identifier cannot be 0")
 	errMQTTUnsupportedCharacters      = errors.New("character ' ' not supported for MQTT topics")
 	errMQTTInvalidSession             = errors.New("invalid MQTT session")
+	errMQTTMalformedProperties        = errors.New("malformed properties")
+	errMQTTSubIDsNotSupported         = errors.New("subscription identifiers not supported")
 )
 
 type srvMQTT struct {
@@ -338,6 +413,12 @@ type mqttSession struct {
 	tmaxack  int
 	clean    bool
 	domainTk string
+
+	// MQTT v5 session expiry interval (in seconds) and the timer that removes
+	// the session once its client has been gone for that long, since disc.
+	expiry   uint32
+	expTimer *time.Timer
+	disc     time.Time
 }
 
 type mqttPersistedSession struct {
@@ -347,6 +428,10 @@ type mqttPersistedSession struct {
 	Subs   map[string]byte            `json:"subs,omitempty"`
 	Cons   map[string]*ConsumerConfig `json:"cons,omitempty"`
 	PubRel *ConsumerConfig            `json:"pubrel,omitempty"`
+	// MQTT v5 session expiry interval (in seconds) and when the client went
+	// away (in Unix nanoseconds), so that any server can expire the session.
+	Expiry uint32 `json:"expiry,omitempty"`
+	Disc   int64  `json:"disc,omitempty"`
 }
 
 type mqttRetainedMsg struct {
@@ -357,6 +442,12 @@ type mqttRetainedMsg struct {
 	Flags   byte   `json:"flags,omitempty"`
 	Source  string `json:"source,omitempty"`
 
+	// NATS header (or header lines) carrying the MQTT v5 properties of the
+	// message, and the time the message was stored, which is needed for its
+	// message expiry interval.
+	hdr    []byte
+	stored time.Time
+
 	expiresFromCache time.Time
 }
 
@@ -396,6 +487,16 @@ type mqtt struct {
 	sess *mqttSession               // quick reference to session, immutable after processConnect()
 	cid  string                     // client ID
 
+	// v5 is set when the client connected with the MQTT 5.0 protocol level,
+	// and is immutable after the CONNECT packet has been parsed.
+	v5 bool
+	// cidAssigned is set when the client ID was generated by the server,
+	// which needs to be reported to MQTT v5 clients in the CONNACK.
+	cidAssigned bool
+	// Topic aliases set by the MQTT v5 client in PUBLISH packets. Only
+	// accessed from the readLoop.
+	aliases map[uint16][]byte
+
 	// rejectQoS2Pub tells the MQTT client to not accept QoS2 PUBLISH, instead
 	// error and terminate the connection.
 	rejectQoS2Pub bool
@@ -415,6 +516,7 @@ type mqttConnectProto struct {
 	rd    time.Duration
 	will  *mqttWill
 	flags byte
+	props *mqttProperties // MQTT v5 only
 }
 
 type mqttIOReader interface {
@@ -441,11 +543,17 @@ type mqttWill struct {
 	message []byte
 	qos     byte
 	retain  bool
+	hdr     []byte // MQTT v5 only, see mqttPublish
+	reply   []byte // MQTT v5 only, see mqttPublish
 }
 
 type mqttFilter struct {
 	filter string
 	qos    byte
+	// MQTT v5 only: the share name of a shared subscription, which is used
+	// as the NATS queue group, and the retain handling subscription option.
+	queue string
+	rh    byte
 	// Used only for tracing and should not be used after parsing of (un)sub protocols.
 	ttopic []byte
 }
@@ -458,6 +566,39 @@ type mqttPublish struct {
 	sz      int
 	pi      uint16
 	flags   byte
+	// MQTT v5 only: the PUBLISH properties converted to NATS header lines,
+	// and the NATS subject converted from the response topic property.
+	hdr   []byte
+	reply []byte
+}
+
+// MQTT v5 properties. Only the properties that this server acts on are kept,
+// the others are parsed and ignored.
+type mqttProperties struct {
+	payloadFormat   byte
+	msgExpiry       uint32
+	contentType     string
+	responseTopic   []byte
+	correlationData []byte
+	subIDs          bool
+	sessExpiry      uint32
+	hasSessExpiry   bool
+	assignedCID     string
+	authMethod      string
+	receiveMax      uint16
+	topicAliasMax   uint16
+	topicAlias      uint16
+	maxQoS          byte
+	hasMaxQoS       bool
+	subIDAvailable  byte
+	hasSubIDAvail   bool
+	reasonString    string
+	userProps       []mqttUserProperty
+}
+
+type mqttUserProperty struct {
+	key   string
+	value string
 }
 
 // When we re-encode incoming MQTT PUBLISH messages for NATS delivery, we add
@@ -467,6 +608,9 @@ type mqttPublish struct {
 //   - "Nmqtt-Subject" contains the original MQTT subject from mqttParsePub.
 //   - "Nmqtt-Mapped" contains the mapping during mqttParsePub.
 //
+// For MQTT v5 publishers, the PUBLISH properties are also added as headers,
+// see mqttPropertiesToNATSHeader. User properties are added as is.
+//
 // When we submit a PUBREL for delivery, we add a "Nmqtt-PubRel" header that
 // contains the PI.
 const (
@@ -488,6 +632,22 @@ const (
 	// NATS headers to store the original MQTT subject and the subject mapping.
 	mqttNatsHeaderSubject = "Nmqtt-Subject"
 	mqttNatsHeaderMapped  = "Nmqtt-Mapped"
+
+	// NATS headers to store the MQTT v5 PUBLISH properties. The correlation
+	// data is base64 encoded, the reply is the NATS subject of the response
+	// topic and the expiry is the message expiry interval in seconds.
+	mqttNatsHeaderContentType = "Nmqtt-Content-Type"
+	mqttNatsHeaderFormat      = "Nmqtt-Format"
+	mqttNatsHeaderCorrelation = "Nmqtt-Correlation"
+	mqttNatsHeaderReply       = "Nmqtt-Reply"
+	mqttNatsHeaderExpiry      = "Nmqtt-Expiry"
+
+	// Prefix of the headers that are reserved for the MQTT implementation,
+	// and so not converted from/to MQTT v5 user properties.
+	mqttNatsHeaderPrefix = "Nmqtt-"
+	// Headers with this prefix drive JetStream, and so they are not converted
+	// from/to MQTT v5 user properties either.
+	mqttNatsReservedHeaderPrefix = "Nats-"
 )
 
 type mqttParsedPublishNATSHeader struct {
@@ -798,7 +958,7 @@ func (c *client) mqttParse(buf []byte) error {
 		// PUBREC, PUBCOMP.
 		case mqttPacketPubAck:
 			var pi uint16
-			pi, err = mqttParsePIPacket(r)
+			pi, _, err = c.mqttParsePIPacketWithReasonCode(r, pl)
 			if trace {
 				c.traceInOp("PUBACK", errOrTrace(err, fmt.Sprintf("pi=%v", pi)))
 			}
@@ -808,17 +968,24 @@ func (c *client) mqttParse(buf []byte) error {
 
 		case mqttPacketPubRec:
 			var pi uint16
-			pi, err = mqttParsePIPacket(r)
+			var rc byte
+			pi, rc, err = c.mqttParsePIPacketWithReasonCode(r, pl)
 			if trace {
-				c.traceInOp("PUBREC", errOrTrace(err, fmt.Sprintf("pi=%v", pi)))
+				c.traceInOp("PUBREC", errOrTrace(err, fmt.Sprintf("pi=%v rc=%v", pi, rc)))
 			}
 			if err == nil {
-				err = c.mqttProcessPubRec(pi)
+				// A MQTT v5 receiver may reject the message, in which case
+				// the exchange is over, as for a PUBACK. Spec v5 [4.3.3]
+				if rc >= mqttReasonUnspecifiedError {
+					err = c.mqttProcessPubAck(pi)
+				} else {
+					err = c.mqttProcessPubRec(pi)
+				}
 			}
 
 		case mqttPacketPubComp:
 			var pi uint16
-			pi, err = mqttParsePIPacket(r)
+			pi, _, err = c.mqttParsePIPacketWithReasonCode(r, pl)
 			if trace {
 				c.traceInOp("PUBCOMP", errOrTrace(err, fmt.Sprintf("pi=%v", pi)))
 			}
@@ -843,7 +1010,7 @@ func (c *client) mqttParse(buf []byte) error {
 
 		case mqttPacketPubRel:
 			var pi uint16
-			pi, err = mqttParsePIPacket(r)
+			pi, _, err = c.mqttParsePIPacketWithReasonCode(r, pl)
 			if trace {
 				c.traceInOp("PUBREL", errOrTrace(err, fmt.Sprintf("pi=%v", pi)))
 			}
@@ -884,7 +1051,7 @@ func (c *client) mqttParse(buf []byte) error {
 				}
 			}
 			if err == nil {
-				c.mqttEnqueueUnsubAck(pi)
+				c.mqttEnqueueUnsubAck(pi, filters)
 			}
 
 		// Packets that we get both as a receiver and sender: PING, CONNECT, DISCONNECT
@@ -932,16 +1099,30 @@ func (c *client) mqttParse(buf []byte) error {
 			}
 
 		case mqttPacketDisconnect:
+			var rc byte
+			var props *mqttProperties
+			var dtrace []byte
+			if c.mqtt.v5 {
+				rc, props, err = mqttParseDisconnect(r, pl)
+				dtrace = errOrTrace(err, fmt.Sprintf("rc=%v", rc))
+			}
 			if trace {
-				c.traceInOp("DISCONNECT", nil)
+				c.traceInOp("DISCONNECT", dtrace)
 			}
-			// Normal disconnect, we need to discard the will.
-			// Spec [MQTT-3.1.2-8]
+			if err != nil {
+				break
+			}
+			// Normal disconnect, we need to discard the will, unless a MQTT
+			// v5 client asks for it to be sent.
+			// Spec [MQTT-3.1.2-8], Spec v5 [3.14.2.1]
 			c.mu.Lock()
-			if c.mqtt.cp != nil {
+			if c.mqtt.cp != nil && rc != mqttReasonDisconnectWithWill {
 				c.mqtt.cp.will = nil
 			}
 			c.mu.Unlock()
+			if props != nil && props.hasSessExpiry {
+				c.mqttUpdateSessionExpiry(props.sessExpiry)
+			}
 			s.mqttHandleClosedClient(c)
 			c.closeConnection(ClientClosed)
 			return nil
@@ -994,6 +1175,14 @@ func (s *Server) mqttHandleClosedClient(c *client) {
 	sess.mu.Lock()
 	sess.c = nil
 	doClean := sess.clean
+	// A MQTT v5 session is removed once its expiry interval has elapsed.
+	doExpire := !doClean && sess.expiry != mqttSessExpiryNever && sess.expiry > 0
+	if doExpire {
+		sess.disc = time.Now()
+		sess.expTimer = time.AfterFunc(time.Duration(sess.expiry)*time.Second, func() {
+			asm.expireSession(s, sess)
+		})
+	}
 	sess.mu.Unlock()
 	// If it was a clean session, then we remove from the account manager,
 	// and we will call clear() outside of any lock.
@@ -1009,12 +1198,140 @@ func (s *Server) mqttHandleClosedClient(c *client) {
 		if err := sess.clear(true); err != nil {
 			c.Errorf(err.Error())
 		}
+	} else if doExpire {
+		// Persist when the client went away, so that the session still expires
+		// if this server restarts or another server loads the session.
+		if err := sess.save(); err != nil {
+			c.Warnf("Unable to persist disconnect time of session: %v", err)
+		}
 	}
 
 	// Now handle the "will". This function will be a no-op if there is no "will" to send.
 	s.mqttHandleWill(c)
 }
 
+// Removes a MQTT v5 session whose expiry interval has elapsed since its client
+// went away, unless a client is bound to the session again.
+//
+// Runs from the session's expiration timer.
+// No lock held on entry.
+func (as *mqttAccountSessionManager) expireSession(log *Server, sess *mqttSession) {
+	select {
+	case <-as.jsa.quitCh:
+		return
+	default:
+	}
+	// This fails if a client is bound, or being bound, to the session.
+	if err := as.lockSession(sess, nil); err != nil {
+		return
+	}
+	defer as.unlockSession(sess)
+
+	as.mu.Lock()
+	// Check that the session was not taken over by a remote server.
+	if as.sessions[sess.id] != sess {
+		as.mu.Unlock()
+		return
+	}
+	as.removeSession(sess, false)
+	as.mu.Unlock()
+	if err := sess.clear(true); err != nil {
+		log.Errorf("Unable to remove expired MQTT session %q: %v", sess.id, err)
+	}
+}
+
+// Arms the expiration timers of the persisted MQTT v5 sessions whose client
+// was gone when they were last saved, or that were bound to a client of this
+// server before it restarted. Without it, such sessions would only expire if
+// their client came back.
+//
+// Runs from its own go routine once the account's session manager is created.
+// No lock held on entry.
+func (as *mqttAccountSessionManager) armPersistedSessionExpiries(log *Server, opts *Options) {
+	jsa := &as.jsa
+	filter := mqttSessStreamSubjectPrefix + as.domainTk + ">"
+	start := time.Now()
+	for seq := uint64(1); ; {
+		select {
+		case <-jsa.quitCh:
+			return
+		default:
+		}
+		smsg, err := jsa.loadNextMsgForFromSeq(mqttSessStreamName, filter, seq)
+		if err != nil {
+			if isErrorOtherThan(err, JSNoMessageFoundErr) {
+				log.Warnf("Unable to load MQTT session records to expire: %v", err)
+			}
+			return
+		}
+		seq = smsg.Sequence + 1
+		ps := &mqttPersistedSession{}
+		if err := json.Unmarshal(smsg.Data, ps); err != nil || ps.Expiry == 0 || ps.Expiry == mqttSessExpiryNever {
+			continue
+		}
+		disc := time.Unix(0, ps.Disc)
+		if ps.Disc == 0 {
+			// A client of another server may still be bound to this session.
+			if ps.Origin != jsa.id {
+				continue
+			}
+			disc = start
+		}
+
+		as.mu.Lock()
+		// Sessions that are already known are bound or expire on their own.
+		if _, ok := as.sessions[ps.ID]; ok {
+			as.mu.Unlock()
+			continue
+		}
+		sess := mqttSessionCreate(jsa, ps.ID, getHash(ps.ID), smsg.Sequence, opts)
+		sess.domainTk = as.domainTk
+		sess.subs, sess.cons, sess.pubRelConsumer = ps.Subs, ps.Cons, ps.PubRel
+		sess.expiry, sess.disc = ps.Expiry, disc
+		sess.expTimer = time.AfterFunc(time.Until(disc.Add(time.Duration(ps.Expiry)*time.Second)), func() {
+			as.expireSession(log, sess)
+		})
+		as.addSession(sess, false)
+		as.mu.Unlock()
+	}
+}
+
+// Parses the reason code and properties of a MQTT v5 DISCONNECT packet, both
+// of which are optional. Spec v5 [3.14.2]
+func mqttParseDisconnect(r *mqttReader, pl int) (byte, *mqttProperties, error) {
+	var rc byte
+	var props *mqttProperties
+	var err error
+	if pl > 0 {
+		if rc, err = r.readByte("reason code"); err != nil {
+			return 0, nil, err
+		}
+	}
+	if pl > 1 {
+		if props, err = r.readProperties("disconnect"); err != nil {
+			return 0, nil, err
+		}
+	}
+	return rc, props, nil
+}
+
+// Updates the session expiry interval from a MQTT v5 DISCONNECT packet. It
+// can't be changed if it was 0 in the CONNECT packet. Spec v5 [MQTT-3.14.2-2]
+//
+// Runs from the client's readLoop.
+// No lock held on entry.
+func (c *client) mqttUpdateSessionExpiry(expiry uint32) {
+	sess := c.mqtt.sess
+	if sess == nil {
+		return
+	}
+	sess.mu.Lock()
+	if sess.expiry != 0 {
+		sess.expiry, sess.clean = expiry, expiry == 0
+	}
+	sess.mu.Unlock()
+}
+
 // Updates the MaxAckPending for all MQTT sessions, updating the
 // JetStream consumers and updating their max ack pending and forcing
 // a expiration of pending messages.
@@ -1072,6 +1389,7 @@ func (s *Server) mqttStoreQoSMsgForAccountOnNewSubject(hdr int, msg []byte, acc,
 	if jsa == nil {
 		return
 	}
+	hdr, msg = mqttAddMsgTTLHeader(hdr, msg)
 	jsa.storeMsg(mqttStreamSubjectPrefix+subject, hdr, msg)
 }
 
@@ -1331,17 +1649,25 @@ func (s *Server) mqttCreateAccountSessionManager(acc *Account, quitCh chan struc
 	if si, err := lookupStream(mqttStreamName, "messages"); err != nil {
 		return nil, err
 	} else if si == nil {
-		// Create the stream for the messages.
+		// Create the stream for the messages. Per-message TTLs are used for
+		// the MQTT v5 message expiry interval.
 		cfg := &StreamConfig{
-			Name:      mqttStreamName,
-			Subjects:  []string{mqttStreamSubjectPrefix + ">"},
-			Storage:   FileStorage,
-			Retention: InterestPolicy,
-			Replicas:  replicas,
+			Name:        mqttStreamName,
+			Subjects:    []string{mqttStreamSubjectPrefix + ">"},
+			Storage:     FileStorage,
+			Retention:   InterestPolicy,
+			Replicas:    replicas,
+			AllowMsgTTL: true,
 		}
 		if _, _, err := jsa.createStream(cfg); isErrorOtherThan(err, JSStreamNameExistErr) {
 			return nil, fmt.Errorf("create messages stream for account %q: %v", accName, err)
 		}
+	} else if !si.Config.AllowMsgTTL {
+		// Stream created before MQTT v5 support.
+		si.Config.AllowMsgTTL = true
+		if _, err := jsa.updateStream(&si.Config); err != nil {
+			return nil, fmt.Errorf("update messages stream for account %q: %v", accName, err)
+		}
 	}
 
 	if si, err := lookupStream(mqttQoS2IncomingMsgsStreamName, "QoS2 incoming messages"); err != nil {
@@ -1394,12 +1720,13 @@ func (s *Server) mqttCreateAccountSessionManager(acc *Account, quitCh chan struc
 	case si == nil:
 		// Create the stream for retained messages.
 		cfg := &StreamConfig{
-			Name:       mqttRetainedMsgsStreamName,
-			Subjects:   []string{mqttRetainedMsgsStreamSubject + ">"},
-			Storage:    FileStorage,
-			Retention:  LimitsPolicy,
-			Replicas:   replicas,
-			MaxMsgsPer: 1,
+			Name:        mqttRetainedMsgsStreamName,
+			Subjects:    []string{mqttRetainedMsgsStreamSubject + ">"},
+			Storage:     FileStorage,
+			Retention:   LimitsPolicy,
+			Replicas:    replicas,
+			MaxMsgsPer:  1,
+			AllowMsgTTL: true,
 		}
 		// We will need "si" outside of this block.
 		si, _, err = jsa.createStream(cfg)
@@ -1423,9 +1750,11 @@ func (s *Server) mqttCreateAccountSessionManager(acc *Account, quitCh chan struc
 	// Doing this check outside of above if/else due to possible race when
 	// creating the stream.
 	wantedSubj := mqttRetainedMsgsStreamSubject + ">"
-	if len(si.Config.Subjects) != 1 || si.Config.Subjects[0] != wantedSubj {
-		// Update only the Subjects at this stage, not MaxMsgsPer yet.
+	if len(si.Config.Subjects) != 1 || si.Config.Subjects[0] != wantedSubj || !si.Config.AllowMsgTTL {
+		// Update only the Subjects (and per-message TTLs for the MQTT v5
+		// message expiry interval) at this stage, not MaxMsgsPer yet.
 		si.Config.Subjects = []string{wantedSubj}
+		si.Config.AllowMsgTTL = true
 		if si, err = jsa.updateStream(&si.Config); err != nil {
 			return nil, fmt.Errorf("failed to update stream config: %w", err)
 		}
@@ -1523,6 +1852,12 @@ func (s *Server) mqttCreateAccountSessionManager(acc *Account, quitCh chan struc
 	// Set this so that on defer we don't cleanup.
 	success = true
 
+	// Expire the persisted MQTT v5 sessions that no client is bound to.
+	s.startGoRoutine(func() {
+		defer s.grWG.Done()
+		as.armPersistedSessionExpiries(s, opts)
+	})
+
 	return as, nil
 }
 
@@ -1826,6 +2161,20 @@ func (jsa *mqttJSA) loadNextMsgFor(streamName string, subject string) (*StoredMs
 	return lmr.Message, lmr.ToError()
 }
 
+func (jsa *mqttJSA) loadNextMsgForFromSeq(streamName string, subject string, seq uint64) (*StoredMsg, error) {
+	mreq := &JSApiMsgGetRequest{Seq: seq, NextFor: subject}
+	req, err := json.Marshal(mreq)
+	if err != nil {
+		return nil, err
+	}
+	lmri, err := jsa.newRequest(mqttJSAMsgLoad, fmt.Sprintf(JSApiMsgGetT, streamName), 0, req)
+	if err != nil {
+		return nil, err
+	}
+	lmr := lmri.(*JSApiMsgGetResponse)
+	return lmr.Message, lmr.ToError()
+}
+
 func (jsa *mqttJSA) loadMsg(streamName string, seq uint64) (*StoredMsg, error) {
 	mreq := &JSApiMsgGetRequest{Seq: seq}
 	req, err := json.Marshal(mreq)
@@ -2006,7 +2355,8 @@ func (as *mqttAccountSessionManager) processRetainedMsg(_ *subscription, c *clie
 	as.mu.RUnlock()
 
 	// At this point we either recover from our own server, or process a remote retained message.
-	seq, _, _ := ackReplyInfo(reply)
+	seq, _, _, ts, _ := replyInfo(reply)
+	rm.stored = time.Unix(0, ts)
 
 	// Handle this retained message, no need to copy the bytes.
 	as.handleRetainedMsg(rm.Subject, &mqttRetainedMsgRef{sseq: seq}, rm, false)
@@ -2090,6 +2440,12 @@ func (as *mqttAccountSessionManager) processSessionPersist(_ *subscription, pc *
 	}
 	as.removeSession(sess, false)
 	sess.mu.Lock()
+	// The session is now owned by a remote server, it is not up to this
+	// server to expire it.
+	if sess.expTimer != nil {
+		sess.expTimer.Stop()
+		sess.expTimer = nil
+	}
 	if ec := sess.c; ec != nil {
 		as.addSessToFlappers(sess.id)
 		ec.Warnf("Closing because a remote connection has started with the same client ID: %q", sess.id)
@@ -2426,14 +2782,14 @@ func (as *mqttAccountSessionManager) removeSession(sess *mqttSession, lock bool)
 // waiting.
 func (sess *mqttSession) processQOS12Sub(
 	c *client, // subscribing client.
-	subject, sid []byte, isReserved bool, qos byte, jsDurName string, h msgHandler, // subscription parameters.
+	subject, queue, sid []byte, isReserved bool, qos byte, jsDurName string, h msgHandler, // subscription parameters.
 ) (*subscription, error) {
-	return sess.processSub(c, subject, sid, isReserved, qos, jsDurName, h, false, nil, false, nil)
+	return sess.processSub(c, subject, queue, sid, isReserved, qos, jsDurName, h, false, nil, false, nil)
 }
 
 func (sess *mqttSession) processSub(
 	c *client, // subscribing client.
-	subject, sid []byte, isReserved bool, qos byte, jsDurName string, h msgHandler, // subscription parameters.
+	subject, queue, sid []byte, isReserved bool, qos byte, jsDurName string, h msgHandler, // subscription parameters.
 	initShadow bool, // do we need to scan for shadow subscriptions? (not for QOS1+)
 	rms map[string]*mqttRetainedMsg, // preloaded rms (can be empty, or missing items if errors)
 	trace bool, // trace serialized retained messages in the log?
@@ -2452,7 +2808,7 @@ func (sess *mqttSession) processSub(
 	sess.subsMu.Lock()
 	defer sess.subsMu.Unlock()
 
-	sub, err := c.processSub(subject, nil, sid, h, false)
+	sub, err := c.processSub(subject, queue, sid, h, false)
 	if err != nil {
 		// c.processSub already called c.Errorf(), so no need here.
 		return nil, err
@@ -2507,18 +2863,33 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 
 	// Helper to determine if we need to create a separate top-level
 	// subscription for a wildcard.
-	fwc := func(subject string) (bool, string, string) {
+	fwc := func(subject, sid string) (bool, string, string) {
 		if !mqttNeedSubForLevelUp(subject) {
 			return false, _EMPTY_, _EMPTY_
 		}
 		// Say subject is "foo.>", remove the ".>" so that it becomes "foo"
 		fwcsubject := subject[:len(subject)-2]
-		// Change the sid to "foo fwc"
-		fwcsid := fwcsubject + mqttMultiLevelSidSuffix
+		// Change the sid to "foo fwc" (the sid ends with the subject)
+		fwcsid := sid[:len(sid)-2] + mqttMultiLevelSidSuffix
 
 		return true, fwcsubject, fwcsid
 	}
 
+	// Retained messages are not sent to shared subscriptions, and for MQTT
+	// v5 clients, depend on the retain handling option. Spec v5 [3.8.3.1]
+	// and [4.8.2]. This needs to be evaluated before the session is updated.
+	sendRMS := make(map[*mqttFilter]bool, len(filters))
+	for _, f := range filters {
+		switch {
+		case f.queue != _EMPTY_, f.rh == 2:
+		case f.rh == 1:
+			_, exists := sess.subs[f.sid()]
+			sendRMS[f] = !exists
+		default:
+			sendRMS[f] = true
+		}
+	}
+
 	rmSubjects := map[string]struct{}{}
 	// Preload retained messages for all requested subscriptions.  Also, since
 	// it's the first iteration over the filter list, do some cleanup.
@@ -2550,7 +2921,7 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 		}
 
 		// Find retained messages.
-		if fromSubProto {
+		if fromSubProto && sendRMS[f] {
 			addRMSubjects := func(subject string) error {
 				sub := &subscription{
 					client:  c,
@@ -2574,7 +2945,7 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 				f.qos = mqttSubAckFailure
 				continue
 			}
-			if need, subject, _ := fwc(f.filter); need {
+			if need, subject, _ := fwc(f.filter, f.filter); need {
 				if err := addRMSubjects(subject); err != nil {
 					f.qos = mqttSubAckFailure
 					continue
@@ -2611,9 +2982,17 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 		}
 		subject := f.filter
 		bsubject := []byte(subject)
-		sid := subject
-		bsid := bsubject
+		sid := f.sid()
+		bsid := []byte(sid)
+		var bqueue []byte
+		if f.queue != _EMPTY_ {
+			bqueue = []byte(f.queue)
+		}
 		isReserved := isMQTTReservedSubscription(subject)
+		frms := rms
+		if !sendRMS[f] {
+			frms = nil
+		}
 
 		var jscons *ConsumerConfig
 		var jssub *subscription
@@ -2628,10 +3007,10 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 		as.mu.Lock()
 		sess.mu.Lock()
 		sub, err = sess.processSub(c,
-			bsubject, bsid, isReserved, f.qos, // main subject
+			bsubject, bqueue, bsid, isReserved, f.qos, // main subject
 			_EMPTY_, mqttDeliverMsgCbQoS0, // no jsDur for QOS0
 			processShadowSubs,
-			rms, trace, as)
+			frms, trace, as)
 		sess.mu.Unlock()
 		as.mu.Unlock()
 
@@ -2645,7 +3024,7 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 		// subscriptions of QoS >= 1. But if a JS consumer already exists and
 		// the subscription for same subject is now a QoS==0, then the JS
 		// consumer will be deleted.
-		jscons, jssub, err = sess.processJSConsumer(c, subject, sid, f.qos, fromSubProto)
+		jscons, jssub, err = sess.processJSConsumer(c, subject, f.queue, sid, f.qos, fromSubProto)
 		if err != nil {
 			f.qos = mqttSubAckFailure
 			sess.cleanupFailedSub(c, sub, jscons, jssub)
@@ -2653,7 +3032,7 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 		}
 
 		// Process the wildcard subject if needed.
-		if need, fwcsubject, fwcsid := fwc(subject); need {
+		if need, fwcsubject, fwcsid := fwc(subject, sid); need {
 			var fwjscons *ConsumerConfig
 			var fwjssub *subscription
 			var fwcsub *subscription
@@ -2662,10 +3041,10 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 			as.mu.Lock()
 			sess.mu.Lock()
 			fwcsub, err = sess.processSub(c,
-				[]byte(fwcsubject), []byte(fwcsid), isReserved, f.qos, // FWC (top-level wildcard) subject
+				[]byte(fwcsubject), bqueue, []byte(fwcsid), isReserved, f.qos, // FWC (top-level wildcard) subject
 				_EMPTY_, mqttDeliverMsgCbQoS0, // no jsDur for QOS0
 				processShadowSubs,
-				rms, trace, as)
+				frms, trace, as)
 			sess.mu.Unlock()
 			as.mu.Unlock()
 			if err != nil {
@@ -2675,7 +3054,7 @@ func (as *mqttAccountSessionManager) processSubs(sess *mqttSession, c *client,
 				continue
 			}
 
-			fwjscons, fwjssub, err = sess.processJSConsumer(c, fwcsubject, fwcsid, f.qos, fromSubProto)
+			fwjscons, fwjssub, err = sess.processJSConsumer(c, fwcsubject, f.queue, fwcsid, f.qos, fromSubProto)
 			if err != nil {
 				// c.processSub already called c.Errorf(), so no need here.
 				f.qos = mqttSubAckFailure
@@ -2725,6 +3104,15 @@ func (as *mqttAccountSessionManager) serializeRetainedMsgsForSub(rms map[string]
 			// calling serialize.
 			continue
 		}
+		// Skip the message if its MQTT v5 message expiry interval has elapsed.
+		var props []byte
+		p, expired := mqttNATSHeaderToProperties(rm.hdr, _EMPTY_, time.Since(rm.stored))
+		if expired {
+			continue
+		}
+		if c.mqtt.v5 {
+			props = mqttEncodeProperties(p)
+		}
 		var pi uint16
 		qos := mqttGetQoS(rm.Flags)
 		if qos > sub.mqtt.qos {
@@ -2747,7 +3135,7 @@ func (as *mqttAccountSessionManager) serializeRetainedMsgsForSub(rms map[string]
 		// Need to use the subject for the retained message, not the `sub` subject.
 		// We can find the published retained message in rm.sub.subject.
 		// Set the RETAIN flag: [MQTT-3.3.1-8].
-		flags, headerBytes := mqttMakePublishHeader(pi, qos, false, true, []byte(rm.Topic), len(rm.Msg))
+		flags, headerBytes := mqttMakePublishHeaderWithProperties(pi, qos, false, true, []byte(rm.Topic), props, len(rm.Msg))
 		c.mu.Lock()
 		sub.mqtt.prm = append(sub.mqtt.prm, headerBytes, rm.Msg)
 		c.mu.Unlock()
@@ -2821,8 +3209,12 @@ func (as *mqttAccountSessionManager) loadRetainedMessages(subjects map[string]st
 		if result == nil {
 			continue // skip requests that timed out
 		}
-		if result.ToError() != nil {
-			w.Warnf("failed to load retained message for subject %q: %v", ss[i], err)
+		if err := result.ToError(); err != nil {
+			// The message may have been removed following its MQTT v5
+			// message expiry interval.
+			if !IsNatsErr(err, JSNoMessageFoundErr) {
+				w.Warnf("failed to load retained message for subject %q: %v", ss[i], err)
+			}
 			continue
 		}
 		rm, err := mqttDecodeRetainedMessage(result.Message.Header, result.Message.Data)
@@ -2830,6 +3222,7 @@ func (as *mqttAccountSessionManager) loadRetainedMessages(subjects map[string]st
 			w.Warnf("failed to decode retained message for subject %q: %v", ss[i], err)
 			continue
 		}
+		rm.stored = result.Message.Time
 
 		// Add the loaded retained message to the cache, and to the results map.
 		key := ss[i][len(mqttRetainedMsgsStreamSubject):]
@@ -2853,6 +3246,12 @@ func mqttEncodeRetainedMessage(rm *mqttRetainedMsg) (natsMsg []byte, headerLen i
 	l += len(mqttNatsRetainedMessageFlags) + 1 + 2 + 2 // 1 byte for ':', 2 bytes for the flags, 2 bytes for CRLF
 	l += 2                                             // 2 bytes for the extra CRLF after the header
 	l += len(rm.Msg)
+	// MQTT v5 properties, and the TTL from the message expiry interval.
+	exp := mqttGetPropertiesNATSHeader(mqttNatsHeaderExpiry, rm.hdr)
+	l += len(rm.hdr)
+	if len(exp) > 0 {
+		l += len(JSMessageTTL) + 1 + len(exp) + 2 // 1 byte for ':', 2 bytes for CRLF
+	}
 
 	buf := bytes.NewBuffer(make([]byte, 0, l))
 
@@ -2880,6 +3279,13 @@ func mqttEncodeRetainedMessage(rm *mqttRetainedMsg) (natsMsg []byte, headerLen i
 		buf.WriteString(rm.Source)
 		buf.WriteString(_CRLF_)
 	}
+	buf.Write(rm.hdr)
+	if len(exp) > 0 {
+		buf.WriteString(JSMessageTTL)
+		buf.WriteByte(':')
+		buf.Write(exp)
+		buf.WriteString(_CRLF_)
+	}
 
 	// End of header, finalize
 	buf.WriteString(_CRLF_)
@@ -2904,6 +3310,7 @@ func mqttDecodeRetainedMessage(h, m []byte) (*mqttRetainedMsg, error) {
 			Origin:  string(getHeader(mqttNatsRetainedMessageOrigin, h)),
 			Source:  string(getHeader(mqttNatsRetainedMessageSource, h)),
 			Msg:     m,
+			hdr:     h,
 		}, nil
 	} else {
 		var rm mqttRetainedMsg
@@ -2956,6 +3363,7 @@ func (as *mqttAccountSessionManager) createOrRestoreSession(clientID string, opt
 	sess.subs = ps.Subs
 	sess.cons = ps.Cons
 	sess.pubRelConsumer = ps.PubRel
+	sess.expiry = ps.Expiry
 	as.addSession(sess, true)
 	return sess, true, nil
 }
@@ -3125,6 +3533,7 @@ func (as *mqttAccountSessionManager) setCachedRetainedMsg(subject string, rm *mq
 	}
 	if copyBytesToCache {
 		rm.Msg = copyBytes(rm.Msg)
+		rm.hdr = copyBytes(rm.hdr)
 	}
 	as.rmsCache.Store(subject, rm)
 }
@@ -3168,6 +3577,10 @@ func (sess *mqttSession) save() error {
 		Subs:   sess.subs,
 		Cons:   sess.cons,
 		PubRel: sess.pubRelConsumer,
+		Expiry: sess.expiry,
+	}
+	if sess.c == nil && !sess.disc.IsZero() {
+		ps.Disc = sess.disc.UnixNano()
 	}
 	b, _ := json.Marshal(&ps)
 
@@ -3215,7 +3628,10 @@ func (sess *mqttSession) clear(noWait bool) error {
 	}
 	for sid, cc := range sess.cons {
 		delete(sess.cons, sid)
-		durs = append(durs, cc.Durable)
+		// See deleteConsumer() for MQTT v5 shared subscriptions.
+		if cc.DeliverGroup == _EMPTY_ {
+			durs = append(durs, cc.Durable)
+		}
 	}
 	if sess.pubRelConsumer != nil {
 		pubRelDur = sess.pubRelConsumer.Durable
@@ -3268,16 +3684,16 @@ func (sess *mqttSession) update(filters []*mqttFilter, add bool) error {
 			if f.qos == mqttSubAckFailure {
 				continue
 			}
-			if qos, ok := sess.subs[f.filter]; !ok || qos != f.qos {
+			if qos, ok := sess.subs[f.sid()]; !ok || qos != f.qos {
 				if sess.subs == nil {
 					sess.subs = make(map[string]byte)
 				}
-				sess.subs[f.filter] = f.qos
+				sess.subs[f.sid()] = f.qos
 				needUpdate = true
 			}
 		} else {
-			if _, ok := sess.subs[f.filter]; ok {
-				delete(sess.subs, f.filter)
+			if _, ok := sess.subs[f.sid()]; ok {
+				delete(sess.subs, f.sid())
 				needUpdate = true
 			}
 		}
@@ -3505,11 +3921,17 @@ func (sess *mqttSession) untrackPubRel(pi uint16) (jsAckSubject string) {
 
 // Sends a consumer delete request, but does not wait for response.
 //
+// Consumers of MQTT v5 shared subscriptions are not deleted since other
+// members of the group may still use them. The server removes them once
+// they have been inactive long enough.
+//
 // Lock not held on entry.
 func (sess *mqttSession) deleteConsumer(cc *ConsumerConfig) {
 	sess.mu.Lock()
 	sess.tmaxack -= cc.MaxAckPending
-	sess.jsa.deleteConsumer(mqttStreamName, cc.Durable, true)
+	if cc.DeliverGroup == _EMPTY_ {
+		sess.jsa.deleteConsumer(mqttStreamName, cc.Durable, true)
+	}
 	sess.mu.Unlock()
 }
 
@@ -3542,9 +3964,10 @@ func (c *client) mqttParseConnect(r *mqttReader, hasMappings bool) (byte, *mqttC
 		return 0, nil, err
 	}
 	// Spec [MQTT-3.1.2-2]
-	if level != mqttProtoLevel {
+	if level != mqttProtoLevel && level != mqttProtoLevel5 {
 		return mqttConnAckRCUnacceptableProtocolVersion, nil, fmt.Errorf("unacceptable protocol version of %v", level)
 	}
+	c.mqtt.v5 = level == mqttProtoLevel5
 
 	cp := &mqttConnectProto{}
 	// Connect flags
@@ -3603,6 +4026,17 @@ func (c *client) mqttParseConnect(r *mqttReader, hasMappings bool) (byte, *mqttC
 		cp.rd = time.Duration(float64(ka)*1.5) * time.Second
 	}
 
+	if c.mqtt.v5 {
+		cp.props, err = r.readProperties("connect")
+		if err != nil {
+			return 0, nil, err
+		}
+		// Enhanced authentication, Spec v5 [4.12], is not supported.
+		if cp.props.authMethod != _EMPTY_ {
+			return mqttReasonBadAuthMethod, nil, fmt.Errorf("unsupported authentication method %q", cp.props.authMethod)
+		}
+	}
+
 	// Payload starts here and order is mandated by:
 	// Spec [MQTT-3.1.3-1]: client ID, will topic, will message, username, password
 
@@ -3611,13 +4045,14 @@ func (c *client) mqttParseConnect(r *mqttReader, hasMappings bool) (byte, *mqttC
 	if err != nil {
 		return 0, nil, err
 	}
-	// Spec [MQTT-3.1.3-7]
+	// Spec [MQTT-3.1.3-7], which does not apply to MQTT v5.
 	if c.mqtt.cid == _EMPTY_ {
-		if cp.flags&mqttConnFlagCleanSession == 0 {
+		if !c.mqtt.v5 && cp.flags&mqttConnFlagCleanSession == 0 {
 			return mqttConnAckRCIdentifierRejected, nil, errMQTTCIDEmptyNeedsCleanFlag
 		}
 		// Spec [MQTT-3.1.3-6]
 		c.mqtt.cid = nuid.Next()
+		c.mqtt.cidAssigned = true
 	}
 	// Spec [MQTT-3.1.3-4] and [MQTT-3.1.3-9]
 	if !utf8.ValidString(c.mqtt.cid) {
@@ -3629,6 +4064,17 @@ func (c *client) mqttParseConnect(r *mqttReader, hasMappings bool) (byte, *mqttC
 			qos:    wqos,
 			retain: wretain,
 		}
+		// Spec v5 [3.1.3.2]: the Will properties come before the Will topic.
+		if c.mqtt.v5 {
+			wprops, err := r.readProperties("Will")
+			if err != nil {
+				return 0, nil, err
+			}
+			cp.will.hdr, cp.will.reply, err = mqttPropertiesToNATSHeader(wprops)
+			if err != nil {
+				return 0, nil, err
+			}
+		}
 		var topic []byte
 		// Need to make a copy since we need to hold to this topic after the
 		// parsing of this protocol.
@@ -3825,6 +4271,15 @@ CHECK:
 
 	// Is the client requesting a clean session or not.
 	cleanSess := cp.flags&mqttConnFlagCleanSession != 0
+	// For MQTT v5 clients, this is the "clean start" flag that applies only to
+	// an existing session. Whether the session is removed when the client goes
+	// away depends on the session expiry interval. Spec v5 [3.1.2.4] and [3.1.2.11.2]
+	removeOnClose := cleanSess
+	var expiry uint32
+	if cp.props != nil {
+		expiry = cp.props.sessExpiry
+		removeOnClose = expiry == 0
+	}
 	// Session present? Assume false, will be set to true only when applicable.
 	sessp := false
 	// Do we have an existing session for this client ID
@@ -3864,7 +4319,11 @@ CHECK:
 		es.mu.Lock()
 		ec := es.c
 		es.c = c
-		es.clean = cleanSess
+		es.clean, es.expiry = removeOnClose, expiry
+		if es.expTimer != nil {
+			es.expTimer.Stop()
+			es.expTimer = nil
+		}
 		es.mu.Unlock()
 		if ec != nil {
 			// Remove "will" of existing client before closing
@@ -3883,7 +4342,7 @@ CHECK:
 		// Spec [MQTT-3.2.2-3]: if the Server does not have stored Session state,
 		// it MUST set Session Present to 0 in the CONNACK packet.
 		es.mu.Lock()
-		es.c, es.clean = c, cleanSess
+		es.c, es.clean, es.expiry = c, removeOnClose, expiry
 		es.mu.Unlock()
 		// Now add this new session into the account sessions
 		asm.addSession(es, true)
@@ -3908,8 +4367,9 @@ CHECK:
 	// Process possible saved subscriptions.
 	if l := len(es.subs); l > 0 {
 		filters := make([]*mqttFilter, 0, l)
-		for subject, qos := range es.subs {
-			filters = append(filters, &mqttFilter{filter: subject, qos: qos})
+		for sid, qos := range es.subs {
+			queue, subject := mqttParseSharedSubKey(sid)
+			filters = append(filters, &mqttFilter{filter: subject, queue: queue, qos: qos})
 		}
 		if _, err := asm.processSubs(es, c, filters, false, trace); err != nil {
 			return err
@@ -3919,6 +4379,10 @@ CHECK:
 }
 
 func (c *client) mqttEnqueueConnAck(rc byte, sessionPresent bool) {
+	if c.mqtt.v5 {
+		c.mqttEnqueueConnAckV5(rc, sessionPresent)
+		return
+	}
 	proto := [4]byte{mqttPacketConnectAck, 2, 0, rc}
 	c.mu.Lock()
 	// Spec [MQTT-3.2.2-4]. If return code is different from 0, then
@@ -3932,6 +4396,56 @@ func (c *client) mqttEnqueueConnAck(rc byte, sessionPresent bool) {
 	c.mu.Unlock()
 }
 
+// Sends a MQTT v5 CONNACK, with the properties that tell the client about the
+// features that this server does not support, or that differ from defaults.
+func (c *client) mqttEnqueueConnAckV5(rc byte, sessionPresent bool) {
+	var sp byte
+	props := &mqttProperties{}
+	if rc == mqttConnAckRCConnectionAccepted {
+		if sessionPresent {
+			sp = 1
+		}
+		props.topicAliasMax = mqttTopicAliasMax
+		props.hasSubIDAvail = true
+		if c.mqtt.cidAssigned {
+			props.assignedCID = c.mqtt.cid
+		}
+		if c.mqtt.rejectQoS2Pub {
+			props.maxQoS, props.hasMaxQoS = 1, true
+		}
+	}
+	pb := mqttEncodeProperties(props)
+	w := newMQTTWriter(4 + len(pb))
+	w.WriteByte(mqttPacketConnectAck)
+	w.WriteVarInt(2 + len(pb))
+	w.WriteByte(sp)
+	w.WriteByte(mqttConnAckReasonCodeV5(rc))
+	w.Write(pb)
+	c.mu.Lock()
+	c.enqueueProto(w.Bytes())
+	c.mu.Unlock()
+}
+
+// Returns the MQTT v5 reason code for the given CONNACK return code, which
+// may already be a MQTT v5 reason code.
+func mqttConnAckReasonCodeV5(rc byte) byte {
+	switch rc {
+	case mqttConnAckRCUnacceptableProtocolVersion:
+		return mqttReasonUnsupportedProtoVersion
+	case mqttConnAckRCIdentifierRejected:
+		return mqttReasonClientIDNotValid
+	case mqttConnAckRCServerUnavailable:
+		return mqttReasonServerUnavailable
+	case mqttConnAckRCBadUserOrPassword:
+		return mqttReasonBadUserOrPassword
+	case mqttConnAckRCNotAuthorized:
+		return mqttReasonNotAuthorized
+	case mqttConnAckRCQoS2WillRejected:
+		return mqttReasonQoSNotSupported
+	}
+	return rc
+}
+
 func (s *Server) mqttHandleWill(c *client) {
 	c.mu.Lock()
 	if c.mqtt.cp == nil {
@@ -3950,6 +4464,8 @@ func (s *Server) mqttHandleWill(c *client) {
 	pp.msg = will.message
 	pp.sz = len(will.message)
 	pp.pi = 0
+	pp.hdr = will.hdr
+	pp.reply = will.reply
 	pp.flags = will.qos << 1
 	if will.retain {
 		pp.flags |= mqttPubFlagRetain
@@ -3983,9 +4499,37 @@ func (c *client) mqttParsePub(r *mqttReader, pl int, pp *mqttPublish, hasMapping
 	if err != nil {
 		return err
 	}
-	if len(pp.topic) == 0 {
+	// With MQTT v5, the topic can be empty if a topic alias is used.
+	if len(pp.topic) == 0 && !c.mqtt.v5 {
 		return errMQTTTopicIsEmpty
 	}
+
+	if qos > 0 {
+		pp.pi, err = r.readUint16("packet identifier")
+		if err != nil {
+			return err
+		}
+		if pp.pi == 0 {
+			return fmt.Errorf("with QoS=%v, packet identifier cannot be 0", qos)
+		}
+	} else {
+		pp.pi = 0
+	}
+
+	pp.hdr, pp.reply = nil, nil
+	if c.mqtt.v5 {
+		props, err := r.readProperties("publish")
+		if err != nil {
+			return err
+		}
+		if err = c.mqttResolveTopicAlias(pp, props.topicAlias); err != nil {
+			return err
+		}
+		if pp.hdr, pp.reply, err = mqttPropertiesToNATSHeader(props); err != nil {
+			return err
+		}
+	}
+
 	// Convert the topic to a NATS subject. This call will also check that
 	// there is no MQTT wildcards (Spec [MQTT-3.3.2-2] and [MQTT-4.7.1-1])
 	// Note that this may not result in a copy if there is no conversion.
@@ -4013,18 +4557,6 @@ func (c *client) mqttParsePub(r *mqttReader, pl int, pp *mqttPublish, hasMapping
 		c.pa.subject, c.pa.mapped = nil, nil
 	}
 
-	if qos > 0 {
-		pp.pi, err = r.readUint16("packet identifier")
-		if err != nil {
-			return err
-		}
-		if pp.pi == 0 {
-			return fmt.Errorf("with QoS=%v, packet identifier cannot be 0", qos)
-		}
-	} else {
-		pp.pi = 0
-	}
-
 	// The message payload will be the total packet length minus
 	// what we have consumed for the variable header
 	pp.sz = pl - (r.pos - start)
@@ -4038,6 +4570,37 @@ func (c *client) mqttParsePub(r *mqttReader, pl int, pp *mqttPublish, hasMapping
 	return nil
 }
 
+// Resolves the topic alias of a MQTT v5 PUBLISH packet. If the packet has a
+// topic, the alias is (re)bound to it, otherwise the topic is the one that is
+// bound to the alias. Spec v5 [3.3.2.3.4]
+//
+// Runs from the client's readLoop.
+func (c *client) mqttResolveTopicAlias(pp *mqttPublish, alias uint16) error {
+	if alias == 0 {
+		if len(pp.topic) == 0 {
+			return errMQTTTopicIsEmpty
+		}
+		return nil
+	}
+	// Spec v5 [MQTT-3.3.2-9]
+	if alias > mqttTopicAliasMax {
+		return fmt.Errorf("topic alias %v greater than maximum of %v", alias, mqttTopicAliasMax)
+	}
+	if len(pp.topic) == 0 {
+		topic, ok := c.mqtt.aliases[alias]
+		if !ok {
+			return fmt.Errorf("unknown topic alias %v", alias)
+		}
+		pp.topic = topic
+		return nil
+	}
+	if c.mqtt.aliases == nil {
+		c.mqtt.aliases = make(map[uint16][]byte)
+	}
+	c.mqtt.aliases[alias] = copyBytes(pp.topic)
+	return nil
+}
+
 func mqttPubTrace(pp *mqttPublish) string {
 	dup := pp.flags&mqttPubFlagDup != 0
 	qos := mqttGetQoS(pp.flags)
@@ -4073,6 +4636,7 @@ func mqttNewDeliverableMessage(pp *mqttPublish, encodePP bool) (natsMsg []byte,
 				len(pp.mapped) + 2 // 2 for CRLF
 		}
 	}
+	size += len(pp.hdr)
 	buf := bytes.NewBuffer(make([]byte, 0, size))
 
 	qos := mqttGetQoS(pp.flags)
@@ -4097,6 +4661,9 @@ func mqttNewDeliverableMessage(pp *mqttPublish, encodePP bool) (natsMsg []byte,
 		}
 	}
 
+	// MQTT v5 properties, if any.
+	buf.Write(pp.hdr)
+
 	// End of header
 	buf.WriteString(_CRLF_)
 
@@ -4177,7 +4744,7 @@ func (s *Server) mqttInitiateMsgDelivery(c *client, pp *mqttPublish) error {
 	// Set the client's pubarg for processing.
 	c.pa.subject = pp.subject
 	c.pa.mapped = pp.mapped
-	c.pa.reply = nil
+	c.pa.reply = pp.reply
 	c.pa.hdr = headerLen
 	c.pa.hdb = []byte(strconv.FormatInt(int64(c.pa.hdr), 10))
 	c.pa.size = len(natsMsg)
@@ -4210,11 +4777,203 @@ func (s *Server) mqttInitiateMsgDelivery(c *client, pp *mqttPublish) error {
 	// see addToPCD and writeLoop for details).
 	c.flushClients(0)
 
+	headerLen, natsMsg = mqttAddMsgTTLHeader(headerLen, natsMsg)
 	_, err := c.mqtt.sess.jsa.storeMsg(mqttStreamSubjectPrefix+string(c.pa.subject), headerLen, natsMsg)
 
 	return err
 }
 
+// If the message has a MQTT v5 message expiry interval, returns a copy of the
+// message with the JetStream per-message TTL header set to that interval, so
+// that the stored message is removed once expired.
+func mqttAddMsgTTLHeader(hdr int, msg []byte) (int, []byte) {
+	exp := sliceHeader(mqttNatsHeaderExpiry, msg[:hdr])
+	if len(exp) == 0 {
+		return hdr, msg
+	}
+	nhdr := genHeader(msg[:hdr], JSMessageTTL, string(exp))
+	return len(nhdr), append(nhdr, msg[hdr:]...)
+}
+
+// Converts the properties of a MQTT v5 PUBLISH packet (or will message) to
+// NATS header lines, and returns the NATS subject of the response topic, if
+// any, that is used as the reply subject of the NATS message. User properties
+// that can't be represented as NATS headers, or that use reserved header
+// names, are dropped.
+func mqttPropertiesToNATSHeader(p *mqttProperties) (hdr, reply []byte, err error) {
+	if p == nil {
+		return nil, nil, nil
+	}
+	var bb bytes.Buffer
+	addLine := func(k, v string) {
+		bb.WriteString(k)
+		bb.WriteByte(':')
+		bb.WriteString(v)
+		bb.WriteString(_CRLF_)
+	}
+	if p.contentType != _EMPTY_ {
+		addLine(mqttNatsHeaderContentType, p.contentType)
+	}
+	if p.payloadFormat != 0 {
+		addLine(mqttNatsHeaderFormat, strconv.Itoa(int(p.payloadFormat)))
+	}
+	if len(p.correlationData) > 0 {
+		addLine(mqttNatsHeaderCorrelation, base64.StdEncoding.EncodeToString(p.correlationData))
+	}
+	if p.msgExpiry > 0 {
+		addLine(mqttNatsHeaderExpiry, strconv.FormatUint(uint64(p.msgExpiry), 10))
+	}
+	if len(p.responseTopic) > 0 {
+		// Spec v5 [MQTT-3.3.2-14]
+		subj, err := mqttTopicToNATSPubSubject(p.responseTopic)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid response topic %q: %v", p.responseTopic, err)
+		}
+		reply = copyBytes(subj)
+		addLine(mqttNatsHeaderReply, string(reply))
+	}
+	for _, up := range p.userProps {
+		if !mqttIsValidNATSHeader(up.key, up.value) ||
+			strings.HasPrefix(up.key, mqttNatsHeaderPrefix) ||
+			strings.HasPrefix(up.key, mqttNatsReservedHeaderPrefix) {
+			continue
+		}
+		addLine(up.key, up.value)
+	}
+	if bb.Len() > 0 {
+		hdr = bb.Bytes()
+	}
+	return hdr, reply, nil
+}
+
+// Returns true if the key and value can be used as a NATS header line.
+func mqttIsValidNATSHeader(key, value string) bool {
+	if key == _EMPTY_ {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c >= 0x7f || c == ':' {
+			return false
+		}
+	}
+	return !strings.ContainsAny(value, "\r\n")
+}
+
+// Invokes `f` for each "key:value" line of the NATS header `hdr`, which may
+// start with the NATS header version line, or be only header lines.
+func mqttRangeNATSHeader(hdr []byte, f func(key, value []byte)) {
+	if bytes.HasPrefix(hdr, []byte(hdrLine[:5])) {
+		if i := bytes.Index(hdr, []byte(_CRLF_)); i >= 0 {
+			hdr = hdr[i+2:]
+		} else {
+			return
+		}
+	}
+	for len(hdr) > 0 {
+		var line []byte
+		if i := bytes.Index(hdr, []byte(_CRLF_)); i >= 0 {
+			line, hdr = hdr[:i], hdr[i+2:]
+		} else {
+			line, hdr = hdr, nil
+		}
+		if len(line) == 0 {
+			return
+		}
+		if i := bytes.IndexByte(line, ':'); i > 0 {
+			f(line[:i], bytes.TrimLeft(line[i+1:], " "))
+		}
+	}
+}
+
+// Returns the value of the header `key` from the NATS header `hdr`, or nil.
+func mqttGetPropertiesNATSHeader(key string, hdr []byte) []byte {
+	var v []byte
+	mqttRangeNATSHeader(hdr, func(k, val []byte) {
+		if v == nil && string(k) == key {
+			v = val
+		}
+	})
+	return v
+}
+
+// Returns the header lines of a stored NATS message that carry the MQTT v5
+// properties, that is all but the ones that are added by mqttParsePub.
+func mqttPropertiesNATSHeaderFrom(hdr []byte) []byte {
+	var bb bytes.Buffer
+	mqttRangeNATSHeader(hdr, func(k, v []byte) {
+		switch string(k) {
+		case mqttNatsHeader, mqttNatsHeaderSubject, mqttNatsHeaderMapped, JSMessageTTL:
+			return
+		}
+		bb.Write(k)
+		bb.WriteByte(':')
+		bb.Write(v)
+		bb.WriteString(_CRLF_)
+	})
+	if bb.Len() == 0 {
+		return nil
+	}
+	return bb.Bytes()
+}
+
+// Converts the NATS header of a message that is delivered to a MQTT v5
+// client to PUBLISH properties. The response topic is from the MQTT reply
+// header if present, otherwise from `reply`, the reply subject of the NATS
+// message. The message expiry interval is reduced by `elapsed`, and if it has
+// elapsed, returns true, indicating that the message must not be delivered.
+// Spec v5 [3.3.2.3]
+func mqttNATSHeaderToProperties(hdr []byte, reply string, elapsed time.Duration) (*mqttProperties, bool) {
+	var p *mqttProperties
+	get := func() *mqttProperties {
+		if p == nil {
+			p = &mqttProperties{}
+		}
+		return p
+	}
+	var expired, hasReply bool
+	mqttRangeNATSHeader(hdr, func(k, v []byte) {
+		switch key := string(k); key {
+		case mqttNatsHeaderContentType:
+			get().contentType = string(v)
+		case mqttNatsHeaderFormat:
+			if n, err := strconv.Atoi(string(v)); err == nil {
+				get().payloadFormat = byte(n)
+			}
+		case mqttNatsHeaderCorrelation:
+			if cd, err := base64.StdEncoding.DecodeString(string(v)); err == nil {
+				get().correlationData = cd
+			}
+		case mqttNatsHeaderExpiry:
+			exp, err := strconv.ParseUint(string(v), 10, 32)
+			if err != nil {
+				return
+			}
+			left := time.Duration(exp)*time.Second - elapsed
+			if left <= 0 {
+				expired = true
+				return
+			}
+			// Spec v5 [MQTT-3.3.2-6]
+			get().msgExpiry = uint32((left + time.Second - 1) / time.Second)
+		case mqttNatsHeaderReply:
+			hasReply = true
+			get().responseTopic = natsSubjectToMQTTTopic(copyBytes(v))
+		default:
+			if strings.HasPrefix(key, mqttNatsHeaderPrefix) || strings.HasPrefix(key, mqttNatsReservedHeaderPrefix) {
+				return
+			}
+			get().userProps = append(get().userProps, mqttUserProperty{key, string(v)})
+		}
+	})
+	if expired {
+		return nil, true
+	}
+	if !hasReply && reply != _EMPTY_ && !strings.HasPrefix(reply, jsAckPre) {
+		get().responseTopic = natsSubjectStrToMQTTTopic(reply)
+	}
+	return p, false
+}
+
 var mqttMaxMsgErrPattern = fmt.Sprintf("%s (%v)", ErrMaxMsgsPerSubject.Error(), JSStreamStoreFailedF)
 
 func (s *Server) mqttStoreQoS2MsgOnce(c *client, pp *mqttPublish) error {
@@ -4278,6 +5037,8 @@ func (s *Server) mqttProcessPubRel(c *client, pi uint16, trace bool) error {
 		sz:      len(stored.Data),
 		pi:      pi,
 		flags:   h.qos << 1,
+		hdr:     mqttPropertiesNATSHeaderFrom(stored.Header),
+		reply:   getHeader(mqttNatsHeaderReply, stored.Header),
 	}
 
 	return s.mqttInitiateMsgDelivery(c, pp)
@@ -4330,6 +5091,8 @@ func (c *client) mqttHandlePubRetain() {
 		Msg:    pp.msg, // will copy these bytes later as we process rm.
 		Flags:  pp.flags,
 		Source: c.opts.Username,
+		hdr:    pp.hdr,
+		stored: time.Now(),
 	}
 
 	if retainSparkbBirth {
@@ -4575,6 +5338,29 @@ func mqttParsePIPacket(r *mqttReader) (uint16, error) {
 	return pi, nil
 }
 
+// Parses a PUBACK, PUBREC, PUBREL or PUBCOMP packet, which for MQTT v5
+// clients may have a reason code and properties after the packet identifier.
+// The properties are of no interest and are skipped. Spec v5 [3.4.2]
+func (c *client) mqttParsePIPacketWithReasonCode(r *mqttReader, pl int) (uint16, byte, error) {
+	start := r.pos
+	pi, err := mqttParsePIPacket(r)
+	if err != nil || !c.mqtt.v5 {
+		return pi, 0, err
+	}
+	var rc byte
+	if pl > 2 {
+		if rc, err = r.readByte("reason code"); err != nil {
+			return 0, 0, err
+		}
+	}
+	if end := start + pl; end > len(r.buf) {
+		return 0, 0, fmt.Errorf("error reading properties: %v", io.ErrUnexpectedEOF)
+	} else {
+		r.pos = end
+	}
+	return pi, rc, nil
+}
+
 // Process a PUBACK (QoS1) or a PUBREC (QoS2) packet, acting as Sender. Set
 // isPubRec to false to process as a PUBACK.
 //
@@ -4705,6 +5491,15 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 		return 0, nil, fmt.Errorf("reading packet identifier: %v", err)
 	}
 	end := r.pos + (pl - 2)
+	if c.mqtt.v5 {
+		props, err := r.readProperties(action + "subscribe")
+		if err != nil {
+			return 0, nil, err
+		}
+		if props.subIDs {
+			return 0, nil, errMQTTSubIDsNotSupported
+		}
+	}
 	var filters []*mqttFilter
 	for r.pos < end {
 		// Don't make a copy now because, this will happen during conversion
@@ -4720,7 +5515,14 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 		if !utf8.Valid(topic) {
 			return 0, nil, fmt.Errorf("invalid utf8 for topic filter %q", topic)
 		}
-		var qos byte
+		ttopic := topic
+		var queue string
+		if c.mqtt.v5 && bytes.HasPrefix(topic, []byte(mqttSharedSubPrefix)) {
+			if queue, topic, err = mqttParseSharedSubscription(topic); err != nil {
+				return 0, nil, err
+			}
+		}
+		var qos, rh byte
 		// We are going to report if we had an error during the conversion,
 		// but we don't fail the parsing. When processing the sub, we will
 		// have an error then, and the processing of subs code will send
@@ -4734,12 +5536,29 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 			if err != nil {
 				return 0, nil, err
 			}
+			// For MQTT v5, this is the subscription options byte, of which
+			// the No Local and Retain As Published options are ignored.
+			if c.mqtt.v5 {
+				opts := qos
+				// Spec v5 [MQTT-3.8.3-5].
+				if opts&mqttSubOptReserved != 0 {
+					return 0, nil, fmt.Errorf("subscribe options reserved bits must be 0, got %x", opts)
+				}
+				// Spec v5 [MQTT-3.8.3-4].
+				if queue != _EMPTY_ && opts&mqttSubOptNoLocal != 0 {
+					return 0, nil, fmt.Errorf("no local option not allowed for shared subscription %q", ttopic)
+				}
+				if rh = (opts & mqttSubOptRetainHandling) >> 4; rh > 2 {
+					return 0, nil, fmt.Errorf("subscribe retain handling value must be 0, 1 or 2, got %v", rh)
+				}
+				qos = opts & mqttSubOptQoS
+			}
 			// Spec [MQTT-3-8.3-4].
 			if qos > 2 {
 				return 0, nil, fmt.Errorf("subscribe QoS value must be 0, 1 or 2, got %v", qos)
 			}
 		}
-		f := &mqttFilter{ttopic: topic, filter: string(filter), qos: qos}
+		f := &mqttFilter{ttopic: ttopic, filter: string(filter), qos: qos, queue: queue, rh: rh}
 		filters = append(filters, f)
 	}
 	// Spec [MQTT-3.8.3-3], [MQTT-3.10.3-2]
@@ -4749,6 +5568,46 @@ func (c *client) mqttParseSubsOrUnsubs(r *mqttReader, b byte, pl int, sub bool)
 	return pi, filters, nil
 }
 
+// Returns the key of this filter in the session's subscriptions, which is also
+// the sid of the NATS subscription. This is the NATS subject, unless this is
+// a MQTT v5 shared subscription.
+func (f *mqttFilter) sid() string {
+	if f.queue == _EMPTY_ {
+		return f.filter
+	}
+	return mqttSharedSubKey(f.queue, f.filter)
+}
+
+// Returns the session's key for a shared subscription: "$share/<group>/<subject>".
+// The NATS subject can't be confused with a MQTT "$share/..." topic filter
+// because such topic would have been converted to "$share.<...>".
+func mqttSharedSubKey(queue, subject string) string {
+	return mqttSharedSubPrefix + queue + "/" + subject
+}
+
+// Returns the queue group (possibly empty) and the NATS subject from the
+// session's key of a subscription.
+func mqttParseSharedSubKey(sid string) (string, string) {
+	if rest, ok := strings.CutPrefix(sid, mqttSharedSubPrefix); ok {
+		if queue, subject, ok := strings.Cut(rest, "/"); ok && queue != _EMPTY_ {
+			return queue, subject
+		}
+	}
+	return _EMPTY_, sid
+}
+
+// Parses a MQTT v5 shared subscription topic filter, that is of the form
+// "$share/<share name>/<topic filter>". Spec v5 [4.8.2]
+func mqttParseSharedSubscription(topic []byte) (string, []byte, error) {
+	rest := topic[len(mqttSharedSubPrefix):]
+	i := bytes.IndexByte(rest, mqttTopicLevelSep)
+	// Spec v5 [MQTT-4.8.2-1], [MQTT-4.8.2-2]
+	if i <= 0 || i == len(rest)-1 || bytes.ContainsAny(rest[:i], "+#") {
+		return _EMPTY_, nil, fmt.Errorf("invalid shared subscription %q", topic)
+	}
+	return string(rest[:i]), rest[i+1:], nil
+}
+
 func mqttSubscribeTrace(pi uint16, filters []*mqttFilter) string {
 	var sep string
 	sb := &strings.Builder{}
@@ -4846,8 +5705,16 @@ func mqttDeliverMsgCbQoS0(sub *subscription, pc *client, _ *Account, subject, re
 		topic = natsSubjectStrToMQTTTopic(subject)
 	}
 
+	// For MQTT v5 subscribers, the reply subject of the message is the
+	// response topic, unless the message has one in its header.
+	var props []byte
+	if cc.mqtt.v5 {
+		p, _ := mqttNATSHeaderToProperties(hdr, reply, 0)
+		props = mqttEncodeProperties(p)
+	}
+
 	// Message never has a packet identifier nor is marked as duplicate.
-	pc.mqttEnqueuePublishMsgTo(cc, sub, 0, 0, false, topic, msg)
+	pc.mqttEnqueuePublishMsgTo(cc, sub, 0, 0, false, topic, props, msg)
 }
 
 // This is the callback attached to a JS durable subscription for a MQTT QoS 1+
@@ -4879,6 +5746,23 @@ func mqttDeliverMsgCbQoS12(sub *subscription, pc *client, _ *Account, subject, r
 	// This is immutable
 	sess := cc.mqtt.sess
 
+	// For MQTT v5 subscribers, the message expiry interval is reduced by the
+	// time the message spent in the stream, and if elapsed, the message is
+	// acknowledged and not delivered. Spec v5 [MQTT-3.3.2-5], [MQTT-3.3.2-6]
+	var props []byte
+	if cc.mqtt.v5 {
+		var elapsed time.Duration
+		if _, _, _, ts, _ := replyInfo(reply); ts > 0 {
+			elapsed = time.Since(time.Unix(0, ts))
+		}
+		p, expired := mqttNATSHeaderToProperties(hdr, _EMPTY_, elapsed)
+		if expired {
+			sess.jsa.sendAck(reply)
+			return
+		}
+		props = mqttEncodeProperties(p)
+	}
+
 	// We lock to check some of the subscription's fields and if we need to keep
 	// track of pending acks, etc. There is no need to acquire the subsMu RLock
 	// since sess.Lock is overarching for modifying subscriptions.
@@ -4923,7 +5807,7 @@ func mqttDeliverMsgCbQoS12(sub *subscription, pc *client, _ *Account, subject, r
 	}
 
 	originalTopic := natsSubjectStrToMQTTTopic(strippedSubj)
-	pc.mqttEnqueuePublishMsgTo(cc, sub, pi, qos, dup, originalTopic, msg)
+	pc.mqttEnqueuePublishMsgTo(cc, sub, pi, qos, dup, originalTopic, props, msg)
 }
 
 func mqttDeliverPubRelCb(sub *subscription, pc *client, _ *Account, subject, reply string, rmsg []byte) {
@@ -5028,8 +5912,9 @@ func sparkbReplaceDeathTimestamp(msg []byte) []byte {
 }
 
 // Common function to mqtt delivery callbacks to serialize and send the message
-// to the `cc` client.
-func (c *client) mqttEnqueuePublishMsgTo(cc *client, sub *subscription, pi uint16, qos byte, dup bool, topic, msg []byte) {
+// to the `cc` client. The `props` are the encoded properties for MQTT v5
+// clients, nil otherwise.
+func (c *client) mqttEnqueuePublishMsgTo(cc *client, sub *subscription, pi uint16, qos byte, dup bool, topic, props, msg []byte) {
 	// [tck-id-conformance-mqtt-aware-nbirth-mqtt-retain] A Sparkplug Aware
 	// MQTT Server MUST make NBIRTH messages available on the topic:
 	// $sparkplug/certificates/namespace/group_id/NBIRTH/edge_node_id with
@@ -5051,7 +5936,7 @@ func (c *client) mqttEnqueuePublishMsgTo(cc *client, sub *subscription, pi uint1
 		msg = sparkbReplaceDeathTimestamp(msg)
 	}
 
-	flags, headerBytes := mqttMakePublishHeader(pi, qos, dup, retain, topic, len(msg))
+	flags, headerBytes := mqttMakePublishHeaderWithProperties(pi, qos, dup, retain, topic, props, len(msg))
 
 	cc.mu.Lock()
 	if sub.mqtt.prm != nil {
@@ -5079,8 +5964,14 @@ func (c *client) mqttEnqueuePublishMsgTo(cc *client, sub *subscription, pi uint1
 
 // Serializes to the given writer the message for the given subject.
 func (w *mqttWriter) WritePublishHeader(pi uint16, qos byte, dup, retained bool, topic []byte, msgLen int) byte {
+	return w.WritePublishHeaderWithProperties(pi, qos, dup, retained, topic, nil, msgLen)
+}
+
+// Same as WritePublishHeader, but for MQTT v5 clients, `props` being the
+// encoded properties (including their length). They are omitted if nil.
+func (w *mqttWriter) WritePublishHeaderWithProperties(pi uint16, qos byte, dup, retained bool, topic, props []byte, msgLen int) byte {
 	// Compute len (will have to add packet id if message is sent as QoS>=1)
-	pkLen := 2 + len(topic) + msgLen
+	pkLen := 2 + len(topic) + len(props) + msgLen
 	var flags byte
 
 	// Set flags for dup/retained/qos1
@@ -5101,14 +5992,20 @@ func (w *mqttWriter) WritePublishHeader(pi uint16, qos byte, dup, retained bool,
 	if qos > 0 {
 		w.WriteUint16(pi)
 	}
+	w.Write(props)
 
 	return flags
 }
 
 // Serializes to the given writer the message for the given subject.
 func mqttMakePublishHeader(pi uint16, qos byte, dup, retained bool, topic []byte, msgLen int) (byte, []byte) {
-	headerBuf := newMQTTWriter(mqttInitialPubHeader + len(topic))
-	flags := headerBuf.WritePublishHeader(pi, qos, dup, retained, topic, msgLen)
+	return mqttMakePublishHeaderWithProperties(pi, qos, dup, retained, topic, nil, msgLen)
+}
+
+// Same as mqttMakePublishHeader, with the encoded MQTT v5 properties.
+func mqttMakePublishHeaderWithProperties(pi uint16, qos byte, dup, retained bool, topic, props []byte, msgLen int) (byte, []byte) {
+	headerBuf := newMQTTWriter(mqttInitialPubHeader + len(topic) + len(props))
+	flags := headerBuf.WritePublishHeaderWithProperties(pi, qos, dup, retained, topic, props, msgLen)
 	return flags, headerBuf.Bytes()
 }
 
@@ -5241,9 +6138,13 @@ func (sess *mqttSession) ensurePubRelConsumerSubscription(c *client) error {
 // With a QoS > 0, creates or update the existing JS durable consumer along with
 // its NATS subscription on a delivery subject.
 //
+// For MQTT v5 shared subscriptions (non empty `queue`), the JS durable consumer
+// is shared by all the members of the group, and each member has a queue
+// subscription on its delivery subject.
+//
 // Session lock is acquired and released as needed. Session is in the locked
 // map.
-func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
+func (sess *mqttSession) processJSConsumer(c *client, subject, queue, sid string,
 	qos byte, fromSubProto bool) (*ConsumerConfig, *subscription, error) {
 
 	sess.mu.Lock()
@@ -5291,7 +6192,14 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 	if exists {
 		inbox = cc.DeliverSubject
 	} else {
+		durName := idHash + "_" + nuid.Next()
 		inbox = mqttSubPrefix + nuid.Next()
+		if queue != _EMPTY_ {
+			// All members need to resolve to the same consumer.
+			hash := getHash(queue + " " + subject)
+			durName = mqttSharedConsumerDurablePrefix + hash
+			inbox = mqttSubPrefix + mqttSharedDeliverySubjectToken + hash
+		}
 		opts := c.srv.getOpts()
 		ackWait := opts.MQTT.AckWait
 		if ackWait == 0 {
@@ -5308,11 +6216,11 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 				after, mqttMaxAckTotalLimit)
 		}
 
-		durName := idHash + "_" + nuid.Next()
 		ccr := &CreateConsumerRequest{
 			Stream: mqttStreamName,
 			Config: ConsumerConfig{
 				DeliverSubject: inbox,
+				DeliverGroup:   queue,
 				Durable:        durName,
 				AckPolicy:      AckExplicit,
 				DeliverPolicy:  DeliverNew,
@@ -5324,6 +6232,8 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 		}
 		if opts.MQTT.ConsumerInactiveThreshold > 0 {
 			ccr.Config.InactiveThreshold = opts.MQTT.ConsumerInactiveThreshold
+		} else if queue != _EMPTY_ {
+			ccr.Config.InactiveThreshold = mqttDefaultSharedConsumerInactiveThreshold
 		}
 		if _, err := sess.jsa.createDurableConsumer(ccr); err != nil {
 			c.Errorf("Unable to add JetStream consumer for subscription on %q: err=%v", subject, err)
@@ -5337,7 +6247,11 @@ func (sess *mqttSession) processJSConsumer(c *client, subject, sid string,
 	// for the JS durable's deliver subject.
 	sess.mu.Lock()
 	sess.tmaxack = tmaxack
-	sub, err := sess.processQOS12Sub(c, []byte(inbox), []byte(inbox),
+	var bqueue []byte
+	if queue != _EMPTY_ {
+		bqueue = []byte(queue)
+	}
+	sub, err := sess.processQOS12Sub(c, []byte(inbox), bqueue, []byte(inbox),
 		isMQTTReservedSubscription(subject), qos, cc.Durable, mqttDeliverMsgCbQoS12)
 	sess.mu.Unlock()
 
@@ -5366,11 +6280,20 @@ func (c *client) mqttSendRetainedMsgsToNewSubs(subs []*subscription) {
 }
 
 func (c *client) mqttEnqueueSubAck(pi uint16, filters []*mqttFilter) {
-	w := newMQTTWriter(7 + len(filters))
+	var pl int
+	if c.mqtt.v5 {
+		// Empty properties.
+		pl = 1
+	}
+	w := newMQTTWriter(7 + pl + len(filters))
 	w.WriteByte(mqttPacketSubAck)
 	// packet length is 2 (for packet identifier) and 1 byte per filter.
-	w.WriteVarInt(2 + len(filters))
+	w.WriteVarInt(2 + pl + len(filters))
 	w.WriteUint16(pi)
+	if pl > 0 {
+		w.WriteByte(0)
+	}
+	// The granted QoS and the failure code are also the MQTT v5 reason codes.
 	for _, f := range filters {
 		w.WriteByte(f.qos)
 	}
@@ -5429,15 +6352,15 @@ func (c *client) mqttProcessUnsubs(filters []*mqttFilter) error {
 		}
 	}
 	for _, f := range filters {
-		sid := f.filter
+		sid := f.sid()
 		// Remove JS Consumer if one exists for this sid
 		removeJSCons(sid)
 		if err := c.processUnsub([]byte(sid)); err != nil {
 			c.Errorf("error unsubscribing from %q: %v", sid, err)
 		}
-		if mqttNeedSubForLevelUp(sid) {
-			subject := sid[:len(sid)-2]
-			sid = subject + mqttMultiLevelSidSuffix
+		if mqttNeedSubForLevelUp(f.filter) {
+			subject := f.filter[:len(f.filter)-2]
+			sid = sid[:len(sid)-2] + mqttMultiLevelSidSuffix
 			removeJSCons(sid)
 			if err := c.processUnsub([]byte(sid)); err != nil {
 				c.Errorf("error unsubscribing from %q: %v", subject, err)
@@ -5447,11 +6370,21 @@ func (c *client) mqttProcessUnsubs(filters []*mqttFilter) error {
 	return sess.update(filters, false)
 }
 
-func (c *client) mqttEnqueueUnsubAck(pi uint16) {
-	w := newMQTTWriter(4)
+func (c *client) mqttEnqueueUnsubAck(pi uint16, filters []*mqttFilter) {
+	w := newMQTTWriter(5 + len(filters))
 	w.WriteByte(mqttPacketUnsubAck)
-	w.WriteVarInt(2)
-	w.WriteUint16(pi)
+	if c.mqtt.v5 {
+		// Packet identifier, empty properties and a reason code per filter.
+		w.WriteVarInt(3 + len(filters))
+		w.WriteUint16(pi)
+		w.WriteByte(0)
+		for range filters {
+			w.WriteByte(mqttReasonSuccess)
+		}
+	} else {
+		w.WriteVarInt(2)
+		w.WriteUint16(pi)
+	}
 	c.mu.Lock()
 	c.enqueueProto(w.Bytes())
 	c.mu.Unlock()
@@ -5760,6 +6693,115 @@ func (r *mqttReader) readUint16(field string) (uint16, error) {
 	return binary.BigEndian.Uint16(r.buf[start:r.pos]), nil
 }
 
+func (r *mqttReader) readUint32(field string) (uint32, error) {
+	if len(r.buf)-r.pos < 4 {
+		return 0, fmt.Errorf("error reading %s: %v", field, io.ErrUnexpectedEOF)
+	}
+	start := r.pos
+	r.pos += 4
+	return binary.BigEndian.Uint32(r.buf[start:r.pos]), nil
+}
+
+// Reads a variable byte integer that is part of a packet (as opposed to the
+// packet length), so the whole packet is known to be in the buffer.
+func (r *mqttReader) readVarInt(field string) (int, error) {
+	m := 1
+	v := 0
+	for {
+		b, err := r.readByte(field)
+		if err != nil {
+			return 0, err
+		}
+		v += int(b&0x7f) * m
+		if (b & 0x80) == 0 {
+			return v, nil
+		}
+		m *= 0x80
+		if m > 0x200000 {
+			return 0, errMQTTMalformedVarInt
+		}
+	}
+}
+
+// Reads the properties of a MQTT v5 packet. The `packet` is used for error
+// reporting. Properties that are not relevant to this server are skipped.
+// Spec v5 [2.2.2]
+func (r *mqttReader) readProperties(packet string) (*mqttProperties, error) {
+	pl, err := r.readVarInt(packet + " properties length")
+	if err != nil {
+		return nil, err
+	}
+	end := r.pos + pl
+	if end > len(r.buf) {
+		return nil, fmt.Errorf("error reading %s properties: %v", packet, io.ErrUnexpectedEOF)
+	}
+	p := &mqttProperties{}
+	for err == nil && r.pos < end {
+		var id byte
+		if id, err = r.readByte(packet + " property identifier"); err != nil {
+			break
+		}
+		switch id {
+		case mqttPropPayloadFormat:
+			p.payloadFormat, err = r.readByte("payload format indicator")
+		case mqttPropMessageExpiry:
+			p.msgExpiry, err = r.readUint32("message expiry interval")
+		case mqttPropContentType:
+			p.contentType, err = r.readString("content type")
+		case mqttPropResponseTopic:
+			p.responseTopic, err = r.readBytes("response topic", false)
+		case mqttPropCorrelationData:
+			p.correlationData, err = r.readBytes("correlation data", false)
+		case mqttPropSubscriptionID:
+			p.subIDs = true
+			_, err = r.readVarInt("subscription identifier")
+		case mqttPropSessionExpiry:
+			p.hasSessExpiry = true
+			p.sessExpiry, err = r.readUint32("session expiry interval")
+		case mqttPropAssignedClientID:
+			p.assignedCID, err = r.readString("assigned client identifier")
+		case mqttPropAuthMethod:
+			p.authMethod, err = r.readString("authentication method")
+		case mqttPropAuthData:
+			_, err = r.readBytes("authentication data", false)
+		case mqttPropRequestProblemInfo, mqttPropRequestResponseInfo:
+			_, err = r.readByte("request information")
+		case mqttPropWillDelay, mqttPropMaximumPacketSize:
+			_, err = r.readUint32(packet + " property")
+		case mqttPropReasonString:
+			p.reasonString, err = r.readString("reason string")
+		case mqttPropReceiveMaximum:
+			p.receiveMax, err = r.readUint16("receive maximum")
+		case mqttPropTopicAliasMaximum:
+			p.topicAliasMax, err = r.readUint16("topic alias maximum")
+		case mqttPropTopicAlias:
+			p.topicAlias, err = r.readUint16("topic alias")
+		case mqttPropMaximumQoS:
+			p.hasMaxQoS = true
+			p.maxQoS, err = r.readByte("maximum QoS")
+		case mqttPropSubIDAvailable:
+			p.hasSubIDAvail = true
+			p.subIDAvailable, err = r.readByte("subscription identifiers available")
+		case mqttPropUserProperty:
+			var up mqttUserProperty
+			if up.key, err = r.readString("user property key"); err == nil {
+				if up.value, err = r.readString("user property value"); err == nil {
+					p.userProps = append(p.userProps, up)
+				}
+			}
+		default:
+			err = fmt.Errorf("%s property %#x: %v", packet, id, errMQTTMalformedProperties)
+		}
+	}
+	if err == nil && r.pos != end {
+		err = fmt.Errorf("%s properties: %v", packet, errMQTTMalformedProperties)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
 //////////////////////////////////////////////////////////////////////////////
 //
 // MQTT Writer functions
@@ -5771,6 +6813,11 @@ func (w *mqttWriter) WriteUint16(i uint16) {
 	w.WriteByte(byte(i))
 }
 
+func (w *mqttWriter) WriteUint32(i uint32) {
+	w.WriteUint16(uint16(i >> 16))
+	w.WriteUint16(uint16(i))
+}
+
 func (w *mqttWriter) WriteString(s string) {
 	w.WriteBytes([]byte(s))
 }
@@ -5799,3 +6846,69 @@ func newMQTTWriter(cap int) *mqttWriter {
 	w.Grow(cap)
 	return w
 }
+
+// Encodes the MQTT v5 properties, including the properties length. A nil `p`
+// results in an empty property list.
+func mqttEncodeProperties(p *mqttProperties) []byte {
+	if p == nil {
+		return []byte{0}
+	}
+	w := newMQTTWriter(32)
+	if p.payloadFormat != 0 {
+		w.WriteByte(mqttPropPayloadFormat)
+		w.WriteByte(p.payloadFormat)
+	}
+	if p.msgExpiry > 0 {
+		w.WriteByte(mqttPropMessageExpiry)
+		w.WriteUint32(p.msgExpiry)
+	}
+	if p.contentType != _EMPTY_ {
+		w.WriteByte(mqttPropContentType)
+		w.WriteString(p.contentType)
+	}
+	if len(p.responseTopic) > 0 {
+		w.WriteByte(mqttPropResponseTopic)
+		w.WriteBytes(p.responseTopic)
+	}
+	if len(p.correlationData) > 0 {
+		w.WriteByte(mqttPropCorrelationData)
+		w.WriteBytes(p.correlationData)
+	}
+	if p.hasSessExpiry {
+		w.WriteByte(mqttPropSessionExpiry)
+		w.WriteUint32(p.sessExpiry)
+	}
+	if p.assignedCID != _EMPTY_ {
+		w.WriteByte(mqttPropAssignedClientID)
+		w.WriteString(p.assignedCID)
+	}
+	if p.topicAliasMax > 0 {
+		w.WriteByte(mqttPropTopicAliasMaximum)
+		w.WriteUint16(p.topicAliasMax)
+	}
+	if p.topicAlias > 0 {
+		w.WriteByte(mqttPropTopicAlias)
+		w.WriteUint16(p.topicAlias)
+	}
+	if p.hasMaxQoS {
+		w.WriteByte(mqttPropMaximumQoS)
+		w.WriteByte(p.maxQoS)
+	}
+	if p.hasSubIDAvail {
+		w.WriteByte(mqttPropSubIDAvailable)
+		w.WriteByte(p.subIDAvailable)
+	}
+	if p.reasonString != _EMPTY_ {
+		w.WriteByte(mqttPropReasonString)
+		w.WriteString(p.reasonString)
+	}
+	for _, up := range p.userProps {
+		w.WriteByte(mqttPropUserProperty)
+		w.WriteString(up.key)
+		w.WriteString(up.value)
+	}
+	out := newMQTTWriter(4 + w.Len())
+	out.WriteVarInt(w.Len())
+	out.Write(w.Bytes())
+	return out.Bytes()
+}
@@ -777,6 +777,8 @@ type mqttConnInfo struct {
 	ws        bool
 	tls       bool
 	tlsc      *tls.Config
+	v5        bool
+	props     *mqttProperties
 }
 
 func testMQTTGetClient(t testing.TB, s *Server, clientID string) *client {
@@ -926,9 +928,19 @@ func mqttCreateConnectProto(ci *mqttConnInfo) []byte {
 		2 + // keepAlive
 		2 + len(ci.clientID)
 
+	level := mqttProtoLevel
+	var props []byte
+	if ci.v5 {
+		level = mqttProtoLevel5
+		props = mqttEncodeProperties(ci.props)
+		pkLen += len(props)
+	}
 	if ci.will != nil {
 		pkLen += 2 + len(ci.will.topic)
 		pkLen += 2 + len(ci.will.message)
+		if ci.v5 {
+			pkLen++ // empty will properties
+		}
 	}
 	if ci.user != _EMPTY_ {
 		pkLen += 2 + len(ci.user)
@@ -941,11 +953,15 @@ func mqttCreateConnectProto(ci *mqttConnInfo) []byte {
 	w.WriteByte(mqttPacketConnect)
 	w.WriteVarInt(pkLen)
 	w.WriteString(string(mqttProtoName))
-	w.WriteByte(0x4)
+	w.WriteByte(level)
 	w.WriteByte(flags)
 	w.WriteUint16(ci.keepAlive)
+	w.Write(props)
 	w.WriteString(ci.clientID)
 	if ci.will != nil {
+		if ci.v5 {
+			w.WriteByte(0)
+		}
 		w.WriteBytes(ci.will.topic)
 		w.WriteBytes(ci.will.message)
 	}
@@ -7713,6 +7729,498 @@ func TestMQTTSparkbBirthHandling(t *testing.T) {
 	})
 }
 
+//////////////////////////////////////////////////////////////////////////
+//
+// MQTT v5 tests
+//
+//////////////////////////////////////////////////////////////////////////
+
+func testMQTTCheckConnAckV5(t testing.TB, r *mqttReader, rc byte, sessionPresent bool) *mqttProperties {
+	t.Helper()
+	b, _ := testMQTTReadPacket(t, r)
+	if pt := b & mqttPacketMask; pt != mqttPacketConnectAck {
+		t.Fatalf("Expected ConnAck (%x), got %x", mqttPacketConnectAck, pt)
+	}
+	caf, err := r.readByte("connack flags")
+	require_NoError(t, err)
+	if sp := caf == 1; sp != sessionPresent {
+		t.Fatalf("Expected session present flag=%v got %v", sessionPresent, sp)
+	}
+	carc, err := r.readByte("connack reason code")
+	require_NoError(t, err)
+	if carc != rc {
+		t.Fatalf("Expected reason code to be %v, got %v", rc, carc)
+	}
+	props, err := r.readProperties("connack")
+	require_NoError(t, err)
+	return props
+}
+
+func testMQTTSubV5(t testing.TB, pi uint16, c net.Conn, r *mqttReader, filters []*mqttFilter, expected []byte) {
+	t.Helper()
+	w := newMQTTWriter(0)
+	pkLen := 2 + 1 // for pi and empty properties
+	for _, f := range filters {
+		pkLen += 2 + len(f.filter) + 1
+	}
+	w.WriteByte(mqttPacketSub | mqttSubscribeFlags)
+	w.WriteVarInt(pkLen)
+	w.WriteUint16(pi)
+	w.WriteByte(0)
+	for _, f := range filters {
+		w.WriteString(f.filter)
+		w.WriteByte(f.qos)
+	}
+	if _, err := testMQTTWrite(c, w.Bytes()); err != nil {
+		t.Fatalf("Error writing SUBSCRIBE protocol: %v", err)
+	}
+	b, pl := testMQTTReadPacket(t, r)
+	if pt := b & mqttPacketMask; pt != mqttPacketSubAck {
+		t.Fatalf("Expected SUBACK packet %x, got %x", mqttPacketSubAck, pt)
+	}
+	start := r.pos
+	rpi, err := r.readUint16("packet identifier")
+	if err != nil || rpi != pi {
+		t.Fatalf("Error with packet identifier expected=%v got: %v err=%v", pi, rpi, err)
+	}
+	_, err = r.readProperties("suback")
+	require_NoError(t, err)
+	rcs := r.buf[r.pos : start+pl]
+	r.pos = start + pl
+	if !bytes.Equal(rcs, expected) {
+		t.Fatalf("Expected reason codes %v, got %v", expected, rcs)
+	}
+}
+
+func testMQTTSendPublishPacketV5(t testing.TB, c net.Conn, qos byte, retain bool, topic string, pi uint16, props *mqttProperties, payload []byte) {
+	t.Helper()
+	_, header := mqttMakePublishHeaderWithProperties(pi, qos, false, retain, []byte(topic), mqttEncodeProperties(props), len(payload))
+	if _, err := testMQTTWrite(c, append(header, payload...)); err != nil {
+		t.Fatalf("Error writing PUBLISH proto: %v", err)
+	}
+}
+
+func testMQTTGetPubMsgV5(t testing.TB, r *mqttReader, topic string, payload []byte) (byte, uint16, *mqttProperties) {
+	t.Helper()
+	b, pl := testMQTTReadPacket(t, r)
+	if pt := b & mqttPacketMask; pt != mqttPacketPub {
+		t.Fatalf("Expected PUBLISH packet %x, got %x", mqttPacketPub, pt)
+	}
+	flags := b & mqttPacketFlagMask
+	start := r.pos
+	ptopic, err := r.readString("topic name")
+	require_NoError(t, err)
+	if ptopic != topic {
+		t.Fatalf("Expected topic %q, got %q", topic, ptopic)
+	}
+	var pi uint16
+	if mqttGetQoS(flags) > 0 {
+		pi, err = r.readUint16("packet identifier")
+		require_NoError(t, err)
+	}
+	props, err := r.readProperties("publish")
+	require_NoError(t, err)
+	ppayload := r.buf[r.pos : start+pl]
+	r.pos = start + pl
+	if !bytes.Equal(ppayload, payload) {
+		t.Fatalf("Expected payload %q, got %q", payload, ppayload)
+	}
+	return flags, pi, props
+}
+
+func TestMQTTV5Connect(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownServer(s)
+
+	// A MQTT v5 client may not provide a client ID, even for a persisted
+	// session, in which case one is assigned by the server.
+	ci := &mqttConnInfo{v5: true, props: &mqttProperties{sessExpiry: 60, hasSessExpiry: true}}
+	mc, r := testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	defer mc.Close()
+	props := testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+	if props.assignedCID == _EMPTY_ {
+		t.Fatal("Expected an assigned client ID")
+	}
+	if props.topicAliasMax != mqttTopicAliasMax {
+		t.Fatalf("Expected topic alias maximum %v, got %v", mqttTopicAliasMax, props.topicAliasMax)
+	}
+	if !props.hasSubIDAvail || props.subIDAvailable != 0 {
+		t.Fatalf("Expected subscription identifiers to be reported as not available, got %+v", props)
+	}
+	testMQTTFlush(t, mc, nil, r)
+
+	// A MQTT v3.1.1 client can connect on the same port.
+	mc3, r3 := testMQTTConnect(t, &mqttConnInfo{clientID: "v3", cleanSess: true}, o.MQTT.Host, o.MQTT.Port)
+	defer mc3.Close()
+	testMQTTCheckConnAck(t, r3, mqttConnAckRCConnectionAccepted, false)
+	testMQTTFlush(t, mc3, nil, r3)
+
+	// Both clients see each other's messages.
+	testMQTTSub(t, 1, mc3, r3, []*mqttFilter{{filter: "v5/foo", qos: 1}}, []byte{1})
+	testMQTTSubV5(t, 1, mc, r, []*mqttFilter{{filter: "v3/foo", qos: 1}}, []byte{1})
+	testMQTTFlush(t, mc, nil, r)
+
+	testMQTTSendPublishPacketV5(t, mc, 0, false, "v5/foo", 0, nil, []byte("from v5"))
+	testMQTTCheckPubMsg(t, mc3, r3, "v5/foo", 0, []byte("from v5"))
+
+	testMQTTPublish(t, mc3, r3, 0, false, false, "v3/foo", 0, []byte("from v3"))
+	testMQTTGetPubMsgV5(t, r, "v3/foo", []byte("from v3"))
+
+	// Subscription identifiers are not supported.
+	w := newMQTTWriter(0)
+	w.WriteByte(mqttPacketSub | mqttSubscribeFlags)
+	w.WriteVarInt(2 + 3 + 2 + 3 + 1)
+	w.WriteUint16(2)
+	w.WriteByte(2)
+	w.WriteByte(mqttPropSubscriptionID)
+	w.WriteByte(1)
+	w.WriteString("foo")
+	w.WriteByte(0)
+	testMQTTWrite(mc, w.Bytes())
+	testMQTTExpectDisconnect(t, mc)
+}
+
+func TestMQTTV5SessionExpiry(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownServer(s)
+
+	ci := &mqttConnInfo{clientID: "sess", v5: true, props: &mqttProperties{sessExpiry: 1, hasSessExpiry: true}}
+	mc, r := testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+	testMQTTSubV5(t, 1, mc, r, []*mqttFilter{{filter: "foo", qos: 1}}, []byte{1})
+	mc.Close()
+
+	// The session is still there right after the client went away.
+	mc, r = testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, true)
+	mc.Close()
+
+	// But is removed once the expiry interval has elapsed.
+	time.Sleep(2 * time.Second)
+	mc, r = testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	defer mc.Close()
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+
+	// A session expiry interval of 0 means that the session ends with the
+	// connection, as for a clean session.
+	ci.clientID = "sess0"
+	ci.props = nil
+	mc0, r0 := testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	testMQTTCheckConnAckV5(t, r0, mqttReasonSuccess, false)
+	testMQTTSubV5(t, 1, mc0, r0, []*mqttFilter{{filter: "foo", qos: 1}}, []byte{1})
+	testMQTTDisconnect(t, mc0, nil)
+	mc0.Close()
+
+	mc0, r0 = testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	defer mc0.Close()
+	testMQTTCheckConnAckV5(t, r0, mqttReasonSuccess, false)
+}
+
+func TestMQTTV5SessionExpiryAfterRestart(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownRestartedServer(&s)
+
+	// A session whose client went away before the restart, and one whose
+	// client was still connected when the server was shut down.
+	ci := &mqttConnInfo{clientID: "gone", v5: true, props: &mqttProperties{sessExpiry: 2, hasSessExpiry: true}}
+	mc, r := testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+	testMQTTSubV5(t, 1, mc, r, []*mqttFilter{{filter: "foo", qos: 1}}, []byte{1})
+	mc.Close()
+
+	ci2 := &mqttConnInfo{clientID: "connected", v5: true, props: &mqttProperties{sessExpiry: 2, hasSessExpiry: true}}
+	mc2, r2 := testMQTTConnect(t, ci2, o.MQTT.Host, o.MQTT.Port)
+	defer mc2.Close()
+	testMQTTCheckConnAckV5(t, r2, mqttReasonSuccess, false)
+	testMQTTSubV5(t, 1, mc2, r2, []*mqttFilter{{filter: "bar", qos: 1}}, []byte{1})
+
+	// Wait for the disconnect time of the first session to be persisted.
+	nc, js := jsClientConnect(t, s)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		sm, err := js.GetLastMsg(mqttSessStreamName, mqttSessStreamSubjectPrefix+getHash("gone"))
+		if err != nil {
+			return err
+		}
+		var ps mqttPersistedSession
+		require_NoError(t, json.Unmarshal(sm.Data, &ps))
+		if ps.Expiry != 2 || ps.Disc == 0 {
+			return fmt.Errorf("disconnect time not persisted: %+v", ps)
+		}
+		return nil
+	})
+	nc.Close()
+
+	dir := strings.TrimSuffix(s.JetStreamConfig().StoreDir, JetStreamStoreDir)
+	s.Shutdown()
+	mc2.Close()
+
+	o.Port = -1
+	o.MQTT.Port = -1
+	o.StoreDir = dir
+	s = testMQTTRunServer(t, o)
+
+	// Sessions are loaded when the account's session manager is created by
+	// any client, and both are removed without their client coming back.
+	oc, or := testMQTTConnect(t, &mqttConnInfo{clientID: "other", cleanSess: true}, o.MQTT.Host, o.MQTT.Port)
+	defer oc.Close()
+	testMQTTCheckConnAck(t, or, mqttConnAckRCConnectionAccepted, false)
+	nc, js = jsClientConnect(t, s)
+	defer nc.Close()
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		for _, id := range []string{"gone", "connected"} {
+			if _, err := js.GetLastMsg(mqttSessStreamName, mqttSessStreamSubjectPrefix+getHash(id)); err == nil {
+				return fmt.Errorf("session %q not removed", id)
+			}
+		}
+		return nil
+	})
+	mc, r = testMQTTConnect(t, ci, o.MQTT.Host, o.MQTT.Port)
+	defer mc.Close()
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+}
+
+func TestMQTTV5PublishProperties(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownServer(s)
+
+	nc := natsConnect(t, s.ClientURL())
+	defer nc.Close()
+	sub := natsSubSync(t, nc, "props.>")
+	natsFlush(t, nc)
+
+	sc, sr := testMQTTConnect(t, &mqttConnInfo{clientID: "sub", cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+	defer sc.Close()
+	testMQTTCheckConnAckV5(t, sr, mqttReasonSuccess, false)
+	testMQTTSubV5(t, 1, sc, sr, []*mqttFilter{{filter: "props/#", qos: 0}}, []byte{0})
+	testMQTTFlush(t, sc, nil, sr)
+
+	sc3, sr3 := testMQTTConnect(t, &mqttConnInfo{clientID: "sub3", cleanSess: true}, o.MQTT.Host, o.MQTT.Port)
+	defer sc3.Close()
+	testMQTTCheckConnAck(t, sr3, mqttConnAckRCConnectionAccepted, false)
+	testMQTTSub(t, 1, sc3, sr3, []*mqttFilter{{filter: "props/#", qos: 0}}, []byte{0})
+	testMQTTFlush(t, sc3, nil, sr3)
+
+	pc, pr := testMQTTConnect(t, &mqttConnInfo{clientID: "pub", cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+	defer pc.Close()
+	testMQTTCheckConnAckV5(t, pr, mqttReasonSuccess, false)
+
+	pubProps := &mqttProperties{
+		payloadFormat:   1,
+		contentType:     "application/json",
+		responseTopic:   []byte("resp/me"),
+		correlationData: []byte{0, 1, 2},
+		userProps: []mqttUserProperty{
+			{"k1", "v1"},
+			{"Nats-Msg-Id", "ignored"},
+			{"bad key", "ignored"},
+		},
+	}
+	testMQTTSendPublishPacketV5(t, pc, 0, false, "props/foo", 0, pubProps, []byte("msg"))
+
+	// NATS subscribers get the properties as headers, and the response
+	// topic as the reply subject.
+	msg := natsNexMsg(t, sub, time.Second)
+	if msg.Reply != "resp.me" {
+		t.Fatalf("Expected reply %q, got %q", "resp.me", msg.Reply)
+	}
+	for k, v := range map[string]string{
+		mqttNatsHeaderContentType: "application/json",
+		mqttNatsHeaderFormat:      "1",
+		"k1":                      "v1",
+		"Nats-Msg-Id":             _EMPTY_,
+	} {
+		if hv := msg.Header.Get(k); hv != v {
+			t.Fatalf("Expected header %q to be %q, got %q", k, v, hv)
+		}
+	}
+
+	// MQTT v5 subscribers get them back as properties.
+	_, _, props := testMQTTGetPubMsgV5(t, sr, "props/foo", []byte("msg"))
+	if props.contentType != "application/json" || props.payloadFormat != 1 ||
+		string(props.responseTopic) != "resp/me" || !bytes.Equal(props.correlationData, []byte{0, 1, 2}) {
+		t.Fatalf("Unexpected properties: %+v", props)
+	}
+	if len(props.userProps) != 1 || props.userProps[0] != (mqttUserProperty{"k1", "v1"}) {
+		t.Fatalf("Unexpected user properties: %+v", props.userProps)
+	}
+
+	// MQTT v3.1.1 subscribers only get the payload.
+	testMQTTCheckPubMsg(t, sc3, sr3, "props/foo", 0, []byte("msg"))
+
+	// Headers and reply subject of NATS messages are converted to properties.
+	hmsg := nats.NewMsg("props.bar")
+	hmsg.Header.Set("k2", "v2")
+	hmsg.Reply = "reply.subj"
+	hmsg.Data = []byte("from nats")
+	require_NoError(t, nc.PublishMsg(hmsg))
+
+	_, _, props = testMQTTGetPubMsgV5(t, sr, "props/bar", []byte("from nats"))
+	if string(props.responseTopic) != "reply/subj" {
+		t.Fatalf("Expected response topic %q, got %q", "reply/subj", props.responseTopic)
+	}
+	if len(props.userProps) != 1 || props.userProps[0] != (mqttUserProperty{"k2", "v2"}) {
+		t.Fatalf("Unexpected user properties: %+v", props.userProps)
+	}
+	testMQTTCheckPubMsg(t, sc3, sr3, "props/bar", 0, []byte("from nats"))
+}
+
+func TestMQTTV5TopicAlias(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownServer(s)
+
+	sc, sr := testMQTTConnect(t, &mqttConnInfo{clientID: "sub", cleanSess: true}, o.MQTT.Host, o.MQTT.Port)
+	defer sc.Close()
+	testMQTTCheckConnAck(t, sr, mqttConnAckRCConnectionAccepted, false)
+	testMQTTSub(t, 1, sc, sr, []*mqttFilter{{filter: "alias/#", qos: 1}}, []byte{1})
+	testMQTTFlush(t, sc, nil, sr)
+
+	pc, pr := testMQTTConnect(t, &mqttConnInfo{clientID: "pub", cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+	defer pc.Close()
+	testMQTTCheckConnAckV5(t, pr, mqttReasonSuccess, false)
+
+	testMQTTSendPublishPacketV5(t, pc, 0, false, "alias/a", 0, &mqttProperties{topicAlias: 1}, []byte("1"))
+	testMQTTCheckPubMsg(t, sc, sr, "alias/a", 0, []byte("1"))
+	testMQTTSendPublishPacketV5(t, pc, 0, false, _EMPTY_, 0, &mqttProperties{topicAlias: 1}, []byte("2"))
+	testMQTTCheckPubMsg(t, sc, sr, "alias/a", 0, []byte("2"))
+
+	// Rebind the alias to another topic.
+	testMQTTSendPublishPacketV5(t, pc, 0, false, "alias/b", 0, &mqttProperties{topicAlias: 1}, []byte("3"))
+	testMQTTCheckPubMsg(t, sc, sr, "alias/b", 0, []byte("3"))
+	testMQTTSendPublishPacketV5(t, pc, 0, false, _EMPTY_, 0, &mqttProperties{topicAlias: 1}, []byte("4"))
+	testMQTTCheckPubMsg(t, sc, sr, "alias/b", 0, []byte("4"))
+
+	// Using an alias that is not bound is a protocol error.
+	testMQTTSendPublishPacketV5(t, pc, 0, false, _EMPTY_, 0, &mqttProperties{topicAlias: 2}, []byte("5"))
+	testMQTTExpectDisconnect(t, pc)
+}
+
+func TestMQTTV5SharedSubscription(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownServer(s)
+
+	for _, qos := range []byte{0, 1} {
+		t.Run(fmt.Sprintf("qos %v", qos), func(t *testing.T) {
+			topic := fmt.Sprintf("shared/%v", qos)
+			var readers []*mqttReader
+			for i := 0; i < 2; i++ {
+				cid := fmt.Sprintf("member%v_%v", qos, i)
+				mc, r := testMQTTConnect(t, &mqttConnInfo{clientID: cid, cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+				defer mc.Close()
+				testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+				testMQTTSubV5(t, 1, mc, r, []*mqttFilter{{filter: "$share/grp/" + topic, qos: qos}}, []byte{qos})
+				testMQTTFlush(t, mc, nil, r)
+				readers = append(readers, r)
+			}
+
+			pc, pr := testMQTTConnect(t, &mqttConnInfo{clientID: "pub", cleanSess: true}, o.MQTT.Host, o.MQTT.Port)
+			defer pc.Close()
+			testMQTTCheckConnAck(t, pr, mqttConnAckRCConnectionAccepted, false)
+
+			const N = 100
+			for i := 0; i < N; i++ {
+				testMQTTPublish(t, pc, pr, qos, false, false, topic, uint16(i+1), []byte("msg"))
+			}
+
+			// Counts the PUBLISH packets received until the connection is idle.
+			countPubs := func(r *mqttReader) int {
+				var buf []byte
+				var tmp [4096]byte
+				for {
+					r.reader.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+					n, err := r.reader.Read(tmp[:])
+					if err != nil {
+						break
+					}
+					buf = append(buf, tmp[:n]...)
+				}
+				r.reader.SetReadDeadline(time.Time{})
+				r.reset(buf)
+				var pubs int
+				for r.hasMore() {
+					b, err := r.readByte("packet type")
+					require_NoError(t, err)
+					pl, _, err := r.readPacketLen()
+					require_NoError(t, err)
+					if b&mqttPacketMask == mqttPacketPub {
+						pubs++
+					}
+					r.pos += pl
+				}
+				return pubs
+			}
+
+			// Each message is delivered to only one of the group members.
+			n1, n2 := countPubs(readers[0]), countPubs(readers[1])
+			if n1+n2 != N || n1 == 0 || n2 == 0 {
+				t.Fatalf("Expected %v messages shared by both members, got %v and %v", N, n1, n2)
+			}
+		})
+	}
+
+	// No local is not allowed for shared subscriptions.
+	mc, r := testMQTTConnect(t, &mqttConnInfo{clientID: "nolocal", cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+	defer mc.Close()
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+	w := newMQTTWriter(0)
+	filter := "$share/grp/foo"
+	w.WriteByte(mqttPacketSub | mqttSubscribeFlags)
+	w.WriteVarInt(2 + 1 + 2 + len(filter) + 1)
+	w.WriteUint16(1)
+	w.WriteByte(0)
+	w.WriteString(filter)
+	w.WriteByte(mqttSubOptNoLocal)
+	testMQTTWrite(mc, w.Bytes())
+	testMQTTExpectDisconnect(t, mc)
+}
+
+func TestMQTTV5MessageExpiry(t *testing.T) {
+	o := testMQTTDefaultOptions()
+	s := testMQTTRunServer(t, o)
+	defer testMQTTShutdownServer(s)
+
+	pc, pr := testMQTTConnect(t, &mqttConnInfo{clientID: "pub", cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+	defer pc.Close()
+	testMQTTCheckConnAckV5(t, pr, mqttReasonSuccess, false)
+
+	testMQTTSendPublishPacketV5(t, pc, 1, true, "exp/long", 1, &mqttProperties{msgExpiry: 60}, []byte("long"))
+	testMQTTCheckPubAck(t, pr, mqttPacketPubAck)
+	testMQTTSendPublishPacketV5(t, pc, 1, true, "exp/short", 2, &mqttProperties{msgExpiry: 1}, []byte("short"))
+	testMQTTCheckPubAck(t, pr, mqttPacketPubAck)
+
+	// The retained messages are stored with the JetStream per-message TTL.
+	nc, js := jsClientConnect(t, s)
+	defer nc.Close()
+	rm, err := js.GetLastMsg(mqttRetainedMsgsStreamName, mqttRetainedMsgsStreamSubject+"exp.long")
+	require_NoError(t, err)
+	if ttl := rm.Header.Get(JSMessageTTL); ttl != "60" {
+		t.Fatalf("Expected TTL header to be %q, got %q", "60", ttl)
+	}
+
+	// Wait for the short one to expire. It should not be delivered, while the
+	// other is, with a reduced message expiry interval.
+	time.Sleep(1500 * time.Millisecond)
+
+	mc, r := testMQTTConnect(t, &mqttConnInfo{clientID: "sub", cleanSess: true, v5: true}, o.MQTT.Host, o.MQTT.Port)
+	defer mc.Close()
+	testMQTTCheckConnAckV5(t, r, mqttReasonSuccess, false)
+	testMQTTSubV5(t, 1, mc, r, []*mqttFilter{{filter: "exp/#", qos: 1}}, []byte{1})
+	flags, pi, props := testMQTTGetPubMsgV5(t, r, "exp/long", []byte("long"))
+	if !mqttIsRetained(flags) {
+		t.Fatal("Expected retained flag to be set")
+	}
+	if props.msgExpiry == 0 || props.msgExpiry >= 60 {
+		t.Fatalf("Expected message expiry to be reduced, got %v", props.msgExpiry)
+	}
+	testMQTTSendPIPacket(mqttPacketPubAck, t, mc, pi)
+	testMQTTFlush(t, mc, nil, r)
+}
+
 //////////////////////////////////////////////////////////////////////////
 //
 // Benchmarks
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"slices"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -29,6 +30,9 @@ const (
 	MsgTraceHop           = "Nats-Trace-Hop"
 	MsgTraceOriginAccount = "Nats-Trace-Origin-Account"
 	MsgTraceOnly          = "Nats-Trace-Only"
+	// Set by the origin server of an external trace when routing the message,
+	// with the outputs it sampled the trace for, so that remotes do the same.
+	MsgTraceSampled = "Nats-Trace-Sampled"
 
 	// External trace header. Note that this header is normally in lower
 	// case (https://www.w3.org/TR/trace-context/#header-name). Vendors
@@ -258,10 +262,18 @@ type msgTrace struct {
 	js    *MsgTraceJetStream
 	hop   string
 	nhop  string
-	tonly bool // Will only trace the message, not do delivery.
+	tonly bool   // Will only trace the message, not do delivery.
+	otlp  bool   // Will export the event as an OTLP span.
+	smp   string // Outputs the origin server sampled an external trace for.
 	ct    compressionType
 }
 
+// Values of the MsgTraceSampled header.
+const (
+	msgTraceSampledDest = "dest"
+	msgTraceSampledOTLP = "otlp"
+)
+
 // This will be false outside of the tests, so when building the server binary,
 // any code where you see `if msgTraceRunInTests` statement will be compiled
 // out, so this will have no performance penalty.
@@ -436,21 +448,55 @@ func (c *client) initMsgTrace() *msgTrace {
 	}
 	// If external, we need to have the account's trace destination set,
 	// otherwise, we are not enabling tracing.
+	// Sampling of the OTLP export, which is 0 if the export is not enabled.
+	otlpSampling := c.srv.msgTraceOTLPSampling(acc)
+	otlp := otlpSampling > 0
+	var smp string
 	if external {
 		var sampling int
 		if acc != nil {
 			dest, sampling = acc.getTraceDestAndSampling()
 		}
-		if dest == _EMPTY_ {
-			// No account destination, no tracing for external trace headers.
+		if dest == _EMPTY_ && !otlp {
+			// No account destination nor OTLP export, no tracing for
+			// external trace headers.
 			return nil
 		}
 		// Check sampling, but only from origin server.
-		if c.kind == CLIENT && !sample(sampling) {
-			// Need to desactivate the traceParentHdr so that if the message
-			// is routed, it does possibly trigger a trace there.
-			disableTraceHeaders(c, hdr)
-			return nil
+		if c.kind == CLIENT {
+			if dest != _EMPTY_ && !sample(sampling) {
+				dest = _EMPTY_
+			}
+			if otlp && !sample(otlpSampling) {
+				otlp = false
+			}
+			if dest == _EMPTY_ && !otlp {
+				// Need to desactivate the traceParentHdr so that if the message
+				// is routed, it does possibly trigger a trace there.
+				disableTraceHeaders(c, hdr)
+				return nil
+			}
+			// Remotes follow our decisions, even if they sample differently.
+			var sampled []string
+			if dest != _EMPTY_ {
+				sampled = append(sampled, msgTraceSampledDest)
+			}
+			if otlp {
+				sampled = append(sampled, msgTraceSampledOTLP)
+			}
+			smp = strings.Join(sampled, ",")
+		} else if smp = getHdrVal(MsgTraceSampled); smp != _EMPTY_ {
+			// Otherwise the origin server is older and we trace as configured.
+			sampled := strings.Split(smp, ",")
+			if !slices.Contains(sampled, msgTraceSampledDest) {
+				dest = _EMPTY_
+			}
+			if !slices.Contains(sampled, msgTraceSampledOTLP) {
+				otlp = false
+			}
+			if dest == _EMPTY_ && !otlp {
+				return nil
+			}
 		}
 	}
 	c.pa.trace = &msgTrace{
@@ -478,6 +524,8 @@ func (c *client) initMsgTrace() *msgTrace {
 			}),
 		},
 		tonly: traceOnly,
+		otlp:  otlp,
+		smp:   smp,
 	}
 	return c.pa.trace
 }
@@ -645,6 +693,9 @@ func (t *msgTrace) setHopHeader(c *client, msg []byte) []byte {
 	} else {
 		t.nhop = fmt.Sprintf("%d", e.Hops)
 	}
+	if t.smp != _EMPTY_ {
+		msg = c.setHeader(MsgTraceSampled, t.smp, msg)
+	}
 	return c.setHeader(MsgTraceHop, t.nhop, msg)
 }
 
@@ -842,5 +893,13 @@ func (t *msgTrace) sendEvent() {
 			return
 		}
 	}
+	if t.otlp {
+		t.exportOTLPSpan()
+	}
+	// With external trace headers, the account destination may not
+	// be set if the trace is only exported as an OTLP span.
+	if t.dest == _EMPTY_ {
+		return
+	}
 	t.srv.sendInternalAccountSysMsg(t.acc, t.dest, &t.event.Server, t.event, t.ct)
 }
@@ -0,0 +1,510 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MsgTraceOTLPOpts configures the export of message trace events as
+// OpenTelemetry spans to a collector using OTLP/HTTP with JSON encoding.
+type MsgTraceOTLPOpts struct {
+	// Endpoint is the full URL of the collector traces endpoint,
+	// for instance "http://localhost:4318/v1/traces".
+	Endpoint string
+	// Headers are added to every export request (e.g. for authentication).
+	Headers map[string]string
+	// ServiceName is the value of the "service.name" resource attribute.
+	ServiceName string
+	// Sampling is the default percentage [1..100] of sampled traces exported
+	// for accounts that do not set their own "otlp_sampling".
+	Sampling int
+	// Timeout of a single export request.
+	Timeout time.Duration
+	// MaxBatch is the maximum number of spans sent in one export request.
+	MaxBatch int
+	// FlushInterval is how often pending spans are exported.
+	FlushInterval time.Duration
+}
+
+const (
+	otlpDefaultServiceName   = "nats-server"
+	otlpDefaultTimeout       = 10 * time.Second
+	otlpDefaultMaxBatch      = 512
+	otlpDefaultFlushInterval = time.Second
+	// Spans are dropped if this many are waiting to be exported.
+	otlpMaxPending = 64 * 1024
+)
+
+// Span kinds and status codes as defined by the OTLP protocol.
+const (
+	otlpSpanKindServer   = 2
+	otlpSpanKindConsumer = 5
+
+	otlpStatusCodeError = 2
+)
+
+// Message trace statistics of the OTLP exporter.
+type MsgTraceOTLPStats struct {
+	Exported uint64 `json:"exported"`
+	Dropped  uint64 `json:"dropped"`
+	Failed   uint64 `json:"failed"`
+}
+
+type otlpExporter struct {
+	srv      *Server
+	opts     MsgTraceOTLPOpts
+	hc       *http.Client
+	spans    *ipQueue[*otlpSpan]
+	exported atomic.Uint64
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+}
+
+// The types below mirror the OTLP/JSON encoding of ExportTraceServiceRequest.
+// Trace and span IDs are hex encoded, 64 bit integers are sent as strings.
+
+type otlpExportRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+func otlpString(k, v string) otlpKeyValue {
+	return otlpKeyValue{Key: k, Value: otlpValue{StringValue: &v}}
+}
+
+func otlpInt(k string, v int64) otlpKeyValue {
+	s := strconv.FormatInt(v, 10)
+	return otlpKeyValue{Key: k, Value: otlpValue{IntValue: &s}}
+}
+
+func otlpBool(k string, v bool) otlpKeyValue {
+	return otlpKeyValue{Key: k, Value: otlpValue{BoolValue: &v}}
+}
+
+func otlpKindString(kind int) string {
+	if s, ok := kindStringMap[kind]; ok {
+		return s
+	}
+	return "Unknown Type"
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (o *MsgTraceOTLPOpts) validate() error {
+	if o.Endpoint == _EMPTY_ {
+		return fmt.Errorf("message trace OTLP endpoint is required")
+	}
+	if !strings.HasPrefix(o.Endpoint, "http://") && !strings.HasPrefix(o.Endpoint, "https://") {
+		return fmt.Errorf("message trace OTLP endpoint %q must be an http or https URL", o.Endpoint)
+	}
+	if o.Sampling < 0 || o.Sampling > 100 {
+		return fmt.Errorf("message trace OTLP sampling value %d is invalid, needs to be [1..100]", o.Sampling)
+	}
+	if o.Timeout < 0 || o.FlushInterval < 0 || o.MaxBatch < 0 {
+		return fmt.Errorf("message trace OTLP timeout, flush interval and max batch can not be negative")
+	}
+	return nil
+}
+
+// Creates and starts the OTLP exporter if configured.
+func (s *Server) startMsgTraceOTLPExporter() {
+	opts := s.getOpts().MsgTraceOTLP
+	if opts == nil {
+		return
+	}
+	e := &otlpExporter{srv: s, opts: *opts}
+	if e.opts.ServiceName == _EMPTY_ {
+		e.opts.ServiceName = otlpDefaultServiceName
+	}
+	if e.opts.Sampling == 0 {
+		e.opts.Sampling = 100
+	}
+	if e.opts.Timeout == 0 {
+		e.opts.Timeout = otlpDefaultTimeout
+	}
+	if e.opts.MaxBatch == 0 {
+		e.opts.MaxBatch = otlpDefaultMaxBatch
+	}
+	if e.opts.FlushInterval == 0 {
+		e.opts.FlushInterval = otlpDefaultFlushInterval
+	}
+	e.hc = &http.Client{Timeout: e.opts.Timeout}
+	e.spans = newIPQueue[*otlpSpan](s, "Message trace OTLP spans", ipqLimitByLen[*otlpSpan](otlpMaxPending))
+
+	s.otlp.Store(e)
+
+	s.Noticef("Exporting message traces as OTLP spans to %s", e.opts.Endpoint)
+	s.startGoRoutine(e.exportLoop)
+}
+
+func (s *Server) msgTraceOTLPExporter() *otlpExporter {
+	return s.otlp.Load()
+}
+
+// Returns the percentage of sampled traces to export for this account,
+// or 0 if OTLP export is not enabled.
+func (s *Server) msgTraceOTLPSampling(acc *Account) int {
+	if s == nil {
+		return 0
+	}
+	e := s.msgTraceOTLPExporter()
+	if e == nil {
+		return 0
+	}
+	if acc != nil {
+		acc.mu.RLock()
+		sampling := acc.traceOTLPSampling
+		acc.mu.RUnlock()
+		if sampling > 0 {
+			return sampling
+		}
+	}
+	return e.opts.Sampling
+}
+
+// MsgTraceOTLPStats returns the statistics of the OTLP exporter,
+// or nil if it is not enabled.
+func (s *Server) MsgTraceOTLPStats() *MsgTraceOTLPStats {
+	e := s.msgTraceOTLPExporter()
+	if e == nil {
+		return nil
+	}
+	return &MsgTraceOTLPStats{
+		Exported: e.exported.Load(),
+		Dropped:  e.dropped.Load(),
+		Failed:   e.failed.Load(),
+	}
+}
+
+func (e *otlpExporter) exportLoop() {
+	s := e.srv
+	defer s.grWG.Done()
+	defer e.spans.unregister()
+
+	ticker := time.NewTicker(e.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var pending []*otlpSpan
+	for {
+		select {
+		case <-s.quitCh:
+			// Best effort to not lose what we already have.
+			pending = append(pending, e.spans.pop()...)
+			e.export(pending)
+			return
+		case <-e.spans.ch:
+			spans := e.spans.pop()
+			pending = append(pending, spans...)
+			e.spans.recycle(&spans)
+			if len(pending) < e.opts.MaxBatch {
+				continue
+			}
+		case <-ticker.C:
+		}
+		for len(pending) > 0 {
+			n := min(len(pending), e.opts.MaxBatch)
+			e.export(pending[:n])
+			pending = pending[n:]
+		}
+		pending = nil
+	}
+}
+
+func (e *otlpExporter) export(spans []*otlpSpan) {
+	if len(spans) == 0 {
+		return
+	}
+	s := e.srv
+	req := &otlpExportRequest{
+		ResourceSpans: []*otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []otlpKeyValue{
+				otlpString("service.name", e.opts.ServiceName),
+				otlpString("service.instance.id", s.ID()),
+				otlpString("service.version", VERSION),
+			}},
+			ScopeSpans: []*otlpScopeSpans{{
+				Scope: otlpScope{Name: "nats-server/msgtrace", Version: VERSION},
+				Spans: spans,
+			}},
+		}},
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		e.failed.Add(uint64(len(spans)))
+		s.Warnf("Unable to encode message trace OTLP spans: %v", err)
+		return
+	}
+	hreq, err := http.NewRequest(http.MethodPost, e.opts.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		e.failed.Add(uint64(len(spans)))
+		s.Warnf("Unable to create message trace OTLP request: %v", err)
+		return
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		hreq.Header.Set(k, v)
+	}
+	resp, err := e.hc.Do(hreq)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			err = fmt.Errorf("unexpected status %q", resp.Status)
+		}
+	}
+	if err != nil {
+		e.failed.Add(uint64(len(spans)))
+		s.RateLimitWarnf("Unable to export message trace OTLP spans to %s: %v", e.opts.Endpoint, err)
+		return
+	}
+	e.exported.Add(uint64(len(spans)))
+}
+
+// Parses a W3C traceparent value and returns the trace and parent IDs, and
+// whether the caller sampled the trace.
+func parseTraceParent(tp string) (string, string, bool, bool) {
+	tk := strings.Split(strings.TrimSpace(tp), "-")
+	if len(tk) != 4 || len(tk[0]) != 2 || len(tk[1]) != 32 || len(tk[2]) != 16 || len(tk[3]) != 2 {
+		return _EMPTY_, _EMPTY_, false, false
+	}
+	flags, err := hex.DecodeString(tk[3])
+	if err != nil {
+		return _EMPTY_, _EMPTY_, false, false
+	}
+	traceID, parentID := strings.ToLower(tk[1]), strings.ToLower(tk[2])
+	if _, err := hex.DecodeString(traceID); err != nil {
+		return _EMPTY_, _EMPTY_, false, false
+	}
+	if _, err := hex.DecodeString(parentID); err != nil {
+		return _EMPTY_, _EMPTY_, false, false
+	}
+	// All zeroes are invalid IDs.
+	if strings.Trim(traceID, "0") == _EMPTY_ || strings.Trim(parentID, "0") == _EMPTY_ {
+		return _EMPTY_, _EMPTY_, false, false
+	}
+	return traceID, parentID, flags[0]&0x1 == 0x1, true
+}
+
+// The span ID of a server's hop is derived from the trace ID and the value of the
+// Nats-Trace-Hop header, which is how the server that routed the message to us
+// computed the span ID of its own hop, making us a child of it without having
+// to rewrite the traceparent header.
+func msgTraceSpanID(traceID, hop string) string {
+	h := sha256.New()
+	h.Write([]byte(traceID))
+	h.Write([]byte{'|'})
+	h.Write([]byte(hop))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Returns the hop that sent a message with the given hop, that is "1.2" for
+// "1.2.3", or the empty string (origin server) for "1".
+func msgTraceParentHop(hop string) string {
+	if i := strings.LastIndexByte(hop, '.'); i >= 0 {
+		return hop[:i]
+	}
+	return _EMPTY_
+}
+
+// Converts the trace event of this hop into an OTLP span. Returns nil if the
+// message does not carry a valid traceparent header, or the caller did not sample it.
+func (t *msgTrace) otlpSpan() *otlpSpan {
+	e := t.event
+	vv := e.Request.Header[traceParentHdr]
+	if len(vv) == 0 {
+		return nil
+	}
+	traceID, parentID, sampled, ok := parseTraceParent(vv[0])
+	if !ok || !sampled {
+		return nil
+	}
+	if t.hop != _EMPTY_ {
+		parentID = msgTraceSpanID(traceID, msgTraceParentHop(t.hop))
+	}
+	s := t.srv
+	in := e.Ingress()
+	if in == nil {
+		return nil
+	}
+	span := &otlpSpan{
+		TraceID:           traceID,
+		SpanID:            msgTraceSpanID(traceID, t.hop),
+		ParentSpanID:      parentID,
+		Name:              fmt.Sprintf("process %s", in.Subject),
+		Kind:              otlpSpanKindServer,
+		StartTimeUnixNano: otlpTime(in.Timestamp),
+		EndTimeUnixNano:   otlpTime(time.Now()),
+		Attributes: []otlpKeyValue{
+			otlpString("messaging.system", "nats"),
+			otlpString("messaging.operation.type", "process"),
+			otlpString("messaging.destination.name", in.Subject),
+			otlpInt("messaging.message.body.size", int64(e.Request.MsgSize)),
+			otlpString("nats.server.id", s.ID()),
+			otlpString("nats.server.name", s.Name()),
+			otlpString("nats.account", in.Account),
+			otlpString("nats.ingress.kind", otlpKindString(in.Kind)),
+			otlpString("nats.ingress.name", in.Name),
+			otlpInt("nats.ingress.cid", int64(in.CID)),
+		},
+	}
+	if in.Kind != CLIENT {
+		span.Kind = otlpSpanKindConsumer
+	}
+	if cn := s.ClusterName(); cn != _EMPTY_ {
+		span.Attributes = append(span.Attributes, otlpString("nats.cluster", cn))
+	}
+	if t.hop != _EMPTY_ {
+		span.Attributes = append(span.Attributes, otlpString("nats.hop", t.hop))
+	}
+	var errs []string
+	if in.Error != _EMPTY_ {
+		errs = append(errs, in.Error)
+	}
+	for _, ev := range e.Events {
+		var oe otlpEvent
+		switch ev := ev.(type) {
+		case *MsgTraceSubjectMapping:
+			oe = otlpEvent{TimeUnixNano: otlpTime(ev.Timestamp), Name: "subject_mapping",
+				Attributes: []otlpKeyValue{otlpString("nats.mapped_to", ev.MappedTo)}}
+		case *MsgTraceStreamExport:
+			oe = otlpEvent{TimeUnixNano: otlpTime(ev.Timestamp), Name: "stream_export",
+				Attributes: []otlpKeyValue{otlpString("nats.account", ev.Account), otlpString("nats.to", ev.To)}}
+		case *MsgTraceServiceImport:
+			oe = otlpEvent{TimeUnixNano: otlpTime(ev.Timestamp), Name: "service_import",
+				Attributes: []otlpKeyValue{otlpString("nats.account", ev.Account),
+					otlpString("nats.from", ev.From), otlpString("nats.to", ev.To)}}
+		case *MsgTraceJetStream:
+			oe = otlpEvent{TimeUnixNano: otlpTime(ev.Timestamp), Name: "jetstream",
+				Attributes: []otlpKeyValue{otlpString("nats.stream", ev.Stream)}}
+			if ev.Subject != _EMPTY_ {
+				oe.Attributes = append(oe.Attributes, otlpString("nats.subject", ev.Subject))
+			}
+			if ev.NoInterest {
+				oe.Attributes = append(oe.Attributes, otlpBool("nats.no_interest", true))
+			}
+			if ev.Error != _EMPTY_ {
+				oe.Attributes = append(oe.Attributes, otlpString("error.message", ev.Error))
+				errs = append(errs, ev.Error)
+			}
+		case *MsgTraceEgress:
+			oe = otlpEvent{TimeUnixNano: otlpTime(ev.Timestamp), Name: "egress",
+				Attributes: []otlpKeyValue{otlpString("nats.egress.kind", otlpKindString(ev.Kind)),
+					otlpString("nats.egress.name", ev.Name), otlpInt("nats.egress.cid", int64(ev.CID))}}
+			if ev.Hop != _EMPTY_ {
+				// This is the span ID of the remote server's hop, so that
+				// backends can link the egress to the child span.
+				oe.Attributes = append(oe.Attributes, otlpString("nats.hop", ev.Hop),
+					otlpString("nats.hop.span_id", msgTraceSpanID(traceID, ev.Hop)))
+			}
+			if ev.Account != _EMPTY_ {
+				oe.Attributes = append(oe.Attributes, otlpString("nats.account", ev.Account))
+			}
+			if ev.Subscription != _EMPTY_ {
+				oe.Attributes = append(oe.Attributes, otlpString("nats.subscription", ev.Subscription))
+			}
+			if ev.Queue != _EMPTY_ {
+				oe.Attributes = append(oe.Attributes, otlpString("nats.queue", ev.Queue))
+			}
+			if ev.Error != _EMPTY_ {
+				oe.Attributes = append(oe.Attributes, otlpString("error.message", ev.Error))
+			}
+		default:
+			continue
+		}
+		span.Events = append(span.Events, oe)
+	}
+	if len(errs) > 0 {
+		span.Status = &otlpStatus{Code: otlpStatusCodeError, Message: strings.Join(errs, "; ")}
+	}
+	return span
+}
+
+// Queues the span of this hop for export.
+func (t *msgTrace) exportOTLPSpan() {
+	e := t.srv.msgTraceOTLPExporter()
+	if e == nil {
+		return
+	}
+	span := t.otlpSpan()
+	if span == nil {
+		return
+	}
+	if _, err := e.spans.push(span); err != nil {
+		e.dropped.Add(1)
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skip_msgtrace_tests
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+type testOTLPCollector struct {
+	sync.Mutex
+	ts    *httptest.Server
+	hdrs  http.Header
+	spans []*otlpSpan
+}
+
+func newTestOTLPCollector(t *testing.T) *testOTLPCollector {
+	t.Helper()
+	c := &testOTLPCollector{}
+	c.ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req otlpExportRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.Lock()
+		c.hdrs = r.Header.Clone()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+		c.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(c.ts.Close)
+	return c
+}
+
+func (c *testOTLPCollector) waitForSpans(t *testing.T, n int) []*otlpSpan {
+	t.Helper()
+	var spans []*otlpSpan
+	checkFor(t, 2*time.Second, 15*time.Millisecond, func() error {
+		c.Lock()
+		defer c.Unlock()
+		if len(c.spans) != n {
+			return fmt.Errorf("expected %d spans, got %d", n, len(c.spans))
+		}
+		spans = append([]*otlpSpan(nil), c.spans...)
+		return nil
+	})
+	return spans
+}
+
+func TestMsgTraceOTLPParseConfig(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		port: -1
+		msg_trace_otlp {
+			endpoint: "http://127.0.0.1:4318/v1/traces"
+			headers: { "x-api-key": "secret" }
+			service_name: "edge"
+			sampling: "50%"
+			timeout: "2s"
+			flush_interval: "100ms"
+			max_batch: 10
+		}
+		accounts {
+			A { users: [{user: A, password: pwd}], msg_trace: { otlp_sampling: 25 } }
+		}
+	`))
+	o, err := ProcessConfigFile(conf)
+	require_NoError(t, err)
+	require_True(t, o.MsgTraceOTLP != nil)
+	require_Equal(t, o.MsgTraceOTLP.Endpoint, "http://127.0.0.1:4318/v1/traces")
+	require_Equal(t, o.MsgTraceOTLP.Headers["x-api-key"], "secret")
+	require_Equal(t, o.MsgTraceOTLP.ServiceName, "edge")
+	require_Equal(t, o.MsgTraceOTLP.Sampling, 50)
+	require_Equal(t, o.MsgTraceOTLP.Timeout, 2*time.Second)
+	require_Equal(t, o.MsgTraceOTLP.FlushInterval, 100*time.Millisecond)
+	require_Equal(t, o.MsgTraceOTLP.MaxBatch, 10)
+	require_Len(t, len(o.Accounts), 1)
+	require_Equal(t, o.Accounts[0].traceOTLPSampling, 25)
+
+	for _, test := range []struct {
+		name string
+		cfg  string
+	}{
+		{"no endpoint", `msg_trace_otlp { sampling: 10 }`},
+		{"bad endpoint", `msg_trace_otlp: "127.0.0.1:4318"`},
+		{"bad sampling", `msg_trace_otlp { endpoint: "http://127.0.0.1:4318", sampling: 200 }`},
+		{"bad account sampling", `accounts { A { msg_trace: { otlp_sampling: 0 } } }`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			conf := createConfFile(t, []byte(test.cfg))
+			_, err := ProcessConfigFile(conf)
+			require_Error(t, err)
+		})
+	}
+}
+
+func TestMsgTraceOTLPParentSpans(t *testing.T) {
+	require_Equal(t, msgTraceParentHop("1.2.3"), "1.2")
+	require_Equal(t, msgTraceParentHop("1"), _EMPTY_)
+
+	traceID, parentID, sampled, ok := parseTraceParent("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01")
+	require_True(t, ok)
+	require_True(t, sampled)
+	require_Equal(t, traceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	require_Equal(t, parentID, "00f067aa0ba902b7")
+
+	_, _, sampled, ok = parseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-02")
+	require_True(t, ok)
+	require_False(t, sampled)
+
+	for _, tp := range []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	} {
+		_, _, _, ok := parseTraceParent(tp)
+		require_False(t, ok)
+	}
+}
+
+func TestMsgTraceOTLPExportAcrossRoute(t *testing.T) {
+	col := newTestOTLPCollector(t)
+
+	tmpl := `
+		port: -1
+		server_name: "%s"
+		msg_trace_otlp {
+			endpoint: "%s"
+			headers: { "x-api-key": "secret" }
+			flush_interval: "50ms"
+		}
+		accounts {
+			A { users: [{user: A, password: pwd}] }
+		}
+		cluster {
+			name: "local"
+			port: -1
+			%s
+		}
+	`
+	conf1 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "S1", col.ts.URL, _EMPTY_)))
+	s1, o1 := RunServerWithConfig(conf1)
+	defer s1.Shutdown()
+
+	conf2 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "S2", col.ts.URL,
+		fmt.Sprintf(`routes: ["nats://127.0.0.1:%d"]`, o1.Cluster.Port))))
+	s2, _ := RunServerWithConfig(conf2)
+	defer s2.Shutdown()
+
+	checkClusterFormed(t, s1, s2)
+
+	nc2 := natsConnect(t, s2.ClientURL(), nats.UserInfo("A", "pwd"))
+	defer nc2.Close()
+	sub := natsSubSync(t, nc2, "foo")
+	natsFlush(t, nc2)
+
+	checkSubInterest(t, s1, "A", "foo", time.Second)
+
+	nc1 := natsConnect(t, s1.ClientURL(), nats.UserInfo("A", "pwd"))
+	defer nc1.Close()
+
+	// Not sampled by the application, so nothing should be exported.
+	msg := nats.NewMsg("foo")
+	msg.Header.Set(traceParentHdr, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	msg.Data = []byte("hello")
+	require_NoError(t, nc1.PublishMsg(msg))
+	natsNexMsg(t, sub, time.Second)
+
+	msg = nats.NewMsg("foo")
+	msg.Header.Set(traceParentHdr, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	msg.Data = []byte("hello")
+	require_NoError(t, nc1.PublishMsg(msg))
+	natsNexMsg(t, sub, time.Second)
+
+	spans := col.waitForSpans(t, 2)
+	col.Lock()
+	require_Equal(t, col.hdrs.Get("x-api-key"), "secret")
+	require_Equal(t, col.hdrs.Get("Content-Type"), "application/json")
+	col.Unlock()
+
+	var origin, remote *otlpSpan
+	for _, span := range spans {
+		require_Equal(t, span.TraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+		require_Equal(t, span.Name, "process foo")
+		if span.Kind == otlpSpanKindServer {
+			origin = span
+		} else {
+			remote = span
+		}
+	}
+	require_True(t, origin != nil)
+	require_True(t, remote != nil)
+	// The origin server's span is a child of the application span, and
+	// the span from the server that received the message over the route
+	// is a child of the origin server's span.
+	require_Equal(t, origin.ParentSpanID, "00f067aa0ba902b7")
+	require_Equal(t, remote.ParentSpanID, origin.SpanID)
+	require_Equal(t, remote.SpanID, msgTraceSpanID(origin.TraceID, "1"))
+
+	var egress int
+	for _, ev := range origin.Events {
+		if ev.Name != "egress" {
+			continue
+		}
+		egress++
+		for _, attr := range ev.Attributes {
+			if attr.Key == "nats.hop.span_id" {
+				require_Equal(t, *attr.Value.StringValue, remote.SpanID)
+			}
+		}
+	}
+	require_Equal(t, egress, 1)
+
+	stats := s1.MsgTraceOTLPStats()
+	require_True(t, stats != nil)
+	require_Equal(t, stats.Exported, 1)
+}
+
+func TestMsgTraceOTLPSamplingFromOrigin(t *testing.T) {
+	col := newTestOTLPCollector(t)
+
+	// S1 traces to the account destination only, and S2 exports OTLP spans only,
+	// so any output of S2 must come from its own sampling, not the origin's.
+	tmpl := `
+		port: -1
+		server_name: "%s"
+		%s
+		accounts {
+			A {
+				users: [{user: A, password: pwd}]
+				%s
+			}
+		}
+		cluster {
+			name: "local"
+			port: -1
+			%s
+		}
+	`
+	conf1 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "S1", _EMPTY_, `msg_trace: {dest: "acc.trace.dest"}`, _EMPTY_)))
+	s1, o1 := RunServerWithConfig(conf1)
+	defer s1.Shutdown()
+
+	otlp := fmt.Sprintf(`msg_trace_otlp { endpoint: "%s", flush_interval: "50ms" }`, col.ts.URL)
+	conf2 := createConfFile(t, []byte(fmt.Sprintf(tmpl, "S2", otlp, _EMPTY_,
+		fmt.Sprintf(`routes: ["nats://127.0.0.1:%d"]`, o1.Cluster.Port))))
+	s2, _ := RunServerWithConfig(conf2)
+	defer s2.Shutdown()
+
+	checkClusterFormed(t, s1, s2)
+
+	nc2 := natsConnect(t, s2.ClientURL(), nats.UserInfo("A", "pwd"))
+	defer nc2.Close()
+	sub := natsSubSync(t, nc2, "foo")
+	natsFlush(t, nc2)
+	checkSubInterest(t, s1, "A", "foo", time.Second)
+
+	nc1 := natsConnect(t, s1.ClientURL(), nats.UserInfo("A", "pwd"))
+	defer nc1.Close()
+	traceSub := natsSubSync(t, nc1, "acc.trace.dest")
+	natsFlush(t, nc1)
+
+	msg := nats.NewMsg("foo")
+	msg.Header.Set(traceParentHdr, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	msg.Data = []byte("hello")
+	require_NoError(t, nc1.PublishMsg(msg))
+	rmsg := natsNexMsg(t, sub, time.Second)
+	require_Equal(t, rmsg.Header.Get(MsgTraceSampled), msgTraceSampledDest)
+
+	// Only the origin sends an event, since S2 does not have the destination.
+	tmsg := natsNexMsg(t, traceSub, time.Second)
+	var e MsgTraceEvent
+	require_NoError(t, json.Unmarshal(tmsg.Data, &e))
+	require_Equal(t, e.Server.Name, "S1")
+
+	// The origin did not sample the OTLP export, so S2 must not export a span.
+	time.Sleep(250 * time.Millisecond)
+	col.Lock()
+	require_Len(t, len(col.spans), 0)
+	col.Unlock()
+}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -349,6 +350,11 @@ type Options struct {
 	JetStreamTpm               JSTpmOpts
 	JetStreamMaxCatchup        int64
 	JetStreamRequestQueueLimit int64
+	JetStreamRaftPreVote       bool              `json:"-"`
+	JetStreamRaftCheckQuorum   bool              `json:"-"`
+	JetStreamTieredStoreDir    string            `json:"-"`
+	JetStreamTieredStore       BlobStore         `json:"-"`
+	JetStreamTieredCacheSize   int64             `json:"-"`
 	StreamMaxBufferedMsgs      int               `json:"-"`
 	StreamMaxBufferedSize      int64             `json:"-"`
 	StoreDir                   string            `json:"-"`
@@ -436,6 +442,9 @@ type Options struct {
 	// Metadata describing the server. They will be included in 'Z' responses.
 	Metadata map[string]string `json:"-"`
 
+	// MsgTraceOTLP enables the export of message traces as OTLP spans.
+	MsgTraceOTLP *MsgTraceOTLPOpts `json:"-"`
+
 	// OCSPConfig enables OCSP Stapling in the server.
 	OCSPConfig    *OCSPConfig
 	tlsConfigOpts *TLSConfigOpts
@@ -1631,6 +1640,11 @@ func (o *Options) processConfigFileLine(k string, v any, errors *[]error, warnin
 			*errors = append(*errors, err)
 			return
 		}
+	case "msg_trace_otlp":
+		if err := parseMsgTraceOTLP(tk, o, errors, warnings); err != nil {
+			*errors = append(*errors, err)
+			return
+		}
 	case "server_tags":
 		var err error
 		switch v := v.(type) {
@@ -2238,6 +2252,12 @@ func parseJetStreamForAccount(v any, acc *Account, errors *[]error) error {
 				default:
 					return &configErr{tk, fmt.Sprintf("Expected 'system' or 'owner' string value for %q, got %v", mk, mv)}
 				}
+			case "auto_streams", "auto_create_streams":
+				rules, err := parseJetStreamAutoStreams(mv, errors)
+				if err != nil {
+					return err
+				}
+				acc.jsAutoStrs = rules
 			default:
 				if !tk.IsUsedVariable() {
 					err := &unknownConfigFieldErr{
@@ -2258,6 +2278,119 @@ func parseJetStreamForAccount(v any, acc *Account, errors *[]error) error {
 	return nil
 }
 
+// Stream config fields that can be given as a duration string in auto-create rules.
+var autoStreamDurationFields = map[string]struct{}{
+	"max_age":                   {},
+	"duplicate_window":          {},
+	"subject_delete_marker_ttl": {},
+}
+
+// parseJetStreamAutoStreams parses the rules to auto-create streams for an account, e.g.
+//
+//	auto_streams: [
+//	  {subject: "tenant.*.>", name: "TENANT.{{wildcard(1)}}", config: {storage: file, max_age: "24h"}}
+//	]
+//
+// The config uses the same fields as the stream create API.
+func parseJetStreamAutoStreams(v any, errors *[]error) ([]*StreamAutoCreateRule, error) {
+	var lt token
+	tk, v := unwrapValue(v, &lt)
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, &configErr{tk, fmt.Sprintf("Expected auto-create stream rules to be an array, got %T", v)}
+	}
+	var rules []*StreamAutoCreateRule
+	for _, e := range arr {
+		tk, e := unwrapValue(e, &lt)
+		rm, ok := e.(map[string]any)
+		if !ok {
+			return nil, &configErr{tk, fmt.Sprintf("Expected auto-create stream rule to be a map, got %T", e)}
+		}
+		r := &StreamAutoCreateRule{}
+		for k, mv := range rm {
+			tk, mv := unwrapValue(mv, &lt)
+			switch strings.ToLower(k) {
+			case "subject":
+				if r.Subject, ok = mv.(string); !ok {
+					return nil, &configErr{tk, fmt.Sprintf("Expected a string for %q, got %T", k, mv)}
+				}
+			case "name":
+				if r.Name, ok = mv.(string); !ok {
+					return nil, &configErr{tk, fmt.Sprintf("Expected a string for %q, got %T", k, mv)}
+				}
+			case "config", "template":
+				cm, ok := mv.(map[string]any)
+				if !ok {
+					return nil, &configErr{tk, fmt.Sprintf("Expected a map for %q, got %T", k, mv)}
+				}
+				jv, err := autoStreamConfigValue(cm, _EMPTY_)
+				if err != nil {
+					return nil, &configErr{tk, err.Error()}
+				}
+				b, err := json.Marshal(jv)
+				if err == nil {
+					err = json.Unmarshal(b, &r.Config)
+				}
+				if err != nil {
+					return nil, &configErr{tk, fmt.Sprintf("Invalid auto-create stream config: %v", err)}
+				}
+			default:
+				if !tk.IsUsedVariable() {
+					err := &unknownConfigFieldErr{
+						field: k,
+						configErr: configErr{
+							token: tk,
+						},
+					}
+					*errors = append(*errors, err)
+				}
+			}
+		}
+		if _, err := r.compile(); err != nil {
+			return nil, &configErr{tk, err.Error()}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Returns the config value without tokens so it can be marshaled as JSON.
+func autoStreamConfigValue(v any, field string) (any, error) {
+	var lt token
+	_, v = unwrapValue(v, &lt)
+	switch vv := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(vv))
+		for k, mv := range vv {
+			jv, err := autoStreamConfigValue(mv, k)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = jv
+		}
+		return m, nil
+	case []any:
+		a := make([]any, 0, len(vv))
+		for _, av := range vv {
+			jv, err := autoStreamConfigValue(av, field)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, jv)
+		}
+		return a, nil
+	case string:
+		if _, ok := autoStreamDurationFields[field]; ok {
+			d, err := time.ParseDuration(vv)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration for %q: %v", field, err)
+			}
+			return int64(d), nil
+		}
+	}
+	return v, nil
+}
+
 // takes in a storage size as either an int or a string and returns an int64 value based on the input.
 func getStorageSize(v any) (int64, error) {
 	_, ok := v.(int64)
@@ -2496,6 +2629,26 @@ func parseJetStream(v any, opts *Options, errors *[]error, warnings *[]error) er
 					return &configErr{tk, fmt.Sprintf("Expected a parseable size for %q, got %v", mk, mv)}
 				}
 				opts.JetStreamRequestQueueLimit = lim
+			case "raft_pre_vote":
+				if v, ok := mv.(bool); ok {
+					opts.JetStreamRaftPreVote = v
+				} else {
+					return &configErr{tk, fmt.Sprintf("Expected 'true' or 'false' for bool value, got '%s'", mv)}
+				}
+			case "raft_check_quorum":
+				if v, ok := mv.(bool); ok {
+					opts.JetStreamRaftCheckQuorum = v
+				} else {
+					return &configErr{tk, fmt.Sprintf("Expected 'true' or 'false' for bool value, got '%s'", mv)}
+				}
+			case "tiered_store_dir", "tiered_storage_dir":
+				opts.JetStreamTieredStoreDir = mv.(string)
+			case "tiered_cache_size":
+				s, err := getStorageSize(mv)
+				if err != nil {
+					return &configErr{tk, fmt.Sprintf("%s %s", strings.ToLower(mk), err)}
+				}
+				opts.JetStreamTieredCacheSize = s
 			default:
 				if !tk.IsUsedVariable() {
 					err := &unknownConfigFieldErr{
@@ -3243,24 +3396,29 @@ func parseAccountMsgTrace(mv any, topKey string, acc *Account) error {
 				if err := processDest(tk, k, v); err != nil {
 					return err
 				}
-			case "sampling":
+			case "sampling", "otlp_sampling":
+				var n int
 				switch vv := v.(type) {
 				case int64:
-					if err := processSampling(tk, int(vv)); err != nil {
-						return err
-					}
+					n = int(vv)
 				case string:
 					s := strings.TrimSuffix(vv, "%")
-					n, err := strconv.Atoi(s)
+					var err error
+					n, err = strconv.Atoi(s)
 					if err != nil {
 						return &configErr{tk, fmt.Sprintf("Invalid trace destination sampling value %q", vv)}
 					}
-					if err := processSampling(tk, n); err != nil {
-						return err
-					}
 				default:
 					return &configErr{tk, fmt.Sprintf("Trace destination sampling field %q should be an integer or a percentage, got %T", k, v)}
 				}
+				if strings.ToLower(k) == "otlp_sampling" {
+					if n <= 0 || n > 100 {
+						return &configErr{tk, fmt.Sprintf("Trace OTLP sampling value %d is invalid, needs to be [1..100]", n)}
+					}
+					acc.traceOTLPSampling = n
+				} else if err := processSampling(tk, n); err != nil {
+					return err
+				}
 			default:
 				if !tk.IsUsedVariable() {
 					return &configErr{tk, fmt.Sprintf("Unknown field %q parsing account message trace map/struct %q", k, topKey)}
@@ -3273,6 +3431,78 @@ func parseAccountMsgTrace(mv any, topKey string, acc *Account) error {
 	return nil
 }
 
+// parseMsgTraceOTLP parses the export of message traces as OTLP spans.
+func parseMsgTraceOTLP(v any, o *Options, errors *[]error, warnings *[]error) error {
+	var lt token
+	defer convertPanicToErrorList(&lt, errors)
+
+	tk, v := unwrapValue(v, &lt)
+	var ot MsgTraceOTLPOpts
+	switch vv := v.(type) {
+	case string:
+		ot.Endpoint = vv
+	case map[string]any:
+		for mk, mv := range vv {
+			tk, mv = unwrapValue(mv, &lt)
+			switch strings.ToLower(mk) {
+			case "endpoint", "url":
+				ot.Endpoint = mv.(string)
+			case "headers":
+				hm, ok := mv.(map[string]any)
+				if !ok {
+					return &configErr{tk, fmt.Sprintf("Expected OTLP headers to be a map/struct, got %T", mv)}
+				}
+				ot.Headers = make(map[string]string, len(hm))
+				for hk, hv := range hm {
+					_, hv = unwrapValue(hv, &lt)
+					ot.Headers[hk] = hv.(string)
+				}
+			case "service_name":
+				ot.ServiceName = mv.(string)
+			case "sampling":
+				switch sv := mv.(type) {
+				case int64:
+					ot.Sampling = int(sv)
+				case string:
+					n, err := strconv.Atoi(strings.TrimSuffix(sv, "%"))
+					if err != nil {
+						return &configErr{tk, fmt.Sprintf("Invalid OTLP sampling value %q", sv)}
+					}
+					ot.Sampling = n
+				default:
+					return &configErr{tk, fmt.Sprintf("OTLP sampling should be an integer or a percentage, got %T", mv)}
+				}
+				if ot.Sampling <= 0 {
+					return &configErr{tk, fmt.Sprintf("OTLP sampling value %d is invalid, needs to be [1..100]", ot.Sampling)}
+				}
+			case "timeout":
+				ot.Timeout = parseDuration(mk, tk, mv, errors, warnings)
+			case "flush_interval":
+				ot.FlushInterval = parseDuration(mk, tk, mv, errors, warnings)
+			case "max_batch":
+				ot.MaxBatch = int(mv.(int64))
+			default:
+				if !tk.IsUsedVariable() {
+					err := &unknownConfigFieldErr{
+						field: mk,
+						configErr: configErr{
+							token: tk,
+						},
+					}
+					*errors = append(*errors, err)
+				}
+			}
+		}
+	default:
+		return &configErr{tk, fmt.Sprintf("Expected msg_trace_otlp to be a string or a map/struct, got %T", v)}
+	}
+	if err := ot.validate(); err != nil {
+		return &configErr{tk, err.Error()}
+	}
+	o.MsgTraceOTLP = &ot
+	return nil
+}
+
 // parseAccounts will parse the different accounts syntax.
 func parseAccounts(v any, opts *Options, errors *[]error, warnings *[]error) error {
 	var (
@@ -4298,6 +4528,10 @@ func parseUsers(mv any, errors *[]error) ([]*NkeyUser, []*User, error) {
 				cts := parseAllowedConnectionTypes(tk, &lt, v, errors)
 				nkey.AllowedConnectionTypes = cts
 				user.AllowedConnectionTypes = cts
+			case "rate_limit", "rate_limits":
+				rls := parseRateLimits(tk, &lt, v, errors)
+				nkey.RateLimits = rls
+				user.RateLimits = rls
 			default:
 				if !tk.IsUsedVariable() {
 					err := &unknownConfigFieldErr{
@@ -4354,6 +4588,69 @@ func parseAllowedConnectionTypes(tk token, lt *token, mv any, errors *[]error) m
 	return m
 }
 
+// Helper function to parse the publish rate limits of a user. Can be a single
+// limit or an array of them, each optionally scoped to a subject.
+func parseRateLimits(tk token, lt *token, mv any, errors *[]error) []*RateLimit {
+	var lv []any
+	switch v := mv.(type) {
+	case map[string]any:
+		lv = []any{tk}
+	case []any:
+		lv = v
+	default:
+		*errors = append(*errors, &configErr{tk, fmt.Sprintf("Expected rate limit to be a map/struct or array, got %T", v)})
+		return nil
+	}
+	var limits []*RateLimit
+	for _, v := range lv {
+		tk, v := unwrapValue(v, lt)
+		m, ok := v.(map[string]any)
+		if !ok {
+			*errors = append(*errors, &configErr{tk, fmt.Sprintf("Expected rate limit entry to be a map/struct, got %T", v)})
+			continue
+		}
+		rl := &RateLimit{}
+		for mk, mv := range m {
+			tk, mv := unwrapValue(mv, lt)
+			var err error
+			switch strings.ToLower(mk) {
+			case "subject":
+				rl.Subject, ok = mv.(string)
+			case "msgs", "messages":
+				rl.Msgs, ok = mv.(int64)
+			case "bytes":
+				if rl.Bytes, err = getStorageSize(mv); err != nil {
+					err = fmt.Errorf("rate limit bytes %s", err)
+				}
+			case "action":
+				var a string
+				a, ok = mv.(string)
+				rl.Action = RateLimitAction(strings.ToLower(a))
+			default:
+				if !tk.IsUsedVariable() {
+					err = &unknownConfigFieldErr{field: mk, configErr: configErr{token: tk}}
+				}
+			}
+			if !ok {
+				err = fmt.Errorf("invalid value for rate limit %q: %v", mk, mv)
+				ok = true
+			}
+			if err != nil {
+				if _, isCfgErr := err.(*unknownConfigFieldErr); !isCfgErr {
+					err = &configErr{tk, err.Error()}
+				}
+				*errors = append(*errors, err)
+			}
+		}
+		if err := rl.validate(); err != nil {
+			*errors = append(*errors, &configErr{tk, err.Error()})
+			continue
+		}
+		limits = append(limits, rl)
+	}
+	return limits
+}
+
 // Helper function to parse auth callouts.
 func parseAuthCallout(mv any, errors *[]error) (*AuthCallout, error) {
 	var (
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -58,6 +59,11 @@ type RaftNode interface {
 	StepDown(preferred ...string) error
 	SetObserver(isObserver bool)
 	IsObserver() bool
+	SetLearners(learners []string)
+	IsLearner() bool
+	Learners() []*Peer
+	ReadIndex(timeout time.Duration) (uint64, error)
+	ReadIndexAsync(timeout time.Duration, cb func(index uint64, err error))
 	Campaign() error
 	CampaignImmediately() error
 	ID() string
@@ -149,6 +155,8 @@ type raft struct {
 	leaderState atomic.Bool  // Is in (complete) leader state.
 	hh          hash.Hash64  // Highwayhash, used for snapshots
 	snapfile    string       // Snapshot filename
+	lsnap       time.Time    // Last time a snapshot was installed
+	metrics     raftMetrics  // WAL and throughput metrics
 
 	csz   int             // Cluster size
 	qn    int             // Number of nodes needed to establish quorum
@@ -192,18 +200,42 @@ type raft struct {
 	initializing bool // The node is new, and "empty log" checks can be temporarily relaxed.
 	scaleUp      bool // The node is part of a scale up, puts us in observer mode until the log contains data.
 
+	learner  bool                // The node is a learner, i.e. not able to vote or become leader
+	learners map[string]struct{} // Non-voting peers in the group, these don't count toward quorum
+
+	prevote   bool // Pre-vote enabled, i.e. a candidate needs to win a pre-election before incrementing its term
+	prevoting bool // Are we in a pre-election?
+	checkq    bool // Check-quorum enabled, i.e. the leader steps down if it hasn't heard from a quorum within an election timeout
+
+	pvlost   uint64 // Number of pre-elections we lost, each avoiding a disruptive election
+	pvreject uint64 // Number of pre-votes we rejected since we are still hearing from the leader
+	cqsd     uint64 // Number of times we stepped down as leader after not hearing from a quorum
+
 	extSt extensionState // Extension state
 
-	psubj  string // Proposals subject
-	rpsubj string // Remove peers subject
-	vsubj  string // Vote requests subject
-	vreply string // Vote responses subject
-	asubj  string // Append entries subject
-	areply string // Append entries responses subject
+	psubj   string // Proposals subject
+	rpsubj  string // Remove peers subject
+	risubj  string // Read index requests subject
+	vsubj   string // Vote requests subject
+	vreply  string // Vote responses subject
+	pvsubj  string // Pre-vote requests subject
+	pvreply string // Pre-vote responses subject
+	asubj   string // Append entries subject
+	areply  string // Append entries responses subject
 
 	sq    *sendq        // Send queue for outbound RPC messages
 	aesub *subscription // Subscription for handleAppendEntry callbacks
 
+	rireqs  []*readIndexReq     // Linearizable reads waiting for the next read index round
+	riround []*readIndexReq     // Linearizable reads in the read index round in flight
+	rigen   uint64              // Generation of the read index round in flight
+	risub   *subscription       // Inbox for responses to the read index round in flight
+	riterm  uint64              // Term of the read index round in flight as leader
+	riindex uint64              // Commit index of the read index round in flight as leader
+	riacks  map[string]struct{} // Voting peers that responded to the read index round in flight
+	ritimer *time.Timer         // Retries the read index round in flight
+	rwaits  []*readIndexReq     // Linearizable reads waiting for an index to be applied
+
 	wtv []byte // Term and vote to be written
 	wps []byte // Peer state to be written
 
@@ -256,6 +288,7 @@ const (
 	lostQuorumCheckIntervalDefault = hbIntervalDefault * 10 // 10 seconds
 	observerModeIntervalDefault    = 48 * time.Hour
 	peerRemoveTimeoutDefault       = 5 * time.Minute
+	readIndexTimeoutDefault        = hbIntervalDefault * 2
 )
 
 var (
@@ -268,6 +301,7 @@ var (
 	lostQuorumCheck      = lostQuorumCheckIntervalDefault
 	observerModeInterval = observerModeIntervalDefault
 	peerRemoveTimeout    = peerRemoveTimeoutDefault
+	readIndexTimeout     = readIndexTimeoutDefault
 )
 
 type RaftConfig struct {
@@ -276,6 +310,7 @@ type RaftConfig struct {
 	Log      WAL
 	Track    bool
 	Observer bool
+	Learners []string // Non-voting peers, these receive the log but never vote or count toward quorum.
 
 	// Recovering must be set for a Raft group that's recovering after a restart, or if it's
 	// first seen after a catchup from another server. If a server recovers with an empty log,
@@ -307,6 +342,9 @@ var (
 	errTooManyEntries    = errors.New("raft: append entry can contain a max of 64k entries")
 	errBadAppendEntry    = errors.New("raft: append entry corrupt")
 	errNoInternalClient  = errors.New("raft: no internal client")
+	errReadIndexTimeout  = errors.New("raft: timeout waiting for read index")
+	errUnknownRaftGroup  = errors.New("raft: unknown group")
+	errRaftWALNotFile    = errors.New("raft: group WAL is not file based")
 )
 
 // This will bootstrap a raftNode by writing its config into the store directory.
@@ -385,6 +423,7 @@ func (s *Server) initRaftNode(accName string, cfg *RaftConfig, labels pprofLabel
 	}
 	hash := s.sys.shash
 	s.mu.RUnlock()
+	opts := s.getOpts()
 
 	// Do this here to process error quicker.
 	ps, err := readPeerState(cfg.Store)
@@ -421,8 +460,11 @@ func (s *Server) initRaftNode(accName string, cfg *RaftConfig, labels pprofLabel
 		accName:  accName,
 		leadc:    make(chan bool, 32),
 		observer: cfg.Observer,
+		prevote:  opts.JetStreamRaftPreVote,
+		checkq:   opts.JetStreamRaftCheckQuorum,
 		extSt:    ps.domainExt,
 	}
+	n.setLearnersLocked(cfg.Learners)
 
 	// Setup our internal subscriptions for proposals, votes and append entries.
 	// If we fail to do this for some reason then this is fatal — we cannot
@@ -589,6 +631,22 @@ func (n *raft) checkAccountNRGStatus() bool {
 	return enabled
 }
 
+// Returns whether all peers within this group advertise that they answer pre-votes.
+// Servers that don't would stall elections during a rolling upgrade.
+// Lock must be held.
+func (n *raft) peersSupportPreVote() bool {
+	for pn := range n.peers {
+		if pn == n.id {
+			continue
+		}
+		si, ok := n.s.nodeToInfo.Load(pn)
+		if !ok || si == nil || !si.(nodeInfo).preVote {
+			return false
+		}
+	}
+	return true
+}
+
 // Whether we are using the system account or not.
 func (n *raft) IsSystemAccount() bool {
 	return n.isSysAcc.Load()
@@ -1086,6 +1144,10 @@ func (n *raft) ResumeApply() {
 // apply queue. It will return the number of entries and an estimation of the
 // byte size that could be removed with a snapshot/compact.
 func (n *raft) Applied(index uint64) (entries uint64, bytes uint64) {
+	// Linearizable reads are served once we have released the lock.
+	var ready []*readIndexReq
+	defer func() { n.completeReadIndexes(ready) }()
+
 	n.Lock()
 	defer n.Unlock()
 
@@ -1097,6 +1159,7 @@ func (n *raft) Applied(index uint64) (entries uint64, bytes uint64) {
 	// Ignore if already applied.
 	if index > n.applied {
 		n.applied = index
+		ready = n.signalReadIndexWaitsLocked()
 	}
 
 	// If it was set, and we reached the minimum applied index, reset and send signal to upper layer.
@@ -1233,6 +1296,7 @@ func (n *raft) installSnapshot(snap *snapshot) error {
 	}
 	// Remember our latest snapshot file.
 	n.snapfile = sfile
+	n.lsnap = time.Now()
 	if _, err := n.wal.Compact(snap.lastIndex + 1); err != nil {
 		n.setWriteErrLocked(err)
 		return err
@@ -1318,6 +1382,9 @@ func (n *raft) setupLastSnapshot() {
 	defer n.Unlock()
 
 	n.snapfile = latest
+	if fi, err := os.Stat(latest); err == nil {
+		n.lsnap = fi.ModTime()
+	}
 	snap, err := n.loadLastSnapshot()
 	if err != nil {
 		// We failed to recover the last snapshot for some reason, so we will
@@ -1567,7 +1634,7 @@ func (n *raft) Leaderless() bool {
 func (n *raft) selectNextLeader() string {
 	nextLeader, hli := noLeader, uint64(0)
 	for peer, ps := range n.peers {
-		if peer == n.id || ps.li <= hli {
+		if peer == n.id || ps.li <= hli || n.isLearner(peer) {
 			continue
 		}
 		hli = ps.li
@@ -1609,7 +1676,7 @@ func (n *raft) StepDown(preferred ...string) error {
 	// If we have a preferred check it first.
 	if maybeLeader != noLeader {
 		var isHealthy bool
-		if ps, ok := n.peers[maybeLeader]; ok {
+		if ps, ok := n.peers[maybeLeader]; ok && !n.isLearner(maybeLeader) {
 			si, ok := n.s.nodeToInfo.Load(maybeLeader)
 			isHealthy = ok && !si.(nodeInfo).offline && time.Since(ps.ts) < hbInterval*3
 		}
@@ -1622,7 +1689,7 @@ func (n *raft) StepDown(preferred ...string) error {
 	// Make sure not ourselves.
 	if maybeLeader == noLeader {
 		for peer, ps := range n.peers {
-			if peer == n.id {
+			if peer == n.id || n.isLearner(peer) {
 				continue
 			}
 			si, ok := n.s.nodeToInfo.Load(peer)
@@ -1745,21 +1812,45 @@ func (n *raft) Peers() []*Peer {
 
 	var peers []*Peer
 	for id, ps := range n.peers {
-		var lag uint64
-		if n.commit > ps.li {
-			lag = n.commit - ps.li
-		}
-		p := &Peer{
-			ID:      id,
-			Current: id == n.leader || ps.li >= n.applied,
-			Last:    ps.ts,
-			Lag:     lag,
+		if n.isLearner(id) {
+			continue
 		}
-		peers = append(peers, p)
+		peers = append(peers, n.peerInfo(id, ps))
 	}
 	return peers
 }
 
+// Learners returns the non-voting peers of the group. Only the leader
+// tracks their progress, followers report them without any state.
+func (n *raft) Learners() []*Peer {
+	n.RLock()
+	defer n.RUnlock()
+
+	var learners []*Peer
+	for id := range n.learners {
+		if ps := n.peers[id]; ps != nil {
+			learners = append(learners, n.peerInfo(id, ps))
+		} else {
+			learners = append(learners, &Peer{ID: id})
+		}
+	}
+	return learners
+}
+
+// Lock should be held.
+func (n *raft) peerInfo(id string, ps *lps) *Peer {
+	var lag uint64
+	if n.commit > ps.li {
+		lag = n.commit - ps.li
+	}
+	return &Peer{
+		ID:      id,
+		Current: id == n.leader || ps.li >= n.applied,
+		Last:    ps.ts,
+		Lag:     lag,
+	}
+}
+
 // Update and propose our known set of peers.
 func (n *raft) ProposeKnownPeers(knownPeers []string) {
 	// If we are the leader update and send this update out.
@@ -1831,9 +1922,11 @@ func (n *raft) shutdown() {
 const (
 	raftAllSubj        = "$NRG.>"
 	raftVoteSubj       = "$NRG.V.%s"
+	raftPreVoteSubj    = "$NRG.PV.%s"
 	raftAppendSubj     = "$NRG.AE.%s"
 	raftPropSubj       = "$NRG.P.%s"
 	raftRemovePeerSubj = "$NRG.RP.%s"
+	raftReadIndexSubj  = "$NRG.RI.%s"
 	raftReply          = "$NRG.R.%s"
 	raftCatchupReply   = "$NRG.CR.%s"
 )
@@ -1878,9 +1971,11 @@ func (n *raft) unsubscribe(sub *subscription) {
 // Lock should be held.
 func (n *raft) createInternalSubs() error {
 	n.vsubj, n.vreply = fmt.Sprintf(raftVoteSubj, n.group), n.newInbox()
+	n.pvsubj, n.pvreply = fmt.Sprintf(raftPreVoteSubj, n.group), n.newInbox()
 	n.asubj, n.areply = fmt.Sprintf(raftAppendSubj, n.group), n.newInbox()
 	n.psubj = fmt.Sprintf(raftPropSubj, n.group)
 	n.rpsubj = fmt.Sprintf(raftRemovePeerSubj, n.group)
+	n.risubj = fmt.Sprintf(raftReadIndexSubj, n.group)
 
 	// Votes
 	if _, err := n.subscribe(n.vreply, n.handleVoteResponse); err != nil {
@@ -1889,6 +1984,13 @@ func (n *raft) createInternalSubs() error {
 	if _, err := n.subscribe(n.vsubj, n.handleVoteRequest); err != nil {
 		return err
 	}
+	// Pre-votes, we always answer these even if we don't use them ourselves.
+	if _, err := n.subscribe(n.pvreply, n.handlePreVoteResponse); err != nil {
+		return err
+	}
+	if _, err := n.subscribe(n.pvsubj, n.handlePreVoteRequest); err != nil {
+		return err
+	}
 	// AppendEntry
 	if _, err := n.subscribe(n.areply, n.handleAppendEntryResponse); err != nil {
 		return err
@@ -2060,6 +2162,42 @@ func (n *raft) IsObserver() bool {
 	return n.observer
 }
 
+// SetLearners updates the non-voting peers of the group. If we are
+// no longer a learner ourselves we are able to campaign again.
+func (n *raft) SetLearners(learners []string) {
+	n.Lock()
+	defer n.Unlock()
+	n.setLearnersLocked(learners)
+}
+
+// Lock should be held.
+func (n *raft) setLearnersLocked(learners []string) {
+	wasLearner := n.learner
+	n.learners = make(map[string]struct{}, len(learners))
+	for _, peer := range learners {
+		n.learners[peer] = struct{}{}
+	}
+	_, n.learner = n.learners[n.id]
+
+	// If we have been promoted reset the election timer, we might
+	// otherwise end up waiting for up to the observerModeInterval.
+	if wasLearner && !n.learner {
+		n.resetElect(randCampaignTimeout())
+	}
+}
+
+func (n *raft) IsLearner() bool {
+	n.RLock()
+	defer n.RUnlock()
+	return n.learner
+}
+
+// Lock should be held.
+func (n *raft) isLearner(peer string) bool {
+	_, ok := n.learners[peer]
+	return ok
+}
+
 // Sets the state to observer only.
 func (n *raft) SetObserver(isObserver bool) {
 	n.setObserver(isObserver, extUndetermined)
@@ -2131,6 +2269,9 @@ func (n *raft) runAsFollower() {
 			} else if n.IsObserver() {
 				n.resetElectWithLock(observerModeInterval)
 				n.debug("Not switching to candidate, observer only")
+			} else if n.IsLearner() {
+				n.resetElectWithLock(observerModeInterval)
+				n.debug("Not switching to candidate, learner only")
 			} else if n.isCatchingUp() {
 				n.debug("Not switching to candidate, catching up")
 				// Check to see if our catchup has stalled.
@@ -2521,13 +2662,262 @@ func (n *raft) handleForwardedProposal(sub *subscription, c *client, _ *Account,
 	prop.push(newProposedEntry(newEntry(EntryNormal, msg), reply))
 }
 
+// A linearizable read waiting for a read index, and possibly for the upper layer to apply up to it.
+type readIndexReq struct {
+	index uint64                        // Read index, once known
+	wait  bool                          // Whether to wait for the upper layer to apply up to the index
+	done  bool                          // Whether the read completed or timed out
+	timer *time.Timer                   // Fails the read once it times out
+	cb    func(index uint64, err error) // Called once the read can be served, or with an error
+}
+
+// ReadIndex blocks until a linearizable read can be served from the state of the upper layer.
+func (n *raft) ReadIndex(timeout time.Duration) (uint64, error) {
+	type result struct {
+		index uint64
+		err   error
+	}
+	rch := make(chan result, 1)
+	n.ReadIndexAsync(timeout, func(index uint64, err error) {
+		rch <- result{index, err}
+	})
+	select {
+	case r := <-rch:
+		return r.index, r.err
+	case <-n.quit:
+		return 0, errNodeClosed
+	}
+}
+
+// ReadIndexAsync calls cb once a linearizable read can be served from the state of the upper layer,
+// or with an error if that is not possible within the timeout. The leader confirms it is still the
+// leader with a heartbeat round to a quorum of the voting peers and uses its commit index, followers
+// and learners request that index from the leader. Reads arriving while a round is in flight are
+// batched into the next one. We then wait for the upper layer to have applied up to the read index.
+// The callback is called without holding the lock and must not block.
+func (n *raft) ReadIndexAsync(timeout time.Duration, cb func(index uint64, err error)) {
+	n.readIndex(timeout, true, cb)
+}
+
+func (n *raft) readIndex(timeout time.Duration, wait bool, cb func(index uint64, err error)) {
+	if n.State() == Closed {
+		cb(0, errNodeClosed)
+		return
+	}
+	req := &readIndexReq{wait: wait, cb: cb}
+	n.Lock()
+	req.timer = time.AfterFunc(timeout, func() { n.expireReadIndex(req) })
+	n.rireqs = append(n.rireqs, req)
+	ready := n.startReadIndexRoundLocked()
+	n.Unlock()
+	n.completeReadIndexes(ready)
+}
+
+// Fails a read that timed out, unless it completed already.
+func (n *raft) expireReadIndex(req *readIndexReq) {
+	n.Lock()
+	if req.done {
+		n.Unlock()
+		return
+	}
+	req.done = true
+	n.rwaits = slices.DeleteFunc(n.rwaits, func(r *readIndexReq) bool { return r == req })
+	n.Unlock()
+	req.cb(0, errReadIndexTimeout)
+}
+
+// Calls back the reads that can be served now.
+// Lock should not be held.
+func (n *raft) completeReadIndexes(ready []*readIndexReq) {
+	for _, req := range ready {
+		req.timer.Stop()
+		req.cb(req.index, nil)
+	}
+}
+
+// Starts a read index round for the queued reads, unless one is in flight already.
+// Returns the reads that can be served right away.
+// Lock should be held.
+func (n *raft) startReadIndexRoundLocked() []*readIndexReq {
+	if n.riround != nil || len(n.rireqs) == 0 {
+		return nil
+	}
+	n.riround, n.rireqs = n.rireqs, nil
+	n.rigen++
+	// If the round doesn't complete in time, its reads are retried with the next one.
+	gen := n.rigen
+	n.ritimer = time.AfterFunc(readIndexTimeout, func() { n.retryReadIndexRound(gen) })
+
+	if n.State() != Leader {
+		if n.leader == noLeader {
+			return nil
+		}
+		inbox := n.newInbox()
+		sub, err := n.subscribe(inbox, n.handleReadIndexReply)
+		if err != nil {
+			return nil
+		}
+		n.risub = sub
+		n.sendRPC(n.risubj, inbox, nil)
+		return nil
+	}
+
+	// Our commit index is only known to be current once we have applied
+	// all entries from previous terms, which is when we signal leadership.
+	if !n.leaderState.Load() {
+		return nil
+	}
+	n.riterm, n.riindex = n.term, n.commit
+	if n.qn <= 1 {
+		return n.finishReadIndexRoundLocked(n.riindex)
+	}
+
+	// Responses for this heartbeat go to their own inbox so only those count, but
+	// they are still handed to the regular response handling for catchups etc.
+	inbox := n.newInbox()
+	sub, err := n.subscribe(inbox, n.handleReadIndexAck)
+	if err != nil {
+		return nil
+	}
+	ae := n.buildAppendEntry(nil)
+	var scratch [256]byte
+	buf, err := ae.encode(scratch[:])
+	ae.returnToPool()
+	if err != nil {
+		n.unsubscribe(sub)
+		return nil
+	}
+	n.risub = sub
+	clear(n.riacks)
+	n.sendRPC(n.asubj, inbox, buf)
+	return nil
+}
+
+// Completes the read index round in flight with the given read index. Returns the reads
+// that can be served right away, the others wait for the upper layer to apply up to it.
+// Lock should be held.
+func (n *raft) finishReadIndexRoundLocked(index uint64) []*readIndexReq {
+	n.ritimer.Stop()
+	n.unsubscribe(n.risub)
+	n.risub = nil
+	var ready []*readIndexReq
+	for _, req := range n.riround {
+		if req.done {
+			continue
+		}
+		req.index = index
+		if !req.wait || n.applied >= index {
+			req.done = true
+			ready = append(ready, req)
+		} else {
+			n.rwaits = append(n.rwaits, req)
+		}
+	}
+	n.riround = nil
+	return append(ready, n.startReadIndexRoundLocked()...)
+}
+
+// Called when the read index round in flight did not complete in time.
+func (n *raft) retryReadIndexRound(gen uint64) {
+	n.Lock()
+	if gen != n.rigen || n.riround == nil {
+		n.Unlock()
+		return
+	}
+	n.unsubscribe(n.risub)
+	n.risub = nil
+	n.rireqs = slices.DeleteFunc(append(n.riround, n.rireqs...), func(req *readIndexReq) bool { return req.done })
+	n.riround = nil
+	var ready []*readIndexReq
+	if n.State() != Closed {
+		ready = n.startReadIndexRoundLocked()
+	}
+	n.Unlock()
+	n.completeReadIndexes(ready)
+}
+
+// Handles the responses to the heartbeat of the read index round in flight. Any response
+// from the current term confirms the peer still follows us, even if it needs to catch up.
+func (n *raft) handleReadIndexAck(sub *subscription, _ *client, _ *Account, _, reply string, msg []byte) {
+	ar := n.decodeAppendEntryResponse(msg)
+	if ar == nil {
+		return
+	}
+	var ready []*readIndexReq
+	n.Lock()
+	if sub == n.risub && ar.term == n.riterm && n.term == n.riterm && n.State() == Leader {
+		if _, ok := n.peers[ar.peer]; ok && !n.isLearner(ar.peer) {
+			if n.riacks == nil {
+				n.riacks = make(map[string]struct{})
+			}
+			n.riacks[ar.peer] = struct{}{}
+			if len(n.riacks)+1 >= n.qn {
+				ready = n.finishReadIndexRoundLocked(n.riindex)
+			}
+		}
+	}
+	n.Unlock()
+	ar.reply = reply
+	n.resp.push(ar)
+	n.completeReadIndexes(ready)
+}
+
+// Handles the leader's reply to the read index request of the round in flight.
+func (n *raft) handleReadIndexReply(sub *subscription, _ *client, _ *Account, _, _ string, msg []byte) {
+	if len(msg) != 8 {
+		return
+	}
+	var ready []*readIndexReq
+	n.Lock()
+	if sub == n.risub {
+		ready = n.finishReadIndexRoundLocked(binary.LittleEndian.Uint64(msg))
+	}
+	n.Unlock()
+	n.completeReadIndexes(ready)
+}
+
+// Called when a peer requests a read index from us as the leader.
+func (n *raft) handleReadIndexRequest(sub *subscription, c *client, _ *Account, _, reply string, msg []byte) {
+	if reply == _EMPTY_ || n.State() != Leader {
+		return
+	}
+	// We only confirm we are still the leader, the peer waits for its own upper layer.
+	n.readIndex(readIndexTimeout, false, func(index uint64, err error) {
+		if err != nil {
+			n.debug("Not responding to read index request: %v", err)
+			return
+		}
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], index)
+		n.sendReply(reply, buf[:])
+	})
+}
+
+// Returns the reads waiting for an index we have now applied.
+// Lock should be held.
+func (n *raft) signalReadIndexWaitsLocked() []*readIndexReq {
+	if len(n.rwaits) == 0 {
+		return nil
+	}
+	var ready []*readIndexReq
+	n.rwaits = slices.DeleteFunc(n.rwaits, func(req *readIndexReq) bool {
+		if req.index <= n.applied {
+			req.done = true
+			ready = append(ready, req)
+			return true
+		}
+		return false
+	})
+	return ready
+}
+
 func (n *raft) runAsLeader() {
 	if n.State() == Closed {
 		return
 	}
 
 	n.Lock()
-	psubj, rpsubj := n.psubj, n.rpsubj
+	psubj, rpsubj, risubj := n.psubj, n.rpsubj, n.risubj
 
 	// For forwarded proposals, both normal and remove peer proposals.
 	fsub, err := n.subscribe(psubj, n.handleForwardedProposal)
@@ -2545,6 +2935,15 @@ func (n *raft) runAsLeader() {
 		n.Unlock()
 		return
 	}
+	risub, err := n.subscribe(risubj, n.handleReadIndexRequest)
+	if err != nil {
+		n.warn("Error subscribing to read index requests: %v", err)
+		n.unsubscribe(fsub)
+		n.unsubscribe(rpsub)
+		n.stepdownLocked(noLeader)
+		n.Unlock()
+		return
+	}
 	n.Unlock()
 
 	// Cleanup our subscription when we leave.
@@ -2552,6 +2951,7 @@ func (n *raft) runAsLeader() {
 		n.Lock()
 		n.unsubscribe(fsub)
 		n.unsubscribe(rpsub)
+		n.unsubscribe(risub)
 		n.Unlock()
 	}()
 
@@ -2561,7 +2961,12 @@ func (n *raft) runAsLeader() {
 	hb := time.NewTicker(hbInterval)
 	defer hb.Stop()
 
-	lq := time.NewTicker(lostQuorumCheck)
+	// With check-quorum we need to notice within an election timeout that we lost quorum.
+	lqc := lostQuorumCheck
+	if n.checkq {
+		lqc = hbInterval
+	}
+	lq := time.NewTicker(lqc)
 	defer lq.Stop()
 
 	for n.State() == Leader {
@@ -2617,7 +3022,12 @@ func (n *raft) runAsLeader() {
 			}
 		case <-lq.C:
 			if n.lostQuorum() {
-				n.stepdown(noLeader)
+				n.Lock()
+				if n.checkq {
+					n.cqsd++
+				}
+				n.stepdownLocked(noLeader)
+				n.Unlock()
 				return
 			}
 		case <-n.votes.ch:
@@ -2649,6 +3059,9 @@ func (n *raft) Quorum() bool {
 
 	nc := 0
 	for id, peer := range n.peers {
+		if n.isLearner(id) {
+			continue
+		}
 		if id == n.id || time.Since(peer.ts) < lostQuorumInterval {
 			if nc++; nc >= n.qn {
 				return true
@@ -2672,9 +3085,18 @@ func (n *raft) lostQuorumLocked() bool {
 		return false
 	}
 
+	// With check-quorum we need to have heard from a quorum within an election timeout.
+	window := lostQuorumInterval
+	if n.checkq {
+		window = minElectionTimeout
+	}
+
 	nc := 0
 	for id, peer := range n.peers {
-		if id == n.id || time.Since(peer.ts) < lostQuorumInterval {
+		if n.isLearner(id) {
+			continue
+		}
+		if id == n.id || time.Since(peer.ts) < window {
 			if nc++; nc >= n.qn {
 				return false
 			}
@@ -3068,8 +3490,8 @@ func (n *raft) trackResponse(ar *appendEntryResponse) {
 		indexUpdateQ.push(ar.index)
 	}
 
-	// Ignore items already committed.
-	if ar.index <= n.commit {
+	// Ignore items already committed, learners don't count toward quorum.
+	if ar.index <= n.commit || n.isLearner(ar.peer) {
 		n.Unlock()
 		return
 	}
@@ -3140,7 +3562,7 @@ func (n *raft) trackPeer(peer string) error {
 			isRemoved = false
 		}
 	}
-	if n.State() == Leader {
+	if n.State() == Leader && !n.isLearner(peer) {
 		if lp, ok := n.peers[peer]; !ok || !lp.kp {
 			// Check if this peer had been removed previously.
 			needPeerAdd = !isRemoved
@@ -3163,10 +3585,24 @@ func (n *raft) runAsCandidate() {
 	n.Lock()
 	// Drain old responses.
 	n.votes.drain()
+	prevoting := n.prevoting
 	n.Unlock()
 
-	// Send out our request for votes.
-	n.requestVote()
+	// Send out our request for (pre-)votes.
+	if prevoting {
+		n.requestPreVote()
+	} else {
+		n.requestVote()
+	}
+
+	// Count the pre-elections we didn't win, each of these avoided disrupting the group with a higher term.
+	defer func() {
+		if prevoting && !n.isClosed() {
+			n.Lock()
+			n.pvlost++
+			n.Unlock()
+		}
+	}()
 
 	// We vote for ourselves.
 	votes := map[string]struct{}{
@@ -3201,9 +3637,17 @@ func (n *raft) runAsCandidate() {
 			n.RLock()
 			nterm := n.term
 			csz := n.csz
+			learner := n.isLearner(vresp.peer)
 			n.RUnlock()
 
-			if vresp.granted && nterm == vresp.term {
+			// Learners don't vote, ignore if they do.
+			// Also ignore pre-votes once we are in the election and vice versa.
+			if learner || vresp.prevote != prevoting {
+				continue
+			}
+
+			// Pre-votes are granted for the next term, so the peer's term can't be ahead of ours.
+			if vresp.granted && (nterm == vresp.term || prevoting && vresp.term < nterm) {
 				// only track peers that would be our followers
 				n.trackPeer(vresp.peer)
 				if !vresp.empty {
@@ -3211,17 +3655,23 @@ func (n *raft) runAsCandidate() {
 				} else {
 					emptyVotes[vresp.peer] = struct{}{}
 				}
-				if n.wonElection(len(votes)) {
-					// Become LEADER if we have won and gotten a quorum with everyone we should hear from.
-					n.switchToLeader()
-					return
-				} else if len(votes)+len(emptyVotes) == csz {
-					// Become LEADER if we've got voted in by ALL servers.
-					// We couldn't get quorum based on just our normal votes.
-					// But, we have heard from the full cluster, and some servers came up empty.
-					// We know for sure we have the most up-to-date log.
-					n.switchToLeader()
-					return
+				// Become LEADER if we have won and gotten a quorum with everyone we should hear from.
+				// Or if we've got voted in by ALL servers. We couldn't get quorum based on just our
+				// normal votes. But, we have heard from the full cluster, and some servers came up empty.
+				// We know for sure we have the most up-to-date log.
+				if n.wonElection(len(votes)) || len(votes)+len(emptyVotes) == csz {
+					if !prevoting {
+						n.switchToLeader()
+						return
+					}
+					// We won the pre-election, so now start the actual election.
+					if !n.startElection() {
+						return
+					}
+					prevoting = false
+					votes = map[string]struct{}{n.ID(): {}}
+					emptyVotes = map[string]struct{}{}
+					n.requestVote()
 				}
 			} else if vresp.term > nterm {
 				// if we observe a bigger term, we should start over again or risk forming a quorum fully knowing
@@ -3855,11 +4305,13 @@ func (n *raft) storeToWAL(ae *appendEntry) error {
 		return n.werr
 	}
 
+	start := time.Now()
 	seq, _, err := n.wal.StoreMsg(_EMPTY_, nil, ae.buf, 0)
 	if err != nil {
 		n.setWriteErrLocked(err)
 		return err
 	}
+	n.metrics.trackAppend(time.Since(start))
 
 	// Sanity checking for now.
 	if index := ae.pindex + 1; index != seq {
@@ -3903,6 +4355,7 @@ func (n *raft) sendAppendEntry(entries []*Entry) {
 			return
 		}
 		n.active = time.Now()
+		n.metrics.trackProposals(n.active, len(entries))
 
 		// Save in memory for faster processing during applyCommit.
 		n.pae[n.pindex] = ae
@@ -4185,6 +4638,8 @@ type voteResponse struct {
 	peer    string
 	granted bool
 	empty   bool // "Empty vote", whether this peer's log is empty.
+	// internal only.
+	prevote bool // Response to a pre-vote, received on the pre-vote inbox.
 }
 
 const voteResponseLen = 8 + 8 + 1
@@ -4238,13 +4693,19 @@ func (n *raft) processVoteRequest(vr *voteRequest) error {
 	}
 	n.debug("Received a voteRequest %+v", vr)
 
+	// Learners don't vote.
+	if n.IsLearner() {
+		n.debug("Ignoring voteRequest, learner only")
+		return nil
+	}
+
 	if err := n.trackPeer(vr.candidate); err != nil {
 		return err
 	}
 
 	n.Lock()
 
-	vresp := &voteResponse{n.term, n.id, false, n.pindex == 0}
+	vresp := &voteResponse{term: n.term, peer: n.id, empty: n.pindex == 0}
 	defer n.debug("Sending a voteResponse %+v -> %q", vresp, vr.reply)
 
 	// Ignore if we are newer. This is important so that we don't accidentally process
@@ -4310,6 +4771,107 @@ func (n *raft) handleVoteRequest(sub *subscription, c *client, _ *Account, subje
 	n.reqs.push(vr)
 }
 
+// startElection increments our term after winning a pre-election.
+// Returns false if we are no longer a candidate.
+func (n *raft) startElection() bool {
+	n.Lock()
+	defer n.Unlock()
+	if n.State() != Candidate || !n.prevoting {
+		return false
+	}
+	n.debug("Won pre-election, starting election")
+	n.prevoting = false
+	n.term++
+	n.votes.drain()
+	n.resetElectionTimeout()
+	return true
+}
+
+// requestPreVote asks the peers whether they would vote for us in the next term.
+func (n *raft) requestPreVote() {
+	n.Lock()
+	if n.State() != Candidate {
+		n.Unlock()
+		return
+	}
+	vr := voteRequest{n.term + 1, n.pterm, n.pindex, n.id, _EMPTY_}
+	subj, reply := n.pvsubj, n.pvreply
+	n.Unlock()
+
+	n.debug("Sending out preVoteRequest %+v", vr)
+
+	// Now send it out.
+	n.sendRPC(subj, reply, vr.encode())
+}
+
+func (n *raft) handlePreVoteRequest(sub *subscription, c *client, _ *Account, subject, reply string, msg []byte) {
+	vr := decodeVoteRequest(msg, reply)
+	if vr == nil {
+		n.error("Received malformed pre-vote request for %q", n.group)
+		return
+	}
+	n.processPreVoteRequest(vr)
+}
+
+// processPreVoteRequest tells a candidate whether we would vote for it in the term it proposes.
+// This doesn't change our term or vote. We don't grant pre-votes while we still hear from the
+// leader, so a node that rejoins after a partition can't disrupt the group with a higher term.
+func (n *raft) processPreVoteRequest(vr *voteRequest) {
+	n.debug("Received a preVoteRequest %+v", vr)
+
+	// Learners don't vote.
+	if n.IsLearner() {
+		n.debug("Ignoring preVoteRequest, learner only")
+		return
+	}
+
+	n.Lock()
+	vresp := &voteResponse{term: n.term, peer: n.id, empty: n.pindex == 0}
+	if vr.term > n.term && (vr.lastTerm > n.pterm || vr.lastTerm == n.pterm && vr.lastIndex >= n.pindex) {
+		if n.heardFromLeaderLocked() {
+			n.pvreject++
+		} else {
+			vresp.granted = true
+			// Same as for votes, when initializing we only need quorum.
+			if vresp.empty && n.initializing {
+				vresp.empty = false
+			}
+		}
+	}
+	n.Unlock()
+
+	n.debug("Sending a preVoteResponse %+v -> %q", vresp, vr.reply)
+	n.sendReply(vr.reply, vresp.encode())
+}
+
+// Returns true if we are the leader or heard from it within the minimum election timeout.
+// Lock should be held.
+func (n *raft) heardFromLeaderLocked() bool {
+	if n.State() == Leader {
+		return true
+	}
+	if n.leader == noLeader {
+		return false
+	}
+	ps := n.peers[n.leader]
+	return ps != nil && time.Since(ps.ts) < minElectionTimeout
+}
+
+func (n *raft) handlePreVoteResponse(sub *subscription, c *client, _ *Account, _, reply string, msg []byte) {
+	vr := decodeVoteResponse(msg)
+	n.debug("Received a preVoteResponse %+v", vr)
+	if vr == nil {
+		n.error("Received malformed pre-vote response for %q", n.group)
+		return
+	}
+	if n.State() != Candidate {
+		n.debug("Ignoring old pre-vote response, we have stepped down")
+		return
+	}
+	vr.prevote = true
+	n.votes.push(vr)
+}
+
 func (n *raft) requestVote() {
 	n.Lock()
 	if n.State() != Candidate {
@@ -4447,7 +5009,7 @@ func (n *raft) switchToCandidate() {
 
 	// If we are catching up or are in observer mode we can not switch.
 	// Avoid petitioning to become leader if we're behind on applies.
-	if n.observer || n.paused || n.applied < n.commit {
+	if n.observer || n.learner || n.paused || n.applied < n.commit {
 		n.resetElect(minElectionTimeout / 4)
 		return
 	}
@@ -4461,8 +5023,14 @@ func (n *raft) switchToCandidate() {
 			n.llqrt = time.Now()
 		}
 	}
-	// Increment the term.
-	n.term++
+	// Increment the term, unless we need to win a pre-election first. Leadership
+	// transfers skip it since the current leader asked us to take over.
+	if n.prevote && !n.lxfer && n.peersSupportPreVote() {
+		n.prevoting = true
+	} else {
+		n.prevoting = false
+		n.term++
+	}
 	// Clear current Leader.
 	n.updateLeader(noLeader)
 	n.switchState(Candidate)
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -122,9 +123,15 @@ func (c *cluster) createRaftGroupEx(name string, numMembers int, smf smFactory,
 
 func (c *cluster) createRaftGroupWithPeers(name string, servers []*Server, smf smFactory, st StorageType) smGroup {
 	c.t.Helper()
+	return c.createRaftGroupWithLearners(name, servers, nil, smf, st)
+}
+
+// Creates a raft group with the servers as voters and learners as non-voting members.
+func (c *cluster) createRaftGroupWithLearners(name string, servers, learnerServers []*Server, smf smFactory, st StorageType) smGroup {
+	c.t.Helper()
 
 	var sg smGroup
-	var peers []string
+	var peers, learners []string
 
 	for _, s := range servers {
 		// generate peer names.
@@ -132,8 +139,13 @@ func (c *cluster) createRaftGroupWithPeers(name string, servers []*Server, smf s
 		peers = append(peers, s.sys.shash)
 		s.mu.RUnlock()
 	}
+	for _, s := range learnerServers {
+		s.mu.RLock()
+		learners = append(learners, s.sys.shash)
+		s.mu.RUnlock()
+	}
 
-	for _, s := range servers {
+	for _, s := range append(slices.Clone(servers), learnerServers...) {
 		var cfg *RaftConfig
 		if st == FileStorage {
 			fs, err := newFileStore(
@@ -141,11 +153,11 @@ func (c *cluster) createRaftGroupWithPeers(name string, servers []*Server, smf s
 				StreamConfig{Name: name, Storage: FileStorage},
 			)
 			require_NoError(c.t, err)
-			cfg = &RaftConfig{Name: name, Store: c.t.TempDir(), Log: fs}
+			cfg = &RaftConfig{Name: name, Store: c.t.TempDir(), Log: fs, Learners: learners}
 		} else {
 			ms, err := newMemStore(&StreamConfig{Name: name, Storage: MemoryStorage})
 			require_NoError(c.t, err)
-			cfg = &RaftConfig{Name: name, Store: c.t.TempDir(), Log: ms}
+			cfg = &RaftConfig{Name: name, Store: c.t.TempDir(), Log: ms, Learners: learners}
 		}
 		s.bootstrapRaftNode(cfg, peers, true)
 		n, err := s.startRaftNode(globalAccountName, cfg, pprofLabels{})
@@ -0,0 +1,101 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+)
+
+// Upper bounds of the WAL append latency histogram buckets.
+// Appends slower than the last bound are only counted in the total.
+var raftAppendLatencyBounds = [...]time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	time.Second,
+}
+
+// Interval over which the proposal rate is calculated.
+const raftProposalRateInterval = time.Second
+
+// raftMetrics tracks the WAL and throughput metrics of a Raft group.
+// The raft lock should be held to access these.
+type raftMetrics struct {
+	appends  uint64                               // Total number of WAL appends
+	appendNs uint64                               // Total time spent appending to the WAL
+	appendLe [len(raftAppendLatencyBounds)]uint64 // Appends per latency bucket, not cumulative
+
+	props  uint64    // Total number of entries proposed as leader
+	pstart time.Time // Start of the current proposal rate interval
+	pcount uint64    // Entries proposed in the current interval
+	prate  float64   // Entries proposed per second in the last interval
+}
+
+// trackAppend records the latency of a WAL append.
+func (m *raftMetrics) trackAppend(d time.Duration) {
+	m.appends++
+	m.appendNs += uint64(d)
+	for i, le := range raftAppendLatencyBounds {
+		if d <= le {
+			m.appendLe[i]++
+			break
+		}
+	}
+}
+
+// trackProposals records entries proposed by us as leader.
+func (m *raftMetrics) trackProposals(now time.Time, entries int) {
+	m.props += uint64(entries)
+	if elapsed := now.Sub(m.pstart); elapsed >= raftProposalRateInterval {
+		// Rate of the last interval, if it was not too long ago.
+		if elapsed < 2*raftProposalRateInterval {
+			m.prate = float64(m.pcount) / elapsed.Seconds()
+		} else {
+			m.prate = 0
+		}
+		m.pstart, m.pcount = now, 0
+	}
+	m.pcount += uint64(entries)
+}
+
+// proposalRate returns the entries proposed per second.
+func (m *raftMetrics) proposalRate(now time.Time) float64 {
+	if now.Sub(m.pstart) >= 2*raftProposalRateInterval {
+		return 0
+	}
+	return m.prate
+}
+
+// appendLatency returns the WAL append latency histogram with cumulative bucket counts.
+func (m *raftMetrics) appendLatency() *RaftzLatencyHistogram {
+	h := &RaftzLatencyHistogram{
+		Count:   m.appends,
+		Sum:     time.Duration(m.appendNs),
+		Buckets: make([]RaftzLatencyBucket, 0, len(raftAppendLatencyBounds)),
+	}
+	var count uint64
+	for i, le := range raftAppendLatencyBounds {
+		count += m.appendLe[i]
+		h.Buckets = append(h.Buckets, RaftzLatencyBucket{LE: le, Count: count})
+	}
+	return h
+}
@@ -241,6 +241,389 @@ func TestNRGObserverMode(t *testing.T) {
 	}
 }
 
+func TestNRGLearners(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R4S", 4)
+	defer c.shutdown()
+
+	rg := c.createRaftGroupWithLearners("TEST", c.servers[:3], c.servers[3:], newStateAdder, FileStorage)
+	lsm := rg.waitOnLeader()
+	require_NotNil(t, lsm)
+	leader := lsm.(*stateAdder)
+	learner := rg[3].(*stateAdder)
+	require_True(t, learner.node().IsLearner())
+
+	// The learner applies all entries.
+	leader.proposeDelta(1)
+	leader.proposeDelta(2)
+	rg.waitOnTotal(t, 3)
+
+	// But is not part of the peers or cluster size.
+	ln := leader.node()
+	require_Equal(t, ln.ClusterSize(), 3)
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		for _, p := range ln.Peers() {
+			if p.ID == learner.node().ID() {
+				return fmt.Errorf("learner is reported as a peer")
+			}
+		}
+		if learners := ln.Learners(); len(learners) != 1 || learners[0].ID != learner.node().ID() || learners[0].Lag > 0 {
+			return fmt.Errorf("expected learner to be current, got %+v", learners)
+		}
+		return nil
+	})
+
+	// Learners don't count toward quorum, so with two voters down nothing is committed.
+	for _, sm := range rg[:3] {
+		if sm != lsm && sm.node().State() != Closed {
+			sm.stop()
+			break
+		}
+	}
+	leader.proposeDelta(4)
+	checkFor(t, 2*time.Second, 100*time.Millisecond, func() error {
+		if lt, st := leader.total(), learner.total(); lt != 7 || st != 7 {
+			return fmt.Errorf("expected total of 7, got %d and %d", lt, st)
+		}
+		return nil
+	})
+	for _, sm := range rg[:3] {
+		if sm != lsm && sm.node().State() != Closed {
+			sm.stop()
+			break
+		}
+	}
+	leader.proposeDelta(8)
+	time.Sleep(250 * time.Millisecond)
+	require_Equal(t, leader.total(), 7)
+	require_Equal(t, learner.total(), 7)
+
+	// Once promoted the learner is a voter and restores quorum.
+	for _, sm := range rg {
+		if sm.node().State() != Closed {
+			sm.node().SetLearners(nil)
+		}
+	}
+	require_False(t, learner.node().IsLearner())
+	checkFor(t, 5*time.Second, 100*time.Millisecond, func() error {
+		if total := learner.total(); total != 15 {
+			return fmt.Errorf("expected total of 15, got %d", total)
+		}
+		if csz := ln.ClusterSize(); csz != 4 {
+			return fmt.Errorf("expected cluster size of 4, got %d", csz)
+		}
+		return nil
+	})
+}
+
+func TestNRGReadIndex(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	lsm := rg.waitOnLeader()
+	require_NotNil(t, lsm)
+	leader := lsm.(*stateAdder)
+
+	leader.proposeDelta(1)
+	leader.proposeDelta(2)
+	rg.waitOnTotal(t, 3)
+
+	// Leader and followers return a read index they have applied.
+	for _, sm := range rg {
+		n := sm.node()
+		index, err := n.ReadIndex(2 * time.Second)
+		require_NoError(t, err)
+		_, commit, applied := n.Progress()
+		require_True(t, index > 0)
+		require_True(t, commit >= index)
+		require_True(t, applied >= index)
+		require_Equal(t, sm.(*stateAdder).total(), 3)
+	}
+
+	// A follower that has not applied up to the read index waits for it.
+	var follower *stateAdder
+	for _, sm := range rg {
+		if sm != lsm {
+			follower = sm.(*stateAdder)
+			break
+		}
+	}
+	fn := follower.node()
+	require_NoError(t, fn.PauseApply())
+	leader.proposeDelta(4)
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if total := leader.total(); total != 7 {
+			return fmt.Errorf("expected total of 7, got %d", total)
+		}
+		return nil
+	})
+	_, err := fn.ReadIndex(250 * time.Millisecond)
+	require_Error(t, err, errReadIndexTimeout)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := fn.ReadIndex(2 * time.Second)
+		errCh <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	fn.ResumeApply()
+	select {
+	case err := <-errCh:
+		require_NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timeout waiting for read index")
+	}
+	require_Equal(t, follower.total(), 7)
+
+	// Concurrent reads are batched into shared rounds and answered asynchronously.
+	for _, n := range []*raft{leader.node().(*raft), fn.(*raft)} {
+		n.RLock()
+		gen := n.rigen
+		n.RUnlock()
+		const reads = 100
+		errCh := make(chan error, reads)
+		for i := 0; i < reads; i++ {
+			n.ReadIndexAsync(2*time.Second, func(_ uint64, err error) { errCh <- err })
+		}
+		for i := 0; i < reads; i++ {
+			select {
+			case err := <-errCh:
+				require_NoError(t, err)
+			case <-time.After(3 * time.Second):
+				t.Fatalf("Timeout waiting for read index")
+			}
+		}
+		n.RLock()
+		rounds := n.rigen - gen
+		n.RUnlock()
+		require_True(t, rounds >= 1 && rounds <= 2)
+	}
+
+	// Without a quorum the leader can't confirm it is still the leader.
+	for _, sm := range rg {
+		if sm != lsm {
+			sm.stop()
+		}
+	}
+	_, err = leader.node().ReadIndex(250 * time.Millisecond)
+	require_Error(t, err, errReadIndexTimeout)
+}
+
+func TestNRGPreVote(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", "store_dir: '%s', raft_pre_vote: true}", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	lsm := rg.waitOnLeader()
+	require_NotNil(t, lsm)
+	leader := lsm.(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	var follower *stateAdder
+	for _, sm := range rg {
+		if sm != lsm {
+			follower = sm.(*stateAdder)
+			break
+		}
+	}
+	fn := follower.node().(*raft)
+	require_True(t, fn.prevote)
+	term := leader.node().Term()
+
+	fn.RLock()
+	pvlost := fn.pvlost
+	fn.RUnlock()
+
+	// Partition the follower from the leader's append entries, like a node that can't
+	// hear from the leader. It campaigns but loses the pre-election as the others do.
+	fn.Lock()
+	fn.unsubscribe(fn.aesub)
+	fn.Unlock()
+	checkFor(t, 10*time.Second, 50*time.Millisecond, func() error {
+		fn.RLock()
+		defer fn.RUnlock()
+		if fn.pvlost <= pvlost {
+			return errors.New("expected pre-election to be lost")
+		}
+		return nil
+	})
+	var rejected uint64
+	for _, sm := range rg {
+		n := sm.node().(*raft)
+		n.RLock()
+		rejected += n.pvreject
+		n.RUnlock()
+	}
+	require_True(t, rejected > 0)
+
+	// Neither the leader nor the term changed.
+	require_True(t, leader.node().Leader())
+	require_Equal(t, leader.node().Term(), term)
+	require_Equal(t, fn.Term(), term)
+
+	// Once healed the follower rejoins without disrupting the group.
+	fn.Lock()
+	fn.aesub, _ = fn.subscribe(fn.asubj, fn.handleAppendEntry)
+	fn.Unlock()
+	leader.proposeDelta(2)
+	rg.waitOnTotal(t, 3)
+	require_True(t, leader.node().Leader())
+	require_Equal(t, leader.node().Term(), term)
+
+	// Counters are reported in raftz.
+	rz := follower.server().Raftz(&RaftzOptions{AccountFilter: globalAccountName, GroupFilter: "TEST"})
+	require_NotNil(t, rz)
+	info := (*rz)[globalAccountName]["TEST"]
+	require_True(t, info.PreVote)
+	require_False(t, info.CheckQuorum)
+	require_True(t, info.PreVotesLost > 0)
+
+	// Once the leader is gone the pre-election is won and a new leader elected.
+	leader.stop()
+	checkFor(t, 10*time.Second, 100*time.Millisecond, func() error {
+		for _, sm := range rg {
+			if sm != lsm && sm.node().Leader() {
+				return nil
+			}
+		}
+		return errors.New("no new leader")
+	})
+	for _, sm := range rg {
+		if sm != lsm && sm.node().Leader() {
+			require_True(t, sm.node().Term() > term)
+		}
+	}
+}
+
+func TestNRGPreVoteOlderPeers(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", "store_dir: '%s', raft_pre_vote: true}", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	lsm := rg.waitOnLeader()
+	require_NotNil(t, lsm)
+	leader := lsm.(*stateAdder)
+	leader.proposeDelta(1)
+	rg.waitOnTotal(t, 1)
+
+	var follower *stateAdder
+	for _, sm := range rg {
+		if sm != lsm {
+			follower = sm.(*stateAdder)
+			break
+		}
+	}
+	fn := follower.node().(*raft)
+	fn.RLock()
+	require_True(t, fn.peersSupportPreVote())
+	fn.RUnlock()
+
+	// A peer that doesn't advertise pre-votes won't answer them, whatever its
+	// version, so a normal election is held.
+	s := follower.server()
+	lid := leader.node().ID()
+	si, ok := s.nodeToInfo.Load(lid)
+	require_True(t, ok)
+	ni := si.(nodeInfo)
+	require_True(t, ni.preVote)
+	ni.preVote = false
+	s.nodeToInfo.Store(lid, ni)
+
+	term := fn.Term()
+	fn.switchToCandidate()
+	fn.RLock()
+	prevoting := fn.prevoting
+	fn.RUnlock()
+	require_False(t, prevoting)
+	require_Equal(t, fn.Term(), term+1)
+}
+
+func TestNRGCheckQuorum(t *testing.T) {
+	tmpl := strings.Replace(jsClusterTempl, "store_dir: '%s'}", "store_dir: '%s', raft_check_quorum: true}", 1)
+	c := createJetStreamClusterWithTemplate(t, tmpl, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	lsm := rg.waitOnLeader()
+	require_NotNil(t, lsm)
+	leader := lsm.(*stateAdder)
+	ln := leader.node().(*raft)
+	require_True(t, ln.checkq)
+
+	// The leader steps down within an election timeout after losing quorum.
+	for _, sm := range rg {
+		if sm != lsm {
+			sm.stop()
+		}
+	}
+	checkFor(t, 2*minElectionTimeout, 50*time.Millisecond, func() error {
+		if ln.State() == Leader {
+			return errors.New("still leader")
+		}
+		return nil
+	})
+
+	rz := leader.server().Raftz(&RaftzOptions{AccountFilter: globalAccountName, GroupFilter: "TEST"})
+	require_NotNil(t, rz)
+	info := (*rz)[globalAccountName]["TEST"]
+	require_True(t, info.CheckQuorum)
+	require_Equal(t, info.CheckQuorumStepDowns, 1)
+}
+
+func TestNRGRaftzMetrics(t *testing.T) {
+	c := createJetStreamClusterExplicit(t, "R3S", 3)
+	defer c.shutdown()
+
+	rg := c.createRaftGroup("TEST", 3, newStateAdder)
+	leader := rg.waitOnLeader().(*stateAdder)
+
+	for i := 0; i < 10; i++ {
+		leader.proposeDelta(1)
+	}
+	rg.waitOnTotal(t, 10)
+
+	raftz := func(sa *stateAdder) *RaftzGroupMetrics {
+		t.Helper()
+		rz := sa.server().Raftz(&RaftzOptions{AccountFilter: globalAccountName, GroupFilter: "TEST"})
+		require_NotNil(t, rz)
+		info := (*rz)[globalAccountName]["TEST"]
+		require_NotNil(t, info.Metrics)
+		return info.Metrics
+	}
+
+	m := raftz(leader)
+	// Proposals may be batched into fewer WAL appends.
+	require_True(t, m.Proposals >= 10)
+	require_Equal(t, m.SnapshotAge, _EMPTY_)
+	require_True(t, m.AppendLatency.Count > 0)
+	require_True(t, m.AppendLatency.Sum > 0)
+	require_Len(t, len(m.AppendLatency.Buckets), len(raftAppendLatencyBounds))
+	// Buckets are cumulative.
+	var last uint64
+	for _, b := range m.AppendLatency.Buckets {
+		require_True(t, b.Count >= last)
+		last = b.Count
+	}
+	require_True(t, last <= m.AppendLatency.Count)
+
+	// Followers append to their WAL as well, but don't count proposals.
+	for _, sm := range rg {
+		if sm == leader {
+			continue
+		}
+		fm := raftz(sm.(*stateAdder))
+		require_Equal(t, fm.Proposals, 0)
+		require_True(t, fm.AppendLatency.Count > 0)
+	}
+
+	leader.snapshot(t)
+	m = raftz(leader)
+	require_NotEqual(t, m.SnapshotAge, _EMPTY_)
+}
+
 func TestNRGAEFromOldLeader(t *testing.T) {
 	c := createJetStreamClusterExplicit(t, "R3S", 3)
 	defer c.shutdown()
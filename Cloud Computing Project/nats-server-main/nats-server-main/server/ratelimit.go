@@ -0,0 +1,342 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+)
+
+// RateLimitAction is what happens to a published message that exceeds a rate limit.
+type RateLimitAction string
+
+const (
+	// RateLimitDrop drops the message and sends a rate limit -ERR to the client. This is the default.
+	RateLimitDrop = RateLimitAction("drop")
+	// RateLimitPause pauses reading from the client until the message is within the limit.
+	RateLimitPause = RateLimitAction("pause")
+	// RateLimitDisconnect closes the client connection.
+	RateLimitDisconnect = RateLimitAction("disconnect")
+)
+
+// Used to pick the action when several limits are exceeded.
+func (a RateLimitAction) severity() int {
+	switch a {
+	case RateLimitDrop:
+		return 1
+	case RateLimitPause:
+		return 2
+	case RateLimitDisconnect:
+		return 3
+	}
+	return 0
+}
+
+// Longest we will pause reading from a client for a single message.
+const rateLimitMaxPause = time.Second
+
+// Prefix of JWT user claim tags carrying a rate limit, e.g.
+// "rate_limit:msgs=1000,bytes=1m,subject=orders.>,action=pause".
+const rateLimitTagPrefix = "rate_limit:"
+
+// RateLimit is a token bucket limit on what a user publishes, in messages and/or
+// bytes per second, optionally scoped to a subject pattern. Buckets hold one
+// second worth of tokens, which is the largest burst allowed.
+type RateLimit struct {
+	Subject string          `json:"subject,omitempty"`
+	Msgs    int64           `json:"msgs,omitempty"`
+	Bytes   int64           `json:"bytes,omitempty"`
+	Action  RateLimitAction `json:"action,omitempty"`
+	// Set for limits from JWT tags, which are lower cased.
+	foldCase bool
+}
+
+func (rl *RateLimit) validate() error {
+	if rl.Msgs < 0 || rl.Bytes < 0 {
+		return fmt.Errorf("rate limit can not be negative")
+	}
+	if rl.Msgs == 0 && rl.Bytes == 0 {
+		return fmt.Errorf("rate limit requires msgs or bytes")
+	}
+	if rl.Subject != _EMPTY_ && !IsValidSubject(rl.Subject) {
+		return fmt.Errorf("invalid rate limit subject %q", rl.Subject)
+	}
+	switch rl.Action {
+	case _EMPTY_, RateLimitDrop, RateLimitPause, RateLimitDisconnect:
+	default:
+		return fmt.Errorf("invalid rate limit action %q", rl.Action)
+	}
+	return nil
+}
+
+func cloneRateLimits(limits []*RateLimit) []*RateLimit {
+	if limits == nil {
+		return nil
+	}
+	clone := make([]*RateLimit, 0, len(limits))
+	for _, rl := range limits {
+		l := *rl
+		clone = append(clone, &l)
+	}
+	return clone
+}
+
+// RateLimitStats are the counters of messages that exceeded a rate limit.
+type RateLimitStats struct {
+	Dropped int64 `json:"dropped,omitempty"`
+	Paused  int64 `json:"paused,omitempty"`
+}
+
+// tokenBucket refills at rate tokens per second up to rate tokens.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   int64
+}
+
+func newTokenBucket(rate int64, now int64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+func (tb *tokenBucket) refill(now int64) {
+	if elapsed := now - tb.last; elapsed > 0 {
+		tb.tokens = min(tb.rate, tb.tokens+tb.rate*float64(elapsed)/float64(time.Second))
+		tb.last = now
+	}
+}
+
+// wait returns how long until the bucket holds at least min tokens.
+func (tb *tokenBucket) wait(min float64) time.Duration {
+	if tb.tokens >= min {
+		return 0
+	}
+	return time.Duration((min - tb.tokens) / tb.rate * float64(time.Second))
+}
+
+// A configured limit with its buckets.
+type rateLimitBucket struct {
+	*RateLimit
+	msgs  *tokenBucket
+	bytes *tokenBucket
+}
+
+func (rb *rateLimitBucket) action() RateLimitAction {
+	if rb.Action == _EMPTY_ {
+		return RateLimitDrop
+	}
+	return rb.Action
+}
+
+// matches returns whether the limit applies to subject. The lower cased
+// subject is computed once for limits that need it and kept in folded.
+func (rb *rateLimitBucket) matches(subject string, folded *string) bool {
+	if rb.Subject == _EMPTY_ {
+		return true
+	}
+	if rb.foldCase {
+		if *folded == _EMPTY_ {
+			*folded = strings.ToLower(subject)
+		}
+		subject = *folded
+	}
+	return subjectIsSubsetMatch(subject, rb.Subject)
+}
+
+// wait returns how long until a message is within the limit. A message only
+// needs a positive byte balance, so messages larger than the bucket can pass.
+func (rb *rateLimitBucket) wait() time.Duration {
+	var d time.Duration
+	if rb.msgs != nil {
+		d = rb.msgs.wait(1)
+	}
+	if rb.bytes != nil {
+		// Smallest amount of tokens we would consider positive.
+		d = max(d, rb.bytes.wait(1/rb.bytes.rate))
+	}
+	return d
+}
+
+// rateLimiter enforces the rate limits of a client. It is only used from the
+// client's readLoop, the counters are read with atomics.
+type rateLimiter struct {
+	limits  []*rateLimitBucket
+	dropped int64
+	paused  int64
+}
+
+func newRateLimiter(limits []*RateLimit) *rateLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	now := time.Now().UnixNano()
+	rl := &rateLimiter{}
+	for _, l := range limits {
+		rl.limits = append(rl.limits, &rateLimitBucket{
+			RateLimit: l,
+			msgs:      newTokenBucket(l.Msgs, now),
+			bytes:     newTokenBucket(l.Bytes, now),
+		})
+	}
+	return rl
+}
+
+// check returns whether a message can be published right away. If not, the
+// most severe action of the exceeded limits is returned along with how long
+// until the message would be within all the limits.
+func (rl *rateLimiter) check(subject string, size int, now int64) (bool, RateLimitAction, time.Duration) {
+	var action RateLimitAction
+	var wait time.Duration
+	var folded string
+	for _, rb := range rl.limits {
+		if !rb.matches(subject, &folded) {
+			continue
+		}
+		if rb.msgs != nil {
+			rb.msgs.refill(now)
+		}
+		if rb.bytes != nil {
+			rb.bytes.refill(now)
+		}
+		if d := rb.wait(); d > 0 {
+			wait = max(wait, d)
+			if a := rb.action(); a.severity() > action.severity() {
+				action = a
+			}
+		}
+	}
+	if wait == 0 {
+		rl.consume(subject, size)
+		return true, _EMPTY_, 0
+	}
+	return false, action, wait
+}
+
+// consume takes a message from the buckets it matches. The balance can go negative.
+func (rl *rateLimiter) consume(subject string, size int) {
+	var folded string
+	for _, rb := range rl.limits {
+		if !rb.matches(subject, &folded) {
+			continue
+		}
+		if rb.msgs != nil {
+			rb.msgs.tokens--
+		}
+		if rb.bytes != nil {
+			rb.bytes.tokens -= float64(size)
+		}
+	}
+}
+
+func (rl *rateLimiter) stats() *RateLimitStats {
+	if rl == nil {
+		return nil
+	}
+	return &RateLimitStats{
+		Dropped: atomic.LoadInt64(&rl.dropped),
+		Paused:  atomic.LoadInt64(&rl.paused),
+	}
+}
+
+// checkRateLimits is called from processInboundClientMsg, and returns
+// whether the message should be processed.
+func (c *client) checkRateLimits(rl *rateLimiter, acc *Account, size int) bool {
+	subject := bytesToString(c.pa.subject)
+	ok, action, wait := rl.check(subject, size, time.Now().UnixNano())
+	if ok {
+		return true
+	}
+
+	acc.stats.Lock()
+	acc.stats.rateLimited++
+	acc.stats.Unlock()
+
+	switch action {
+	case RateLimitPause:
+		atomic.AddInt64(&rl.paused, 1)
+		if wait > rateLimitMaxPause {
+			wait = rateLimitMaxPause
+		}
+		delay := time.NewTimer(wait)
+		defer delay.Stop()
+		select {
+		case <-delay.C:
+		case <-c.srv.quitCh:
+			return false
+		}
+		rl.consume(subject, size)
+		return true
+	case RateLimitDisconnect:
+		atomic.AddInt64(&rl.dropped, 1)
+		c.RateLimitWarnf("Rate limit exceeded for publish to %q, closing connection", subject)
+		c.sendErrAndErr(fmt.Sprintf("Rate Limit Exceeded for Publish to %q", subject))
+		c.closeConnection(RateLimitExceeded)
+	default:
+		atomic.AddInt64(&rl.dropped, 1)
+		// The connection stays open, the message is only dropped.
+		errTxt := fmt.Sprintf("Rate Limit Exceeded for Publish to %q, Message Dropped", subject)
+		if mt, _ := c.isMsgTraceEnabled(); mt != nil {
+			mt.setIngressError(errTxt)
+		}
+		c.sendErr(errTxt)
+	}
+	return false
+}
+
+// parseRateLimitTags returns the rate limits found in the tags of a JWT user claim.
+// Tags are lower cased by the JWT library, so their subjects are matched
+// regardless of case, e.g. "orders.>" also limits "ORDERS.new".
+func parseRateLimitTags(tags jwt.TagList) ([]*RateLimit, error) {
+	var limits []*RateLimit
+	for _, tag := range tags {
+		spec, ok := strings.CutPrefix(tag, rateLimitTagPrefix)
+		if !ok {
+			continue
+		}
+		rl := &RateLimit{foldCase: true}
+		for _, field := range strings.Split(spec, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(field), "=")
+			var err error
+			switch k {
+			case "msgs":
+				rl.Msgs, err = strconv.ParseInt(v, 10, 64)
+			case "bytes":
+				if rl.Bytes, err = strconv.ParseInt(v, 10, 64); err != nil {
+					rl.Bytes, err = getStorageSize(strings.ToUpper(v))
+				}
+			case "subject":
+				rl.Subject = strings.ToLower(v)
+			case "action":
+				rl.Action = RateLimitAction(v)
+			default:
+				err = fmt.Errorf("unknown field %q", k)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("rate limit tag %q: %v", tag, err)
+			}
+		}
+		if err := rl.validate(); err != nil {
+			return nil, fmt.Errorf("rate limit tag %q: %v", tag, err)
+		}
+		limits = append(limits, rl)
+	}
+	return limits, nil
+}
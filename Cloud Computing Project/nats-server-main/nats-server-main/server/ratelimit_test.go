@@ -0,0 +1,286 @@
+// Copyright 2025 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats.go"
+)
+
+func TestRateLimiterCheck(t *testing.T) {
+	rl := newRateLimiter([]*RateLimit{
+		{Msgs: 10},
+		{Subject: "big.>", Bytes: 1000, Action: RateLimitPause},
+	})
+	now := time.Now().UnixNano()
+
+	// Full burst of one second is allowed.
+	for i := 0; i < 10; i++ {
+		ok, _, _ := rl.check("foo", 10, now)
+		require_True(t, ok)
+	}
+	ok, action, wait := rl.check("foo", 10, now)
+	require_False(t, ok)
+	require_Equal(t, action, RateLimitDrop)
+	require_True(t, wait > 0 && wait <= 100*time.Millisecond)
+
+	// Refills over time.
+	now += int64(100 * time.Millisecond)
+	ok, _, _ = rl.check("foo", 10, now)
+	require_True(t, ok)
+
+	// Subject scoped limit only applies to matching subjects, and a message
+	// larger than the bucket still passes when the balance is positive.
+	now += int64(time.Second)
+	ok, _, _ = rl.check("big.one", 5000, now)
+	require_True(t, ok)
+	ok, action, wait = rl.check("big.one", 1, now+int64(time.Second))
+	require_False(t, ok)
+	require_Equal(t, action, RateLimitPause)
+	require_True(t, wait > 0)
+	ok, _, _ = rl.check("foo", 5000, now+int64(time.Second))
+	require_True(t, ok)
+
+	// No limits, no limiter.
+	require_True(t, newRateLimiter(nil) == nil)
+	require_True(t, newRateLimiter(nil).stats() == nil)
+}
+
+func TestRateLimitTags(t *testing.T) {
+	limits, err := parseRateLimitTags(jwt.TagList{"team:a", "rate_limit:msgs=100,bytes=1m,subject=orders.>,action=pause", "rate_limit:bytes=500"})
+	require_NoError(t, err)
+	require_Len(t, len(limits), 2)
+	require_Equal(t, *limits[0], RateLimit{Subject: "orders.>", Msgs: 100, Bytes: 1024 * 1024, Action: RateLimitPause, foldCase: true})
+	require_Equal(t, *limits[1], RateLimit{Bytes: 500, foldCase: true})
+
+	// Tag subjects are lower cased, so they match regardless of case.
+	uc := jwt.NewUserClaims("U")
+	uc.Tags.Add("rate_limit:msgs=1,subject=Orders.>")
+	limits, err = parseRateLimitTags(uc.Tags)
+	require_NoError(t, err)
+	require_Len(t, len(limits), 1)
+	require_Equal(t, limits[0].Subject, "orders.>")
+	rl := newRateLimiter(limits)
+	now := time.Now().UnixNano()
+	ok, _, _ := rl.check("ORDERS.new", 0, now)
+	require_True(t, ok)
+	ok, _, _ = rl.check("Orders.new", 0, now)
+	require_False(t, ok)
+	ok, _, _ = rl.check("other", 0, now)
+	require_True(t, ok)
+
+	for _, tag := range []string{
+		"rate_limit:msgs=-1",
+		"rate_limit:subject=foo",
+		"rate_limit:msgs=1,action=block",
+		"rate_limit:msgs=1,subject=foo..bar",
+		"rate_limit:msgs=1,burst=5",
+		"rate_limit:bytes=lots",
+	} {
+		_, err := parseRateLimitTags(jwt.TagList{tag})
+		require_Error(t, err)
+	}
+
+	// Limits are picked up from user claims.
+	uc = jwt.NewUserClaims("U")
+	uc.Tags.Add("rate_limit:msgs=5")
+	nu := buildInternalNkeyUser(uc, nil, NewAccount("A"))
+	require_Len(t, len(nu.RateLimits), 1)
+	require_Equal(t, nu.RateLimits[0].Msgs, 5)
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		authorization {
+			users = [
+				{user: a, password: pwd, rate_limit: {msgs: 100, bytes: 1KB}}
+				{user: b, password: pwd, rate_limits: [
+					{subject: "orders.>", msgs: 10, action: pause}
+					{bytes: "2M", action: disconnect}
+				]}
+			]
+		}
+	`))
+	opts, err := ProcessConfigFile(conf)
+	require_NoError(t, err)
+	require_Len(t, len(opts.Users), 2)
+	for _, u := range opts.Users {
+		switch u.Username {
+		case "a":
+			require_Len(t, len(u.RateLimits), 1)
+			require_Equal(t, *u.RateLimits[0], RateLimit{Msgs: 100, Bytes: 1024})
+		case "b":
+			require_Len(t, len(u.RateLimits), 2)
+			require_Equal(t, *u.RateLimits[0], RateLimit{Subject: "orders.>", Msgs: 10, Action: RateLimitPause})
+			require_Equal(t, *u.RateLimits[1], RateLimit{Bytes: 2 * 1024 * 1024, Action: RateLimitDisconnect})
+		}
+	}
+
+	for _, rl := range []string{
+		`{}`,
+		`{msgs: 10, action: block}`,
+		`{msgs: 10, subject: "foo..bar"}`,
+		`{msgs: 10, burst: 10}`,
+		`[ 10 ]`,
+	} {
+		conf := createConfFile(t, []byte(fmt.Sprintf(`
+			authorization { users = [ {user: a, password: pwd, rate_limit: %s} ] }
+		`, rl)))
+		_, err := ProcessConfigFile(conf)
+		require_Error(t, err)
+	}
+}
+
+func checkRateLimitPending(t *testing.T, sub *nats.Subscription, expected int) {
+	t.Helper()
+	checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+		if n, _, _ := sub.Pending(); n != expected {
+			return fmt.Errorf("expected %d msgs, got %d", expected, n)
+		}
+		return nil
+	})
+}
+
+func TestRateLimitActions(t *testing.T) {
+	conf := createConfFile(t, []byte(`
+		listen: "127.0.0.1:-1"
+		authorization {
+			users = [
+				{user: drop, password: pwd, rate_limit: {subject: "limited.>", msgs: 5}}
+				{user: pause, password: pwd, rate_limit: {msgs: 20, action: pause}}
+				{user: disconnect, password: pwd, rate_limit: {msgs: 5, action: disconnect}}
+			]
+		}
+	`))
+	s, _ := RunServerWithConfig(conf)
+	defer s.Shutdown()
+
+	t.Run("Drop", func(t *testing.T) {
+		// Only used to count delivered messages.
+		snc, err := nats.Connect(s.ClientURL(), nats.UserInfo("pause", "pwd"))
+		require_NoError(t, err)
+		defer snc.Close()
+		sub, err := snc.SubscribeSync(">")
+		require_NoError(t, err)
+		require_NoError(t, snc.Flush())
+
+		// Clients close the connection on unknown errors, so use a raw connection
+		// to check that the server keeps it open.
+		conn, err := net.Dial("tcp", s.Addr().String())
+		require_NoError(t, err)
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		_, err = br.ReadString('\n')
+		require_NoError(t, err)
+
+		var buf strings.Builder
+		buf.WriteString("CONNECT {\"user\":\"drop\",\"pass\":\"pwd\",\"verbose\":false}\r\n")
+		// Not limited.
+		for i := 0; i < 20; i++ {
+			buf.WriteString("PUB free 0\r\n\r\n")
+		}
+		for i := 0; i < 20; i++ {
+			buf.WriteString("PUB limited.foo 0\r\n\r\n")
+		}
+		buf.WriteString("PING\r\n")
+		_, err = conn.Write([]byte(buf.String()))
+		require_NoError(t, err)
+
+		var errs int
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			line, err := br.ReadString('\n')
+			require_NoError(t, err)
+			if strings.HasPrefix(line, "PONG") {
+				break
+			}
+			require_Equal(t, line, "-ERR 'Rate Limit Exceeded for Publish to \"limited.foo\", Message Dropped'\r\n")
+			errs++
+		}
+		require_Equal(t, errs, 15)
+		checkRateLimitPending(t, sub, 25)
+
+		connz, err := s.Connz(&ConnzOptions{User: "drop", Username: true})
+		require_NoError(t, err)
+		require_Len(t, len(connz.Conns), 1)
+		ci := connz.Conns[0]
+		require_True(t, ci.RateLimit != nil)
+		require_Equal(t, ci.RateLimit.Dropped, 15)
+
+		stz, err := s.AccountStatz(&AccountStatzOptions{Accounts: []string{globalAccountName}})
+		require_NoError(t, err)
+		require_Len(t, len(stz.Accounts), 1)
+		require_Equal(t, stz.Accounts[0].RateLimited, 15)
+	})
+
+	t.Run("Pause", func(t *testing.T) {
+		nc, err := nats.Connect(s.ClientURL(), nats.UserInfo("pause", "pwd"))
+		require_NoError(t, err)
+		defer nc.Close()
+
+		sub, err := nc.SubscribeSync("foo")
+		require_NoError(t, err)
+		require_NoError(t, nc.Flush())
+
+		// One second worth of burst, then 20 msgs/s.
+		start := time.Now()
+		for i := 0; i < 30; i++ {
+			require_NoError(t, nc.Publish("foo", nil))
+		}
+		require_NoError(t, nc.FlushTimeout(5*time.Second))
+		require_True(t, time.Since(start) >= 400*time.Millisecond)
+		// Nothing is dropped.
+		checkRateLimitPending(t, sub, 30)
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		closed := make(chan struct{})
+		nc, err := nats.Connect(s.ClientURL(), nats.UserInfo("disconnect", "pwd"),
+			nats.NoReconnect(), nats.ErrorHandler(func(*nats.Conn, *nats.Subscription, error) {}),
+			nats.ClosedHandler(func(*nats.Conn) { close(closed) }))
+		require_NoError(t, err)
+		defer nc.Close()
+
+		for i := 0; i < 10; i++ {
+			nc.Publish("foo", nil)
+		}
+		nc.Flush()
+		select {
+		case <-closed:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected connection to be closed")
+		}
+
+		checkFor(t, 2*time.Second, 50*time.Millisecond, func() error {
+			connz, err := s.Connz(&ConnzOptions{State: ConnClosed, User: "disconnect", Username: true})
+			if err != nil {
+				return err
+			}
+			if len(connz.Conns) != 1 {
+				return fmt.Errorf("expected a closed connection, got %d", len(connz.Conns))
+			}
+			if reason := connz.Conns[0].Reason; !strings.Contains(reason, RateLimitExceeded.String()) {
+				return fmt.Errorf("unexpected reason %q", reason)
+			}
+			return nil
+		})
+	})
+}
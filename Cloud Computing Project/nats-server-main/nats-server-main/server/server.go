@@ -351,6 +351,9 @@ type Server struct {
 	rateLimitLogging   sync.Map
 	rateLimitLoggingCh chan time.Duration
 
+	// Exporter of message traces as OTLP spans, if enabled.
+	otlp atomic.Pointer[otlpExporter]
+
 	// Total outstanding catchup bytes in flight.
 	gcbMu     sync.RWMutex
 	gcbOut    int64
@@ -387,6 +390,7 @@ type nodeInfo struct {
 	js              bool
 	binarySnapshots bool
 	accountNRG      bool
+	preVote         bool
 }
 
 type stats struct {
@@ -812,7 +816,7 @@ func NewServer(opts *Options) (*Server, error) {
 			opts.Tags,
 			&JetStreamConfig{MaxMemory: opts.JetStreamMaxMemory, MaxStore: opts.JetStreamMaxStore, CompressOK: true},
 			nil,
-			false, true, true, true,
+			false, true, true, true, true,
 		})
 	}
 
@@ -2248,6 +2252,7 @@ func (s *Server) Start() {
 	s.grMu.Unlock()
 
 	s.startRateLimitLogExpiration()
+	s.startMsgTraceOTLPExporter()
 
 	// Pprof http endpoint for the profiler.
 	if opts.ProfPort != 0 {
@@ -2964,6 +2969,7 @@ const (
 	HealthzPath      = "/healthz"
 	IPQueuesPath     = "/ipqueuesz"
 	RaftzPath        = "/raftz"
+	MetricszPath     = "/metricsz"
 )
 
 func (s *Server) basePath(p string) string {
@@ -3082,6 +3088,8 @@ func (s *Server) startMonitoring(secure bool) error {
 	mux.HandleFunc(s.basePath(IPQueuesPath), s.HandleIPQueuesz)
 	// Raftz
 	mux.HandleFunc(s.basePath(RaftzPath), s.HandleRaftz)
+	// Metricsz
+	mux.HandleFunc(s.basePath(MetricszPath), s.HandleMetricsz)
 
 	// Do not set a WriteTimeout because it could cause cURL/browser
 	// to return empty response or unable to display page if the
@@ -71,6 +71,10 @@ type StreamConfig struct {
 	Compression  StoreCompression `json:"compression"`
 	FirstSeq     uint64           `json:"first_seq,omitempty"`
 
+	// CompressionLevel is the compression level for zstd compression, from
+	// 1 (fastest) to 22 (best compression). Zero selects the default level.
+	CompressionLevel int `json:"compression_level,omitempty"`
+
 	// Allow applying a subject transform to incoming messages before doing anything else
 	SubjectTransform *SubjectTransformConfig `json:"subject_transform,omitempty"`
 
@@ -116,16 +120,50 @@ type StreamConfig struct {
 	// AllowAtomicPublish allows atomic batch publishing into the stream.
 	AllowAtomicPublish bool `json:"allow_atomic"`
 
+	// AllowMsgSchedules allows header initiated delayed or scheduled messages,
+	// which are hidden from consumers until they are due.
+	AllowMsgSchedules bool `json:"allow_msg_schedules,omitempty"`
+
+	// Tiering allows sealed message blocks to be moved to tiered storage.
+	Tiering *StreamTieringPolicy `json:"tiering,omitempty"`
+
+	// Compaction removes superseded messages per subject once they are older than a head window.
+	Compaction *StreamCompactionPolicy `json:"compaction,omitempty"`
+
+	// Schema validates the payload of published messages before they are stored.
+	Schema *StreamSchema `json:"schema,omitempty"`
+
+	// Partitioning splits the stream into partitions, each with its own Raft group.
+	Partitioning *StreamPartitioning `json:"partitioning,omitempty"`
+
 	// Metadata is additional metadata for the Stream.
 	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// withLearners returns a copy of the config with the given number of learners.
+func (cfg *StreamConfig) withLearners(n int) *StreamConfig {
+	ncfg := cfg.clone()
+	if ncfg.Placement == nil || ncfg.Placement.Learners == nil {
+		return ncfg
+	}
+	if n == 0 {
+		ncfg.Placement.Learners = nil
+	} else {
+		ncfg.Placement.Learners.Replicas = n
+	}
+	return ncfg
+}
+
 // clone performs a deep copy of the StreamConfig struct, returning a new clone with
 // all values copied.
 func (cfg *StreamConfig) clone() *StreamConfig {
 	clone := *cfg
 	if cfg.Placement != nil {
 		placement := *cfg.Placement
+		if cfg.Placement.Learners != nil {
+			learners := *cfg.Placement.Learners
+			placement.Learners = &learners
+		}
 		clone.Placement = &placement
 	}
 	if cfg.Mirror != nil {
@@ -147,6 +185,20 @@ func (cfg *StreamConfig) clone() *StreamConfig {
 		rePublish := *cfg.RePublish
 		clone.RePublish = &rePublish
 	}
+	if cfg.Tiering != nil {
+		tiering := *cfg.Tiering
+		clone.Tiering = &tiering
+	}
+	if cfg.Compaction != nil {
+		compaction := *cfg.Compaction
+		clone.Compaction = &compaction
+	}
+	if cfg.Schema != nil {
+		clone.Schema = cfg.Schema.clone()
+	}
+	if cfg.Partitioning != nil {
+		clone.Partitioning = cfg.Partitioning.clone()
+	}
 	if cfg.Metadata != nil {
 		clone.Metadata = make(map[string]string, len(cfg.Metadata))
 		for k, v := range cfg.Metadata {
@@ -213,14 +265,18 @@ type CounterSources map[string]map[string]string
 
 // StreamInfo shows config and current state for this stream.
 type StreamInfo struct {
-	Config     StreamConfig        `json:"config"`
-	Created    time.Time           `json:"created"`
-	State      StreamState         `json:"state"`
-	Domain     string              `json:"domain,omitempty"`
-	Cluster    *ClusterInfo        `json:"cluster,omitempty"`
-	Mirror     *StreamSourceInfo   `json:"mirror,omitempty"`
-	Sources    []*StreamSourceInfo `json:"sources,omitempty"`
-	Alternates []StreamAlternate   `json:"alternates,omitempty"`
+	Config     StreamConfig           `json:"config"`
+	Created    time.Time              `json:"created"`
+	State      StreamState            `json:"state"`
+	Domain     string                 `json:"domain,omitempty"`
+	Cluster    *ClusterInfo           `json:"cluster,omitempty"`
+	Mirror     *StreamSourceInfo      `json:"mirror,omitempty"`
+	Sources    []*StreamSourceInfo    `json:"sources,omitempty"`
+	Alternates []StreamAlternate      `json:"alternates,omitempty"`
+	Tiering    *StreamTieringInfo     `json:"tiering,omitempty"`
+	Compaction *StreamCompactionInfo  `json:"compaction,omitempty"`
+	Schema     *StreamSchemaInfo      `json:"schema,omitempty"`
+	Partitions []*StreamPartitionInfo `json:"partitions,omitempty"`
 	// TimeStamp indicates when the info was gathered
 	TimeStamp time.Time `json:"ts"`
 }
@@ -238,6 +294,7 @@ type ClusterInfo struct {
 	RaftGroup string      `json:"raft_group,omitempty"`
 	Leader    string      `json:"leader,omitempty"`
 	Replicas  []*PeerInfo `json:"replicas,omitempty"`
+	Learners  []*PeerInfo `json:"learners,omitempty"`
 }
 
 // PeerInfo shows information about all the peers in the cluster that
@@ -258,6 +315,7 @@ type StreamSourceInfo struct {
 	Name              string                   `json:"name"`
 	External          *ExternalStream          `json:"external,omitempty"`
 	Lag               uint64                   `json:"lag"`
+	LastSeq           uint64                   `json:"last_seq,omitempty"`
 	Active            time.Duration            `json:"active"`
 	Error             *ApiError                `json:"error,omitempty"`
 	FilterSubject     string                   `json:"filter_subject,omitempty"`
@@ -273,6 +331,11 @@ type StreamSource struct {
 	SubjectTransforms []SubjectTransformConfig `json:"subject_transforms,omitempty"`
 	External          *ExternalStream          `json:"external,omitempty"`
 
+	// LagThreshold makes the leader of a mirror send an advisory when the mirror falls more
+	// than this many messages behind its origin, and another once it has caught up again.
+	// Not used for sources.
+	LagThreshold uint64 `json:"lag_threshold,omitempty"`
+
 	// Internal
 	iname string // For indexing when stream names are the same for multiple sources.
 }
@@ -335,9 +398,18 @@ type stream struct {
 	qch       chan struct{}           // The quit channel.
 	mqch      chan struct{}           // The monitor's quit channel.
 	active    bool                    // Indicates that there are active internal subscriptions (for the subject filters)
+	seeding   bool                    // Indicates that the stream is still being seeded from a clone.
 	// and/or mirror/sources consumers are scheduled to be established or already started.
 	closed atomic.Bool // Set to true when stop() is called on the stream.
 
+	schema           *streamSchema // Validates the payload of published messages.
+	schemaRejections atomic.Uint64 // Number of messages rejected by the schema.
+	schemaAdvisory   atomic.Int64  // When the last schema rejected advisory was sent.
+	schemaSuppressed atomic.Uint64 // Number of schema rejected advisories not sent since the last one.
+
+	part *StreamPartitioning // Partitioning of the stream, set on creation and immutable.
+	ppre string              // Prefix of the subjects messages for our partition are mapped to.
+
 	// Mirror
 	mirror *sourceInfo
 
@@ -390,6 +462,10 @@ type stream struct {
 	clusteredCounterTotal       map[string]*msgCounterRunningTotal // Inflight counter totals.
 	expectedPerSubjectSequence  map[uint64]string                  // Inflight 'expected per subject' subjects per clseq.
 	expectedPerSubjectInProcess map[string]struct{}                // Current 'expected per subject' subjects in process.
+	batchAckSequence            map[uint64]string                  // Inflight batch consumer acks per clseq.
+	batchAckInProcess           map[string]struct{}                // Current batch consumer acks in process.
+	batchAcks                   map[string]map[uint64]string       // Committed batch consumer acks not yet applied, per consumer.
+	bafn                        string                             // File the committed batch consumer acks are persisted in.
 
 	// Direct get subscription.
 	directSub *subscription
@@ -398,6 +474,10 @@ type stream struct {
 	monitorWg sync.WaitGroup // Wait group for the monitor routine.
 
 	batches *batching // Inflight batches prior to committing them.
+
+	sched atomic.Pointer[msgSchedules] // Scheduled messages that are not due yet.
+
+	cmptmr *time.Timer // Compacts the stream by subject while we are the leader.
 }
 
 // msgCounterRunningTotal stores a running total and a number of inflight
@@ -426,6 +506,7 @@ type sourceInfo struct {
 	sseq  uint64              // Last stream message sequence number seen from the source.
 	dseq  uint64              // Last delivery (i.e. consumer's) sequence number.
 	lag   uint64              // 0 or number of messages pending (as last reported by the consumer) - 1.
+	lagd  bool                // (mirrors only) Whether the lag threshold was exceeded.
 	err   *ApiError           // The API error that caused the last consumer setup to fail.
 	fails int                 // The number of times trying to setup the consumer failed.
 	last  atomic.Int64        // Time the consumer was created or of last message it received.
@@ -466,6 +547,10 @@ const (
 	JSBatchId                 = "Nats-Batch-Id"
 	JSBatchSeq                = "Nats-Batch-Sequence"
 	JSBatchCommit             = "Nats-Batch-Commit"
+	JSBatchAck                = "Nats-Batch-Ack"
+	JSScheduleAt              = "Nats-Schedule-At"
+	JSScheduleDelay           = "Nats-Delay"
+	JSScheduledSeq            = "Nats-Scheduled-Sequence"
 )
 
 // Headers for republished messages and direct gets.
@@ -609,9 +694,12 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	if isClustered {
 		_, reserved = tieredStreamAndReservationCount(js.cluster.streams[a.Name], tier, cfg)
 	}
-	if err := js.checkAllLimits(&selected, cfg, reserved, 0); err != nil {
-		js.mu.RUnlock()
-		return nil, err
+	// Partitioned streams hold no messages themselves, their partitions are checked instead.
+	if !cfg.Partitioning.isParent() {
+		if err := js.checkAllLimits(&selected, cfg, reserved, 0); err != nil {
+			js.mu.RUnlock()
+			return nil, err
+		}
 	}
 	js.mu.RUnlock()
 	jsa.mu.Lock()
@@ -674,7 +762,7 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 
 	// Check for overlapping subjects with other streams.
 	// These are not allowed for now.
-	if jsa.subjectsOverlap(cfg.Subjects, nil) {
+	if jsa.subjectsOverlap(cfg.Subjects, nil, cfg.partitionedStream()) {
 		jsa.mu.Unlock()
 		return nil, NewJSStreamSubjectOverlapError()
 	}
@@ -709,6 +797,7 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 		sysc:      ic,
 		tier:      tier,
 		stype:     cfg.Storage,
+		part:      cfg.Partitioning,
 		consumers: make(map[string]*consumer),
 		msgs: newIPQueue[*inMsg](s, qpfx+"messages",
 			ipqSizeCalculation(func(msg *inMsg) uint64 {
@@ -724,6 +813,10 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 		sch:  make(chan struct{}, 1),
 	}
 
+	if p := cfg.Partitioning; p.isPartition() {
+		mset.ppre = partitionSubjectPrefix(p.Stream, p.Partition)
+	}
+
 	// Start our signaling routine to process consumers.
 	mset.sigq = newIPQueue[*cMsg](s, qpfx+"obs") // of *cMsg
 	go mset.signalConsumersLoop()
@@ -777,12 +870,22 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	fsCfg.SyncInterval = s.getOpts().SyncInterval
 	fsCfg.SyncAlways = s.getOpts().SyncAlways
 	fsCfg.Compression = config.Compression
+	fsCfg.CompressionLevel = config.CompressionLevel
+	fsCfg.BlobStore = s.tieredBlobStore()
+	fsCfg.BlobCacheSize = s.getOpts().JetStreamTieredCacheSize
+	fsCfg.blobCache = s.tieredBlobCache()
 
 	if err := mset.setupStore(fsCfg); err != nil {
 		mset.stop(true, false)
 		return nil, NewJSStreamStoreFailedError(err)
 	}
 
+	// Recover committed batch consumer acks the consumers did not apply yet.
+	if config.Storage == FileStorage {
+		mset.bafn = filepath.Join(storeDir, batchAcksFile)
+		mset.loadBatchAcks()
+	}
+
 	// Create our pubAck template here. Better than json marshal each time on success.
 	if domain := s.getOpts().JetStreamDomain; domain != _EMPTY_ {
 		mset.pubAck = []byte(fmt.Sprintf("{%q:%q, %q:%q, %q:", "stream", cfg.Name, "domain", domain, "seq"))
@@ -799,6 +902,7 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	// Possible race with consumer.setLeader during recovery.
 	mset.mu.Lock()
 	mset.lseq = state.LastSeq
+	mset.setSchema(cfg.Schema)
 
 	// Ensure dedupe state is loaded.
 	mset.ddMu.Lock()
@@ -806,6 +910,11 @@ func (a *Account) addStreamWithAssignment(config *StreamConfig, fsConfig *FileSt
 	mset.ddMu.Unlock()
 	mset.mu.Unlock()
 
+	// Track any scheduled messages that are not due yet.
+	if cfg.AllowMsgSchedules {
+		mset.enableMsgSchedules()
+	}
+
 	// Set our stream assignment if in clustered mode.
 	reserveResources := true
 	if sa != nil {
@@ -1040,8 +1149,14 @@ func (mset *stream) setLeader(isLeader bool) error {
 		// Clear catchup state
 		mset.clearAllCatchupPeers()
 	}
+	mset.setupCompaction(isLeader)
 	mset.mu.Unlock()
 
+	// Only the leader fires scheduled messages.
+	if ms := mset.sched.Load(); ms != nil {
+		ms.setLeader(isLeader)
+	}
+
 	// If we are interest based make sure to check consumers.
 	// This is to make sure we process any outstanding acks.
 	mset.checkInterestState()
@@ -1289,6 +1404,39 @@ func (mset *stream) sendUpdateAdvisoryLocked() {
 	}
 }
 
+// Roles of the streams of a replication pair.
+const (
+	StreamRolePrimary = "primary"
+	StreamRoleMirror  = "mirror"
+)
+
+// Lock should be held.
+func (mset *stream) sendRoleChangedAdvisoryLocked(role string, mirror *StreamSource, gap uint64) {
+	if mset.outq == nil {
+		return
+	}
+
+	m := JSStreamRoleChangedAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamRoleChangedAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:  mset.cfg.Name,
+		Role:    role,
+		Mirror:  mirror,
+		LastSeq: mset.lseq,
+		Gap:     gap,
+		Domain:  mset.srv.getOpts().JetStreamDomain,
+	}
+
+	j, err := json.Marshal(m)
+	if err == nil {
+		subj := JSAdvisoryStreamRoleChangedPre + "." + mset.cfg.Name
+		mset.outq.sendMsg(subj, j)
+	}
+}
+
 // Created returns created time.
 func (mset *stream) createdTime() time.Time {
 	mset.mu.RLock()
@@ -1307,9 +1455,19 @@ func (mset *stream) setCreatedTime(created time.Time) {
 // subjectsOverlap to see if these subjects overlap with existing subjects.
 // Use only for non-clustered JetStream
 // RLock minimum should be held.
-func (jsa *jsAccount) subjectsOverlap(subjects []string, self *stream) bool {
+func (jsa *jsAccount) subjectsOverlap(subjects []string, self *stream, partitioned string) bool {
+	return jsa.subjectsOverlapExcept(subjects, partitioned, self)
+}
+
+// Same as subjectsOverlap, but skips all of the given streams.
+// RLock minimum should be held.
+func (jsa *jsAccount) subjectsOverlapExcept(subjects []string, partitioned string, skip ...*stream) bool {
 	for _, mset := range jsa.streams {
-		if self != nil && mset == self {
+		if slices.Contains(skip, mset) {
+			continue
+		}
+		// The partitions of a partitioned stream all share its subjects.
+		if partitioned != _EMPTY_ && mset.cfg.partitionedStream() == partitioned {
 			continue
 		}
 		for _, subj := range mset.cfg.Subjects {
@@ -1419,6 +1577,51 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("roll-ups require the purge permission"))
 	}
 
+	switch cfg.Compression {
+	case NoCompression, S2Compression, ZstdCompression:
+	default:
+		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("compression algorithm not known"))
+	}
+	if cfg.CompressionLevel != 0 {
+		if cfg.Compression != ZstdCompression {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("compression level requires zstd compression"))
+		}
+		if cfg.CompressionLevel < ZstdMinCompressionLevel || cfg.CompressionLevel > ZstdMaxCompressionLevel {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("compression level must be between %d and %d",
+				ZstdMinCompressionLevel, ZstdMaxCompressionLevel))
+		}
+	}
+
+	if cfg.Tiering != nil {
+		if cfg.Storage != FileStorage {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("tiering requires file storage"))
+		}
+		if cfg.Tiering.OffloadAfter <= 0 {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("tiering offload age must be positive"))
+		}
+		if s.tieredBlobStore() == nil {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("tiering requires tiered storage to be configured"))
+		}
+	}
+
+	if cfg.Compaction != nil {
+		if cfg.Storage != FileStorage {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("compaction requires file storage"))
+		}
+		if cfg.Compaction.HeadWindow < 0 {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("compaction head window can not be negative"))
+		}
+		if r := cfg.Compaction.MinCleanableRatio; r < 0 || r > 1 {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("compaction min cleanable ratio must be between 0 and 1"))
+		}
+	}
+
+	if cfg.Schema != nil {
+		if _, err := cfg.Schema.compile(); err != nil {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("invalid schema: %v", err))
+		}
+	}
+
 	// Counter is not compatible with some settings.
 	if cfg.AllowMsgCounter {
 		if cfg.Discard == DiscardNew {
@@ -1427,6 +1630,12 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		if cfg.AllowMsgTTL {
 			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("counter stream cannot use message TTLs"))
 		}
+		if cfg.AllowMsgSchedules {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("counter stream cannot use message schedules"))
+		}
+		if cfg.Schema != nil {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("counter stream cannot use a schema"))
+		}
 		if cfg.Retention != LimitsPolicy {
 			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("counter stream can only use limits retention"))
 		}
@@ -1504,6 +1713,12 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		if cfg.AllowMsgCounter {
 			return StreamConfig{}, NewJSMirrorWithCountersError()
 		}
+		if cfg.AllowMsgSchedules {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("message schedules forbidden on mirrors"))
+		}
+		if cfg.Schema != nil {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("schema forbidden on mirrors"))
+		}
 		if cfg.Mirror.FilterSubject != _EMPTY_ && len(cfg.Mirror.SubjectTransforms) != 0 {
 			return StreamConfig{}, NewJSMirrorMultipleFiltersNotAllowedError()
 		}
@@ -1823,6 +2038,21 @@ func (s *Server) checkStreamCfg(config *StreamConfig, acc *Account, pedantic boo
 		return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("preferred server not permitted in placement"))
 	}
 
+	if cfg.Placement != nil && cfg.Placement.Learners != nil {
+		if lr := cfg.Placement.Learners.Replicas; lr < 1 || lr > StreamMaxReplicas {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("learner replicas must be between 1 and %d", StreamMaxReplicas))
+		}
+		if cfg.Replicas < 2 {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(fmt.Errorf("learners require a replicated stream"))
+		}
+	}
+
+	if cfg.Partitioning != nil {
+		if err := s.checkStreamPartitioning(&cfg); err != nil {
+			return StreamConfig{}, NewJSStreamInvalidConfigError(err)
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -1907,11 +2137,21 @@ func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedan
 		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("message TTL status can not be disabled"))
 	}
 
+	// Check on the allowed message schedules status.
+	if old.AllowMsgSchedules && !cfg.AllowMsgSchedules {
+		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("message schedules status can not be disabled"))
+	}
+
 	// Can't change counter setting.
 	if cfg.AllowMsgCounter != old.AllowMsgCounter {
 		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change message counter setting"))
 	}
 
+	// Can't change partitioning.
+	if !reflect.DeepEqual(cfg.Partitioning, old.Partitioning) {
+		return nil, NewJSStreamInvalidConfigError(fmt.Errorf("stream configuration update can not change partitioning"))
+	}
+
 	// Do some adjustments for being sealed.
 	// Pedantic mode will allow those changes to be made, as they are deterministic and important to get a sealed stream.
 	if cfg.Sealed {
@@ -1980,6 +2220,20 @@ func (jsa *jsAccount) configUpdateCheck(old, new *StreamConfig, s *Server, pedan
 	return &cfg, nil
 }
 
+// Same as configUpdateCheck, but if failover is set the mirror is allowed to be added or removed.
+// The origin of a promoted mirror is sealed while fenced, so it is also allowed to be unsealed.
+func (jsa *jsAccount) failoverUpdateCheck(old, new *StreamConfig, s *Server, pedantic, failover bool) (*StreamConfig, error) {
+	if failover {
+		ocfg := *old
+		ocfg.Mirror = new.Mirror
+		if ocfg.Sealed && !new.Sealed {
+			ocfg.Sealed, ocfg.DenyDelete, ocfg.DenyPurge = false, new.DenyDelete, new.DenyPurge
+		}
+		old = &ocfg
+	}
+	return jsa.configUpdateCheck(old, new, s, pedantic)
+}
+
 // Update will allow certain configuration properties of an existing stream to be updated.
 func (mset *stream) update(config *StreamConfig) error {
 	return mset.updateWithAdvisory(config, true, false)
@@ -1991,6 +2245,12 @@ func (mset *stream) updatePedantic(config *StreamConfig, pedantic bool) error {
 
 // Update will allow certain configuration properties of an existing stream to be updated.
 func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool, pedantic bool) error {
+	return mset.updateWithFailover(config, sendAdvisory, pedantic, nil)
+}
+
+// Same as updateWithAdvisory, but if failover is set the update is also allowed to change
+// the mirror, which switches the stream between the primary and mirror role of a replication pair.
+func (mset *stream) updateWithFailover(config *StreamConfig, sendAdvisory, pedantic bool, failover *streamFailover) error {
 	_, jsa, err := mset.acc.checkForJetStream()
 	if err != nil {
 		return err
@@ -2001,7 +2261,7 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 	s := mset.srv
 	mset.mu.RUnlock()
 
-	cfg, err := mset.jsa.configUpdateCheck(&ocfg, config, s, pedantic)
+	cfg, err := mset.jsa.failoverUpdateCheck(&ocfg, config, s, pedantic, failover != nil)
 	if err != nil {
 		return NewJSStreamInvalidConfigError(err, Unless(err))
 	}
@@ -2036,14 +2296,20 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 	}
 
 	jsa.mu.RLock()
-	if jsa.subjectsOverlap(cfg.Subjects, mset) {
+	// A promoted mirror takes over the subjects of its origin, which is demoted right after.
+	var origin *stream
+	if failover != nil && ocfg.Mirror != nil && ocfg.Mirror.External == nil && cfg.Mirror == nil {
+		origin = jsa.streams[ocfg.Mirror.Name]
+	}
+	if jsa.subjectsOverlapExcept(cfg.Subjects, cfg.partitionedStream(), mset, origin) {
 		jsa.mu.RUnlock()
 		return NewJSStreamSubjectOverlapError()
 	}
 	jsa.mu.RUnlock()
 
 	mset.mu.Lock()
-	if mset.isLeader() {
+	// Partitioned streams receive their messages through the partition mappings instead.
+	if mset.isLeader() && mset.part == nil {
 		// Now check for subject interest differences.
 		current := make(map[string]struct{}, len(ocfg.Subjects))
 		for _, s := range ocfg.Subjects {
@@ -2138,6 +2404,29 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 		}
 	}
 
+	// Check for a switch between the primary and mirror role.
+	// The mirror consumer of a new mirror is set up below once the config is updated.
+	promoted := ocfg.Mirror != nil && cfg.Mirror == nil
+	demoted := ocfg.Mirror == nil && cfg.Mirror != nil
+	var gap uint64
+	if promoted && mset.mirror != nil {
+		gap = mset.mirror.lag
+		mset.cancelMirrorConsumer()
+		if mset.mirror.lbsub != nil {
+			mset.unsubscribe(mset.mirror.lbsub)
+		}
+		mset.mirror = nil
+	}
+	// Messages after the last replicated sequence conflict with those of the new primary.
+	if demoted && failover != nil && mset.lseq > failover.Seq {
+		gap = mset.lseq - failover.Seq
+		if err := mset.store.Truncate(failover.Seq); err != nil {
+			s.Warnf("Could not drop %d conflicting messages of demoted stream '%s > %s': %v", gap, mset.acc.Name, cfg.Name, err)
+		} else {
+			mset.lseq = failover.Seq
+		}
+	}
+
 	// Check for a change in allow direct status.
 	// These will run on all members, so just update as appropriate here.
 	// We do make sure we are caught up under monitorStream() during initial startup.
@@ -2228,6 +2517,15 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 	mset.cfg = *cfg
 	mset.cfgMu.Unlock()
 
+	if cfg.Compaction == nil || ocfg.Compaction == nil {
+		mset.setupCompaction(mset.isLeader())
+	}
+
+	// New schema versions apply to the next message published.
+	if !reflect.DeepEqual(cfg.Schema, ocfg.Schema) {
+		mset.setSchema(cfg.Schema)
+	}
+
 	// If we're changing retention and haven't errored because of consumer
 	// replicas by now, whip through and update the consumer retention.
 	if ocfg.Retention != cfg.Retention {
@@ -2251,9 +2549,18 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 		mset.mu.Lock()
 	}
 
+	if demoted && mset.isLeader() {
+		mset.setupMirrorConsumer()
+	}
+
 	// If we are the leader never suppress update advisory, simply send.
 	if mset.isLeader() && sendAdvisory {
 		mset.sendUpdateAdvisoryLocked()
+		if promoted {
+			mset.sendRoleChangedAdvisoryLocked(StreamRolePrimary, ocfg.Mirror, gap)
+		} else if demoted {
+			mset.sendRoleChangedAdvisoryLocked(StreamRoleMirror, cfg.Mirror, gap)
+		}
 	}
 	mset.mu.Unlock()
 
@@ -2276,6 +2583,14 @@ func (mset *stream) updateWithAdvisory(config *StreamConfig, sendAdvisory bool,
 
 	mset.store.UpdateConfig(cfg)
 
+	// Start tracking scheduled messages if they were just allowed.
+	if cfg.AllowMsgSchedules && !ocfg.AllowMsgSchedules {
+		mset.enableMsgSchedules()
+		if ms := mset.sched.Load(); ms != nil && mset.IsLeader() {
+			ms.setLeader(true)
+		}
+	}
+
 	return nil
 }
 
@@ -2311,6 +2626,11 @@ func (mset *stream) purge(preq *JSApiStreamPurgeRequest) (purged uint64, err err
 		return purged, err
 	}
 
+	// Stop tracking any scheduled messages that were purged.
+	if ms := mset.sched.Load(); ms != nil {
+		ms.prune(store)
+	}
+
 	// Grab our stream state.
 	var state StreamState
 	store.FastState(&state)
@@ -2423,7 +2743,7 @@ func (mset *stream) sourceInfo(si *sourceInfo) *StreamSourceInfo {
 		return nil
 	}
 
-	var ssi = StreamSourceInfo{Name: si.name, Lag: si.lag, Error: si.err, FilterSubject: si.sf}
+	var ssi = StreamSourceInfo{Name: si.name, Lag: si.lag, LastSeq: si.sseq, Error: si.err, FilterSubject: si.sf}
 
 	trConfigs := make([]SubjectTransformConfig, len(si.sfs))
 	for i := range si.sfs {
@@ -2569,6 +2889,9 @@ func (mset *stream) processMirrorMsgs(mirror *sourceInfo, ready *sync.WaitGroup)
 			}
 			// We are stalled.
 			if stalled {
+				mset.mu.Lock()
+				mset.checkMirrorLag()
+				mset.mu.Unlock()
 				mset.retryMirrorConsumer()
 			}
 		}
@@ -2662,6 +2985,7 @@ func (mset *stream) processInboundMirrorMsg(m *inMsg) bool {
 	} else {
 		mset.mirror.lag = pending - 1
 	}
+	mset.checkMirrorLag()
 
 	// Check if we allow mirror direct here. If so check they we have mostly caught up.
 	// The reason we do not require 0 is if the source is active we may always be slightly behind.
@@ -2753,6 +3077,52 @@ func (mset *stream) cancelMirrorConsumer() {
 	mset.cancelSourceInfo(mset.mirror)
 }
 
+// Sends a replication lag advisory when the mirror starts or stops exceeding
+// its lag threshold. The mirror only counts as recovered once it has fully caught
+// up, so that we do not keep sending advisories when the lag hovers around the threshold.
+// A stalled mirror no longer receives its lag, so it is considered to exceed the threshold.
+//
+// Lock should be held.
+func (mset *stream) checkMirrorLag() {
+	mirror := mset.mirror
+	if mirror == nil || mset.cfg.Mirror == nil || mset.cfg.Mirror.LagThreshold == 0 {
+		return
+	}
+	threshold := mset.cfg.Mirror.LagThreshold
+	stalled := time.Since(time.Unix(0, mirror.last.Load())) > sourceHealthCheckInterval
+	lagging := stalled || mirror.lag > threshold || (mirror.lagd && mirror.lag > 0)
+	if lagging == mirror.lagd {
+		return
+	}
+	mirror.lagd = lagging
+	if mset.outq == nil {
+		return
+	}
+
+	m := JSStreamReplicationLagAdvisory{
+		TypedEvent: TypedEvent{
+			Type: JSStreamReplicationLagRecoveredAdvisoryType,
+			ID:   nuid.Next(),
+			Time: time.Now().UTC(),
+		},
+		Stream:    mset.cfg.Name,
+		Mirror:    mset.cfg.Mirror.Name,
+		Lag:       mirror.lag,
+		LastSeq:   mirror.sseq,
+		Threshold: threshold,
+		Domain:    mset.srv.getOpts().JetStreamDomain,
+	}
+	subj := JSAdvisoryStreamReplicationLagRecoveredPre + "." + mset.cfg.Name
+	if lagging {
+		m.Type = JSStreamReplicationLagExceededAdvisoryType
+		subj = JSAdvisoryStreamReplicationLagExceededPre + "." + mset.cfg.Name
+	}
+	j, err := json.Marshal(m)
+	if err == nil {
+		mset.outq.sendMsg(subj, j)
+	}
+}
+
 // Similar to setupMirrorConsumer except that it will print a debug statement
 // indicating that there is a retry.
 //
@@ -3019,6 +3389,7 @@ func (mset *stream) setupMirrorConsumer() error {
 				// If we need to retry, schedule now
 				if retry {
 					mset.mirror.fails++
+					mset.checkMirrorLag()
 					// Cancel here since we can not do anything with this consumer at this point.
 					mset.cancelSourceInfo(mset.mirror)
 					mset.scheduleSetupMirrorConsumerRetry()
@@ -3652,6 +4023,8 @@ func (mset *stream) processInboundSourceMsg(si *sourceInfo, m *inMsg) bool {
 		hdr = removeHeaderIfPrefixPresent(hdr, "Nats-Expected-")
 		// Remove any Nats-Batch- headers, batching is not supported when sourcing.
 		hdr = removeHeaderIfPrefixPresent(hdr, "Nats-Batch-")
+		// Only our own due copies of scheduled messages may point back at the original.
+		hdr = removeHeaderIfPresent(hdr, JSScheduledSeq)
 	}
 	// Hold onto the origin reply which has all the metadata.
 	hdr = genHeader(hdr, JSStreamSource, si.genSourceHeader(m.rply))
@@ -3994,10 +4367,18 @@ func (mset *stream) subscribeToStream() error {
 	if mset.active {
 		return nil
 	}
-	for _, subject := range mset.cfg.Subjects {
-		if _, err := mset.subscribeInternal(subject, mset.processInboundJetStreamMsg); err != nil {
+	// Messages for a partitioned stream are received by its partitions,
+	// on the subjects they are mapped to by the partitioned stream.
+	if mset.ppre != _EMPTY_ {
+		if _, err := mset.subscribeInternal(mset.ppre+fwcs, mset.processInboundJetStreamMsg); err != nil {
 			return err
 		}
+	} else if mset.part == nil {
+		for _, subject := range mset.cfg.Subjects {
+			if _, err := mset.subscribeInternal(subject, mset.processInboundJetStreamMsg); err != nil {
+				return err
+			}
+		}
 	}
 	// Check if we need to setup mirroring.
 	if mset.cfg.Mirror != nil {
@@ -4133,6 +4514,9 @@ func (mset *stream) removeInternalConsumer(si *sourceInfo) {
 // Will unsubscribe from the stream.
 // Lock should be held.
 func (mset *stream) unsubscribeToStream(stopping bool) error {
+	if mset.ppre != _EMPTY_ {
+		mset.unsubscribeInternal(mset.ppre + fwcs)
+	}
 	for _, subject := range mset.cfg.Subjects {
 		mset.unsubscribeInternal(subject)
 	}
@@ -4279,7 +4663,10 @@ func (mset *stream) setupStore(fsCfg *FileStoreConfig) error {
 func (mset *stream) storeUpdates(md, bd int64, seq uint64, subj string) {
 	// If we have a single negative update then we will process our consumers for stream pending.
 	// Purge and Store handled separately inside individual calls.
-	if md == -1 && seq > 0 && subj != _EMPTY_ {
+	// Scheduled messages that are not due yet were never counted by consumers.
+	if ms := mset.sched.Load(); ms != nil && md == -1 && seq > 0 && ms.remove(seq) {
+		// Nothing to do for consumers.
+	} else if md == -1 && seq > 0 && subj != _EMPTY_ {
 		// We use our consumer list mutex here instead of the main stream lock since it may be held already.
 		mset.clsMu.RLock()
 		if mset.csl != nil {
@@ -4414,6 +4801,14 @@ func getExpectedLastSeq(hdr []byte) (uint64, bool) {
 	return uint64(parseInt64(bseq)), true
 }
 
+// Fast lookup of the consumer ack reply subject carried by a batch.
+func getBatchAck(hdr []byte) string {
+	if len(hdr) == 0 {
+		return _EMPTY_
+	}
+	return string(getHeader(JSBatchAck, hdr))
+}
+
 // Fast lookup of rollups.
 func getRollup(hdr []byte) string {
 	r := getHeader(JSMsgRollup, hdr)
@@ -4596,12 +4991,32 @@ func (mset *stream) processDirectGetRequest(_ *subscription, c *client, _ *Accou
 		(req.LastFor != _EMPTY_ && req.Batch > 0) ||
 		(req.LastFor != _EMPTY_ && len(req.MultiLastFor) > 0) ||
 		(req.NextFor != _EMPTY_ && len(req.MultiLastFor) > 0) ||
-		(req.UpToSeq > 0 && req.UpToTime != nil) {
+		(req.UpToSeq > 0 && req.UpToTime != nil) ||
+		(req.Filter != nil && (req.LastFor != _EMPTY_ || len(req.MultiLastFor) > 0)) ||
+		(req.Filter != nil && req.NextFor == _EMPTY_ && req.Batch == 0) ||
+		(req.Filter != nil && req.Filter.validate() != nil) {
 		hdr := []byte("NATS/1.0 408 Bad Request\r\n\r\n")
 		mset.outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, hdr, nil, nil, 0))
 		return
 	}
 
+	// Linearizable reads are queued once this replica has applied up to the read index.
+	if req.Linearizable {
+		if n := mset.raftNode(); n != nil {
+			n.ReadIndexAsync(linearizableReadTimeout, func(_ uint64, err error) {
+				if err != nil {
+					hdr := []byte("NATS/1.0 503 Linearizable Read Unavailable\r\n\r\n")
+					mset.outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, hdr, nil, nil, 0))
+					return
+				}
+				dg := dgPool.Get().(*directGetReq)
+				dg.req, dg.reply = req, reply
+				mset.gets.push(dg)
+			})
+			return
+		}
+	}
+
 	inlineOk := c.kind != ROUTER && c.kind != GATEWAY && c.kind != LEAF
 	if !inlineOk {
 		dg := dgPool.Get().(*directGetReq)
@@ -4612,6 +5027,9 @@ func (mset *stream) processDirectGetRequest(_ *subscription, c *client, _ *Accou
 	}
 }
 
+// How long a linearizable read waits for the stream to apply up to the read index.
+const linearizableReadTimeout = 2 * time.Second
+
 // This is for direct get by last subject which is part of the subject itself.
 func (mset *stream) processDirectGetLastBySubjectRequest(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
 	if len(reply) == 0 {
@@ -4774,6 +5192,9 @@ func (mset *stream) getDirectMulti(req *JSApiMsgGetRequest, reply string) {
 	mset.outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, hdr, nil, nil, 0))
 }
 
+// Most messages a direct get skips for not matching its filter before responding.
+var directGetMaxFilterSkip = 10_000
+
 // Do actual work on a direct msg request.
 // This could be called in a Go routine if we are inline for a non-client connection.
 func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
@@ -4816,6 +5237,8 @@ func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
 	}
 	// Track what we have sent.
 	var sentBytes int
+	// Messages skipped for not matching the filter.
+	var skipped int
 
 	// Loop over batch, which defaults to 1.
 	for i := 0; i < batch; i++ {
@@ -4826,7 +5249,7 @@ func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
 		)
 		if seq > 0 && req.NextFor == _EMPTY_ {
 			// Only do direct lookup for first in a batch.
-			if i == 0 {
+			if i == 0 && skipped == 0 {
 				sm, err = store.LoadMsg(seq, &svp)
 			} else {
 				// We want to use load next with fwcs to step over deleted msgs.
@@ -4850,6 +5273,24 @@ func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
 			mset.outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, hdr, nil, nil, 0))
 			return
 		}
+		// Messages not matching the filter do not count toward the batch.
+		if req.Filter != nil && !req.Filter.match(sm.hdr, sm.msg) {
+			if np > 0 {
+				np--
+			}
+			if skipped++; skipped >= directGetMaxFilterSkip {
+				if !isBatchRequest {
+					hdr := []byte("NATS/1.0 404 Message Not Found\r\n\r\n")
+					mset.outq.send(newJSPubMsg(reply, _EMPTY_, _EMPTY_, hdr, nil, nil, 0))
+					return
+				}
+				// Report where we stopped, so the next batch can start from there.
+				lseq = sm.seq
+				break
+			}
+			i--
+			continue
+		}
 
 		ts := time.Unix(0, sm.ts).UTC()
 		var hdr []byte
@@ -4905,7 +5346,15 @@ func (mset *stream) getDirectRequest(req *JSApiMsgGetRequest, reply string) {
 
 // processInboundJetStreamMsg handles processing messages bound for a stream.
 func (mset *stream) processInboundJetStreamMsg(_ *subscription, c *client, _ *Account, subject, reply string, rmsg []byte) {
+	// Partitions receive the messages on their mapped subject, store them under the original one.
+	if mset.ppre != _EMPTY_ {
+		subject = strings.TrimPrefix(subject, mset.ppre)
+	}
 	hdr, msg := c.msgParts(copyBytes(rmsg)) // Need to copy.
+	// Only our own due copies of scheduled messages may point back at the original.
+	if len(hdr) > 0 && mset.sched.Load() != nil {
+		hdr = removeHeaderIfPresent(hdr, JSScheduledSeq)
+	}
 	if mt, traceOnly := c.isMsgTraceEnabled(); mt != nil {
 		// If message is delivered, we need to disable the message trace headers
 		// to prevent a trace event to be generated when a stored message
@@ -4929,6 +5378,8 @@ var (
 	errInvalidMsgHandler = errors.New("undefined message handler")
 	errStreamMismatch    = errors.New("expected stream does not match")
 	errMsgTTLDisabled    = errors.New("message TTL disabled")
+
+	errMsgSchedulesDisabled = errors.New("message schedules disabled")
 )
 
 // processJetStreamMsg is where we try to actually process the stream msg.
@@ -5002,8 +5453,9 @@ func (mset *stream) processJetStreamMsg(subject, reply string, hdr, msg []byte,
 	var buf [256]byte
 	pubAck := append(buf[:0], mset.pubAck...)
 
-	// If this is a non-clustered msg and we are not considered active, meaning no active subscription, do not process.
-	if lseq == 0 && ts == 0 && !mset.active {
+	// If this is a non-clustered msg and we are not considered active, meaning no active subscription
+	// or still being seeded from a clone, do not process.
+	if lseq == 0 && ts == 0 && (!mset.active || mset.seeding) {
 		mset.mu.Unlock()
 		return nil
 	}
@@ -5160,6 +5612,28 @@ func (mset *stream) processJetStreamMsg(subject, reply string, hdr, msg []byte,
 			return errMsgTTLDisabled
 		}
 
+		// Scheduled messages are rejected entirely if schedules are not enabled on the stream,
+		// or if the schedule is invalid. Same as above, clustered mode should have caught this already.
+		if due, err := getMessageSchedule(hdr, time.Now().UnixNano()); !sourced && (due != 0 || err != nil) {
+			var apiErr *ApiError
+			if !mset.cfg.AllowMsgSchedules {
+				apiErr, err = NewJSMessageSchedulesDisabledError(), errMsgSchedulesDisabled
+			} else if err != nil {
+				apiErr = NewJSMessageScheduleInvalidError()
+			}
+			if apiErr != nil {
+				mset.mu.Unlock()
+				bumpCLFS()
+				if canRespond {
+					resp.PubAck = &PubAck{Stream: name}
+					resp.Error = apiErr
+					b, _ := json.Marshal(resp)
+					outq.sendMsg(reply, b)
+				}
+				return err
+			}
+		}
+
 		// Dedupe detection. This is done at the cluster level for dedupe detection above the
 		// lower layers. But we still need to pull out the msgId.
 		if msgId = getMsgId(hdr); msgId != _EMPTY_ {
@@ -5286,6 +5760,23 @@ func (mset *stream) processJetStreamMsg(subject, reply string, hdr, msg []byte,
 		}
 	}
 
+	// Validate the payload against the schema, if any.
+	// Same as above, clustered mode should have caught this already.
+	if sch := mset.schema; sch != nil && !sourced && (!isClustered || traceOnly) {
+		if err := sch.validate(msg); err != nil {
+			mset.mu.Unlock()
+			bumpCLFS()
+			apiErr := mset.schemaRejected(sch, name, subject, err)
+			if canRespond {
+				resp.PubAck = &PubAck{Stream: name}
+				resp.Error = apiErr
+				b, _ := json.Marshal(resp)
+				outq.sendMsg(reply, b)
+			}
+			return err
+		}
+	}
+
 	if !isClustered && incr == nil && allowMsgCounter {
 		mset.mu.Unlock()
 		// FIXME(mvv): we're not clustered, does bumping CLFS result in issues?
@@ -5640,6 +6131,13 @@ func (mset *stream) processJetStreamMsg(subject, reply string, hdr, msg []byte,
 		mset.ddMu.Unlock()
 	}
 
+	// If the batch commit carried a consumer ack, it can be applied now.
+	if batchId != _EMPTY_ && isClustered {
+		if ackSubj := getBatchAck(hdr); ackSubj != _EMPTY_ {
+			mset.applyBatchAck(ackSubj, isLeader)
+		}
+	}
+
 	// No errors, this is the normal path.
 	if rollupSub {
 		mset.purge(&JSApiStreamPurgeRequest{Subject: subject, Keep: 1})
@@ -5691,8 +6189,11 @@ func (mset *stream) processJetStreamMsg(subject, reply string, hdr, msg []byte,
 		outq.sendMsg(reply, response)
 	}
 
+	// Scheduled messages are hidden from consumers until due.
+	scheduled := mset.trackScheduledMsg(seq, subject, hdr, ts)
+
 	// Signal consumers for new messages.
-	if numConsumers > 0 {
+	if numConsumers > 0 && !scheduled {
 		mset.sigq.push(newCMsg(subject, seq))
 		select {
 		case mset.sch <- struct{}{}:
@@ -6080,6 +6581,11 @@ func (mset *stream) stop(deleteFlag, advisory bool) error {
 	// Mark closed.
 	mset.closed.Store(true)
 
+	// Stop firing scheduled messages.
+	if ms := mset.sched.Load(); ms != nil {
+		ms.stop()
+	}
+
 	// Signal to the monitor loop.
 	// Can't use qch here.
 	if mset.mqch != nil {
@@ -6169,6 +6675,8 @@ func (mset *stream) stop(deleteFlag, advisory bool) error {
 	}
 	mset.ddMu.Unlock()
 
+	mset.setupCompaction(false)
+
 	sysc := mset.sysc
 	mset.sysc = nil
 
@@ -6223,6 +6731,8 @@ func (mset *stream) stop(deleteFlag, advisory bool) error {
 			os.Remove(accDir)
 		}()
 	} else if store != nil {
+		// Persist scheduled messages so they don't need to be scanned for on restart.
+		mset.writeMsgSchedules()
 		// Ignore errors.
 		store.Stop()
 	}
@@ -6435,6 +6945,114 @@ func (mset *stream) numDirectConsumers() (num int) {
 	return num
 }
 
+// Returns how the stream data is split between local and tiered storage,
+// or nil if the stream does not use tiering.
+func (mset *stream) tieringInfo() *StreamTieringInfo {
+	mset.mu.RLock()
+	tiered := mset.cfg.Tiering != nil
+	mset.mu.RUnlock()
+	fs, ok := mset.store.(*fileStore)
+	if !ok || fs.blobs == nil {
+		return nil
+	}
+	// Still report if tiering was disabled but blocks remain offloaded.
+	if ti := fs.tieringInfo(); tiered || ti.OffloadedBlocks > 0 {
+		return ti
+	}
+	return nil
+}
+
+// setupCompaction starts compacting the stream by subject when we are the leader, and stops otherwise.
+// Lock should be held.
+func (mset *stream) setupCompaction(isLeader bool) {
+	if isLeader && mset.cfg.Compaction != nil {
+		if mset.cmptmr == nil {
+			mset.cmptmr = time.AfterFunc(mset.cfg.Compaction.interval(), mset.runCompaction)
+		}
+	} else if mset.cmptmr != nil {
+		mset.cmptmr.Stop()
+		mset.cmptmr = nil
+	}
+}
+
+func (mset *stream) runCompaction() {
+	mset.compactByKey()
+
+	mset.mu.Lock()
+	defer mset.mu.Unlock()
+	if mset.cmptmr != nil && mset.cfg.Compaction != nil {
+		mset.cmptmr.Reset(mset.cfg.Compaction.interval())
+	}
+}
+
+// compactByKey removes the messages older than the head window that were superseded by a newer
+// message on the same subject. The leader decides which messages to remove and proposes their
+// removal, so all replicas remove the same messages.
+func (mset *stream) compactByKey() {
+	mset.mu.RLock()
+	fs, _ := mset.store.(*fileStore)
+	node, name, isLeader := mset.node, mset.cfg.Name, mset.isLeader()
+	mset.mu.RUnlock()
+	if fs == nil || !isLeader {
+		return
+	}
+
+	seqs := fs.supersededMsgs()
+	if node == nil {
+		for _, seq := range seqs {
+			mset.removeSupersededMsg(seq)
+		}
+		return
+	}
+	var entries []*Entry
+	for _, seq := range seqs {
+		md := streamMsgDelete{Seq: seq, NoErase: true, Compact: true, Stream: name}
+		entries = append(entries, newEntry(EntryNormal, encodeMsgDelete(&md)))
+		// So a single proposal does not get too big.
+		if len(entries) >= 10_000 {
+			if node.ProposeMulti(entries) != nil {
+				return
+			}
+			entries = nil
+		}
+	}
+	if len(entries) > 0 {
+		node.ProposeMulti(entries)
+	}
+}
+
+// removeSupersededMsg removes a message that compaction by subject found to be superseded.
+func (mset *stream) removeSupersededMsg(seq uint64) (bool, error) {
+	fs, ok := mset.store.(*fileStore)
+	if !ok {
+		return mset.removeMsg(seq)
+	}
+	if mset.closed.Load() {
+		return false, errStreamClosed
+	}
+	removed, err := fs.removeSupersededMsg(seq)
+	if err != nil {
+		return removed, err
+	}
+	mset.mu.Lock()
+	mset.clearAllPreAcks(seq)
+	mset.mu.Unlock()
+	return removed, err
+}
+
+// Returns the progress of background compaction by subject,
+// or nil if the stream does not use compaction.
+func (mset *stream) compactionInfo() *StreamCompactionInfo {
+	mset.mu.RLock()
+	compaction := mset.cfg.Compaction != nil
+	mset.mu.RUnlock()
+	fs, ok := mset.store.(*fileStore)
+	if !ok || !compaction {
+		return nil
+	}
+	return fs.compactionInfo()
+}
+
 // State will return the current state for this stream.
 func (mset *stream) state() StreamState {
 	return mset.stateWithDetail(false)